@@ -0,0 +1,325 @@
+package anomalydetectionprocessor
+
+import (
+	"context"
+	"math"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+
+	"otelcommon/attrhash"
+)
+
+// madScale converts the median absolute deviation of normally distributed
+// values into an estimate of their standard deviation
+const madScale = 1.4826
+
+type anomalyDetectionProcessor struct {
+	config      *Config
+	logger      *zap.Logger
+	reMetric    *regexp.Regexp
+	seriesLock  sync.Mutex
+	series      map[uint64]*series
+	stopChannel chan struct{}
+	stopWaiters sync.WaitGroup
+}
+
+// series holds the recent values of a time series in a ring buffer.
+type series struct {
+	values    []float64
+	next      int
+	count     int
+	lastSeen  time.Time
+	anomalous bool
+
+	// the previous value of a cumulative counter, whose rate is checked
+	// instead of its value
+	prevValue float64
+	prevTime  pcommon.Timestamp
+	hasPrev   bool
+}
+
+// anomaly describes an anomalous value.
+type anomaly struct {
+	value     float64
+	median    float64
+	mad       float64
+	deviation float64
+}
+
+// processor constructor
+func newAnomalyDetectionProcessor(config *Config, logger *zap.Logger) *anomalyDetectionProcessor {
+	p := &anomalyDetectionProcessor{
+		config:      config,
+		logger:      logger,
+		series:      make(map[uint64]*series),
+		stopChannel: make(chan struct{}),
+	}
+	if config.MetricRegex != "" {
+		p.reMetric = regexp.MustCompile(config.MetricRegex)
+	}
+	return p
+}
+
+func (p *anomalyDetectionProcessor) start() {
+	p.stopWaiters.Add(1)
+	go p.expireLoop()
+}
+
+// processor destructor
+func (p *anomalyDetectionProcessor) cleanup() {
+	close(p.stopChannel)
+	p.stopWaiters.Wait()
+}
+
+func (p *anomalyDetectionProcessor) expireLoop() {
+	defer p.stopWaiters.Done()
+
+	ticker := time.NewTicker(min(p.config.SeriesTTL, time.Minute))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.expireSeries()
+		case <-p.stopChannel:
+			return
+		}
+	}
+}
+
+func (p *anomalyDetectionProcessor) expireSeries() {
+	cutoff := time.Now().Add(-p.config.SeriesTTL)
+
+	p.seriesLock.Lock()
+	defer p.seriesLock.Unlock()
+
+	for key, s := range p.series {
+		if s.lastSeen.Before(cutoff) {
+			delete(p.series, key)
+		}
+	}
+}
+
+func (p *anomalyDetectionProcessor) selected(name string) bool {
+	if name == p.config.EventMetric {
+		// events passing through a second processor aren't checked
+		return false
+	}
+	return slices.Contains(p.config.MetricNames, name) ||
+		(p.reMetric != nil && p.reMetric.MatchString(name))
+}
+
+func (p *anomalyDetectionProcessor) processMetrics(
+	_ context.Context,
+	md pmetric.Metrics,
+) (pmetric.Metrics, error) {
+	p.seriesLock.Lock()
+	defer p.seriesLock.Unlock()
+
+	now := time.Now()
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		rm := rms.At(i)
+		resourceHash := attrhash.Attributes(0, rm.Resource().Attributes())
+		sms := rm.ScopeMetrics()
+		for j := 0; j < sms.Len(); j++ {
+			metrics := sms.At(j).Metrics()
+
+			// events are appended after the loop, so that the loop
+			// only sees incoming metrics
+			var events []pmetric.Metric
+			for k := 0; k < metrics.Len(); k++ {
+				metric := metrics.At(k)
+				if !p.selected(metric.Name()) {
+					continue
+				}
+				if event, found := p.checkMetric(metric, resourceHash, now); found {
+					events = append(events, event)
+				}
+			}
+			for _, event := range events {
+				event.MoveTo(metrics.AppendEmpty())
+			}
+		}
+	}
+	return md, nil
+}
+
+// checkMetric adds the values of a gauge or sum to the baselines of their
+// series, and returns an event metric with a datapoint for each anomalous
+// value, if any. The rate of cumulative counters is checked instead of their
+// value.
+func (p *anomalyDetectionProcessor) checkMetric(
+	metric pmetric.Metric,
+	resourceHash uint64,
+	now time.Time,
+) (pmetric.Metric, bool) {
+	var dps pmetric.NumberDataPointSlice
+	cumulative := false
+	switch metric.Type() {
+	case pmetric.MetricTypeGauge:
+		dps = metric.Gauge().DataPoints()
+	case pmetric.MetricTypeSum:
+		dps = metric.Sum().DataPoints()
+		cumulative = metric.Sum().IsMonotonic() &&
+			metric.Sum().AggregationTemporality() ==
+				pmetric.AggregationTemporalityCumulative
+	default:
+		return pmetric.Metric{}, false
+	}
+
+	event := pmetric.NewMetric()
+	for i := 0; i < dps.Len(); i++ {
+		dp := dps.At(i)
+		key := attrhash.Attributes(attrhash.String(resourceHash, metric.Name()),
+			dp.Attributes())
+		s, exists := p.series[key]
+		if !exists {
+			if len(p.series) >= p.config.MaxSeries {
+				continue
+			}
+			s = &series{values: make([]float64, p.config.WindowSize)}
+			p.series[key] = s
+		}
+		s.lastSeen = now
+
+		value := dp.DoubleValue()
+		if dp.ValueType() == pmetric.NumberDataPointValueTypeInt {
+			value = float64(dp.IntValue())
+		}
+		if cumulative {
+			rate, ok := s.rate(value, dp.Timestamp())
+			if !ok {
+				continue
+			}
+			value = rate
+		}
+
+		a, anomalous := p.check(s, value)
+		if anomalous != s.anomalous {
+			s.anomalous = anomalous
+			p.logTransition(metric.Name(), dp.Attributes(), a, anomalous)
+		}
+		if anomalous {
+			p.appendEvent(event, metric.Name(), dp, a)
+		}
+	}
+
+	if event.Type() == pmetric.MetricTypeEmpty {
+		return pmetric.Metric{}, false
+	}
+	return event, true
+}
+
+// rate returns the per second rate of a cumulative counter since its previous
+// value, or false for the first value and after the counter was reset.
+func (s *series) rate(value float64, ts pcommon.Timestamp) (float64, bool) {
+	prevValue, prevTime, hasPrev := s.prevValue, s.prevTime, s.hasPrev
+	s.prevValue, s.prevTime, s.hasPrev = value, ts, true
+
+	if !hasPrev || value < prevValue || ts <= prevTime {
+		return 0, false
+	}
+	seconds := time.Duration(ts - prevTime).Seconds()
+	return (value - prevValue) / seconds, true
+}
+
+// check returns whether the value is anomalous compared to the values before
+// it, then adds it to the window of the series.
+func (p *anomalyDetectionProcessor) check(s *series, value float64) (anomaly, bool) {
+	var a anomaly
+	anomalous := false
+	if s.count >= p.config.MinSamples {
+		window := slices.Clone(s.values[:s.count])
+		a.value = value
+		a.median = median(window)
+		for i, v := range window {
+			window[i] = math.Abs(v - a.median)
+		}
+		a.mad = median(window)
+		a.deviation = value - a.median
+
+		threshold := max(p.config.Sigma*madScale*a.mad, p.config.MinDeviation)
+		anomalous = math.Abs(a.deviation) > threshold
+	}
+
+	s.values[s.next] = value
+	s.next = (s.next + 1) % len(s.values)
+	s.count = min(s.count+1, len(s.values))
+	return a, anomalous
+}
+
+// median sorts the values and returns their median.
+func median(values []float64) float64 {
+	slices.Sort(values)
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
+
+// appendEvent adds a datapoint for an anomalous value to the event metric. Its
+// value is the deviation from the median, and it has the attributes of the
+// anomalous datapoint along with a description of the anomaly.
+func (p *anomalyDetectionProcessor) appendEvent(
+	event pmetric.Metric,
+	name string,
+	dp pmetric.NumberDataPoint,
+	a anomaly,
+) {
+	if event.Type() == pmetric.MetricTypeEmpty {
+		event.SetName(p.config.EventMetric)
+		event.SetDescription("Deviation of anomalous values from the " +
+			"median of their series")
+		event.SetEmptyGauge()
+	}
+
+	eventDp := event.Gauge().DataPoints().AppendEmpty()
+	eventDp.SetTimestamp(dp.Timestamp())
+	eventDp.SetDoubleValue(a.deviation)
+	dp.Attributes().CopyTo(eventDp.Attributes())
+	attrs := eventDp.Attributes()
+	attrs.PutStr("anomaly.metric_name", name)
+	attrs.PutDouble("anomaly.value", a.value)
+	attrs.PutDouble("anomaly.median", a.median)
+	attrs.PutDouble("anomaly.mad", a.mad)
+	if a.mad > 0 {
+		attrs.PutDouble("anomaly.sigma",
+			math.Abs(a.deviation)/(madScale*a.mad))
+	}
+	if a.deviation > 0 {
+		attrs.PutStr("anomaly.direction", "high")
+	} else {
+		attrs.PutStr("anomaly.direction", "low")
+	}
+}
+
+// logTransition logs when a series becomes anomalous and when it is back to
+// normal, so that anomalies can be found in the collector's journal even if
+// the events never reached the backend.
+func (p *anomalyDetectionProcessor) logTransition(
+	name string,
+	attrs pcommon.Map,
+	a anomaly,
+	anomalous bool,
+) {
+	if !anomalous {
+		p.logger.Info("Series back to normal",
+			zap.String("metric", name),
+			zap.Any("attributes", attrs.AsRaw()))
+		return
+	}
+	p.logger.Warn("Anomalous value",
+		zap.String("metric", name),
+		zap.Any("attributes", attrs.AsRaw()),
+		zap.Float64("value", a.value),
+		zap.Float64("median", a.median),
+		zap.Float64("mad", a.mad))
+}
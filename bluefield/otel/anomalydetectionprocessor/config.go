@@ -0,0 +1,101 @@
+package anomalydetectionprocessor
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+// Config defines the configuration of the anomaly_detection processor.
+type Config struct {
+	// MetricNames is a list of names of metrics checked for anomalies.
+	MetricNames []string `mapstructure:"metric_names"`
+
+	// MetricRegex is a regular expression matching names of metrics
+	// checked for anomalies. At least one of MetricNames and MetricRegex
+	// must be specified.
+	MetricRegex string `mapstructure:"metric_regex"`
+
+	// WindowSize is the number of recent values of each series the
+	// baseline is computed from. Defaults to 60.
+	WindowSize int `mapstructure:"window_size"`
+
+	// MinSamples is the number of values a series needs before its values
+	// are checked. Defaults to 20.
+	MinSamples int `mapstructure:"min_samples"`
+
+	// Sigma is the number of standard deviations, estimated from the
+	// median absolute deviation, a value must deviate from the median of
+	// its series to be anomalous. Defaults to 3.
+	Sigma float64 `mapstructure:"sigma"`
+
+	// MinDeviation is the absolute deviation from the median a value must
+	// exceed to be anomalous, so that series that are nearly constant
+	// don't report tiny changes. Defaults to 0.
+	MinDeviation float64 `mapstructure:"min_deviation"`
+
+	// SeriesTTL configures how long the baseline of a series without new
+	// values is kept. Defaults to "1h".
+	SeriesTTL time.Duration `mapstructure:"series_ttl"`
+
+	// MaxSeries limits the number of series with baselines. Values of
+	// further series are not checked. Defaults to 10000.
+	MaxSeries int `mapstructure:"max_series"`
+
+	// EventMetric is the name of the gauge metric emitted for each
+	// anomalous value. Defaults to "anomaly.event".
+	EventMetric string `mapstructure:"event_metric"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if len(cfg.MetricNames) == 0 && cfg.MetricRegex == "" {
+		return errors.New("metric_names or metric_regex must be specified")
+	}
+	if cfg.MetricRegex != "" {
+		if _, err := regexp.Compile(cfg.MetricRegex); err != nil {
+			return fmt.Errorf("invalid metric_regex: %w", err)
+		}
+	}
+	if cfg.WindowSize < 3 {
+		return errors.New("window_size must be at least 3")
+	}
+	if cfg.MinSamples < 3 || cfg.MinSamples > cfg.WindowSize {
+		return errors.New("min_samples must be at least 3 and not exceed " +
+			"window_size")
+	}
+	if cfg.Sigma <= 0 {
+		return errors.New("sigma must be positive")
+	}
+	if cfg.MinDeviation < 0 {
+		return errors.New("min_deviation cannot be negative")
+	}
+	if cfg.SeriesTTL <= 0 {
+		return errors.New("series_ttl must be positive")
+	}
+	if cfg.MaxSeries <= 0 {
+		return errors.New("max_series must be positive")
+	}
+	if cfg.EventMetric == "" {
+		return errors.New("event_metric cannot be empty")
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		WindowSize:  60,
+		MinSamples:  20,
+		Sigma:       3,
+		SeriesTTL:   time.Hour,
+		MaxSeries:   10000,
+		EventMetric: "anomaly.event",
+	}
+}
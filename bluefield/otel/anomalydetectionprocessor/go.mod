@@ -0,0 +1,3 @@
+module anomalydetectionprocessor
+
+go 1.22
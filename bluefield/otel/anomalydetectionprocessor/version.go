@@ -0,0 +1,3 @@
+package anomalydetectionprocessor
+
+const Version = "0.0.1"
@@ -0,0 +1,240 @@
+package attributehashprocessor
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultIPv4Prefix = 24
+	defaultIPv6Prefix = 48
+
+	// replaces values that cannot be bucketed
+	otherBucket = "other"
+)
+
+type attributeHashProcessor struct {
+	logger *zap.Logger
+	config *Config
+	salt   []byte
+}
+
+// processor constructor
+func newAttributeHashProcessor(config *Config, logger *zap.Logger) *attributeHashProcessor {
+	return &attributeHashProcessor{
+		logger: logger,
+		config: config,
+		salt:   []byte(config.Salt),
+	}
+}
+
+func (p *attributeHashProcessor) start(context.Context, component.Host) error {
+	if p.config.SaltFile == "" {
+		return nil
+	}
+	salt, err := os.ReadFile(p.config.SaltFile)
+	if err != nil {
+		return fmt.Errorf("failed to read salt file: %w", err)
+	}
+	salt = []byte(strings.TrimSpace(string(salt)))
+	if len(salt) == 0 {
+		return errors.New("salt file is empty")
+	}
+	p.salt = salt
+	return nil
+}
+
+func (p *attributeHashProcessor) processTraces(
+	ctx context.Context,
+	td ptrace.Traces,
+) (ptrace.Traces, error) {
+	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		rs := td.ResourceSpans().At(i)
+		p.processAttributes(rs.Resource().Attributes())
+		for j := 0; j < rs.ScopeSpans().Len(); j++ {
+			ss := rs.ScopeSpans().At(j)
+			p.processAttributes(ss.Scope().Attributes())
+			for k := 0; k < ss.Spans().Len(); k++ {
+				span := ss.Spans().At(k)
+				p.processAttributes(span.Attributes())
+				for l := 0; l < span.Events().Len(); l++ {
+					p.processAttributes(span.Events().At(l).Attributes())
+				}
+				for l := 0; l < span.Links().Len(); l++ {
+					p.processAttributes(span.Links().At(l).Attributes())
+				}
+			}
+		}
+	}
+	return td, nil
+}
+
+func (p *attributeHashProcessor) processMetrics(
+	ctx context.Context,
+	md pmetric.Metrics,
+) (pmetric.Metrics, error) {
+	for i := 0; i < md.ResourceMetrics().Len(); i++ {
+		rm := md.ResourceMetrics().At(i)
+		p.processAttributes(rm.Resource().Attributes())
+		for j := 0; j < rm.ScopeMetrics().Len(); j++ {
+			sm := rm.ScopeMetrics().At(j)
+			p.processAttributes(sm.Scope().Attributes())
+			for k := 0; k < sm.Metrics().Len(); k++ {
+				p.processMetric(sm.Metrics().At(k))
+			}
+		}
+	}
+	return md, nil
+}
+
+func (p *attributeHashProcessor) processMetric(metric pmetric.Metric) {
+	switch metric.Type() {
+	case pmetric.MetricTypeGauge:
+		dps := metric.Gauge().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			p.processAttributes(dps.At(i).Attributes())
+		}
+	case pmetric.MetricTypeSum:
+		dps := metric.Sum().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			p.processAttributes(dps.At(i).Attributes())
+		}
+	case pmetric.MetricTypeHistogram:
+		dps := metric.Histogram().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			p.processAttributes(dps.At(i).Attributes())
+		}
+	case pmetric.MetricTypeExponentialHistogram:
+		dps := metric.ExponentialHistogram().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			p.processAttributes(dps.At(i).Attributes())
+		}
+	case pmetric.MetricTypeSummary:
+		dps := metric.Summary().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			p.processAttributes(dps.At(i).Attributes())
+		}
+	}
+}
+
+func (p *attributeHashProcessor) processLogs(
+	ctx context.Context,
+	ld plog.Logs,
+) (plog.Logs, error) {
+	for i := 0; i < ld.ResourceLogs().Len(); i++ {
+		rl := ld.ResourceLogs().At(i)
+		p.processAttributes(rl.Resource().Attributes())
+		for j := 0; j < rl.ScopeLogs().Len(); j++ {
+			sl := rl.ScopeLogs().At(j)
+			p.processAttributes(sl.Scope().Attributes())
+			for k := 0; k < sl.LogRecords().Len(); k++ {
+				p.processAttributes(sl.LogRecords().At(k).Attributes())
+			}
+		}
+	}
+	return ld, nil
+}
+
+// processAttributes replaces the values of configured attributes in place.
+func (p *attributeHashProcessor) processAttributes(attrs pcommon.Map) {
+	for i := range p.config.Attributes {
+		action := &p.config.Attributes[i]
+		value, exists := attrs.Get(action.Key)
+		if !exists {
+			continue
+		}
+		switch action.Action {
+		case actionHash:
+			attrs.PutStr(action.Key, p.hash(value.AsString()))
+		case actionBucket:
+			attrs.PutStr(action.Key, bucket(action, value))
+		}
+	}
+}
+
+// hash returns the truncated HMAC-SHA256 of the value keyed by the salt.
+func (p *attributeHashProcessor) hash(value string) string {
+	mac := hmac.New(sha256.New, p.salt)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))[:p.config.HashLength]
+}
+
+// bucket returns the network of an IP address value, or the boundaries
+// enclosing a numeric value. Values that are neither are replaced by "other"
+// so that unexpected values are never exported as they are.
+func bucket(action *AttributeAction, value pcommon.Value) string {
+	switch value.Type() {
+	case pcommon.ValueTypeInt:
+		return numericBucket(action.Boundaries, float64(value.Int()))
+	case pcommon.ValueTypeDouble:
+		return numericBucket(action.Boundaries, value.Double())
+	case pcommon.ValueTypeStr:
+		if addr, ok := parseAddr(value.Str()); ok {
+			return addrBucket(action, addr)
+		}
+		if number, err := strconv.ParseFloat(value.Str(), 64); err == nil {
+			return numericBucket(action.Boundaries, number)
+		}
+	}
+	return otherBucket
+}
+
+// parseAddr parses an IP address, optionally with a port which is dropped.
+func parseAddr(s string) (netip.Addr, bool) {
+	if addr, err := netip.ParseAddr(s); err == nil {
+		return addr, true
+	}
+	if addrPort, err := netip.ParseAddrPort(s); err == nil {
+		return addrPort.Addr(), true
+	}
+	return netip.Addr{}, false
+}
+
+func addrBucket(action *AttributeAction, addr netip.Addr) string {
+	addr = addr.Unmap()
+	bits := action.IPv4Prefix
+	if bits == 0 {
+		bits = defaultIPv4Prefix
+	}
+	if addr.Is6() {
+		bits = action.IPv6Prefix
+		if bits == 0 {
+			bits = defaultIPv6Prefix
+		}
+	}
+	prefix, err := addr.WithZone("").Prefix(bits)
+	if err != nil {
+		return otherBucket
+	}
+	return prefix.String()
+}
+
+func numericBucket(boundaries []float64, value float64) string {
+	if len(boundaries) == 0 {
+		return otherBucket
+	}
+	lower := "-inf"
+	for _, boundary := range boundaries {
+		upper := strconv.FormatFloat(boundary, 'g', -1, 64)
+		if value <= boundary {
+			return "(" + lower + "," + upper + "]"
+		}
+		lower = upper
+	}
+	return "(" + lower + ",+inf)"
+}
@@ -0,0 +1,117 @@
+package attributehashprocessor
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+const (
+	actionHash   = "hash"
+	actionBucket = "bucket"
+)
+
+// Config defines the configuration of the attribute_hash processor.
+type Config struct {
+	// Salt is mixed into every hash so that hashed values cannot be
+	// recovered by hashing candidate values. Either Salt or SaltFile must
+	// be specified if any attribute is hashed.
+	Salt string `mapstructure:"salt"`
+
+	// SaltFile is the path of a file containing the salt, read when the
+	// processor starts.
+	SaltFile string `mapstructure:"salt_file"`
+
+	// HashLength is the number of hex characters hashed values are
+	// truncated to. Defaults to 16.
+	HashLength int `mapstructure:"hash_length"`
+
+	// Attributes configures which attributes are replaced and how.
+	Attributes []AttributeAction `mapstructure:"attributes"`
+}
+
+// AttributeAction defines how the values of a single attribute are replaced.
+type AttributeAction struct {
+	// Key is the attribute name. The attribute is replaced wherever it
+	// appears, on resources, scopes, and log records, datapoints or spans.
+	Key string `mapstructure:"key"`
+
+	// Action is "hash" to replace the value with a salted hash, or
+	// "bucket" to replace it with a coarse bucket.
+	Action string `mapstructure:"action"`
+
+	// IPv4Prefix is the prefix length IPv4 addresses are bucketed to,
+	// e.g. 10.1.2.3 becomes 10.1.2.0/24. If zero, defaults to 24.
+	IPv4Prefix int `mapstructure:"ipv4_prefix"`
+
+	// IPv6Prefix is the prefix length IPv6 addresses are bucketed to. If
+	// zero, defaults to 48.
+	IPv6Prefix int `mapstructure:"ipv6_prefix"`
+
+	// Boundaries are the ascending upper bounds numeric values are
+	// bucketed by, e.g. boundaries [100, 1000] bucket 250 to
+	// "(100,1000]".
+	Boundaries []float64 `mapstructure:"boundaries"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if len(cfg.Attributes) == 0 {
+		return errors.New("at least one attribute must be configured")
+	}
+	if cfg.Salt != "" && cfg.SaltFile != "" {
+		return errors.New("only one of salt and salt_file may be specified")
+	}
+	if cfg.HashLength < 8 || cfg.HashLength > 64 {
+		return errors.New("hash_length must be between 8 and 64")
+	}
+
+	keys := make(map[string]bool)
+	for _, attr := range cfg.Attributes {
+		if attr.Key == "" {
+			return errors.New("attribute key cannot be empty")
+		}
+		if keys[attr.Key] {
+			return fmt.Errorf("attribute %s is configured more than once",
+				attr.Key)
+		}
+		keys[attr.Key] = true
+
+		switch attr.Action {
+		case actionHash:
+			if cfg.Salt == "" && cfg.SaltFile == "" {
+				return fmt.Errorf("attribute %s is hashed but neither "+
+					"salt nor salt_file is specified", attr.Key)
+			}
+		case actionBucket:
+			if attr.IPv4Prefix < 0 || attr.IPv4Prefix > 32 {
+				return fmt.Errorf("ipv4_prefix of attribute %s must be "+
+					"between 0 and 32", attr.Key)
+			}
+			if attr.IPv6Prefix < 0 || attr.IPv6Prefix > 128 {
+				return fmt.Errorf("ipv6_prefix of attribute %s must be "+
+					"between 0 and 128", attr.Key)
+			}
+			if !slices.IsSorted(attr.Boundaries) {
+				return fmt.Errorf("boundaries of attribute %s must be "+
+					"ascending", attr.Key)
+			}
+		default:
+			return fmt.Errorf("action of attribute %s must be %q or %q",
+				attr.Key, actionHash, actionBucket)
+		}
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		HashLength: 16,
+	}
+}
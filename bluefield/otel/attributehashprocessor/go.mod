@@ -0,0 +1,3 @@
+module attributehashprocessor
+
+go 1.22
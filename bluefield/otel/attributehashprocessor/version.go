@@ -0,0 +1,3 @@
+package attributehashprocessor
+
+const Version = "0.0.1"
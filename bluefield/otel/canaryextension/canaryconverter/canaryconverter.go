@@ -0,0 +1,318 @@
+package canaryconverter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/collector/confmap"
+)
+
+const (
+	// canaryEnv enables the converter when set to "1", so that the same
+	// collector binary runs both the current and the candidate
+	// configurations.
+	canaryEnv = "OTELCOL_CANARY"
+
+	// dirEnv overrides the directory of the state of the candidate, such as
+	// receiver cursors
+	dirEnv     = "OTELCOL_CANARY_DIR"
+	defaultDir = "/run/otelcol-contrib/canary"
+
+	// endpointEnv overrides the log stats endpoint of the candidate
+	endpointEnv     = "OTELCOL_CANARY_ENDPOINT"
+	defaultEndpoint = "localhost:18890"
+
+	// baselineEndpointEnv overrides the log stats endpoint of the collector
+	// running the current configuration, which otherwise is the log stats
+	// endpoint of the first telemetry_stats processor of the candidate.
+	baselineEndpointEnv = "OTELCOL_CANARY_BASELINE_ENDPOINT"
+
+	// durationEnv and summaryFileEnv override the duration and summary file
+	// of the canary extension
+	durationEnv    = "OTELCOL_CANARY_DURATION"
+	summaryFileEnv = "OTELCOL_CANARY_SUMMARY"
+
+	canaryExporterID  = "debug/canary"
+	canaryExtensionID = "canary"
+)
+
+// Receivers with any of these settings listen for telemetry pushed to them,
+// which only reaches the collector running the current configuration, and
+// whose ports are already taken by it.
+var listenerKeys = []string{"protocols", "listen_address", "tcp", "udp"}
+
+type canaryConverter struct {
+	enabled          bool
+	dir              string
+	endpoint         string
+	baselineEndpoint string
+	duration         string
+	summaryFile      string
+}
+
+// NewFactory returns a factory for the converter that turns a candidate
+// configuration into one that runs alongside the current configuration
+// without exporting anything, when OTELCOL_CANARY is "1".
+func NewFactory() confmap.ConverterFactory {
+	return confmap.NewConverterFactory(newConverter)
+}
+
+func newConverter(confmap.ConverterSettings) confmap.Converter {
+	return &canaryConverter{
+		enabled:          os.Getenv(canaryEnv) == "1",
+		dir:              getenv(dirEnv, defaultDir),
+		endpoint:         getenv(endpointEnv, defaultEndpoint),
+		baselineEndpoint: os.Getenv(baselineEndpointEnv),
+		duration:         os.Getenv(durationEnv),
+		summaryFile:      os.Getenv(summaryFileEnv),
+	}
+}
+
+func getenv(name, defaultValue string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// Convert rewrites the candidate configuration so that it processes the same
+// input as the current configuration and counts it with its telemetry_stats
+// processors, but:
+//   - exports to a debug exporter instead of its exporters,
+//   - leaves out the receivers listening for pushed telemetry,
+//   - keeps its file_storage extensions in a directory of its own and leaves
+//     out its other extensions,
+//   - serves log stats at its own endpoint, without the debug and push
+//     endpoints,
+//   - doesn't serve its own telemetry,
+//
+// and adds the canary extension comparing its log stats with those of the
+// current configuration.
+func (c *canaryConverter) Convert(_ context.Context, conf *confmap.Conf) error {
+	if !c.enabled {
+		return nil
+	}
+
+	cfg := conf.ToStringMap()
+	if cfg == nil {
+		cfg = make(map[string]any)
+	}
+	service := section(cfg, "service")
+
+	canary := map[string]any{"candidate_endpoint": c.endpoint}
+	baselineEndpoint := c.convertProcessors(section(cfg, "processors"))
+	if c.baselineEndpoint != "" {
+		baselineEndpoint = c.baselineEndpoint
+	}
+	if baselineEndpoint != "" {
+		canary["baseline_endpoint"] = baselineEndpoint
+	}
+	if c.duration != "" {
+		canary["duration"] = c.duration
+	}
+	if c.summaryFile != "" {
+		canary["summary_file"] = c.summaryFile
+	}
+	service["extensions"] = c.convertExtensions(section(cfg, "extensions"),
+		list(service["extensions"]), canary)
+
+	cfg["exporters"] = map[string]any{
+		canaryExporterID: map[string]any{"verbosity": "basic"},
+	}
+	convertPipelines(section(service, "pipelines"),
+		listeners(section(cfg, "receivers")), section(cfg, "connectors"))
+
+	section(service, "telemetry")["metrics"] = map[string]any{"level": "none"}
+
+	*conf = *confmap.NewFromStringMap(cfg)
+	return nil
+}
+
+// convertProcessors moves the log stats of telemetry_stats processors to the
+// endpoint of the candidate, leaves out the endpoints the current
+// configuration serves, and returns the log stats endpoint of the first
+// telemetry_stats processor, which the current configuration is assumed to
+// share.
+func (c *canaryConverter) convertProcessors(processors map[string]any) string {
+	baselineEndpoint := ""
+	for _, id := range sortedKeys(processors) {
+		settings := section(processors, id)
+		delete(settings, "debug_endpoint")
+		delete(settings, "push_endpoint")
+
+		switch componentType(id) {
+		case "telemetry_stats":
+			endpoint, _ := settings["log_stats_endpoint"].(string)
+			if port := fmt.Sprint(settings["log_stats_port"]); endpoint == "" &&
+				settings["log_stats_port"] != nil && port != "0" {
+				endpoint = "localhost:" + port
+			}
+			if endpoint == "" {
+				continue
+			}
+			if baselineEndpoint == "" {
+				baselineEndpoint = endpoint
+			}
+			delete(settings, "log_stats_port")
+			settings["log_stats_endpoint"] = c.endpoint
+		case "sensitive_window":
+			// windows started through the API only reach the current
+			// configuration, while flag files are seen by both
+			delete(settings, "endpoint")
+			if flagFile, _ := settings["flag_file"].(string); flagFile == "" {
+				settings["flag_file"] = filepath.Join(c.dir, "sensitive-window")
+			}
+		}
+	}
+	return baselineEndpoint
+}
+
+// convertExtensions keeps the enabled file_storage extensions, storing in the
+// directory of the candidate so that receivers resume from cursors of their
+// own, and returns them along with the canary extension.
+func (c *canaryConverter) convertExtensions(
+	extensions map[string]any,
+	enabled []any,
+	canary map[string]any,
+) []any {
+	kept := make([]any, 0, len(enabled)+1)
+	for _, value := range enabled {
+		id, _ := value.(string)
+		if componentType(id) != "file_storage" {
+			continue
+		}
+		settings := section(extensions, id)
+		directory := filepath.Join(c.dir, strings.ReplaceAll(id, "/", "_"))
+		settings["directory"] = directory
+		settings["create_directory"] = true
+		if compaction, exists := settings["compaction"].(map[string]any); exists {
+			compaction["directory"] = directory
+		}
+		kept = append(kept, id)
+	}
+	extensions[canaryExtensionID] = canary
+	return append(kept, canaryExtensionID)
+}
+
+// listeners returns the IDs of the receivers listening for pushed telemetry.
+func listeners(receivers map[string]any) map[string]bool {
+	ids := make(map[string]bool)
+	for id, value := range receivers {
+		settings, _ := value.(map[string]any)
+		for _, key := range listenerKeys {
+			if _, exists := settings[key]; exists {
+				ids[id] = true
+			}
+		}
+	}
+	return ids
+}
+
+// convertPipelines replaces the exporters of the pipelines with the debug
+// exporter of the candidate, and removes the listening receivers from them.
+// Pipelines left without receivers are removed, along with the connectors
+// they were the only ends of, until the remaining pipelines are consistent.
+func convertPipelines(
+	pipelines map[string]any,
+	listening map[string]bool,
+	connectors map[string]any,
+) {
+	for _, id := range sortedKeys(pipelines) {
+		pipeline := section(pipelines, id)
+		pipeline["receivers"] = filter(list(pipeline["receivers"]),
+			func(id string) bool { return !listening[id] })
+		pipeline["exporters"] = filter(list(pipeline["exporters"]),
+			func(id string) bool { return hasKey(connectors, id) })
+	}
+
+	for changed := true; changed; {
+		changed = false
+		exporting := make(map[string]bool)
+		receiving := make(map[string]bool)
+		for id := range pipelines {
+			pipeline := section(pipelines, id)
+			if len(list(pipeline["receivers"])) == 0 {
+				delete(pipelines, id)
+				changed = true
+				continue
+			}
+			for _, value := range list(pipeline["exporters"]) {
+				exporting[fmt.Sprint(value)] = true
+			}
+			for _, value := range list(pipeline["receivers"]) {
+				receiving[fmt.Sprint(value)] = true
+			}
+		}
+		for id := range pipelines {
+			pipeline := section(pipelines, id)
+			receivers := filter(list(pipeline["receivers"]), func(id string) bool {
+				return !hasKey(connectors, id) || exporting[id]
+			})
+			exporters := filter(list(pipeline["exporters"]), func(id string) bool {
+				return receiving[id]
+			})
+			if len(receivers) != len(list(pipeline["receivers"])) ||
+				len(exporters) != len(list(pipeline["exporters"])) {
+				changed = true
+			}
+			pipeline["receivers"] = receivers
+			pipeline["exporters"] = exporters
+		}
+	}
+
+	for id := range pipelines {
+		pipeline := section(pipelines, id)
+		exporters := list(pipeline["exporters"])
+		pipeline["exporters"] = append(exporters, canaryExporterID)
+	}
+}
+
+// section returns the map of settings under a key, replacing anything else
+// there, such as the nil value of a component configured without settings.
+func section(parent map[string]any, key string) map[string]any {
+	settings, _ := parent[key].(map[string]any)
+	if settings == nil {
+		settings = make(map[string]any)
+		parent[key] = settings
+	}
+	return settings
+}
+
+func list(value any) []any {
+	values, _ := value.([]any)
+	return values
+}
+
+func filter(values []any, keep func(id string) bool) []any {
+	kept := make([]any, 0, len(values))
+	for _, value := range values {
+		if keep(fmt.Sprint(value)) {
+			kept = append(kept, value)
+		}
+	}
+	return kept
+}
+
+func hasKey(settings map[string]any, key string) bool {
+	_, exists := settings[key]
+	return exists
+}
+
+func sortedKeys(settings map[string]any) []string {
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// componentType returns the type of a component ID such as "file_storage/x".
+func componentType(id string) string {
+	componentType, _, _ := strings.Cut(id, "/")
+	return componentType
+}
@@ -0,0 +1,405 @@
+package canaryextension
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.uber.org/zap"
+)
+
+const (
+	verdictMatched  = "matched"
+	verdictDiverged = "diverged"
+	verdictFailed   = "failed"
+
+	// the path of the log stats served by telemetry_stats, as JSON
+	logStatsPath = "/metrics?format=json"
+
+	// how long a request for log stats may take
+	fetchTimeout = 10 * time.Second
+)
+
+type canaryExtension struct {
+	config      *Config
+	logger      *zap.Logger
+	stopOnce    sync.Once
+	stopChannel chan struct{}
+	stopWaiters sync.WaitGroup
+}
+
+// logStat is a log stat served as JSON by telemetry_stats.
+type logStat struct {
+	Name     string            `json:"name"`
+	Grouping string            `json:"grouping"`
+	Labels   map[string]string `json:"labels"`
+	Value    json.Number       `json:"value"`
+	value    float64
+}
+
+// snapshot holds the log stats of a collector by series, identified by
+// their name, grouping and labels.
+type snapshot map[string]logStat
+
+// summary is the result of a comparison written to the summary file.
+type summary struct {
+	Verdict             string       `json:"verdict"`
+	Error               string       `json:"error,omitempty"`
+	BaselineEndpoint    string       `json:"baseline_endpoint"`
+	CandidateEndpoint   string       `json:"candidate_endpoint"`
+	Start               time.Time    `json:"start"`
+	End                 time.Time    `json:"end"`
+	MaxDifference       float64      `json:"max_difference"`
+	Stats               []statDiff   `json:"stats"`
+	Series              []seriesDiff `json:"series"`
+	BaselineOnlySeries  int          `json:"baseline_only_series"`
+	CandidateOnlySeries int          `json:"candidate_only_series"`
+}
+
+// statDiff compares the increase of a stat of a grouping, summed over its
+// series. The difference is relative to the baseline, and 1 for a stat only
+// the candidate has.
+type statDiff struct {
+	Name       string  `json:"name"`
+	Grouping   string  `json:"grouping"`
+	Baseline   float64 `json:"baseline"`
+	Candidate  float64 `json:"candidate"`
+	Difference float64 `json:"difference"`
+	Diverged   bool    `json:"diverged"`
+}
+
+// seriesDiff compares the increase of a single series.
+type seriesDiff struct {
+	Name      string            `json:"name"`
+	Grouping  string            `json:"grouping"`
+	Labels    map[string]string `json:"labels"`
+	Baseline  float64           `json:"baseline"`
+	Candidate float64           `json:"candidate"`
+}
+
+func newCanaryExtension(config *Config, logger *zap.Logger) *canaryExtension {
+	return &canaryExtension{
+		config:      config,
+		logger:      logger,
+		stopChannel: make(chan struct{}),
+	}
+}
+
+func (c *canaryExtension) Start(_ context.Context, _ component.Host) error {
+	os.Remove(c.config.SummaryFile)
+
+	c.stopWaiters.Add(1)
+	go c.run()
+	return nil
+}
+
+func (c *canaryExtension) Shutdown(context.Context) error {
+	c.stopOnce.Do(func() {
+		close(c.stopChannel)
+		c.stopWaiters.Wait()
+	})
+	return nil
+}
+
+// run takes snapshots of the log stats of both collectors after the warmup
+// and after the duration, and reports how much the stats increased in each
+// of them in between.
+func (c *canaryExtension) run() {
+	defer c.stopWaiters.Done()
+
+	if !c.wait(c.config.Warmup) {
+		return
+	}
+	s := summary{
+		BaselineEndpoint:  c.config.BaselineEndpoint,
+		CandidateEndpoint: c.config.CandidateEndpoint,
+		Start:             time.Now().UTC(),
+		MaxDifference:     c.config.MaxDifference,
+	}
+	baselineStart, candidateStart, err := c.snapshots()
+	if err == nil {
+		if !c.wait(c.config.Duration) {
+			return
+		}
+		var baselineEnd, candidateEnd snapshot
+		baselineEnd, candidateEnd, err = c.snapshots()
+		if err == nil {
+			c.compare(&s, increase(baselineStart, baselineEnd),
+				increase(candidateStart, candidateEnd))
+		}
+	}
+	s.End = time.Now().UTC()
+	if err != nil {
+		s.Verdict = verdictFailed
+		s.Error = err.Error()
+	}
+	c.report(s)
+}
+
+// wait returns whether the duration elapsed before the extension was shut
+// down.
+func (c *canaryExtension) wait(duration time.Duration) bool {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-c.stopChannel:
+		return false
+	}
+}
+
+func (c *canaryExtension) snapshots() (snapshot, snapshot, error) {
+	baseline, err := fetch(c.config.BaselineEndpoint)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get baseline log stats: %w", err)
+	}
+	candidate, err := fetch(c.config.CandidateEndpoint)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get candidate log stats: %w", err)
+	}
+	return baseline, candidate, nil
+}
+
+// fetch returns the log stats counted by the groupings of the telemetry_stats
+// processors serving them at the endpoint. Pushed counters and stats about
+// telemetry_stats itself are left out, as they don't depend on the
+// configuration of the collector.
+func fetch(endpoint string) (snapshot, error) {
+	client, url := newClient(endpoint)
+	response, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", response.Status)
+	}
+
+	var stats []logStat
+	if err := json.NewDecoder(response.Body).Decode(&stats); err != nil {
+		return nil, fmt.Errorf("failed to decode log stats: %w", err)
+	}
+	s := make(snapshot, len(stats))
+	for _, stat := range stats {
+		if stat.Grouping == "" {
+			continue
+		}
+		value, err := stat.Value.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of %s: %w", stat.Name, err)
+		}
+		stat.value = value
+		s[seriesKey(stat)] = stat
+	}
+	return s, nil
+}
+
+// newClient returns a client for a log stats endpoint, which is either an
+// address or the path of a unix socket prefixed with "unix:", and the URL of
+// the log stats it serves.
+func newClient(endpoint string) (*http.Client, string) {
+	client := &http.Client{Timeout: fetchTimeout}
+	path, isUnix := strings.CutPrefix(endpoint, "unix:")
+	if !isUnix {
+		return client, "http://" + endpoint + logStatsPath
+	}
+	client.Transport = &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", path)
+		},
+	}
+	return client, "http://localhost" + logStatsPath
+}
+
+func seriesKey(stat logStat) string {
+	labels := make([]string, 0, len(stat.Labels))
+	for name, value := range stat.Labels {
+		labels = append(labels, name+"="+value)
+	}
+	sort.Strings(labels)
+	return stat.Name + "\x00" + stat.Grouping + "\x00" +
+		strings.Join(labels, "\x00")
+}
+
+// increase returns how much each series increased between two snapshots. A
+// value lower than in the first snapshot was reset, so the value is the
+// increase, as for the stats of groupings reporting deltas.
+func increase(start, end snapshot) snapshot {
+	increased := make(snapshot, len(end))
+	for key, stat := range end {
+		if previous, exists := start[key]; exists && stat.value >= previous.value {
+			stat.value -= previous.value
+		}
+		increased[key] = stat
+	}
+	return increased
+}
+
+// compare fills the summary with the differences between the increases of the
+// stats of the baseline and the candidate.
+func (c *canaryExtension) compare(s *summary, baseline, candidate snapshot) {
+	type statKey struct{ name, grouping string }
+	stats := make(map[statKey]*statDiff)
+	statDiffOf := func(stat logStat) *statDiff {
+		key := statKey{stat.Name, stat.Grouping}
+		diff, exists := stats[key]
+		if !exists {
+			diff = &statDiff{Name: stat.Name, Grouping: stat.Grouping}
+			stats[key] = diff
+		}
+		return diff
+	}
+
+	seriesKeys := make(map[string]bool, len(baseline)+len(candidate))
+	for key, stat := range baseline {
+		statDiffOf(stat).Baseline += stat.value
+		seriesKeys[key] = true
+	}
+	for key, stat := range candidate {
+		statDiffOf(stat).Candidate += stat.value
+		seriesKeys[key] = true
+	}
+
+	s.Verdict = verdictMatched
+	s.Stats = make([]statDiff, 0, len(stats))
+	for _, diff := range stats {
+		diff.Difference = relativeDifference(diff.Baseline, diff.Candidate)
+		diff.Diverged = math.Abs(diff.Difference) > c.config.MaxDifference
+		if diff.Diverged {
+			s.Verdict = verdictDiverged
+		}
+		s.Stats = append(s.Stats, *diff)
+	}
+	sort.Slice(s.Stats, func(i, j int) bool {
+		if s.Stats[i].Grouping != s.Stats[j].Grouping {
+			return s.Stats[i].Grouping < s.Stats[j].Grouping
+		}
+		return s.Stats[i].Name < s.Stats[j].Name
+	})
+
+	keys := make([]string, 0, len(seriesKeys))
+	for key := range seriesKeys {
+		baselineStat, inBaseline := baseline[key]
+		candidateStat, inCandidate := candidate[key]
+		switch {
+		case !inCandidate:
+			s.BaselineOnlySeries++
+		case !inBaseline:
+			s.CandidateOnlySeries++
+		}
+		if baselineStat.value != candidateStat.value {
+			keys = append(keys, key)
+		}
+	}
+	difference := func(key string) float64 {
+		return math.Abs(candidate[key].value - baseline[key].value)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if di, dj := difference(keys[i]), difference(keys[j]); di != dj {
+			return di > dj
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > c.config.TopSeries {
+		keys = keys[:c.config.TopSeries]
+	}
+	s.Series = make([]seriesDiff, 0, len(keys))
+	for _, key := range keys {
+		stat, exists := baseline[key]
+		if !exists {
+			stat = candidate[key]
+		}
+		s.Series = append(s.Series, seriesDiff{
+			Name:      stat.Name,
+			Grouping:  stat.Grouping,
+			Labels:    stat.Labels,
+			Baseline:  baseline[key].value,
+			Candidate: candidate[key].value,
+		})
+	}
+}
+
+func relativeDifference(baseline, candidate float64) float64 {
+	switch {
+	case baseline != 0:
+		return (candidate - baseline) / baseline
+	case candidate != 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (c *canaryExtension) report(s summary) {
+	if err := c.writeSummary(s); err != nil {
+		c.logger.Error("Failed to write canary summary", zap.Error(err))
+	}
+
+	diverged := 0
+	for _, diff := range s.Stats {
+		if diff.Diverged {
+			diverged++
+		}
+	}
+	fields := []zap.Field{
+		zap.String("verdict", s.Verdict),
+		zap.Int("stats", len(s.Stats)),
+		zap.Int("diverged_stats", diverged),
+		zap.Int("baseline_only_series", s.BaselineOnlySeries),
+		zap.Int("candidate_only_series", s.CandidateOnlySeries),
+		zap.String("summary_file", c.config.SummaryFile),
+	}
+	switch s.Verdict {
+	case verdictFailed:
+		c.logger.Error("Canary comparison failed",
+			append(fields, zap.String("error", s.Error))...)
+	case verdictDiverged:
+		c.logger.Warn("Canary comparison completed", fields...)
+	default:
+		c.logger.Info("Canary comparison completed", fields...)
+	}
+}
+
+// writeSummary atomically replaces the summary file, so that it is never read
+// partially written.
+func (c *canaryExtension) writeSummary(s summary) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(c.config.SummaryFile)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create summary directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".canary-summary-*")
+	if err != nil {
+		return fmt.Errorf("failed to create summary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write summary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write summary file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.config.SummaryFile); err != nil {
+		return fmt.Errorf("failed to write summary file: %w", err)
+	}
+	return nil
+}
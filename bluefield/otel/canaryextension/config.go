@@ -0,0 +1,90 @@
+package canaryextension
+
+import (
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+// Config defines the configuration of the canary extension. It is added by
+// the canary converter to the candidate configuration run by
+// otelcol-wrapper-canary, and is not meant to be configured otherwise.
+type Config struct {
+	// BaselineEndpoint is the telemetry_stats log stats endpoint of the
+	// collector running the current configuration. Defaults to
+	// "localhost:8890".
+	BaselineEndpoint string `mapstructure:"baseline_endpoint"`
+
+	// CandidateEndpoint is the telemetry_stats log stats endpoint of this
+	// collector, running the candidate configuration. Defaults to
+	// "localhost:18890".
+	CandidateEndpoint string `mapstructure:"candidate_endpoint"`
+
+	// Warmup delays the comparison until the pipelines of the candidate
+	// have started. Defaults to "30s".
+	Warmup time.Duration `mapstructure:"warmup"`
+
+	// Duration is how long the log stats of both collectors are compared.
+	// Defaults to "10m".
+	Duration time.Duration `mapstructure:"duration"`
+
+	// MaxDifference is the relative difference between the baseline and
+	// candidate counts of a stat of a grouping above which the candidate is
+	// reported as diverged. Defaults to 0.1.
+	MaxDifference float64 `mapstructure:"max_difference"`
+
+	// TopSeries limits the series listed in the summary to those with the
+	// largest differences. Defaults to 20.
+	TopSeries int `mapstructure:"top_series"`
+
+	// SummaryFile is written with the summary of the comparison when it
+	// completes or fails. Defaults to
+	// "/run/otelcol-contrib/canary-summary.json".
+	SummaryFile string `mapstructure:"summary_file"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.BaselineEndpoint == "" {
+		return errors.New("baseline_endpoint cannot be empty")
+	}
+	if cfg.CandidateEndpoint == "" {
+		return errors.New("candidate_endpoint cannot be empty")
+	}
+	if cfg.BaselineEndpoint == cfg.CandidateEndpoint {
+		return errors.New("baseline_endpoint and candidate_endpoint must differ")
+	}
+	if cfg.Warmup < 0 {
+		return errors.New("warmup cannot be negative")
+	}
+	if cfg.Duration <= 0 {
+		return errors.New("duration must be positive")
+	}
+	if cfg.MaxDifference < 0 {
+		return errors.New("max_difference cannot be negative")
+	}
+	if cfg.TopSeries < 0 {
+		return errors.New("top_series cannot be negative")
+	}
+	if cfg.SummaryFile == "" {
+		return errors.New("summary_file cannot be empty")
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		BaselineEndpoint:  "localhost:8890",
+		CandidateEndpoint: "localhost:18890",
+		Warmup:            30 * time.Second,
+		Duration:          10 * time.Minute,
+		MaxDifference:     0.1,
+		TopSeries:         20,
+		SummaryFile:       "/run/otelcol-contrib/canary-summary.json",
+	}
+}
@@ -0,0 +1,30 @@
+package canaryextension
+
+import (
+	"context"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/extension"
+)
+
+const (
+	typeStr   = "canary"
+	stability = component.StabilityLevelAlpha
+)
+
+func NewFactory() extension.Factory {
+	return extension.NewFactory(
+		component.MustNewType(typeStr),
+		createDefaultConfig,
+		createExtension,
+		stability,
+	)
+}
+
+func createExtension(
+	_ context.Context,
+	set extension.CreateSettings,
+	cfg component.Config,
+) (extension.Extension, error) {
+	return newCanaryExtension(cfg.(*Config), set.Logger), nil
+}
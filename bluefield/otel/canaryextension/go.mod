@@ -0,0 +1,3 @@
+module canaryextension
+
+go 1.22
@@ -0,0 +1,3 @@
+package canaryextension
+
+const Version = "0.0.1"
@@ -0,0 +1,343 @@
+package certexpiryreceiver
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+const scopeName = "certexpiryreceiver"
+
+// expiry levels of a certificate, from least to most severe
+const (
+	levelOK = iota
+	levelWarning
+	levelCritical
+	levelExpired
+)
+
+type certExpiryReceiver struct {
+	config          *Config
+	logger          *zap.Logger
+	metricsConsumer consumer.Metrics
+	logsConsumer    consumer.Logs
+	startOnce       sync.Once
+	stopOnce        sync.Once
+	stopChannel     chan struct{}
+	stopWaiters     sync.WaitGroup
+
+	// expiry levels of the previous scan by certificate, only accessed by
+	// the scan loop
+	lastLevels map[certKey]int
+}
+
+type certKey struct {
+	source string
+	serial string
+}
+
+// certificate is a certificate found at a path or endpoint.
+type certificate struct {
+	source     string // path or endpoint
+	chainIndex int
+	cert       *x509.Certificate
+}
+
+func newCertExpiryReceiver(config *Config, logger *zap.Logger) *certExpiryReceiver {
+	return &certExpiryReceiver{
+		config:      config,
+		logger:      logger,
+		stopChannel: make(chan struct{}),
+		lastLevels:  make(map[certKey]int),
+	}
+}
+
+func (r *certExpiryReceiver) Start(_ context.Context, _ component.Host) error {
+	r.startOnce.Do(func() {
+		r.stopWaiters.Add(1)
+		go r.scanLoop()
+	})
+	return nil
+}
+
+func (r *certExpiryReceiver) Shutdown(context.Context) error {
+	r.stopOnce.Do(func() {
+		close(r.stopChannel)
+		r.stopWaiters.Wait()
+		removeReceiver(r.config)
+	})
+	return nil
+}
+
+func (r *certExpiryReceiver) scanLoop() {
+	defer r.stopWaiters.Done()
+
+	ticker := time.NewTicker(r.config.CollectionInterval)
+	defer ticker.Stop()
+
+	r.scan()
+	for {
+		select {
+		case <-ticker.C:
+			r.scan()
+		case <-r.stopChannel:
+			return
+		}
+	}
+}
+
+func (r *certExpiryReceiver) scan() {
+	ctx, cancel := context.WithTimeout(context.Background(),
+		r.config.CollectionInterval)
+	defer cancel()
+
+	var certs []certificate
+	failed := make(map[string]bool) // sources that couldn't be read
+	for _, pattern := range r.config.Paths {
+		paths, _ := filepath.Glob(pattern)
+		if len(paths) == 0 {
+			r.logger.Warn("No certificate files found",
+				zap.String("path", pattern))
+			continue
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			fileCerts, err := readCertificates(path)
+			if err != nil {
+				r.logger.Error("Failed to read certificates",
+					zap.String("path", path), zap.Error(err))
+				failed[path] = true
+				continue
+			}
+			certs = append(certs, fileCerts...)
+		}
+	}
+	for _, endpoint := range r.config.Endpoints {
+		endpointCerts, err := r.fetchCertificates(ctx, endpoint)
+		if err != nil {
+			r.logger.Error("Failed to fetch certificates",
+				zap.String("endpoint", endpoint.Endpoint), zap.Error(err))
+			failed[endpoint.Endpoint] = true
+			continue
+		}
+		certs = append(certs, endpointCerts...)
+	}
+
+	now := time.Now()
+	levels := make(map[certKey]int, len(certs))
+	for _, c := range certs {
+		levels[c.key()] = r.level(c.cert, now)
+	}
+	// keep the levels of sources that failed, so that their events are not
+	// emitted again once they recover
+	for key, level := range r.lastLevels {
+		if failed[key.source] {
+			levels[key] = level
+		}
+	}
+
+	if r.metricsConsumer != nil && len(certs) > 0 {
+		md := buildMetrics(certs, now)
+		if err := r.metricsConsumer.ConsumeMetrics(ctx, md); err != nil {
+			r.logger.Error("Failed to consume certificate metrics",
+				zap.Error(err))
+		}
+	}
+
+	if r.logsConsumer != nil {
+		ld := r.buildEventLogs(certs, levels, now)
+		if ld.LogRecordCount() > 0 {
+			if err := r.logsConsumer.ConsumeLogs(ctx, ld); err != nil {
+				r.logger.Error("Failed to consume certificate logs",
+					zap.Error(err))
+			}
+		}
+	}
+
+	r.lastLevels = levels
+}
+
+func (c certificate) key() certKey {
+	return certKey{c.source, c.cert.SerialNumber.String()}
+}
+
+// level returns the expiry level of a certificate.
+func (r *certExpiryReceiver) level(cert *x509.Certificate, now time.Time) int {
+	remaining := cert.NotAfter.Sub(now)
+	switch {
+	case remaining <= 0:
+		return levelExpired
+	case remaining <= r.config.CriticalThreshold:
+		return levelCritical
+	case remaining <= r.config.WarningThreshold:
+		return levelWarning
+	}
+	return levelOK
+}
+
+// readCertificates returns the certificates in a PEM file.
+func readCertificates(path string) ([]certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var certs []certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			// e.g. the private key in a combined file
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate %d: %w", len(certs), err)
+		}
+		certs = append(certs, certificate{
+			source:     path,
+			chainIndex: len(certs),
+			cert:       cert,
+		})
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+	return certs, nil
+}
+
+// fetchCertificates returns the certificate chain presented by a TLS server.
+func (r *certExpiryReceiver) fetchCertificates(
+	ctx context.Context,
+	endpoint Endpoint,
+) ([]certificate, error) {
+	serverName := endpoint.ServerName
+	if serverName == "" {
+		serverName, _, _ = net.SplitHostPort(endpoint.Endpoint)
+	}
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: r.config.DialTimeout},
+		Config: &tls.Config{
+			ServerName: serverName,
+			// the chain is inspected rather than verified, so that
+			// expired or otherwise invalid certificates are reported
+			InsecureSkipVerify: true,
+		},
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.config.DialTimeout)
+	defer cancel()
+	conn, err := dialer.DialContext(ctx, "tcp", endpoint.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var certs []certificate
+	for i, cert := range conn.(*tls.Conn).ConnectionState().PeerCertificates {
+		certs = append(certs, certificate{
+			source:     endpoint.Endpoint,
+			chainIndex: i,
+			cert:       cert,
+		})
+	}
+	return certs, nil
+}
+
+func buildMetrics(certs []certificate, now time.Time) pmetric.Metrics {
+	md := pmetric.NewMetrics()
+	sm := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty()
+	sm.Scope().SetName(scopeName)
+	sm.Scope().SetVersion(Version)
+
+	expiry := sm.Metrics().AppendEmpty()
+	expiry.SetName("certificate.expiry_seconds")
+	expiry.SetDescription("Time until the certificate expires, negative if it expired")
+	expiry.SetUnit("s")
+	dps := expiry.SetEmptyGauge().DataPoints()
+
+	timestamp := pcommon.NewTimestampFromTime(now)
+	for _, c := range certs {
+		dp := dps.AppendEmpty()
+		dp.SetTimestamp(timestamp)
+		dp.SetDoubleValue(c.cert.NotAfter.Sub(now).Seconds())
+		putCertificateAttributes(dp.Attributes(), c)
+	}
+	return md
+}
+
+// buildEventLogs emits a log record for each certificate whose expiry level
+// got more severe since the previous scan, including certificates seen for the
+// first time that are not ok.
+func (r *certExpiryReceiver) buildEventLogs(
+	certs []certificate,
+	levels map[certKey]int,
+	now time.Time,
+) plog.Logs {
+	ld := plog.NewLogs()
+	sl := ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty()
+	sl.Scope().SetName(scopeName)
+	sl.Scope().SetVersion(Version)
+
+	timestamp := pcommon.NewTimestampFromTime(now)
+	for _, c := range certs {
+		level := levels[c.key()]
+		if level == levelOK || level <= r.lastLevels[c.key()] {
+			continue
+		}
+
+		lr := sl.LogRecords().AppendEmpty()
+		lr.SetObservedTimestamp(timestamp)
+		lr.SetTimestamp(timestamp)
+		remaining := c.cert.NotAfter.Sub(now).Round(time.Minute)
+		switch level {
+		case levelExpired:
+			lr.SetSeverityNumber(plog.SeverityNumberError)
+			lr.SetSeverityText("ERROR")
+			lr.Body().SetStr(fmt.Sprintf("certificate %s from %s expired %s ago",
+				c.cert.Subject, c.source, -remaining))
+		case levelCritical:
+			lr.SetSeverityNumber(plog.SeverityNumberError)
+			lr.SetSeverityText("ERROR")
+			lr.Body().SetStr(fmt.Sprintf("certificate %s from %s expires in %s",
+				c.cert.Subject, c.source, remaining))
+		default:
+			lr.SetSeverityNumber(plog.SeverityNumberWarn)
+			lr.SetSeverityText("WARN")
+			lr.Body().SetStr(fmt.Sprintf("certificate %s from %s expires in %s",
+				c.cert.Subject, c.source, remaining))
+		}
+
+		attrs := lr.Attributes()
+		putCertificateAttributes(attrs, c)
+		attrs.PutDouble("certificate.expiry_seconds", c.cert.NotAfter.Sub(now).Seconds())
+	}
+	return ld
+}
+
+func putCertificateAttributes(attrs pcommon.Map, c certificate) {
+	attrs.PutStr("certificate.source", c.source)
+	attrs.PutInt("certificate.chain_index", int64(c.chainIndex))
+	attrs.PutStr("certificate.subject", c.cert.Subject.String())
+	attrs.PutStr("certificate.issuer", c.cert.Issuer.String())
+	attrs.PutStr("certificate.serial_number", c.cert.SerialNumber.String())
+	attrs.PutStr("certificate.not_after", c.cert.NotAfter.UTC().Format(time.RFC3339))
+}
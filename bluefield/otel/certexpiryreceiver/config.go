@@ -0,0 +1,89 @@
+package certexpiryreceiver
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+// Config defines the configuration of the cert_expiry receiver.
+type Config struct {
+	// CollectionInterval configures how often certificates are scanned.
+	// Defaults to "1h".
+	CollectionInterval time.Duration `mapstructure:"collection_interval"`
+
+	// Paths are PEM files, or glob patterns matching PEM files, whose
+	// certificates are checked, e.g. the device identity and the
+	// certificates of API servers on the DPU. Every certificate in a file
+	// is checked, including intermediates of a chain.
+	Paths []string `mapstructure:"paths"`
+
+	// Endpoints are TLS servers whose certificate chains are checked.
+	Endpoints []Endpoint `mapstructure:"endpoints"`
+
+	// DialTimeout limits how long connecting to an endpoint and the TLS
+	// handshake may take. Defaults to "10s".
+	DialTimeout time.Duration `mapstructure:"dial_timeout"`
+
+	// WarningThreshold is the time before expiry at which a certificate is
+	// reported with WARN severity. Defaults to "720h" (30 days).
+	WarningThreshold time.Duration `mapstructure:"warning_threshold"`
+
+	// CriticalThreshold is the time before expiry at which a certificate
+	// is reported with ERROR severity. Defaults to "168h" (7 days).
+	CriticalThreshold time.Duration `mapstructure:"critical_threshold"`
+}
+
+// Endpoint is a TLS server whose certificate chain is checked.
+type Endpoint struct {
+	// Endpoint is the address of the server, e.g. "localhost:6443".
+	Endpoint string `mapstructure:"endpoint"`
+
+	// ServerName is sent as SNI. Defaults to the host of Endpoint.
+	ServerName string `mapstructure:"server_name"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.CollectionInterval <= 0 {
+		return errors.New("collection_interval must be positive")
+	}
+	if len(cfg.Paths) == 0 && len(cfg.Endpoints) == 0 {
+		return errors.New("paths or endpoints must be specified")
+	}
+	for _, path := range cfg.Paths {
+		if _, err := filepath.Match(path, ""); err != nil {
+			return fmt.Errorf("invalid path %q: %w", path, err)
+		}
+	}
+	for _, endpoint := range cfg.Endpoints {
+		if _, _, err := net.SplitHostPort(endpoint.Endpoint); err != nil {
+			return fmt.Errorf("invalid endpoint %q: %w", endpoint.Endpoint, err)
+		}
+	}
+	if cfg.DialTimeout <= 0 {
+		return errors.New("dial_timeout must be positive")
+	}
+	if cfg.CriticalThreshold < 0 || cfg.WarningThreshold < cfg.CriticalThreshold {
+		return errors.New("critical_threshold cannot be negative or exceed " +
+			"warning_threshold")
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		CollectionInterval: time.Hour,
+		DialTimeout:        10 * time.Second,
+		WarningThreshold:   30 * 24 * time.Hour,
+		CriticalThreshold:  7 * 24 * time.Hour,
+	}
+}
@@ -0,0 +1,72 @@
+package certexpiryreceiver
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/receiver"
+)
+
+const (
+	typeStr   = "cert_expiry"
+	stability = component.StabilityLevelAlpha
+)
+
+var (
+	// a receiver configured in both metrics and logs pipelines scans
+	// certificates once for both
+	receiversLock sync.Mutex
+	receivers     = make(map[*Config]*certExpiryReceiver)
+)
+
+func NewFactory() receiver.Factory {
+	return receiver.NewFactory(
+		component.MustNewType(typeStr),
+		createDefaultConfig,
+		receiver.WithMetrics(createMetricsReceiver, stability),
+		receiver.WithLogs(createLogsReceiver, stability),
+	)
+}
+
+func createMetricsReceiver(
+	_ context.Context,
+	set receiver.CreateSettings,
+	cfg component.Config,
+	nextConsumer consumer.Metrics,
+) (receiver.Metrics, error) {
+	r := getReceiver(cfg.(*Config), set)
+	r.metricsConsumer = nextConsumer
+	return r, nil
+}
+
+func createLogsReceiver(
+	_ context.Context,
+	set receiver.CreateSettings,
+	cfg component.Config,
+	nextConsumer consumer.Logs,
+) (receiver.Logs, error) {
+	r := getReceiver(cfg.(*Config), set)
+	r.logsConsumer = nextConsumer
+	return r, nil
+}
+
+func getReceiver(config *Config, set receiver.CreateSettings) *certExpiryReceiver {
+	receiversLock.Lock()
+	defer receiversLock.Unlock()
+
+	r, exists := receivers[config]
+	if !exists {
+		r = newCertExpiryReceiver(config, set.Logger)
+		receivers[config] = r
+	}
+	return r
+}
+
+func removeReceiver(config *Config) {
+	receiversLock.Lock()
+	defer receiversLock.Unlock()
+
+	delete(receivers, config)
+}
@@ -0,0 +1,3 @@
+module certexpiryreceiver
+
+go 1.22
@@ -0,0 +1,3 @@
+package certexpiryreceiver
+
+const Version = "0.0.1"
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	// registers the pure Go "sqlite" driver, like the ring store exporter
+	_ "modernc.org/sqlite"
+)
+
+const (
+	formatAuto      = "auto"
+	formatJSON      = "json"
+	formatProto     = "proto"
+	formatRingStore = "ringstore"
+
+	signalLogs    = "logs"
+	signalMetrics = "metrics"
+	signalTraces  = "traces"
+
+	// maxRequestSize limits the size of a captured request
+	maxRequestSize = 64 << 20
+)
+
+// batch is a captured OTLP request. Exactly one of logs, metrics and traces is
+// set, according to the signal.
+type batch struct {
+	signal  string
+	time    time.Time // earliest timestamp of the batch, or zero
+	logs    plog.Logs
+	metrics pmetric.Metrics
+	traces  ptrace.Traces
+}
+
+// readCapture reads the batches of a capture file. The auto format detects
+// ring store databases by their extension and proto captures by the signal
+// being specified.
+func readCapture(path, format, signal string) ([]*batch, error) {
+	if format == formatAuto {
+		switch {
+		case slices.Contains([]string{".db", ".sqlite"}, filepath.Ext(path)):
+			format = formatRingStore
+		case signal != "":
+			format = formatProto
+		default:
+			format = formatJSON
+		}
+	}
+
+	switch format {
+	case formatJSON:
+		return readJSONCapture(path)
+	case formatProto:
+		return readProtoCapture(path, signal)
+	case formatRingStore:
+		return readRingStore(path)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// readJSONCapture reads one OTLP JSON request per line, telling the signal by
+// its top-level field.
+func readJSONCapture(path string) ([]*batch, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var batches []*batch
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 1<<20), maxRequestSize)
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		var signal string
+		switch {
+		case fields["resourceLogs"] != nil:
+			signal = signalLogs
+		case fields["resourceMetrics"] != nil:
+			signal = signalMetrics
+		case fields["resourceSpans"] != nil:
+			signal = signalTraces
+		default:
+			return nil, fmt.Errorf("line %d: not an OTLP request", line)
+		}
+		b, err := unmarshalBatch(signal, data, true)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		batches = append(batches, b)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return batches, nil
+}
+
+// readProtoCapture reads OTLP protobuf requests, each preceded by its size as
+// a 4-byte big-endian integer.
+func readProtoCapture(path, signal string) ([]*batch, error) {
+	if signal != signalLogs && signal != signalMetrics && signal != signalTraces {
+		return nil, errors.New("signal must be logs, metrics or traces for proto captures")
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var batches []*batch
+	reader := bufio.NewReader(file)
+	var size [4]byte
+	for {
+		if _, err := io.ReadFull(reader, size[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				return batches, nil
+			}
+			return nil, err
+		}
+		n := binary.BigEndian.Uint32(size[:])
+		if n > maxRequestSize {
+			return nil, fmt.Errorf("request %d of %d bytes is too large",
+				len(batches)+1, n)
+		}
+		data := make([]byte, n)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		b, err := unmarshalBatch(signal, data, false)
+		if err != nil {
+			return nil, fmt.Errorf("request %d: %w", len(batches)+1, err)
+		}
+		batches = append(batches, b)
+	}
+}
+
+func unmarshalBatch(signal string, data []byte, isJSON bool) (*batch, error) {
+	b := &batch{signal: signal}
+	var err error
+	switch signal {
+	case signalLogs:
+		if isJSON {
+			b.logs, err = (&plog.JSONUnmarshaler{}).UnmarshalLogs(data)
+		} else {
+			b.logs, err = (&plog.ProtoUnmarshaler{}).UnmarshalLogs(data)
+		}
+	case signalMetrics:
+		if isJSON {
+			b.metrics, err = (&pmetric.JSONUnmarshaler{}).UnmarshalMetrics(data)
+		} else {
+			b.metrics, err = (&pmetric.ProtoUnmarshaler{}).UnmarshalMetrics(data)
+		}
+	case signalTraces:
+		if isJSON {
+			b.traces, err = (&ptrace.JSONUnmarshaler{}).UnmarshalTraces(data)
+		} else {
+			b.traces, err = (&ptrace.ProtoUnmarshaler{}).UnmarshalTraces(data)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	b.time = earliest(b)
+	return b, nil
+}
+
+// readRingStore reads the metrics and logs of a ring store exporter database,
+// one batch for the rows of each timestamp. The database keeps only values,
+// so number datapoints become gauges, and histograms and summaries gauges of
+// their sum with their count as the attribute "count".
+func readRingStore(path string) ([]*batch, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	metrics, err := readRingStoreMetrics(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metrics: %w", err)
+	}
+	logs, err := readRingStoreLogs(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read logs: %w", err)
+	}
+	return append(metrics, logs...), nil
+}
+
+func readRingStoreMetrics(db *sql.DB) ([]*batch, error) {
+	rows, err := db.Query("SELECT ts, name, value, count, attributes, " +
+		"resource FROM metrics ORDER BY ts, rowid")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var batches []*batch
+	var current *batch
+	var currentTS int64
+	for rows.Next() {
+		var ts int64
+		var name, attrs, resource string
+		var value float64
+		var count sql.NullInt64
+		if err := rows.Scan(&ts, &name, &value, &count, &attrs, &resource); err != nil {
+			return nil, err
+		}
+		if current == nil || ts != currentTS {
+			current = &batch{
+				signal:  signalMetrics,
+				time:    time.Unix(0, ts),
+				metrics: pmetric.NewMetrics(),
+			}
+			currentTS = ts
+			batches = append(batches, current)
+		}
+		rm := current.metrics.ResourceMetrics().AppendEmpty()
+		if err := putAttributes(rm.Resource().Attributes(), resource); err != nil {
+			return nil, err
+		}
+		metric := rm.ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+		metric.SetName(name)
+		dp := metric.SetEmptyGauge().DataPoints().AppendEmpty()
+		dp.SetTimestamp(pcommon.Timestamp(ts))
+		dp.SetDoubleValue(value)
+		if err := putAttributes(dp.Attributes(), attrs); err != nil {
+			return nil, err
+		}
+		if count.Valid {
+			dp.Attributes().PutInt("count", count.Int64)
+		}
+	}
+	return batches, rows.Err()
+}
+
+func readRingStoreLogs(db *sql.DB) ([]*batch, error) {
+	rows, err := db.Query("SELECT ts, severity_number, severity_text, body, " +
+		"attributes, resource FROM logs ORDER BY ts, rowid")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var batches []*batch
+	var current *batch
+	var currentTS int64
+	for rows.Next() {
+		var ts, severityNumber int64
+		var severityText, body, attrs, resource string
+		if err := rows.Scan(&ts, &severityNumber, &severityText, &body,
+			&attrs, &resource); err != nil {
+			return nil, err
+		}
+		if current == nil || ts != currentTS {
+			current = &batch{
+				signal: signalLogs,
+				time:   time.Unix(0, ts),
+				logs:   plog.NewLogs(),
+			}
+			currentTS = ts
+			batches = append(batches, current)
+		}
+		rl := current.logs.ResourceLogs().AppendEmpty()
+		if err := putAttributes(rl.Resource().Attributes(), resource); err != nil {
+			return nil, err
+		}
+		lr := rl.ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+		lr.SetTimestamp(pcommon.Timestamp(ts))
+		lr.SetSeverityNumber(plog.SeverityNumber(severityNumber))
+		lr.SetSeverityText(severityText)
+		lr.Body().SetStr(body)
+		if err := putAttributes(lr.Attributes(), attrs); err != nil {
+			return nil, err
+		}
+	}
+	return batches, rows.Err()
+}
+
+// putAttributes puts the attributes stored as a JSON object by the ring store
+// exporter.
+func putAttributes(m pcommon.Map, data string) error {
+	if strings.TrimSpace(data) == "" {
+		return nil
+	}
+	var raw map[string]any
+	if err := json.Unmarshal([]byte(data), &raw); err != nil {
+		return fmt.Errorf("invalid attributes: %w", err)
+	}
+	return m.FromRaw(raw)
+}
+
+// sortBatches orders the batches of all captures by time. Batches without a
+// timestamp keep their place after the batch before them.
+func sortBatches(batches []*batch) {
+	var last time.Time
+	for _, b := range batches {
+		if b.time.IsZero() {
+			b.time = last
+		}
+		last = b.time
+	}
+	slices.SortStableFunc(batches, func(a, b *batch) int {
+		return a.time.Compare(b.time)
+	})
+}
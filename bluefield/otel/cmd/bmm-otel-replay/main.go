@@ -0,0 +1,84 @@
+// Command bmm-otel-replay replays captured OTLP traffic into a collector on
+// the bench, at its original or accelerated timing, to reproduce field issues
+// with processors such as telemetry_stats and filters.
+//
+// It reads OTLP JSON captures (one request per line, as written by the file
+// exporter), OTLP protobuf captures (length-prefixed requests, as written by
+// the file exporter with format proto), and ring store exporter databases, and
+// posts their batches over OTLP/HTTP to a collector running the pipeline under
+// test.
+//
+// Usage:
+//
+//	bmm-otel-replay [flags] <capture>...
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+)
+
+func main() {
+	endpoint := flag.String("endpoint", "http://localhost:4318",
+		"OTLP/HTTP endpoint of the collector batches are replayed into")
+	format := flag.String("format", formatAuto,
+		"format of the captures: auto, json, proto or ringstore")
+	signalName := flag.String("signal", "",
+		"signal of proto captures: logs, metrics or traces")
+	speed := flag.Float64("speed", 1,
+		"replay speed relative to the original timing, or 0 to replay as fast as possible")
+	retime := flag.Bool("retime", false,
+		"shift timestamps so that each batch appears to be sent as it is replayed")
+	repeat := flag.Int("repeat", 1, "number of times the captures are replayed")
+	timeout := flag.Duration("timeout", 10*time.Second, "timeout of each request")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"Usage: %s [flags] <capture>...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *speed < 0 {
+		fmt.Fprintln(os.Stderr, "speed cannot be negative")
+		os.Exit(2)
+	}
+	if *repeat <= 0 {
+		fmt.Fprintln(os.Stderr, "repeat must be positive")
+		os.Exit(2)
+	}
+
+	var batches []*batch
+	for _, path := range flag.Args() {
+		read, err := readCapture(path, *format, *signalName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to read %s: %v\n", path, err)
+			os.Exit(1)
+		}
+		batches = append(batches, read...)
+	}
+	sortBatches(batches)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	r := newReplayer(*endpoint, *speed, *retime, *timeout)
+	for i := 0; i < *repeat; i++ {
+		if err := r.replay(ctx, batches); err != nil {
+			fmt.Fprintf(os.Stderr, "replay stopped: %v\n", err)
+			break
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "replayed %d batches, %d failed\n", r.sent, r.failed)
+	if r.failed > 0 || ctx.Err() != nil {
+		os.Exit(1)
+	}
+}
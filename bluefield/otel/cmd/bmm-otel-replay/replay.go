@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// replayer posts batches to an OTLP/HTTP endpoint.
+type replayer struct {
+	endpoint string
+	speed    float64
+	retime   bool
+	client   *http.Client
+
+	sent   int // batches accepted by the endpoint
+	failed int // batches rejected by or not delivered to the endpoint
+}
+
+func newReplayer(endpoint string, speed float64, retime bool, timeout time.Duration) *replayer {
+	return &replayer{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		speed:    speed,
+		retime:   retime,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+// replay posts the batches in order, each once the time elapsed since the
+// first batch in the capture, divided by the speed, has elapsed since the
+// replay started. Batches that fail are reported and skipped, so that a replay
+// is not thrown off by a collector refusing some of them.
+func (r *replayer) replay(ctx context.Context, batches []*batch) error {
+	if len(batches) == 0 {
+		return nil
+	}
+	start := time.Now()
+	first := batches[0].time
+	for _, b := range batches {
+		if r.speed > 0 && !first.IsZero() {
+			due := start.Add(time.Duration(float64(b.time.Sub(first)) / r.speed))
+			if err := sleepUntil(ctx, due); err != nil {
+				return err
+			}
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := r.send(ctx, b); err != nil {
+			r.failed++
+			fmt.Fprintf(os.Stderr, "failed to replay %s batch of %s: %v\n",
+				b.signal, b.time.Format(time.RFC3339Nano), err)
+			continue
+		}
+		r.sent++
+	}
+	return nil
+}
+
+func (r *replayer) send(ctx context.Context, b *batch) error {
+	if r.retime && !b.time.IsZero() {
+		b = retimed(b, time.Since(b.time))
+	}
+	body, err := marshalBatch(b)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		r.endpoint+"/v1/"+b.signal, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// retimed returns a copy of the batch with its timestamps shifted by delta,
+// leaving the captured batch as is for later repeats.
+func retimed(b *batch, delta time.Duration) *batch {
+	c := &batch{signal: b.signal, time: b.time.Add(delta)}
+	switch b.signal {
+	case signalLogs:
+		c.logs = plog.NewLogs()
+		b.logs.CopyTo(c.logs)
+	case signalMetrics:
+		c.metrics = pmetric.NewMetrics()
+		b.metrics.CopyTo(c.metrics)
+	case signalTraces:
+		c.traces = ptrace.NewTraces()
+		b.traces.CopyTo(c.traces)
+	}
+	shift(c, delta)
+	return c
+}
+
+func marshalBatch(b *batch) ([]byte, error) {
+	switch b.signal {
+	case signalLogs:
+		return (&plog.ProtoMarshaler{}).MarshalLogs(b.logs)
+	case signalMetrics:
+		return (&pmetric.ProtoMarshaler{}).MarshalMetrics(b.metrics)
+	case signalTraces:
+		return (&ptrace.ProtoMarshaler{}).MarshalTraces(b.traces)
+	default:
+		return nil, fmt.Errorf("unsupported signal %q", b.signal)
+	}
+}
+
+func sleepUntil(ctx context.Context, t time.Time) error {
+	d := time.Until(t)
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
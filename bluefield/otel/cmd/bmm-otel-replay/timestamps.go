@@ -0,0 +1,137 @@
+package main
+
+import (
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// earliest returns the earliest timestamp of a batch: of log records, falling
+// back to their observed timestamps, of metric datapoints, and of span starts.
+// It returns the zero time if the batch has no timestamps.
+func earliest(b *batch) time.Time {
+	var min pcommon.Timestamp
+	visitTimestamps(b, func(ts pcommon.Timestamp) pcommon.Timestamp {
+		if ts != 0 && (min == 0 || ts < min) {
+			min = ts
+		}
+		return ts
+	})
+	if min == 0 {
+		return time.Time{}
+	}
+	return min.AsTime()
+}
+
+// shift moves the timestamps of a batch by delta, leaving unset ones unset.
+func shift(b *batch, delta time.Duration) {
+	visitTimestamps(b, func(ts pcommon.Timestamp) pcommon.Timestamp {
+		if ts == 0 {
+			return 0
+		}
+		return pcommon.NewTimestampFromTime(ts.AsTime().Add(delta))
+	})
+}
+
+// visitTimestamps replaces each timestamp of a batch by the one visit returns.
+func visitTimestamps(b *batch, visit func(pcommon.Timestamp) pcommon.Timestamp) {
+	switch b.signal {
+	case signalLogs:
+		visitLogTimestamps(b.logs, visit)
+	case signalMetrics:
+		visitMetricTimestamps(b.metrics, visit)
+	case signalTraces:
+		visitSpanTimestamps(b.traces, visit)
+	}
+}
+
+func visitLogTimestamps(ld plog.Logs, visit func(pcommon.Timestamp) pcommon.Timestamp) {
+	rls := ld.ResourceLogs()
+	for i := 0; i < rls.Len(); i++ {
+		sls := rls.At(i).ScopeLogs()
+		for j := 0; j < sls.Len(); j++ {
+			records := sls.At(j).LogRecords()
+			for k := 0; k < records.Len(); k++ {
+				lr := records.At(k)
+				lr.SetTimestamp(visit(lr.Timestamp()))
+				lr.SetObservedTimestamp(visit(lr.ObservedTimestamp()))
+			}
+		}
+	}
+}
+
+// point is implemented by all metric datapoint types.
+type point interface {
+	Timestamp() pcommon.Timestamp
+	SetTimestamp(pcommon.Timestamp)
+	StartTimestamp() pcommon.Timestamp
+	SetStartTimestamp(pcommon.Timestamp)
+}
+
+func visitPoint(p point, visit func(pcommon.Timestamp) pcommon.Timestamp) {
+	p.SetTimestamp(visit(p.Timestamp()))
+	p.SetStartTimestamp(visit(p.StartTimestamp()))
+}
+
+func visitMetricTimestamps(md pmetric.Metrics, visit func(pcommon.Timestamp) pcommon.Timestamp) {
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		sms := rms.At(i).ScopeMetrics()
+		for j := 0; j < sms.Len(); j++ {
+			metrics := sms.At(j).Metrics()
+			for k := 0; k < metrics.Len(); k++ {
+				metric := metrics.At(k)
+				switch metric.Type() {
+				case pmetric.MetricTypeGauge:
+					points := metric.Gauge().DataPoints()
+					for l := 0; l < points.Len(); l++ {
+						visitPoint(points.At(l), visit)
+					}
+				case pmetric.MetricTypeSum:
+					points := metric.Sum().DataPoints()
+					for l := 0; l < points.Len(); l++ {
+						visitPoint(points.At(l), visit)
+					}
+				case pmetric.MetricTypeHistogram:
+					points := metric.Histogram().DataPoints()
+					for l := 0; l < points.Len(); l++ {
+						visitPoint(points.At(l), visit)
+					}
+				case pmetric.MetricTypeExponentialHistogram:
+					points := metric.ExponentialHistogram().DataPoints()
+					for l := 0; l < points.Len(); l++ {
+						visitPoint(points.At(l), visit)
+					}
+				case pmetric.MetricTypeSummary:
+					points := metric.Summary().DataPoints()
+					for l := 0; l < points.Len(); l++ {
+						visitPoint(points.At(l), visit)
+					}
+				}
+			}
+		}
+	}
+}
+
+func visitSpanTimestamps(td ptrace.Traces, visit func(pcommon.Timestamp) pcommon.Timestamp) {
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		sss := rss.At(i).ScopeSpans()
+		for j := 0; j < sss.Len(); j++ {
+			spans := sss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				span := spans.At(k)
+				span.SetStartTimestamp(visit(span.StartTimestamp()))
+				span.SetEndTimestamp(visit(span.EndTimestamp()))
+				events := span.Events()
+				for l := 0; l < events.Len(); l++ {
+					event := events.At(l)
+					event.SetTimestamp(visit(event.Timestamp()))
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package correlationidprocessor
+
+import (
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+// Config defines the configuration of the correlation_id processor.
+type Config struct {
+	// Attribute is the attribute of log records and metric datapoints
+	// the correlation ID is stamped on. Defaults to "correlation.id".
+	Attribute string `mapstructure:"attribute"`
+
+	// ResourceAttributes are resource attributes the correlation ID is
+	// computed from, in addition to the host's serial number and boot ID.
+	// Telemetry of resources lacking any of them is not stamped, so that
+	// unrelated telemetry never shares a correlation ID.
+	ResourceAttributes []string `mapstructure:"resource_attributes"`
+
+	// IncludeSerial includes the host's serial number in the correlation
+	// ID. Defaults to true.
+	IncludeSerial bool `mapstructure:"include_serial"`
+
+	// IncludeBootID includes the kernel's boot ID in the correlation ID,
+	// so that telemetry of different boots of the same host is not
+	// correlated. Defaults to true.
+	IncludeBootID bool `mapstructure:"include_boot_id"`
+
+	// Interval is the length of the time buckets telemetry is correlated
+	// by. The timestamp of each log record or datapoint is truncated to
+	// the interval. Defaults to "1m".
+	Interval time.Duration `mapstructure:"interval"`
+
+	// HashLength is the number of hex characters correlation IDs are
+	// truncated to. Defaults to 16.
+	HashLength int `mapstructure:"hash_length"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.Attribute == "" {
+		return errors.New("attribute cannot be empty")
+	}
+	for _, key := range cfg.ResourceAttributes {
+		if key == "" {
+			return errors.New("resource attribute cannot be empty")
+		}
+	}
+	if len(cfg.ResourceAttributes) == 0 && !cfg.IncludeSerial && !cfg.IncludeBootID {
+		return errors.New("at least one of resource_attributes, " +
+			"include_serial and include_boot_id must be specified")
+	}
+	if cfg.Interval <= 0 {
+		return errors.New("interval must be positive")
+	}
+	if cfg.HashLength < 8 || cfg.HashLength > 64 {
+		return errors.New("hash_length must be between 8 and 64")
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		Attribute:     "correlation.id",
+		IncludeSerial: true,
+		IncludeBootID: true,
+		Interval:      time.Minute,
+		HashLength:    16,
+	}
+}
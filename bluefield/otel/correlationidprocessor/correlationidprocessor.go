@@ -0,0 +1,208 @@
+package correlationidprocessor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+// the first readable path is used
+var (
+	serialPaths = []string{
+		"/sys/class/dmi/id/product_serial",
+		"/sys/firmware/devicetree/base/serial-number",
+	}
+	bootIDPath = "/proc/sys/kernel/random/boot_id"
+)
+
+type correlationIDProcessor struct {
+	logger *zap.Logger
+	config *Config
+
+	// host components of the correlation ID, read when the processor
+	// starts
+	host []string
+}
+
+// processor constructor
+func newCorrelationIDProcessor(config *Config, logger *zap.Logger) *correlationIDProcessor {
+	return &correlationIDProcessor{
+		logger: logger,
+		config: config,
+	}
+}
+
+func (p *correlationIDProcessor) start(context.Context, component.Host) error {
+	// the processors of every pipeline read the same values, so a missing
+	// value still yields the same correlation IDs for metrics and logs
+	if p.config.IncludeSerial {
+		serial := readFirst(serialPaths)
+		if serial == "" {
+			p.logger.Warn("Failed to read the serial number, correlation " +
+				"IDs don't include it")
+		}
+		p.host = append(p.host, serial)
+	}
+	if p.config.IncludeBootID {
+		bootID := readFirst([]string{bootIDPath})
+		if bootID == "" {
+			p.logger.Warn("Failed to read the boot ID, correlation IDs " +
+				"don't include it")
+		}
+		p.host = append(p.host, bootID)
+	}
+	return nil
+}
+
+// readFirst returns the trimmed content of the first non-empty file of paths.
+func readFirst(paths []string) string {
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		// device tree strings are NUL terminated
+		if value := strings.TrimSpace(strings.TrimRight(string(data), "\x00")); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func (p *correlationIDProcessor) processMetrics(
+	ctx context.Context,
+	md pmetric.Metrics,
+) (pmetric.Metrics, error) {
+	now := pcommon.NewTimestampFromTime(time.Now())
+	for i := 0; i < md.ResourceMetrics().Len(); i++ {
+		rm := md.ResourceMetrics().At(i)
+		ids := p.newResourceIDs(rm.Resource().Attributes())
+		if ids == nil {
+			continue
+		}
+		for j := 0; j < rm.ScopeMetrics().Len(); j++ {
+			sm := rm.ScopeMetrics().At(j)
+			for k := 0; k < sm.Metrics().Len(); k++ {
+				p.processMetric(sm.Metrics().At(k), ids, now)
+			}
+		}
+	}
+	return md, nil
+}
+
+func (p *correlationIDProcessor) processMetric(
+	metric pmetric.Metric,
+	ids *resourceIDs,
+	now pcommon.Timestamp,
+) {
+	switch metric.Type() {
+	case pmetric.MetricTypeGauge:
+		dps := metric.Gauge().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			p.stamp(dps.At(i).Attributes(), ids, dps.At(i).Timestamp(), now)
+		}
+	case pmetric.MetricTypeSum:
+		dps := metric.Sum().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			p.stamp(dps.At(i).Attributes(), ids, dps.At(i).Timestamp(), now)
+		}
+	case pmetric.MetricTypeHistogram:
+		dps := metric.Histogram().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			p.stamp(dps.At(i).Attributes(), ids, dps.At(i).Timestamp(), now)
+		}
+	case pmetric.MetricTypeExponentialHistogram:
+		dps := metric.ExponentialHistogram().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			p.stamp(dps.At(i).Attributes(), ids, dps.At(i).Timestamp(), now)
+		}
+	case pmetric.MetricTypeSummary:
+		dps := metric.Summary().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			p.stamp(dps.At(i).Attributes(), ids, dps.At(i).Timestamp(), now)
+		}
+	}
+}
+
+func (p *correlationIDProcessor) processLogs(
+	ctx context.Context,
+	ld plog.Logs,
+) (plog.Logs, error) {
+	now := pcommon.NewTimestampFromTime(time.Now())
+	for i := 0; i < ld.ResourceLogs().Len(); i++ {
+		rl := ld.ResourceLogs().At(i)
+		ids := p.newResourceIDs(rl.Resource().Attributes())
+		if ids == nil {
+			continue
+		}
+		for j := 0; j < rl.ScopeLogs().Len(); j++ {
+			sl := rl.ScopeLogs().At(j)
+			for k := 0; k < sl.LogRecords().Len(); k++ {
+				lr := sl.LogRecords().At(k)
+				timestamp := lr.Timestamp()
+				if timestamp == 0 {
+					timestamp = lr.ObservedTimestamp()
+				}
+				p.stamp(lr.Attributes(), ids, timestamp, now)
+			}
+		}
+	}
+	return ld, nil
+}
+
+// resourceIDs are the correlation IDs of a resource by time bucket.
+type resourceIDs struct {
+	prefix  string // host and resource components
+	buckets map[int64]string
+}
+
+// newResourceIDs returns nil if the resource lacks a configured attribute.
+func (p *correlationIDProcessor) newResourceIDs(resource pcommon.Map) *resourceIDs {
+	components := make([]string, 0, len(p.host)+len(p.config.ResourceAttributes))
+	components = append(components, p.host...)
+	for _, key := range p.config.ResourceAttributes {
+		value, exists := resource.Get(key)
+		if !exists {
+			return nil
+		}
+		components = append(components, value.AsString())
+	}
+
+	// components are separated by a NUL character, which they cannot
+	// contain, so that different components never hash alike
+	return &resourceIDs{
+		prefix:  strings.Join(components, "\x00") + "\x00",
+		buckets: make(map[int64]string),
+	}
+}
+
+// stamp sets the correlation ID of the time bucket of the timestamp, or of the
+// current time if the timestamp is unset.
+func (p *correlationIDProcessor) stamp(
+	attrs pcommon.Map,
+	ids *resourceIDs,
+	timestamp pcommon.Timestamp,
+	now pcommon.Timestamp,
+) {
+	if timestamp == 0 {
+		timestamp = now
+	}
+	bucket := timestamp.AsTime().Truncate(p.config.Interval).Unix()
+	id, exists := ids.buckets[bucket]
+	if !exists {
+		sum := sha256.Sum256([]byte(ids.prefix + strconv.FormatInt(bucket, 10)))
+		id = hex.EncodeToString(sum[:])[:p.config.HashLength]
+		ids.buckets[bucket] = id
+	}
+	attrs.PutStr(p.config.Attribute, id)
+}
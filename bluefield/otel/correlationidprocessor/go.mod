@@ -0,0 +1,3 @@
+module correlationidprocessor
+
+go 1.22
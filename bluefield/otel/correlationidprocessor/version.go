@@ -0,0 +1,3 @@
+package correlationidprocessor
+
+const Version = "0.0.1"
@@ -0,0 +1,91 @@
+package cryptooffloadreceiver
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+const (
+	aggregationInterface = "interface"
+	aggregationPeer      = "peer"
+	aggregationSA        = "sa"
+)
+
+// Config defines the configuration of the crypto_offload receiver.
+type Config struct {
+	// CollectionInterval configures how often offload statistics are
+	// collected. Defaults to "30s".
+	CollectionInterval time.Duration `mapstructure:"collection_interval"`
+
+	// InterfaceRegex matches the names of the interfaces whose offload
+	// counters are collected, and the offload devices of the SAs that
+	// are reported. Defaults to the DPU uplinks (p0, p1) and host PF
+	// representors (pf0hpf).
+	InterfaceRegex string `mapstructure:"interface_regex"`
+
+	// EthtoolPath is the path of the ethtool binary used to query the
+	// driver counters. Defaults to "ethtool".
+	EthtoolPath string `mapstructure:"ethtool_path"`
+
+	// IPPath is the path of the ip binary used to list the IPsec SAs.
+	// Defaults to "ip".
+	IPPath string `mapstructure:"ip_path"`
+
+	// IncludeTLS configures whether the kTLS offload counters of the
+	// driver are collected along with the IPsec ones. Defaults to true.
+	IncludeTLS bool `mapstructure:"include_tls"`
+
+	// IncludeSAs configures whether the IPsec SAs are listed, to report
+	// the SAs installed, their traffic and rekey events. Defaults to
+	// true.
+	IncludeSAs bool `mapstructure:"include_sas"`
+
+	// SAAggregation configures how the statistics of SAs are aggregated:
+	// "interface" per offload device and direction, "peer" also per
+	// source and destination address, or "sa" per SA, adding its SPI.
+	// Defaults to "interface".
+	SAAggregation string `mapstructure:"sa_aggregation"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.CollectionInterval <= 0 {
+		return errors.New("collection_interval must be positive")
+	}
+	if _, err := regexp.Compile(cfg.InterfaceRegex); err != nil {
+		return fmt.Errorf("invalid interface_regex: %w", err)
+	}
+	if cfg.EthtoolPath == "" {
+		return errors.New("ethtool_path cannot be empty")
+	}
+	if cfg.IncludeSAs && cfg.IPPath == "" {
+		return errors.New("ip_path cannot be empty when include_sas is true")
+	}
+	switch cfg.SAAggregation {
+	case aggregationInterface, aggregationPeer, aggregationSA:
+	default:
+		return fmt.Errorf("sa_aggregation must be %q, %q or %q",
+			aggregationInterface, aggregationPeer, aggregationSA)
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		CollectionInterval: 30 * time.Second,
+		InterfaceRegex:     `^(p[0-9]+|pf[0-9]+hpf)$`,
+		EthtoolPath:        "ethtool",
+		IPPath:             "ip",
+		IncludeTLS:         true,
+		IncludeSAs:         true,
+		SAAggregation:      aggregationInterface,
+	}
+}
@@ -0,0 +1,465 @@
+package cryptooffloadreceiver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+const scopeName = "cryptooffloadreceiver"
+
+// metricInfo describes a metric of the receiver.
+type metricInfo struct {
+	unit        string
+	description string
+	gauge       bool
+}
+
+var metricInfos = map[string]metricInfo{
+	"crypto_offload.ipsec.packets": {"{packets}",
+		"Packets processed by IPsec offload", false},
+	"crypto_offload.ipsec.bytes": {"By",
+		"Bytes processed by IPsec offload", false},
+	"crypto_offload.ipsec.dropped_packets": {"{packets}",
+		"Packets dropped by IPsec offload", false},
+	"crypto_offload.ipsec.dropped_bytes": {"By",
+		"Bytes dropped by IPsec offload", false},
+	"crypto_offload.ipsec.drops": {"{packets}",
+		"Packets dropped by IPsec offload, by reason", false},
+	"crypto_offload.tls.packets": {"{packets}",
+		"Packets encrypted or decrypted by kTLS offload", false},
+	"crypto_offload.tls.bytes": {"By",
+		"Bytes encrypted or decrypted by kTLS offload", false},
+	"crypto_offload.tls.contexts_installed": {"{contexts}",
+		"kTLS offload contexts installed", false},
+	"crypto_offload.tls.contexts_deleted": {"{contexts}",
+		"kTLS offload contexts deleted", false},
+	"crypto_offload.tls.drops": {"{packets}",
+		"Packets dropped or failed by kTLS offload, by reason", false},
+	"crypto_offload.sa.count": {"{SAs}",
+		"IPsec SAs currently installed", true},
+	"crypto_offload.sa.bytes": {"By",
+		"Bytes processed by the IPsec SAs", false},
+	"crypto_offload.sa.packets": {"{packets}",
+		"Packets processed by the IPsec SAs", false},
+	"crypto_offload.sa.replay_drops": {"{packets}",
+		"Packets dropped by the replay check of the IPsec SAs", false},
+	"crypto_offload.sa.integrity_failures": {"{packets}",
+		"Packets failing the integrity check of the IPsec SAs", false},
+	"crypto_offload.sa.installed": {"{SAs}",
+		"IPsec SAs installed", false},
+	"crypto_offload.sa.removed": {"{SAs}",
+		"IPsec SAs removed", false},
+	"crypto_offload.sa.rekeys": {"{SAs}",
+		"IPsec SAs installed replacing an SA of the same flow", false},
+}
+
+type cryptoOffloadReceiver struct {
+	config       *Config
+	reInterface  *regexp.Regexp
+	logger       *zap.Logger
+	nextConsumer consumer.Metrics
+	startTime    pcommon.Timestamp
+	stopChannel  chan struct{}
+	stopWaiters  sync.WaitGroup
+
+	// the SAs of the previous collection by identity, nil before the
+	// first collection
+	previousSAs map[string]*securityAssociation
+	// the flows of the previous collection, to detect rekeys
+	previousFlows map[string]bool
+	// the statistics of SAs by aggregation key, removed once no SA is
+	// aggregated under the key
+	aggregates map[string]*saAggregate
+	// the SA lifecycle events by offload device, direction and mode
+	lifecycles map[string]*saLifecycle
+}
+
+// saAggregate is the statistics of the SAs aggregated under a key. Counters
+// accumulate the increments of each SA, so that they keep growing when SAs
+// are replaced.
+type saAggregate struct {
+	attrs    pcommon.Map
+	start    pcommon.Timestamp
+	count    int
+	counters saCounters
+}
+
+type saLifecycle struct {
+	attrs     pcommon.Map
+	installed int64
+	removed   int64
+	rekeys    int64
+}
+
+func newCryptoOffloadReceiver(
+	config *Config,
+	reInterface *regexp.Regexp,
+	logger *zap.Logger,
+	nextConsumer consumer.Metrics,
+) *cryptoOffloadReceiver {
+	return &cryptoOffloadReceiver{
+		config:       config,
+		reInterface:  reInterface,
+		logger:       logger,
+		nextConsumer: nextConsumer,
+		stopChannel:  make(chan struct{}),
+		aggregates:   make(map[string]*saAggregate),
+		lifecycles:   make(map[string]*saLifecycle),
+	}
+}
+
+func (r *cryptoOffloadReceiver) Start(_ context.Context, _ component.Host) error {
+	r.startTime = pcommon.NewTimestampFromTime(time.Now())
+	r.stopWaiters.Add(1)
+	go r.collectLoop()
+	return nil
+}
+
+func (r *cryptoOffloadReceiver) Shutdown(context.Context) error {
+	close(r.stopChannel)
+	r.stopWaiters.Wait()
+	return nil
+}
+
+func (r *cryptoOffloadReceiver) collectLoop() {
+	defer r.stopWaiters.Done()
+
+	ticker := time.NewTicker(r.config.CollectionInterval)
+	defer ticker.Stop()
+
+	r.collect()
+	for {
+		select {
+		case <-ticker.C:
+			r.collect()
+		case <-r.stopChannel:
+			return
+		}
+	}
+}
+
+func (r *cryptoOffloadReceiver) collect() {
+	ctx, cancel := context.WithTimeout(context.Background(),
+		r.config.CollectionInterval)
+	defer cancel()
+
+	now := pcommon.NewTimestampFromTime(time.Now())
+	md := pmetric.NewMetrics()
+	sm := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty()
+	sm.Scope().SetName(scopeName)
+	sm.Scope().SetVersion(Version)
+	metrics := newMetricBuilder(sm.Metrics(), now)
+
+	r.collectDriverCounters(ctx, metrics)
+	if r.config.IncludeSAs {
+		r.collectSAs(ctx, metrics, now)
+	}
+
+	if md.DataPointCount() == 0 {
+		return
+	}
+	if err := r.nextConsumer.ConsumeMetrics(ctx, md); err != nil {
+		r.logger.Error("Failed to consume crypto offload statistics",
+			zap.Error(err))
+	}
+}
+
+// collectDriverCounters reports the offload counters of the driver of each
+// matching interface.
+func (r *cryptoOffloadReceiver) collectDriverCounters(
+	ctx context.Context,
+	metrics *metricBuilder,
+) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		r.logger.Error("Failed to list interfaces", zap.Error(err))
+		return
+	}
+	for _, iface := range interfaces {
+		if !r.reInterface.MatchString(iface.Name) {
+			continue
+		}
+		output, err := r.run(ctx, r.config.EthtoolPath, "-S", iface.Name)
+		if err != nil {
+			r.logger.Error("Failed to query driver counters",
+				zap.String("interface", iface.Name), zap.Error(err))
+			continue
+		}
+		stats := parseEthtoolStats(output)
+		names := make([]string, 0, len(stats))
+		for name := range stats {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			counter, ok := classifyCounter(name, r.config.IncludeTLS)
+			if !ok {
+				continue
+			}
+			attrs := pcommon.NewMap()
+			attrs.PutStr("interface", iface.Name)
+			attrs.PutStr("direction", counter.direction)
+			if counter.reason != "" {
+				attrs.PutStr("reason", counter.reason)
+			}
+			metrics.add(counter.metric, stats[name], attrs, r.startTime)
+		}
+	}
+}
+
+// collectSAs lists the IPsec SAs, reports them aggregated as configured, and
+// counts the SAs installed, removed and rekeyed since the previous collection.
+func (r *cryptoOffloadReceiver) collectSAs(
+	ctx context.Context,
+	metrics *metricBuilder,
+	now pcommon.Timestamp,
+) {
+	output, err := r.run(ctx, r.config.IPPath, "-s", "xfrm", "state")
+	if err != nil {
+		r.logger.Error("Failed to list IPsec SAs", zap.Error(err))
+		return
+	}
+	all, err := parseXfrmState(output)
+	if err != nil {
+		r.logger.Error("Failed to parse IPsec SAs", zap.Error(err))
+		return
+	}
+
+	// SAs offloaded to other devices are left out, while SAs that aren't
+	// offloaded are reported, as they may have failed to be.
+	sas := make(map[string]*securityAssociation, len(all))
+	flows := make(map[string]bool, len(all))
+	for _, sa := range all {
+		if sa.dev != "" && !r.reInterface.MatchString(sa.dev) {
+			continue
+		}
+		sas[sa.identity()] = sa
+		flows[sa.flow()] = true
+	}
+
+	for _, aggregate := range r.aggregates {
+		aggregate.count = 0
+	}
+	for identity, sa := range sas {
+		previous, existed := r.previousSAs[identity]
+		increment := sa.counters
+		if existed {
+			increment = counterIncrement(previous.counters, sa.counters)
+		}
+		aggregate := r.aggregate(sa, now)
+		aggregate.count++
+		aggregate.counters.bytes += increment.bytes
+		aggregate.counters.packets += increment.packets
+		aggregate.counters.replay += increment.replay
+		aggregate.counters.failed += increment.failed
+
+		// SAs present when the receiver starts are not counted as
+		// installed
+		if !existed && r.previousSAs != nil {
+			lifecycle := r.lifecycle(sa)
+			lifecycle.installed++
+			if r.previousFlows[sa.flow()] {
+				lifecycle.rekeys++
+			}
+		}
+	}
+	for identity, sa := range r.previousSAs {
+		if _, exists := sas[identity]; !exists {
+			r.lifecycle(sa).removed++
+		}
+	}
+	r.previousSAs = sas
+	r.previousFlows = flows
+
+	keys := make([]string, 0, len(r.aggregates))
+	for key, aggregate := range r.aggregates {
+		if aggregate.count == 0 {
+			delete(r.aggregates, key)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		aggregate := r.aggregates[key]
+		metrics.add("crypto_offload.sa.count", int64(aggregate.count),
+			aggregate.attrs, aggregate.start)
+		metrics.add("crypto_offload.sa.bytes", aggregate.counters.bytes,
+			aggregate.attrs, aggregate.start)
+		metrics.add("crypto_offload.sa.packets", aggregate.counters.packets,
+			aggregate.attrs, aggregate.start)
+		metrics.add("crypto_offload.sa.replay_drops", aggregate.counters.replay,
+			aggregate.attrs, aggregate.start)
+		metrics.add("crypto_offload.sa.integrity_failures",
+			aggregate.counters.failed, aggregate.attrs, aggregate.start)
+	}
+
+	keys = keys[:0]
+	for key := range r.lifecycles {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		lifecycle := r.lifecycles[key]
+		metrics.add("crypto_offload.sa.installed", lifecycle.installed,
+			lifecycle.attrs, r.startTime)
+		metrics.add("crypto_offload.sa.removed", lifecycle.removed,
+			lifecycle.attrs, r.startTime)
+		metrics.add("crypto_offload.sa.rekeys", lifecycle.rekeys,
+			lifecycle.attrs, r.startTime)
+	}
+}
+
+// counterIncrement returns the increments of the counters of an SA since the
+// previous collection. Counters that went down were reset, so their current
+// value is the increment.
+func counterIncrement(previous, current saCounters) saCounters {
+	increment := func(previous, current int64) int64 {
+		if current < previous {
+			return current
+		}
+		return current - previous
+	}
+	return saCounters{
+		bytes:   increment(previous.bytes, current.bytes),
+		packets: increment(previous.packets, current.packets),
+		replay:  increment(previous.replay, current.replay),
+		failed:  increment(previous.failed, current.failed),
+	}
+}
+
+// aggregate returns the statistics the SA is aggregated under, creating them
+// if needed.
+func (r *cryptoOffloadReceiver) aggregate(
+	sa *securityAssociation,
+	now pcommon.Timestamp,
+) *saAggregate {
+	attrs := offloadAttributes(sa)
+	switch r.config.SAAggregation {
+	case aggregationSA:
+		attrs.PutStr("sa.src", sa.src)
+		attrs.PutStr("sa.dst", sa.dst)
+		attrs.PutStr("sa.proto", sa.proto)
+		attrs.PutStr("sa.spi", sa.spi)
+	case aggregationPeer:
+		attrs.PutStr("sa.src", sa.src)
+		attrs.PutStr("sa.dst", sa.dst)
+	}
+	key := attributesKey(attrs)
+	aggregate, exists := r.aggregates[key]
+	if !exists {
+		aggregate = &saAggregate{attrs: attrs, start: now}
+		r.aggregates[key] = aggregate
+	}
+	return aggregate
+}
+
+// lifecycle returns the lifecycle events of the offload of the SA, creating
+// them if needed.
+func (r *cryptoOffloadReceiver) lifecycle(sa *securityAssociation) *saLifecycle {
+	attrs := offloadAttributes(sa)
+	key := attributesKey(attrs)
+	lifecycle, exists := r.lifecycles[key]
+	if !exists {
+		lifecycle = &saLifecycle{attrs: attrs}
+		r.lifecycles[key] = lifecycle
+	}
+	return lifecycle
+}
+
+// offloadAttributes returns the attributes of the offload of an SA. SAs that
+// aren't offloaded only have an "offload.mode" of "none".
+func offloadAttributes(sa *securityAssociation) pcommon.Map {
+	attrs := pcommon.NewMap()
+	if sa.dev == "" {
+		attrs.PutStr("offload.mode", "none")
+		return attrs
+	}
+	attrs.PutStr("interface", sa.dev)
+	attrs.PutStr("direction", sa.dir)
+	attrs.PutStr("offload.mode", sa.mode)
+	return attrs
+}
+
+func attributesKey(attrs pcommon.Map) string {
+	var parts []string
+	attrs.Range(func(k string, v pcommon.Value) bool {
+		parts = append(parts, k+"="+v.AsString())
+		return true
+	})
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+// run runs a command and returns its output.
+func (r *cryptoOffloadReceiver) run(ctx context.Context, path string, args ...string) ([]byte, error) {
+	output, err := exec.CommandContext(ctx, path, args...).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("%w: %s", err, exitErr.Stderr)
+		}
+		return nil, err
+	}
+	return output, nil
+}
+
+// metricBuilder appends the metrics of a collection, creating each metric
+// when its first datapoint is added.
+type metricBuilder struct {
+	metrics    pmetric.MetricSlice
+	datapoints map[string]pmetric.NumberDataPointSlice
+	now        pcommon.Timestamp
+}
+
+func newMetricBuilder(metrics pmetric.MetricSlice, now pcommon.Timestamp) *metricBuilder {
+	return &metricBuilder{
+		metrics:    metrics,
+		datapoints: make(map[string]pmetric.NumberDataPointSlice),
+		now:        now,
+	}
+}
+
+func (b *metricBuilder) add(
+	name string,
+	value int64,
+	attrs pcommon.Map,
+	start pcommon.Timestamp,
+) {
+	info := metricInfos[name]
+	dps, exists := b.datapoints[name]
+	if !exists {
+		metric := b.metrics.AppendEmpty()
+		metric.SetName(name)
+		metric.SetUnit(info.unit)
+		metric.SetDescription(info.description)
+		if info.gauge {
+			dps = metric.SetEmptyGauge().DataPoints()
+		} else {
+			sum := metric.SetEmptySum()
+			sum.SetIsMonotonic(true)
+			sum.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+			dps = sum.DataPoints()
+		}
+		b.datapoints[name] = dps
+	}
+	dp := dps.AppendEmpty()
+	if !info.gauge {
+		dp.SetStartTimestamp(start)
+	}
+	dp.SetTimestamp(b.now)
+	dp.SetIntValue(value)
+	attrs.CopyTo(dp.Attributes())
+}
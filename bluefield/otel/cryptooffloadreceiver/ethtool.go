@@ -0,0 +1,120 @@
+package cryptooffloadreceiver
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// offloadCounter is a driver counter mapped to a metric.
+type offloadCounter struct {
+	metric    string
+	direction string
+	reason    string // set for drop counters of a specific reason
+}
+
+// counterRule maps the driver counters matching the regular expression, whose
+// first group is the direction, to a metric. If the metric is empty, the
+// second group selects it from names, and otherwise is the drop reason.
+type counterRule struct {
+	re     *regexp.Regexp
+	tls    bool
+	metric string
+	names  map[string]string
+}
+
+// The mlx5 driver counters, as documented in the kernel's mlx5 counters.rst.
+// Rules are tried in order, so that the totals of dropped packets and bytes
+// aren't taken as drop reasons.
+var counterRules = []counterRule{
+	{
+		re: regexp.MustCompile(`^ipsec_(rx|tx)_(pkts|bytes)$`),
+		names: map[string]string{
+			"pkts":  "crypto_offload.ipsec.packets",
+			"bytes": "crypto_offload.ipsec.bytes",
+		},
+	},
+	{
+		re: regexp.MustCompile(`^ipsec_(rx|tx)_drop_(pkts|bytes)$`),
+		names: map[string]string{
+			"pkts":  "crypto_offload.ipsec.dropped_packets",
+			"bytes": "crypto_offload.ipsec.dropped_bytes",
+		},
+	},
+	{
+		re:     regexp.MustCompile(`^ipsec_(rx|tx)_drop_([a-z0-9_]+)$`),
+		metric: "crypto_offload.ipsec.drops",
+	},
+	{
+		re:  regexp.MustCompile(`^(tx|rx)_tls_(?:encrypted|decrypted)_(packets|bytes)$`),
+		tls: true,
+		names: map[string]string{
+			"packets": "crypto_offload.tls.packets",
+			"bytes":   "crypto_offload.tls.bytes",
+		},
+	},
+	{
+		re:  regexp.MustCompile(`^(tx|rx)_tls_(ctx|del)$`),
+		tls: true,
+		names: map[string]string{
+			"ctx": "crypto_offload.tls.contexts_installed",
+			"del": "crypto_offload.tls.contexts_deleted",
+		},
+	},
+	{
+		re:     regexp.MustCompile(`^(tx|rx)_tls_(drop_[a-z0-9_]+|err)$`),
+		tls:    true,
+		metric: "crypto_offload.tls.drops",
+	},
+}
+
+// classifyCounter returns the metric of a driver counter, if it is an offload
+// counter.
+func classifyCounter(name string, includeTLS bool) (offloadCounter, bool) {
+	for _, rule := range counterRules {
+		if rule.tls && !includeTLS {
+			continue
+		}
+		match := rule.re.FindStringSubmatch(name)
+		if match == nil {
+			continue
+		}
+		if rule.metric != "" {
+			reason := strings.TrimPrefix(match[2], "drop_")
+			return offloadCounter{
+				metric:    rule.metric,
+				direction: match[1],
+				reason:    reason,
+			}, true
+		}
+		return offloadCounter{
+			metric:    rule.names[match[2]],
+			direction: match[1],
+		}, true
+	}
+	return offloadCounter{}, false
+}
+
+// parseEthtoolStats parses the output of "ethtool -S", e.g.
+//
+//	NIC statistics:
+//	     rx_packets: 1520
+//	     ipsec_rx_pkts: 310
+func parseEthtoolStats(output []byte) map[string]int64 {
+	stats := make(map[string]int64)
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		name, value, found := strings.Cut(scanner.Text(), ":")
+		if !found {
+			continue
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			continue
+		}
+		stats[strings.TrimSpace(name)] = n
+	}
+	return stats
+}
@@ -0,0 +1,37 @@
+package cryptooffloadreceiver
+
+import (
+	"context"
+	"regexp"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/receiver"
+)
+
+const (
+	typeStr   = "crypto_offload"
+	stability = component.StabilityLevelAlpha
+)
+
+func NewFactory() receiver.Factory {
+	return receiver.NewFactory(
+		component.MustNewType(typeStr),
+		createDefaultConfig,
+		receiver.WithMetrics(createMetricsReceiver, stability),
+	)
+}
+
+func createMetricsReceiver(
+	_ context.Context,
+	set receiver.CreateSettings,
+	cfg component.Config,
+	nextConsumer consumer.Metrics,
+) (receiver.Metrics, error) {
+	config := cfg.(*Config)
+	reInterface, err := regexp.Compile(config.InterfaceRegex)
+	if err != nil {
+		return nil, err
+	}
+	return newCryptoOffloadReceiver(config, reInterface, set.Logger, nextConsumer), nil
+}
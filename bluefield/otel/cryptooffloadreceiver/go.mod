@@ -0,0 +1,3 @@
+module cryptooffloadreceiver
+
+go 1.22
@@ -0,0 +1,3 @@
+package cryptooffloadreceiver
+
+const Version = "0.0.1"
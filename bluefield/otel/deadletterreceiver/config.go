@@ -0,0 +1,16 @@
+package deadletterreceiver
+
+import (
+	"go.opentelemetry.io/collector/component"
+)
+
+// Config defines the configuration of the dead_letter receiver, which has no
+// settings: exporters refer to it by its component ID.
+type Config struct{}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+func createDefaultConfig() component.Config {
+	return &Config{}
+}
@@ -0,0 +1,57 @@
+package deadletterreceiver
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.uber.org/zap"
+
+	"otelcommon/deadletter"
+)
+
+// deadLetterReceiver starts the dead-letter pipelines, passing them the data
+// captured by the exporters configuring its ID.
+type deadLetterReceiver struct {
+	config          *Config
+	id              string
+	logger          *zap.Logger
+	logsConsumer    consumer.Logs
+	metricsConsumer consumer.Metrics
+	tracesConsumer  consumer.Traces
+	startOnce       sync.Once
+	stopOnce        sync.Once
+}
+
+func newDeadLetterReceiver(config *Config, id string, logger *zap.Logger) *deadLetterReceiver {
+	return &deadLetterReceiver{
+		config: config,
+		id:     id,
+		logger: logger,
+	}
+}
+
+func (r *deadLetterReceiver) Start(_ context.Context, _ component.Host) error {
+	r.startOnce.Do(func() {
+		if r.logsConsumer != nil {
+			deadletter.RegisterLogs(r.id, r.logsConsumer)
+		}
+		if r.metricsConsumer != nil {
+			deadletter.RegisterMetrics(r.id, r.metricsConsumer)
+		}
+		if r.tracesConsumer != nil {
+			deadletter.RegisterTraces(r.id, r.tracesConsumer)
+		}
+		r.logger.Debug("Receiving undeliverable data", zap.String("id", r.id))
+	})
+	return nil
+}
+
+func (r *deadLetterReceiver) Shutdown(context.Context) error {
+	r.stopOnce.Do(func() {
+		deadletter.Unregister(r.id)
+		removeReceiver(r.config)
+	})
+	return nil
+}
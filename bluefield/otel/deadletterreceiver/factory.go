@@ -0,0 +1,86 @@
+package deadletterreceiver
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/receiver"
+
+	"otelcommon/deadletter"
+)
+
+const (
+	typeStr   = deadletter.ReceiverType
+	stability = component.StabilityLevelAlpha
+)
+
+var (
+	// a receiver configured in pipelines of several signals registers the
+	// consumers of all of them under its ID
+	receiversLock sync.Mutex
+	receivers     = make(map[*Config]*deadLetterReceiver)
+)
+
+func NewFactory() receiver.Factory {
+	return receiver.NewFactory(
+		component.MustNewType(typeStr),
+		createDefaultConfig,
+		receiver.WithLogs(createLogsReceiver, stability),
+		receiver.WithMetrics(createMetricsReceiver, stability),
+		receiver.WithTraces(createTracesReceiver, stability),
+	)
+}
+
+func createLogsReceiver(
+	_ context.Context,
+	set receiver.CreateSettings,
+	cfg component.Config,
+	nextConsumer consumer.Logs,
+) (receiver.Logs, error) {
+	r := getReceiver(cfg.(*Config), set)
+	r.logsConsumer = nextConsumer
+	return r, nil
+}
+
+func createMetricsReceiver(
+	_ context.Context,
+	set receiver.CreateSettings,
+	cfg component.Config,
+	nextConsumer consumer.Metrics,
+) (receiver.Metrics, error) {
+	r := getReceiver(cfg.(*Config), set)
+	r.metricsConsumer = nextConsumer
+	return r, nil
+}
+
+func createTracesReceiver(
+	_ context.Context,
+	set receiver.CreateSettings,
+	cfg component.Config,
+	nextConsumer consumer.Traces,
+) (receiver.Traces, error) {
+	r := getReceiver(cfg.(*Config), set)
+	r.tracesConsumer = nextConsumer
+	return r, nil
+}
+
+func getReceiver(config *Config, set receiver.CreateSettings) *deadLetterReceiver {
+	receiversLock.Lock()
+	defer receiversLock.Unlock()
+
+	r, exists := receivers[config]
+	if !exists {
+		r = newDeadLetterReceiver(config, set.ID.String(), set.Logger)
+		receivers[config] = r
+	}
+	return r
+}
+
+func removeReceiver(config *Config) {
+	receiversLock.Lock()
+	defer receiversLock.Unlock()
+
+	delete(receivers, config)
+}
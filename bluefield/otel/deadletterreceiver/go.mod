@@ -0,0 +1,3 @@
+module deadletterreceiver
+
+go 1.22
@@ -0,0 +1,3 @@
+package deadletterreceiver
+
+const Version = "0.0.1"
@@ -0,0 +1,64 @@
+package devlinkhealthreceiver
+
+import (
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+// Config defines the configuration of the devlink_health receiver.
+type Config struct {
+	// CollectionInterval configures how often devlink health reporters are
+	// polled. Defaults to "30s".
+	CollectionInterval time.Duration `mapstructure:"collection_interval"`
+
+	// DevlinkPath is the path of the devlink binary used to query health
+	// reporters. Defaults to "devlink".
+	DevlinkPath string `mapstructure:"devlink_path"`
+
+	// Devices limits which devlink devices such as "pci/0000:03:00.0" are
+	// reported. If empty, all devices are reported.
+	Devices []string `mapstructure:"devices"`
+
+	// Reporters limits which health reporters are reported. If empty, all
+	// reporters are reported. Defaults to the mlx5 fw, fw_fatal, rx and tx
+	// reporters.
+	Reporters []string `mapstructure:"reporters"`
+
+	// IncludeDumps configures whether the dump of a reporter is attached
+	// to the event log emitted when a new dump is taken.
+	IncludeDumps bool `mapstructure:"include_dumps"`
+
+	// MaxDumpSize limits the size in bytes of an attached dump, which is
+	// truncated beyond it. Defaults to 65536.
+	MaxDumpSize int `mapstructure:"max_dump_size"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.CollectionInterval <= 0 {
+		return errors.New("collection_interval must be positive")
+	}
+	if cfg.DevlinkPath == "" {
+		return errors.New("devlink_path cannot be empty")
+	}
+	if cfg.IncludeDumps && cfg.MaxDumpSize <= 0 {
+		return errors.New("max_dump_size must be positive when " +
+			"include_dumps is enabled")
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		CollectionInterval: 30 * time.Second,
+		DevlinkPath:        "devlink",
+		Reporters:          []string{"fw", "fw_fatal", "rx", "tx"},
+		MaxDumpSize:        64 * 1024,
+	}
+}
@@ -0,0 +1,315 @@
+package devlinkhealthreceiver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+const scopeName = "devlinkhealthreceiver"
+
+type devlinkHealthReceiver struct {
+	config          *Config
+	logger          *zap.Logger
+	metricsConsumer consumer.Metrics
+	logsConsumer    consumer.Logs
+	startOnce       sync.Once
+	stopOnce        sync.Once
+	startTime       pcommon.Timestamp
+	stopChannel     chan struct{}
+	stopWaiters     sync.WaitGroup
+
+	// reporter state of the previous poll by device and reporter name,
+	// only accessed by the poll loop
+	lastReporters map[reporterKey]healthReporter
+}
+
+type reporterKey struct {
+	device   string
+	reporter string
+}
+
+// healthReporter is a reporter as output by "devlink -j health show".
+type healthReporter struct {
+	Name         string `json:"reporter"`
+	State        string `json:"state"`
+	Errors       int64  `json:"error"`
+	Recoveries   int64  `json:"recover"`
+	LastDumpDate string `json:"last_dump_date"`
+	LastDumpTime string `json:"last_dump_time"`
+}
+
+type healthOutput struct {
+	Health map[string][]healthReporter `json:"health"`
+}
+
+func newDevlinkHealthReceiver(config *Config, logger *zap.Logger) *devlinkHealthReceiver {
+	return &devlinkHealthReceiver{
+		config:      config,
+		logger:      logger,
+		stopChannel: make(chan struct{}),
+	}
+}
+
+func (r *devlinkHealthReceiver) Start(_ context.Context, _ component.Host) error {
+	r.startOnce.Do(func() {
+		r.startTime = pcommon.NewTimestampFromTime(time.Now())
+		r.stopWaiters.Add(1)
+		go r.pollLoop()
+	})
+	return nil
+}
+
+func (r *devlinkHealthReceiver) Shutdown(context.Context) error {
+	r.stopOnce.Do(func() {
+		close(r.stopChannel)
+		r.stopWaiters.Wait()
+		removeReceiver(r.config)
+	})
+	return nil
+}
+
+func (r *devlinkHealthReceiver) pollLoop() {
+	defer r.stopWaiters.Done()
+
+	ticker := time.NewTicker(r.config.CollectionInterval)
+	defer ticker.Stop()
+
+	r.poll()
+	for {
+		select {
+		case <-ticker.C:
+			r.poll()
+		case <-r.stopChannel:
+			return
+		}
+	}
+}
+
+func (r *devlinkHealthReceiver) poll() {
+	ctx, cancel := context.WithTimeout(context.Background(),
+		r.config.CollectionInterval)
+	defer cancel()
+
+	output, err := r.devlink(ctx, "health", "show")
+	if err != nil {
+		r.logger.Error("Failed to query devlink health reporters",
+			zap.Error(err))
+		return
+	}
+	var health healthOutput
+	if err := json.Unmarshal(output, &health); err != nil {
+		r.logger.Error("Failed to parse devlink health reporters",
+			zap.Error(err))
+		return
+	}
+
+	now := pcommon.NewTimestampFromTime(time.Now())
+	reporters := make(map[reporterKey]healthReporter)
+	for device, deviceReporters := range health.Health {
+		if len(r.config.Devices) > 0 &&
+			!slices.Contains(r.config.Devices, device) {
+			continue
+		}
+		for _, reporter := range deviceReporters {
+			if len(r.config.Reporters) > 0 &&
+				!slices.Contains(r.config.Reporters, reporter.Name) {
+				continue
+			}
+			reporters[reporterKey{device, reporter.Name}] = reporter
+		}
+	}
+
+	if r.metricsConsumer != nil {
+		md := r.buildMetrics(reporters, now)
+		if err := r.metricsConsumer.ConsumeMetrics(ctx, md); err != nil {
+			r.logger.Error("Failed to consume devlink health metrics",
+				zap.Error(err))
+		}
+	}
+
+	if r.logsConsumer != nil {
+		ld := r.buildEventLogs(ctx, reporters, now)
+		if ld.LogRecordCount() > 0 {
+			if err := r.logsConsumer.ConsumeLogs(ctx, ld); err != nil {
+				r.logger.Error("Failed to consume devlink health logs",
+					zap.Error(err))
+			}
+		}
+	}
+
+	r.lastReporters = reporters
+}
+
+func (r *devlinkHealthReceiver) buildMetrics(
+	reporters map[reporterKey]healthReporter,
+	now pcommon.Timestamp,
+) pmetric.Metrics {
+	md := pmetric.NewMetrics()
+	sm := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty()
+	sm.Scope().SetName(scopeName)
+	sm.Scope().SetVersion(Version)
+
+	healthy := sm.Metrics().AppendEmpty()
+	healthy.SetName("devlink.health.reporter.healthy")
+	healthy.SetDescription("Whether the devlink health reporter is healthy (1) or in error (0)")
+	healthy.SetUnit("1")
+	healthyPoints := healthy.SetEmptyGauge().DataPoints()
+
+	errorCount := sm.Metrics().AppendEmpty()
+	errorCount.SetName("devlink.health.reporter.errors")
+	errorCount.SetDescription("Number of errors reported by the devlink health reporter")
+	errorCount.SetUnit("{errors}")
+	errorsSum := errorCount.SetEmptySum()
+	errorsSum.SetIsMonotonic(true)
+	errorsSum.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+
+	recoveries := sm.Metrics().AppendEmpty()
+	recoveries.SetName("devlink.health.reporter.recoveries")
+	recoveries.SetDescription("Number of recoveries by the devlink health reporter")
+	recoveries.SetUnit("{recoveries}")
+	recoveriesSum := recoveries.SetEmptySum()
+	recoveriesSum.SetIsMonotonic(true)
+	recoveriesSum.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+
+	for key, reporter := range reporters {
+		dp := healthyPoints.AppendEmpty()
+		dp.SetTimestamp(now)
+		if reporter.State == "healthy" {
+			dp.SetIntValue(1)
+		} else {
+			dp.SetIntValue(0)
+		}
+		putReporterAttributes(dp.Attributes(), key)
+
+		dp = errorsSum.DataPoints().AppendEmpty()
+		dp.SetStartTimestamp(r.startTime)
+		dp.SetTimestamp(now)
+		dp.SetIntValue(reporter.Errors)
+		putReporterAttributes(dp.Attributes(), key)
+
+		dp = recoveriesSum.DataPoints().AppendEmpty()
+		dp.SetStartTimestamp(r.startTime)
+		dp.SetTimestamp(now)
+		dp.SetIntValue(reporter.Recoveries)
+		putReporterAttributes(dp.Attributes(), key)
+	}
+
+	return md
+}
+
+// buildEventLogs emits a log record for each reporter that entered the error
+// state, reported new errors or took a new dump since the previous poll.
+func (r *devlinkHealthReceiver) buildEventLogs(
+	ctx context.Context,
+	reporters map[reporterKey]healthReporter,
+	now pcommon.Timestamp,
+) plog.Logs {
+	ld := plog.NewLogs()
+	sl := ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty()
+	sl.Scope().SetName(scopeName)
+	sl.Scope().SetVersion(Version)
+
+	for key, reporter := range reporters {
+		last, seen := r.lastReporters[key]
+		if !seen {
+			// reporters seen for the first time are only logged if
+			// they are in the error state
+			if reporter.State == "healthy" {
+				continue
+			}
+			last = reporter
+		}
+
+		newErrors := reporter.Errors - last.Errors
+		newDump := reporter.hasDump() && (!seen ||
+			reporter.LastDumpDate != last.LastDumpDate ||
+			reporter.LastDumpTime != last.LastDumpTime)
+		enteredError := reporter.State != "healthy" &&
+			(!seen || last.State == "healthy")
+		if newErrors <= 0 && !newDump && !enteredError {
+			continue
+		}
+
+		lr := sl.LogRecords().AppendEmpty()
+		lr.SetObservedTimestamp(now)
+		lr.SetTimestamp(now)
+		if reporter.Name == "fw_fatal" || reporter.State != "healthy" {
+			lr.SetSeverityNumber(plog.SeverityNumberError)
+			lr.SetSeverityText("ERROR")
+		} else {
+			lr.SetSeverityNumber(plog.SeverityNumberWarn)
+			lr.SetSeverityText("WARN")
+		}
+		lr.Body().SetStr(fmt.Sprintf(
+			"devlink health reporter %s on %s is %s with %d new errors",
+			reporter.Name, key.device, reporter.State, max(newErrors, 0)))
+
+		attrs := lr.Attributes()
+		putReporterAttributes(attrs, key)
+		attrs.PutStr("devlink.health.state", reporter.State)
+		attrs.PutInt("devlink.health.errors", reporter.Errors)
+		attrs.PutInt("devlink.health.recoveries", reporter.Recoveries)
+		if reporter.hasDump() {
+			attrs.PutStr("devlink.health.last_dump",
+				reporter.LastDumpDate+" "+reporter.LastDumpTime)
+		}
+		if newDump && r.config.IncludeDumps {
+			if dump, err := r.readDump(ctx, key); err != nil {
+				r.logger.Error("Failed to read devlink health dump",
+					zap.String("device", key.device),
+					zap.String("reporter", key.reporter),
+					zap.Error(err))
+			} else {
+				attrs.PutStr("devlink.health.dump", dump)
+			}
+		}
+	}
+
+	return ld
+}
+
+func (r *devlinkHealthReceiver) readDump(ctx context.Context, key reporterKey) (string, error) {
+	output, err := r.devlink(ctx, "health", "dump", "show", key.device,
+		"reporter", key.reporter)
+	if err != nil {
+		return "", err
+	}
+	if len(output) > r.config.MaxDumpSize {
+		output = output[:r.config.MaxDumpSize]
+	}
+	return string(output), nil
+}
+
+func (r *devlinkHealthReceiver) devlink(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, r.config.DevlinkPath,
+		append([]string{"-j"}, args...)...)
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("%w: %s", err, exitErr.Stderr)
+		}
+		return nil, err
+	}
+	return output, nil
+}
+
+func (reporter healthReporter) hasDump() bool {
+	return reporter.LastDumpDate != "" || reporter.LastDumpTime != ""
+}
+
+func putReporterAttributes(attrs pcommon.Map, key reporterKey) {
+	attrs.PutStr("devlink.device", key.device)
+	attrs.PutStr("devlink.health.reporter", key.reporter)
+}
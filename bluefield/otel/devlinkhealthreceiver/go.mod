@@ -0,0 +1,3 @@
+module devlinkhealthreceiver
+
+go 1.22
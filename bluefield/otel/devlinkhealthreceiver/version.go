@@ -0,0 +1,3 @@
+package devlinkhealthreceiver
+
+const Version = "0.0.1"
@@ -0,0 +1,64 @@
+package devlinktrapreceiver
+
+import (
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+// Config defines the configuration of the devlink_trap receiver.
+type Config struct {
+	// CollectionInterval configures how often trap statistics are polled,
+	// and how often sampled drop events are emitted. Defaults to "30s".
+	CollectionInterval time.Duration `mapstructure:"collection_interval"`
+
+	// DevlinkPath is the path of the devlink binary used to query trap
+	// statistics. Defaults to "devlink".
+	DevlinkPath string `mapstructure:"devlink_path"`
+
+	// Devices limits which devlink devices such as "pci/0000:03:00.0" are
+	// reported. If empty, all devices are reported.
+	Devices []string `mapstructure:"devices"`
+
+	// IncludeTraps configures whether the statistics of each trap are
+	// reported as well as those of the trap groups.
+	IncludeTraps bool `mapstructure:"include_traps"`
+
+	// DropEvents configures whether packets dropped by hardware traps are
+	// reported as log records in a logs pipeline, from the hardware drop
+	// alerts of the kernel's drop monitor.
+	DropEvents bool `mapstructure:"drop_events"`
+
+	// MaxDropEvents limits the drop events emitted each collection
+	// interval to a uniform sample of the drops of the interval. Defaults
+	// to 100.
+	MaxDropEvents int `mapstructure:"max_drop_events"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.CollectionInterval <= 0 {
+		return errors.New("collection_interval must be positive")
+	}
+	if cfg.DevlinkPath == "" {
+		return errors.New("devlink_path cannot be empty")
+	}
+	if cfg.DropEvents && cfg.MaxDropEvents <= 0 {
+		return errors.New("max_drop_events must be positive when " +
+			"drop_events is enabled")
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		CollectionInterval: 30 * time.Second,
+		DevlinkPath:        "devlink",
+		MaxDropEvents:      100,
+	}
+}
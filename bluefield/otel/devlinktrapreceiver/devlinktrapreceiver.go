@@ -0,0 +1,363 @@
+package devlinktrapreceiver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"os/exec"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+const scopeName = "devlinktrapreceiver"
+
+type devlinkTrapReceiver struct {
+	config          *Config
+	logger          *zap.Logger
+	metricsConsumer consumer.Metrics
+	logsConsumer    consumer.Logs
+	startOnce       sync.Once
+	stopOnce        sync.Once
+	startTime       pcommon.Timestamp
+	stopChannel     chan struct{}
+	stopWaiters     sync.WaitGroup
+
+	// drop events sampled since the previous poll
+	dropsLock  sync.Mutex
+	drops      []dropAlert
+	dropsSeen  int64 // since the previous poll
+	suppressed int64 // since the receiver started
+}
+
+// trap is a trap as output by "devlink -j -s trap show".
+type trap struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Action string `json:"action"`
+	Group  string `json:"group"`
+	Stats  struct {
+		Rx struct {
+			Bytes   int64 `json:"bytes"`
+			Packets int64 `json:"packets"`
+		} `json:"rx"`
+	} `json:"stats"`
+}
+
+type trapOutput struct {
+	Trap map[string][]trap `json:"trap"`
+}
+
+type groupKey struct {
+	device   string
+	group    string
+	trapType string
+}
+
+type groupStats struct {
+	bytes   int64
+	packets int64
+}
+
+// dropAlert is a packet dropped by a hardware trap, as reported by the
+// kernel's drop monitor.
+type dropAlert struct {
+	trap      string
+	group     string
+	port      string
+	ifindex   int64
+	protocol  uint16
+	length    int64
+	cookie    string
+	timestamp time.Time
+}
+
+func newDevlinkTrapReceiver(config *Config, logger *zap.Logger) *devlinkTrapReceiver {
+	return &devlinkTrapReceiver{
+		config:      config,
+		logger:      logger,
+		stopChannel: make(chan struct{}),
+	}
+}
+
+func (r *devlinkTrapReceiver) Start(_ context.Context, _ component.Host) error {
+	r.startOnce.Do(func() {
+		r.startTime = pcommon.NewTimestampFromTime(time.Now())
+		if r.config.DropEvents && r.logsConsumer != nil {
+			r.stopWaiters.Add(1)
+			go r.watchLoop()
+		}
+		r.stopWaiters.Add(1)
+		go r.pollLoop()
+	})
+	return nil
+}
+
+func (r *devlinkTrapReceiver) Shutdown(context.Context) error {
+	r.stopOnce.Do(func() {
+		close(r.stopChannel)
+		r.stopWaiters.Wait()
+		removeReceiver(r.config)
+	})
+	return nil
+}
+
+func (r *devlinkTrapReceiver) pollLoop() {
+	defer r.stopWaiters.Done()
+
+	ticker := time.NewTicker(r.config.CollectionInterval)
+	defer ticker.Stop()
+
+	r.poll()
+	for {
+		select {
+		case <-ticker.C:
+			r.poll()
+		case <-r.stopChannel:
+			return
+		}
+	}
+}
+
+func (r *devlinkTrapReceiver) watchLoop() {
+	defer r.stopWaiters.Done()
+
+	if err := watchDrops(r.stopChannel, r.sampleDrop); err != nil {
+		r.logger.Error("Failed to watch hardware drops, drop events "+
+			"are not reported", zap.Error(err))
+	}
+}
+
+// sampleDrop keeps a uniform sample of up to max_drop_events drops of the
+// collection interval by reservoir sampling.
+func (r *devlinkTrapReceiver) sampleDrop(alert dropAlert) {
+	r.dropsLock.Lock()
+	defer r.dropsLock.Unlock()
+
+	r.dropsSeen++
+	if len(r.drops) < r.config.MaxDropEvents {
+		r.drops = append(r.drops, alert)
+		return
+	}
+	r.suppressed++
+	if i := rand.Int64N(r.dropsSeen); i < int64(len(r.drops)) {
+		r.drops[i] = alert
+	}
+}
+
+// takeDrops returns the drops sampled since the previous poll, and the drops
+// suppressed since the receiver started.
+func (r *devlinkTrapReceiver) takeDrops() ([]dropAlert, int64) {
+	r.dropsLock.Lock()
+	defer r.dropsLock.Unlock()
+
+	drops := r.drops
+	r.drops = nil
+	r.dropsSeen = 0
+	return drops, r.suppressed
+}
+
+func (r *devlinkTrapReceiver) poll() {
+	ctx, cancel := context.WithTimeout(context.Background(),
+		r.config.CollectionInterval)
+	defer cancel()
+
+	now := pcommon.NewTimestampFromTime(time.Now())
+	drops, suppressed := r.takeDrops()
+
+	if r.metricsConsumer != nil {
+		if md, err := r.buildMetrics(ctx, suppressed, now); err != nil {
+			r.logger.Error("Failed to query devlink traps", zap.Error(err))
+		} else if err := r.metricsConsumer.ConsumeMetrics(ctx, md); err != nil {
+			r.logger.Error("Failed to consume devlink trap metrics",
+				zap.Error(err))
+		}
+	}
+
+	if r.logsConsumer != nil && len(drops) > 0 {
+		ld := buildDropLogs(drops, now)
+		if err := r.logsConsumer.ConsumeLogs(ctx, ld); err != nil {
+			r.logger.Error("Failed to consume devlink trap logs",
+				zap.Error(err))
+		}
+	}
+}
+
+func (r *devlinkTrapReceiver) buildMetrics(
+	ctx context.Context,
+	suppressed int64,
+	now pcommon.Timestamp,
+) (pmetric.Metrics, error) {
+	output, err := r.devlink(ctx, "-s", "trap", "show")
+	if err != nil {
+		return pmetric.Metrics{}, err
+	}
+	var traps trapOutput
+	if err := json.Unmarshal(output, &traps); err != nil {
+		return pmetric.Metrics{}, fmt.Errorf("failed to parse devlink "+
+			"traps: %w", err)
+	}
+
+	md := pmetric.NewMetrics()
+	sm := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty()
+	sm.Scope().SetName(scopeName)
+	sm.Scope().SetVersion(Version)
+
+	groupPackets := r.appendSum(sm.Metrics(), "devlink.trap.group.packets",
+		"Number of packets trapped by the devlink trap group", "{packets}")
+	groupBytes := r.appendSum(sm.Metrics(), "devlink.trap.group.bytes",
+		"Number of bytes trapped by the devlink trap group", "By")
+	var trapPackets, trapBytes pmetric.NumberDataPointSlice
+	if r.config.IncludeTraps {
+		trapPackets = r.appendSum(sm.Metrics(), "devlink.trap.packets",
+			"Number of packets trapped by the devlink trap", "{packets}")
+		trapBytes = r.appendSum(sm.Metrics(), "devlink.trap.bytes",
+			"Number of bytes trapped by the devlink trap", "By")
+	}
+
+	groups := make(map[groupKey]*groupStats)
+	for device, deviceTraps := range traps.Trap {
+		if len(r.config.Devices) > 0 &&
+			!slices.Contains(r.config.Devices, device) {
+			continue
+		}
+		for _, t := range deviceTraps {
+			key := groupKey{device, t.Group, t.Type}
+			stats, exists := groups[key]
+			if !exists {
+				stats = &groupStats{}
+				groups[key] = stats
+			}
+			stats.packets += t.Stats.Rx.Packets
+			stats.bytes += t.Stats.Rx.Bytes
+
+			if !r.config.IncludeTraps {
+				continue
+			}
+			for _, point := range []struct {
+				points pmetric.NumberDataPointSlice
+				value  int64
+			}{
+				{trapPackets, t.Stats.Rx.Packets},
+				{trapBytes, t.Stats.Rx.Bytes},
+			} {
+				dp := r.appendPoint(point.points, point.value, now)
+				putGroupAttributes(dp.Attributes(), key)
+				dp.Attributes().PutStr("devlink.trap.name", t.Name)
+				dp.Attributes().PutStr("devlink.trap.action", t.Action)
+			}
+		}
+	}
+
+	for key, stats := range groups {
+		dp := r.appendPoint(groupPackets, stats.packets, now)
+		putGroupAttributes(dp.Attributes(), key)
+		dp = r.appendPoint(groupBytes, stats.bytes, now)
+		putGroupAttributes(dp.Attributes(), key)
+	}
+
+	if r.config.DropEvents {
+		suppressedPoints := r.appendSum(sm.Metrics(),
+			"devlink.trap.drop_events.suppressed",
+			"Number of hardware drops not reported as drop events because "+
+				"of max_drop_events", "{packets}")
+		r.appendPoint(suppressedPoints, suppressed, now)
+	}
+
+	return md, nil
+}
+
+func (r *devlinkTrapReceiver) appendSum(
+	metrics pmetric.MetricSlice,
+	name, description, unit string,
+) pmetric.NumberDataPointSlice {
+	metric := metrics.AppendEmpty()
+	metric.SetName(name)
+	metric.SetDescription(description)
+	metric.SetUnit(unit)
+	sum := metric.SetEmptySum()
+	sum.SetIsMonotonic(true)
+	sum.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	return sum.DataPoints()
+}
+
+func (r *devlinkTrapReceiver) appendPoint(
+	points pmetric.NumberDataPointSlice,
+	value int64,
+	now pcommon.Timestamp,
+) pmetric.NumberDataPoint {
+	dp := points.AppendEmpty()
+	dp.SetStartTimestamp(r.startTime)
+	dp.SetTimestamp(now)
+	dp.SetIntValue(value)
+	return dp
+}
+
+// buildDropLogs emits a log record for each sampled drop, timestamped when
+// the packet was dropped.
+func buildDropLogs(drops []dropAlert, now pcommon.Timestamp) plog.Logs {
+	ld := plog.NewLogs()
+	sl := ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty()
+	sl.Scope().SetName(scopeName)
+	sl.Scope().SetVersion(Version)
+
+	for _, drop := range drops {
+		lr := sl.LogRecords().AppendEmpty()
+		lr.SetObservedTimestamp(now)
+		if drop.timestamp.IsZero() {
+			lr.SetTimestamp(now)
+		} else {
+			lr.SetTimestamp(pcommon.NewTimestampFromTime(drop.timestamp))
+		}
+		lr.SetSeverityNumber(plog.SeverityNumberWarn)
+		lr.SetSeverityText("WARN")
+		lr.Body().SetStr(fmt.Sprintf(
+			"packet of %d bytes received on %s dropped by devlink trap %s",
+			drop.length, drop.port, drop.trap))
+
+		attrs := lr.Attributes()
+		attrs.PutStr("devlink.trap.name", drop.trap)
+		attrs.PutStr("devlink.trap.group", drop.group)
+		if drop.port != "" {
+			attrs.PutStr("devlink.trap.in_port", drop.port)
+		}
+		if drop.ifindex != 0 {
+			attrs.PutInt("devlink.trap.in_port.ifindex", drop.ifindex)
+		}
+		attrs.PutInt("devlink.trap.packet.length", drop.length)
+		attrs.PutStr("devlink.trap.packet.protocol",
+			fmt.Sprintf("0x%04x", drop.protocol))
+		if drop.cookie != "" {
+			attrs.PutStr("devlink.trap.flow_action_cookie", drop.cookie)
+		}
+	}
+
+	return ld
+}
+
+func (r *devlinkTrapReceiver) devlink(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, r.config.DevlinkPath,
+		append([]string{"-j"}, args...)...)
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("%w: %s", err, exitErr.Stderr)
+		}
+		return nil, err
+	}
+	return output, nil
+}
+
+func putGroupAttributes(attrs pcommon.Map, key groupKey) {
+	attrs.PutStr("devlink.device", key.device)
+	attrs.PutStr("devlink.trap.group", key.group)
+	attrs.PutStr("devlink.trap.type", key.trapType)
+}
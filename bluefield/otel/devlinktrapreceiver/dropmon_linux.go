@@ -0,0 +1,351 @@
+package devlinktrapreceiver
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// generic netlink and drop monitor definitions from linux/genetlink.h,
+// linux/net_dropmon.h and linux/socket.h, which the syscall package doesn't have
+const (
+	genlIDCtrl             = 0x10
+	genlHeaderLen          = 4
+	ctrlCmdGetFamily       = 3
+	ctrlAttrFamilyID       = 1
+	ctrlAttrFamilyName     = 2
+	ctrlAttrMcastGroups    = 7
+	ctrlAttrMcastGroupName = 1
+	ctrlAttrMcastGroupID   = 2
+
+	netDMFamily      = "NET_DM"
+	netDMEventsGroup = "events"
+	netDMVersion     = 2
+
+	netDMCmdConfig      = 2
+	netDMCmdStart       = 3
+	netDMCmdStop        = 4
+	netDMCmdPacketAlert = 5
+
+	netDMAttrAlertMode       = 1
+	netDMAttrInPort          = 4
+	netDMAttrTimestamp       = 5
+	netDMAttrProto           = 6
+	netDMAttrTruncLen        = 9
+	netDMAttrOrigLen         = 10
+	netDMAttrOrigin          = 14
+	netDMAttrHWTrapGroupName = 15
+	netDMAttrHWTrapName      = 16
+	netDMAttrHWDrops         = 21
+	netDMAttrFlowCookie      = 22
+
+	netDMAttrPortIfindex = 0
+	netDMAttrPortName    = 1
+
+	netDMAlertModePacket = 1
+	netDMOriginHW        = 1
+
+	// the payload of alerts isn't reported, so only its first bytes are
+	// requested
+	netDMTruncLen = 64
+
+	nlaTypeMask = 0x3fff
+	solNetlink  = 270
+)
+
+// dropMonitor is a generic netlink socket receiving the hardware drop alerts
+// of the kernel's drop monitor.
+type dropMonitor struct {
+	fd       int
+	file     *os.File
+	familyID uint16
+	seq      atomic.Uint32
+}
+
+// watchDrops starts hardware drop monitoring in packet mode and calls handle
+// with each packet dropped by a hardware trap, until stop is closed.
+// Monitoring is stopped again before returning. Only one drop monitoring
+// session can run at a time, so it fails if e.g. dropwatch is running.
+func watchDrops(stop <-chan struct{}, handle func(dropAlert)) error {
+	fd, err := syscall.Socket(syscall.AF_NETLINK,
+		syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_GENERIC)
+	if err != nil {
+		return fmt.Errorf("failed to open netlink socket: %w", err)
+	}
+	m := &dropMonitor{fd: fd, file: os.NewFile(uintptr(fd), "netlink")}
+	defer m.file.Close()
+
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{
+		Family: syscall.AF_NETLINK,
+	}); err != nil {
+		return fmt.Errorf("failed to bind netlink socket: %w", err)
+	}
+	// receive with a timeout, so that stop is noticed
+	timeout := syscall.Timeval{Sec: 1}
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET,
+		syscall.SO_RCVTIMEO, &timeout); err != nil {
+		return fmt.Errorf("failed to set netlink receive timeout: %w", err)
+	}
+
+	groupID, err := m.resolveFamily()
+	if err != nil {
+		return err
+	}
+	if err := syscall.SetsockoptInt(fd, solNetlink,
+		syscall.NETLINK_ADD_MEMBERSHIP, int(groupID)); err != nil {
+		return fmt.Errorf("failed to join drop monitor events: %w", err)
+	}
+
+	config := appendAttr(nil, netDMAttrAlertMode, []byte{netDMAlertModePacket})
+	config = appendAttr(config, netDMAttrTruncLen,
+		binary.NativeEndian.AppendUint32(nil, netDMTruncLen))
+	if err := m.request(netDMCmdConfig, config); err != nil {
+		return fmt.Errorf("failed to configure drop monitor: %w", err)
+	}
+	hwDrops := appendAttr(nil, netDMAttrHWDrops, nil)
+	if err := m.request(netDMCmdStart, hwDrops); err != nil {
+		return fmt.Errorf("failed to start drop monitor: %w", err)
+	}
+	defer m.request(netDMCmdStop, hwDrops)
+
+	buf := make([]byte, os.Getpagesize()*4)
+	for {
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+
+		n, _, err := syscall.Recvfrom(fd, buf, 0)
+		if err != nil {
+			if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR) {
+				continue
+			}
+			if errors.Is(err, syscall.ENOBUFS) {
+				// alerts were dropped because the socket buffer
+				// overflowed, which the sample can do without
+				continue
+			}
+			return err
+		}
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return fmt.Errorf("invalid netlink message: %w", err)
+		}
+		for _, msg := range msgs {
+			if msg.Header.Type != m.familyID || len(msg.Data) < genlHeaderLen ||
+				msg.Data[0] != netDMCmdPacketAlert {
+				continue
+			}
+			if alert, ok := parseDropAlert(msg.Data[genlHeaderLen:]); ok {
+				handle(alert)
+			}
+		}
+	}
+}
+
+// resolveFamily looks up the drop monitor family, and returns the ID of its
+// events multicast group.
+func (m *dropMonitor) resolveFamily() (uint32, error) {
+	name := append([]byte(netDMFamily), 0)
+	msgs, err := m.exchange(genlIDCtrl, ctrlCmdGetFamily,
+		appendAttr(nil, ctrlAttrFamilyName, name))
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve the drop monitor family, "+
+			"is the drop_monitor module loaded? %w", err)
+	}
+
+	var groupID uint32
+	for _, msg := range msgs {
+		if len(msg.Data) < genlHeaderLen {
+			continue
+		}
+		for _, attr := range parseAttrs(msg.Data[genlHeaderLen:]) {
+			switch attr.typ {
+			case ctrlAttrFamilyID:
+				if len(attr.value) >= 2 {
+					m.familyID = binary.NativeEndian.Uint16(attr.value)
+				}
+			case ctrlAttrMcastGroups:
+				for _, group := range parseAttrs(attr.value) {
+					var groupName string
+					var id uint32
+					for _, groupAttr := range parseAttrs(group.value) {
+						switch groupAttr.typ {
+						case ctrlAttrMcastGroupName:
+							groupName = nullTerminated(groupAttr.value)
+						case ctrlAttrMcastGroupID:
+							if len(groupAttr.value) >= 4 {
+								id = binary.NativeEndian.Uint32(groupAttr.value)
+							}
+						}
+					}
+					if groupName == netDMEventsGroup {
+						groupID = id
+					}
+				}
+			}
+		}
+	}
+	if m.familyID == 0 || groupID == 0 {
+		return 0, errors.New("the drop monitor family has no events group")
+	}
+	return groupID, nil
+}
+
+// request sends a drop monitor command and waits for its acknowledgement.
+func (m *dropMonitor) request(cmd uint8, attrs []byte) error {
+	_, err := m.exchange(m.familyID, cmd, attrs)
+	return err
+}
+
+// exchange sends a generic netlink request and returns the messages of the
+// response, up to its acknowledgement. Drop alerts received meanwhile are
+// skipped.
+func (m *dropMonitor) exchange(family uint16, cmd uint8, attrs []byte) ([]syscall.NetlinkMessage, error) {
+	seq := m.seq.Add(1)
+	length := syscall.NLMSG_HDRLEN + genlHeaderLen + len(attrs)
+	msg := make([]byte, 0, length)
+	msg = binary.NativeEndian.AppendUint32(msg, uint32(length))
+	msg = binary.NativeEndian.AppendUint16(msg, family)
+	msg = binary.NativeEndian.AppendUint16(msg,
+		syscall.NLM_F_REQUEST|syscall.NLM_F_ACK)
+	msg = binary.NativeEndian.AppendUint32(msg, seq)
+	msg = binary.NativeEndian.AppendUint32(msg, 0)
+	msg = append(msg, cmd, netDMVersion, 0, 0)
+	msg = append(msg, attrs...)
+	if err := syscall.Sendto(m.fd, msg, 0, &syscall.SockaddrNetlink{
+		Family: syscall.AF_NETLINK,
+	}); err != nil {
+		return nil, err
+	}
+
+	var response []syscall.NetlinkMessage
+	buf := make([]byte, os.Getpagesize()*4)
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		n, _, err := syscall.Recvfrom(m.fd, buf, 0)
+		if err != nil {
+			if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR) {
+				continue
+			}
+			return nil, err
+		}
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return nil, fmt.Errorf("invalid netlink message: %w", err)
+		}
+		for _, msg := range msgs {
+			if msg.Header.Seq != seq {
+				continue
+			}
+			if msg.Header.Type == syscall.NLMSG_ERROR {
+				if len(msg.Data) < 4 {
+					return nil, errors.New("truncated netlink error")
+				}
+				if errno := int32(binary.NativeEndian.Uint32(msg.Data)); errno != 0 {
+					return nil, syscall.Errno(-errno)
+				}
+				return response, nil
+			}
+			response = append(response, msg)
+		}
+	}
+	return nil, errors.New("timed out waiting for a netlink response")
+}
+
+// parseDropAlert parses the attributes of a packet alert, which are only
+// returned for drops by hardware traps.
+func parseDropAlert(data []byte) (dropAlert, bool) {
+	var alert dropAlert
+	hardware := false
+	for _, attr := range parseAttrs(data) {
+		switch attr.typ {
+		case netDMAttrOrigin:
+			hardware = len(attr.value) >= 2 &&
+				binary.NativeEndian.Uint16(attr.value) == netDMOriginHW
+		case netDMAttrHWTrapGroupName:
+			alert.group = nullTerminated(attr.value)
+		case netDMAttrHWTrapName:
+			alert.trap = nullTerminated(attr.value)
+		case netDMAttrTimestamp:
+			if len(attr.value) >= 8 {
+				alert.timestamp = time.Unix(0,
+					int64(binary.NativeEndian.Uint64(attr.value)))
+			}
+		case netDMAttrProto:
+			if len(attr.value) >= 2 {
+				// the ethertype, in host byte order
+				alert.protocol = binary.NativeEndian.Uint16(attr.value)
+			}
+		case netDMAttrOrigLen:
+			if len(attr.value) >= 4 {
+				alert.length = int64(binary.NativeEndian.Uint32(attr.value))
+			}
+		case netDMAttrFlowCookie:
+			alert.cookie = fmt.Sprintf("%x", attr.value)
+		case netDMAttrInPort:
+			for _, portAttr := range parseAttrs(attr.value) {
+				switch portAttr.typ {
+				case netDMAttrPortIfindex:
+					if len(portAttr.value) >= 4 {
+						alert.ifindex = int64(binary.NativeEndian.Uint32(portAttr.value))
+					}
+				case netDMAttrPortName:
+					alert.port = nullTerminated(portAttr.value)
+				}
+			}
+		}
+	}
+	return alert, hardware && alert.trap != ""
+}
+
+type netlinkAttr struct {
+	typ   uint16
+	value []byte
+}
+
+// parseAttrs parses netlink attributes, ignoring a truncated last one.
+func parseAttrs(data []byte) []netlinkAttr {
+	var attrs []netlinkAttr
+	for len(data) >= syscall.SizeofNlAttr {
+		length := int(binary.NativeEndian.Uint16(data))
+		if length < syscall.SizeofNlAttr || length > len(data) {
+			break
+		}
+		attrs = append(attrs, netlinkAttr{
+			typ:   binary.NativeEndian.Uint16(data[2:]) & nlaTypeMask,
+			value: data[syscall.SizeofNlAttr:length],
+		})
+		aligned := (length + syscall.NLA_ALIGNTO - 1) &^ (syscall.NLA_ALIGNTO - 1)
+		if aligned > len(data) {
+			break
+		}
+		data = data[aligned:]
+	}
+	return attrs
+}
+
+// appendAttr appends a netlink attribute, padded to the attribute alignment.
+func appendAttr(data []byte, typ uint16, value []byte) []byte {
+	length := syscall.SizeofNlAttr + len(value)
+	data = binary.NativeEndian.AppendUint16(data, uint16(length))
+	data = binary.NativeEndian.AppendUint16(data, typ)
+	data = append(data, value...)
+	for length%syscall.NLA_ALIGNTO != 0 {
+		data = append(data, 0)
+		length++
+	}
+	return data
+}
+
+func nullTerminated(value []byte) string {
+	if len(value) > 0 && value[len(value)-1] == 0 {
+		value = value[:len(value)-1]
+	}
+	return string(value)
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package devlinktrapreceiver
+
+import (
+	"errors"
+)
+
+func watchDrops(<-chan struct{}, func(dropAlert)) error {
+	return errors.New("watching hardware drops is only supported on linux")
+}
@@ -0,0 +1,3 @@
+module devlinktrapreceiver
+
+go 1.22
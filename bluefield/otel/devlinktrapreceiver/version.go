@@ -0,0 +1,3 @@
+package devlinktrapreceiver
+
+const Version = "0.0.1"
@@ -0,0 +1,57 @@
+package docaflowreceiver
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+
+	"otelcommon/dpdktelemetry"
+)
+
+// Config defines the configuration of the doca_flow receiver.
+type Config struct {
+	// CollectionInterval configures how often pipe counters are
+	// collected. Defaults to "30s".
+	CollectionInterval time.Duration `mapstructure:"collection_interval"`
+
+	// SocketGlob matches the DPDK telemetry sockets of the DOCA Flow
+	// applications to query. Defaults to
+	// "/var/run/dpdk/*/dpdk_telemetry.v2".
+	SocketGlob string `mapstructure:"socket_glob"`
+
+	// PipeNameRegex optionally limits the pipes reported to those whose
+	// name matches, e.g. the uplink and downlink PDR pipes of a UPF.
+	PipeNameRegex string `mapstructure:"pipe_name_regex"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.CollectionInterval <= 0 {
+		return errors.New("collection_interval must be positive")
+	}
+	if cfg.SocketGlob == "" {
+		return errors.New("socket_glob cannot be empty")
+	}
+	if _, err := filepath.Match(cfg.SocketGlob, ""); err != nil {
+		return fmt.Errorf("invalid socket_glob: %w", err)
+	}
+	if _, err := regexp.Compile(cfg.PipeNameRegex); err != nil {
+		return fmt.Errorf("invalid pipe_name_regex: %w", err)
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		CollectionInterval: 30 * time.Second,
+		SocketGlob:         dpdktelemetry.DefaultSocketGlob,
+	}
+}
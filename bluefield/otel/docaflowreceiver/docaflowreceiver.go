@@ -0,0 +1,309 @@
+package docaflowreceiver
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+
+	"otelcommon/dpdktelemetry"
+	"otelcommon/metricappend"
+)
+
+const scopeName = "docaflowreceiver"
+
+// flowCounter is a DOCA Flow counter as returned by doca_flow_query_entry and
+// doca_flow_query_pipe_miss.
+type flowCounter struct {
+	TotalPkts  int64 `json:"total_pkts"`
+	TotalBytes int64 `json:"total_bytes"`
+}
+
+// pipeInfo is the response to "/doca_flow/pipe/info,<id>". Hits are the sum of
+// the counters of the entries of the pipe, and are missing if its entries
+// have no counters. Misses are missing unless the pipe was created with a
+// miss counter.
+type pipeInfo struct {
+	Name    string       `json:"name"`
+	PortID  int64        `json:"port_id"`
+	Type    string       `json:"type"`
+	Entries *int64       `json:"nb_entries"`
+	Hits    *flowCounter `json:"hits"`
+	Miss    *flowCounter `json:"miss"`
+}
+
+// pipeState is what the receiver remembers of a pipe between collections.
+type pipeState struct {
+	start  pcommon.Timestamp
+	hits   int64
+	misses int64
+	seen   bool // in the current collection
+}
+
+// pipeMetrics holds the metrics of the pipes of a collection.
+type pipeMetrics struct {
+	hits      pmetric.NumberDataPointSlice
+	hitBytes  pmetric.NumberDataPointSlice
+	misses    pmetric.NumberDataPointSlice
+	missBytes pmetric.NumberDataPointSlice
+	entries   pmetric.NumberDataPointSlice
+	missRatio pmetric.NumberDataPointSlice
+}
+
+func newPipeMetrics(metrics pmetric.MetricSlice) pipeMetrics {
+	return pipeMetrics{
+		hits: metricappend.Sum(metrics, "doca_flow.pipe.hits", "{packets}",
+			"Packets matching an entry of the DOCA Flow pipe"),
+		hitBytes: metricappend.Sum(metrics, "doca_flow.pipe.hit_bytes", "By",
+			"Bytes matching an entry of the DOCA Flow pipe"),
+		misses: metricappend.Sum(metrics, "doca_flow.pipe.misses", "{packets}",
+			"Packets matching no entry of the DOCA Flow pipe"),
+		missBytes: metricappend.Sum(metrics, "doca_flow.pipe.miss_bytes", "By",
+			"Bytes matching no entry of the DOCA Flow pipe"),
+		entries: metricappend.Gauge(metrics, "doca_flow.pipe.entries", "{entries}",
+			"Entries of the DOCA Flow pipe"),
+		missRatio: metricappend.Gauge(metrics, "doca_flow.pipe.miss_ratio", "1",
+			"Fraction of the packets of the collection interval matching "+
+				"no entry of the DOCA Flow pipe"),
+	}
+}
+
+type docaFlowReceiver struct {
+	config       *Config
+	rePipeName   *regexp.Regexp // nil to report all pipes
+	logger       *zap.Logger
+	nextConsumer consumer.Metrics
+	stopChannel  chan struct{}
+	stopWaiters  sync.WaitGroup
+
+	// pipes by socket, process and pipe ID, removed once the pipe is gone
+	pipes map[string]*pipeState
+}
+
+func newDOCAFlowReceiver(
+	config *Config,
+	rePipeName *regexp.Regexp,
+	logger *zap.Logger,
+	nextConsumer consumer.Metrics,
+) *docaFlowReceiver {
+	return &docaFlowReceiver{
+		config:       config,
+		rePipeName:   rePipeName,
+		logger:       logger,
+		nextConsumer: nextConsumer,
+		stopChannel:  make(chan struct{}),
+		pipes:        make(map[string]*pipeState),
+	}
+}
+
+func (r *docaFlowReceiver) Start(_ context.Context, _ component.Host) error {
+	r.stopWaiters.Add(1)
+	go r.collectLoop()
+	return nil
+}
+
+func (r *docaFlowReceiver) Shutdown(context.Context) error {
+	close(r.stopChannel)
+	r.stopWaiters.Wait()
+	return nil
+}
+
+func (r *docaFlowReceiver) collectLoop() {
+	defer r.stopWaiters.Done()
+
+	ticker := time.NewTicker(r.config.CollectionInterval)
+	defer ticker.Stop()
+
+	r.collect()
+	for {
+		select {
+		case <-ticker.C:
+			r.collect()
+		case <-r.stopChannel:
+			return
+		}
+	}
+}
+
+func (r *docaFlowReceiver) collect() {
+	ctx, cancel := context.WithTimeout(context.Background(),
+		r.config.CollectionInterval)
+	defer cancel()
+
+	now := pcommon.NewTimestampFromTime(time.Now())
+	md := pmetric.NewMetrics()
+	sm := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty()
+	sm.Scope().SetName(scopeName)
+	sm.Scope().SetVersion(Version)
+
+	sockets, err := filepath.Glob(r.config.SocketGlob)
+	if err != nil {
+		r.logger.Error("Failed to list DPDK telemetry sockets", zap.Error(err))
+		return
+	}
+	sort.Strings(sockets)
+
+	pm := newPipeMetrics(sm.Metrics())
+	for _, state := range r.pipes {
+		state.seen = false
+	}
+	for _, socket := range sockets {
+		if err := r.collectApp(ctx, socket, pm, now); err != nil {
+			// sockets of applications that exited are left behind, so
+			// failures to connect are common
+			r.logger.Debug("Failed to collect DOCA Flow pipe counters",
+				zap.String("socket", socket), zap.Error(err))
+		}
+	}
+	for key, state := range r.pipes {
+		if !state.seen {
+			delete(r.pipes, key)
+		}
+	}
+
+	// e.g. the miss metrics of pipes without miss counters
+	sm.Metrics().RemoveIf(func(metric pmetric.Metric) bool {
+		if metric.Type() == pmetric.MetricTypeSum {
+			return metric.Sum().DataPoints().Len() == 0
+		}
+		return metric.Gauge().DataPoints().Len() == 0
+	})
+
+	if md.DataPointCount() == 0 {
+		return
+	}
+	if err := r.nextConsumer.ConsumeMetrics(ctx, md); err != nil {
+		r.logger.Error("Failed to consume DOCA Flow pipe counters",
+			zap.Error(err))
+	}
+}
+
+// collectApp appends the counters of each pipe of the application serving the
+// socket. Applications that don't register the DOCA Flow commands answer them
+// with null and are skipped.
+func (r *docaFlowReceiver) collectApp(
+	ctx context.Context,
+	socket string,
+	pm pipeMetrics,
+	now pcommon.Timestamp,
+) error {
+	client, err := dpdktelemetry.Dial(ctx, socket)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	var pipeIDs []int64
+	if err := client.Query("/doca_flow/pipe/list", &pipeIDs); err != nil {
+		return err
+	}
+	for _, id := range pipeIDs {
+		var info pipeInfo
+		if err := client.Query("/doca_flow/pipe/info,"+strconv.FormatInt(id, 10),
+			&info); err != nil {
+			return err
+		}
+		// the pipe was destroyed after it was listed
+		if info.Name == "" {
+			continue
+		}
+		if r.rePipeName != nil && !r.rePipeName.MatchString(info.Name) {
+			continue
+		}
+
+		attrs := pcommon.NewMap()
+		attrs.PutStr("doca_flow.app", dpdktelemetry.AppName(socket))
+		attrs.PutInt("process.pid", client.Info.PID)
+		attrs.PutInt("doca_flow.port.id", info.PortID)
+		attrs.PutInt("doca_flow.pipe.id", id)
+		attrs.PutStr("doca_flow.pipe.name", info.Name)
+		if info.Type != "" {
+			attrs.PutStr("doca_flow.pipe.type", info.Type)
+		}
+
+		key := socket + "|" + strconv.FormatInt(client.Info.PID, 10) + "|" +
+			strconv.FormatInt(id, 10)
+		r.appendPipe(key, &info, attrs, pm, now)
+	}
+	return nil
+}
+
+// appendPipe appends the counters of a pipe, and the fraction of packets
+// missing it since the previous collection if both hits and misses are
+// counted.
+func (r *docaFlowReceiver) appendPipe(
+	key string,
+	info *pipeInfo,
+	attrs pcommon.Map,
+	pm pipeMetrics,
+	now pcommon.Timestamp,
+) {
+	var hits, misses int64
+	if info.Hits != nil {
+		hits = info.Hits.TotalPkts
+	}
+	if info.Miss != nil {
+		misses = info.Miss.TotalPkts
+	}
+
+	state, existed := r.pipes[key]
+	// counters going down belong to a pipe created again with the same ID
+	if !existed || hits < state.hits || misses < state.misses {
+		state = &pipeState{start: now}
+		r.pipes[key] = state
+		existed = false
+	}
+
+	if info.Hits != nil {
+		appendIntDatapoint(pm.hits, info.Hits.TotalPkts, attrs, state.start, now)
+		appendIntDatapoint(pm.hitBytes, info.Hits.TotalBytes, attrs, state.start, now)
+	}
+	if info.Miss != nil {
+		appendIntDatapoint(pm.misses, info.Miss.TotalPkts, attrs, state.start, now)
+		appendIntDatapoint(pm.missBytes, info.Miss.TotalBytes, attrs, state.start, now)
+	}
+	if info.Entries != nil {
+		appendIntDatapoint(pm.entries, *info.Entries, attrs, 0, now)
+	}
+	if existed && info.Hits != nil && info.Miss != nil {
+		hitIncrement := hits - state.hits
+		missIncrement := misses - state.misses
+		if total := hitIncrement + missIncrement; total > 0 {
+			dp := pm.missRatio.AppendEmpty()
+			dp.SetTimestamp(now)
+			dp.SetDoubleValue(float64(missIncrement) / float64(total))
+			attrs.CopyTo(dp.Attributes())
+		}
+	}
+
+	state.hits = hits
+	state.misses = misses
+	state.seen = true
+}
+
+// appendIntDatapoint appends a datapoint, with a start timestamp unless it is
+// zero.
+func appendIntDatapoint(
+	dps pmetric.NumberDataPointSlice,
+	value int64,
+	attrs pcommon.Map,
+	start pcommon.Timestamp,
+	now pcommon.Timestamp,
+) {
+	dp := dps.AppendEmpty()
+	if start != 0 {
+		dp.SetStartTimestamp(start)
+	}
+	dp.SetTimestamp(now)
+	dp.SetIntValue(value)
+	attrs.CopyTo(dp.Attributes())
+}
@@ -0,0 +1,41 @@
+package docaflowreceiver
+
+import (
+	"context"
+	"regexp"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/receiver"
+)
+
+const (
+	typeStr   = "doca_flow"
+	stability = component.StabilityLevelAlpha
+)
+
+func NewFactory() receiver.Factory {
+	return receiver.NewFactory(
+		component.MustNewType(typeStr),
+		createDefaultConfig,
+		receiver.WithMetrics(createMetricsReceiver, stability),
+	)
+}
+
+func createMetricsReceiver(
+	_ context.Context,
+	set receiver.CreateSettings,
+	cfg component.Config,
+	nextConsumer consumer.Metrics,
+) (receiver.Metrics, error) {
+	config := cfg.(*Config)
+	var rePipeName *regexp.Regexp
+	if config.PipeNameRegex != "" {
+		var err error
+		rePipeName, err = regexp.Compile(config.PipeNameRegex)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return newDOCAFlowReceiver(config, rePipeName, set.Logger, nextConsumer), nil
+}
@@ -0,0 +1,3 @@
+module docaflowreceiver
+
+go 1.22
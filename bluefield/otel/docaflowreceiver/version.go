@@ -0,0 +1,3 @@
+package docaflowreceiver
+
+const Version = "0.0.1"
@@ -0,0 +1,101 @@
+package drainextension
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/collector/component"
+
+	"otelcommon/httpregistry"
+)
+
+// Config defines the configuration of the drain extension.
+type Config struct {
+	// Endpoint is the local address serving the drain API. It may be shared
+	// with other components such as the telemetry_stats log stats endpoint.
+	// Defaults to "localhost:8890".
+	Endpoint string `mapstructure:"endpoint"`
+
+	// TLS optionally serves the drain API over HTTPS with a certificate
+	// and key, and requires client certificates signed by a CA if one is
+	// configured. It must match the settings of any other component
+	// sharing the endpoint.
+	TLS httpregistry.TLSConfig `mapstructure:"tls"`
+
+	// Auth optionally requires requests to the drain API to authenticate,
+	// with a bearer token, basic auth credentials or a collector auth
+	// extension. Credentials must match the settings of any other
+	// component sharing the endpoint.
+	Auth AuthConfig `mapstructure:"auth"`
+
+	// Path is the URL path of the drain API. Defaults to "/drain".
+	Path string `mapstructure:"path"`
+
+	// StatusFile is written with the drain state when a drain is requested
+	// and when it completes. While it exists, otelcol-wrapper does not
+	// start the collector again. Defaults to
+	// "/run/otelcol-contrib/drained".
+	StatusFile string `mapstructure:"status_file"`
+}
+
+// AuthConfig defines how requests to the drain API authenticate.
+type AuthConfig struct {
+	httpregistry.AuthConfig `mapstructure:",squash"`
+
+	// Authenticator optional ID of a collector auth extension, e.g.
+	// "basicauth/drain", authenticating requests instead
+	Authenticator string `mapstructure:"authenticator"`
+}
+
+// Validate checks that the credentials are usable and the authenticator is
+// an extension ID, and not combined with credentials.
+func (cfg *AuthConfig) Validate() error {
+	if err := cfg.AuthConfig.Validate(); err != nil {
+		return err
+	}
+	if cfg.Authenticator == "" {
+		return nil
+	}
+	if cfg.AuthConfig.Enabled() {
+		return errors.New("authenticator cannot be combined with " +
+			"bearer_token or username")
+	}
+	var id component.ID
+	if err := id.UnmarshalText([]byte(cfg.Authenticator)); err != nil {
+		return fmt.Errorf("invalid authenticator: %w", err)
+	}
+	return nil
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.Endpoint == "" {
+		return errors.New("endpoint cannot be empty")
+	}
+	if !strings.HasPrefix(cfg.Path, "/") {
+		return errors.New("path must start with /")
+	}
+	if err := cfg.TLS.Validate(); err != nil {
+		return fmt.Errorf("invalid tls: %w", err)
+	}
+	if err := cfg.Auth.Validate(); err != nil {
+		return fmt.Errorf("invalid auth: %w", err)
+	}
+	if cfg.StatusFile == "" {
+		return errors.New("status_file cannot be empty")
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		Endpoint:   "localhost:8890",
+		Path:       "/drain",
+		StatusFile: "/run/otelcol-contrib/drained",
+	}
+}
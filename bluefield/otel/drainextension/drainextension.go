@@ -0,0 +1,217 @@
+package drainextension
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/extension/auth"
+	"go.uber.org/zap"
+
+	"otelcommon/httpregistry"
+)
+
+const (
+	stateRunning  = "running"
+	stateDraining = "draining"
+	stateDrained  = "drained"
+)
+
+type drainExtension struct {
+	config       *Config
+	logger       *zap.Logger
+	registration *httpregistry.Registration
+	// the auth extension of `authenticator`, if configured
+	authenticator auth.Server
+	stateLock     sync.Mutex
+	state         string
+	requestedAt   time.Time
+}
+
+type drainStatus struct {
+	State       string `json:"state"`
+	RequestedAt string `json:"requested_at,omitempty"`
+}
+
+func newDrainExtension(config *Config, logger *zap.Logger) *drainExtension {
+	return &drainExtension{
+		config: config,
+		logger: logger,
+		state:  stateRunning,
+	}
+}
+
+// Start looks up the auth extension before serving the drain API, so that no
+// request is served unauthenticated. Extensions are started in the order they
+// are listed in, so the auth extension must be listed before drain.
+func (d *drainExtension) Start(_ context.Context, host component.Host) error {
+	if d.config.Auth.Authenticator != "" {
+		var id component.ID
+		if err := id.UnmarshalText([]byte(d.config.Auth.Authenticator)); err != nil {
+			return fmt.Errorf("invalid authenticator: %w", err)
+		}
+		extension, found := host.GetExtensions()[id]
+		if !found {
+			return fmt.Errorf("authenticator %s is not configured as an "+
+				"extension listed before drain", id)
+		}
+		authenticator, ok := extension.(auth.Server)
+		if !ok {
+			return fmt.Errorf("extension %s is not a server authenticator", id)
+		}
+		d.authenticator = authenticator
+	}
+
+	registration, err := httpregistry.Register(
+		httpregistry.ServerConfig{
+			Endpoint: d.config.Endpoint,
+			TLS:      d.config.TLS,
+			Auth:     d.config.Auth.AuthConfig,
+		},
+		d.config.Path,
+		d,
+		d.logger,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register drain endpoint: %w", err)
+	}
+	d.registration = registration
+	return nil
+}
+
+// Shutdown runs after all pipelines have been shut down, so when a drain was
+// requested, receivers have stopped, processors have flushed and exporters
+// have sent or persisted their queues by now.
+func (d *drainExtension) Shutdown(context.Context) error {
+	if d.registration != nil {
+		d.registration.Unregister()
+		d.registration = nil
+	}
+
+	d.stateLock.Lock()
+	defer d.stateLock.Unlock()
+
+	if d.state != stateDraining {
+		return nil
+	}
+	d.state = stateDrained
+	if err := d.writeStatus(); err != nil {
+		return err
+	}
+	d.logger.Info("Collector drained",
+		zap.Duration("duration", time.Since(d.requestedAt)))
+	return nil
+}
+
+func (d *drainExtension) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if d.authenticator != nil {
+		if _, err := d.authenticator.Authenticate(r.Context(), r.Header); err != nil {
+			d.logger.Debug("Refused unauthenticated drain request",
+				zap.String("remote_addr", r.RemoteAddr), zap.Error(err))
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+	switch r.Method {
+	case http.MethodGet:
+		d.writeResponse(w, http.StatusOK)
+	case http.MethodPost:
+		if err := d.drain(); err != nil {
+			d.logger.Error("Failed to start drain", zap.Error(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		d.writeResponse(w, http.StatusAccepted)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// drain records that a drain was requested and asks the collector to shut
+// down. The collector stops its receivers first, then shuts down processors
+// and exporters in pipeline order so that no data in flight is dropped, and
+// finally the extensions, which closes persistent storage. Requesting a drain
+// while one is in progress has no further effect.
+func (d *drainExtension) drain() error {
+	d.stateLock.Lock()
+	defer d.stateLock.Unlock()
+
+	if d.state != stateRunning {
+		return nil
+	}
+
+	d.state = stateDraining
+	d.requestedAt = time.Now()
+	if err := d.writeStatus(); err != nil {
+		d.state = stateRunning
+		return err
+	}
+
+	d.logger.Info("Drain requested, shutting down collector")
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		d.state = stateRunning
+		os.Remove(d.config.StatusFile)
+		return fmt.Errorf("failed to signal collector: %w", err)
+	}
+	return nil
+}
+
+func (d *drainExtension) writeResponse(w http.ResponseWriter, statusCode int) {
+	d.stateLock.Lock()
+	status := d.status()
+	d.stateLock.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		d.logger.Error("Failed to write drain status", zap.Error(err))
+	}
+}
+
+// status must be called while holding stateLock
+func (d *drainExtension) status() drainStatus {
+	status := drainStatus{State: d.state}
+	if !d.requestedAt.IsZero() {
+		status.RequestedAt = d.requestedAt.UTC().Format(time.RFC3339)
+	}
+	return status
+}
+
+// writeStatus atomically replaces the status file with the current state, and
+// must be called while holding stateLock.
+func (d *drainExtension) writeStatus() error {
+	data, err := json.Marshal(d.status())
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(d.config.StatusFile)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create status directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".drain-status-*")
+	if err != nil {
+		return fmt.Errorf("failed to create status file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write status file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write status file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), d.config.StatusFile); err != nil {
+		return fmt.Errorf("failed to write status file: %w", err)
+	}
+	return nil
+}
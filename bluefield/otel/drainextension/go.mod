@@ -0,0 +1,3 @@
+module drainextension
+
+go 1.22
@@ -0,0 +1,3 @@
+package drainextension
+
+const Version = "0.0.1"
@@ -0,0 +1,250 @@
+//go:build ebpf
+
+package ebpfaccountingreceiver
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Each slot is a pair of entries of the counter map, those of the traffic
+// received and transmitted, of a packet and a byte counter each.
+const (
+	entriesPerSlot = 2
+	entrySize      = 16
+)
+
+// helperMapLookupElem is the number of the bpf_map_lookup_elem helper.
+const helperMapLookupElem = 1
+
+// bpfAccounting counts traffic with a cgroup_skb program per cgroup and
+// direction, incrementing the counters of its entry of an array map. The
+// programs are attached as links, which the kernel detaches when the collector
+// exits, so that programs aren't left behind by a crash.
+type bpfAccounting struct {
+	mapFD int
+}
+
+// bpfLinks are the links of the programs accounting for a cgroup.
+type bpfLinks []int
+
+// bpfInstruction is a struct bpf_insn.
+type bpfInstruction struct {
+	code uint8
+	regs uint8 // the destination register in the low, the source in the high nibble
+	off  int16
+	imm  int32
+}
+
+func instruction(code uint8, dst, src uint8, off int16, imm int32) bpfInstruction {
+	return bpfInstruction{code: code, regs: dst | src<<4, off: off, imm: imm}
+}
+
+// countingProgram returns a program counting the packets it sees and their
+// bytes in an entry of the counter map, and letting them pass.
+func countingProgram(mapFD, entry int) []bpfInstruction {
+	return []bpfInstruction{
+		// r6 = r1, the packet
+		instruction(unix.BPF_ALU64|unix.BPF_MOV|unix.BPF_X, 6, 1, 0, 0),
+		// *(u32 *)(r10 - 4) = entry, the key on the stack
+		instruction(unix.BPF_ST|unix.BPF_MEM|unix.BPF_W, 10, 0, -4, int32(entry)),
+		// r2 = r10 - 4
+		instruction(unix.BPF_ALU64|unix.BPF_MOV|unix.BPF_X, 2, 10, 0, 0),
+		instruction(unix.BPF_ALU64|unix.BPF_ADD|unix.BPF_K, 2, 0, 0, -4),
+		// r1 = the counter map, a 64-bit immediate over two instructions
+		instruction(unix.BPF_LD|unix.BPF_IMM|unix.BPF_DW, 1, unix.BPF_PSEUDO_MAP_FD, 0, int32(mapFD)),
+		instruction(0, 0, 0, 0, 0),
+		// r0 = the counters of the entry
+		instruction(unix.BPF_JMP|unix.BPF_CALL, 0, 0, 0, helperMapLookupElem),
+		// if r0 == NULL, skip to letting the packet pass
+		instruction(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, 0, 0, 4, 0),
+		// atomically add 1 to the packets and the length to the bytes
+		instruction(unix.BPF_ALU64|unix.BPF_MOV|unix.BPF_K, 1, 0, 0, 1),
+		instruction(unix.BPF_STX|unix.BPF_XADD|unix.BPF_DW, 0, 1, 0, 0),
+		instruction(unix.BPF_LDX|unix.BPF_MEM|unix.BPF_W, 1, 6, 0, 0), // skb->len
+		instruction(unix.BPF_STX|unix.BPF_XADD|unix.BPF_DW, 0, 1, 8, 0),
+		// return 1, letting the packet pass
+		instruction(unix.BPF_ALU64|unix.BPF_MOV|unix.BPF_K, 0, 0, 0, 1),
+		instruction(unix.BPF_JMP|unix.BPF_EXIT, 0, 0, 0, 0),
+	}
+}
+
+// bpf calls the bpf syscall with an attribute struct, returning the file
+// descriptor it may have created.
+func bpf[T any](cmd int, attr *T) (int, error) {
+	fd, _, errno := unix.Syscall(unix.SYS_BPF, uintptr(cmd),
+		uintptr(unsafe.Pointer(attr)), unsafe.Sizeof(*attr))
+	if errno != 0 {
+		return -1, errno
+	}
+	return int(fd), nil
+}
+
+func newNetAccounting(slots int) (netAccounting, error) {
+	attr := struct {
+		mapType, keySize, valueSize, maxEntries, mapFlags uint32
+	}{
+		mapType:    unix.BPF_MAP_TYPE_ARRAY,
+		keySize:    4,
+		valueSize:  entrySize,
+		maxEntries: uint32(slots * entriesPerSlot),
+	}
+	fd, err := bpf(unix.BPF_MAP_CREATE, &attr)
+	if err != nil {
+		if errors.Is(err, unix.EPERM) {
+			return nil, fmt.Errorf("failed to create counter map, "+
+				"CAP_BPF or CAP_SYS_ADMIN is required: %w", err)
+		}
+		return nil, fmt.Errorf("failed to create counter map: %w", err)
+	}
+	return &bpfAccounting{mapFD: fd}, nil
+}
+
+func (a *bpfAccounting) Close() error {
+	return unix.Close(a.mapFD)
+}
+
+func (a *bpfAccounting) attach(dir string, slot int) (closer io.Closer, err error) {
+	cgroupFD, err := unix.Open(dir, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cgroup: %w", err)
+	}
+	defer unix.Close(cgroupFD)
+
+	var links bpfLinks
+	defer func() {
+		if err != nil {
+			links.Close()
+		}
+	}()
+	for direction, attachType := range []uint32{
+		unix.BPF_CGROUP_INET_INGRESS,
+		unix.BPF_CGROUP_INET_EGRESS,
+	} {
+		entry := slot*entriesPerSlot + direction
+		if err := a.reset(entry); err != nil {
+			return nil, fmt.Errorf("failed to reset counters: %w", err)
+		}
+		progFD, err := loadCountingProgram(a.mapFD, entry, attachType)
+		if err != nil {
+			return nil, err
+		}
+		linkAttr := struct {
+			progFD, targetFD, attachType, flags uint32
+		}{
+			progFD:     uint32(progFD),
+			targetFD:   uint32(cgroupFD),
+			attachType: attachType,
+		}
+		linkFD, err := bpf(unix.BPF_LINK_CREATE, &linkAttr)
+		// the link holds a reference to the program
+		unix.Close(progFD)
+		if err != nil {
+			return nil, fmt.Errorf("failed to attach program to cgroup: %w", err)
+		}
+		links = append(links, linkFD)
+	}
+	return links, nil
+}
+
+// loadCountingProgram loads the program counting the traffic of a direction
+// in an entry. The verifier log is included in the error if it is rejected.
+func loadCountingProgram(mapFD, entry int, attachType uint32) (int, error) {
+	instructions := countingProgram(mapFD, entry)
+	license := []byte("Apache-2.0\x00")
+	attr := struct {
+		progType           uint32
+		insnCount          uint32
+		insns              uint64
+		license            uint64
+		logLevel           uint32
+		logSize            uint32
+		logBuf             uint64
+		kernVersion        uint32
+		progFlags          uint32
+		progName           [unix.BPF_OBJ_NAME_LEN]byte
+		progIfindex        uint32
+		expectedAttachType uint32
+	}{
+		progType:           unix.BPF_PROG_TYPE_CGROUP_SKB,
+		insnCount:          uint32(len(instructions)),
+		insns:              uint64(uintptr(unsafe.Pointer(&instructions[0]))),
+		license:            uint64(uintptr(unsafe.Pointer(&license[0]))),
+		expectedAttachType: attachType,
+	}
+	copy(attr.progName[:], "otel_accounting")
+	fd, err := bpf(unix.BPF_PROG_LOAD, &attr)
+	if err != nil && errors.Is(err, unix.EACCES) {
+		// load again for the verifier log
+		log := make([]byte, 64*1024)
+		attr.logLevel = 1
+		attr.logSize = uint32(len(log))
+		attr.logBuf = uint64(uintptr(unsafe.Pointer(&log[0])))
+		if fd, err := bpf(unix.BPF_PROG_LOAD, &attr); err == nil {
+			unix.Close(fd)
+		}
+		runtime.KeepAlive(log)
+		err = fmt.Errorf("%w: %s", err, unix.ByteSliceToString(log))
+	}
+	runtime.KeepAlive(instructions)
+	runtime.KeepAlive(license)
+	if err != nil {
+		return -1, fmt.Errorf("failed to load accounting program: %w", err)
+	}
+	return fd, nil
+}
+
+// reset zeroes the counters of an entry.
+func (a *bpfAccounting) reset(entry int) error {
+	var counters [2]uint64
+	return a.elementCall(unix.BPF_MAP_UPDATE_ELEM, uint32(entry), &counters)
+}
+
+func (a *bpfAccounting) read(slot int) (trafficCounts, error) {
+	var counts trafficCounts
+	for direction, fields := range [][2]*uint64{
+		{&counts.receivedPackets, &counts.receivedBytes},
+		{&counts.transmittedPackets, &counts.transmittedBytes},
+	} {
+		var counters [2]uint64
+		if err := a.elementCall(unix.BPF_MAP_LOOKUP_ELEM,
+			uint32(slot*entriesPerSlot+direction), &counters); err != nil {
+			return trafficCounts{}, err
+		}
+		*fields[0], *fields[1] = counters[0], counters[1]
+	}
+	return counts, nil
+}
+
+// elementCall looks up or updates the packet and byte counters of an entry of
+// the counter map.
+func (a *bpfAccounting) elementCall(cmd int, key uint32, counters *[2]uint64) error {
+	attr := struct {
+		mapFD uint32
+		_     uint32
+		key   uint64
+		value uint64
+		flags uint64
+	}{
+		mapFD: uint32(a.mapFD),
+		key:   uint64(uintptr(unsafe.Pointer(&key))),
+		value: uint64(uintptr(unsafe.Pointer(counters))),
+	}
+	_, err := bpf(cmd, &attr)
+	runtime.KeepAlive(&key)
+	runtime.KeepAlive(counters)
+	return err
+}
+
+func (l bpfLinks) Close() error {
+	var errs []error
+	for _, fd := range l {
+		errs = append(errs, unix.Close(fd))
+	}
+	return errors.Join(errs...)
+}
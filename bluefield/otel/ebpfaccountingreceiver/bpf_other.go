@@ -0,0 +1,12 @@
+//go:build !linux || !ebpf
+
+package ebpfaccountingreceiver
+
+import (
+	"errors"
+)
+
+func newNetAccounting(int) (netAccounting, error) {
+	return nil, errors.New("eBPF network accounting is only supported on " +
+		"linux, by a collector built with the ebpf build tag")
+}
@@ -0,0 +1,70 @@
+package ebpfaccountingreceiver
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+// Config defines the configuration of the eBPF accounting receiver.
+type Config struct {
+	// CollectionInterval configures how often the usage of the cgroups is
+	// reported. The cgroups matching `cgroups` are also listed on every
+	// interval, to account for services started or restarted since.
+	// Defaults to "30s".
+	CollectionInterval time.Duration `mapstructure:"collection_interval"`
+
+	// CgroupRoot is the mount point of the cgroup v2 hierarchy. Defaults
+	// to "/sys/fs/cgroup".
+	CgroupRoot string `mapstructure:"cgroup_root"`
+
+	// Cgroups are the glob patterns of the paths of the cgroups to
+	// account for, relative to `cgroup_root`, e.g.
+	// "system.slice/forge-dpu-agent.service" or "system.slice/*.service".
+	Cgroups []string `mapstructure:"cgroups"`
+
+	// MaxCgroups limits the number of cgroups accounted for at once.
+	// Cgroups matched beyond it are skipped with a warning. Defaults to
+	// 64.
+	MaxCgroups int `mapstructure:"max_cgroups"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.CollectionInterval <= 0 {
+		return errors.New("collection_interval must be positive")
+	}
+	if !filepath.IsAbs(cfg.CgroupRoot) {
+		return errors.New("cgroup_root must be an absolute path")
+	}
+	if len(cfg.Cgroups) == 0 {
+		return errors.New("cgroups must not be empty")
+	}
+	for _, pattern := range cfg.Cgroups {
+		if pattern == "" || filepath.IsAbs(pattern) {
+			return fmt.Errorf("cgroup %q must be a path relative to cgroup_root", pattern)
+		}
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid cgroup pattern %q: %w", pattern, err)
+		}
+	}
+	if cfg.MaxCgroups <= 0 {
+		return errors.New("max_cgroups must be positive")
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		CollectionInterval: 30 * time.Second,
+		CgroupRoot:         "/sys/fs/cgroup",
+		MaxCgroups:         64,
+	}
+}
@@ -0,0 +1,352 @@
+package ebpfaccountingreceiver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+const scopeName = "ebpfaccountingreceiver"
+
+type ebpfAccountingReceiver struct {
+	config       *Config
+	logger       *zap.Logger
+	nextConsumer consumer.Metrics
+	stopChannel  chan struct{}
+	stopWaiters  sync.WaitGroup
+
+	// the network accounting, nil if unavailable, and the cgroups
+	// accounted for, only accessed by the collect loop once started
+	accounting netAccounting
+	cgroups    map[string]*cgroup // by path relative to the cgroup root
+	freeSlots  []int
+	skipped    map[string]bool // cgroups skipped as max_cgroups was reached
+}
+
+// cgroup is a cgroup accounted for. A cgroup removed and created again under
+// the same path, e.g. by a restarted service, is a new cgroup.
+type cgroup struct {
+	path  string
+	info  os.FileInfo // of the directory, to tell it was created again
+	start pcommon.Timestamp
+
+	// the slot counting the traffic of the cgroup, and the eBPF programs
+	// counting it, detached when closed
+	slot     int
+	programs io.Closer
+}
+
+// netAccounting counts the packets and bytes received and transmitted by the
+// processes of cgroups, each counted in a slot of its own.
+type netAccounting interface {
+	// attach starts counting the traffic of the cgroup directory in a
+	// slot, from zero, until the returned closer is closed
+	attach(dir string, slot int) (io.Closer, error)
+
+	// read returns the traffic counted in a slot
+	read(slot int) (trafficCounts, error)
+
+	Close() error
+}
+
+type trafficCounts struct {
+	receivedPackets    uint64
+	receivedBytes      uint64
+	transmittedPackets uint64
+	transmittedBytes   uint64
+}
+
+// cpuTimes are the CPU times of a cgroup from its cpu.stat.
+type cpuTimes struct {
+	userMicros   int64
+	systemMicros int64
+}
+
+func newEBPFAccountingReceiver(
+	config *Config,
+	logger *zap.Logger,
+	nextConsumer consumer.Metrics,
+) *ebpfAccountingReceiver {
+	r := &ebpfAccountingReceiver{
+		config:       config,
+		logger:       logger,
+		nextConsumer: nextConsumer,
+		stopChannel:  make(chan struct{}),
+		cgroups:      make(map[string]*cgroup),
+		skipped:      make(map[string]bool),
+	}
+	for slot := config.MaxCgroups - 1; slot >= 0; slot-- {
+		r.freeSlots = append(r.freeSlots, slot)
+	}
+	return r
+}
+
+func (r *ebpfAccountingReceiver) Start(_ context.Context, _ component.Host) error {
+	accounting, err := newNetAccounting(r.config.MaxCgroups)
+	if err != nil {
+		r.logger.Warn("Network accounting is unavailable, only CPU time "+
+			"is reported", zap.Error(err))
+	} else {
+		r.accounting = accounting
+	}
+
+	r.stopWaiters.Add(1)
+	go r.collectLoop()
+	return nil
+}
+
+func (r *ebpfAccountingReceiver) Shutdown(context.Context) error {
+	close(r.stopChannel)
+	r.stopWaiters.Wait()
+	for path := range r.cgroups {
+		r.remove(path)
+	}
+	if r.accounting != nil {
+		if err := r.accounting.Close(); err != nil {
+			r.logger.Warn("Failed to close network accounting", zap.Error(err))
+		}
+		r.accounting = nil
+	}
+	return nil
+}
+
+func (r *ebpfAccountingReceiver) collectLoop() {
+	defer r.stopWaiters.Done()
+
+	ticker := time.NewTicker(r.config.CollectionInterval)
+	defer ticker.Stop()
+
+	r.discover()
+	for {
+		select {
+		case <-ticker.C:
+			r.collect()
+			r.discover()
+		case <-r.stopChannel:
+			return
+		}
+	}
+}
+
+// discover lists the cgroups matching the configured patterns, starts
+// accounting for those created since the previous interval, and stops
+// accounting for those removed.
+func (r *ebpfAccountingReceiver) discover() {
+	current := make(map[string]os.FileInfo)
+	for _, pattern := range r.config.Cgroups {
+		matches, _ := filepath.Glob(filepath.Join(r.config.CgroupRoot, pattern))
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil || !info.IsDir() {
+				continue
+			}
+			path, err := filepath.Rel(r.config.CgroupRoot, match)
+			if err != nil {
+				continue
+			}
+			current[path] = info
+		}
+	}
+
+	for path, cg := range r.cgroups {
+		if info, exists := current[path]; !exists || !os.SameFile(info, cg.info) {
+			r.remove(path)
+		}
+	}
+	for path := range r.skipped {
+		if _, exists := current[path]; !exists {
+			delete(r.skipped, path)
+		}
+	}
+
+	paths := make([]string, 0, len(current))
+	for path := range current {
+		if _, exists := r.cgroups[path]; !exists {
+			paths = append(paths, path)
+		}
+	}
+	slices.Sort(paths)
+	for _, path := range paths {
+		r.add(path, current[path])
+	}
+}
+
+// add starts accounting for a cgroup, if a slot is free.
+func (r *ebpfAccountingReceiver) add(path string, info os.FileInfo) {
+	if len(r.freeSlots) == 0 {
+		if !r.skipped[path] {
+			r.logger.Warn("Cgroup not accounted for as max_cgroups was reached",
+				zap.String("cgroup", path),
+				zap.Int("max_cgroups", r.config.MaxCgroups))
+			r.skipped[path] = true
+		}
+		return
+	}
+	delete(r.skipped, path)
+
+	cg := &cgroup{
+		path:  path,
+		info:  info,
+		start: pcommon.NewTimestampFromTime(time.Now()),
+		slot:  r.freeSlots[len(r.freeSlots)-1],
+	}
+	r.freeSlots = r.freeSlots[:len(r.freeSlots)-1]
+	if r.accounting != nil {
+		programs, err := r.accounting.attach(
+			filepath.Join(r.config.CgroupRoot, path), cg.slot)
+		if err != nil {
+			r.logger.Warn("Failed to attach network accounting to cgroup",
+				zap.String("cgroup", path), zap.Error(err))
+		} else {
+			cg.programs = programs
+		}
+	}
+	r.cgroups[path] = cg
+}
+
+// remove stops accounting for a cgroup and frees its slot.
+func (r *ebpfAccountingReceiver) remove(path string) {
+	cg := r.cgroups[path]
+	if cg.programs != nil {
+		if err := cg.programs.Close(); err != nil {
+			r.logger.Warn("Failed to detach network accounting from cgroup",
+				zap.String("cgroup", path), zap.Error(err))
+		}
+	}
+	r.freeSlots = append(r.freeSlots, cg.slot)
+	delete(r.cgroups, path)
+}
+
+// collect emits the CPU time and network traffic of the cgroups accounted for.
+func (r *ebpfAccountingReceiver) collect() {
+	if len(r.cgroups) == 0 {
+		return
+	}
+
+	md := pmetric.NewMetrics()
+	sm := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty()
+	sm.Scope().SetName(scopeName)
+	sm.Scope().SetVersion(Version)
+
+	newSum := func(name, description, unit string) pmetric.NumberDataPointSlice {
+		metric := sm.Metrics().AppendEmpty()
+		metric.SetName(name)
+		metric.SetDescription(description)
+		metric.SetUnit(unit)
+		sum := metric.SetEmptySum()
+		sum.SetIsMonotonic(true)
+		sum.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+		return sum.DataPoints()
+	}
+	cpuTime := newSum("cgroup.cpu.time",
+		"CPU time consumed by the processes of the cgroup", "s")
+	var networkIO, networkPackets pmetric.NumberDataPointSlice
+	if r.accounting != nil {
+		networkIO = newSum("cgroup.network.io",
+			"Bytes received and transmitted by the processes of the cgroup", "By")
+		networkPackets = newSum("cgroup.network.packets",
+			"Packets received and transmitted by the processes of the cgroup",
+			"{packets}")
+	}
+
+	now := pcommon.NewTimestampFromTime(time.Now())
+	paths := make([]string, 0, len(r.cgroups))
+	for path := range r.cgroups {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	for _, path := range paths {
+		cg := r.cgroups[path]
+		add := func(dps pmetric.NumberDataPointSlice, attribute, value string) pmetric.NumberDataPoint {
+			dp := dps.AppendEmpty()
+			dp.SetStartTimestamp(cg.start)
+			dp.SetTimestamp(now)
+			dp.Attributes().PutStr("cgroup.path", path)
+			dp.Attributes().PutStr(attribute, value)
+			return dp
+		}
+
+		times, err := readCPUTimes(filepath.Join(r.config.CgroupRoot, path))
+		if err != nil {
+			// removed since discovered
+			r.logger.Debug("Failed to read CPU time of cgroup",
+				zap.String("cgroup", path), zap.Error(err))
+		} else {
+			add(cpuTime, "state", "user").SetDoubleValue(
+				float64(times.userMicros) / 1e6)
+			add(cpuTime, "state", "system").SetDoubleValue(
+				float64(times.systemMicros) / 1e6)
+		}
+
+		if cg.programs == nil {
+			continue
+		}
+		counts, err := r.accounting.read(cg.slot)
+		if err != nil {
+			r.logger.Warn("Failed to read network accounting of cgroup",
+				zap.String("cgroup", path), zap.Error(err))
+			continue
+		}
+		add(networkIO, "direction", "receive").SetIntValue(int64(counts.receivedBytes))
+		add(networkIO, "direction", "transmit").SetIntValue(int64(counts.transmittedBytes))
+		add(networkPackets, "direction", "receive").SetIntValue(int64(counts.receivedPackets))
+		add(networkPackets, "direction", "transmit").SetIntValue(int64(counts.transmittedPackets))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(),
+		r.config.CollectionInterval)
+	defer cancel()
+	if err := r.nextConsumer.ConsumeMetrics(ctx, md); err != nil {
+		r.logger.Error("Failed to consume cgroup metrics", zap.Error(err))
+	}
+}
+
+// readCPUTimes reads the user and system CPU time of a cgroup from its
+// cpu.stat, which the cgroup v2 hierarchy provides even without the cpu
+// controller enabled.
+func readCPUTimes(dir string) (cpuTimes, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return cpuTimes{}, err
+	}
+	var times cpuTimes
+	found := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		name, value, ok := bytes.Cut(scanner.Bytes(), []byte(" "))
+		if !ok {
+			continue
+		}
+		var field *int64
+		switch string(name) {
+		case "user_usec":
+			field = &times.userMicros
+		case "system_usec":
+			field = &times.systemMicros
+		default:
+			continue
+		}
+		if *field, err = strconv.ParseInt(string(value), 10, 64); err != nil {
+			return cpuTimes{}, fmt.Errorf("invalid %s in cpu.stat: %w", name, err)
+		}
+		found++
+	}
+	if found < 2 {
+		return cpuTimes{}, fmt.Errorf("cpu.stat of %s lacks user_usec or system_usec", dir)
+	}
+	return times, nil
+}
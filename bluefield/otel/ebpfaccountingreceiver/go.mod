@@ -0,0 +1,3 @@
+module ebpfaccountingreceiver
+
+go 1.22
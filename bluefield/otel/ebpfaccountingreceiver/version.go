@@ -0,0 +1,3 @@
+package ebpfaccountingreceiver
+
+const Version = "0.0.1"
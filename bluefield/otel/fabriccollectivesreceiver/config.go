@@ -0,0 +1,55 @@
+package fabriccollectivesreceiver
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+// Config defines the configuration of the fabric_collectives receiver.
+type Config struct {
+	// CollectionInterval configures how often job counters are collected.
+	// Defaults to "30s".
+	CollectionInterval time.Duration `mapstructure:"collection_interval"`
+
+	// SocketGlob matches the telemetry sockets the jobs to query serve
+	// themselves, as described in the README. Defaults to
+	// "/var/run/sharp/telemetry/*.sock".
+	SocketGlob string `mapstructure:"socket_glob"`
+
+	// Timeout limits how long a job may take to answer. Defaults to
+	// "5s".
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.CollectionInterval <= 0 {
+		return errors.New("collection_interval must be positive")
+	}
+	if cfg.SocketGlob == "" {
+		return errors.New("socket_glob cannot be empty")
+	}
+	if _, err := filepath.Match(cfg.SocketGlob, ""); err != nil {
+		return fmt.Errorf("invalid socket_glob: %w", err)
+	}
+	if cfg.Timeout <= 0 || cfg.Timeout > cfg.CollectionInterval {
+		return errors.New("timeout must be positive and at most collection_interval")
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		CollectionInterval: 30 * time.Second,
+		SocketGlob:         "/var/run/sharp/telemetry/*.sock",
+		Timeout:            5 * time.Second,
+	}
+}
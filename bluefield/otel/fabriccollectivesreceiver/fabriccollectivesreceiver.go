@@ -0,0 +1,268 @@
+package fabriccollectivesreceiver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+
+	"otelcommon/metricappend"
+)
+
+const scopeName = "fabriccollectivesreceiver"
+
+// maxResponseSize limits the stats read from a job.
+const maxResponseSize = 1 << 20
+
+// jobStats is the answer of a job to the "stats" request. Counters are
+// cumulative since the job started.
+type jobStats struct {
+	JobID       string            `json:"job_id"`
+	Provider    string            `json:"provider"`
+	PID         int64             `json:"pid"`
+	Rank        *int64            `json:"rank"`
+	Collectives []collectiveStats `json:"collectives"`
+	Errors      *int64            `json:"errors"`
+}
+
+// collectiveStats are the counters of a type of collective operation, e.g.
+// allreduce, of a job. Offloaded operations were aggregated by the switches
+// of the fabric, the others fell back to the hosts.
+type collectiveStats struct {
+	Type       string `json:"type"`
+	Operations int64  `json:"operations"`
+	Offloaded  int64  `json:"offloaded"`
+	Bytes      *int64 `json:"bytes"`
+}
+
+// jobState is what the receiver remembers of a job between collections.
+type jobState struct {
+	start      pcommon.Timestamp
+	operations int64
+	seen       bool // in the current collection
+}
+
+type fabricCollectivesReceiver struct {
+	config       *Config
+	logger       *zap.Logger
+	nextConsumer consumer.Metrics
+	stopChannel  chan struct{}
+	stopWaiters  sync.WaitGroup
+
+	// jobs by socket and process, removed once the job is gone
+	jobs map[string]*jobState
+}
+
+func newFabricCollectivesReceiver(
+	config *Config,
+	logger *zap.Logger,
+	nextConsumer consumer.Metrics,
+) *fabricCollectivesReceiver {
+	return &fabricCollectivesReceiver{
+		config:       config,
+		logger:       logger,
+		nextConsumer: nextConsumer,
+		stopChannel:  make(chan struct{}),
+		jobs:         make(map[string]*jobState),
+	}
+}
+
+func (r *fabricCollectivesReceiver) Start(_ context.Context, _ component.Host) error {
+	r.stopWaiters.Add(1)
+	go r.collectLoop()
+	return nil
+}
+
+func (r *fabricCollectivesReceiver) Shutdown(context.Context) error {
+	close(r.stopChannel)
+	r.stopWaiters.Wait()
+	return nil
+}
+
+func (r *fabricCollectivesReceiver) collectLoop() {
+	defer r.stopWaiters.Done()
+
+	ticker := time.NewTicker(r.config.CollectionInterval)
+	defer ticker.Stop()
+
+	r.collect()
+	for {
+		select {
+		case <-ticker.C:
+			r.collect()
+		case <-r.stopChannel:
+			return
+		}
+	}
+}
+
+func (r *fabricCollectivesReceiver) collect() {
+	ctx, cancel := context.WithTimeout(context.Background(),
+		r.config.CollectionInterval)
+	defer cancel()
+
+	sockets, err := filepath.Glob(r.config.SocketGlob)
+	if err != nil {
+		r.logger.Error("Failed to list job telemetry sockets", zap.Error(err))
+		return
+	}
+	sort.Strings(sockets)
+
+	now := pcommon.NewTimestampFromTime(time.Now())
+	md := pmetric.NewMetrics()
+	for _, state := range r.jobs {
+		state.seen = false
+	}
+	for _, socket := range sockets {
+		stats, err := queryJob(ctx, socket, r.config.Timeout)
+		if err != nil {
+			// sockets of jobs that ended are left behind, so
+			// failures to connect are common
+			r.logger.Debug("Failed to collect fabric collective stats",
+				zap.String("socket", socket), zap.Error(err))
+			continue
+		}
+		r.appendJob(md, socket, stats, now)
+	}
+	for key, state := range r.jobs {
+		if !state.seen {
+			delete(r.jobs, key)
+		}
+	}
+
+	// e.g. jobs that ran no collective operations yet
+	md.ResourceMetrics().RemoveIf(func(rm pmetric.ResourceMetrics) bool {
+		return rm.ScopeMetrics().At(0).Metrics().Len() == 0
+	})
+
+	if md.DataPointCount() == 0 {
+		return
+	}
+	if err := r.nextConsumer.ConsumeMetrics(ctx, md); err != nil {
+		r.logger.Error("Failed to consume fabric collective stats",
+			zap.Error(err))
+	}
+}
+
+// queryJob requests the stats of the job serving a socket, which answers with
+// a JSON object and closes the connection.
+func queryJob(ctx context.Context, socket string, timeout time.Duration) (*jobStats, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", socket)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if _, err := conn.Write([]byte("stats\n")); err != nil {
+		return nil, err
+	}
+	var stats jobStats
+	if err := json.NewDecoder(io.LimitReader(conn, maxResponseSize)).
+		Decode(&stats); err != nil {
+		return nil, fmt.Errorf("invalid stats: %w", err)
+	}
+	if stats.JobID == "" {
+		return nil, errors.New("stats lack the job_id")
+	}
+	return &stats, nil
+}
+
+// appendJob appends the counters of a job as a resource of its own, labeled
+// with the job ID.
+func (r *fabricCollectivesReceiver) appendJob(
+	md pmetric.Metrics,
+	socket string,
+	stats *jobStats,
+	now pcommon.Timestamp,
+) {
+	var operations int64
+	for _, c := range stats.Collectives {
+		operations += c.Operations
+	}
+	key := socket + "|" + strconv.FormatInt(stats.PID, 10)
+	state, existed := r.jobs[key]
+	// counters going down belong to a job started again on the socket
+	if !existed || operations < state.operations {
+		state = &jobState{start: now}
+		r.jobs[key] = state
+	}
+	state.operations = operations
+	state.seen = true
+
+	rm := md.ResourceMetrics().AppendEmpty()
+	resource := rm.Resource().Attributes()
+	resource.PutStr("fabric.job.id", stats.JobID)
+	if stats.Provider != "" {
+		resource.PutStr("fabric.provider", stats.Provider)
+	}
+	if stats.PID != 0 {
+		resource.PutInt("process.pid", stats.PID)
+	}
+	if stats.Rank != nil {
+		resource.PutInt("fabric.job.rank", *stats.Rank)
+	}
+	sm := rm.ScopeMetrics().AppendEmpty()
+	sm.Scope().SetName(scopeName)
+	sm.Scope().SetVersion(Version)
+
+	opsPoints := metricappend.Sum(sm.Metrics(), "fabric.collective.operations",
+		"{operations}", "Collective operations of the job, offloaded to "+
+			"the fabric or falling back to the hosts")
+	bytesPoints := metricappend.Sum(sm.Metrics(), "fabric.collective.bytes", "By",
+		"Bytes reduced or exchanged by collective operations of the job")
+	errorPoints := metricappend.Sum(sm.Metrics(), "fabric.collective.errors",
+		"{errors}", "Collective operations of the job that failed")
+	for _, c := range stats.Collectives {
+		if c.Type == "" {
+			continue
+		}
+		dp := appendIntDatapoint(opsPoints, c.Offloaded, state.start, now)
+		dp.Attributes().PutStr("collective.type", c.Type)
+		dp.Attributes().PutBool("collective.offloaded", true)
+		dp = appendIntDatapoint(opsPoints, max(c.Operations-c.Offloaded, 0),
+			state.start, now)
+		dp.Attributes().PutStr("collective.type", c.Type)
+		dp.Attributes().PutBool("collective.offloaded", false)
+
+		if c.Bytes != nil {
+			dp = appendIntDatapoint(bytesPoints, *c.Bytes, state.start, now)
+			dp.Attributes().PutStr("collective.type", c.Type)
+		}
+	}
+	if stats.Errors != nil {
+		appendIntDatapoint(errorPoints, *stats.Errors, state.start, now)
+	}
+
+	// e.g. the bytes of jobs not counting them
+	sm.Metrics().RemoveIf(func(metric pmetric.Metric) bool {
+		return metric.Sum().DataPoints().Len() == 0
+	})
+}
+
+func appendIntDatapoint(
+	dps pmetric.NumberDataPointSlice,
+	value int64,
+	start pcommon.Timestamp,
+	now pcommon.Timestamp,
+) pmetric.NumberDataPoint {
+	dp := dps.AppendEmpty()
+	dp.SetStartTimestamp(start)
+	dp.SetTimestamp(now)
+	dp.SetIntValue(value)
+	return dp
+}
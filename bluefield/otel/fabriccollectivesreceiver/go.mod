@@ -0,0 +1,3 @@
+module fabriccollectivesreceiver
+
+go 1.22
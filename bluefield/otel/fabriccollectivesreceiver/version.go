@@ -0,0 +1,3 @@
+package fabriccollectivesreceiver
+
+const Version = "0.0.1"
@@ -0,0 +1,156 @@
+package fanoutexporter
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/exporter/otlpexporter"
+
+	"otelcommon/autocompression"
+	"otelcommon/filter"
+	"otelcommon/queuestats"
+)
+
+// Config defines the configuration of the otlp_fanout exporter.
+type Config struct {
+	// Destinations configures the OTLP destinations data is sent to.
+	Destinations []Destination `mapstructure:"destinations"`
+
+	// QueueStatsEndpoint serves the statistics of the hand-off to each
+	// destination at /metrics/exporters. Empty disables them. Defaults to
+	// "localhost:8890".
+	QueueStatsEndpoint string `mapstructure:"queue_stats_endpoint"`
+}
+
+// Destination defines a single OTLP destination and the subset of data sent to
+// it.
+type Destination struct {
+	// Name identifies the destination, and the OTLP exporter created for
+	// it is named "otlp/<exporter name>/<name>" in the collector's logs
+	// and internal metrics.
+	Name string `mapstructure:"name"`
+
+	// OTLP is the configuration of the OTLP exporter for the destination,
+	// exactly as for the otlp exporter, including its own sending_queue
+	// and retry_on_failure settings.
+	OTLP map[string]any `mapstructure:"otlp"`
+
+	// AutoCompression optionally selects the compression of the OTLP
+	// exporter from the compressions' cost measured at startup and the
+	// link and CPU budgets of the destination, instead of its
+	// compression setting.
+	AutoCompression autocompression.Config `mapstructure:"auto_compression"`
+
+	// Include optionally limits the data sent to the destination to data
+	// matching the filter.
+	Include *Filter `mapstructure:"include"`
+
+	// Exclude optionally removes data matching the filter from the data
+	// sent to the destination.
+	Exclude *Filter `mapstructure:"exclude"`
+}
+
+// Filter defines criteria matching log records, metric datapoints or spans.
+// Data matches the filter if it matches all of the specified criteria.
+type Filter struct {
+	// ResourceAttributes match the attributes of the resource.
+	ResourceAttributes []filter.LabelFilter `mapstructure:"resource_attributes"`
+
+	// Attributes match the attributes of the log record, datapoint or
+	// span itself.
+	Attributes []filter.LabelFilter `mapstructure:"attributes"`
+
+	// MetricNames is a list of metric names to match. Log records and
+	// spans never match a filter with metric criteria.
+	MetricNames []string `mapstructure:"metric_names"`
+
+	// MetricRegex is a regular expression matching metric names.
+	MetricRegex string `mapstructure:"metric_regex"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if len(cfg.Destinations) == 0 {
+		return errors.New("at least one destination must be configured")
+	}
+
+	names := make(map[string]bool)
+	for _, dest := range cfg.Destinations {
+		if dest.Name == "" {
+			return errors.New("destination name cannot be empty")
+		}
+		if names[dest.Name] {
+			return fmt.Errorf("destination %s is configured more than once",
+				dest.Name)
+		}
+		names[dest.Name] = true
+
+		if _, err := dest.otlpConfig(""); err != nil {
+			return fmt.Errorf("destination %s: %w", dest.Name, err)
+		}
+		if err := dest.AutoCompression.Validate(); err != nil {
+			return fmt.Errorf("destination %s: invalid auto_compression: %w",
+				dest.Name, err)
+		}
+		if _, exists := dest.OTLP["compression"]; exists &&
+			dest.AutoCompression.Enabled {
+			return fmt.Errorf("destination %s: compression cannot be set "+
+				"when auto_compression is enabled", dest.Name)
+		}
+		for _, f := range []*Filter{dest.Include, dest.Exclude} {
+			if f == nil {
+				continue
+			}
+			if err := f.validate(); err != nil {
+				return fmt.Errorf("destination %s: %w", dest.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// otlpConfig returns the validated configuration of the destination's OTLP
+// exporter, with its compression set to the given one unless it is empty.
+func (dest *Destination) otlpConfig(compression string) (component.Config, error) {
+	otlp := dest.OTLP
+	if compression != "" {
+		otlp = maps.Clone(dest.OTLP)
+		if otlp == nil {
+			otlp = make(map[string]any)
+		}
+		otlp["compression"] = compression
+	}
+
+	cfg := otlpexporter.NewFactory().CreateDefaultConfig()
+	if err := confmap.NewFromStringMap(otlp).Unmarshal(cfg); err != nil {
+		return nil, fmt.Errorf("invalid otlp config: %w", err)
+	}
+	if validator, ok := cfg.(interface{ Validate() error }); ok {
+		if err := validator.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid otlp config: %w", err)
+		}
+	}
+	return cfg, nil
+}
+
+func (f *Filter) validate() error {
+	if len(f.ResourceAttributes) == 0 && len(f.Attributes) == 0 &&
+		len(f.MetricNames) == 0 && f.MetricRegex == "" {
+		return errors.New("filter must specify at least one criterion")
+	}
+	_, err := compileFilter(f)
+	return err
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		QueueStatsEndpoint: queuestats.DefaultEndpoint,
+	}
+}
@@ -0,0 +1,126 @@
+package fanoutexporter
+
+import (
+	"context"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/exporter"
+	"go.opentelemetry.io/collector/exporter/exporterhelper"
+)
+
+const (
+	typeStr   = "otlp_fanout"
+	stability = component.StabilityLevelAlpha
+)
+
+// the wrapped exporters neither mutate data nor need it copied
+var exporterCapabilities = consumer.Capabilities{MutatesData: false}
+
+func NewFactory() exporter.Factory {
+	return exporter.NewFactory(
+		component.MustNewType(typeStr),
+		createDefaultConfig,
+		exporter.WithTraces(createTracesExporter, stability),
+		exporter.WithMetrics(createMetricsExporter, stability),
+		exporter.WithLogs(createLogsExporter, stability),
+	)
+}
+
+func createTracesExporter(
+	ctx context.Context,
+	set exporter.CreateSettings,
+	cfg component.Config,
+) (exporter.Traces, error) {
+	e, err := newFanoutExporter(ctx, set, cfg.(*Config), "traces", func(
+		ctx context.Context,
+		factory exporter.Factory,
+		set exporter.CreateSettings,
+		cfg component.Config,
+		d *destination,
+	) error {
+		var err error
+		d.traces, err = factory.CreateTracesExporter(ctx, set, cfg)
+		d.component = d.traces
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Queueing and retries are left to the destinations' exporters, so
+	// that a slow destination doesn't hold back the others.
+	return exporterhelper.NewTracesExporter(
+		ctx,
+		set,
+		cfg,
+		e.consumeTraces,
+		exporterhelper.WithCapabilities(exporterCapabilities),
+		exporterhelper.WithStart(e.start),
+		exporterhelper.WithShutdown(e.shutdown),
+	)
+}
+
+func createMetricsExporter(
+	ctx context.Context,
+	set exporter.CreateSettings,
+	cfg component.Config,
+) (exporter.Metrics, error) {
+	e, err := newFanoutExporter(ctx, set, cfg.(*Config), "metrics", func(
+		ctx context.Context,
+		factory exporter.Factory,
+		set exporter.CreateSettings,
+		cfg component.Config,
+		d *destination,
+	) error {
+		var err error
+		d.metrics, err = factory.CreateMetricsExporter(ctx, set, cfg)
+		d.component = d.metrics
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return exporterhelper.NewMetricsExporter(
+		ctx,
+		set,
+		cfg,
+		e.consumeMetrics,
+		exporterhelper.WithCapabilities(exporterCapabilities),
+		exporterhelper.WithStart(e.start),
+		exporterhelper.WithShutdown(e.shutdown),
+	)
+}
+
+func createLogsExporter(
+	ctx context.Context,
+	set exporter.CreateSettings,
+	cfg component.Config,
+) (exporter.Logs, error) {
+	e, err := newFanoutExporter(ctx, set, cfg.(*Config), "logs", func(
+		ctx context.Context,
+		factory exporter.Factory,
+		set exporter.CreateSettings,
+		cfg component.Config,
+		d *destination,
+	) error {
+		var err error
+		d.logs, err = factory.CreateLogsExporter(ctx, set, cfg)
+		d.component = d.logs
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return exporterhelper.NewLogsExporter(
+		ctx,
+		set,
+		cfg,
+		e.consumeLogs,
+		exporterhelper.WithCapabilities(exporterCapabilities),
+		exporterhelper.WithStart(e.start),
+		exporterhelper.WithShutdown(e.shutdown),
+	)
+}
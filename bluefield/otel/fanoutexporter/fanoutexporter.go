@@ -0,0 +1,317 @@
+package fanoutexporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/exporter"
+	"go.opentelemetry.io/collector/exporter/otlpexporter"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+
+	"otelcommon/autocompression"
+	"otelcommon/queuestats"
+)
+
+type fanoutExporter struct {
+	logger       *zap.Logger
+	destinations []*destination
+}
+
+// destination wraps the OTLP exporter created for a configured destination.
+// Only the exporter of the signal the fan-out exporter was created for is set.
+type destination struct {
+	name      string
+	include   *compiledFilter
+	exclude   *compiledFilter
+	component component.Component
+	traces    exporter.Traces
+	metrics   exporter.Metrics
+	logs      exporter.Logs
+	stats     *queuestats.Stats
+}
+
+// newFanoutExporter creates an OTLP exporter for each configured destination
+// using createExporter for the signal of the pipeline.
+func newFanoutExporter(
+	ctx context.Context,
+	set exporter.CreateSettings,
+	config *Config,
+	signal string,
+	createExporter func(context.Context, exporter.Factory, exporter.CreateSettings, component.Config, *destination) error,
+) (*fanoutExporter, error) {
+	e := &fanoutExporter{logger: set.Logger}
+	if err := e.addDestinations(ctx, set, config, signal, createExporter); err != nil {
+		for _, d := range e.destinations {
+			d.stats.Unregister()
+		}
+		return nil, err
+	}
+	return e, nil
+}
+
+// addDestinations creates the exporter of each destination.
+func (e *fanoutExporter) addDestinations(
+	ctx context.Context,
+	set exporter.CreateSettings,
+	config *Config,
+	signal string,
+	createExporter func(context.Context, exporter.Factory, exporter.CreateSettings, component.Config, *destination) error,
+) error {
+	factory := otlpexporter.NewFactory()
+
+	for i := range config.Destinations {
+		dest := &config.Destinations[i]
+		destSet := set
+		destSet.ID = component.NewIDWithName(factory.Type(),
+			set.ID.String()+"/"+dest.Name)
+		destSet.Logger = set.Logger.With(zap.String("destination", dest.Name))
+
+		// the compressions are benchmarked once, when the first
+		// destination with auto_compression is created
+		var compression string
+		if dest.AutoCompression.Enabled {
+			var err error
+			compression, err = autocompression.Select(dest.AutoCompression,
+				destSet.Logger)
+			if err != nil {
+				return fmt.Errorf("destination %s: %w", dest.Name, err)
+			}
+		}
+		otlpConfig, err := dest.otlpConfig(compression)
+		if err != nil {
+			return fmt.Errorf("destination %s: %w", dest.Name, err)
+		}
+		include, err := compileFilter(dest.Include)
+		if err != nil {
+			return fmt.Errorf("destination %s: include: %w", dest.Name, err)
+		}
+		exclude, err := compileFilter(dest.Exclude)
+		if err != nil {
+			return fmt.Errorf("destination %s: exclude: %w", dest.Name, err)
+		}
+
+		// The destination's exporter queues and retries the data, so
+		// only handing it to the queue is recorded.
+		stats, err := queuestats.Register(queuestats.Settings{
+			Endpoint:    config.QueueStatsEndpoint,
+			Exporter:    set.ID.String(),
+			Destination: dest.Name,
+			Signal:      signal,
+			Untracked:   true,
+		}, destSet.Logger)
+		if err != nil {
+			return fmt.Errorf("destination %s: %w", dest.Name, err)
+		}
+
+		d := &destination{
+			name:    dest.Name,
+			include: include,
+			exclude: exclude,
+			stats:   stats,
+		}
+		if err := createExporter(ctx, factory, destSet, otlpConfig, d); err != nil {
+			stats.Unregister()
+			return fmt.Errorf("failed to create exporter for "+
+				"destination %s: %w", dest.Name, err)
+		}
+		e.destinations = append(e.destinations, d)
+	}
+
+	return nil
+}
+
+func (e *fanoutExporter) start(ctx context.Context, host component.Host) error {
+	for _, d := range e.destinations {
+		if err := d.component.Start(ctx, host); err != nil {
+			return fmt.Errorf("failed to start exporter for destination "+
+				"%s: %w", d.name, err)
+		}
+	}
+	return nil
+}
+
+// shutdown shuts down every destination's exporter, which drains or persists
+// its queue, even if others fail.
+func (e *fanoutExporter) shutdown(ctx context.Context) error {
+	var errs []error
+	for _, d := range e.destinations {
+		d.stats.Unregister()
+		if d.component == nil {
+			continue
+		}
+		if err := d.component.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("destination %s: %w", d.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// The consume functions hand the data to each destination's exporter, which
+// queues and retries it independently of the other destinations. Data is only
+// copied for destinations with filters, since exporters don't mutate data.
+// Handing the data over is recorded as a push attempt of the destination.
+
+func (e *fanoutExporter) consumeTraces(ctx context.Context, td ptrace.Traces) error {
+	var errs []error
+	for _, d := range e.destinations {
+		data := td
+		if d.filtered() {
+			data = ptrace.NewTraces()
+			td.CopyTo(data)
+			d.filterTraces(data)
+			if data.SpanCount() == 0 {
+				continue
+			}
+		}
+		if err := d.stats.PushTraces(d.traces.ConsumeTraces)(ctx, data); err != nil {
+			errs = append(errs, fmt.Errorf("destination %s: %w", d.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (e *fanoutExporter) consumeMetrics(ctx context.Context, md pmetric.Metrics) error {
+	var errs []error
+	for _, d := range e.destinations {
+		data := md
+		if d.filtered() {
+			data = pmetric.NewMetrics()
+			md.CopyTo(data)
+			d.filterMetrics(data)
+			if data.DataPointCount() == 0 {
+				continue
+			}
+		}
+		if err := d.stats.PushMetrics(d.metrics.ConsumeMetrics)(ctx, data); err != nil {
+			errs = append(errs, fmt.Errorf("destination %s: %w", d.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (e *fanoutExporter) consumeLogs(ctx context.Context, ld plog.Logs) error {
+	var errs []error
+	for _, d := range e.destinations {
+		data := ld
+		if d.filtered() {
+			data = plog.NewLogs()
+			ld.CopyTo(data)
+			d.filterLogs(data)
+			if data.LogRecordCount() == 0 {
+				continue
+			}
+		}
+		if err := d.stats.PushLogs(d.logs.ConsumeLogs)(ctx, data); err != nil {
+			errs = append(errs, fmt.Errorf("destination %s: %w", d.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (d *destination) filtered() bool {
+	return d.include != nil || d.exclude != nil
+}
+
+// keep returns whether a log record, datapoint or span is sent to the
+// destination. The metric name is empty for log records and spans.
+func (d *destination) keep(resource pcommon.Map, metricName string, attrs pcommon.Map) bool {
+	if d.include != nil && !matches(d.include, resource, metricName, attrs) {
+		return false
+	}
+	if d.exclude != nil && matches(d.exclude, resource, metricName, attrs) {
+		return false
+	}
+	return true
+}
+
+func matches(f *compiledFilter, resource pcommon.Map, metricName string, attrs pcommon.Map) bool {
+	if metricName == "" && f.hasMetricCriteria() {
+		return false
+	}
+	return f.matchResource(resource) && f.matchMetricName(metricName) &&
+		f.matchAttributes(attrs)
+}
+
+func (d *destination) filterTraces(td ptrace.Traces) {
+	td.ResourceSpans().RemoveIf(func(rs ptrace.ResourceSpans) bool {
+		resource := rs.Resource().Attributes()
+		rs.ScopeSpans().RemoveIf(func(ss ptrace.ScopeSpans) bool {
+			ss.Spans().RemoveIf(func(span ptrace.Span) bool {
+				return !d.keep(resource, "", span.Attributes())
+			})
+			return ss.Spans().Len() == 0
+		})
+		return rs.ScopeSpans().Len() == 0
+	})
+}
+
+func (d *destination) filterLogs(ld plog.Logs) {
+	ld.ResourceLogs().RemoveIf(func(rl plog.ResourceLogs) bool {
+		resource := rl.Resource().Attributes()
+		rl.ScopeLogs().RemoveIf(func(sl plog.ScopeLogs) bool {
+			sl.LogRecords().RemoveIf(func(lr plog.LogRecord) bool {
+				return !d.keep(resource, "", lr.Attributes())
+			})
+			return sl.LogRecords().Len() == 0
+		})
+		return rl.ScopeLogs().Len() == 0
+	})
+}
+
+func (d *destination) filterMetrics(md pmetric.Metrics) {
+	md.ResourceMetrics().RemoveIf(func(rm pmetric.ResourceMetrics) bool {
+		resource := rm.Resource().Attributes()
+		rm.ScopeMetrics().RemoveIf(func(sm pmetric.ScopeMetrics) bool {
+			sm.Metrics().RemoveIf(func(metric pmetric.Metric) bool {
+				return d.filterDatapoints(resource, metric) == 0
+			})
+			return sm.Metrics().Len() == 0
+		})
+		return rm.ScopeMetrics().Len() == 0
+	})
+}
+
+// filterDatapoints removes the datapoints of the metric not sent to the
+// destination and returns the number of remaining datapoints.
+func (d *destination) filterDatapoints(resource pcommon.Map, metric pmetric.Metric) int {
+	name := metric.Name()
+	switch metric.Type() {
+	case pmetric.MetricTypeGauge:
+		dps := metric.Gauge().DataPoints()
+		dps.RemoveIf(func(dp pmetric.NumberDataPoint) bool {
+			return !d.keep(resource, name, dp.Attributes())
+		})
+		return dps.Len()
+	case pmetric.MetricTypeSum:
+		dps := metric.Sum().DataPoints()
+		dps.RemoveIf(func(dp pmetric.NumberDataPoint) bool {
+			return !d.keep(resource, name, dp.Attributes())
+		})
+		return dps.Len()
+	case pmetric.MetricTypeHistogram:
+		dps := metric.Histogram().DataPoints()
+		dps.RemoveIf(func(dp pmetric.HistogramDataPoint) bool {
+			return !d.keep(resource, name, dp.Attributes())
+		})
+		return dps.Len()
+	case pmetric.MetricTypeExponentialHistogram:
+		dps := metric.ExponentialHistogram().DataPoints()
+		dps.RemoveIf(func(dp pmetric.ExponentialHistogramDataPoint) bool {
+			return !d.keep(resource, name, dp.Attributes())
+		})
+		return dps.Len()
+	case pmetric.MetricTypeSummary:
+		dps := metric.Summary().DataPoints()
+		dps.RemoveIf(func(dp pmetric.SummaryDataPoint) bool {
+			return !d.keep(resource, name, dp.Attributes())
+		})
+		return dps.Len()
+	}
+	return 0
+}
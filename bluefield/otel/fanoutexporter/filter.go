@@ -0,0 +1,69 @@
+package fanoutexporter
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+
+	"otelcommon/filter"
+)
+
+// compiledFilter is a Filter with its regular expressions compiled.
+type compiledFilter struct {
+	resourceAttributes *filter.LabelMatcher
+	attributes         *filter.LabelMatcher
+	metricNames        []string
+	reMetric           *regexp.Regexp
+}
+
+// compileFilter returns nil for a nil filter.
+func compileFilter(f *Filter) (*compiledFilter, error) {
+	if f == nil {
+		return nil, nil
+	}
+	resourceAttributes, err := filter.CompileLabelFilters(f.ResourceAttributes)
+	if err != nil {
+		return nil, fmt.Errorf("resource_attributes: %w", err)
+	}
+	attributes, err := filter.CompileLabelFilters(f.Attributes)
+	if err != nil {
+		return nil, fmt.Errorf("attributes: %w", err)
+	}
+	compiled := &compiledFilter{
+		resourceAttributes: resourceAttributes,
+		attributes:         attributes,
+		metricNames:        f.MetricNames,
+	}
+	if f.MetricRegex != "" {
+		compiled.reMetric, err = regexp.Compile(f.MetricRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid metric_regex: %w", err)
+		}
+	}
+	return compiled, nil
+}
+
+// hasMetricCriteria returns whether the filter can only match metrics.
+func (f *compiledFilter) hasMetricCriteria() bool {
+	return len(f.metricNames) > 0 || f.reMetric != nil
+}
+
+func (f *compiledFilter) matchResource(attrs pcommon.Map) bool {
+	return f.resourceAttributes.MatchAll(filter.MapAttributes(attrs))
+}
+
+func (f *compiledFilter) matchMetricName(name string) bool {
+	if len(f.metricNames) > 0 && !slices.Contains(f.metricNames, name) {
+		return false
+	}
+	if f.reMetric != nil && !f.reMetric.MatchString(name) {
+		return false
+	}
+	return true
+}
+
+func (f *compiledFilter) matchAttributes(attrs pcommon.Map) bool {
+	return f.attributes.MatchAll(filter.MapAttributes(attrs))
+}
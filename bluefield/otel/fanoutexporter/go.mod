@@ -0,0 +1,3 @@
+module fanoutexporter
+
+go 1.22
@@ -0,0 +1,3 @@
+package fanoutexporter
+
+const Version = "0.0.1"
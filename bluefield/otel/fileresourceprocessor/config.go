@@ -2,17 +2,140 @@ package fileresourceprocessor
 
 import (
 	"errors"
+	"fmt"
+	"regexp"
+	"slices"
 	"time"
 
 	"go.opentelemetry.io/collector/component"
 )
 
+const (
+	outputFormatEnv  = "env"
+	outputFormatJSON = "json"
+
+	// schemaAttribute is the resource attribute holding the schema version
+	schemaAttribute = "bmm.attr_schema"
+
+	changeDetectionNone = "none"
+	changeDetectionHash = "hash"
+
+	formatText       = "text"
+	formatBinaryGUID = "binary_guid"
+	formatBinaryMAC  = "binary_mac"
+)
+
 type Config struct {
 	// FilePaths configured files from which to read resource attributes
 	FilePaths []string `mapstructure:"file_paths"`
 
 	// PollInterval how often to try reading the configured file until successful
 	PollInterval time.Duration `mapstructure:"poll_interval"`
+
+	// ChangeDetection how files are checked for changes once read, "none"
+	// to stop polling a file after a successful read, or "hash" to keep
+	// hashing its content and read it again whenever it changes, which
+	// works without inotify, e.g. on squashfs images with tmpfs overlays.
+	// Defaults to "none".
+	ChangeDetection string `mapstructure:"change_detection"`
+
+	// HashInterval how often files are hashed with change_detection
+	// "hash". Defaults to the poll interval.
+	HashInterval time.Duration `mapstructure:"hash_interval"`
+
+	// PathOverrides optional change_detection and hash_interval of
+	// individual files, overriding the settings above, and the format of
+	// files holding raw bytes
+	PathOverrides []PathOverride `mapstructure:"path_overrides"`
+
+	// ReadConcurrency maximum number of files read at the same time on
+	// each poll. Defaults to 4.
+	ReadConcurrency int `mapstructure:"read_concurrency"`
+
+	// ReadBudget optional time after which a poll starts no further reads,
+	// leaving the files not yet read to be read first on the next poll.
+	// Defaults to the poll interval.
+	ReadBudget time.Duration `mapstructure:"read_budget"`
+
+	// Validation optional per-attribute rules that values read from files
+	// must satisfy to be attached
+	Validation []AttributeValidation `mapstructure:"validation"`
+
+	// Owners optional users, by name or numeric uid, one of which must own
+	// each file before its content is trusted, e.g. "root". Files must
+	// then also not be world-writable. Overridden per file by
+	// path_overrides.
+	Owners []string `mapstructure:"owners"`
+
+	// OutputFile optional path to which the merged attributes are written
+	// whenever they change, for other agents on the card to consume
+	OutputFile string `mapstructure:"output_file"`
+
+	// OutputFormat format of the output file, "env" for name=value lines
+	// or "json" for an object. Defaults to "env".
+	OutputFormat string `mapstructure:"output_format"`
+
+	// SchemaVersion optional version of the attribute names, attached as
+	// the resource attribute `bmm.attr_schema` and written to the output
+	// file, so that consumers can tell which names to expect
+	SchemaVersion string `mapstructure:"schema_version"`
+
+	// Migrations optional renames of legacy attribute names found in files
+	// written by older images to their current names
+	Migrations []AttributeMigration `mapstructure:"migrations"`
+
+	// DebugEndpoint optional endpoint such as "localhost:<port>" serving
+	// the current attributes, the file each was read from and when as
+	// JSON at http://<endpoint>/fileresource/attributes. It may be shared
+	// with the debug endpoints of other components.
+	DebugEndpoint string `mapstructure:"debug_endpoint"`
+}
+
+// PathOverride change detection settings of a configured file.
+type PathOverride struct {
+	// Path of the file, as configured in file_paths
+	Path string `mapstructure:"path"`
+
+	// ChangeDetection of the file, or empty for the default
+	ChangeDetection string `mapstructure:"change_detection"`
+
+	// HashInterval of the file, or zero for the default
+	HashInterval time.Duration `mapstructure:"hash_interval"`
+
+	// Format of the file content, "text" for a name=value pair, or
+	// "binary_guid" or "binary_mac" for the raw bytes of a GUID or MAC
+	// address, stored in canonical string form. Defaults to "text".
+	Format string `mapstructure:"format"`
+
+	// Key name of the attribute holding the value of a binary file, which
+	// has no name of its own. Required with a binary format.
+	Key string `mapstructure:"key"`
+
+	// Owners of the file, or empty for the default
+	Owners []string `mapstructure:"owners"`
+}
+
+// AttributeMigration renames a legacy attribute name read from a file. Values
+// are validated under the current name.
+type AttributeMigration struct {
+	// From legacy name of the attribute
+	From string `mapstructure:"from"`
+
+	// To current name of the attribute
+	To string `mapstructure:"to"`
+}
+
+// AttributeValidation rules for the value of an attribute read from a file. A
+// value failing validation is rejected, and the file is polled again.
+type AttributeValidation struct {
+	// Key name of the attribute the rules apply to
+	Key string `mapstructure:"key"`
+
+	// ValidationRegex regular expression the value must match
+	ValidationRegex string `mapstructure:"validation_regex"`
+
+	// AllowedValues list of values the value must be one of
+	AllowedValues []string `mapstructure:"allowed_values"`
 }
 
 var _ component.Config = (*Config)(nil)
@@ -29,12 +152,168 @@ func (c *Config) Validate() error {
 	if c.PollInterval <= 0 {
 		return errors.New("poll_interval must be positive")
 	}
+	if err := validateChangeDetection(c.ChangeDetection, false); err != nil {
+		return err
+	}
+	if c.HashInterval < 0 {
+		return errors.New("hash_interval cannot be negative")
+	}
+	if slices.Contains(c.Owners, "") {
+		return errors.New("owner cannot be empty")
+	}
+	overridden := make(map[string]bool)
+	for _, o := range c.PathOverrides {
+		if !slices.Contains(c.FilePaths, o.Path) {
+			return fmt.Errorf("path override of %s is not a configured file", o.Path)
+		}
+		if overridden[o.Path] {
+			return fmt.Errorf("path override of %s is configured more than once", o.Path)
+		}
+		overridden[o.Path] = true
+		if err := validateChangeDetection(o.ChangeDetection, true); err != nil {
+			return fmt.Errorf("path override of %s: %w", o.Path, err)
+		}
+		if o.HashInterval < 0 {
+			return fmt.Errorf("hash_interval of %s cannot be negative", o.Path)
+		}
+		if slices.Contains(o.Owners, "") {
+			return fmt.Errorf("owner of %s cannot be empty", o.Path)
+		}
+		switch o.Format {
+		case "", formatText:
+			if o.Key != "" {
+				return fmt.Errorf("key of %s is only used with a binary format",
+					o.Path)
+			}
+		case formatBinaryGUID, formatBinaryMAC:
+			if o.Key == "" {
+				return fmt.Errorf("key of %s must be specified with format %s",
+					o.Path, o.Format)
+			}
+			if o.Key == schemaAttribute {
+				return fmt.Errorf("key of %s cannot be %s", o.Path,
+					schemaAttribute)
+			}
+		default:
+			return fmt.Errorf("format of %s must be %q, %q or %q", o.Path,
+				formatText, formatBinaryGUID, formatBinaryMAC)
+		}
+	}
+	if c.ReadConcurrency <= 0 {
+		return errors.New("read_concurrency must be positive")
+	}
+	if c.ReadBudget < 0 {
+		return errors.New("read_budget cannot be negative")
+	}
+	if c.OutputFile != "" && c.OutputFormat != outputFormatEnv &&
+		c.OutputFormat != outputFormatJSON {
+		return fmt.Errorf("output_format must be %q or %q",
+			outputFormatEnv, outputFormatJSON)
+	}
+	migrated := make(map[string]bool)
+	for _, m := range c.Migrations {
+		if m.From == "" || m.To == "" {
+			return errors.New("migration from and to cannot be empty")
+		}
+		if m.From == m.To {
+			return fmt.Errorf("migration of %s renames it to itself", m.From)
+		}
+		if migrated[m.From] {
+			return fmt.Errorf("migration of %s is configured more than once", m.From)
+		}
+		migrated[m.From] = true
+	}
+	for _, m := range c.Migrations {
+		if migrated[m.To] {
+			return fmt.Errorf("migration of %s renames it to %s, which is "+
+				"migrated itself", m.From, m.To)
+		}
+		if m.To == schemaAttribute || m.From == schemaAttribute {
+			return fmt.Errorf("migration of %s cannot involve %s", m.From,
+				schemaAttribute)
+		}
+	}
+	keys := make(map[string]bool)
+	for _, v := range c.Validation {
+		if v.Key == "" {
+			return errors.New("validation key cannot be empty")
+		}
+		if keys[v.Key] {
+			return fmt.Errorf("validation of %s is configured more than once", v.Key)
+		}
+		keys[v.Key] = true
+		if v.ValidationRegex == "" && len(v.AllowedValues) == 0 {
+			return fmt.Errorf("validation of %s needs validation_regex or allowed_values", v.Key)
+		}
+		if v.ValidationRegex != "" {
+			if _, err := regexp.Compile(v.ValidationRegex); err != nil {
+				return fmt.Errorf("invalid validation_regex of %s: %w", v.Key, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateChangeDetection(strategy string, allowEmpty bool) error {
+	if strategy == "" && allowEmpty {
+		return nil
+	}
+	if strategy != changeDetectionNone && strategy != changeDetectionHash {
+		return fmt.Errorf("change_detection must be %q or %q",
+			changeDetectionNone, changeDetectionHash)
+	}
 	return nil
 }
 
+// changeDetection returns the change detection strategy and hash interval of a
+// configured file.
+func (c *Config) changeDetection(path string) (string, time.Duration) {
+	strategy, interval := c.ChangeDetection, c.HashInterval
+	for _, o := range c.PathOverrides {
+		if o.Path != path {
+			continue
+		}
+		if o.ChangeDetection != "" {
+			strategy = o.ChangeDetection
+		}
+		if o.HashInterval > 0 {
+			interval = o.HashInterval
+		}
+	}
+	if interval <= 0 {
+		interval = c.PollInterval
+	}
+	return strategy, interval
+}
+
+// owners returns the users one of which must own a configured file, or nil if
+// its ownership is not checked.
+func (c *Config) owners(path string) []string {
+	for _, o := range c.PathOverrides {
+		if o.Path == path && len(o.Owners) > 0 {
+			return o.Owners
+		}
+	}
+	return c.Owners
+}
+
+// format returns the format of a configured file and, for binary formats, the
+// name of its attribute.
+func (c *Config) format(path string) (string, string) {
+	for _, o := range c.PathOverrides {
+		if o.Path == path && o.Format != "" {
+			return o.Format, o.Key
+		}
+	}
+	return formatText, ""
+}
+
 func createDefaultConfig() component.Config {
 	return &Config{
-		FilePaths:    []string{},
-		PollInterval: 1 * time.Minute,
+		FilePaths:       []string{},
+		PollInterval:    1 * time.Minute,
+		ChangeDetection: changeDetectionNone,
+		ReadConcurrency: 4,
+		OutputFormat:    outputFormatEnv,
 	}
 }
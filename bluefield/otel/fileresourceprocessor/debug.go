@@ -0,0 +1,161 @@
+package fileresourceprocessor
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"otelcommon/httpregistry"
+)
+
+// attributesPath is the path of the debug endpoint listing the current
+// attributes
+const attributesPath = "/fileresource/attributes"
+
+var (
+	// debug endpoints by address, shared by the processors configuring
+	// the same debug_endpoint, e.g. the instances of the processor in the
+	// traces, metrics and logs pipelines
+	debugEndpointsLock sync.Mutex
+	debugEndpoints     = make(map[string]*debugEndpoint)
+)
+
+type debugEndpoint struct {
+	registration *httpregistry.Registration
+	processors   []*fileResourceProcessor
+}
+
+// attributeState is an attribute served as JSON along with the file it was
+// read from and when. The schema version is configured rather than read, so it
+// has neither.
+type attributeState struct {
+	Key    string     `json:"key"`
+	Value  string     `json:"value"`
+	Path   string     `json:"path,omitempty"`
+	ReadAt *time.Time `json:"read_at,omitempty"`
+}
+
+// registerDebugEndpoint serves the current attributes on the configured debug
+// endpoint, once for all processors configuring the same one.
+func registerDebugEndpoint(p *fileResourceProcessor) error {
+	endpoint := p.config.DebugEndpoint
+
+	debugEndpointsLock.Lock()
+	defer debugEndpointsLock.Unlock()
+
+	d, exists := debugEndpoints[endpoint]
+	if !exists {
+		d = &debugEndpoint{}
+		registration, err := httpregistry.Register(
+			httpregistry.ServerConfig{Endpoint: endpoint},
+			attributesPath,
+			http.HandlerFunc(d.serveAttributes),
+			p.logger,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to register debug endpoint: %w", err)
+		}
+		d.registration = registration
+		debugEndpoints[endpoint] = d
+	}
+	d.processors = append(d.processors, p)
+	return nil
+}
+
+// unregisterDebugEndpoint stops serving the debug endpoint once the last
+// processor configuring it is shut down.
+func unregisterDebugEndpoint(p *fileResourceProcessor) {
+	endpoint := p.config.DebugEndpoint
+
+	debugEndpointsLock.Lock()
+	d, exists := debugEndpoints[endpoint]
+	if !exists {
+		debugEndpointsLock.Unlock()
+		return
+	}
+	d.processors = slices.DeleteFunc(d.processors,
+		func(other *fileResourceProcessor) bool { return other == p })
+	if len(d.processors) > 0 {
+		debugEndpointsLock.Unlock()
+		return
+	}
+	delete(debugEndpoints, endpoint)
+	debugEndpointsLock.Unlock()
+
+	// Unregister without holding the lock, since unregistering waits for
+	// in progress requests that need the lock to complete.
+	d.registration.Unregister()
+}
+
+// serveAttributes lists the attributes of the processors configuring the
+// debug endpoint, sorted by key. An attribute read by several processors from
+// the same file is listed once, with the time of its latest read.
+func (d *debugEndpoint) serveAttributes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	debugEndpointsLock.Lock()
+	states := make(map[attributeState]attributeState)
+	for _, p := range d.processors {
+		for _, state := range p.attributeStates() {
+			id := attributeState{Key: state.Key, Value: state.Value, Path: state.Path}
+			if seen, exists := states[id]; exists && seen.ReadAt != nil &&
+				state.ReadAt != nil && seen.ReadAt.After(*state.ReadAt) {
+				continue
+			}
+			states[id] = state
+		}
+	}
+	debugEndpointsLock.Unlock()
+
+	list := make([]attributeState, 0, len(states))
+	for _, state := range states {
+		list = append(list, state)
+	}
+	slices.SortFunc(list, func(a, b attributeState) int {
+		return cmp.Or(cmp.Compare(a.Key, b.Key), cmp.Compare(a.Path, b.Path),
+			cmp.Compare(a.Value, b.Value))
+	})
+
+	data, err := json.Marshal(list)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// attributeStates returns the current attributes of the processor along with
+// the file each was read from and when.
+func (p *fileResourceProcessor) attributeStates() []attributeState {
+	p.attributesRWLock.RLock()
+	defer p.attributesRWLock.RUnlock()
+
+	states := make([]attributeState, 0, len(p.attributes))
+	read := make(map[string]bool, len(p.fileNames))
+	for path, name := range p.fileNames {
+		readAt := p.readTimes[path].UTC()
+		states = append(states, attributeState{
+			Key:    name,
+			Value:  p.attributes[name],
+			Path:   path,
+			ReadAt: &readAt,
+		})
+		read[name] = true
+	}
+	for name, value := range p.attributes {
+		if !read[name] {
+			states = append(states, attributeState{Key: name, Value: value})
+		}
+	}
+	return states
+}
@@ -35,7 +35,7 @@ func createTracesProcessor(
 	cfg component.Config,
 	nextConsumer consumer.Traces,
 ) (processor.Traces, error) {
-	proc, err := newProcessor(cfg.(*Config), settings.Logger)
+	proc, err := newProcessor(cfg, settings.TelemetrySettings)
 	if err != nil {
 		return nil, err
 	}
@@ -70,7 +70,7 @@ func createMetricsProcessor(
 	cfg component.Config,
 	nextConsumer consumer.Metrics,
 ) (processor.Metrics, error) {
-	proc, err := newProcessor(cfg, settings.Logger)
+	proc, err := newProcessor(cfg, settings.TelemetrySettings)
 	if err != nil {
 		return nil, err
 	}
@@ -105,7 +105,7 @@ func createLogsProcessor(
 	cfg component.Config,
 	nextConsumer consumer.Logs,
 ) (processor.Logs, error) {
-	proc, err := newProcessor(cfg, settings.Logger)
+	proc, err := newProcessor(cfg, settings.TelemetrySettings)
 	if err != nil {
 		return nil, err
 	}
@@ -2,69 +2,188 @@ package fileresourceprocessor
 
 import (
 	"bufio"
+	"bytes"
+	"cmp"
 	"context"
-        "fmt"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 	"go.uber.org/zap"
 )
 
 type fileResourceProcessor struct {
 	config           *Config
 	logger           *zap.Logger
-        unreadFiles      map[string]struct{}
-        attributesRWLock sync.RWMutex
+	unreadFiles      map[string]struct{}
+	watchedFiles     map[string]*watchedFile
+	lastAttempts     map[string]int // poll of the last read of each file
+	nextPoll         time.Time      // when unread files are read next
+	attributesRWLock sync.RWMutex
 	attributes       map[string]string
+	fileNames        map[string]string    // attribute name of each file, guarded by attributesRWLock
+	readTimes        map[string]time.Time // when each file was applied, guarded by attributesRWLock
+	validators       map[string]*validator
+	migrations       map[string]string // current names by legacy name
+	rejectedValues   map[string]string // last rejected value of each file
+	rejectedCounter  metric.Int64Counter
+	owners           map[string][]uint32 // uids one of which must own each checked file
+	untrustedFiles   map[string]string   // last reason each file was not trusted
+	untrustedCounter metric.Int64Counter
+	outputLock       sync.Mutex
+	clock            clock
+	fs               fileSystem
 	ctx              context.Context
 	cancel           context.CancelFunc
 }
 
-func newProcessor(cfg component.Config, logger *zap.Logger) (*fileResourceProcessor, error) {
+// watchedFile is a file read with change_detection "hash", which is hashed
+// again at its hash interval and read again when its content changed.
+type watchedFile struct {
+	hash     [sha256.Size]byte
+	interval time.Duration
+	next     time.Time
+}
+
+type validator struct {
+	re            *regexp.Regexp
+	allowedValues []string
+}
+
+// rejectedValueError is returned when the value read from a file fails
+// validation
+type rejectedValueError struct {
+	name  string
+	value string
+}
+
+func (e *rejectedValueError) Error() string {
+	return fmt.Sprintf("value %q of %s failed validation", e.value, e.name)
+}
+
+func newProcessor(cfg component.Config, settings component.TelemetrySettings) (*fileResourceProcessor, error) {
+	return newProcessorWith(cfg, settings, systemClock{}, osFileSystem{})
+}
+
+// newProcessorWith creates a processor reading files from a file system on the
+// ticks of a clock, which tests replace to poll deterministically.
+func newProcessorWith(
+	cfg component.Config,
+	settings component.TelemetrySettings,
+	clock clock,
+	fs fileSystem,
+) (*fileResourceProcessor, error) {
 	pCfg := cfg.(*Config)
+
+	// self-metric exposed with the collector's internal metrics
+	meter := settings.MeterProvider.Meter("fileresourceprocessor")
+	rejectedCounter, err := meter.Int64Counter(
+		"fileresource_rejected_values",
+		metric.WithDescription("Number of attribute values read from files that failed validation"),
+		metric.WithUnit("{values}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rejected values counter: %w", err)
+	}
+	untrustedCounter, err := meter.Int64Counter(
+		"fileresource_untrusted_files",
+		metric.WithDescription("Number of reads of attribute files that failed their ownership or permission checks"),
+		metric.WithUnit("{reads}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create untrusted files counter: %w", err)
+	}
+	owners, err := resolveOwners(pCfg)
+	if err != nil {
+		return nil, err
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	p := &fileResourceProcessor{
-		config:      pCfg,
-		logger:      logger,
-                unreadFiles: make(map[string]struct{}),
-                attributes:  make(map[string]string),
-		ctx:         ctx,
-		cancel:      cancel,
+		config:           pCfg,
+		logger:           settings.Logger,
+		unreadFiles:      make(map[string]struct{}),
+		watchedFiles:     make(map[string]*watchedFile),
+		lastAttempts:     make(map[string]int),
+		fileNames:        make(map[string]string),
+		readTimes:        make(map[string]time.Time),
+		attributes:       make(map[string]string),
+		validators:       make(map[string]*validator),
+		migrations:       make(map[string]string),
+		rejectedValues:   make(map[string]string),
+		rejectedCounter:  rejectedCounter,
+		owners:           owners,
+		untrustedFiles:   make(map[string]string),
+		untrustedCounter: untrustedCounter,
+		clock:            clock,
+		fs:               fs,
+		ctx:              ctx,
+		cancel:           cancel,
+	}
+
+	for _, path := range p.config.FilePaths {
+		p.unreadFiles[path] = struct{}{}
+	}
+	for _, v := range p.config.Validation {
+		p.validators[v.Key] = &validator{allowedValues: v.AllowedValues}
+		if v.ValidationRegex != "" {
+			p.validators[v.Key].re = regexp.MustCompile(v.ValidationRegex)
+		}
 	}
 
-        for _, path := range p.config.FilePaths {
-            p.unreadFiles[path] = struct{}{}
-        }
+	for _, m := range p.config.Migrations {
+		p.migrations[m.From] = m.To
+	}
+	if p.config.SchemaVersion != "" {
+		p.attributes[schemaAttribute] = p.config.SchemaVersion
+	}
 
+	if p.config.DebugEndpoint != "" {
+		if err := registerDebugEndpoint(p); err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+
+	p.nextPoll = p.clock.Now().Add(p.config.PollInterval)
 	go p.pollFiles()
 
 	return p, nil
 }
 
 func (p *fileResourceProcessor) pollFiles() {
-	ticker := time.NewTicker(p.config.PollInterval)
-	defer ticker.Stop()
+	// files are hashed more often than they are polled if so configured
+	interval := p.config.PollInterval
+	for _, path := range p.config.FilePaths {
+		if strategy, hashInterval := p.config.changeDetection(path); strategy == changeDetectionHash {
+			interval = min(interval, hashInterval)
+		}
+	}
+	ticks, stop := p.clock.NewTicker(interval)
+	defer stop()
 
-	for {
+	for poll := 1; ; poll++ {
 		select {
-		case <-ticker.C:
-                        for path := range p.unreadFiles {
-                                // Continue without complaint while a file doesn't exist
-                                if err := p.readFile(path); err == nil {
-                                        p.logger.Info(fmt.Sprintf("Stop polling %s after successful read", path))
-                                        delete(p.unreadFiles, path)
-                                } else if !os.IsNotExist(err) {
-                                        p.logger.Error("Failed to read file", zap.Error(err))
-                                }
-                        }
-                        if len(p.unreadFiles) == 0 {
-                                p.logger.Info("All files successfully read, stop polling")
-                                return
-                        }
+		case <-ticks:
+			p.poll(poll)
+			if len(p.unreadFiles) == 0 && len(p.watchedFiles) == 0 {
+				p.logger.Info("All files successfully read, stop polling")
+				return
+			}
 		case <-p.ctx.Done():
 			p.logger.Info("Stop polling due to context cancellation")
 			return
@@ -72,48 +191,359 @@ func (p *fileResourceProcessor) pollFiles() {
 	}
 }
 
+type readResult struct {
+	path string
+	hash [sha256.Size]byte
+	err  error
+}
+
+// poll reads the unread files when they are due to be polled and the watched
+// files due to be hashed, up to read_concurrency at a time, and starts no
+// further reads once the read budget is spent. Files are read in the order of
+// their last read, so that the files left over by a poll running out of budget
+// are read first on the next poll, and no file is starved by others.
+func (p *fileResourceProcessor) poll(poll int) {
+	budget := p.config.ReadBudget
+	if budget <= 0 {
+		budget = p.config.PollInterval
+	}
+	deadline, stop := p.clock.NewTimer(budget)
+	defer stop()
+
+	now := p.clock.Now()
+	paths := make([]string, 0, len(p.unreadFiles)+len(p.watchedFiles))
+	if !now.Before(p.nextPoll) {
+		p.nextPoll = now.Add(p.config.PollInterval)
+		for path := range p.unreadFiles {
+			paths = append(paths, path)
+		}
+	}
+	for path, watched := range p.watchedFiles {
+		if !now.Before(watched.next) {
+			paths = append(paths, path)
+		}
+	}
+	slices.SortFunc(paths, func(a, b string) int {
+		if c := cmp.Compare(p.lastAttempts[a], p.lastAttempts[b]); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+
+	results := make(chan readResult, len(paths))
+	readers := make(chan struct{}, p.config.ReadConcurrency)
+	started := 0
+start:
+	for _, path := range paths {
+		select {
+		case readers <- struct{}{}:
+		case <-deadline:
+			break start
+		case <-p.ctx.Done():
+			break start
+		}
+		p.lastAttempts[path] = poll
+		started++
+		var previous *[sha256.Size]byte
+		if watched, exists := p.watchedFiles[path]; exists {
+			hash := watched.hash
+			previous = &hash
+		}
+		go func() {
+			defer func() { <-readers }()
+			hash, err := p.readFile(path, previous)
+			results <- readResult{path: path, hash: hash, err: err}
+		}()
+	}
+	if started < len(paths) {
+		p.logger.Debug("Read budget spent, deferring files to the next poll",
+			zap.Int("deferred", len(paths)-started))
+	}
+
+	for i := 0; i < started; i++ {
+		result := <-results
+		if watched, exists := p.watchedFiles[result.path]; exists {
+			watched.next = p.clock.Now().Add(watched.interval)
+			p.checked(result, watched)
+			continue
+		}
+		// Continue without complaint while a file doesn't exist
+		var rejected *rejectedValueError
+		var untrusted *untrustedFileError
+		if result.err == nil {
+			delete(p.unreadFiles, result.path)
+			delete(p.untrustedFiles, result.path)
+			if strategy, interval := p.config.changeDetection(result.path); strategy == changeDetectionHash {
+				p.logger.Info(fmt.Sprintf("Hashing %s for changes after successful read", result.path))
+				p.watchedFiles[result.path] = &watchedFile{
+					hash:     result.hash,
+					interval: interval,
+					next:     p.clock.Now().Add(interval),
+				}
+			} else {
+				p.logger.Info(fmt.Sprintf("Stop polling %s after successful read", result.path))
+				delete(p.lastAttempts, result.path)
+			}
+		} else if errors.As(result.err, &rejected) {
+			p.reject(result.path, rejected)
+		} else if errors.As(result.err, &untrusted) {
+			p.distrust(untrusted)
+		} else if !os.IsNotExist(result.err) {
+			p.logger.Error("Failed to read file", zap.Error(result.err))
+		}
+	}
+}
+
+// checked handles the result of hashing a watched file. A watched file that
+// disappears or can't be read keeps its last attribute, and a changed value
+// failing validation, or a file failing its ownership checks, keeps the
+// previous value until the file holds a valid one.
+func (p *fileResourceProcessor) checked(result readResult, watched *watchedFile) {
+	var rejected *rejectedValueError
+	var untrusted *untrustedFileError
+	if result.err == nil {
+		delete(p.untrustedFiles, result.path)
+		if result.hash != watched.hash {
+			p.logger.Info("Read changed file", zap.String("path", result.path))
+			watched.hash = result.hash
+		}
+	} else if errors.As(result.err, &rejected) {
+		p.reject(result.path, rejected)
+	} else if errors.As(result.err, &untrusted) {
+		p.distrust(untrusted)
+	} else if os.IsNotExist(result.err) {
+		p.logger.Debug("Watched file doesn't exist, keeping its attribute",
+			zap.String("path", result.path))
+	} else {
+		p.logger.Error("Failed to read file", zap.Error(result.err))
+	}
+}
+
 func (p *fileResourceProcessor) cleanup() {
 	p.cancel() // stop polling
+	if p.config.DebugEndpoint != "" {
+		unregisterDebugEndpoint(p)
+	}
+}
+
+// readFile reads the attribute from a file and returns the hash of its content.
+// If the content has the previous hash, if any, the attribute is unchanged and
+// not applied again.
+func (p *fileResourceProcessor) readFile(
+	path string,
+	previous *[sha256.Size]byte,
+) ([sha256.Size]byte, error) {
+	data, err := p.readTrusted(path)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	hash := sha256.Sum256(data)
+	if previous != nil && *previous == hash {
+		return hash, nil
+	}
+	return hash, p.applyFile(path, data)
+}
+
+// applyFile applies the attribute read from the content of a file.
+func (p *fileResourceProcessor) applyFile(path string, data []byte) error {
+	var name, value string
+	var err error
+	if format, key := p.config.format(path); format == formatText {
+		name, value, err = parseAttribute(data, path)
+	} else {
+		name = key
+		value, err = parseBinaryValue(data, format, path)
+	}
+	if err != nil {
+		return err
+	}
+	if current, exists := p.migrations[name]; exists {
+		p.logger.Info("Migrated legacy attribute name read from file",
+			zap.String("path", path),
+			zap.String("legacy_key", name),
+			zap.String("key", current))
+		name = current
+	}
+	if name == schemaAttribute && p.config.SchemaVersion != "" {
+		p.logger.Warn("Ignoring schema version read from file, since one "+
+			"is configured", zap.String("path", path), zap.String("value", value))
+		return nil
+	}
+	if v, exists := p.validators[name]; exists && !v.valid(value) {
+		return &rejectedValueError{name: name, value: value}
+	}
+
+	p.attributesRWLock.Lock()
+	changed := p.attributes[name] != value
+	p.attributes[name] = value
+	// a file changed to hold another attribute no longer holds the old one
+	if previousName, exists := p.fileNames[path]; exists && previousName != name {
+		delete(p.attributes, previousName)
+		changed = true
+	}
+	p.fileNames[path] = name
+	p.readTimes[path] = p.clock.Now()
+	p.attributesRWLock.Unlock()
+
+	if changed && p.config.OutputFile != "" {
+		if err := p.writeOutputFile(); err != nil {
+			p.logger.Error("Failed to write output file", zap.Error(err))
+		}
+	}
+
+	return nil
 }
 
-func (p *fileResourceProcessor) readFile(path string) error {
-	file, err := os.Open(path)
+// writeOutputFile atomically replaces the output file with the current
+// attributes, sorted by name so that the file only changes when they do. Files
+// read at the same time write the output file one after the other.
+func (p *fileResourceProcessor) writeOutputFile() error {
+	p.outputLock.Lock()
+	defer p.outputLock.Unlock()
+
+	p.attributesRWLock.RLock()
+	names := make([]string, 0, len(p.attributes))
+	for name := range p.attributes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var data []byte
+	if p.config.OutputFormat == outputFormatJSON {
+		var err error
+		data, err = json.MarshalIndent(p.attributes, "", "  ")
+		if err != nil {
+			p.attributesRWLock.RUnlock()
+			return err
+		}
+		data = append(data, '\n')
+	} else {
+		for _, name := range names {
+			data = fmt.Appendf(data, "%s=%s\n", name, p.attributes[name])
+		}
+	}
+	p.attributesRWLock.RUnlock()
+
+	dir := filepath.Dir(p.config.OutputFile)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(p.config.OutputFile)+"-*")
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	defer os.Remove(tmp.Name())
 
-	scanner := bufio.NewScanner(file)
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	// readable by other agents, like the files the attributes are read from
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p.config.OutputFile)
+}
+
+// reject counts a value that failed validation, logging it unless the file
+// held the same value when it was last polled, and leaves the file to be
+// polled again.
+func (p *fileResourceProcessor) reject(path string, rejected *rejectedValueError) {
+	p.rejectedCounter.Add(p.ctx, 1,
+		metric.WithAttributes(attribute.String("key", rejected.name)))
+
+	if p.rejectedValues[path] == rejected.value {
+		return
+	}
+	p.rejectedValues[path] = rejected.value
+	p.logger.Error("Rejected attribute value read from file",
+		zap.String("path", path),
+		zap.String("key", rejected.name),
+		zap.String("value", rejected.value))
+}
+
+func (v *validator) valid(value string) bool {
+	if v.re != nil && !v.re.MatchString(value) {
+		return false
+	}
+	if len(v.allowedValues) > 0 && !slices.Contains(v.allowedValues, value) {
+		return false
+	}
+	return true
+}
+
+// ReadAttributeFile reads the first non-empty name=value pair from a file. It
+// is exported so that other components, such as the hostvars config converter,
+// interpret attribute files exactly like this processor.
+func ReadAttributeFile(path string) (string, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", err
+	}
+	return parseAttribute(data, path)
+}
+
+// parseAttribute parses the first non-empty name=value pair from the content of
+// a file.
+func parseAttribute(data []byte, path string) (string, string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
 	for scanner.Scan() {
 		line := scanner.Text()
 		parts := strings.SplitN(line, "=", 2)
 		if len(parts) == 2 {
-                        name := strings.TrimSpace(parts[0])
-                        value := strings.TrimSpace(parts[1])
-                        if name != "" && value != "" {
-                                p.attributesRWLock.Lock()
-                                p.attributes[name] = value
-                                p.attributesRWLock.Unlock()
-                                // only reads the first name=value line
-                                return nil
-                        }
+			name := strings.TrimSpace(parts[0])
+			value := strings.TrimSpace(parts[1])
+			if name != "" && value != "" {
+				// only reads the first name=value line
+				return name, value, nil
+			}
 		}
 	}
 
-        if err := scanner.Err(); err != nil {
-                return err
-        }
-        return fmt.Errorf("no valid key=value pair found in %s", path)
+	if err := scanner.Err(); err != nil {
+		return "", "", err
+	}
+	return "", "", fmt.Errorf("no valid key=value pair found in %s", path)
+}
+
+// parseBinaryValue formats the raw bytes of a binary file in canonical string
+// form: a GUID as 8-4-4-4-12 lowercase hex digits, with its first three fields
+// stored little-endian as in SMBIOS and EFI, and a MAC address as colon
+// separated lowercase hex bytes. Files of another size, e.g. still being
+// written, are an error so that they are polled again.
+func parseBinaryValue(data []byte, format string, path string) (string, error) {
+	switch format {
+	case formatBinaryGUID:
+		if len(data) != 16 {
+			return "", fmt.Errorf("%s holds %d bytes instead of the 16 "+
+				"bytes of a GUID", path, len(data))
+		}
+		return fmt.Sprintf("%08x-%04x-%04x-%x-%x",
+			binary.LittleEndian.Uint32(data[0:4]),
+			binary.LittleEndian.Uint16(data[4:6]),
+			binary.LittleEndian.Uint16(data[6:8]),
+			data[8:10],
+			data[10:16]), nil
+	case formatBinaryMAC:
+		if len(data) != 6 && len(data) != 8 {
+			return "", fmt.Errorf("%s holds %d bytes instead of the 6 or 8 "+
+				"bytes of a MAC address", path, len(data))
+		}
+		return net.HardwareAddr(data).String(), nil
+	default:
+		return "", fmt.Errorf("unsupported format %s of %s", format, path)
+	}
 }
 
 // processResource copies all attributes from the processor to the resource
 // (assumed to be a small number), overwriting any existing attributes with the
 // same names.
 func (p *fileResourceProcessor) processResource(resource pcommon.Resource) {
-        p.attributesRWLock.RLock()
-        defer p.attributesRWLock.RUnlock()
+	p.attributesRWLock.RLock()
+	defer p.attributesRWLock.RUnlock()
 
-        for name, value := range p.attributes {
-                resource.Attributes().PutStr(name, value)
-        }
+	for name, value := range p.attributes {
+		resource.Attributes().PutStr(name, value)
+	}
 }
@@ -0,0 +1,257 @@
+package fileresourceprocessor
+
+import (
+	"io/fs"
+	"maps"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.uber.org/zap"
+)
+
+func TestParseAttribute(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		wantName  string
+		wantValue string
+		wantErr   bool
+	}{
+		{"pair", "serial=MT2231X12345\n", "serial", "MT2231X12345", false},
+		{"no trailing newline", "site=sjc4", "site", "sjc4", false},
+		{"spaces trimmed", "  site =  sjc4  \n", "site", "sjc4", false},
+		{"first pair only", "site=sjc4\nsite=pdx1\n", "site", "sjc4", false},
+		{"leading empty lines", "\n\nsite=sjc4\n", "site", "sjc4", false},
+		{"lines without pair skipped", "# identity\nsite=sjc4\n", "site", "sjc4", false},
+		{"equals in value", "labels=a=b\n", "labels", "a=b", false},
+		{"empty value skipped", "site=\nrack=r12\n", "rack", "r12", false},
+		{"empty name skipped", "=sjc4\nrack=r12\n", "rack", "r12", false},
+		{"CRLF", "site=sjc4\r\n", "site", "sjc4", false},
+		{"empty", "", "", "", true},
+		{"no pair", "ERROR: device busy\n", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, value, err := parseAttribute([]byte(tt.data), "/run/attr")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAttribute(%q) error = %v, want error %t", tt.data, err, tt.wantErr)
+			}
+			if name != tt.wantName || value != tt.wantValue {
+				t.Errorf("parseAttribute(%q) = %q, %q, want %q, %q",
+					tt.data, name, value, tt.wantName, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestParseBinaryValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{"guid", []byte{0x44, 0x45, 0x4c, 0x4c, 0x4d, 0x00, 0x10, 0x38,
+			0x80, 0x36, 0xb4, 0xc0, 0x4f, 0x4e, 0x58, 0x32},
+			formatBinaryGUID, "4c4c4544-004d-3810-8036-b4c04f4e5832", false},
+		{"guid too short", make([]byte, 15), formatBinaryGUID, "", true},
+		{"guid too long", make([]byte, 17), formatBinaryGUID, "", true},
+		{"mac", []byte{0xb8, 0x3f, 0xd2, 0x0a, 0x1b, 0x2c},
+			formatBinaryMAC, "b8:3f:d2:0a:1b:2c", false},
+		{"eui-64", []byte{0xb8, 0x3f, 0xd2, 0xff, 0xfe, 0x0a, 0x1b, 0x2c},
+			formatBinaryMAC, "b8:3f:d2:ff:fe:0a:1b:2c", false},
+		{"mac partially written", []byte{0xb8, 0x3f, 0xd2}, formatBinaryMAC, "", true},
+		{"empty", nil, formatBinaryMAC, "", true},
+		{"unsupported format", make([]byte, 16), formatText, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseBinaryValue(tt.data, tt.format, "/run/eeprom")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseBinaryValue(%x) error = %v, want error %t", tt.data, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseBinaryValue(%x) = %q, want %q", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeClock is a clock whose time only moves when advanced. Its tickers and
+// timers never fire, so that tests call poll themselves.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) NewTicker(time.Duration) (<-chan time.Time, func()) {
+	return nil, func() {}
+}
+
+func (c *fakeClock) NewTimer(time.Duration) (<-chan time.Time, func()) {
+	return nil, func() {}
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// mapFileSystem holds files in memory by absolute path. Files must not be
+// changed while a poll reads them.
+type mapFileSystem struct {
+	files fstest.MapFS
+}
+
+func (m mapFileSystem) Open(path string) (fs.File, error) {
+	return m.files.Open(strings.TrimPrefix(path, "/"))
+}
+
+func (m mapFileSystem) write(path, data string) {
+	m.files[strings.TrimPrefix(path, "/")] = &fstest.MapFile{Data: []byte(data), Mode: 0644}
+}
+
+func (m mapFileSystem) remove(path string) {
+	delete(m.files, strings.TrimPrefix(path, "/"))
+}
+
+func newTestProcessor(t *testing.T, cfg *Config) (*fileResourceProcessor, *fakeClock, mapFileSystem) {
+	t.Helper()
+	clock := &fakeClock{now: time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)}
+	fs := mapFileSystem{files: fstest.MapFS{}}
+	settings := component.TelemetrySettings{
+		Logger:        zap.NewNop(),
+		MeterProvider: noop.NewMeterProvider(),
+	}
+	p, err := newProcessorWith(cfg, settings, clock, fs)
+	if err != nil {
+		t.Fatalf("newProcessorWith() error = %v", err)
+	}
+	t.Cleanup(p.cleanup)
+	return p, clock, fs
+}
+
+func attributes(p *fileResourceProcessor) map[string]string {
+	resource := pcommon.NewResource()
+	p.processResource(resource)
+	got := make(map[string]string)
+	resource.Attributes().Range(func(name string, value pcommon.Value) bool {
+		got[name] = value.Str()
+		return true
+	})
+	return got
+}
+
+func TestPoll(t *testing.T) {
+	const serial, site = "/run/attrs/serial", "/run/attrs/site"
+
+	type step struct {
+		advance time.Duration
+		write   map[string]string // content by path
+		remove  []string
+		want    map[string]string
+		unread  int
+		watched int
+	}
+	tests := []struct {
+		name  string
+		cfg   *Config
+		steps []step
+	}{
+		{
+			name: "unread files polled on the poll interval",
+			cfg: &Config{
+				FilePaths:       []string{serial, site},
+				PollInterval:    10 * time.Second,
+				ChangeDetection: changeDetectionNone,
+				ReadConcurrency: 1,
+			},
+			steps: []step{
+				{write: map[string]string{serial: "serial=S1\n"},
+					want: map[string]string{}, unread: 2},
+				{advance: 10 * time.Second,
+					want: map[string]string{"serial": "S1"}, unread: 1},
+				{advance: 5 * time.Second, write: map[string]string{site: "site=sjc4\n"},
+					want: map[string]string{"serial": "S1"}, unread: 1},
+				{advance: 5 * time.Second,
+					want: map[string]string{"serial": "S1", "site": "sjc4"}},
+				{advance: 10 * time.Second, write: map[string]string{site: "site=pdx1\n"},
+					want: map[string]string{"serial": "S1", "site": "sjc4"}},
+			},
+		},
+		{
+			name: "invalid values polled again",
+			cfg: &Config{
+				FilePaths:       []string{serial},
+				PollInterval:    10 * time.Second,
+				ChangeDetection: changeDetectionNone,
+				ReadConcurrency: 4,
+				Validation: []AttributeValidation{
+					{Key: "serial", ValidationRegex: "^S[0-9]+$"},
+				},
+			},
+			steps: []step{
+				{advance: 10 * time.Second, write: map[string]string{serial: "serial=ERROR\n"},
+					want: map[string]string{}, unread: 1},
+				{advance: 10 * time.Second, write: map[string]string{serial: "serial=S1\n"},
+					want: map[string]string{"serial": "S1"}},
+			},
+		},
+		{
+			name: "watched files hashed on the hash interval",
+			cfg: &Config{
+				FilePaths:       []string{serial, site},
+				PollInterval:    10 * time.Second,
+				ChangeDetection: changeDetectionNone,
+				PathOverrides: []PathOverride{
+					{Path: site, ChangeDetection: changeDetectionHash, HashInterval: 30 * time.Second},
+				},
+				ReadConcurrency: 4,
+			},
+			steps: []step{
+				{advance: 10 * time.Second,
+					write: map[string]string{serial: "serial=S1\n", site: "site=sjc4\n"},
+					want:  map[string]string{"serial": "S1", "site": "sjc4"}, watched: 1},
+				{advance: 20 * time.Second, write: map[string]string{site: "site=pdx1\n"},
+					want: map[string]string{"serial": "S1", "site": "sjc4"}, watched: 1},
+				{advance: 10 * time.Second,
+					want: map[string]string{"serial": "S1", "site": "pdx1"}, watched: 1},
+				{advance: 30 * time.Second, remove: []string{site},
+					want: map[string]string{"serial": "S1", "site": "pdx1"}, watched: 1},
+				{advance: 30 * time.Second, write: map[string]string{site: "rack=r12\n"},
+					want: map[string]string{"serial": "S1", "rack": "r12"}, watched: 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, clock, fs := newTestProcessor(t, tt.cfg)
+			for i, s := range tt.steps {
+				clock.advance(s.advance)
+				for path, data := range s.write {
+					fs.write(path, data)
+				}
+				for _, path := range s.remove {
+					fs.remove(path)
+				}
+				p.poll(i + 1)
+
+				if got := attributes(p); !maps.Equal(got, s.want) {
+					t.Errorf("step %d: attributes = %v, want %v", i, got, s.want)
+				}
+				if len(p.unreadFiles) != s.unread || len(p.watchedFiles) != s.watched {
+					t.Errorf("step %d: %d unread and %d watched files, want %d and %d",
+						i, len(p.unreadFiles), len(p.watchedFiles), s.unread, s.watched)
+				}
+			}
+		})
+	}
+}
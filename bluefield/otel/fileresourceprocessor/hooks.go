@@ -0,0 +1,51 @@
+package fileresourceprocessor
+
+import (
+	"io/fs"
+	"os"
+	"time"
+)
+
+// clock is the time source of the processor, which tests replace with a fake
+// clock to drive polls deterministically.
+type clock interface {
+	Now() time.Time
+
+	// NewTicker returns a channel receiving the time every d, and a
+	// function stopping the ticker
+	NewTicker(d time.Duration) (<-chan time.Time, func())
+
+	// NewTimer returns a channel receiving the time once after d, and a
+	// function stopping the timer
+	NewTimer(d time.Duration) (<-chan time.Time, func())
+}
+
+// systemClock is the clock of the system.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTicker(d time.Duration) (<-chan time.Time, func()) {
+	ticker := time.NewTicker(d)
+	return ticker.C, ticker.Stop
+}
+
+func (systemClock) NewTimer(d time.Duration) (<-chan time.Time, func()) {
+	timer := time.NewTimer(d)
+	return timer.C, func() { timer.Stop() }
+}
+
+// fileSystem opens the configured files, which tests replace with files in
+// memory. Unlike fs.FS, it opens absolute paths.
+type fileSystem interface {
+	Open(path string) (fs.File, error)
+}
+
+// osFileSystem is the file system of the system.
+type osFileSystem struct{}
+
+func (osFileSystem) Open(path string) (fs.File, error) {
+	return os.Open(path)
+}
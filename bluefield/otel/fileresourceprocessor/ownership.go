@@ -0,0 +1,106 @@
+package fileresourceprocessor
+
+import (
+	"fmt"
+	"io"
+	"os/user"
+	"slices"
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+// untrustedFileError is returned when a file fails its ownership or
+// permission checks, so that its content is not read
+type untrustedFileError struct {
+	path   string
+	reason string
+}
+
+func (e *untrustedFileError) Error() string {
+	return fmt.Sprintf("%s is not trusted: %s", e.path, e.reason)
+}
+
+// resolveOwners returns the uids of the owners of each configured file whose
+// ownership is checked. Owners are resolved once, so that a user created later
+// by a workload cannot become an owner.
+func resolveOwners(cfg *Config) (map[string][]uint32, error) {
+	uids := make(map[string][]uint32)
+	for _, path := range cfg.FilePaths {
+		for _, owner := range cfg.owners(path) {
+			uid, err := lookupUID(owner)
+			if err != nil {
+				return nil, fmt.Errorf("invalid owner of %s: %w", path, err)
+			}
+			uids[path] = append(uids[path], uid)
+		}
+	}
+	return uids, nil
+}
+
+// lookupUID returns the uid of a user name or numeric uid.
+func lookupUID(owner string) (uint32, error) {
+	if uid, err := strconv.ParseUint(owner, 10, 32); err == nil {
+		return uint32(uid), nil
+	}
+	u, err := user.Lookup(owner)
+	if err != nil {
+		return 0, err
+	}
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("uid %s of %s is not numeric", u.Uid, owner)
+	}
+	return uint32(uid), nil
+}
+
+// readTrusted reads a file, first checking that it is owned by one of its
+// owners and not world-writable if its ownership is checked. The checks are
+// made on the opened file, so that the file checked is the file read even if
+// the path is replaced in between.
+func (p *fileResourceProcessor) readTrusted(path string) ([]byte, error) {
+	f, err := p.fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	owners, checked := p.owners[path]
+	if !checked {
+		return io.ReadAll(f)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	uid, known := fileOwner(info)
+	if !known {
+		return nil, &untrustedFileError{path: path, reason: "its owner is unknown"}
+	}
+	if !slices.Contains(owners, uid) {
+		return nil, &untrustedFileError{path: path,
+			reason: fmt.Sprintf("it is owned by uid %d", uid)}
+	}
+	if info.Mode().Perm()&0o002 != 0 {
+		return nil, &untrustedFileError{path: path, reason: "it is world-writable"}
+	}
+	return io.ReadAll(f)
+}
+
+// distrust counts a file that failed its ownership or permission checks,
+// logging it unless it failed them for the same reason when it was last
+// polled, and leaves the file to be polled again.
+func (p *fileResourceProcessor) distrust(untrusted *untrustedFileError) {
+	p.untrustedCounter.Add(p.ctx, 1,
+		metric.WithAttributes(attribute.String("path", untrusted.path)))
+
+	if p.untrustedFiles[untrusted.path] == untrusted.reason {
+		return
+	}
+	p.untrustedFiles[untrusted.path] = untrusted.reason
+	p.logger.Error("Rejected attribute file failing ownership checks",
+		zap.String("path", untrusted.path),
+		zap.String("reason", untrusted.reason))
+}
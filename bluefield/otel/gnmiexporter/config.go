@@ -0,0 +1,160 @@
+package gnmiexporter
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+
+	"otelcommon/httpregistry"
+	"otelcommon/queuestats"
+)
+
+// Config defines the configuration of the gnmi exporter.
+type Config struct {
+	// Endpoint is the address the gNMI server listens on. Defaults to
+	// "localhost:9339", 9339 being the port registered for gNMI.
+	Endpoint string `mapstructure:"endpoint"`
+
+	// TLS optionally serves gNMI over TLS with a certificate and key, and
+	// requires client certificates signed by a CA if one is configured.
+	TLS httpregistry.TLSConfig `mapstructure:"tls"`
+
+	// Target is the name of the target in the prefix of notifications,
+	// which requests may set in their prefix. Defaults to the host name.
+	Target string `mapstructure:"target"`
+
+	// Paths map the datapoints of metrics to OpenConfig paths. Defaults to
+	// the interface counters of the hostmetrics network scraper, and the
+	// memory and temperature of the platform.
+	Paths []PathMapping `mapstructure:"paths"`
+
+	// MinSampleInterval is the shortest sample interval subscriptions may
+	// request, and the interval of subscriptions that don't request one.
+	// Defaults to "10s".
+	MinSampleInterval time.Duration `mapstructure:"min_sample_interval"`
+
+	// MaxSubscriptions limits the concurrent Subscribe streams. Defaults
+	// to 16.
+	MaxSubscriptions int `mapstructure:"max_subscriptions"`
+
+	// ValueTTL configures how long the last value of a path is served
+	// without an update, e.g. of an interface that was removed. Defaults
+	// to "10m".
+	ValueTTL time.Duration `mapstructure:"value_ttl"`
+
+	// QueueStatsEndpoint serves the statistics of the updates at
+	// /metrics/exporters, like the sending queues of other exporters.
+	// Empty disables them. Defaults to "localhost:8890".
+	QueueStatsEndpoint string `mapstructure:"queue_stats_endpoint"`
+}
+
+// PathMapping maps the datapoints of a metric to an OpenConfig path.
+type PathMapping struct {
+	// Metric is the name of the gauge or cumulative sum metric mapped.
+	Metric string `mapstructure:"metric"`
+
+	// Attributes restricts the mapping to datapoints with these attribute
+	// values, e.g. the direction of network counters.
+	Attributes map[string]string `mapstructure:"attributes"`
+
+	// Path is the OpenConfig path of the values. Key values in braces are
+	// taken from the attribute of that name of the datapoint, or else of
+	// its resource, e.g.
+	// "/interfaces/interface[name={device}]/state/counters/in-octets".
+	// Datapoints lacking an attribute are skipped.
+	Path string `mapstructure:"path"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.Endpoint == "" {
+		return errors.New("endpoint cannot be empty")
+	}
+	if err := cfg.TLS.Validate(); err != nil {
+		return fmt.Errorf("invalid tls: %w", err)
+	}
+	if len(cfg.Paths) == 0 {
+		return errors.New("paths must be specified")
+	}
+	for i, mapping := range cfg.Paths {
+		if mapping.Metric == "" {
+			return fmt.Errorf("paths[%d]: metric must be specified", i)
+		}
+		if _, err := parsePathTemplate(mapping.Path); err != nil {
+			return fmt.Errorf("paths[%d]: invalid path: %w", i, err)
+		}
+	}
+	if cfg.MinSampleInterval <= 0 {
+		return errors.New("min_sample_interval must be positive")
+	}
+	if cfg.MaxSubscriptions <= 0 {
+		return errors.New("max_subscriptions must be positive")
+	}
+	if cfg.ValueTTL <= 0 {
+		return errors.New("value_ttl must be positive")
+	}
+	return nil
+}
+
+// defaultPaths map the metrics of the hostmetrics network and memory scrapers,
+// and the temperature sensors of the card, to the openconfig-interfaces and
+// openconfig-platform models.
+func defaultPaths() []PathMapping {
+	var paths []PathMapping
+	for _, counter := range []struct {
+		metric, leaf string
+	}{
+		{"system.network.io", "octets"},
+		{"system.network.packets", "pkts"},
+		{"system.network.errors", "errors"},
+		{"system.network.dropped", "discards"},
+	} {
+		for _, direction := range []struct {
+			attribute, prefix string
+		}{
+			{"receive", "in"},
+			{"transmit", "out"},
+		} {
+			paths = append(paths, PathMapping{
+				Metric:     counter.metric,
+				Attributes: map[string]string{"direction": direction.attribute},
+				Path: "/interfaces/interface[name={device}]/state/counters/" +
+					direction.prefix + "-" + counter.leaf,
+			})
+		}
+	}
+	return append(paths,
+		PathMapping{
+			Metric:     "system.memory.usage",
+			Attributes: map[string]string{"state": "used"},
+			Path:       "/components/component[name=memory]/state/memory/utilized",
+		},
+		PathMapping{
+			Metric:     "system.memory.usage",
+			Attributes: map[string]string{"state": "free"},
+			Path:       "/components/component[name=memory]/state/memory/available",
+		},
+		PathMapping{
+			Metric: "hw.temperature",
+			Path:   "/components/component[name={sensor}]/state/temperature/instant",
+		},
+	)
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		Endpoint:          "localhost:9339",
+		Paths:             defaultPaths(),
+		MinSampleInterval: 10 * time.Second,
+		MaxSubscriptions:  16,
+		ValueTTL:          10 * time.Minute,
+
+		QueueStatsEndpoint: queuestats.DefaultEndpoint,
+	}
+}
@@ -0,0 +1,68 @@
+package gnmiexporter
+
+import (
+	"context"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/exporter"
+	"go.opentelemetry.io/collector/exporter/exporterhelper"
+
+	"otelcommon/queuestats"
+)
+
+const (
+	typeStr   = "gnmi"
+	stability = component.StabilityLevelAlpha
+)
+
+// metrics are only read into the latest values served
+var exporterCapabilities = consumer.Capabilities{MutatesData: false}
+
+func NewFactory() exporter.Factory {
+	return exporter.NewFactory(
+		component.MustNewType(typeStr),
+		createDefaultConfig,
+		exporter.WithMetrics(createMetricsExporter, stability),
+	)
+}
+
+// createMetricsExporter creates an exporter updating the latest values served
+// to gNMI clients. The values are updated synchronously, without a queue or
+// retries, so only attempts, updated items and errors are reported.
+func createMetricsExporter(
+	ctx context.Context,
+	set exporter.CreateSettings,
+	cfg component.Config,
+) (exporter.Metrics, error) {
+	e, err := newGNMIExporter(cfg.(*Config), set.Logger)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := queuestats.Register(queuestats.Settings{
+		Endpoint:  cfg.(*Config).QueueStatsEndpoint,
+		Exporter:  set.ID.String(),
+		Signal:    "metrics",
+		Untracked: true,
+	}, set.Logger)
+	if err != nil {
+		return nil, err
+	}
+	exp, err := exporterhelper.NewMetricsExporter(
+		ctx,
+		set,
+		cfg,
+		stats.PushMetrics(e.consumeMetrics),
+		exporterhelper.WithCapabilities(exporterCapabilities),
+		exporterhelper.WithStart(e.start),
+		exporterhelper.WithShutdown(func(ctx context.Context) error {
+			stats.Unregister()
+			return e.shutdown(ctx)
+		}),
+	)
+	if err != nil {
+		stats.Unregister()
+		return nil, err
+	}
+	return exp, nil
+}
@@ -0,0 +1,343 @@
+package gnmiexporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	pb "github.com/openconfig/gnmi/proto/gnmi"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+type gnmiExporter struct {
+	config   *Config
+	logger   *zap.Logger
+	target   string
+	mappings map[string][]pathMapping // by metric name
+	values   *valueCache
+
+	// limits the concurrent Subscribe streams
+	subscriptions chan struct{}
+
+	server      *grpc.Server
+	stopChannel chan struct{}
+	stopWaiters sync.WaitGroup
+}
+
+// pathMapping is a parsed PathMapping.
+type pathMapping struct {
+	attributes map[string]string
+	path       pathTemplate
+}
+
+// cachedValue is the latest value of a path.
+type cachedValue struct {
+	path      []*pb.PathElem
+	value     any // int64, uint64 for counters, or float64
+	timestamp int64
+	updated   time.Time
+	sequence  uint64 // of the update, increasing with each update
+}
+
+// valueCache holds the latest value of each path, which Get and Subscribe
+// requests are served from.
+type valueCache struct {
+	lock     sync.RWMutex
+	values   map[string]*cachedValue // by formatted path
+	sequence uint64
+	// closed and replaced on each update, for on-change subscriptions
+	changed chan struct{}
+}
+
+func newGNMIExporter(config *Config, logger *zap.Logger) (*gnmiExporter, error) {
+	e := &gnmiExporter{
+		config:   config,
+		logger:   logger,
+		target:   config.Target,
+		mappings: make(map[string][]pathMapping),
+		values: &valueCache{
+			values:  make(map[string]*cachedValue),
+			changed: make(chan struct{}),
+		},
+		subscriptions: make(chan struct{}, config.MaxSubscriptions),
+		stopChannel:   make(chan struct{}),
+	}
+	for _, mapping := range config.Paths {
+		path, err := parsePathTemplate(mapping.Path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path %s: %w", mapping.Path, err)
+		}
+		e.mappings[mapping.Metric] = append(e.mappings[mapping.Metric],
+			pathMapping{attributes: mapping.Attributes, path: path})
+	}
+	if e.target == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get host name for target: %w", err)
+		}
+		e.target = hostname
+	}
+	return e, nil
+}
+
+func (e *gnmiExporter) start(_ context.Context, _ component.Host) error {
+	var options []grpc.ServerOption
+	if e.config.TLS.Enabled() {
+		tlsConfig, err := e.config.TLS.Load()
+		if err != nil {
+			return err
+		}
+		options = append(options, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	listener, err := net.Listen("tcp", e.config.Endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to start gNMI server: %w", err)
+	}
+	e.server = grpc.NewServer(options...)
+	pb.RegisterGNMIServer(e.server, &gnmiServer{exporter: e})
+	go func() {
+		if err := e.server.Serve(listener); err != nil {
+			e.logger.Error("gNMI server error", zap.Error(err))
+		}
+	}()
+	e.logger.Info("Started gNMI server",
+		zap.String("address", e.config.Endpoint),
+		zap.String("target", e.target),
+		zap.Bool("tls", e.config.TLS.Enabled()),
+	)
+
+	e.stopWaiters.Add(1)
+	go e.expireLoop()
+	return nil
+}
+
+// shutdown stops the server, ending the streams of subscribers, which
+// reconnect to the next collector.
+func (e *gnmiExporter) shutdown(context.Context) error {
+	if e.server == nil {
+		return nil
+	}
+	close(e.stopChannel)
+	e.server.Stop()
+	e.stopWaiters.Wait()
+	return nil
+}
+
+// expireLoop removes the values not updated within value_ttl.
+func (e *gnmiExporter) expireLoop() {
+	defer e.stopWaiters.Done()
+
+	ticker := time.NewTicker(min(e.config.ValueTTL, time.Minute))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			e.values.expire(time.Now().Add(-e.config.ValueTTL))
+		case <-e.stopChannel:
+			return
+		}
+	}
+}
+
+// consumeMetrics updates the values of the datapoints of mapped metrics.
+// Delta sums and histograms have no OpenConfig counterpart and are skipped.
+func (e *gnmiExporter) consumeMetrics(_ context.Context, md pmetric.Metrics) error {
+	now := time.Now()
+	var updates []*cachedValue
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		resource := rms.At(i).Resource().Attributes()
+		sms := rms.At(i).ScopeMetrics()
+		for j := 0; j < sms.Len(); j++ {
+			metrics := sms.At(j).Metrics()
+			for k := 0; k < metrics.Len(); k++ {
+				metric := metrics.At(k)
+				mappings := e.mappings[metric.Name()]
+				if len(mappings) == 0 {
+					continue
+				}
+				var dps pmetric.NumberDataPointSlice
+				counter := false
+				switch metric.Type() {
+				case pmetric.MetricTypeGauge:
+					dps = metric.Gauge().DataPoints()
+				case pmetric.MetricTypeSum:
+					sum := metric.Sum()
+					if sum.AggregationTemporality() !=
+						pmetric.AggregationTemporalityCumulative {
+						continue
+					}
+					dps = sum.DataPoints()
+					counter = sum.IsMonotonic()
+				default:
+					continue
+				}
+				for l := 0; l < dps.Len(); l++ {
+					updates = appendUpdates(updates, mappings, dps.At(l),
+						resource, counter, now)
+				}
+			}
+		}
+	}
+	e.values.update(updates)
+	return nil
+}
+
+// appendUpdates appends the values of a datapoint at the paths it is mapped
+// to.
+func appendUpdates(
+	updates []*cachedValue,
+	mappings []pathMapping,
+	dp pmetric.NumberDataPoint,
+	resource pcommon.Map,
+	counter bool,
+	now time.Time,
+) []*cachedValue {
+	if dp.Flags().NoRecordedValue() {
+		return updates
+	}
+	var value any
+	switch dp.ValueType() {
+	case pmetric.NumberDataPointValueTypeInt:
+		if counter && dp.IntValue() >= 0 {
+			value = uint64(dp.IntValue())
+		} else {
+			value = dp.IntValue()
+		}
+	case pmetric.NumberDataPointValueTypeDouble:
+		// JSON has no NaN or infinities
+		if math.IsNaN(dp.DoubleValue()) || math.IsInf(dp.DoubleValue(), 0) {
+			return updates
+		}
+		value = dp.DoubleValue()
+	default:
+		return updates
+	}
+	timestamp := int64(dp.Timestamp())
+	if timestamp == 0 {
+		timestamp = now.UnixNano()
+	}
+	for _, mapping := range mappings {
+		if !matchAttributes(mapping.attributes, dp.Attributes()) {
+			continue
+		}
+		path, ok := mapping.path.resolve(dp.Attributes(), resource)
+		if !ok {
+			continue
+		}
+		updates = append(updates, &cachedValue{
+			path:      path,
+			value:     value,
+			timestamp: timestamp,
+			updated:   now,
+		})
+	}
+	return updates
+}
+
+func matchAttributes(expected map[string]string, attrs pcommon.Map) bool {
+	for name, value := range expected {
+		attr, exists := attrs.Get(name)
+		if !exists || attr.AsString() != value {
+			return false
+		}
+	}
+	return true
+}
+
+// update stores values, and notifies on-change subscriptions.
+func (c *valueCache) update(values []*cachedValue) {
+	if len(values) == 0 {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, value := range values {
+		c.sequence++
+		value.sequence = c.sequence
+		c.values[formatPath(value.path)] = value
+	}
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// expire removes the values last updated before a time.
+func (c *valueCache) expire(before time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for key, value := range c.values {
+		if value.updated.Before(before) {
+			delete(c.values, key)
+		}
+	}
+}
+
+// matching returns the values at or below any of the paths of a request, and
+// the channel closed on the next update.
+func (c *valueCache) matching(patterns [][]*pb.PathElem) (map[string]*cachedValue, <-chan struct{}) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	matches := make(map[string]*cachedValue)
+	for key, value := range c.values {
+		for _, pattern := range patterns {
+			if matchPath(pattern, value.path) {
+				matches[key] = value
+				break
+			}
+		}
+	}
+	return matches, c.changed
+}
+
+// typedValue encodes a value in the encoding of a request. JSON_IETF encodes
+// 64-bit integers as strings, as RFC 7951 requires.
+func (v *cachedValue) typedValue(encoding pb.Encoding) (*pb.TypedValue, error) {
+	switch encoding {
+	case pb.Encoding_PROTO:
+		switch value := v.value.(type) {
+		case int64:
+			return &pb.TypedValue{Value: &pb.TypedValue_IntVal{IntVal: value}}, nil
+		case uint64:
+			return &pb.TypedValue{Value: &pb.TypedValue_UintVal{UintVal: value}}, nil
+		case float64:
+			return &pb.TypedValue{Value: &pb.TypedValue_DoubleVal{DoubleVal: value}}, nil
+		}
+	case pb.Encoding_JSON:
+		data, err := json.Marshal(v.value)
+		if err != nil {
+			return nil, err
+		}
+		return &pb.TypedValue{Value: &pb.TypedValue_JsonVal{JsonVal: data}}, nil
+	case pb.Encoding_JSON_IETF:
+		var data []byte
+		var err error
+		switch value := v.value.(type) {
+		case int64:
+			data, err = json.Marshal(strconv.FormatInt(value, 10))
+		case uint64:
+			data, err = json.Marshal(strconv.FormatUint(value, 10))
+		default:
+			data, err = json.Marshal(value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &pb.TypedValue{Value: &pb.TypedValue_JsonIetfVal{JsonIetfVal: data}}, nil
+	}
+	return nil, fmt.Errorf("unsupported encoding %s", encoding)
+}
@@ -0,0 +1,3 @@
+module gnmiexporter
+
+go 1.22
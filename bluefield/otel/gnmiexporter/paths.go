@@ -0,0 +1,170 @@
+package gnmiexporter
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	pb "github.com/openconfig/gnmi/proto/gnmi"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+// pathTemplate is a parsed OpenConfig path, whose key values may reference
+// attributes.
+type pathTemplate []elemTemplate
+
+type elemTemplate struct {
+	name string
+	keys []keyTemplate
+}
+
+// keyTemplate is a key of a path element, whose value is the attribute of that
+// name if it is an attribute.
+type keyTemplate struct {
+	name      string
+	value     string
+	attribute bool
+}
+
+// parsePathTemplate parses a path such as
+// "/interfaces/interface[name={device}]/state/counters/in-octets". Key values
+// can't contain "]".
+func parsePathTemplate(path string) (pathTemplate, error) {
+	rest, found := strings.CutPrefix(path, "/")
+	if !found {
+		return nil, errors.New("path must start with /")
+	}
+	var template pathTemplate
+	for rest != "" {
+		var elem elemTemplate
+		end := strings.IndexAny(rest, "/[")
+		if end < 0 {
+			end = len(rest)
+		}
+		elem.name, rest = rest[:end], rest[end:]
+		if elem.name == "" {
+			return nil, errors.New("path elements cannot be empty")
+		}
+		for strings.HasPrefix(rest, "[") {
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated key of %s", elem.name)
+			}
+			name, value, found := strings.Cut(rest[1:end], "=")
+			if !found || name == "" || value == "" {
+				return nil, fmt.Errorf("keys of %s must be name=value",
+					elem.name)
+			}
+			key := keyTemplate{name: name, value: value}
+			if attribute, found := strings.CutPrefix(value, "{"); found {
+				key.value, found = strings.CutSuffix(attribute, "}")
+				if !found || key.value == "" {
+					return nil, fmt.Errorf("invalid attribute reference "+
+						"%s of %s", value, elem.name)
+				}
+				key.attribute = true
+			}
+			elem.keys = append(elem.keys, key)
+			rest = rest[end+1:]
+		}
+		template = append(template, elem)
+		if rest != "" {
+			if rest, found = strings.CutPrefix(rest, "/"); !found {
+				return nil, fmt.Errorf("unexpected %q after %s", rest,
+					elem.name)
+			}
+			if rest == "" {
+				return nil, errors.New("path cannot end with /")
+			}
+		}
+	}
+	if len(template) == 0 {
+		return nil, errors.New("path cannot be empty")
+	}
+	return template, nil
+}
+
+// resolve returns the path elements of a datapoint, with key values taken from
+// its attributes, or else those of its resource. It returns false if an
+// attribute is missing.
+func (t pathTemplate) resolve(attrs, resource pcommon.Map) ([]*pb.PathElem, bool) {
+	elems := make([]*pb.PathElem, 0, len(t))
+	for _, elemTemplate := range t {
+		elem := &pb.PathElem{Name: elemTemplate.name}
+		for _, key := range elemTemplate.keys {
+			value := key.value
+			if key.attribute {
+				attr, exists := attrs.Get(key.value)
+				if !exists {
+					attr, exists = resource.Get(key.value)
+				}
+				if !exists {
+					return nil, false
+				}
+				value = attr.AsString()
+			}
+			if elem.Key == nil {
+				elem.Key = make(map[string]string, len(elemTemplate.keys))
+			}
+			elem.Key[key.name] = value
+		}
+		elems = append(elems, elem)
+	}
+	return elems, true
+}
+
+// formatPath returns the string form of path elements, with keys sorted by
+// name, which identifies the values served.
+func formatPath(elems []*pb.PathElem) string {
+	var b strings.Builder
+	for _, elem := range elems {
+		b.WriteByte('/')
+		b.WriteString(elem.GetName())
+		names := make([]string, 0, len(elem.GetKey()))
+		for name := range elem.GetKey() {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			fmt.Fprintf(&b, "[%s=%s]", name, elem.GetKey()[name])
+		}
+	}
+	return b.String()
+}
+
+// matchPath returns whether a path is at or below the path of a request, whose
+// elements may be "*", matching any element, or "...", matching any number of
+// elements, and whose key values may be "*". Keys missing from the request
+// match any value.
+func matchPath(pattern, path []*pb.PathElem) bool {
+	for i, elem := range pattern {
+		if elem.GetName() == "..." {
+			for j := i; j <= len(path); j++ {
+				if matchPath(pattern[i+1:], path[j:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if i >= len(path) {
+			return false
+		}
+		if elem.GetName() != "*" && elem.GetName() != path[i].GetName() {
+			return false
+		}
+		for name, value := range elem.GetKey() {
+			actual, exists := path[i].GetKey()[name]
+			if !exists || (value != "*" && value != actual) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// joinPath returns the elements of a path of a request below its prefix.
+func joinPath(prefix, path *pb.Path) []*pb.PathElem {
+	elems := slices.Clone(prefix.GetElem())
+	return append(elems, path.GetElem()...)
+}
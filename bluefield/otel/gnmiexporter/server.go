@@ -0,0 +1,412 @@
+package gnmiexporter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	pb "github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// gnmiVersion is the version of the gNMI specification implemented.
+const gnmiVersion = "0.10.0"
+
+// supportedModels are the models of the default paths.
+var supportedModels = []*pb.ModelData{
+	{Name: "openconfig-interfaces", Organization: "OpenConfig working group"},
+	{Name: "openconfig-platform", Organization: "OpenConfig working group"},
+}
+
+var supportedEncodings = []pb.Encoding{
+	pb.Encoding_JSON,
+	pb.Encoding_JSON_IETF,
+	pb.Encoding_PROTO,
+}
+
+// gnmiServer serves the values of an exporter to gNMI clients. The values are
+// state, read-only, so Set is not implemented.
+type gnmiServer struct {
+	pb.UnimplementedGNMIServer
+	exporter *gnmiExporter
+}
+
+func (s *gnmiServer) Capabilities(context.Context, *pb.CapabilityRequest) (*pb.CapabilityResponse, error) {
+	return &pb.CapabilityResponse{
+		SupportedModels:    supportedModels,
+		SupportedEncodings: supportedEncodings,
+		GNMIVersion:        gnmiVersion,
+	}, nil
+}
+
+// Get returns a notification per path of the request with the values at or
+// below it.
+func (s *gnmiServer) Get(_ context.Context, req *pb.GetRequest) (*pb.GetResponse, error) {
+	if err := s.checkRequest(req.GetPrefix(), req.GetEncoding()); err != nil {
+		return nil, err
+	}
+	response := &pb.GetResponse{}
+	now := time.Now().UnixNano()
+	for _, path := range req.GetPath() {
+		pattern := joinPath(req.GetPrefix(), path)
+		values, _ := s.exporter.values.matching([][]*pb.PathElem{pattern})
+		if len(values) == 0 {
+			return nil, status.Errorf(codes.NotFound, "no values at %s",
+				formatPath(pattern))
+		}
+		notification := &pb.Notification{
+			Timestamp: now,
+			Prefix:    s.notificationPrefix(req.GetPrefix()),
+		}
+		for _, value := range sortedValues(values) {
+			update, err := newUpdate(value, req.GetEncoding())
+			if err != nil {
+				return nil, err
+			}
+			notification.Update = append(notification.Update, update)
+		}
+		response.Notification = append(response.Notification, notification)
+	}
+	return response, nil
+}
+
+// Subscribe serves the values of the subscriptions of the first request once,
+// on each poll request, or streamed by sample interval or on change.
+func (s *gnmiServer) Subscribe(stream pb.GNMI_SubscribeServer) error {
+	select {
+	case s.exporter.subscriptions <- struct{}{}:
+		defer func() { <-s.exporter.subscriptions }()
+	default:
+		return status.Errorf(codes.ResourceExhausted, "at most %d "+
+			"subscriptions are served", s.exporter.config.MaxSubscriptions)
+	}
+
+	req, err := stream.Recv()
+	if errors.Is(err, io.EOF) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	list := req.GetSubscribe()
+	if list == nil {
+		return status.Error(codes.InvalidArgument,
+			"the first request must be a subscription list")
+	}
+	if err := s.checkRequest(list.GetPrefix(), list.GetEncoding()); err != nil {
+		return err
+	}
+	if len(list.GetSubscription()) == 0 {
+		return status.Error(codes.InvalidArgument, "no subscriptions")
+	}
+	sender := &subscriptionSender{
+		stream:   stream,
+		prefix:   s.notificationPrefix(list.GetPrefix()),
+		encoding: list.GetEncoding(),
+	}
+	var patterns [][]*pb.PathElem
+	for _, subscription := range list.GetSubscription() {
+		patterns = append(patterns,
+			joinPath(list.GetPrefix(), subscription.GetPath()))
+	}
+
+	switch list.GetMode() {
+	case pb.SubscriptionList_ONCE:
+		return s.sendSnapshot(sender, patterns)
+	case pb.SubscriptionList_POLL:
+		for {
+			if err := s.sendSnapshot(sender, patterns); err != nil {
+				return err
+			}
+			req, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			if req.GetPoll() == nil {
+				return status.Error(codes.InvalidArgument,
+					"only poll requests may follow a poll subscription")
+			}
+		}
+	case pb.SubscriptionList_STREAM:
+		return s.stream(stream, sender, list, patterns)
+	}
+	return status.Errorf(codes.InvalidArgument, "unsupported mode %s",
+		list.GetMode())
+}
+
+// sendSnapshot sends the current values of subscriptions, followed by a sync
+// response.
+func (s *gnmiServer) sendSnapshot(sender *subscriptionSender, patterns [][]*pb.PathElem) error {
+	values, _ := s.exporter.values.matching(patterns)
+	if err := sender.send(sortedValues(values)); err != nil {
+		return err
+	}
+	return sender.sync()
+}
+
+// stream sends the current values of a stream subscription list unless it asks
+// for updates only, and then those of each subscription as it requests, until
+// the client cancels the stream or the server stops.
+func (s *gnmiServer) stream(
+	stream pb.GNMI_SubscribeServer,
+	sender *subscriptionSender,
+	list *pb.SubscriptionList,
+	patterns [][]*pb.PathElem,
+) error {
+	subscriptions := make([]*streamSubscription, 0, len(patterns))
+	for i, subscription := range list.GetSubscription() {
+		streamed, err := s.newStreamSubscription(subscription, patterns[i])
+		if err != nil {
+			return err
+		}
+		subscriptions = append(subscriptions, streamed)
+	}
+
+	values, _ := s.exporter.values.matching(patterns)
+	if !list.GetUpdatesOnly() {
+		if err := sender.send(sortedValues(values)); err != nil {
+			return err
+		}
+	}
+	if err := sender.sync(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	errs := make(chan error, len(subscriptions)+1)
+	var waiters sync.WaitGroup
+	for _, subscription := range subscriptions {
+		subscription.markSent(values)
+		waiters.Add(1)
+		go func() {
+			defer waiters.Done()
+			errs <- subscription.run(ctx, s.exporter.values, sender)
+		}()
+	}
+	// no requests may follow a stream subscription, but receiving is
+	// how a client closing the stream is noticed
+	go func() {
+		_, err := stream.Recv()
+		if err == nil {
+			err = status.Error(codes.InvalidArgument,
+				"no requests may follow a stream subscription")
+		} else if errors.Is(err, io.EOF) {
+			err = nil
+		}
+		errs <- err
+	}()
+
+	err := <-errs
+	cancel()
+	waiters.Wait()
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+// checkRequest checks that a request is for the target of the exporter, if it
+// names one, and in a supported encoding.
+func (s *gnmiServer) checkRequest(prefix *pb.Path, encoding pb.Encoding) error {
+	if target := prefix.GetTarget(); target != "" && target != s.exporter.target {
+		return status.Errorf(codes.NotFound, "unknown target %s", target)
+	}
+	if !slices.Contains(supportedEncodings, encoding) {
+		return status.Errorf(codes.Unimplemented, "unsupported encoding %s",
+			encoding)
+	}
+	return nil
+}
+
+// notificationPrefix returns the prefix of the notifications answering a
+// request, which names the target if the request does.
+func (s *gnmiServer) notificationPrefix(prefix *pb.Path) *pb.Path {
+	if prefix.GetTarget() == "" {
+		return nil
+	}
+	return &pb.Path{Target: s.exporter.target}
+}
+
+// streamSubscription is a subscription of a stream subscription list, and the
+// values sent for it.
+type streamSubscription struct {
+	pattern           []*pb.PathElem
+	mode              pb.SubscriptionMode
+	sampleInterval    time.Duration
+	suppressRedundant bool
+	heartbeatInterval time.Duration
+
+	// the sequence of the last update of each path sent
+	sent map[string]uint64
+}
+
+func (s *gnmiServer) newStreamSubscription(
+	subscription *pb.Subscription,
+	pattern []*pb.PathElem,
+) (*streamSubscription, error) {
+	minInterval := s.exporter.config.MinSampleInterval
+	streamed := &streamSubscription{
+		pattern:           pattern,
+		mode:              subscription.GetMode(),
+		sampleInterval:    time.Duration(subscription.GetSampleInterval()),
+		suppressRedundant: subscription.GetSuppressRedundant(),
+		heartbeatInterval: time.Duration(subscription.GetHeartbeatInterval()),
+	}
+	switch streamed.mode {
+	case pb.SubscriptionMode_SAMPLE:
+		if streamed.sampleInterval == 0 {
+			streamed.sampleInterval = minInterval
+		}
+		if streamed.sampleInterval < minInterval {
+			return nil, status.Errorf(codes.InvalidArgument,
+				"sample_interval of %s is shorter than %s",
+				formatPath(pattern), minInterval)
+		}
+	case pb.SubscriptionMode_ON_CHANGE, pb.SubscriptionMode_TARGET_DEFINED:
+		// values change with each collection, so that on change
+		// is what the target defines
+	default:
+		return nil, status.Errorf(codes.InvalidArgument,
+			"unsupported mode %s", streamed.mode)
+	}
+	if streamed.heartbeatInterval != 0 && streamed.heartbeatInterval < minInterval {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"heartbeat_interval of %s is shorter than %s",
+			formatPath(pattern), minInterval)
+	}
+	return streamed, nil
+}
+
+// markSent records values as sent, e.g. those sent before the sync response.
+func (s *streamSubscription) markSent(values map[string]*cachedValue) {
+	s.sent = make(map[string]uint64)
+	for key, value := range values {
+		if matchPath(s.pattern, value.path) {
+			s.sent[key] = value.sequence
+		}
+	}
+}
+
+// run sends the values of the subscription each sample interval, or as they
+// change. Sampled subscriptions suppressing redundant values, and on-change
+// subscriptions, only send values updated since they were last sent, except
+// each heartbeat interval.
+func (s *streamSubscription) run(ctx context.Context, cache *valueCache, sender *subscriptionSender) error {
+	var sample, heartbeat <-chan time.Time
+	if s.mode == pb.SubscriptionMode_SAMPLE {
+		ticker := time.NewTicker(s.sampleInterval)
+		defer ticker.Stop()
+		sample = ticker.C
+	}
+	if s.heartbeatInterval > 0 {
+		ticker := time.NewTicker(s.heartbeatInterval)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
+	patterns := [][]*pb.PathElem{s.pattern}
+	onChange := s.mode != pb.SubscriptionMode_SAMPLE
+	var changed <-chan struct{}
+	if onChange {
+		_, changed = cache.matching(patterns)
+	}
+	for {
+		all := false
+		select {
+		case <-sample:
+			all = !s.suppressRedundant
+		case <-heartbeat:
+			all = true
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		values, next := cache.matching(patterns)
+		if onChange {
+			changed = next
+		}
+		pending := make(map[string]*cachedValue)
+		for key, value := range values {
+			if all || value.sequence > s.sent[key] {
+				pending[key] = value
+			}
+		}
+		s.markSent(values)
+		if err := sender.send(sortedValues(pending)); err != nil {
+			return err
+		}
+	}
+}
+
+// subscriptionSender sends the responses of a Subscribe stream, which the
+// subscriptions of a stream subscription list share.
+type subscriptionSender struct {
+	lock     sync.Mutex
+	stream   pb.GNMI_SubscribeServer
+	prefix   *pb.Path
+	encoding pb.Encoding
+}
+
+// send sends a notification per value, with the timestamp of its datapoint.
+func (s *subscriptionSender) send(values []*cachedValue) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, value := range values {
+		update, err := newUpdate(value, s.encoding)
+		if err != nil {
+			return err
+		}
+		if err := s.stream.Send(&pb.SubscribeResponse{
+			Response: &pb.SubscribeResponse_Update{
+				Update: &pb.Notification{
+					Timestamp: value.timestamp,
+					Prefix:    s.prefix,
+					Update:    []*pb.Update{update},
+				},
+			},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *subscriptionSender) sync() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.stream.Send(&pb.SubscribeResponse{
+		Response: &pb.SubscribeResponse_SyncResponse{SyncResponse: true},
+	})
+}
+
+func newUpdate(value *cachedValue, encoding pb.Encoding) (*pb.Update, error) {
+	typed, err := value.typedValue(encoding)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.Update{Path: &pb.Path{Elem: value.path}, Val: typed}, nil
+}
+
+// sortedValues returns values sorted by path, so that clients see them in a
+// stable order.
+func sortedValues(values map[string]*cachedValue) []*cachedValue {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	sorted := make([]*cachedValue, 0, len(keys))
+	for _, key := range keys {
+		sorted = append(sorted, values[key])
+	}
+	return sorted
+}
@@ -0,0 +1,3 @@
+package gnmiexporter
+
+const Version = "0.0.1"
@@ -0,0 +1,43 @@
+package heartbeatreceiver
+
+import (
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+// Config defines the configuration of the heartbeat receiver.
+type Config struct {
+	// Interval configures how often a heartbeat is emitted. Defaults to
+	// "1m".
+	Interval time.Duration `mapstructure:"interval"`
+
+	// ConfigFiles optionally lists the configuration files whose content
+	// is hashed into the config hash of heartbeats. If empty, the files
+	// given to the collector with --config are hashed.
+	ConfigFiles []string `mapstructure:"config_files"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.Interval <= 0 {
+		return errors.New("interval must be positive")
+	}
+	for _, file := range cfg.ConfigFiles {
+		if file == "" {
+			return errors.New("config_files cannot contain empty paths")
+		}
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		Interval: time.Minute,
+	}
+}
@@ -0,0 +1,3 @@
+module heartbeatreceiver
+
+go 1.22
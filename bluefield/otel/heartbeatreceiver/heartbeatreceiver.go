@@ -0,0 +1,263 @@
+package heartbeatreceiver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+
+	"telemetrystatsprocessor"
+)
+
+const (
+	scopeName = "heartbeatreceiver"
+
+	// the number of hex characters config hashes are truncated to
+	configHashLength = 16
+)
+
+// processStart approximates the start of the collector, as packages are
+// initialized when it starts.
+var processStart = time.Now()
+
+type heartbeatReceiver struct {
+	config           *Config
+	logger           *zap.Logger
+	collectorVersion string
+	metricsConsumer  consumer.Metrics
+	logsConsumer     consumer.Logs
+	startOnce        sync.Once
+	stopOnce         sync.Once
+	stopChannel      chan struct{}
+	stopWaiters      sync.WaitGroup
+
+	// only accessed by the heartbeat loop
+	configHash    string
+	lastProcessed int64
+	lastBeat      time.Time
+}
+
+// heartbeat is the state of the collector reported by a heartbeat.
+type heartbeat struct {
+	time       time.Time
+	uptime     time.Duration
+	processed  int64   // by telemetry_stats since the collector started
+	throughput float64 // per second since the previous heartbeat
+}
+
+func newHeartbeatReceiver(config *Config, logger *zap.Logger, collectorVersion string) *heartbeatReceiver {
+	return &heartbeatReceiver{
+		config:           config,
+		logger:           logger,
+		collectorVersion: collectorVersion,
+		stopChannel:      make(chan struct{}),
+	}
+}
+
+func (r *heartbeatReceiver) Start(_ context.Context, _ component.Host) error {
+	r.startOnce.Do(func() {
+		files := r.config.ConfigFiles
+		if len(files) == 0 {
+			files = configFilesFromArgs(os.Args[1:])
+		}
+		hash, err := hashConfigFiles(files)
+		if err != nil {
+			r.logger.Warn("Failed to hash the configuration, heartbeats "+
+				"report no config hash", zap.Error(err))
+		}
+		r.configHash = hash
+		r.lastProcessed = telemetrystatsprocessor.ProcessedTotal()
+		r.lastBeat = time.Now()
+
+		r.stopWaiters.Add(1)
+		go r.heartbeatLoop()
+	})
+	return nil
+}
+
+func (r *heartbeatReceiver) Shutdown(context.Context) error {
+	r.stopOnce.Do(func() {
+		close(r.stopChannel)
+		r.stopWaiters.Wait()
+		removeReceiver(r.config)
+	})
+	return nil
+}
+
+func (r *heartbeatReceiver) heartbeatLoop() {
+	defer r.stopWaiters.Done()
+
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.beat()
+		case <-r.stopChannel:
+			return
+		}
+	}
+}
+
+func (r *heartbeatReceiver) beat() {
+	ctx, cancel := context.WithTimeout(context.Background(), r.config.Interval)
+	defer cancel()
+
+	now := time.Now()
+	processed := telemetrystatsprocessor.ProcessedTotal()
+	hb := heartbeat{
+		time:      now,
+		uptime:    now.Sub(processStart),
+		processed: processed,
+	}
+	if seconds := now.Sub(r.lastBeat).Seconds(); seconds > 0 {
+		hb.throughput = float64(processed-r.lastProcessed) / seconds
+	}
+	r.lastProcessed = processed
+	r.lastBeat = now
+
+	if r.metricsConsumer != nil {
+		if err := r.metricsConsumer.ConsumeMetrics(ctx,
+			r.buildMetrics(hb)); err != nil {
+			r.logger.Error("Failed to consume heartbeat metrics",
+				zap.Error(err))
+		}
+	}
+	if r.logsConsumer != nil {
+		if err := r.logsConsumer.ConsumeLogs(ctx, r.buildLogs(hb)); err != nil {
+			r.logger.Error("Failed to consume heartbeat logs", zap.Error(err))
+		}
+	}
+}
+
+func (r *heartbeatReceiver) buildMetrics(hb heartbeat) pmetric.Metrics {
+	md := pmetric.NewMetrics()
+	sm := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty()
+	sm.Scope().SetName(scopeName)
+	sm.Scope().SetVersion(Version)
+	now := pcommon.NewTimestampFromTime(hb.time)
+
+	uptime := sm.Metrics().AppendEmpty()
+	uptime.SetName("otelcol.heartbeat")
+	uptime.SetDescription("Uptime of the collector, emitted every heartbeat interval")
+	uptime.SetUnit("s")
+	dp := uptime.SetEmptyGauge().DataPoints().AppendEmpty()
+	dp.SetTimestamp(now)
+	dp.SetDoubleValue(hb.uptime.Seconds())
+	r.putAttributes(dp.Attributes())
+
+	processed := sm.Metrics().AppendEmpty()
+	processed.SetName("otelcol.heartbeat.processed")
+	processed.SetDescription("Number of log records and metric datapoints seen by telemetry_stats processors")
+	processed.SetUnit("{items}")
+	sum := processed.SetEmptySum()
+	sum.SetIsMonotonic(true)
+	sum.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	dp = sum.DataPoints().AppendEmpty()
+	dp.SetStartTimestamp(pcommon.NewTimestampFromTime(processStart))
+	dp.SetTimestamp(now)
+	dp.SetIntValue(hb.processed)
+
+	throughput := sm.Metrics().AppendEmpty()
+	throughput.SetName("otelcol.heartbeat.throughput")
+	throughput.SetDescription("Log records and metric datapoints seen by telemetry_stats processors per second since the previous heartbeat")
+	throughput.SetUnit("{items}/s")
+	dp = throughput.SetEmptyGauge().DataPoints().AppendEmpty()
+	dp.SetTimestamp(now)
+	dp.SetDoubleValue(hb.throughput)
+
+	return md
+}
+
+func (r *heartbeatReceiver) buildLogs(hb heartbeat) plog.Logs {
+	ld := plog.NewLogs()
+	sl := ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty()
+	sl.Scope().SetName(scopeName)
+	sl.Scope().SetVersion(Version)
+	now := pcommon.NewTimestampFromTime(hb.time)
+
+	lr := sl.LogRecords().AppendEmpty()
+	lr.SetObservedTimestamp(now)
+	lr.SetTimestamp(now)
+	lr.SetSeverityNumber(plog.SeverityNumberInfo)
+	lr.SetSeverityText("INFO")
+	lr.Body().SetStr(fmt.Sprintf("heartbeat after %s uptime, %.1f items/s",
+		hb.uptime.Round(time.Second), hb.throughput))
+
+	attrs := lr.Attributes()
+	r.putAttributes(attrs)
+	attrs.PutInt("otelcol.uptime", int64(hb.uptime.Seconds()))
+	attrs.PutInt("otelcol.processed", hb.processed)
+	attrs.PutDouble("otelcol.throughput", hb.throughput)
+
+	return ld
+}
+
+// putAttributes puts the attributes identifying the collector build and
+// configuration.
+func (r *heartbeatReceiver) putAttributes(attrs pcommon.Map) {
+	if r.collectorVersion != "" {
+		attrs.PutStr("otelcol.version", r.collectorVersion)
+	}
+	if r.configHash != "" {
+		attrs.PutStr("otelcol.config.hash", r.configHash)
+	}
+}
+
+// configFilesFromArgs returns the paths of the configuration files given to
+// the collector with --config. Configurations given as other URIs, e.g.
+// env: or yaml:, are not files and are returned as they are, so that their
+// URI is hashed.
+func configFilesFromArgs(args []string) []string {
+	var files []string
+	for i := 0; i < len(args); i++ {
+		var value string
+		switch arg := args[i]; {
+		case strings.HasPrefix(arg, "--config="):
+			value = strings.TrimPrefix(arg, "--config=")
+		case arg == "--config" && i+1 < len(args):
+			i++
+			value = args[i]
+		default:
+			continue
+		}
+		files = append(files, value)
+	}
+	return files
+}
+
+// hashConfigFiles returns the truncated SHA-256 of the content of the files in
+// order, so that collectors running the same configuration report the same
+// hash.
+func hashConfigFiles(files []string) (string, error) {
+	if len(files) == 0 {
+		return "", nil
+	}
+	hash := sha256.New()
+	for _, file := range files {
+		path := strings.TrimPrefix(file, "file:")
+		if scheme, _, found := strings.Cut(file, ":"); found && path == file &&
+			!strings.ContainsAny(scheme, `/\`) {
+			hash.Write([]byte(file))
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		hash.Write(data)
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:configHashLength], nil
+}
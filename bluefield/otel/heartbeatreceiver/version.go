@@ -0,0 +1,3 @@
+package heartbeatreceiver
+
+const Version = "0.0.1"
@@ -0,0 +1,55 @@
+// Package dpufileprovider resolves ${dpufile:<path>} config references to the
+// value of the attribute file at the path.
+package dpufileprovider
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"go.opentelemetry.io/collector/confmap"
+
+	"fileresourceprocessor"
+)
+
+const schemeName = "dpufile"
+
+type provider struct{}
+
+// NewFactory returns a factory for the provider that resolves
+// ${dpufile:<path>} to the value of the first name=value line of the file,
+// read exactly like the fileresourceprocessor reads its files.
+func NewFactory() confmap.ProviderFactory {
+	return confmap.NewProviderFactory(newProvider)
+}
+
+func newProvider(confmap.ProviderSettings) confmap.Provider {
+	return &provider{}
+}
+
+func (p *provider) Retrieve(
+	_ context.Context,
+	uri string,
+	_ confmap.WatcherFunc,
+) (*confmap.Retrieved, error) {
+	path, found := strings.CutPrefix(uri, schemeName+":")
+	if !found {
+		return nil, fmt.Errorf("%q uri is not supported by %q provider",
+			uri, schemeName)
+	}
+
+	_, value, err := fileresourceprocessor.ReadAttributeFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attribute file: %w", err)
+	}
+	return confmap.NewRetrieved(value)
+}
+
+func (*provider) Scheme() string {
+	return schemeName
+}
+
+func (*provider) Shutdown(context.Context) error {
+	return nil
+}
@@ -0,0 +1,3 @@
+module hostvarsconverter
+
+go 1.22
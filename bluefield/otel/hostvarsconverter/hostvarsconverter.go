@@ -0,0 +1,151 @@
+package hostvarsconverter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/collector/confmap"
+
+	"fileresourceprocessor"
+)
+
+const (
+	// hostVarsDirEnv overrides the directory of host variable files
+	hostVarsDirEnv     = "OTELCOL_HOSTVARS_DIR"
+	defaultHostVarsDir = "/run/otelcol-contrib/hostvars"
+	serialPath         = "/sys/class/dmi/id/product_serial"
+	varPrefix          = "dpu."
+)
+
+var (
+	// matches ${dpu.<name>} with names made of the characters allowed in
+	// resource attribute names
+	reHostVar = regexp.MustCompile(`\$\{dpu\.([a-zA-Z0-9_.\-]+)\}`)
+)
+
+type hostVarsConverter struct {
+	dir string
+}
+
+// NewFactory returns a factory for the converter that expands ${dpu.<name>}
+// host variables in config values at load time.
+func NewFactory() confmap.ConverterFactory {
+	return confmap.NewConverterFactory(newConverter)
+}
+
+func newConverter(confmap.ConverterSettings) confmap.Converter {
+	dir := os.Getenv(hostVarsDirEnv)
+	if dir == "" {
+		dir = defaultHostVarsDir
+	}
+	return &hostVarsConverter{dir: dir}
+}
+
+// Convert replaces every ${dpu.<name>} in string config values with the value
+// of the named host variable, and fails if any referenced variable is not
+// defined so that a config depending on missing host data is rejected at load
+// time rather than producing misattributed telemetry.
+func (c *hostVarsConverter) Convert(_ context.Context, conf *confmap.Conf) error {
+	vars, err := c.loadHostVars()
+	if err != nil {
+		return err
+	}
+
+	out := make(map[string]any)
+	for _, key := range conf.AllKeys() {
+		value, err := expandValue(conf.Get(key), vars)
+		if err != nil {
+			return fmt.Errorf("failed to expand %s: %w", key, err)
+		}
+		out[key] = value
+	}
+
+	return conf.Merge(confmap.NewFromStringMap(out))
+}
+
+// loadHostVars gathers the built-in host variables, then overlays the
+// name=value pairs read from each file in the host variables directory.
+func (c *hostVarsConverter) loadHostVars() (map[string]string, error) {
+	vars := make(map[string]string)
+
+	if hostname, err := os.Hostname(); err == nil {
+		vars["hostname"] = hostname
+	}
+	if serial, err := os.ReadFile(serialPath); err == nil {
+		if value := strings.TrimSpace(string(serial)); value != "" {
+			vars["serial"] = value
+		}
+	}
+
+	entries, err := os.ReadDir(c.dir)
+	if os.IsNotExist(err) {
+		return vars, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read host variables directory: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		path := filepath.Join(c.dir, entry.Name())
+		name, value, err := fileresourceprocessor.ReadAttributeFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read host variable: %w", err)
+		}
+		vars[strings.TrimPrefix(name, varPrefix)] = value
+	}
+
+	return vars, nil
+}
+
+func expandValue(value any, vars map[string]string) (any, error) {
+	switch v := value.(type) {
+	case string:
+		return expandString(v, vars)
+	case []any:
+		expanded := make([]any, len(v))
+		for i, item := range v {
+			e, err := expandValue(item, vars)
+			if err != nil {
+				return nil, err
+			}
+			expanded[i] = e
+		}
+		return expanded, nil
+	case map[string]any:
+		expanded := make(map[string]any, len(v))
+		for k, item := range v {
+			e, err := expandValue(item, vars)
+			if err != nil {
+				return nil, err
+			}
+			expanded[k] = e
+		}
+		return expanded, nil
+	default:
+		return value, nil
+	}
+}
+
+func expandString(s string, vars map[string]string) (string, error) {
+	var missing []string
+	expanded := reHostVar.ReplaceAllStringFunc(s, func(match string) string {
+		name := reHostVar.FindStringSubmatch(match)[1]
+		value, exists := vars[name]
+		if !exists {
+			missing = append(missing, varPrefix+name)
+			return match
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("undefined host variables: %s",
+			strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
@@ -0,0 +1,3 @@
+package hostvarsconverter
+
+const Version = "0.0.1"
@@ -0,0 +1,56 @@
+package hugepagesreceiver
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+
+	"otelcommon/dpdktelemetry"
+)
+
+// Config defines the configuration of the hugepages receiver.
+type Config struct {
+	// CollectionInterval configures how often hugepage and DPDK
+	// statistics are collected. Defaults to "30s".
+	CollectionInterval time.Duration `mapstructure:"collection_interval"`
+
+	// SysfsPath is the mount point of sysfs. Defaults to "/sys".
+	SysfsPath string `mapstructure:"sysfs_path"`
+
+	// DPDKSocketGlob matches the telemetry sockets of DPDK applications,
+	// including DOCA applications built on DPDK. Defaults to
+	// "/var/run/dpdk/*/dpdk_telemetry.v2". Leave empty to only collect
+	// hugepage statistics.
+	DPDKSocketGlob string `mapstructure:"dpdk_socket_glob"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.CollectionInterval <= 0 {
+		return errors.New("collection_interval must be positive")
+	}
+	if cfg.SysfsPath == "" {
+		return errors.New("sysfs_path cannot be empty")
+	}
+	if cfg.DPDKSocketGlob != "" {
+		if _, err := filepath.Match(cfg.DPDKSocketGlob, ""); err != nil {
+			return fmt.Errorf("invalid dpdk_socket_glob: %w", err)
+		}
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		CollectionInterval: 30 * time.Second,
+		SysfsPath:          "/sys",
+		DPDKSocketGlob:     dpdktelemetry.DefaultSocketGlob,
+	}
+}
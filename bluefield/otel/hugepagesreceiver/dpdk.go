@@ -0,0 +1,161 @@
+package hugepagesreceiver
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+
+	"otelcommon/dpdktelemetry"
+	"otelcommon/metricappend"
+)
+
+// mempoolInfo is the response to "/mempool/info,<name>".
+type mempoolInfo struct {
+	Name            string `json:"name"`
+	SocketID        int64  `json:"socket_id"`
+	Size            int64  `json:"size"`
+	PopulatedSize   int64  `json:"populated_size"`
+	CommonPoolCount int64  `json:"common_pool_count"`
+	TotalCacheCount int64  `json:"total_cache_count"`
+}
+
+// ringInfo is the response to "/ring/info,<name>", available since DPDK
+// 23.07.
+type ringInfo struct {
+	Name      string `json:"name"`
+	Socket    int64  `json:"socket"`
+	Capacity  int64  `json:"capacity"`
+	UsedCount int64  `json:"used_count"`
+}
+
+// dpdkMetrics holds the metrics of the mempools and rings of DPDK
+// applications.
+type dpdkMetrics struct {
+	mempoolSize        pmetric.NumberDataPointSlice
+	mempoolInUse       pmetric.NumberDataPointSlice
+	mempoolUtilization pmetric.NumberDataPointSlice
+	ringCapacity       pmetric.NumberDataPointSlice
+	ringUsed           pmetric.NumberDataPointSlice
+	ringUtilization    pmetric.NumberDataPointSlice
+}
+
+func newDPDKMetrics(metrics pmetric.MetricSlice) dpdkMetrics {
+	return dpdkMetrics{
+		mempoolSize: metricappend.Gauge(metrics, "dpdk.mempool.size", "{objects}",
+			"Objects in the DPDK mempool"),
+		mempoolInUse: metricappend.Gauge(metrics, "dpdk.mempool.in_use", "{objects}",
+			"Objects of the DPDK mempool that are in use"),
+		mempoolUtilization: metricappend.Gauge(metrics, "dpdk.mempool.utilization", "1",
+			"Fraction of the objects of the DPDK mempool that are in use"),
+		ringCapacity: metricappend.Gauge(metrics, "dpdk.ring.capacity", "{entries}",
+			"Entries the DPDK ring can hold"),
+		ringUsed: metricappend.Gauge(metrics, "dpdk.ring.used", "{entries}",
+			"Entries in the DPDK ring"),
+		ringUtilization: metricappend.Gauge(metrics, "dpdk.ring.utilization", "1",
+			"Fraction of the capacity of the DPDK ring in use"),
+	}
+}
+
+// collectDPDK appends the mempool and ring statistics of each DPDK
+// application with a telemetry socket.
+func (r *hugepagesReceiver) collectDPDK(
+	ctx context.Context,
+	metrics pmetric.MetricSlice,
+	now pcommon.Timestamp,
+) {
+	sockets, err := filepath.Glob(r.config.DPDKSocketGlob)
+	if err != nil || len(sockets) == 0 {
+		return
+	}
+	sort.Strings(sockets)
+
+	dm := newDPDKMetrics(metrics)
+	for _, socket := range sockets {
+		if err := r.collectApp(ctx, socket, dm, now); err != nil {
+			// sockets of applications that exited are left behind, so
+			// failures to connect are common
+			r.logger.Debug("Failed to collect DPDK telemetry",
+				zap.String("socket", socket), zap.Error(err))
+		}
+	}
+}
+
+func (r *hugepagesReceiver) collectApp(
+	ctx context.Context,
+	socket string,
+	dm dpdkMetrics,
+	now pcommon.Timestamp,
+) error {
+	client, err := dpdktelemetry.Dial(ctx, socket)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	attrs := pcommon.NewMap()
+	attrs.PutStr("dpdk.app", dpdktelemetry.AppName(socket))
+	attrs.PutInt("process.pid", client.Info.PID)
+
+	var mempools []string
+	if err := client.Query("/mempool/list", &mempools); err != nil {
+		return err
+	}
+	for _, name := range mempools {
+		var info mempoolInfo
+		if err := client.Query("/mempool/info,"+name, &info); err != nil {
+			return err
+		}
+		if info.Name == "" {
+			continue
+		}
+		mempoolAttrs := pcommon.NewMap()
+		attrs.CopyTo(mempoolAttrs)
+		mempoolAttrs.PutStr("dpdk.mempool.name", info.Name)
+		mempoolAttrs.PutInt("numa.node", info.SocketID)
+
+		available := info.CommonPoolCount + info.TotalCacheCount
+		inUse := max(info.PopulatedSize-available, 0)
+		appendIntDatapoint(dm.mempoolSize, info.Size, mempoolAttrs, now)
+		appendIntDatapoint(dm.mempoolInUse, inUse, mempoolAttrs, now)
+		if info.Size > 0 {
+			dp := dm.mempoolUtilization.AppendEmpty()
+			dp.SetTimestamp(now)
+			dp.SetDoubleValue(float64(inUse) / float64(info.Size))
+			mempoolAttrs.CopyTo(dp.Attributes())
+		}
+	}
+
+	// applications built against DPDK before 23.07 don't know the ring
+	// commands and return null
+	var rings []string
+	if err := client.Query("/ring/list", &rings); err != nil {
+		return err
+	}
+	for _, name := range rings {
+		var info ringInfo
+		if err := client.Query("/ring/info,"+name, &info); err != nil {
+			return err
+		}
+		if info.Name == "" {
+			continue
+		}
+		ringAttrs := pcommon.NewMap()
+		attrs.CopyTo(ringAttrs)
+		ringAttrs.PutStr("dpdk.ring.name", info.Name)
+		ringAttrs.PutInt("numa.node", info.Socket)
+
+		appendIntDatapoint(dm.ringCapacity, info.Capacity, ringAttrs, now)
+		appendIntDatapoint(dm.ringUsed, info.UsedCount, ringAttrs, now)
+		if info.Capacity > 0 {
+			dp := dm.ringUtilization.AppendEmpty()
+			dp.SetTimestamp(now)
+			dp.SetDoubleValue(float64(info.UsedCount) / float64(info.Capacity))
+			ringAttrs.CopyTo(dp.Attributes())
+		}
+	}
+	return nil
+}
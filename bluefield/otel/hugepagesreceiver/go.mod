@@ -0,0 +1,3 @@
+module hugepagesreceiver
+
+go 1.22
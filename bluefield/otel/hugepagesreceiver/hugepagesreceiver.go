@@ -0,0 +1,214 @@
+package hugepagesreceiver
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+
+	"otelcommon/metricappend"
+)
+
+const scopeName = "hugepagesreceiver"
+
+type hugepagesReceiver struct {
+	config       *Config
+	logger       *zap.Logger
+	nextConsumer consumer.Metrics
+	stopChannel  chan struct{}
+	stopWaiters  sync.WaitGroup
+}
+
+// hugepagePool are the counters of the hugepages of one size on a NUMA node.
+type hugepagePool struct {
+	node    int64
+	size    int64 // in bytes
+	total   int64
+	free    int64
+	surplus int64
+}
+
+func newHugepagesReceiver(
+	config *Config,
+	logger *zap.Logger,
+	nextConsumer consumer.Metrics,
+) *hugepagesReceiver {
+	return &hugepagesReceiver{
+		config:       config,
+		logger:       logger,
+		nextConsumer: nextConsumer,
+		stopChannel:  make(chan struct{}),
+	}
+}
+
+func (r *hugepagesReceiver) Start(_ context.Context, _ component.Host) error {
+	r.stopWaiters.Add(1)
+	go r.collectLoop()
+	return nil
+}
+
+func (r *hugepagesReceiver) Shutdown(context.Context) error {
+	close(r.stopChannel)
+	r.stopWaiters.Wait()
+	return nil
+}
+
+func (r *hugepagesReceiver) collectLoop() {
+	defer r.stopWaiters.Done()
+
+	ticker := time.NewTicker(r.config.CollectionInterval)
+	defer ticker.Stop()
+
+	r.collect()
+	for {
+		select {
+		case <-ticker.C:
+			r.collect()
+		case <-r.stopChannel:
+			return
+		}
+	}
+}
+
+func (r *hugepagesReceiver) collect() {
+	ctx, cancel := context.WithTimeout(context.Background(),
+		r.config.CollectionInterval)
+	defer cancel()
+
+	now := pcommon.NewTimestampFromTime(time.Now())
+	md := pmetric.NewMetrics()
+	sm := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty()
+	sm.Scope().SetName(scopeName)
+	sm.Scope().SetVersion(Version)
+
+	pools, err := r.readHugepages()
+	if err != nil {
+		r.logger.Error("Failed to read hugepage statistics", zap.Error(err))
+	} else if len(pools) > 0 {
+		appendHugepageMetrics(sm.Metrics(), pools, now)
+	}
+
+	if r.config.DPDKSocketGlob != "" {
+		r.collectDPDK(ctx, sm.Metrics(), now)
+	}
+
+	// e.g. the ring metrics of applications built against older DPDK
+	sm.Metrics().RemoveIf(func(metric pmetric.Metric) bool {
+		return metric.Gauge().DataPoints().Len() == 0
+	})
+
+	if md.DataPointCount() == 0 {
+		return
+	}
+	if err := r.nextConsumer.ConsumeMetrics(ctx, md); err != nil {
+		r.logger.Error("Failed to consume hugepage statistics", zap.Error(err))
+	}
+}
+
+// readHugepages reads the hugepage counters of each NUMA node from
+// <sysfs>/devices/system/node/node<N>/hugepages/hugepages-<size>kB.
+func (r *hugepagesReceiver) readHugepages() ([]hugepagePool, error) {
+	dirs, err := filepath.Glob(filepath.Join(r.config.SysfsPath,
+		"devices/system/node/node*/hugepages/hugepages-*kB"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(dirs)
+
+	var pools []hugepagePool
+	for _, dir := range dirs {
+		nodeName := filepath.Base(filepath.Dir(filepath.Dir(dir)))
+		node, err := strconv.ParseInt(strings.TrimPrefix(nodeName, "node"), 10, 64)
+		if err != nil {
+			continue
+		}
+		sizeName := strings.TrimSuffix(
+			strings.TrimPrefix(filepath.Base(dir), "hugepages-"), "kB")
+		sizeKiB, err := strconv.ParseInt(sizeName, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		pool := hugepagePool{node: node, size: sizeKiB * 1024}
+		for _, counter := range []struct {
+			file  string
+			value *int64
+		}{
+			{"nr_hugepages", &pool.total},
+			{"free_hugepages", &pool.free},
+			{"surplus_hugepages", &pool.surplus},
+		} {
+			if *counter.value, err = readInt(filepath.Join(dir, counter.file)); err != nil {
+				return nil, err
+			}
+		}
+		pools = append(pools, pool)
+	}
+	return pools, nil
+}
+
+func appendHugepageMetrics(
+	metrics pmetric.MetricSlice,
+	pools []hugepagePool,
+	now pcommon.Timestamp,
+) {
+	total := metricappend.Gauge(metrics, "hugepages.total", "{pages}",
+		"Hugepages allocated on the NUMA node")
+	free := metricappend.Gauge(metrics, "hugepages.free", "{pages}",
+		"Hugepages allocated on the NUMA node that are not in use")
+	surplus := metricappend.Gauge(metrics, "hugepages.surplus", "{pages}",
+		"Hugepages allocated on the NUMA node beyond the configured number")
+	utilization := metricappend.Gauge(metrics, "hugepages.utilization", "1",
+		"Fraction of the hugepages allocated on the NUMA node that are in use")
+
+	for _, pool := range pools {
+		attrs := pcommon.NewMap()
+		attrs.PutInt("numa.node", pool.node)
+		attrs.PutInt("hugepages.size", pool.size)
+
+		appendIntDatapoint(total, pool.total, attrs, now)
+		appendIntDatapoint(free, pool.free, attrs, now)
+		appendIntDatapoint(surplus, pool.surplus, attrs, now)
+		if pool.total > 0 {
+			dp := utilization.AppendEmpty()
+			dp.SetTimestamp(now)
+			dp.SetDoubleValue(float64(pool.total-pool.free) / float64(pool.total))
+			attrs.CopyTo(dp.Attributes())
+		}
+	}
+}
+
+func appendIntDatapoint(
+	dps pmetric.NumberDataPointSlice,
+	value int64,
+	attrs pcommon.Map,
+	now pcommon.Timestamp,
+) {
+	dp := dps.AppendEmpty()
+	dp.SetTimestamp(now)
+	dp.SetIntValue(value)
+	attrs.CopyTo(dp.Attributes())
+}
+
+func readInt(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	value, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value in %s: %w", path, err)
+	}
+	return value, nil
+}
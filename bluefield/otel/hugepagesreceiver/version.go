@@ -0,0 +1,3 @@
+package hugepagesreceiver
+
+const Version = "0.0.1"
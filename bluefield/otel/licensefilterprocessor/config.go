@@ -0,0 +1,115 @@
+package licensefilterprocessor
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+
+	"otelcommon/filter"
+)
+
+// Config defines the configuration of the license_filter processor.
+type Config struct {
+	// LicenseFile is the path of the JSON license of the node. Exactly one
+	// of LicenseFile and LicenseURL must be specified.
+	LicenseFile string `mapstructure:"license_file"`
+
+	// LicenseURL is the URL of a control plane API serving the JSON
+	// license of the node.
+	LicenseURL string `mapstructure:"license_url"`
+
+	// CAFile is an optional path of a PEM encoded CA bundle used to verify
+	// the control plane API, instead of the system roots.
+	CAFile string `mapstructure:"ca_file"`
+
+	// BearerTokenFile is an optional path of a file holding a token sent
+	// to the control plane API as "Authorization: Bearer <token>". It is
+	// read on every reload, so the token can be rotated.
+	BearerTokenFile string `mapstructure:"bearer_token_file"`
+
+	// ReloadInterval configures how often the license is reloaded.
+	// Defaults to "1m".
+	ReloadInterval time.Duration `mapstructure:"reload_interval"`
+
+	// DefaultFeatures are the features considered licensed until a
+	// license is loaded, e.g. those of the base tier. Telemetry of any
+	// other feature is dropped until then.
+	DefaultFeatures []string `mapstructure:"default_features"`
+
+	// Categories configure the telemetry of each feature, which is dropped
+	// while the feature is not licensed. Telemetry of no category passes
+	// unchanged.
+	Categories []Category `mapstructure:"categories"`
+}
+
+// Category defines the telemetry of a licensed feature. Metric datapoints, log
+// records and spans are of the category if they match the respective filter.
+// Labels of the filters are looked up among the attributes of the datapoint,
+// log record or span, its scope and its resource, in that order.
+type Category struct {
+	// Feature is the name of the feature as listed in the license.
+	Feature string `mapstructure:"feature"`
+
+	// Metrics optionally selects the metric datapoints of the feature.
+	Metrics *filter.MetricFilter `mapstructure:"metrics"`
+
+	// Logs optionally selects the log records of the feature.
+	Logs *filter.LogFilter `mapstructure:"logs"`
+
+	// Spans optionally selects the spans of the feature.
+	Spans *filter.SpanFilter `mapstructure:"spans"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if (cfg.LicenseFile == "") == (cfg.LicenseURL == "") {
+		return errors.New("exactly one of license_file and license_url " +
+			"must be specified")
+	}
+	if cfg.LicenseURL != "" {
+		u, err := url.Parse(cfg.LicenseURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return errors.New("license_url must be an http or https URL")
+		}
+	} else if cfg.CAFile != "" || cfg.BearerTokenFile != "" {
+		return errors.New("ca_file and bearer_token_file require license_url")
+	}
+	if cfg.ReloadInterval <= 0 {
+		return errors.New("reload_interval must be positive")
+	}
+	for _, feature := range cfg.DefaultFeatures {
+		if feature == "" {
+			return errors.New("default_features cannot be empty")
+		}
+	}
+	if len(cfg.Categories) == 0 {
+		return errors.New("at least one category must be configured")
+	}
+	for i, category := range cfg.Categories {
+		if category.Feature == "" {
+			return fmt.Errorf("feature of category %d cannot be empty", i)
+		}
+		if category.Metrics == nil && category.Logs == nil &&
+			category.Spans == nil {
+			return fmt.Errorf("category %s must configure metrics, logs "+
+				"or spans", category.Feature)
+		}
+		if _, err := compileCategory(category); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		ReloadInterval: time.Minute,
+	}
+}
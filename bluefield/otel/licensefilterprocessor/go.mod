@@ -0,0 +1,3 @@
+module licensefilterprocessor
+
+go 1.22
@@ -0,0 +1,141 @@
+package licensefilterprocessor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// maxLicenseSize limits how much of a license is read
+const maxLicenseSize = 1 << 20
+
+// license is the JSON license of the node, e.g.
+//
+//	{"tier": "standard", "features": ["doca_flow", "ebpf_accounting"]}
+type license struct {
+	Tier     string   `json:"tier"`
+	Features []string `json:"features"`
+}
+
+func (l *license) validate() error {
+	for i, feature := range l.Features {
+		if feature == "" {
+			return fmt.Errorf("feature %d cannot be empty", i)
+		}
+	}
+	return nil
+}
+
+// licenseLoader loads the license from the configured file or URL.
+type licenseLoader struct {
+	config  *Config
+	client  *http.Client
+	modTime time.Time // of the license file when it was last loaded
+}
+
+func newLicenseLoader(config *Config) (*licenseLoader, error) {
+	l := &licenseLoader{config: config}
+	if config.LicenseURL == "" {
+		return l, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.CAFile != "" {
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca_file: %w", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificates found in ca_file")
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: roots}
+	}
+	l.client = &http.Client{
+		Transport: transport,
+		Timeout:   30 * time.Second,
+	}
+	return l, nil
+}
+
+// load returns the license, or nil if the license file hasn't changed since it
+// was last loaded.
+func (l *licenseLoader) load(ctx context.Context) (*license, error) {
+	var data []byte
+	var err error
+	if l.config.LicenseURL != "" {
+		data, err = l.fetch(ctx)
+	} else {
+		data, err = l.readFile()
+	}
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	var lic license
+	if err := json.Unmarshal(data, &lic); err != nil {
+		return nil, fmt.Errorf("invalid license: %w", err)
+	}
+	if err := lic.validate(); err != nil {
+		return nil, fmt.Errorf("invalid license: %w", err)
+	}
+	return &lic, nil
+}
+
+func (l *licenseLoader) readFile() ([]byte, error) {
+	info, err := os.Stat(l.config.LicenseFile)
+	if err != nil {
+		return nil, err
+	}
+	if info.ModTime().Equal(l.modTime) {
+		return nil, nil
+	}
+
+	file, err := os.Open(l.config.LicenseFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxLicenseSize))
+	if err != nil {
+		return nil, err
+	}
+	l.modTime = info.ModTime()
+	return data, nil
+}
+
+func (l *licenseLoader) fetch(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		l.config.LicenseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if l.config.BearerTokenFile != "" {
+		token, err := os.ReadFile(l.config.BearerTokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bearer_token_file: %w", err)
+		}
+		req.Header.Set("Authorization",
+			"Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxLicenseSize))
+}
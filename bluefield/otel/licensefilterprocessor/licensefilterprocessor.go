@@ -0,0 +1,408 @@
+package licensefilterprocessor
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+
+	"otelcommon/filter"
+)
+
+// the signal attribute values of the dropped items metric
+const (
+	signalMetrics = "metrics"
+	signalLogs    = "logs"
+	signalTraces  = "traces"
+)
+
+type licenseFilterProcessor struct {
+	config         *Config
+	logger         *zap.Logger
+	loader         *licenseLoader
+	categories     []*category
+	licenseLock    sync.RWMutex
+	licensed       map[string]bool // features currently licensed
+	blocked        []*category     // categories of unlicensed features
+	droppedCounter metric.Int64Counter
+	registration   metric.Registration // of the licensed features gauge
+	stopChannel    chan struct{}
+	stopWaiters    sync.WaitGroup
+}
+
+// category is a compiled Category.
+type category struct {
+	feature string
+	metrics *filter.MetricMatcher
+	logs    *filter.LogMatcher
+	spans   *filter.SpanMatcher
+}
+
+func compileCategory(c Category) (*category, error) {
+	metrics, err := filter.CompileMetricFilter(c.Metrics)
+	if err != nil {
+		return nil, fmt.Errorf("metrics of category %s: %w", c.Feature, err)
+	}
+	logs, err := filter.CompileLogFilter(c.Logs)
+	if err != nil {
+		return nil, fmt.Errorf("logs of category %s: %w", c.Feature, err)
+	}
+	spans, err := filter.CompileSpanFilter(c.Spans)
+	if err != nil {
+		return nil, fmt.Errorf("spans of category %s: %w", c.Feature, err)
+	}
+	return &category{
+		feature: c.Feature,
+		metrics: metrics,
+		logs:    logs,
+		spans:   spans,
+	}, nil
+}
+
+// attributes looks up labels of the category filters among the datapoint, log
+// record or span, scope and resource attributes, in that order.
+type attributes struct {
+	resource pcommon.Map
+	scope    pcommon.Map
+	item     pcommon.Map
+}
+
+func (a *attributes) Get(name string) (string, bool) {
+	for _, m := range []pcommon.Map{a.item, a.scope, a.resource} {
+		if v, exists := m.Get(name); exists {
+			return v.AsString(), true
+		}
+	}
+	return "", false
+}
+
+// processor constructor
+func newLicenseFilterProcessor(
+	config *Config,
+	settings component.TelemetrySettings,
+) (*licenseFilterProcessor, error) {
+	loader, err := newLicenseLoader(config)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &licenseFilterProcessor{
+		config:      config,
+		logger:      settings.Logger,
+		loader:      loader,
+		stopChannel: make(chan struct{}),
+	}
+	for _, c := range config.Categories {
+		compiled, err := compileCategory(c)
+		if err != nil {
+			return nil, err
+		}
+		p.categories = append(p.categories, compiled)
+	}
+	p.setLicensed(config.DefaultFeatures)
+
+	// self-metrics exposed with the collector's internal metrics
+	meter := settings.MeterProvider.Meter("licensefilterprocessor")
+	p.droppedCounter, err = meter.Int64Counter(
+		"licensefilter_dropped_items",
+		metric.WithDescription("Number of datapoints, log records and spans dropped since their feature is not licensed"),
+		metric.WithUnit("{items}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dropped items counter: %w", err)
+	}
+	licensedGauge, err := meter.Int64ObservableGauge(
+		"licensefilter_feature_licensed",
+		metric.WithDescription("Whether the feature of a category is licensed (1) or its telemetry is dropped (0)"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create licensed features gauge: %w", err)
+	}
+	p.registration, err = meter.RegisterCallback(
+		func(_ context.Context, observer metric.Observer) error {
+			p.licenseLock.RLock()
+			defer p.licenseLock.RUnlock()
+			for _, feature := range p.features() {
+				var value int64
+				if p.licensed[feature] {
+					value = 1
+				}
+				observer.ObserveInt64(licensedGauge, value,
+					metric.WithAttributes(attribute.String("feature", feature)))
+			}
+			return nil
+		}, licensedGauge)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register licensed features gauge: %w", err)
+	}
+	return p, nil
+}
+
+func (p *licenseFilterProcessor) start() {
+	// load the license before processing any data, so that telemetry of
+	// licensed features beyond the default ones isn't dropped needlessly
+	p.reload()
+
+	p.stopWaiters.Add(1)
+	go p.reloadLoop()
+}
+
+// processor destructor
+func (p *licenseFilterProcessor) cleanup() {
+	close(p.stopChannel)
+	p.stopWaiters.Wait()
+	if p.registration != nil {
+		if err := p.registration.Unregister(); err != nil {
+			p.logger.Warn("Failed to unregister licensed features gauge",
+				zap.Error(err))
+		}
+	}
+}
+
+func (p *licenseFilterProcessor) reloadLoop() {
+	defer p.stopWaiters.Done()
+
+	ticker := time.NewTicker(p.config.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.reload()
+		case <-p.stopChannel:
+			return
+		}
+	}
+}
+
+// reload applies the license if it loads successfully, and otherwise keeps the
+// last license loaded, or the default features if none was.
+func (p *licenseFilterProcessor) reload() {
+	ctx, cancel := context.WithTimeout(context.Background(),
+		p.config.ReloadInterval)
+	defer cancel()
+
+	lic, err := p.loader.load(ctx)
+	if err != nil {
+		p.logger.Error("Failed to load license", zap.Error(err))
+		return
+	}
+	if lic == nil {
+		return
+	}
+
+	p.setLicensed(lic.Features)
+	p.logger.Info("Loaded license",
+		zap.String("tier", lic.Tier),
+		zap.Strings("features", lic.Features))
+}
+
+// setLicensed replaces the licensed features, and logs the features of
+// categories whose telemetry starts or stops being dropped.
+func (p *licenseFilterProcessor) setLicensed(features []string) {
+	licensed := make(map[string]bool, len(features))
+	for _, feature := range features {
+		licensed[feature] = true
+	}
+	var blocked []*category
+	for _, c := range p.categories {
+		if !licensed[c.feature] {
+			blocked = append(blocked, c)
+		}
+	}
+
+	p.licenseLock.Lock()
+	previous := p.licensed
+	p.licensed = licensed
+	p.blocked = blocked
+	p.licenseLock.Unlock()
+
+	for _, feature := range p.features() {
+		switch {
+		case previous == nil && !licensed[feature]:
+			p.logger.Info("Dropping telemetry of unlicensed feature",
+				zap.String("feature", feature))
+		case previous == nil:
+		case previous[feature] && !licensed[feature]:
+			p.logger.Warn("Feature no longer licensed, dropping its telemetry",
+				zap.String("feature", feature))
+		case !previous[feature] && licensed[feature]:
+			p.logger.Info("Feature licensed, passing its telemetry",
+				zap.String("feature", feature))
+		}
+	}
+}
+
+// features returns the features of the categories, each once.
+func (p *licenseFilterProcessor) features() []string {
+	var features []string
+	for _, c := range p.categories {
+		if !slices.Contains(features, c.feature) {
+			features = append(features, c.feature)
+		}
+	}
+	return features
+}
+
+// blockedCategories returns the categories of unlicensed features.
+func (p *licenseFilterProcessor) blockedCategories() []*category {
+	p.licenseLock.RLock()
+	defer p.licenseLock.RUnlock()
+	return p.blocked
+}
+
+// countDropped adds the items dropped by feature to the dropped items metric.
+func (p *licenseFilterProcessor) countDropped(
+	ctx context.Context,
+	signal string,
+	dropped map[string]int64,
+) {
+	for feature, count := range dropped {
+		p.droppedCounter.Add(ctx, count, metric.WithAttributes(
+			attribute.String("feature", feature),
+			attribute.String("signal", signal)))
+	}
+}
+
+func (p *licenseFilterProcessor) processLogs(
+	ctx context.Context,
+	ld plog.Logs,
+) (plog.Logs, error) {
+	blocked := p.blockedCategories()
+	if len(blocked) == 0 {
+		return ld, nil
+	}
+	dropped := make(map[string]int64)
+	ld.ResourceLogs().RemoveIf(func(rl plog.ResourceLogs) bool {
+		attrs := &attributes{resource: rl.Resource().Attributes()}
+		rl.ScopeLogs().RemoveIf(func(sl plog.ScopeLogs) bool {
+			attrs.scope = sl.Scope().Attributes()
+			sl.LogRecords().RemoveIf(func(lr plog.LogRecord) bool {
+				attrs.item = lr.Attributes()
+				for _, c := range blocked {
+					if c.logs.Match(lr, attrs) {
+						dropped[c.feature]++
+						return true
+					}
+				}
+				return false
+			})
+			return sl.LogRecords().Len() == 0
+		})
+		return rl.ScopeLogs().Len() == 0
+	})
+	p.countDropped(ctx, signalLogs, dropped)
+	return ld, nil
+}
+
+func (p *licenseFilterProcessor) processTraces(
+	ctx context.Context,
+	td ptrace.Traces,
+) (ptrace.Traces, error) {
+	blocked := p.blockedCategories()
+	if len(blocked) == 0 {
+		return td, nil
+	}
+	dropped := make(map[string]int64)
+	td.ResourceSpans().RemoveIf(func(rs ptrace.ResourceSpans) bool {
+		attrs := &attributes{resource: rs.Resource().Attributes()}
+		rs.ScopeSpans().RemoveIf(func(ss ptrace.ScopeSpans) bool {
+			attrs.scope = ss.Scope().Attributes()
+			ss.Spans().RemoveIf(func(span ptrace.Span) bool {
+				attrs.item = span.Attributes()
+				for _, c := range blocked {
+					if c.spans.Match(span, attrs) {
+						dropped[c.feature]++
+						return true
+					}
+				}
+				return false
+			})
+			return ss.Spans().Len() == 0
+		})
+		return rs.ScopeSpans().Len() == 0
+	})
+	p.countDropped(ctx, signalTraces, dropped)
+	return td, nil
+}
+
+func (p *licenseFilterProcessor) processMetrics(
+	ctx context.Context,
+	md pmetric.Metrics,
+) (pmetric.Metrics, error) {
+	blocked := p.blockedCategories()
+	if len(blocked) == 0 {
+		return md, nil
+	}
+	dropped := make(map[string]int64)
+	md.ResourceMetrics().RemoveIf(func(rm pmetric.ResourceMetrics) bool {
+		attrs := &attributes{resource: rm.Resource().Attributes()}
+		rm.ScopeMetrics().RemoveIf(func(sm pmetric.ScopeMetrics) bool {
+			attrs.scope = sm.Scope().Attributes()
+			sm.Metrics().RemoveIf(func(m pmetric.Metric) bool {
+				return removeDatapoints(m, func(dpAttrs pcommon.Map) bool {
+					attrs.item = dpAttrs
+					for _, c := range blocked {
+						if c.metrics.Match(m, attrs) {
+							dropped[c.feature]++
+							return true
+						}
+					}
+					return false
+				}) == 0
+			})
+			return sm.Metrics().Len() == 0
+		})
+		return rm.ScopeMetrics().Len() == 0
+	})
+	p.countDropped(ctx, signalMetrics, dropped)
+	return md, nil
+}
+
+// removeDatapoints removes the datapoints of a metric whose attributes match,
+// and returns the number of remaining datapoints.
+func removeDatapoints(metric pmetric.Metric, match func(pcommon.Map) bool) int {
+	switch metric.Type() {
+	case pmetric.MetricTypeGauge:
+		dps := metric.Gauge().DataPoints()
+		dps.RemoveIf(func(dp pmetric.NumberDataPoint) bool {
+			return match(dp.Attributes())
+		})
+		return dps.Len()
+	case pmetric.MetricTypeSum:
+		dps := metric.Sum().DataPoints()
+		dps.RemoveIf(func(dp pmetric.NumberDataPoint) bool {
+			return match(dp.Attributes())
+		})
+		return dps.Len()
+	case pmetric.MetricTypeHistogram:
+		dps := metric.Histogram().DataPoints()
+		dps.RemoveIf(func(dp pmetric.HistogramDataPoint) bool {
+			return match(dp.Attributes())
+		})
+		return dps.Len()
+	case pmetric.MetricTypeExponentialHistogram:
+		dps := metric.ExponentialHistogram().DataPoints()
+		dps.RemoveIf(func(dp pmetric.ExponentialHistogramDataPoint) bool {
+			return match(dp.Attributes())
+		})
+		return dps.Len()
+	case pmetric.MetricTypeSummary:
+		dps := metric.Summary().DataPoints()
+		dps.RemoveIf(func(dp pmetric.SummaryDataPoint) bool {
+			return match(dp.Attributes())
+		})
+		return dps.Len()
+	}
+	return 1
+}
@@ -0,0 +1,3 @@
+package licensefilterprocessor
+
+const Version = "0.0.1"
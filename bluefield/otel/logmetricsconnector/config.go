@@ -0,0 +1,139 @@
+package logmetricsconnector
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/collector/component"
+
+	"otelcommon/filter"
+)
+
+const (
+	metricTypeGauge   = "gauge"
+	metricTypeCounter = "counter"
+
+	// valueGroup is the name of the capture group of body_regex holding
+	// the value
+	valueGroup = "value"
+)
+
+// Config defines the configuration of the log_metrics connector.
+type Config struct {
+	// Rules extract metrics from log records. A log record may match
+	// several rules.
+	Rules []Rule `mapstructure:"rules"`
+}
+
+// Rule extracts a metric from the log records it matches.
+type Rule struct {
+	// MetricName is the name of the extracted metric. Rules may share a
+	// metric name if they agree on its type, unit and description.
+	MetricName string `mapstructure:"metric_name"`
+
+	// Description is the description of the metric.
+	Description string `mapstructure:"description"`
+
+	// Unit is the unit of the metric, e.g. "By" or "{packets}".
+	Unit string `mapstructure:"unit"`
+
+	// Type is "gauge" for values as they are, or "counter" for values of
+	// a cumulative counter kept by the daemon logging it, which are
+	// emitted as monotonic cumulative sums. Empty means "gauge".
+	Type string `mapstructure:"type"`
+
+	// Include limits the log records the rule applies to. If unspecified,
+	// the rule applies to all log records whose value can be extracted.
+	Include *filter.LogFilter `mapstructure:"include"`
+
+	// BodyRegex is a regular expression matching the log body, with a
+	// capture group named "value" holding the value, e.g.
+	// "rx_drops=(?P<value>\\d+)". Other named capture groups become
+	// datapoint attributes.
+	BodyRegex string `mapstructure:"body_regex"`
+
+	// JSONField is the dot-separated path of the value in a JSON object
+	// body, or in a map body, e.g. "stats.rx.drops". Exactly one of
+	// BodyRegex and JSONField must be specified.
+	JSONField string `mapstructure:"json_field"`
+
+	// Attributes are static datapoint attributes.
+	Attributes map[string]string `mapstructure:"attributes"`
+
+	// RecordAttributes are log record attributes copied to the datapoint
+	// attributes, if the record has them.
+	RecordAttributes []string `mapstructure:"record_attributes"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if len(cfg.Rules) == 0 {
+		return errors.New("rules must be specified")
+	}
+	metrics := make(map[string]Rule)
+	for i, rule := range cfg.Rules {
+		if rule.MetricName == "" {
+			return fmt.Errorf("rules[%d]: metric_name must be specified", i)
+		}
+		if rule.Type != "" && rule.Type != metricTypeGauge &&
+			rule.Type != metricTypeCounter {
+			return fmt.Errorf("rules[%d]: type must be %q or %q", i,
+				metricTypeGauge, metricTypeCounter)
+		}
+		if other, exists := metrics[rule.MetricName]; exists &&
+			(other.metricType() != rule.metricType() || other.Unit != rule.Unit ||
+				other.Description != rule.Description) {
+			return fmt.Errorf("rules[%d]: type, unit and description of "+
+				"metric %s differ from another rule", i, rule.MetricName)
+		}
+		metrics[rule.MetricName] = rule
+		if (rule.BodyRegex == "") == (rule.JSONField == "") {
+			return fmt.Errorf("rules[%d]: exactly one of body_regex and "+
+				"json_field must be specified", i)
+		}
+		if rule.BodyRegex != "" {
+			re, err := regexp.Compile(rule.BodyRegex)
+			if err != nil {
+				return fmt.Errorf("rules[%d]: invalid body_regex: %w", i, err)
+			}
+			if !slices.Contains(re.SubexpNames(), valueGroup) {
+				return fmt.Errorf("rules[%d]: body_regex must have a "+
+					"capture group named %q", i, valueGroup)
+			}
+		}
+		if rule.JSONField != "" &&
+			slices.Contains(strings.Split(rule.JSONField, "."), "") {
+			return fmt.Errorf("rules[%d]: invalid json_field %q", i,
+				rule.JSONField)
+		}
+		if _, err := filter.CompileLogFilter(rule.Include); err != nil {
+			return fmt.Errorf("rules[%d]: include: %w", i, err)
+		}
+		for _, key := range rule.RecordAttributes {
+			if key == "" {
+				return fmt.Errorf("rules[%d]: record attribute cannot be "+
+					"empty", i)
+			}
+		}
+	}
+	return nil
+}
+
+// metricType returns the type of the metric, "gauge" unless specified.
+func (r *Rule) metricType() string {
+	if r.Type == "" {
+		return metricTypeGauge
+	}
+	return r.Type
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{}
+}
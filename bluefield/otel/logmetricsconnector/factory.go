@@ -0,0 +1,31 @@
+package logmetricsconnector
+
+import (
+	"context"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/connector"
+	"go.opentelemetry.io/collector/consumer"
+)
+
+const (
+	typeStr   = "log_metrics"
+	stability = component.StabilityLevelAlpha
+)
+
+func NewFactory() connector.Factory {
+	return connector.NewFactory(
+		component.MustNewType(typeStr),
+		createDefaultConfig,
+		connector.WithLogsToMetrics(createLogsToMetrics, stability),
+	)
+}
+
+func createLogsToMetrics(
+	_ context.Context,
+	set connector.CreateSettings,
+	cfg component.Config,
+	nextConsumer consumer.Metrics,
+) (connector.Logs, error) {
+	return newLogMetricsConnector(cfg.(*Config), set.Logger, nextConsumer)
+}
@@ -0,0 +1,3 @@
+module logmetricsconnector
+
+go 1.22
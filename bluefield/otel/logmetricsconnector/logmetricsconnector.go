@@ -0,0 +1,286 @@
+package logmetricsconnector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+
+	"otelcommon/filter"
+)
+
+const scopeName = "logmetricsconnector"
+
+type logMetricsConnector struct {
+	config          *Config
+	logger          *zap.Logger
+	metricsConsumer consumer.Metrics
+	rules           []*rule
+	startTime       pcommon.Timestamp
+}
+
+// rule is a compiled Rule.
+type rule struct {
+	*Rule
+	metricType string
+	include    *filter.LogMatcher
+	re         *regexp.Regexp
+	jsonPath   []string
+}
+
+// attributes looks up labels of the include filters among the log record,
+// scope and resource attributes, in that order.
+type attributes struct {
+	resource pcommon.Map
+	scope    pcommon.Map
+	record   pcommon.Map
+}
+
+func (a *attributes) Get(name string) (string, bool) {
+	for _, m := range []pcommon.Map{a.record, a.scope, a.resource} {
+		if v, exists := m.Get(name); exists {
+			return v.AsString(), true
+		}
+	}
+	return "", false
+}
+
+func newLogMetricsConnector(
+	config *Config,
+	logger *zap.Logger,
+	metricsConsumer consumer.Metrics,
+) (*logMetricsConnector, error) {
+	c := &logMetricsConnector{
+		config:          config,
+		logger:          logger,
+		metricsConsumer: metricsConsumer,
+	}
+	for i := range config.Rules {
+		r := &rule{Rule: &config.Rules[i], metricType: config.Rules[i].metricType()}
+		include, err := filter.CompileLogFilter(r.Include)
+		if err != nil {
+			return nil, fmt.Errorf("rules[%d]: include: %w", i, err)
+		}
+		r.include = include
+		if r.BodyRegex != "" {
+			r.re = regexp.MustCompile(r.BodyRegex)
+		} else {
+			r.jsonPath = strings.Split(r.JSONField, ".")
+		}
+		c.rules = append(c.rules, r)
+	}
+	return c, nil
+}
+
+func (c *logMetricsConnector) Start(context.Context, component.Host) error {
+	c.startTime = pcommon.NewTimestampFromTime(time.Now())
+	return nil
+}
+
+func (c *logMetricsConnector) Shutdown(context.Context) error {
+	return nil
+}
+
+func (c *logMetricsConnector) Capabilities() consumer.Capabilities {
+	return consumer.Capabilities{MutatesData: false}
+}
+
+// ConsumeLogs emits a datapoint for each log record and rule that the value
+// of the rule can be extracted from, with the resource of the log record.
+func (c *logMetricsConnector) ConsumeLogs(ctx context.Context, ld plog.Logs) error {
+	md := pmetric.NewMetrics()
+	now := pcommon.NewTimestampFromTime(time.Now())
+	failed := 0
+
+	rls := ld.ResourceLogs()
+	for i := 0; i < rls.Len(); i++ {
+		rl := rls.At(i)
+		attrs := &attributes{resource: rl.Resource().Attributes()}
+		var metrics map[string]pmetric.Metric
+		sls := rl.ScopeLogs()
+		for j := 0; j < sls.Len(); j++ {
+			sl := sls.At(j)
+			attrs.scope = sl.Scope().Attributes()
+			records := sl.LogRecords()
+			for k := 0; k < records.Len(); k++ {
+				record := records.At(k)
+				attrs.record = record.Attributes()
+				for _, r := range c.rules {
+					if r.include != nil && !r.include.Match(record, attrs) {
+						continue
+					}
+					value, labels, found, err := r.extract(record.Body())
+					if err != nil {
+						failed++
+						continue
+					}
+					if !found {
+						continue
+					}
+					if metrics == nil {
+						metrics = make(map[string]pmetric.Metric)
+						rm := md.ResourceMetrics().AppendEmpty()
+						rl.Resource().CopyTo(rm.Resource())
+						sm := rm.ScopeMetrics().AppendEmpty()
+						sm.Scope().SetName(scopeName)
+						sm.Scope().SetVersion(Version)
+					}
+					dp := c.metric(md, metrics, r).AppendEmpty()
+					c.setDatapoint(dp, r, record, value, labels, now)
+				}
+			}
+		}
+	}
+
+	if failed > 0 {
+		c.logger.Debug("Failed to parse values of log records",
+			zap.Int("log_records", failed))
+	}
+	if md.DataPointCount() == 0 {
+		return nil
+	}
+	return c.metricsConsumer.ConsumeMetrics(ctx, md)
+}
+
+// metric returns the datapoints of the metric of the rule in the last
+// resource of the batch, appending the metric if the resource has none yet.
+func (c *logMetricsConnector) metric(
+	md pmetric.Metrics,
+	metrics map[string]pmetric.Metric,
+	r *rule,
+) pmetric.NumberDataPointSlice {
+	metric, exists := metrics[r.MetricName]
+	if !exists {
+		rms := md.ResourceMetrics()
+		metric = rms.At(rms.Len() - 1).ScopeMetrics().At(0).Metrics().AppendEmpty()
+		metric.SetName(r.MetricName)
+		metric.SetDescription(r.Description)
+		metric.SetUnit(r.Unit)
+		if r.metricType == metricTypeCounter {
+			sum := metric.SetEmptySum()
+			sum.SetIsMonotonic(true)
+			sum.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+		} else {
+			metric.SetEmptyGauge()
+		}
+		metrics[r.MetricName] = metric
+	}
+	if metric.Type() == pmetric.MetricTypeSum {
+		return metric.Sum().DataPoints()
+	}
+	return metric.Gauge().DataPoints()
+}
+
+func (c *logMetricsConnector) setDatapoint(
+	dp pmetric.NumberDataPoint,
+	r *rule,
+	record plog.LogRecord,
+	value float64,
+	labels map[string]string,
+	now pcommon.Timestamp,
+) {
+	switch {
+	case record.Timestamp() != 0:
+		dp.SetTimestamp(record.Timestamp())
+	case record.ObservedTimestamp() != 0:
+		dp.SetTimestamp(record.ObservedTimestamp())
+	default:
+		dp.SetTimestamp(now)
+	}
+	if r.metricType == metricTypeCounter {
+		dp.SetStartTimestamp(c.startTime)
+	}
+	dp.SetDoubleValue(value)
+
+	for k, v := range r.Attributes {
+		dp.Attributes().PutStr(k, v)
+	}
+	for _, key := range r.RecordAttributes {
+		if v, exists := record.Attributes().Get(key); exists {
+			v.CopyTo(dp.Attributes().PutEmpty(key))
+		}
+	}
+	for k, v := range labels {
+		dp.Attributes().PutStr(k, v)
+	}
+}
+
+// extract returns the value of the rule in the log body along with the
+// labels of the other named capture groups, and whether the body holds a
+// value. It returns an error if the value is not a number.
+func (r *rule) extract(body pcommon.Value) (float64, map[string]string, bool, error) {
+	if r.re != nil {
+		match := r.re.FindStringSubmatch(body.AsString())
+		if match == nil {
+			return 0, nil, false, nil
+		}
+		var value string
+		var labels map[string]string
+		for i, name := range r.re.SubexpNames() {
+			switch name {
+			case "":
+			case valueGroup:
+				value = match[i]
+			default:
+				if labels == nil {
+					labels = make(map[string]string)
+				}
+				labels[name] = match[i]
+			}
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		return f, labels, true, err
+	}
+
+	var raw any
+	switch body.Type() {
+	case pcommon.ValueTypeMap:
+		raw = body.Map().AsRaw()
+	case pcommon.ValueTypeStr:
+		s := strings.TrimSpace(body.Str())
+		if !strings.HasPrefix(s, "{") {
+			return 0, nil, false, nil
+		}
+		if err := json.Unmarshal([]byte(s), &raw); err != nil {
+			return 0, nil, false, err
+		}
+	default:
+		return 0, nil, false, nil
+	}
+	for _, key := range r.jsonPath {
+		object, ok := raw.(map[string]any)
+		if !ok {
+			return 0, nil, false, nil
+		}
+		if raw, ok = object[key]; !ok {
+			return 0, nil, false, nil
+		}
+	}
+	switch v := raw.(type) {
+	case float64:
+		return v, nil, true, nil
+	case int64:
+		return float64(v), nil, true, nil
+	case bool:
+		if v {
+			return 1, nil, true, nil
+		}
+		return 0, nil, true, nil
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, nil, true, err
+	default:
+		return 0, nil, true, fmt.Errorf("value of %s is not a number",
+			r.JSONField)
+	}
+}
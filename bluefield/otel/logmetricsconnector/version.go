@@ -0,0 +1,3 @@
+package logmetricsconnector
+
+const Version = "0.0.1"
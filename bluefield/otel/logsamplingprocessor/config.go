@@ -0,0 +1,68 @@
+package logsamplingprocessor
+
+import (
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+// Config defines the configuration of the log_sampling processor.
+type Config struct {
+	// Grouping is the name of a telemetry_stats log grouping. Log records
+	// are sampled per key of the grouping, at a rate derived from the
+	// counts telemetry_stats reports for the key.
+	Grouping string `mapstructure:"grouping"`
+
+	// TargetRate is the number of log records per second that each key is
+	// sampled down to. Keys below the target rate are kept in full.
+	// Defaults to 10.
+	TargetRate float64 `mapstructure:"target_rate"`
+
+	// MaxSampleRate limits how heavily a single key is sampled, keeping at
+	// least 1 in this many log records. Defaults to 1000.
+	MaxSampleRate int64 `mapstructure:"max_sample_rate"`
+
+	// AdjustInterval configures how often sample rates are recomputed from
+	// the telemetry_stats counts. Defaults to "1m".
+	AdjustInterval time.Duration `mapstructure:"adjust_interval"`
+
+	// SampleRateAttribute is the log record attribute set to the sample
+	// rate N of kept log records sampled 1 in N, so that backends can
+	// extrapolate. Log records of keys kept in full don't get the
+	// attribute. Defaults to "sample_rate".
+	SampleRateAttribute string `mapstructure:"sample_rate_attribute"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.Grouping == "" {
+		return errors.New("grouping must be specified")
+	}
+	if cfg.TargetRate <= 0 {
+		return errors.New("target_rate must be positive")
+	}
+	if cfg.MaxSampleRate < 1 {
+		return errors.New("max_sample_rate must be at least 1")
+	}
+	if cfg.AdjustInterval <= 0 {
+		return errors.New("adjust_interval must be positive")
+	}
+	if cfg.SampleRateAttribute == "" {
+		return errors.New("sample_rate_attribute cannot be empty")
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		TargetRate:          10,
+		MaxSampleRate:       1000,
+		AdjustInterval:      time.Minute,
+		SampleRateAttribute: "sample_rate",
+	}
+}
@@ -0,0 +1,3 @@
+module logsamplingprocessor
+
+go 1.22
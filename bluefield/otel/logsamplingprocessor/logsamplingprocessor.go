@@ -0,0 +1,168 @@
+package logsamplingprocessor
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+
+	"telemetrystatsprocessor"
+)
+
+type logSamplingProcessor struct {
+	logger      *zap.Logger
+	config      *Config
+	stopChannel chan struct{}
+	stopWaiters sync.WaitGroup
+
+	// sample rates by telemetry_stats key, replaced on every adjustment
+	sampleRatesRWLock sync.RWMutex
+	grouping          telemetrystatsprocessor.LogGrouping
+	sampleRates       map[string]int64
+
+	// counts of the previous adjustment, only accessed by the adjust loop
+	lastCounts     map[string]int64
+	lastAdjustment time.Time
+	groupingFound  bool
+}
+
+// processor constructor
+func newLogSamplingProcessor(config *Config, logger *zap.Logger) *logSamplingProcessor {
+	return &logSamplingProcessor{
+		logger:      logger,
+		config:      config,
+		stopChannel: make(chan struct{}),
+	}
+}
+
+func (p *logSamplingProcessor) start() {
+	p.stopWaiters.Add(1)
+	go p.adjustLoop()
+}
+
+// processor destructor
+func (p *logSamplingProcessor) cleanup() {
+	close(p.stopChannel)
+	p.stopWaiters.Wait()
+}
+
+func (p *logSamplingProcessor) processLogs(
+	ctx context.Context,
+	ld plog.Logs,
+) (plog.Logs, error) {
+	p.sampleRatesRWLock.RLock()
+	defer p.sampleRatesRWLock.RUnlock()
+
+	// Keep everything until the first adjustment found heavy keys.
+	if len(p.sampleRates) == 0 {
+		return ld, nil
+	}
+
+	ld.ResourceLogs().RemoveIf(func(rl plog.ResourceLogs) bool {
+		resourceAttrs := rl.Resource().Attributes()
+		rl.ScopeLogs().RemoveIf(func(sl plog.ScopeLogs) bool {
+			scopeAttrs := sl.Scope().Attributes()
+			sl.LogRecords().RemoveIf(func(lr plog.LogRecord) bool {
+				attrs := telemetrystatsprocessor.NewAttributes(
+					resourceAttrs, scopeAttrs, lr.Attributes())
+				attrs.SetSeverity(lr.SeverityNumber(), lr.SeverityText())
+				attrs.SetBody(lr.Body())
+				key := telemetrystatsprocessor.LogKey(p.grouping, attrs)
+				return !p.sample(key, lr)
+			})
+			return sl.LogRecords().Len() == 0
+		})
+		return rl.ScopeLogs().Len() == 0
+	})
+
+	return ld, nil
+}
+
+// sample returns whether to keep a log record, recording the sample rate on
+// the kept records of sampled keys. It must be called while holding the read
+// lock.
+func (p *logSamplingProcessor) sample(key string, lr plog.LogRecord) bool {
+	rate, exists := p.sampleRates[key]
+	if !exists || rate <= 1 {
+		return true
+	}
+	if rand.Int63n(rate) != 0 {
+		return false
+	}
+	lr.Attributes().PutInt(p.config.SampleRateAttribute, rate)
+	return true
+}
+
+func (p *logSamplingProcessor) adjustLoop() {
+	defer p.stopWaiters.Done()
+
+	ticker := time.NewTicker(p.config.AdjustInterval)
+	defer ticker.Stop()
+
+	p.adjustSampleRates(time.Now())
+	for {
+		select {
+		case now := <-ticker.C:
+			p.adjustSampleRates(now)
+		case <-p.stopChannel:
+			return
+		}
+	}
+}
+
+// adjustSampleRates computes the rate of log records of each key since the
+// previous adjustment from the telemetry_stats counts, and samples keys above
+// the target rate 1 in N so that they are reduced to about the target rate.
+// Keys that are new or below the target rate are kept in full.
+func (p *logSamplingProcessor) adjustSampleRates(now time.Time) {
+	grouping, counts, found := telemetrystatsprocessor.LogGroupingCounts(
+		p.config.Grouping)
+	if found != p.groupingFound {
+		if found {
+			p.logger.Info("Sampling logs by telemetry_stats grouping",
+				zap.String("grouping", p.config.Grouping))
+		} else {
+			p.logger.Warn("No telemetry_stats processor configures the "+
+				"grouping, keeping all logs",
+				zap.String("grouping", p.config.Grouping))
+		}
+		p.groupingFound = found
+	}
+
+	sampleRates := make(map[string]int64)
+	if found && p.lastCounts != nil {
+		elapsed := now.Sub(p.lastAdjustment).Seconds()
+		for key, count := range counts {
+			delta := count - p.lastCounts[key]
+			if delta < 0 {
+				// counts were reset by a restarted processor
+				delta = count
+			}
+			rate := float64(delta) / elapsed
+			if rate <= p.config.TargetRate {
+				continue
+			}
+			sampleRate := int64(math.Ceil(rate / p.config.TargetRate))
+			if sampleRate > p.config.MaxSampleRate {
+				sampleRate = p.config.MaxSampleRate
+			}
+			sampleRates[key] = sampleRate
+		}
+	}
+	p.lastCounts = counts
+	p.lastAdjustment = now
+
+	p.sampleRatesRWLock.Lock()
+	p.grouping = grouping
+	p.sampleRates = sampleRates
+	p.sampleRatesRWLock.Unlock()
+
+	if len(sampleRates) > 0 {
+		p.logger.Debug("Adjusted log sample rates",
+			zap.Int("sampled_keys", len(sampleRates)))
+	}
+}
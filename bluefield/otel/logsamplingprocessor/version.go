@@ -0,0 +1,3 @@
+package logsamplingprocessor
+
+const Version = "0.0.1"
@@ -0,0 +1,149 @@
+package maintenancewindowprocessor
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+
+	"otelcommon/filter"
+)
+
+const (
+	actionTag  = "tag"
+	actionDrop = "drop"
+)
+
+// severities maps the severity names accepted by `min_severity` to the lowest
+// OTLP severity number of each range
+var severities = map[string]int32{
+	"trace": 1,
+	"debug": 5,
+	"info":  9,
+	"warn":  13,
+	"error": 17,
+	"fatal": 21,
+}
+
+// Config defines the configuration of the maintenance_window processor.
+type Config struct {
+	// ScheduleFile is the path of a JSON maintenance schedule. Exactly one
+	// of ScheduleFile and ScheduleURL must be specified.
+	ScheduleFile string `mapstructure:"schedule_file"`
+
+	// ScheduleURL is the URL of a control plane API serving the JSON
+	// maintenance schedule.
+	ScheduleURL string `mapstructure:"schedule_url"`
+
+	// CAFile is an optional path of a PEM encoded CA bundle used to verify
+	// the control plane API, instead of the system roots.
+	CAFile string `mapstructure:"ca_file"`
+
+	// BearerTokenFile is an optional path of a file holding a token sent
+	// to the control plane API as "Authorization: Bearer <token>". It is
+	// read on every reload, so the token can be rotated.
+	BearerTokenFile string `mapstructure:"bearer_token_file"`
+
+	// ReloadInterval configures how often the schedule is reloaded.
+	// Defaults to "1m".
+	ReloadInterval time.Duration `mapstructure:"reload_interval"`
+
+	// NodeAttribute is the resource attribute identifying the node that
+	// telemetry is about, matched against the nodes of maintenance
+	// windows. Telemetry without the attribute is about the node the
+	// collector runs on, identified by its host name. Defaults to
+	// "host.name".
+	NodeAttribute string `mapstructure:"node_attribute"`
+
+	// Action is "tag" to add attributes to alert-class telemetry of nodes
+	// under maintenance, or "drop" to drop it. Defaults to "tag".
+	Action string `mapstructure:"action"`
+
+	// TagAttribute is the attribute set to the reason of the maintenance
+	// window when tagging. Defaults to "maintenance.reason".
+	TagAttribute string `mapstructure:"tag_attribute"`
+
+	// Alerts selects the alert-class telemetry that is tagged or dropped.
+	Alerts AlertFilter `mapstructure:"alerts"`
+}
+
+// AlertFilter defines which log records and metric datapoints are alert-class.
+// A log record is alert-class if its severity is at least MinSeverity and it
+// matches Attributes. A metric datapoint is alert-class if its metric matches
+// MetricNames or MetricRegex and it matches Attributes.
+type AlertFilter struct {
+	// MinSeverity is the lowest severity of alert-class log records,
+	// one of "trace", "debug", "info", "warn", "error" or "fatal".
+	// Defaults to "warn".
+	MinSeverity string `mapstructure:"min_severity"`
+
+	// MetricNames is a list of names of alert-class metrics.
+	MetricNames []string `mapstructure:"metric_names"`
+
+	// MetricRegex is a regular expression matching names of alert-class
+	// metrics.
+	MetricRegex string `mapstructure:"metric_regex"`
+
+	// Attributes optionally limits alert-class telemetry to log records
+	// and datapoints with all of the attribute values.
+	Attributes []filter.LabelFilter `mapstructure:"attributes"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if (cfg.ScheduleFile == "") == (cfg.ScheduleURL == "") {
+		return errors.New("exactly one of schedule_file and schedule_url " +
+			"must be specified")
+	}
+	if cfg.ScheduleURL != "" {
+		u, err := url.Parse(cfg.ScheduleURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return errors.New("schedule_url must be an http or https URL")
+		}
+	} else if cfg.CAFile != "" || cfg.BearerTokenFile != "" {
+		return errors.New("ca_file and bearer_token_file require schedule_url")
+	}
+	if cfg.ReloadInterval <= 0 {
+		return errors.New("reload_interval must be positive")
+	}
+	if cfg.NodeAttribute == "" {
+		return errors.New("node_attribute cannot be empty")
+	}
+	if cfg.Action != actionTag && cfg.Action != actionDrop {
+		return fmt.Errorf("action must be %q or %q", actionTag, actionDrop)
+	}
+	if cfg.Action == actionTag && cfg.TagAttribute == "" {
+		return errors.New("tag_attribute cannot be empty")
+	}
+	if _, exists := severities[cfg.Alerts.MinSeverity]; !exists {
+		return fmt.Errorf("invalid min_severity %q", cfg.Alerts.MinSeverity)
+	}
+	if cfg.Alerts.MetricRegex != "" {
+		if _, err := regexp.Compile(cfg.Alerts.MetricRegex); err != nil {
+			return fmt.Errorf("invalid metric_regex: %w", err)
+		}
+	}
+	if _, err := filter.CompileLabelFilters(cfg.Alerts.Attributes); err != nil {
+		return fmt.Errorf("alerts attributes: %w", err)
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		ReloadInterval: time.Minute,
+		NodeAttribute:  "host.name",
+		Action:         actionTag,
+		TagAttribute:   "maintenance.reason",
+		Alerts: AlertFilter{
+			MinSeverity: "warn",
+		},
+	}
+}
@@ -0,0 +1,3 @@
+module maintenancewindowprocessor
+
+go 1.22
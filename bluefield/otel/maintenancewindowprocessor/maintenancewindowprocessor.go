@@ -0,0 +1,277 @@
+package maintenancewindowprocessor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+
+	"otelcommon/filter"
+)
+
+type maintenanceWindowProcessor struct {
+	config       *Config
+	logger       *zap.Logger
+	loader       *scheduleLoader
+	hostname     string
+	minSeverity  plog.SeverityNumber
+	reMetric     *regexp.Regexp
+	attributes   *filter.LabelMatcher
+	scheduleLock sync.RWMutex
+	schedule     *schedule
+	activeLock   sync.Mutex
+	active       map[string]string // reasons of nodes last seen under maintenance
+	stopChannel  chan struct{}
+	stopWaiters  sync.WaitGroup
+}
+
+// processor constructor
+func newMaintenanceWindowProcessor(
+	config *Config,
+	logger *zap.Logger,
+) (*maintenanceWindowProcessor, error) {
+	loader, err := newScheduleLoader(config)
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		logger.Warn("Failed to get host name", zap.Error(err))
+	}
+
+	p := &maintenanceWindowProcessor{
+		config:      config,
+		logger:      logger,
+		loader:      loader,
+		hostname:    hostname,
+		minSeverity: plog.SeverityNumber(severities[config.Alerts.MinSeverity]),
+		schedule:    &schedule{},
+		active:      make(map[string]string),
+		stopChannel: make(chan struct{}),
+	}
+	if config.Alerts.MetricRegex != "" {
+		p.reMetric = regexp.MustCompile(config.Alerts.MetricRegex)
+	}
+	p.attributes, err = filter.CompileLabelFilters(config.Alerts.Attributes)
+	if err != nil {
+		return nil, fmt.Errorf("alerts attributes: %w", err)
+	}
+	return p, nil
+}
+
+func (p *maintenanceWindowProcessor) start() {
+	// load the schedule before processing any data, so that alerts of a
+	// node already under maintenance when the collector starts are handled
+	p.reload()
+
+	p.stopWaiters.Add(1)
+	go p.reloadLoop()
+}
+
+// processor destructor
+func (p *maintenanceWindowProcessor) cleanup() {
+	close(p.stopChannel)
+	p.stopWaiters.Wait()
+}
+
+func (p *maintenanceWindowProcessor) reloadLoop() {
+	defer p.stopWaiters.Done()
+
+	ticker := time.NewTicker(p.config.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.reload()
+		case <-p.stopChannel:
+			return
+		}
+	}
+}
+
+// reload replaces the schedule if it loads successfully, and otherwise keeps
+// the last schedule loaded.
+func (p *maintenanceWindowProcessor) reload() {
+	ctx, cancel := context.WithTimeout(context.Background(),
+		p.config.ReloadInterval)
+	defer cancel()
+
+	s, err := p.loader.load(ctx)
+	if err != nil {
+		p.logger.Error("Failed to load maintenance schedule", zap.Error(err))
+		return
+	}
+	if s == nil {
+		return
+	}
+
+	p.scheduleLock.Lock()
+	p.schedule = s
+	p.scheduleLock.Unlock()
+	p.logger.Info("Loaded maintenance schedule",
+		zap.Int("windows", len(s.Windows)))
+}
+
+// maintenanceReason returns the reason of the maintenance window the node of
+// a resource is under, if any.
+func (p *maintenanceWindowProcessor) maintenanceReason(resource pcommon.Map) (string, bool) {
+	node := p.hostname
+	if value, exists := resource.Get(p.config.NodeAttribute); exists {
+		node = value.AsString()
+	}
+
+	p.scheduleLock.RLock()
+	w, active := p.schedule.activeWindow(node, time.Now())
+	p.scheduleLock.RUnlock()
+
+	reason := w.Reason
+	if reason == "" {
+		// the tag attribute needs a value even for windows without reason
+		reason = "maintenance"
+	}
+	p.logTransition(node, reason, active)
+	return reason, active
+}
+
+// logTransition logs when a node is first seen under maintenance and when its
+// maintenance is over.
+func (p *maintenanceWindowProcessor) logTransition(node, reason string, active bool) {
+	p.activeLock.Lock()
+	defer p.activeLock.Unlock()
+
+	_, wasActive := p.active[node]
+	switch {
+	case active && !wasActive:
+		p.active[node] = reason
+		p.logger.Info("Node under maintenance, handling its alerts",
+			zap.String("node", node),
+			zap.String("reason", reason),
+			zap.String("action", p.config.Action))
+	case !active && wasActive:
+		delete(p.active, node)
+		p.logger.Info("Node maintenance over", zap.String("node", node))
+	}
+}
+
+func (p *maintenanceWindowProcessor) processLogs(
+	_ context.Context,
+	ld plog.Logs,
+) (plog.Logs, error) {
+	ld.ResourceLogs().RemoveIf(func(rl plog.ResourceLogs) bool {
+		reason, active := p.maintenanceReason(rl.Resource().Attributes())
+		if !active {
+			return false
+		}
+		rl.ScopeLogs().RemoveIf(func(sl plog.ScopeLogs) bool {
+			sl.LogRecords().RemoveIf(func(lr plog.LogRecord) bool {
+				if !p.isAlertLog(lr) {
+					return false
+				}
+				if p.config.Action == actionDrop {
+					return true
+				}
+				lr.Attributes().PutStr(p.config.TagAttribute, reason)
+				return false
+			})
+			return sl.LogRecords().Len() == 0
+		})
+		return rl.ScopeLogs().Len() == 0
+	})
+	return ld, nil
+}
+
+func (p *maintenanceWindowProcessor) processMetrics(
+	_ context.Context,
+	md pmetric.Metrics,
+) (pmetric.Metrics, error) {
+	md.ResourceMetrics().RemoveIf(func(rm pmetric.ResourceMetrics) bool {
+		reason, active := p.maintenanceReason(rm.Resource().Attributes())
+		if !active {
+			return false
+		}
+		rm.ScopeMetrics().RemoveIf(func(sm pmetric.ScopeMetrics) bool {
+			sm.Metrics().RemoveIf(func(metric pmetric.Metric) bool {
+				if !p.isAlertMetric(metric.Name()) {
+					return false
+				}
+				return p.handleDatapoints(metric, reason) == 0
+			})
+			return sm.Metrics().Len() == 0
+		})
+		return rm.ScopeMetrics().Len() == 0
+	})
+	return md, nil
+}
+
+func (p *maintenanceWindowProcessor) isAlertLog(lr plog.LogRecord) bool {
+	return lr.SeverityNumber() >= p.minSeverity &&
+		p.matchAttributes(lr.Attributes())
+}
+
+func (p *maintenanceWindowProcessor) isAlertMetric(name string) bool {
+	return slices.Contains(p.config.Alerts.MetricNames, name) ||
+		(p.reMetric != nil && p.reMetric.MatchString(name))
+}
+
+func (p *maintenanceWindowProcessor) matchAttributes(attrs pcommon.Map) bool {
+	return p.attributes.MatchAll(filter.MapAttributes(attrs))
+}
+
+// handleDatapoints tags or drops the alert-class datapoints of an alert-class
+// metric, and returns the number of remaining datapoints.
+func (p *maintenanceWindowProcessor) handleDatapoints(metric pmetric.Metric, reason string) int {
+	handle := func(attrs pcommon.Map) bool {
+		if !p.matchAttributes(attrs) {
+			return false
+		}
+		if p.config.Action == actionDrop {
+			return true
+		}
+		attrs.PutStr(p.config.TagAttribute, reason)
+		return false
+	}
+
+	switch metric.Type() {
+	case pmetric.MetricTypeGauge:
+		dps := metric.Gauge().DataPoints()
+		dps.RemoveIf(func(dp pmetric.NumberDataPoint) bool {
+			return handle(dp.Attributes())
+		})
+		return dps.Len()
+	case pmetric.MetricTypeSum:
+		dps := metric.Sum().DataPoints()
+		dps.RemoveIf(func(dp pmetric.NumberDataPoint) bool {
+			return handle(dp.Attributes())
+		})
+		return dps.Len()
+	case pmetric.MetricTypeHistogram:
+		dps := metric.Histogram().DataPoints()
+		dps.RemoveIf(func(dp pmetric.HistogramDataPoint) bool {
+			return handle(dp.Attributes())
+		})
+		return dps.Len()
+	case pmetric.MetricTypeExponentialHistogram:
+		dps := metric.ExponentialHistogram().DataPoints()
+		dps.RemoveIf(func(dp pmetric.ExponentialHistogramDataPoint) bool {
+			return handle(dp.Attributes())
+		})
+		return dps.Len()
+	case pmetric.MetricTypeSummary:
+		dps := metric.Summary().DataPoints()
+		dps.RemoveIf(func(dp pmetric.SummaryDataPoint) bool {
+			return handle(dp.Attributes())
+		})
+		return dps.Len()
+	}
+	return 1
+}
@@ -0,0 +1,167 @@
+package maintenancewindowprocessor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maxScheduleSize limits how much of a schedule is read
+const maxScheduleSize = 4 << 20
+
+// schedule is the JSON maintenance schedule, e.g.
+//
+//	{"windows": [{"nodes": ["dpu-0123"], "start": "2024-05-01T10:00:00Z",
+//	  "end": "2024-05-01T12:00:00Z", "reason": "firmware update"}]}
+type schedule struct {
+	Windows []window `json:"windows"`
+}
+
+// window is a maintenance window of some or, if Nodes is empty, all nodes.
+type window struct {
+	Nodes  []string  `json:"nodes"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Reason string    `json:"reason"`
+}
+
+func (s *schedule) validate() error {
+	for i, w := range s.Windows {
+		if w.Start.IsZero() || w.End.IsZero() {
+			return fmt.Errorf("window %d: start and end must be specified", i)
+		}
+		if !w.End.After(w.Start) {
+			return fmt.Errorf("window %d: end must be after start", i)
+		}
+	}
+	return nil
+}
+
+// activeWindow returns the maintenance window of the node at the given time,
+// if any.
+func (s *schedule) activeWindow(node string, now time.Time) (window, bool) {
+	for _, w := range s.Windows {
+		if now.Before(w.Start) || !now.Before(w.End) {
+			continue
+		}
+		if len(w.Nodes) == 0 || slices.Contains(w.Nodes, node) {
+			return w, true
+		}
+	}
+	return window{}, false
+}
+
+// scheduleLoader loads the schedule from the configured file or URL.
+type scheduleLoader struct {
+	config  *Config
+	client  *http.Client
+	modTime time.Time // of the schedule file when it was last loaded
+}
+
+func newScheduleLoader(config *Config) (*scheduleLoader, error) {
+	l := &scheduleLoader{config: config}
+	if config.ScheduleURL == "" {
+		return l, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.CAFile != "" {
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca_file: %w", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificates found in ca_file")
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: roots}
+	}
+	l.client = &http.Client{
+		Transport: transport,
+		Timeout:   30 * time.Second,
+	}
+	return l, nil
+}
+
+// load returns the schedule, or nil if the schedule file hasn't changed since
+// it was last loaded.
+func (l *scheduleLoader) load(ctx context.Context) (*schedule, error) {
+	var data []byte
+	var err error
+	if l.config.ScheduleURL != "" {
+		data, err = l.fetch(ctx)
+	} else {
+		data, err = l.readFile()
+	}
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	var s schedule
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid schedule: %w", err)
+	}
+	if err := s.validate(); err != nil {
+		return nil, fmt.Errorf("invalid schedule: %w", err)
+	}
+	return &s, nil
+}
+
+func (l *scheduleLoader) readFile() ([]byte, error) {
+	info, err := os.Stat(l.config.ScheduleFile)
+	if err != nil {
+		return nil, err
+	}
+	if info.ModTime().Equal(l.modTime) {
+		return nil, nil
+	}
+
+	file, err := os.Open(l.config.ScheduleFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxScheduleSize))
+	if err != nil {
+		return nil, err
+	}
+	l.modTime = info.ModTime()
+	return data, nil
+}
+
+func (l *scheduleLoader) fetch(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		l.config.ScheduleURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if l.config.BearerTokenFile != "" {
+		token, err := os.ReadFile(l.config.BearerTokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bearer_token_file: %w", err)
+		}
+		req.Header.Set("Authorization",
+			"Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxScheduleSize))
+}
@@ -0,0 +1,3 @@
+package maintenancewindowprocessor
+
+const Version = "0.0.1"
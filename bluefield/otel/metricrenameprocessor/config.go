@@ -0,0 +1,120 @@
+package metricrenameprocessor
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+const (
+	// on a collision, the metric keeps the name it had before
+	onCollisionKeep = "keep"
+	// on a collision, the metric is dropped
+	onCollisionDrop = "drop"
+)
+
+// Config defines the configuration of the metric_rename processor.
+type Config struct {
+	// Rules rename metrics. The first rule matching the name of a metric
+	// is applied.
+	Rules []Rule `mapstructure:"rules"`
+
+	// Prefix is a namespace prepended to the names of all metrics after
+	// renaming, e.g. "bf3.". Names already starting with the prefix are
+	// left unchanged.
+	Prefix string `mapstructure:"prefix"`
+
+	// OnCollision configures what happens to a metric whose new name is
+	// already the name of a different metric: "keep" keeps its original
+	// name, "drop" drops it. Collisions are logged either way. Defaults to
+	// "keep".
+	OnCollision string `mapstructure:"on_collision"`
+}
+
+// Rule renames the metrics whose names match a regular expression.
+type Rule struct {
+	// Match is a regular expression that must match the whole name of a
+	// metric for the rule to apply.
+	Match string `mapstructure:"match"`
+
+	// Name is the new name of the metric. It can reference capture groups
+	// of Match as $1 or ${name}.
+	Name string `mapstructure:"name"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// groupReference matches references to capture groups in a rule's new name
+var groupReference = regexp.MustCompile(`\$(\$|\{[^}]*\}|[A-Za-z0-9_]*)`)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if len(cfg.Rules) == 0 && cfg.Prefix == "" {
+		return errors.New("rules or prefix must be specified")
+	}
+	for i, rule := range cfg.Rules {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("rules[%d]: %w", i, err)
+		}
+	}
+	if cfg.OnCollision != onCollisionKeep && cfg.OnCollision != onCollisionDrop {
+		return fmt.Errorf("on_collision must be %q or %q",
+			onCollisionKeep, onCollisionDrop)
+	}
+	return nil
+}
+
+func (rule *Rule) validate() error {
+	if rule.Match == "" {
+		return errors.New("match must be specified")
+	}
+	re, err := rule.compile()
+	if err != nil {
+		return fmt.Errorf("invalid match: %w", err)
+	}
+	if rule.Name == "" {
+		return errors.New("name must be specified")
+	}
+
+	// regexp.Expand silently replaces unknown groups with an empty string,
+	// e.g. "$1_total" refers to a group named "1_total"
+	for _, ref := range groupReference.FindAllStringSubmatch(rule.Name, -1) {
+		group := strings.TrimSuffix(strings.TrimPrefix(ref[1], "{"), "}")
+		switch {
+		case group == "$":
+			continue
+		case group == "":
+			return fmt.Errorf("name %q: empty group reference, use $$ "+
+				"for a literal $", rule.Name)
+		}
+		if n, err := strconv.Atoi(group); err == nil {
+			if n > re.NumSubexp() {
+				return fmt.Errorf("name %q: match has no group %d",
+					rule.Name, n)
+			}
+		} else if re.SubexpIndex(group) < 0 {
+			return fmt.Errorf("name %q: match has no group %q, use ${...} "+
+				"to separate a group reference from the text after it",
+				rule.Name, group)
+		}
+	}
+	return nil
+}
+
+// compile returns the rule's regular expression, anchored to match whole
+// names.
+func (rule *Rule) compile() (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + rule.Match + ")$")
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		OnCollision: onCollisionKeep,
+	}
+}
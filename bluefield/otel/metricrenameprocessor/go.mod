@@ -0,0 +1,3 @@
+module metricrenameprocessor
+
+go 1.22
@@ -0,0 +1,105 @@
+package metricrenameprocessor
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+type metricRenameProcessor struct {
+	config     *Config
+	logger     *zap.Logger
+	rules      []*regexp.Regexp
+	namesLock  sync.Mutex
+	sources    map[string]string // original names of the metrics by final name
+	collisions map[[2]string]struct{}
+}
+
+// processor constructor
+func newMetricRenameProcessor(config *Config, logger *zap.Logger) *metricRenameProcessor {
+	p := &metricRenameProcessor{
+		config:     config,
+		logger:     logger,
+		sources:    make(map[string]string),
+		collisions: make(map[[2]string]struct{}),
+	}
+	for _, rule := range config.Rules {
+		re, _ := rule.compile()
+		p.rules = append(p.rules, re)
+	}
+	return p
+}
+
+// newName returns the name of a metric after applying the first matching rule
+// and the prefix.
+func (p *metricRenameProcessor) newName(name string) string {
+	newName := name
+	for i, re := range p.rules {
+		match := re.FindStringSubmatchIndex(name)
+		if match == nil {
+			continue
+		}
+		newName = string(re.ExpandString(nil, p.config.Rules[i].Name, name, match))
+		break
+	}
+	if !strings.HasPrefix(newName, p.config.Prefix) {
+		newName = p.config.Prefix + newName
+	}
+	return newName
+}
+
+func (p *metricRenameProcessor) processMetrics(
+	_ context.Context,
+	md pmetric.Metrics,
+) (pmetric.Metrics, error) {
+	p.namesLock.Lock()
+	defer p.namesLock.Unlock()
+
+	md.ResourceMetrics().RemoveIf(func(rm pmetric.ResourceMetrics) bool {
+		rm.ScopeMetrics().RemoveIf(func(sm pmetric.ScopeMetrics) bool {
+			sm.Metrics().RemoveIf(func(metric pmetric.Metric) bool {
+				return !p.rename(metric)
+			})
+			return sm.Metrics().Len() == 0
+		})
+		return rm.ScopeMetrics().Len() == 0
+	})
+	return md, nil
+}
+
+// rename renames a metric unless its new name collides with the name of a
+// different metric, and returns false if the metric is to be dropped.
+func (p *metricRenameProcessor) rename(metric pmetric.Metric) bool {
+	name := metric.Name()
+	newName := p.newName(name)
+
+	// the sources of names are remembered across batches, since metrics of
+	// different receivers rarely arrive in the same batch
+	source, exists := p.sources[newName]
+	if !exists || source == name {
+		p.sources[newName] = name
+		metric.SetName(newName)
+		return true
+	}
+
+	p.logCollision(name, newName, source)
+	return p.config.OnCollision != onCollisionDrop
+}
+
+// logCollision logs the first collision of each pair of metrics.
+func (p *metricRenameProcessor) logCollision(name, newName, source string) {
+	pair := [2]string{name, source}
+	if _, logged := p.collisions[pair]; logged {
+		return
+	}
+	p.collisions[pair] = struct{}{}
+	p.logger.Error("Metric name collision",
+		zap.String("metric", name),
+		zap.String("new_name", newName),
+		zap.String("colliding_metric", source),
+		zap.String("action", p.config.OnCollision))
+}
@@ -0,0 +1,3 @@
+package metricrenameprocessor
+
+const Version = "0.0.1"
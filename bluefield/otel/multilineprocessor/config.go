@@ -0,0 +1,98 @@
+package multilineprocessor
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+// Config defines the configuration of the multiline processor.
+type Config struct {
+	// StartPattern is a regular expression matching the first line of a
+	// multi-line event, e.g. "^\\[ *[0-9]+\\.[0-9]+\\] ". Unless
+	// ContinuePattern is specified, any other line continues the event.
+	StartPattern string `mapstructure:"start_pattern"`
+
+	// ContinuePattern is a regular expression matching the lines that
+	// continue a multi-line event, e.g. "^\\s+at ". Unless StartPattern is
+	// specified, any other line starts an event. At least one of
+	// StartPattern and ContinuePattern must be specified.
+	ContinuePattern string `mapstructure:"continue_pattern"`
+
+	// StreamAttributes are the log record attributes that, along with the
+	// resource, identify a stream of lines, e.g. a file or a systemd
+	// unit. Lines of different streams are never assembled into one
+	// event. Defaults to ["log.file.path"].
+	StreamAttributes []string `mapstructure:"stream_attributes"`
+
+	// Timeout is how long an event waits for further lines before it is
+	// sent as it is. Defaults to "1s".
+	Timeout time.Duration `mapstructure:"timeout"`
+
+	// MaxLines is the number of lines after which an event is sent even
+	// if further lines continue it. They then start a new event.
+	// Defaults to 500.
+	MaxLines int `mapstructure:"max_lines"`
+
+	// MaxBytes is the size of the body after which an event is sent even
+	// if further lines continue it. Defaults to 65536.
+	MaxBytes int `mapstructure:"max_bytes"`
+
+	// Separator joins the lines of an event. Defaults to "\n".
+	Separator string `mapstructure:"separator"`
+
+	// LineCountAttribute is the log record attribute set to the number of
+	// lines of events with more than one line. Empty to not set it.
+	// Defaults to "log.line_count".
+	LineCountAttribute string `mapstructure:"line_count_attribute"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.StartPattern == "" && cfg.ContinuePattern == "" {
+		return errors.New("start_pattern or continue_pattern must be specified")
+	}
+	if cfg.StartPattern != "" {
+		if _, err := regexp.Compile(cfg.StartPattern); err != nil {
+			return fmt.Errorf("invalid start_pattern: %w", err)
+		}
+	}
+	if cfg.ContinuePattern != "" {
+		if _, err := regexp.Compile(cfg.ContinuePattern); err != nil {
+			return fmt.Errorf("invalid continue_pattern: %w", err)
+		}
+	}
+	for _, key := range cfg.StreamAttributes {
+		if key == "" {
+			return errors.New("stream attribute cannot be empty")
+		}
+	}
+	if cfg.Timeout <= 0 {
+		return errors.New("timeout must be positive")
+	}
+	if cfg.MaxLines < 2 {
+		return errors.New("max_lines must be at least 2")
+	}
+	if cfg.MaxBytes <= 0 {
+		return errors.New("max_bytes must be positive")
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		StreamAttributes:   []string{"log.file.path"},
+		Timeout:            time.Second,
+		MaxLines:           500,
+		MaxBytes:           64 << 10,
+		Separator:          "\n",
+		LineCountAttribute: "log.line_count",
+	}
+}
@@ -0,0 +1,3 @@
+module multilineprocessor
+
+go 1.22
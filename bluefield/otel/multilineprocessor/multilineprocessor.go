@@ -0,0 +1,268 @@
+package multilineprocessor
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+	"go.uber.org/zap"
+
+	"otelcommon/attrhash"
+)
+
+// minFlushInterval limits how often events are checked for their timeout
+const minFlushInterval = 10 * time.Millisecond
+
+type multilineProcessor struct {
+	config      *Config
+	logger      *zap.Logger
+	reStart     *regexp.Regexp
+	reContinue  *regexp.Regexp
+	nextLogs    consumer.Logs
+	stopChannel chan struct{}
+	stopWaiters sync.WaitGroup
+
+	// events being assembled by stream. The lock is held while events
+	// are sent, so that the lines of a stream stay in order.
+	pendingLock sync.Mutex
+	pending     map[uint64]*event
+}
+
+// event is a multi-line event being assembled from the log record of its
+// first line.
+type event struct {
+	resource     pcommon.Resource
+	resourceHash uint64
+	scope        pcommon.InstrumentationScope
+	record       plog.LogRecord
+	body         strings.Builder
+	lines        int
+	lastLine     time.Time
+}
+
+// processor constructor
+func newMultilineProcessor(config *Config, logger *zap.Logger) *multilineProcessor {
+	p := &multilineProcessor{
+		config:      config,
+		logger:      logger,
+		stopChannel: make(chan struct{}),
+		pending:     make(map[uint64]*event),
+	}
+	if config.StartPattern != "" {
+		p.reStart = regexp.MustCompile(config.StartPattern)
+	}
+	if config.ContinuePattern != "" {
+		p.reContinue = regexp.MustCompile(config.ContinuePattern)
+	}
+	return p
+}
+
+func (p *multilineProcessor) start() {
+	p.stopWaiters.Add(1)
+	go p.flushLoop()
+}
+
+// processor destructor
+func (p *multilineProcessor) cleanup(ctx context.Context) {
+	close(p.stopChannel)
+	p.stopWaiters.Wait()
+
+	// events still being assembled are sent as they are
+	p.pendingLock.Lock()
+	defer p.pendingLock.Unlock()
+	p.send(ctx, func(*event) bool { return true })
+}
+
+func (p *multilineProcessor) flushLoop() {
+	defer p.stopWaiters.Done()
+
+	ticker := time.NewTicker(max(p.config.Timeout/4, minFlushInterval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(),
+				p.config.Timeout)
+			now := time.Now()
+			p.pendingLock.Lock()
+			p.send(ctx, func(e *event) bool {
+				return now.Sub(e.lastLine) >= p.config.Timeout
+			})
+			p.pendingLock.Unlock()
+			cancel()
+		case <-p.stopChannel:
+			return
+		}
+	}
+}
+
+// send sends the pending events selected by the filter to the next consumer.
+// The pending lock must be held.
+func (p *multilineProcessor) send(ctx context.Context, selected func(*event) bool) {
+	out := newBatch()
+	for stream, e := range p.pending {
+		if selected(e) {
+			out.appendEvent(e, p.config.LineCountAttribute)
+			delete(p.pending, stream)
+		}
+	}
+	if out.logs.LogRecordCount() == 0 {
+		return
+	}
+	if err := p.nextLogs.ConsumeLogs(ctx, out.logs); err != nil {
+		p.logger.Error("Failed to send multi-line events", zap.Error(err))
+	}
+}
+
+// processLogs passes on the events completed by the log records, and keeps
+// those that further lines may continue. A line continuing an event completes
+// the previous event of its stream, if any.
+func (p *multilineProcessor) processLogs(
+	_ context.Context,
+	ld plog.Logs,
+) (plog.Logs, error) {
+	p.pendingLock.Lock()
+	defer p.pendingLock.Unlock()
+
+	now := time.Now()
+	out := newBatch()
+	for i := 0; i < ld.ResourceLogs().Len(); i++ {
+		rl := ld.ResourceLogs().At(i)
+		resourceHash := attrhash.Attributes(0, rl.Resource().Attributes())
+		for j := 0; j < rl.ScopeLogs().Len(); j++ {
+			sl := rl.ScopeLogs().At(j)
+			for k := 0; k < sl.LogRecords().Len(); k++ {
+				lr := sl.LogRecords().At(k)
+				stream := p.stream(resourceHash, lr)
+				line := lr.Body().AsString()
+
+				e := p.pending[stream]
+				continues := e != nil && p.continues(line)
+				if continues && !p.full(e, line) {
+					e.body.WriteString(p.config.Separator)
+					e.body.WriteString(line)
+					e.lines++
+					e.lastLine = now
+					continue
+				}
+				if e != nil {
+					out.appendEvent(e, p.config.LineCountAttribute)
+					delete(p.pending, stream)
+				}
+				// the rest of a full event starts a new event
+				if p.starts(line) || continues {
+					p.pending[stream] = newEvent(rl.Resource(), resourceHash,
+						sl.Scope(), lr, now)
+				} else {
+					// a continuation without an event to continue
+					lr.CopyTo(out.scopeLogs(rl.Resource(), resourceHash,
+						sl.Scope()).LogRecords().AppendEmpty())
+				}
+			}
+		}
+	}
+
+	if out.logs.LogRecordCount() == 0 {
+		return out.logs, processorhelper.ErrSkipProcessingData
+	}
+	return out.logs, nil
+}
+
+// stream identifies the stream of a log record.
+func (p *multilineProcessor) stream(resourceHash uint64, lr plog.LogRecord) uint64 {
+	stream := resourceHash
+	for _, key := range p.config.StreamAttributes {
+		value, _ := lr.Attributes().Get(key)
+		stream = attrhash.String(stream, value.AsString())
+	}
+	return stream
+}
+
+// starts returns whether a line can start an event.
+func (p *multilineProcessor) starts(line string) bool {
+	return p.reStart == nil || p.reStart.MatchString(line)
+}
+
+// continues returns whether a line continues the event before it.
+func (p *multilineProcessor) continues(line string) bool {
+	if p.reContinue != nil {
+		return p.reContinue.MatchString(line)
+	}
+	return !p.reStart.MatchString(line)
+}
+
+// full returns whether an event is too long to be continued by a line.
+func (p *multilineProcessor) full(e *event, line string) bool {
+	return e.lines >= p.config.MaxLines ||
+		e.body.Len()+len(p.config.Separator)+len(line) > p.config.MaxBytes
+}
+
+func newEvent(
+	resource pcommon.Resource,
+	resourceHash uint64,
+	scope pcommon.InstrumentationScope,
+	lr plog.LogRecord,
+	now time.Time,
+) *event {
+	e := &event{
+		resource:     pcommon.NewResource(),
+		resourceHash: resourceHash,
+		scope:        pcommon.NewInstrumentationScope(),
+		record:       plog.NewLogRecord(),
+		lines:        1,
+		lastLine:     now,
+	}
+	resource.CopyTo(e.resource)
+	scope.CopyTo(e.scope)
+	lr.CopyTo(e.record)
+	e.body.WriteString(lr.Body().AsString())
+	return e
+}
+
+// batch collects log records, grouping those of the same resource and scope.
+type batch struct {
+	logs   plog.Logs
+	scopes map[uint64]plog.ScopeLogs
+}
+
+func newBatch() *batch {
+	return &batch{
+		logs:   plog.NewLogs(),
+		scopes: make(map[uint64]plog.ScopeLogs),
+	}
+}
+
+func (b *batch) scopeLogs(
+	resource pcommon.Resource,
+	resourceHash uint64,
+	scope pcommon.InstrumentationScope,
+) plog.ScopeLogs {
+	key := attrhash.String(attrhash.String(resourceHash, scope.Name()), scope.Version())
+	sl, exists := b.scopes[key]
+	if !exists {
+		rl := b.logs.ResourceLogs().AppendEmpty()
+		resource.CopyTo(rl.Resource())
+		sl = rl.ScopeLogs().AppendEmpty()
+		scope.CopyTo(sl.Scope())
+		b.scopes[key] = sl
+	}
+	return sl
+}
+
+func (b *batch) appendEvent(e *event, lineCountAttribute string) {
+	lr := b.scopeLogs(e.resource, e.resourceHash, e.scope).LogRecords().AppendEmpty()
+	e.record.CopyTo(lr)
+	if e.lines > 1 {
+		lr.Body().SetStr(e.body.String())
+		if lineCountAttribute != "" {
+			lr.Attributes().PutInt(lineCountAttribute, int64(e.lines))
+		}
+	}
+}
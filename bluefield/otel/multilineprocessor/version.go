@@ -0,0 +1,3 @@
+package multilineprocessor
+
+const Version = "0.0.1"
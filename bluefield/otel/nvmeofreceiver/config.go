@@ -0,0 +1,47 @@
+package nvmeofreceiver
+
+import (
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+// Config defines the configuration of the nvme_of receiver.
+type Config struct {
+	// CollectionInterval configures how often controller states are
+	// collected. Defaults to "10s".
+	CollectionInterval time.Duration `mapstructure:"collection_interval"`
+
+	// SysfsPath is the mount point of sysfs. Defaults to "/sys".
+	SysfsPath string `mapstructure:"sysfs_path"`
+
+	// KmsgPath is the kernel log device the receiver follows to count
+	// reconnect attempts and keep-alive failures, which sysfs doesn't
+	// expose. Defaults to "/dev/kmsg". Leave empty to only report the
+	// controller states.
+	KmsgPath string `mapstructure:"kmsg_path"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.CollectionInterval <= 0 {
+		return errors.New("collection_interval must be positive")
+	}
+	if cfg.SysfsPath == "" {
+		return errors.New("sysfs_path cannot be empty")
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		CollectionInterval: 10 * time.Second,
+		SysfsPath:          "/sys",
+		KmsgPath:           "/dev/kmsg",
+	}
+}
@@ -0,0 +1,3 @@
+module nvmeofreceiver
+
+go 1.22
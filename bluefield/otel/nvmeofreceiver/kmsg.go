@@ -0,0 +1,119 @@
+package nvmeofreceiver
+
+import (
+	"errors"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// logged by the rdma and tcp transports before each reconnect attempt
+	reReconnecting = regexp.MustCompile(`^nvme (nvme[0-9]+): Reconnecting in `)
+	// logged when a keep-alive command fails or the transport times it out
+	reKeepAliveFailed = regexp.MustCompile(`^nvme (nvme[0-9]+): (?:failed nvme_keep_alive_end_io|.*\(Keep Alive\).* timeout)`)
+)
+
+// kmsgCounts are the events of a controller logged by the kernel.
+type kmsgCounts struct {
+	reconnects        int64
+	keepAliveFailures int64
+}
+
+// kmsgFollower counts the events the kernel logs about controllers.
+type kmsgFollower struct {
+	file   *os.File
+	logger *zap.Logger
+
+	lock   sync.Mutex
+	counts map[string]*kmsgCounts // by controller name, since the last take
+}
+
+// openKmsg opens the kernel log, skipping the messages logged before.
+func openKmsg(path string, logger *zap.Logger) (*kmsgFollower, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Seek(0, io.SeekEnd); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &kmsgFollower{
+		file:   file,
+		logger: logger,
+		counts: make(map[string]*kmsgCounts),
+	}, nil
+}
+
+// follow reads kernel messages until the kernel log is closed. Each read
+// returns one message.
+func (f *kmsgFollower) follow() {
+	buf := make([]byte, 8192)
+	for {
+		n, err := f.file.Read(buf)
+		if errors.Is(err, syscall.EPIPE) {
+			// messages were overwritten before they were read
+			continue
+		}
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				f.logger.Error("Failed to read kernel messages", zap.Error(err))
+			}
+			return
+		}
+		f.count(kmsgText(buf[:n]))
+	}
+}
+
+func (f *kmsgFollower) close() {
+	f.file.Close()
+}
+
+func (f *kmsgFollower) count(message string) {
+	var counter func(*kmsgCounts)
+	var match []string
+	if match = reReconnecting.FindStringSubmatch(message); match != nil {
+		counter = func(c *kmsgCounts) { c.reconnects++ }
+	} else if match = reKeepAliveFailed.FindStringSubmatch(message); match != nil {
+		counter = func(c *kmsgCounts) { c.keepAliveFailures++ }
+	} else {
+		return
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	counts, exists := f.counts[match[1]]
+	if !exists {
+		counts = &kmsgCounts{}
+		f.counts[match[1]] = counts
+	}
+	counter(counts)
+}
+
+// take returns the events counted since the previous take.
+func (f *kmsgFollower) take() map[string]*kmsgCounts {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	counts := f.counts
+	f.counts = make(map[string]*kmsgCounts)
+	return counts
+}
+
+// kmsgText returns the text of a kernel message record, e.g.
+//
+//	3,1852,81273641,-;nvme nvme0: Reconnecting in 10 seconds...
+//	 SUBSYSTEM=nvme
+func kmsgText(record []byte) string {
+	_, text, found := strings.Cut(string(record), ";")
+	if !found {
+		return ""
+	}
+	text, _, _ = strings.Cut(text, "\n")
+	return text
+}
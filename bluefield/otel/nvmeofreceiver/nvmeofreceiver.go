@@ -0,0 +1,367 @@
+package nvmeofreceiver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+
+	"otelcommon/metricappend"
+)
+
+const scopeName = "nvmeofreceiver"
+
+var reController = regexp.MustCompile(`^nvme[0-9]+$`)
+
+// controllerStates are the states of the kernel's nvme_ctrl_state, which are
+// each reported so that alerts can match the states that aren't "live".
+var controllerStates = []string{
+	"new", "live", "resetting", "connecting", "deleting", "deleting (no IO)", "dead",
+}
+
+// controller is a fabrics controller as listed in
+// <sysfs>/class/nvme-fabrics/ctl.
+type controller struct {
+	name      string
+	state     string
+	transport string
+	address   map[string]string // e.g. traddr and trsvcid
+	subsysNQN string
+	queues    int64 // -1 if unknown
+}
+
+// identity tells controllers apart that were created with the same name.
+func (c *controller) identity() string {
+	return strings.Join([]string{c.transport, c.address["traddr"],
+		c.address["trsvcid"], c.subsysNQN}, "|")
+}
+
+// controllerCounters are the counters of a controller since the receiver first
+// saw it.
+type controllerCounters struct {
+	identity          string
+	start             pcommon.Timestamp
+	state             string
+	stateChanges      int64
+	reconnects        int64
+	keepAliveFailures int64
+}
+
+type nvmeOFReceiver struct {
+	config       *Config
+	logger       *zap.Logger
+	nextConsumer consumer.Metrics
+	kmsg         *kmsgFollower // nil unless following the kernel log
+	stopChannel  chan struct{}
+	stopWaiters  sync.WaitGroup
+
+	// counters by controller name, removed once the controller is gone
+	counters map[string]*controllerCounters
+}
+
+func newNVMeOFReceiver(
+	config *Config,
+	logger *zap.Logger,
+	nextConsumer consumer.Metrics,
+) *nvmeOFReceiver {
+	return &nvmeOFReceiver{
+		config:       config,
+		logger:       logger,
+		nextConsumer: nextConsumer,
+		stopChannel:  make(chan struct{}),
+		counters:     make(map[string]*controllerCounters),
+	}
+}
+
+func (r *nvmeOFReceiver) Start(_ context.Context, _ component.Host) error {
+	if r.config.KmsgPath != "" {
+		kmsg, err := openKmsg(r.config.KmsgPath, r.logger)
+		if err != nil {
+			// e.g. without CAP_SYSLOG, the states are still reported
+			r.logger.Warn("Failed to open kernel log, reconnects and "+
+				"keep-alive failures are not counted", zap.Error(err))
+		} else {
+			r.kmsg = kmsg
+			r.stopWaiters.Add(1)
+			go func() {
+				defer r.stopWaiters.Done()
+				kmsg.follow()
+			}()
+		}
+	}
+	r.stopWaiters.Add(1)
+	go r.collectLoop()
+	return nil
+}
+
+func (r *nvmeOFReceiver) Shutdown(context.Context) error {
+	close(r.stopChannel)
+	if r.kmsg != nil {
+		r.kmsg.close()
+	}
+	r.stopWaiters.Wait()
+	return nil
+}
+
+func (r *nvmeOFReceiver) collectLoop() {
+	defer r.stopWaiters.Done()
+
+	ticker := time.NewTicker(r.config.CollectionInterval)
+	defer ticker.Stop()
+
+	r.collect()
+	for {
+		select {
+		case <-ticker.C:
+			r.collect()
+		case <-r.stopChannel:
+			return
+		}
+	}
+}
+
+func (r *nvmeOFReceiver) collect() {
+	ctx, cancel := context.WithTimeout(context.Background(),
+		r.config.CollectionInterval)
+	defer cancel()
+
+	controllers, err := r.readControllers()
+	if err != nil {
+		r.logger.Error("Failed to read NVMe-oF controllers", zap.Error(err))
+		return
+	}
+	r.updateCounters(controllers)
+	if len(controllers) == 0 {
+		return
+	}
+
+	now := pcommon.NewTimestampFromTime(time.Now())
+	md := pmetric.NewMetrics()
+	sm := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty()
+	sm.Scope().SetName(scopeName)
+	sm.Scope().SetVersion(Version)
+	r.appendMetrics(sm.Metrics(), controllers, now)
+
+	if err := r.nextConsumer.ConsumeMetrics(ctx, md); err != nil {
+		r.logger.Error("Failed to consume NVMe-oF controller statistics",
+			zap.Error(err))
+	}
+}
+
+// readControllers reads the fabrics controllers from
+// <sysfs>/class/nvme-fabrics/ctl/nvme<N>. Hosts without the nvme-fabrics
+// module have none.
+func (r *nvmeOFReceiver) readControllers() ([]*controller, error) {
+	dir := filepath.Join(r.config.SysfsPath, "class/nvme-fabrics/ctl")
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var controllers []*controller
+	for _, entry := range entries {
+		if !reController.MatchString(entry.Name()) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		state, err := readAttribute(path, "state")
+		if os.IsNotExist(err) {
+			continue // deleted while listing
+		}
+		if err != nil {
+			return nil, err
+		}
+		c := &controller{
+			name:    entry.Name(),
+			state:   state,
+			address: make(map[string]string),
+			queues:  -1,
+		}
+		// attributes missing on older kernels are left empty
+		c.transport, _ = readAttribute(path, "transport")
+		c.subsysNQN, _ = readAttribute(path, "subsysnqn")
+		if address, err := readAttribute(path, "address"); err == nil {
+			for _, part := range strings.Split(address, ",") {
+				if k, v, found := strings.Cut(part, "="); found {
+					c.address[k] = v
+				}
+			}
+		}
+		if queues, err := readAttribute(path, "queue_count"); err == nil {
+			if n, err := strconv.ParseInt(queues, 10, 64); err == nil {
+				c.queues = n
+			}
+		}
+		controllers = append(controllers, c)
+	}
+	sort.Slice(controllers, func(i, j int) bool {
+		return controllers[i].name < controllers[j].name
+	})
+	return controllers, nil
+}
+
+// updateCounters counts the state changes since the previous collection and
+// the events logged by the kernel. Counters of a controller whose name now
+// belongs to another one start again.
+func (r *nvmeOFReceiver) updateCounters(controllers []*controller) {
+	now := pcommon.NewTimestampFromTime(time.Now())
+	var logged map[string]*kmsgCounts
+	if r.kmsg != nil {
+		logged = r.kmsg.take()
+	}
+
+	current := make(map[string]bool, len(controllers))
+	for _, c := range controllers {
+		current[c.name] = true
+		counters, exists := r.counters[c.name]
+		if !exists || counters.identity != c.identity() {
+			counters = &controllerCounters{
+				identity: c.identity(),
+				start:    now,
+				state:    c.state,
+			}
+			r.counters[c.name] = counters
+		}
+		if c.state != counters.state {
+			counters.stateChanges++
+			counters.state = c.state
+		}
+		if counts, exists := logged[c.name]; exists {
+			counters.reconnects += counts.reconnects
+			counters.keepAliveFailures += counts.keepAliveFailures
+		}
+	}
+	for name := range r.counters {
+		if !current[name] {
+			delete(r.counters, name)
+		}
+	}
+}
+
+func (r *nvmeOFReceiver) appendMetrics(
+	metrics pmetric.MetricSlice,
+	controllers []*controller,
+	now pcommon.Timestamp,
+) {
+	states := metricappend.Gauge(metrics, "nvme_of.controller.state", "1",
+		"1 for the current state of the NVMe-oF controller, else 0")
+	stateChanges := metricappend.Sum(metrics, "nvme_of.controller.state_changes",
+		"{changes}", "State changes of the NVMe-oF controller seen between "+
+			"collections")
+	queues := metricappend.Gauge(metrics, "nvme_of.controller.queues", "{queues}",
+		"Admin and I/O queues of the NVMe-oF controller")
+	var reconnects, keepAliveFailures pmetric.NumberDataPointSlice
+	if r.kmsg != nil {
+		reconnects = metricappend.Sum(metrics, "nvme_of.controller.reconnects",
+			"{attempts}", "Reconnect attempts of the NVMe-oF controller")
+		keepAliveFailures = metricappend.Sum(metrics,
+			"nvme_of.controller.keep_alive_timeouts", "{timeouts}",
+			"Keep-alive commands of the NVMe-oF controller that failed or "+
+				"timed out")
+	}
+
+	for _, c := range controllers {
+		counters := r.counters[c.name]
+		attrs := controllerAttributes(c)
+
+		known := false
+		for _, state := range controllerStates {
+			known = known || state == c.state
+			appendStateDatapoint(states, state, state == c.state, attrs, now)
+		}
+		if !known {
+			appendStateDatapoint(states, c.state, true, attrs, now)
+		}
+		appendIntDatapoint(stateChanges, counters.stateChanges, attrs,
+			counters.start, now)
+		if c.queues >= 0 {
+			appendIntDatapoint(queues, c.queues, attrs, 0, now)
+		}
+		if r.kmsg != nil {
+			appendIntDatapoint(reconnects, counters.reconnects, attrs,
+				counters.start, now)
+			appendIntDatapoint(keepAliveFailures, counters.keepAliveFailures,
+				attrs, counters.start, now)
+		}
+	}
+
+	// e.g. queues on kernels without queue_count
+	metrics.RemoveIf(func(metric pmetric.Metric) bool {
+		if metric.Type() == pmetric.MetricTypeSum {
+			return metric.Sum().DataPoints().Len() == 0
+		}
+		return metric.Gauge().DataPoints().Len() == 0
+	})
+}
+
+func controllerAttributes(c *controller) pcommon.Map {
+	attrs := pcommon.NewMap()
+	attrs.PutStr("nvme.controller", c.name)
+	attrs.PutStr("nvme.transport", c.transport)
+	attrs.PutStr("nvme.subsystem.nqn", c.subsysNQN)
+	if traddr, exists := c.address["traddr"]; exists {
+		attrs.PutStr("nvme.traddr", traddr)
+	}
+	if trsvcid, exists := c.address["trsvcid"]; exists {
+		attrs.PutStr("nvme.trsvcid", trsvcid)
+	}
+	return attrs
+}
+
+func appendStateDatapoint(
+	dps pmetric.NumberDataPointSlice,
+	state string,
+	current bool,
+	attrs pcommon.Map,
+	now pcommon.Timestamp,
+) {
+	dp := dps.AppendEmpty()
+	dp.SetTimestamp(now)
+	if current {
+		dp.SetIntValue(1)
+	} else {
+		dp.SetIntValue(0)
+	}
+	attrs.CopyTo(dp.Attributes())
+	dp.Attributes().PutStr("nvme.controller.state", state)
+}
+
+// appendIntDatapoint appends a datapoint, with a start timestamp unless it is
+// zero.
+func appendIntDatapoint(
+	dps pmetric.NumberDataPointSlice,
+	value int64,
+	attrs pcommon.Map,
+	start pcommon.Timestamp,
+	now pcommon.Timestamp,
+) {
+	dp := dps.AppendEmpty()
+	if start != 0 {
+		dp.SetStartTimestamp(start)
+	}
+	dp.SetTimestamp(now)
+	dp.SetIntValue(value)
+	attrs.CopyTo(dp.Attributes())
+}
+
+func readAttribute(dir, name string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,3 @@
+package nvmeofreceiver
+
+const Version = "0.0.1"
@@ -0,0 +1,136 @@
+package opensearchbulkexporter
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configretry"
+	"go.opentelemetry.io/collector/exporter/exporterhelper"
+
+	"otelcommon/deadletter"
+	"otelcommon/queuestats"
+)
+
+// placeholderDate is the placeholder of index templates replaced by the date
+// of the log record
+const placeholderDate = "date"
+
+// rePlaceholder matches the placeholders of index templates
+var rePlaceholder = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// Config defines the configuration of the opensearch_bulk exporter.
+type Config struct {
+	// Endpoint is the URL of the OpenSearch or Elasticsearch cluster,
+	// e.g. "https://opensearch.example:9200".
+	Endpoint string `mapstructure:"endpoint"`
+
+	// Index is the template of the index names log records are written
+	// to. "${date}" is replaced by the UTC date of the log record as
+	// "2006.01.02", and "${<key>}" by the value of the resource attribute
+	// <key>. Defaults to "otel-logs-${date}".
+	Index string `mapstructure:"index"`
+
+	// IndexFallback replaces the placeholders of resource attributes that
+	// log records don't have. Defaults to "unknown".
+	IndexFallback string `mapstructure:"index_fallback"`
+
+	// Username is an optional user name for basic authentication.
+	Username string `mapstructure:"username"`
+
+	// PasswordFile is the path of a file holding the password for basic
+	// authentication. It is read on every request, so the password can be
+	// rotated.
+	PasswordFile string `mapstructure:"password_file"`
+
+	// CAFile is an optional path of a PEM encoded CA bundle used to verify
+	// the cluster, instead of the system roots.
+	CAFile string `mapstructure:"ca_file"`
+
+	// MaxRequestSize is the largest _bulk request body in bytes. Batches
+	// are split into several requests if needed. Defaults to 5 MiB.
+	MaxRequestSize int `mapstructure:"max_request_size"`
+
+	// TimeoutSettings configures the timeout of each batch.
+	exporterhelper.TimeoutSettings `mapstructure:",squash"`
+
+	// QueueSettings configures the sending queue.
+	QueueSettings exporterhelper.QueueSettings `mapstructure:"sending_queue"`
+
+	// BackOffConfig configures the retries.
+	BackOffConfig configretry.BackOffConfig `mapstructure:"retry_on_failure"`
+
+	// QueueStatsEndpoint serves the statistics of the sending queue at
+	// /metrics/exporters. Empty disables them. Defaults to
+	// "localhost:8890".
+	QueueStatsEndpoint string `mapstructure:"queue_stats_endpoint"`
+
+	// DeadLetter optionally is the ID of a dead_letter receiver, e.g.
+	// "dead_letter/rejected", whose logs pipelines receive the log records
+	// that could not be written: those rejected permanently by the cluster,
+	// and those still failing when retries give up. Empty drops them.
+	DeadLetter string `mapstructure:"dead_letter"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("endpoint must be an http or https URL")
+	}
+	if cfg.Index == "" {
+		return errors.New("index cannot be empty")
+	}
+	for _, match := range rePlaceholder.FindAllStringSubmatch(cfg.Index, -1) {
+		if match[1] == "" {
+			return errors.New("index contains an empty placeholder")
+		}
+	}
+	if strings.ContainsAny(rePlaceholder.ReplaceAllString(cfg.Index, ""), invalidIndexChars) {
+		return fmt.Errorf("index cannot contain any of %q", invalidIndexChars)
+	}
+	if cfg.IndexFallback == "" {
+		return errors.New("index_fallback cannot be empty")
+	}
+	if (cfg.Username == "") != (cfg.PasswordFile == "") {
+		return errors.New("username and password_file must be specified together")
+	}
+	if cfg.MaxRequestSize <= 0 {
+		return errors.New("max_request_size must be positive")
+	}
+	if err := cfg.QueueSettings.Validate(); err != nil {
+		return fmt.Errorf("invalid sending_queue: %w", err)
+	}
+	if err := cfg.BackOffConfig.Validate(); err != nil {
+		return fmt.Errorf("invalid retry_on_failure: %w", err)
+	}
+	if cfg.DeadLetter != "" {
+		if err := deadletter.ValidateReceiver(cfg.DeadLetter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	timeoutSettings := exporterhelper.NewDefaultTimeoutSettings()
+	timeoutSettings.Timeout = 30 * time.Second
+	return &Config{
+		Index:           "otel-logs-${" + placeholderDate + "}",
+		IndexFallback:   "unknown",
+		MaxRequestSize:  5 << 20,
+		TimeoutSettings: timeoutSettings,
+		QueueSettings:   exporterhelper.NewDefaultQueueSettings(),
+		BackOffConfig:   configretry.NewDefaultBackOffConfig(),
+
+		QueueStatsEndpoint: queuestats.DefaultEndpoint,
+	}
+}
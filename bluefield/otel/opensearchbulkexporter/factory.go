@@ -0,0 +1,83 @@
+package opensearchbulkexporter
+
+import (
+	"context"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/exporter"
+	"go.opentelemetry.io/collector/exporter/exporterhelper"
+
+	"otelcommon/deadletter"
+	"otelcommon/queuestats"
+)
+
+const (
+	typeStr   = "opensearch_bulk"
+	stability = component.StabilityLevelAlpha
+)
+
+// log records written are removed from the batch, so that retries only send
+// the remaining ones
+var exporterCapabilities = consumer.Capabilities{MutatesData: true}
+
+func NewFactory() exporter.Factory {
+	return exporter.NewFactory(
+		component.MustNewType(typeStr),
+		createDefaultConfig,
+		exporter.WithLogs(createLogsExporter, stability),
+	)
+}
+
+func createLogsExporter(
+	ctx context.Context,
+	set exporter.CreateSettings,
+	cfg component.Config,
+) (exporter.Logs, error) {
+	config := cfg.(*Config)
+	e, err := newOpensearchBulkExporter(config, set.Logger)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := queuestats.Register(queuestats.Settings{
+		Endpoint:       config.QueueStatsEndpoint,
+		Exporter:       set.ID.String(),
+		Signal:         "logs",
+		Untracked:      config.QueueSettings.Enabled && config.QueueSettings.StorageID != nil,
+		RetryEnabled:   config.BackOffConfig.Enabled,
+		MaxElapsedTime: config.BackOffConfig.MaxElapsedTime,
+	}, set.Logger)
+	if err != nil {
+		return nil, err
+	}
+
+	e.deadLetter = deadletter.New(deadletter.Settings{
+		Receiver:       config.DeadLetter,
+		Exporter:       set.ID.String(),
+		RetryEnabled:   config.BackOffConfig.Enabled,
+		MaxElapsedTime: config.BackOffConfig.MaxElapsedTime,
+	}, set.Logger)
+
+	exp, err := exporterhelper.NewLogsExporter(
+		ctx,
+		set,
+		cfg,
+		stats.PushLogs(e.deadLetter.PushLogs(e.pushLogs)),
+		exporterhelper.WithCapabilities(exporterCapabilities),
+		exporterhelper.WithTimeout(config.TimeoutSettings),
+		exporterhelper.WithQueue(config.QueueSettings),
+		exporterhelper.WithRetry(config.BackOffConfig),
+		exporterhelper.WithShutdown(func(context.Context) error {
+			stats.Unregister()
+			e.deadLetter.Close()
+			return nil
+		}),
+	)
+	if err != nil {
+		stats.Unregister()
+		e.deadLetter.Close()
+		return nil, err
+	}
+	return stats.WrapLogs(exp), nil
+}
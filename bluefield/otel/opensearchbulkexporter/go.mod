@@ -0,0 +1,3 @@
+module opensearchbulkexporter
+
+go 1.22
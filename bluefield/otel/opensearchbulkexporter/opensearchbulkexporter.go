@@ -0,0 +1,382 @@
+package opensearchbulkexporter
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/exporter/exporterhelper"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+
+	"otelcommon/deadletter"
+	"otelcommon/retryafter"
+)
+
+// invalidIndexChars are the characters OpenSearch doesn't allow in index
+// names, replaced by "_" in rendered index names
+const invalidIndexChars = `\/*?"<>| ,#:`
+
+// maxResponseSize limits the _bulk response read, which lists every document
+const maxResponseSize = 64 << 20
+
+type opensearchBulkExporter struct {
+	config     *Config
+	logger     *zap.Logger
+	client     *http.Client
+	bulkURL    string
+	deadLetter *deadletter.Capture
+}
+
+// document is a log record rendered for the _bulk API.
+type document struct {
+	index  string
+	id     string
+	source []byte
+}
+
+// logDocument is the source of the document of a log record.
+type logDocument struct {
+	Timestamp         string         `json:"@timestamp"`
+	ObservedTimestamp string         `json:"observed_timestamp,omitempty"`
+	SeverityText      string         `json:"severity_text,omitempty"`
+	SeverityNumber    int32          `json:"severity_number,omitempty"`
+	Body              string         `json:"body"`
+	Attributes        map[string]any `json:"attributes,omitempty"`
+	Resource          map[string]any `json:"resource,omitempty"`
+	Scope             *scopeDocument `json:"scope,omitempty"`
+	TraceID           string         `json:"trace_id,omitempty"`
+	SpanID            string         `json:"span_id,omitempty"`
+}
+
+type scopeDocument struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+type bulkResponse struct {
+	Errors bool                  `json:"errors"`
+	Items  []map[string]bulkItem `json:"items"`
+}
+
+// bulkItem is the result of a single action of a _bulk request.
+type bulkItem struct {
+	Status int `json:"status"`
+	Error  *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+func newOpensearchBulkExporter(config *Config, logger *zap.Logger) (*opensearchBulkExporter, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.CAFile != "" {
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca_file: %w", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificates found in ca_file")
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: roots}
+	}
+	return &opensearchBulkExporter{
+		config:  config,
+		logger:  logger,
+		client:  &http.Client{Transport: transport},
+		bulkURL: strings.TrimSuffix(config.Endpoint, "/") + "/_bulk",
+	}, nil
+}
+
+// pushLogs writes the log records with "create" actions. Documents are
+// identified by a hash of their content, so that log records written before
+// a retry are rejected as duplicates rather than written twice. Log records
+// that were written or permanently rejected are removed from the batch, so
+// that a retry only sends the remaining ones.
+func (e *opensearchBulkExporter) pushLogs(ctx context.Context, ld plog.Logs) error {
+	var docs []*document
+	ld.ResourceLogs().RemoveIf(func(rl plog.ResourceLogs) bool {
+		resource := rl.Resource().Attributes()
+		rawResource := resource.AsRaw()
+		rl.ScopeLogs().RemoveIf(func(sl plog.ScopeLogs) bool {
+			sl.LogRecords().RemoveIf(func(lr plog.LogRecord) bool {
+				doc, err := e.newDocument(resource, rawResource, sl.Scope(), lr)
+				if err != nil {
+					e.logger.Error("Failed to render log record, dropping it",
+						zap.Error(err))
+					return true
+				}
+				docs = append(docs, doc)
+				return false
+			})
+			return sl.LogRecords().Len() == 0
+		})
+		return rl.ScopeLogs().Len() == 0
+	})
+
+	retry := make([]bool, len(docs))
+	rejected := make([]string, len(docs))
+	var err error
+	for start := 0; start < len(docs); {
+		end := start
+		size := 0
+		for end < len(docs) && (end == start ||
+			size+bulkSize(docs[end]) <= e.config.MaxRequestSize) {
+			size += bulkSize(docs[end])
+			end++
+		}
+
+		err = e.send(ctx, docs[start:end], retry[start:end], rejected[start:end])
+		if err != nil {
+			// the request failed as a whole, so the remaining
+			// documents are not sent either
+			for i := start; i < len(docs); i++ {
+				retry[i] = true
+			}
+			break
+		}
+		start = end
+	}
+
+	if e.deadLetter != nil {
+		if dead, rejection := rejectedLogs(ld, rejected); rejection != "" {
+			e.deadLetter.RejectLogs(dead, errors.New(rejection))
+		}
+	}
+
+	i := 0
+	ld.ResourceLogs().RemoveIf(func(rl plog.ResourceLogs) bool {
+		rl.ScopeLogs().RemoveIf(func(sl plog.ScopeLogs) bool {
+			sl.LogRecords().RemoveIf(func(plog.LogRecord) bool {
+				i++
+				return !retry[i-1]
+			})
+			return sl.LogRecords().Len() == 0
+		})
+		return rl.ScopeLogs().Len() == 0
+	})
+
+	if err != nil {
+		return err
+	}
+	if count := ld.LogRecordCount(); count > 0 {
+		return fmt.Errorf("%d log records were rejected temporarily", count)
+	}
+	return nil
+}
+
+// rejectedLogs copies the log records whose documents were rejected, and
+// returns the first rejection.
+func rejectedLogs(ld plog.Logs, rejected []string) (plog.Logs, string) {
+	dead := plog.NewLogs()
+	var first string
+	i := 0
+	for j := 0; j < ld.ResourceLogs().Len(); j++ {
+		rl := ld.ResourceLogs().At(j)
+		var deadRL plog.ResourceLogs
+		hasResource := false
+		for k := 0; k < rl.ScopeLogs().Len(); k++ {
+			sl := rl.ScopeLogs().At(k)
+			var deadSL plog.ScopeLogs
+			hasScope := false
+			for l := 0; l < sl.LogRecords().Len(); l++ {
+				i++
+				if rejected[i-1] == "" {
+					continue
+				}
+				if first == "" {
+					first = rejected[i-1]
+				}
+				if !hasResource {
+					deadRL = dead.ResourceLogs().AppendEmpty()
+					rl.Resource().CopyTo(deadRL.Resource())
+					deadRL.SetSchemaUrl(rl.SchemaUrl())
+					hasResource = true
+				}
+				if !hasScope {
+					deadSL = deadRL.ScopeLogs().AppendEmpty()
+					sl.Scope().CopyTo(deadSL.Scope())
+					deadSL.SetSchemaUrl(sl.SchemaUrl())
+					hasScope = true
+				}
+				sl.LogRecords().At(l).CopyTo(deadSL.LogRecords().AppendEmpty())
+			}
+		}
+	}
+	return dead, first
+}
+
+func (e *opensearchBulkExporter) newDocument(
+	resource pcommon.Map,
+	rawResource map[string]any,
+	scope pcommon.InstrumentationScope,
+	lr plog.LogRecord,
+) (*document, error) {
+	timestamp := lr.Timestamp()
+	if timestamp == 0 {
+		timestamp = lr.ObservedTimestamp()
+	}
+	source := logDocument{
+		Timestamp:      timestamp.AsTime().UTC().Format(time.RFC3339Nano),
+		SeverityText:   lr.SeverityText(),
+		SeverityNumber: int32(lr.SeverityNumber()),
+		Body:           lr.Body().AsString(),
+		Attributes:     lr.Attributes().AsRaw(),
+		Resource:       rawResource,
+	}
+	if lr.ObservedTimestamp() != 0 {
+		source.ObservedTimestamp = lr.ObservedTimestamp().AsTime().UTC().
+			Format(time.RFC3339Nano)
+	}
+	if scope.Name() != "" || scope.Version() != "" {
+		source.Scope = &scopeDocument{Name: scope.Name(), Version: scope.Version()}
+	}
+	if traceID := lr.TraceID(); !traceID.IsEmpty() {
+		source.TraceID = hex.EncodeToString(traceID[:])
+	}
+	if spanID := lr.SpanID(); !spanID.IsEmpty() {
+		source.SpanID = hex.EncodeToString(spanID[:])
+	}
+
+	data, err := json.Marshal(source)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	return &document{
+		index:  e.indexName(resource, timestamp.AsTime()),
+		id:     hex.EncodeToString(sum[:20]),
+		source: data,
+	}, nil
+}
+
+// indexName renders the index template for a log record.
+func (e *opensearchBulkExporter) indexName(resource pcommon.Map, timestamp time.Time) string {
+	name := rePlaceholder.ReplaceAllStringFunc(e.config.Index, func(placeholder string) string {
+		key := placeholder[2 : len(placeholder)-1]
+		if key == placeholderDate {
+			return timestamp.UTC().Format("2006.01.02")
+		}
+		if value, exists := resource.Get(key); exists && value.AsString() != "" {
+			return value.AsString()
+		}
+		return e.config.IndexFallback
+	})
+
+	// index names must be lowercase and cannot start with "-", "_" or "+"
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(invalidIndexChars, r) {
+			return '_'
+		}
+		return r
+	}, strings.ToLower(name))
+	return strings.TrimLeft(name, "-_+")
+}
+
+func bulkSize(doc *document) int {
+	// the action line is less than 100 bytes besides the index name
+	return len(doc.source) + len(doc.index) + 100
+}
+
+// send sends a _bulk request, flags the documents that should be retried, and
+// sets the rejection of those rejected permanently. It returns an error if the
+// request failed as a whole.
+func (e *opensearchBulkExporter) send(ctx context.Context, docs []*document, retry []bool, rejected []string) error {
+	var body bytes.Buffer
+	for _, doc := range docs {
+		action, _ := json.Marshal(map[string]any{
+			"create": map[string]string{"_index": doc.index, "_id": doc.id},
+		})
+		body.Write(action)
+		body.WriteByte('\n')
+		body.Write(doc.source)
+		body.WriteByte('\n')
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.bulkURL, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if e.config.Username != "" {
+		password, err := os.ReadFile(e.config.PasswordFile)
+		if err != nil {
+			return fmt.Errorf("failed to read password_file: %w", err)
+		}
+		req.SetBasicAuth(e.config.Username, strings.TrimSpace(string(password)))
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return fmt.Errorf("failed to read _bulk response: %w", err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return exporterhelper.NewThrottleRetry(
+			fmt.Errorf("_bulk request returned %s", resp.Status),
+			retryafter.Parse(resp.Header.Get("Retry-After")))
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return fmt.Errorf("_bulk request returned %s: %s", resp.Status,
+			bytes.TrimSpace(data[:min(len(data), 1024)]))
+	}
+
+	var response bulkResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return fmt.Errorf("invalid _bulk response: %w", err)
+	}
+	if len(response.Items) != len(docs) {
+		return fmt.Errorf("_bulk response has %d items for %d documents",
+			len(response.Items), len(docs))
+	}
+	if !response.Errors {
+		return nil
+	}
+
+	rejectedCount := 0
+	var firstRejection string
+	for i, result := range response.Items {
+		for _, item := range result {
+			switch {
+			case item.Status < 300 || item.Status == http.StatusConflict:
+				// written now, or by an earlier attempt
+			case item.Status == http.StatusTooManyRequests || item.Status >= 500:
+				// e.g. es_rejected_execution_exception when the
+				// write queue is full
+				retry[i] = true
+			default:
+				rejectedCount++
+				rejected[i] = fmt.Sprintf("status %d", item.Status)
+				if item.Error != nil {
+					rejected[i] = item.Error.Type + ": " + item.Error.Reason
+				}
+				if firstRejection == "" && item.Error != nil {
+					firstRejection = rejected[i]
+				}
+			}
+		}
+	}
+	if rejectedCount > 0 {
+		e.logger.Error("Log records rejected by the cluster, dropping them",
+			zap.Int("rejected", rejectedCount), zap.String("error", firstRejection))
+	}
+	return nil
+}
@@ -0,0 +1,3 @@
+package opensearchbulkexporter
+
+const Version = "0.0.1"
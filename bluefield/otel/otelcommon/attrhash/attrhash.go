@@ -0,0 +1,41 @@
+// Package attrhash hashes attributes and strings into 64-bit keys, so that
+// components keeping state per resource, scope, stream or series key it the
+// same way.
+package attrhash
+
+import (
+	"hash/fnv"
+	"slices"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+// Attributes adds the attributes to a hash, independent of their order.
+func Attributes(hash uint64, attrs pcommon.Map) uint64 {
+	keys := make([]string, 0, attrs.Len())
+	attrs.Range(func(k string, _ pcommon.Value) bool {
+		keys = append(keys, k)
+		return true
+	})
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		v, _ := attrs.Get(k)
+		hash = String(hash, k)
+		hash = String(hash, v.AsString())
+	}
+	return hash
+}
+
+// String adds a string to an FNV-1a hash.
+func String(hash uint64, s string) uint64 {
+	h := fnv.New64a()
+	var seed [8]byte
+	for i := range seed {
+		seed[i] = byte(hash >> (8 * i))
+	}
+	h.Write(seed[:])
+	h.Write([]byte(s))
+	h.Write([]byte{0})
+	return h.Sum64()
+}
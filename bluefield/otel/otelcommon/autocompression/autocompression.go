@@ -0,0 +1,267 @@
+// Package autocompression selects the compression used by an exporter for a
+// destination from the cost of each compression measured on this host.
+//
+// The Arm cores of a BlueField are slow compared to the hosts the collector's
+// defaults were tuned on, while the out-of-band links telemetry is sent over
+// are often slow as well. Which compression sends the most data depends on
+// both, so the compressions are benchmarked once at startup on a sample of
+// OTLP data, and for each destination the compression is chosen that sustains
+// the highest rate of uncompressed data within the configured CPU and link
+// budgets.
+package autocompression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+// The compressions supported by OTLP exporters, named as in their
+// `compression` setting.
+const (
+	None   = "none"
+	Gzip   = "gzip"
+	Zstd   = "zstd"
+	Snappy = "snappy"
+)
+
+const (
+	// benchmarkDuration is how long each compression is benchmarked for
+	benchmarkDuration = 100 * time.Millisecond
+
+	// compressions whose rates are within this factor of the best are
+	// considered equal, and the one using less CPU is chosen
+	rateTolerance = 0.95
+)
+
+// Config defines the budgets compression is selected for. It is embedded in
+// exporter configurations as `auto_compression`.
+type Config struct {
+	// Enabled selects the compression automatically instead of using
+	// the exporter's `compression` setting.
+	Enabled bool `mapstructure:"enabled"`
+
+	// LinkBandwidthKbps is the bandwidth available for sending to the
+	// destination, in kilobits per second.
+	LinkBandwidthKbps int64 `mapstructure:"link_bandwidth_kbps"`
+
+	// MaxCPU is the share of a core compression for the destination may
+	// use, e.g. 0.25 for a quarter of a core. Defaults to 0.25.
+	MaxCPU float64 `mapstructure:"max_cpu"`
+}
+
+// Validate checks whether the configuration is usable.
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.LinkBandwidthKbps <= 0 {
+		return errors.New("link_bandwidth_kbps must be positive")
+	}
+	if c.MaxCPU < 0 || c.MaxCPU > 1 {
+		return errors.New("max_cpu must be between 0 and 1")
+	}
+	return nil
+}
+
+// Measurement is the measured cost of a compression.
+type Measurement struct {
+	// Compression is the name of the compression.
+	Compression string
+	// Ratio is the compressed size divided by the uncompressed size.
+	Ratio float64
+	// BytesPerSecond is the rate of uncompressed data a single core
+	// compresses.
+	BytesPerSecond float64
+}
+
+var (
+	measureOnce  sync.Once
+	measurements []Measurement
+	measureErr   error
+)
+
+// Measurements returns the cost of each compression, benchmarking them on the
+// first call.
+func Measurements() ([]Measurement, error) {
+	measureOnce.Do(func() {
+		measurements, measureErr = benchmark()
+	})
+	return measurements, measureErr
+}
+
+// Select returns the name of the compression that sustains the highest rate
+// of uncompressed data to a destination within the configured budgets.
+func Select(config Config, logger *zap.Logger) (string, error) {
+	measured, err := Measurements()
+	if err != nil {
+		return "", fmt.Errorf("failed to benchmark compressions: %w", err)
+	}
+
+	maxCPU := config.MaxCPU
+	if maxCPU == 0 {
+		maxCPU = 0.25
+	}
+	linkBytesPerSecond := float64(config.LinkBandwidthKbps) * 1000 / 8
+
+	// The rate of a compression is limited by whichever of the CPU budget
+	// and the link is exhausted first. Without compression, only the link
+	// limits it.
+	rates := make([]float64, len(measured))
+	best := 0.0
+	for i, m := range measured {
+		rate := linkBytesPerSecond / m.Ratio
+		if m.Compression != None {
+			rate = min(rate, m.BytesPerSecond*maxCPU)
+		}
+		rates[i] = rate
+		best = max(best, rate)
+	}
+
+	selected := None
+	selectedSpeed := 0.0
+	for i, m := range measured {
+		if rates[i] < best*rateTolerance {
+			continue
+		}
+		if m.Compression == None {
+			selected = None
+			break
+		}
+		if m.BytesPerSecond > selectedSpeed {
+			selected = m.Compression
+			selectedSpeed = m.BytesPerSecond
+		}
+	}
+
+	logger.Info("Selected compression",
+		zap.String("compression", selected),
+		zap.Int64("link_bandwidth_kbps", config.LinkBandwidthKbps),
+		zap.Float64("max_cpu", maxCPU),
+		zap.Float64("bytes_per_second", best))
+	return selected, nil
+}
+
+type compressor func(dst *bytes.Buffer, src []byte) error
+
+func benchmark() ([]Measurement, error) {
+	sample, err := samplePayload()
+	if err != nil {
+		return nil, err
+	}
+
+	zstdEncoder, err := zstd.NewWriter(nil,
+		zstd.WithEncoderLevel(zstd.SpeedDefault), zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	defer zstdEncoder.Close()
+
+	compressors := []struct {
+		name     string
+		compress compressor
+	}{
+		{Gzip, func(dst *bytes.Buffer, src []byte) error {
+			w := gzip.NewWriter(dst)
+			if _, err := w.Write(src); err != nil {
+				return err
+			}
+			return w.Close()
+		}},
+		{Zstd, func(dst *bytes.Buffer, src []byte) error {
+			dst.Write(zstdEncoder.EncodeAll(src, nil))
+			return nil
+		}},
+		{Snappy, func(dst *bytes.Buffer, src []byte) error {
+			dst.Write(snappy.Encode(nil, src))
+			return nil
+		}},
+	}
+
+	result := []Measurement{{Compression: None, Ratio: 1}}
+	var buf bytes.Buffer
+	for _, c := range compressors {
+		var iterations int
+		start := time.Now()
+		for time.Since(start) < benchmarkDuration || iterations == 0 {
+			buf.Reset()
+			if err := c.compress(&buf, sample); err != nil {
+				return nil, fmt.Errorf("%s: %w", c.name, err)
+			}
+			iterations++
+		}
+		elapsed := time.Since(start)
+
+		result = append(result, Measurement{
+			Compression: c.name,
+			Ratio:       float64(buf.Len()) / float64(len(sample)),
+			BytesPerSecond: float64(len(sample)*iterations) /
+				elapsed.Seconds(),
+		})
+	}
+	return result, nil
+}
+
+// samplePayload returns OTLP metrics and logs shaped like the telemetry sent
+// from a DPU, so that the measured ratios are close to those of real data.
+func samplePayload() ([]byte, error) {
+	metrics := pmetric.NewMetrics()
+	rm := metrics.ResourceMetrics().AppendEmpty()
+	rm.Resource().Attributes().PutStr("host.name", "dpu-0123456789ab")
+	rm.Resource().Attributes().PutStr("component", "hostmetrics")
+	sm := rm.ScopeMetrics().AppendEmpty()
+	now := pcommon.NewTimestampFromTime(time.Unix(1700000000, 0))
+	for i := 0; i < 50; i++ {
+		metric := sm.Metrics().AppendEmpty()
+		metric.SetName(fmt.Sprintf("system.network.metric_%d", i))
+		metric.SetUnit("By")
+		sum := metric.SetEmptySum()
+		sum.SetIsMonotonic(true)
+		sum.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+		for j := 0; j < 20; j++ {
+			dp := sum.DataPoints().AppendEmpty()
+			dp.SetTimestamp(now)
+			dp.SetIntValue(int64(i*1000003 + j*7919))
+			dp.Attributes().PutStr("device", fmt.Sprintf("pf%dvf%d", j%2, j))
+			dp.Attributes().PutStr("direction", "receive")
+		}
+	}
+
+	logs := plog.NewLogs()
+	rl := logs.ResourceLogs().AppendEmpty()
+	rl.Resource().Attributes().PutStr("host.name", "dpu-0123456789ab")
+	rl.Resource().Attributes().PutStr("component", "journald")
+	sl := rl.ScopeLogs().AppendEmpty()
+	for i := 0; i < 500; i++ {
+		lr := sl.LogRecords().AppendEmpty()
+		lr.SetTimestamp(now + pcommon.Timestamp(i*1000))
+		lr.SetSeverityNumber(plog.SeverityNumberInfo)
+		lr.Body().SetStr(fmt.Sprintf("mlx5_core 0000:03:00.%d: port %d "+
+			"link state changed, speed %d Mbps, request id %08x",
+			i%2, i%4, 100000, uint32(i)*2654435761))
+		lr.Attributes().PutStr("_SYSTEMD_UNIT", "kernel")
+		lr.Attributes().PutInt("_PID", int64(i%37))
+	}
+
+	var metricsMarshaler pmetric.ProtoMarshaler
+	metricsData, err := metricsMarshaler.MarshalMetrics(metrics)
+	if err != nil {
+		return nil, err
+	}
+	var logsMarshaler plog.ProtoMarshaler
+	logsData, err := logsMarshaler.MarshalLogs(logs)
+	if err != nil {
+		return nil, err
+	}
+	return append(metricsData, logsData...), nil
+}
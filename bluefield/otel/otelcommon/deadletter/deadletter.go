@@ -0,0 +1,396 @@
+// Package deadletter routes the data exporters fail to deliver to a
+// dead-letter pipeline instead of dropping it, so that classes of data a
+// backend rejects can be inspected rather than silently lost.
+//
+// The dead-letter pipeline starts with a dead_letter receiver, which registers
+// its consumers under its component ID. Exporters configuring that ID wrap the
+// push function passed to exporterhelper, so that a batch is captured when a
+// push fails permanently, when it fails with retries disabled, or once retries
+// give up after max_elapsed_time. As in queuestats, a batch is identified by
+// its pdata value, which the queue hands to the push function unchanged on
+// each retry. Captured batches are copied, and their resources are labeled
+// with the dead_letter.* attributes naming the exporter and the reason.
+package deadletter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+const (
+	// ReceiverType is the type of the receivers starting dead-letter
+	// pipelines
+	ReceiverType = "dead_letter"
+
+	// The resource attributes of captured data.
+	ExporterAttribute    = "dead_letter.exporter"
+	DestinationAttribute = "dead_letter.destination"
+	ReasonAttribute      = "dead_letter.reason"
+	ErrorAttribute       = "dead_letter.error"
+
+	// The reasons data is captured for.
+	ReasonPermanent        = "permanent"
+	ReasonRetriesDisabled  = "retries_disabled"
+	ReasonRetriesExhausted = "retries_exhausted"
+
+	// maxErrorSize limits the size of the error attribute
+	maxErrorSize = 256
+
+	// giveUpMargin is added to max_elapsed_time before the retries of a
+	// batch are considered to have given up, as the last retry may start
+	// just before it
+	giveUpMargin = time.Minute
+)
+
+var (
+	// the consumers of the dead_letter receivers by component ID
+	receiversLock    sync.RWMutex
+	logsReceivers    = make(map[string]consumer.Logs)
+	metricsReceivers = make(map[string]consumer.Metrics)
+	tracesReceivers  = make(map[string]consumer.Traces)
+)
+
+// RegisterLogs routes the logs captured for the receiver ID to a consumer.
+func RegisterLogs(id string, next consumer.Logs) {
+	receiversLock.Lock()
+	defer receiversLock.Unlock()
+	logsReceivers[id] = next
+}
+
+// RegisterMetrics routes the metrics captured for the receiver ID to a
+// consumer.
+func RegisterMetrics(id string, next consumer.Metrics) {
+	receiversLock.Lock()
+	defer receiversLock.Unlock()
+	metricsReceivers[id] = next
+}
+
+// RegisterTraces routes the traces captured for the receiver ID to a
+// consumer.
+func RegisterTraces(id string, next consumer.Traces) {
+	receiversLock.Lock()
+	defer receiversLock.Unlock()
+	tracesReceivers[id] = next
+}
+
+// Unregister stops routing captured data to the consumers of the receiver ID,
+// waiting for data being routed to them.
+func Unregister(id string) {
+	receiversLock.Lock()
+	defer receiversLock.Unlock()
+	delete(logsReceivers, id)
+	delete(metricsReceivers, id)
+	delete(tracesReceivers, id)
+}
+
+// ValidateReceiver checks that a configured receiver ID is that of a
+// dead_letter receiver.
+func ValidateReceiver(id string) error {
+	if id != ReceiverType && !strings.HasPrefix(id, ReceiverType+"/") {
+		return fmt.Errorf("dead_letter must be the ID of a %s receiver, "+
+			"e.g. %s/<name>", ReceiverType, ReceiverType)
+	}
+	return nil
+}
+
+// Settings identifies the exporter whose undeliverable data is captured.
+type Settings struct {
+	// Receiver is the ID of the dead_letter receiver, e.g.
+	// "dead_letter/rejected". Empty disables capturing.
+	Receiver string
+
+	// Exporter is the ID of the exporter, e.g. "webhook/oncall".
+	Exporter string
+
+	// Destination distinguishes the destinations of exporters with
+	// several, e.g. the name of a webhook. Empty for exporters with one.
+	Destination string
+
+	// RetryEnabled and MaxElapsedTime are the retry_on_failure settings of
+	// the exporter, telling when a failed batch is given up on.
+	RetryEnabled   bool
+	MaxElapsedTime time.Duration
+}
+
+// Capture captures the batches an exporter fails to deliver. A nil *Capture
+// captures nothing, so that exporters need not check whether capturing is
+// enabled.
+type Capture struct {
+	settings Settings
+	logger   *zap.Logger
+
+	lock     sync.Mutex
+	retrying map[any]*retry
+	closed   bool
+}
+
+// retry is a batch that failed and is being retried.
+type retry struct {
+	timer    *time.Timer
+	err      error // of the last attempt
+	inFlight bool
+}
+
+// New returns the capture of the batches an exporter fails to deliver, or nil
+// if no receiver is configured.
+func New(settings Settings, logger *zap.Logger) *Capture {
+	if settings.Receiver == "" {
+		return nil
+	}
+	return &Capture{
+		settings: settings,
+		logger:   logger,
+		retrying: make(map[any]*retry),
+	}
+}
+
+// Close stops waiting for retries to give up, as the exporter shuts down.
+// Batches of persistent queues are retried once the collector starts again.
+func (c *Capture) Close() {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, r := range c.retrying {
+		r.timer.Stop()
+	}
+	c.retrying = make(map[any]*retry)
+	c.closed = true
+}
+
+// attempting records that a push attempt of a batch started, so that a batch
+// being retried is not captured while the exporter may still modify it.
+func (c *Capture) attempting(data any) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if r, exists := c.retrying[data]; exists {
+		r.inFlight = true
+	}
+}
+
+// attempted records the result of a push attempt of a batch, and captures the
+// batch if it failed and won't be retried.
+func (c *Capture) attempted(data any, err error) {
+	c.lock.Lock()
+	r, retrying := c.retrying[data]
+	if retrying {
+		r.inFlight = false
+	}
+	reason := ""
+	switch {
+	case err == nil:
+	case consumererror.IsPermanent(err):
+		reason = ReasonPermanent
+	case !c.settings.RetryEnabled:
+		reason = ReasonRetriesDisabled
+	}
+	if err == nil || reason != "" {
+		if retrying {
+			r.timer.Stop()
+			delete(c.retrying, data)
+		}
+		c.lock.Unlock()
+		if reason != "" {
+			c.capture(data, reason, err)
+		}
+		return
+	}
+
+	// Retries without max_elapsed_time never give up.
+	if retrying || c.closed || c.settings.MaxElapsedTime <= 0 {
+		if retrying {
+			r.err = err
+		}
+		c.lock.Unlock()
+		return
+	}
+	r = &retry{err: err}
+	r.timer = time.AfterFunc(c.settings.MaxElapsedTime+giveUpMargin, func() {
+		c.giveUp(data, r)
+	})
+	c.retrying[data] = r
+	c.lock.Unlock()
+}
+
+// giveUp captures a batch whose retries gave up, unless a retry is in flight.
+func (c *Capture) giveUp(data any, r *retry) {
+	c.lock.Lock()
+	if c.retrying[data] != r {
+		c.lock.Unlock()
+		return
+	}
+	if r.inFlight {
+		r.timer.Reset(giveUpMargin)
+		c.lock.Unlock()
+		return
+	}
+	delete(c.retrying, data)
+	c.lock.Unlock()
+
+	c.capture(data, ReasonRetriesExhausted, r.err)
+}
+
+// capture copies a batch to the pipelines of the dead_letter receiver. Data
+// captured before is not captured again, so that an exporter of a dead-letter
+// pipeline capturing into it doesn't loop.
+func (c *Capture) capture(data any, reason string, err error) {
+	var items int
+	var found bool
+	var sendErr error
+	ctx := context.Background()
+
+	receiversLock.RLock()
+	switch data := data.(type) {
+	case plog.Logs:
+		items = data.LogRecordCount()
+		if items == 0 || data.ResourceLogs().Len() == 0 ||
+			captured(data.ResourceLogs().At(0).Resource()) {
+			items = 0
+			break
+		}
+		var next consumer.Logs
+		if next, found = logsReceivers[c.settings.Receiver]; found {
+			copied := plog.NewLogs()
+			data.CopyTo(copied)
+			for i := 0; i < copied.ResourceLogs().Len(); i++ {
+				c.label(copied.ResourceLogs().At(i).Resource(), reason, err)
+			}
+			sendErr = next.ConsumeLogs(ctx, copied)
+		}
+	case pmetric.Metrics:
+		items = data.DataPointCount()
+		if items == 0 || data.ResourceMetrics().Len() == 0 ||
+			captured(data.ResourceMetrics().At(0).Resource()) {
+			items = 0
+			break
+		}
+		var next consumer.Metrics
+		if next, found = metricsReceivers[c.settings.Receiver]; found {
+			copied := pmetric.NewMetrics()
+			data.CopyTo(copied)
+			for i := 0; i < copied.ResourceMetrics().Len(); i++ {
+				c.label(copied.ResourceMetrics().At(i).Resource(), reason, err)
+			}
+			sendErr = next.ConsumeMetrics(ctx, copied)
+		}
+	case ptrace.Traces:
+		items = data.SpanCount()
+		if items == 0 || data.ResourceSpans().Len() == 0 ||
+			captured(data.ResourceSpans().At(0).Resource()) {
+			items = 0
+			break
+		}
+		var next consumer.Traces
+		if next, found = tracesReceivers[c.settings.Receiver]; found {
+			copied := ptrace.NewTraces()
+			data.CopyTo(copied)
+			for i := 0; i < copied.ResourceSpans().Len(); i++ {
+				c.label(copied.ResourceSpans().At(i).Resource(), reason, err)
+			}
+			sendErr = next.ConsumeTraces(ctx, copied)
+		}
+	}
+	receiversLock.RUnlock()
+
+	fields := []zap.Field{
+		zap.String("receiver", c.settings.Receiver),
+		zap.Int("items", items),
+		zap.String("reason", reason),
+		zap.Error(err),
+	}
+	switch {
+	case items == 0:
+		// nothing to capture, or captured before
+	case !found:
+		c.logger.Warn("Undeliverable data dropped, as the dead-letter "+
+			"receiver is not in a pipeline of its signal", fields...)
+	case sendErr != nil:
+		c.logger.Error("Failed to route undeliverable data to the dead-letter "+
+			"pipeline", append(fields, zap.NamedError("send_error", sendErr))...)
+	default:
+		c.logger.Warn("Routed undeliverable data to the dead-letter pipeline",
+			fields...)
+	}
+}
+
+// label adds the attributes telling why data was captured to a resource.
+func (c *Capture) label(resource pcommon.Resource, reason string, err error) {
+	attrs := resource.Attributes()
+	attrs.PutStr(ExporterAttribute, c.settings.Exporter)
+	if c.settings.Destination != "" {
+		attrs.PutStr(DestinationAttribute, c.settings.Destination)
+	}
+	attrs.PutStr(ReasonAttribute, reason)
+	message := strings.TrimPrefix(err.Error(), "Permanent error: ")
+	if len(message) > maxErrorSize {
+		message = message[:maxErrorSize]
+	}
+	attrs.PutStr(ErrorAttribute, strings.ToValidUTF8(message, "?"))
+}
+
+func captured(resource pcommon.Resource) bool {
+	_, exists := resource.Attributes().Get(ExporterAttribute)
+	return exists
+}
+
+// RejectLogs captures log records an exporter drops itself as they were
+// rejected permanently, e.g. single documents of a bulk request.
+func (c *Capture) RejectLogs(ld plog.Logs, err error) {
+	if c == nil {
+		return
+	}
+	c.capture(ld, ReasonPermanent, err)
+}
+
+// PushLogs returns the push function capturing the batches that fail.
+func (c *Capture) PushLogs(push func(context.Context, plog.Logs) error) func(context.Context, plog.Logs) error {
+	if c == nil {
+		return push
+	}
+	return func(ctx context.Context, ld plog.Logs) error {
+		c.attempting(ld)
+		err := push(ctx, ld)
+		c.attempted(ld, err)
+		return err
+	}
+}
+
+// PushMetrics returns the push function capturing the batches that fail.
+func (c *Capture) PushMetrics(push func(context.Context, pmetric.Metrics) error) func(context.Context, pmetric.Metrics) error {
+	if c == nil {
+		return push
+	}
+	return func(ctx context.Context, md pmetric.Metrics) error {
+		c.attempting(md)
+		err := push(ctx, md)
+		c.attempted(md, err)
+		return err
+	}
+}
+
+// PushTraces returns the push function capturing the batches that fail.
+func (c *Capture) PushTraces(push func(context.Context, ptrace.Traces) error) func(context.Context, ptrace.Traces) error {
+	if c == nil {
+		return push
+	}
+	return func(ctx context.Context, td ptrace.Traces) error {
+		c.attempting(td)
+		err := push(ctx, td)
+		c.attempted(td, err)
+		return err
+	}
+}
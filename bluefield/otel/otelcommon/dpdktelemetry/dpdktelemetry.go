@@ -0,0 +1,106 @@
+// Package dpdktelemetry queries the telemetry socket of DPDK applications,
+// including DOCA applications built on DPDK.
+package dpdktelemetry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"path/filepath"
+	"time"
+)
+
+// DefaultSocketGlob matches the telemetry sockets DPDK applications serve
+// unless started with --no-telemetry.
+const DefaultSocketGlob = "/var/run/dpdk/*/dpdk_telemetry.v2"
+
+// defaultMaxOutputLen is the size of telemetry responses if the application
+// doesn't announce it
+const defaultMaxOutputLen = 16384
+
+// Info is the message a DPDK application sends when a client connects to its
+// telemetry socket.
+type Info struct {
+	Version      string `json:"version"`
+	PID          int64  `json:"pid"`
+	MaxOutputLen int    `json:"max_output_len"`
+}
+
+// Client is a connection to the telemetry socket of a DPDK application, which
+// answers each command with a JSON object keyed by the command without its
+// parameters.
+type Client struct {
+	Info Info
+
+	conn net.Conn
+	buf  []byte
+}
+
+// Dial connects to a telemetry socket. The connection expires with the
+// deadline of the context, or after 10 seconds.
+func Dial(ctx context.Context, socket string) (*Client, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unixpacket", socket)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(10 * time.Second))
+	}
+
+	client := &Client{
+		conn: conn,
+		buf:  make([]byte, defaultMaxOutputLen),
+	}
+	n, err := conn.Read(client.buf)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := json.Unmarshal(client.buf[:n], &client.Info); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("invalid telemetry info: %w", err)
+	}
+	if client.Info.MaxOutputLen > len(client.buf) {
+		client.buf = make([]byte, client.Info.MaxOutputLen)
+	}
+	return client, nil
+}
+
+// Close closes the connection.
+func (c *Client) Close() {
+	c.conn.Close()
+}
+
+// Query sends a command and decodes the value of the response into v.
+// Commands the application doesn't know are answered with null, which leaves
+// v unchanged.
+func (c *Client) Query(command string, v any) error {
+	if _, err := c.conn.Write([]byte(command)); err != nil {
+		return err
+	}
+	n, err := c.conn.Read(c.buf)
+	if err != nil {
+		return err
+	}
+
+	var response map[string]json.RawMessage
+	if err := json.Unmarshal(c.buf[:n], &response); err != nil {
+		return fmt.Errorf("invalid response to %s: %w", command, err)
+	}
+	for _, value := range response {
+		if err := json.Unmarshal(value, v); err != nil {
+			return fmt.Errorf("invalid response to %s: %w", command, err)
+		}
+	}
+	return nil
+}
+
+// AppName returns the name of the application serving a telemetry socket,
+// i.e. its --file-prefix, which names the directory of the socket.
+func AppName(socket string) string {
+	return filepath.Base(filepath.Dir(socket))
+}
@@ -0,0 +1,190 @@
+// Package envelope encrypts tenant telemetry with envelope encryption, so that
+// it can cross infrastructure run by the operator without the operator being
+// able to read it.
+//
+// Each sealer holds a random AES-256-GCM data key, which encrypts any number
+// of payloads, and the data key wrapped with the tenant's RSA public key using
+// RSA-OAEP with SHA-256. The wrapped key and the ID of the tenant key travel
+// along with the payloads, so that only the holder of the tenant's private key
+// can unwrap the data key and decrypt them. A sealed payload is the 12-byte
+// GCM nonce followed by the ciphertext, authenticated along with the key ID.
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+const (
+	// Algorithm identifies the key wrapping and payload encryption.
+	Algorithm = "RSA-OAEP-256+A256GCM"
+
+	// AttributeKeyID is the attribute holding the ID of the tenant key
+	// the data key is wrapped with.
+	AttributeKeyID = "bmm.envelope.key_id"
+
+	// AttributeWrappedKey is the attribute holding the base64 encoded
+	// wrapped data key.
+	AttributeWrappedKey = "bmm.envelope.wrapped_key"
+
+	// AttributeAlgorithm is the attribute holding the Algorithm.
+	AttributeAlgorithm = "bmm.envelope.alg"
+
+	// dataKeySize is the size of AES-256 data keys
+	dataKeySize = 32
+)
+
+// TenantKey configures the public key of a tenant.
+type TenantKey struct {
+	// Tenant is the tenant ID, as found in the tenant attribute of the
+	// telemetry.
+	Tenant string `mapstructure:"tenant"`
+	// PublicKeyFile is the path of the PEM encoded RSA public key of the
+	// tenant, in PKIX ("PUBLIC KEY") or PKCS #1 ("RSA PUBLIC KEY") form.
+	PublicKeyFile string `mapstructure:"public_key_file"`
+	// KeyID identifies the key to the tenant. Defaults to the first 16 hex
+	// digits of the SHA-256 fingerprint of the public key.
+	KeyID string `mapstructure:"key_id"`
+}
+
+// Keyring holds the public keys of the configured tenants.
+type Keyring struct {
+	keys map[string]*tenantKey
+}
+
+type tenantKey struct {
+	id  string
+	key *rsa.PublicKey
+}
+
+// Sealer encrypts payloads with a data key wrapped for a tenant.
+type Sealer struct {
+	// KeyID is the ID of the tenant key the data key is wrapped with.
+	KeyID string
+	// WrappedKey is the data key wrapped with the tenant key.
+	WrappedKey []byte
+
+	aead cipher.AEAD
+}
+
+// Validate checks the tenant keys without reading the key files.
+func Validate(keys []TenantKey) error {
+	tenants := make(map[string]bool)
+	for _, k := range keys {
+		if k.Tenant == "" {
+			return errors.New("tenant cannot be empty")
+		}
+		if tenants[k.Tenant] {
+			return fmt.Errorf("key of tenant %s is configured more than once",
+				k.Tenant)
+		}
+		tenants[k.Tenant] = true
+		if k.PublicKeyFile == "" {
+			return fmt.Errorf("public_key_file of tenant %s must be specified",
+				k.Tenant)
+		}
+	}
+	return nil
+}
+
+// LoadKeyring reads the public keys of the tenants.
+func LoadKeyring(keys []TenantKey) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]*tenantKey, len(keys))}
+	for _, tk := range keys {
+		key, der, err := readPublicKey(tk.PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("key of tenant %s: %w", tk.Tenant, err)
+		}
+		id := tk.KeyID
+		if id == "" {
+			fingerprint := sha256.Sum256(der)
+			id = hex.EncodeToString(fingerprint[:8])
+		}
+		k.keys[tk.Tenant] = &tenantKey{id: id, key: key}
+	}
+	return k, nil
+}
+
+func readPublicKey(path string) (*rsa.PublicKey, []byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		key, ok := parsed.(*rsa.PublicKey)
+		if !ok {
+			return nil, nil, fmt.Errorf("%s does not hold an RSA public key", path)
+		}
+		return key, block.Bytes, nil
+	case "RSA PUBLIC KEY":
+		key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		return key, block.Bytes, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported PEM block %q in %s",
+			block.Type, path)
+	}
+}
+
+// Has returns whether a key is configured for the tenant.
+func (k *Keyring) Has(tenant string) bool {
+	_, exists := k.keys[tenant]
+	return exists
+}
+
+// NewSealer returns a sealer with a new data key wrapped for the tenant.
+func (k *Keyring) NewSealer(tenant string) (*Sealer, error) {
+	tk, exists := k.keys[tenant]
+	if !exists {
+		return nil, fmt.Errorf("no key configured for tenant %s", tenant)
+	}
+
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	wrapped, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, tk.key, dataKey, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Sealer{KeyID: tk.id, WrappedKey: wrapped, aead: aead}, nil
+}
+
+// Seal encrypts a payload with the data key. Random nonces keep sealing safe
+// for far more payloads than a sealer is used for before being replaced.
+func (s *Sealer) Seal(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize(),
+		s.aead.NonceSize()+len(plaintext)+s.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return s.aead.Seal(nonce, nonce, plaintext, []byte(s.KeyID)), nil
+}
@@ -0,0 +1,354 @@
+// Package filter matches metrics, log records and spans against the filters
+// configured in `include` and `exclude` settings, so that processors share
+// one filter schema with the same semantics.
+//
+// A filter matches if any of its name criteria or any of its label criteria
+// matches, and, for metrics, if the metric type is one of the listed types.
+// Filters are compiled once when a component is created, so that regular
+// expressions are not compiled again for each datapoint.
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// MetricFilter defines criteria matching metric datapoints.
+type MetricFilter struct {
+	// MetricNames is a list of metric names to filter by.
+	MetricNames []string `mapstructure:"metric_names"`
+	// MetricRegex is a regular expression that matches metric names to filter by.
+	MetricRegex string `mapstructure:"metric_regex"`
+	// MetricTypes is a list of metric types (Counter, Gauge, Histogram, or
+	// Summary) to filter by.
+	MetricTypes []string `mapstructure:"metric_types"`
+	// Labels is a list of label name and values to filter by.
+	Labels []LabelFilter `mapstructure:"labels"`
+}
+
+// LogFilter defines criteria matching log records.
+type LogFilter struct {
+	// BodyRegex is a regular expression that matches log record bodies to
+	// filter by.
+	BodyRegex string `mapstructure:"body_regex"`
+	// SeverityTexts is a list of severity texts to filter by, compared
+	// case-insensitively.
+	SeverityTexts []string `mapstructure:"severity_texts"`
+	// Labels is a list of label name and values to filter by.
+	Labels []LabelFilter `mapstructure:"labels"`
+}
+
+// SpanFilter defines criteria matching spans.
+type SpanFilter struct {
+	// SpanNames is a list of span names to filter by.
+	SpanNames []string `mapstructure:"span_names"`
+	// SpanRegex is a regular expression that matches span names to filter by.
+	SpanRegex string `mapstructure:"span_regex"`
+	// Labels is a list of label name and values to filter by.
+	Labels []LabelFilter `mapstructure:"labels"`
+}
+
+// LabelFilter defines criteria matching the value of a label. A label
+// matches if it exists and, if values or a regular expression are specified,
+// its value is one of the values or matches the regular expression.
+type LabelFilter struct {
+	// Name is the label name
+	Name string `mapstructure:"name"`
+	// Values is a list of label values to filter by.
+	Values []string `mapstructure:"values"`
+	// ValueRegex is a regular expression that matches label values to filter by.
+	ValueRegex string `mapstructure:"value_regex"`
+}
+
+// Attributes looks up labels by name, typically among the datapoint, log
+// record or span attributes along with those of their scope and resource.
+type Attributes interface {
+	Get(name string) (string, bool)
+}
+
+// MapAttributes looks up labels among the attributes of a single map, e.g.
+// those of a resource matched on their own.
+type MapAttributes pcommon.Map
+
+func (m MapAttributes) Get(name string) (string, bool) {
+	value, exists := pcommon.Map(m).Get(name)
+	if !exists {
+		return "", false
+	}
+	return value.AsString(), true
+}
+
+// MetricMatcher is a compiled MetricFilter.
+type MetricMatcher struct {
+	names  []string
+	re     *regexp.Regexp
+	types  []string
+	labels []labelMatcher
+}
+
+// LogMatcher is a compiled LogFilter.
+type LogMatcher struct {
+	re         *regexp.Regexp
+	severities []string
+	labels     []labelMatcher
+}
+
+// SpanMatcher is a compiled SpanFilter.
+type SpanMatcher struct {
+	names  []string
+	re     *regexp.Regexp
+	labels []labelMatcher
+}
+
+// LabelMatcher is a compiled list of LabelFilter, for components matching
+// labels on their own rather than as part of a metric, log or span filter.
+type LabelMatcher struct {
+	labels []labelMatcher
+}
+
+type labelMatcher struct {
+	name   string
+	values []string
+	re     *regexp.Regexp
+}
+
+// CompileMetricFilter compiles a metric filter. A nil filter compiles to a
+// nil matcher, which matches nothing.
+func CompileMetricFilter(filter *MetricFilter) (*MetricMatcher, error) {
+	if filter == nil {
+		return nil, nil
+	}
+	re, err := compileRegex("metric_regex", filter.MetricRegex)
+	if err != nil {
+		return nil, err
+	}
+	labels, err := compileLabels(filter.Labels)
+	if err != nil {
+		return nil, err
+	}
+	return &MetricMatcher{
+		names:  filter.MetricNames,
+		re:     re,
+		types:  filter.MetricTypes,
+		labels: labels,
+	}, nil
+}
+
+// CompileLogFilter compiles a log filter. A nil filter compiles to a nil
+// matcher, which matches nothing.
+func CompileLogFilter(filter *LogFilter) (*LogMatcher, error) {
+	if filter == nil {
+		return nil, nil
+	}
+	re, err := compileRegex("body_regex", filter.BodyRegex)
+	if err != nil {
+		return nil, err
+	}
+	labels, err := compileLabels(filter.Labels)
+	if err != nil {
+		return nil, err
+	}
+	return &LogMatcher{
+		re:         re,
+		severities: filter.SeverityTexts,
+		labels:     labels,
+	}, nil
+}
+
+// CompileSpanFilter compiles a span filter. A nil filter compiles to a nil
+// matcher, which matches nothing.
+func CompileSpanFilter(filter *SpanFilter) (*SpanMatcher, error) {
+	if filter == nil {
+		return nil, nil
+	}
+	re, err := compileRegex("span_regex", filter.SpanRegex)
+	if err != nil {
+		return nil, err
+	}
+	labels, err := compileLabels(filter.Labels)
+	if err != nil {
+		return nil, err
+	}
+	return &SpanMatcher{names: filter.SpanNames, re: re, labels: labels}, nil
+}
+
+// CompileLabelFilters compiles a list of label filters.
+func CompileLabelFilters(filters []LabelFilter) (*LabelMatcher, error) {
+	labels, err := compileLabels(filters)
+	if err != nil {
+		return nil, err
+	}
+	return &LabelMatcher{labels: labels}, nil
+}
+
+func compileRegex(setting, expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", setting, err)
+	}
+	return re, nil
+}
+
+func compileLabels(filters []LabelFilter) ([]labelMatcher, error) {
+	labels := make([]labelMatcher, 0, len(filters))
+	for _, f := range filters {
+		if f.Name == "" {
+			return nil, errors.New("label filter name cannot be empty")
+		}
+		re, err := compileRegex("value_regex of label "+f.Name, f.ValueRegex)
+		if err != nil {
+			return nil, err
+		}
+		labels = append(labels, labelMatcher{name: f.Name, values: f.Values, re: re})
+	}
+	return labels, nil
+}
+
+// Match returns true if (typeMatches AND (nameMatches OR labelMatches)).
+//   - typeMatches is true if no metric types are listed or if the metric
+//     type matches any of the listed types
+//   - nameMatches is true if the metric name matches any of the listed
+//     names or the regular expression
+//   - labelMatches is true if any label matches any of the label filters
+//
+// A filter listing only metric types matches all metrics of those types.
+func (m *MetricMatcher) Match(metric pmetric.Metric, attrs Attributes) bool {
+	if m == nil {
+		return false
+	}
+	if m.types != nil {
+		metricType := MetricType(metric.Type())
+		if !slices.ContainsFunc(m.types, func(t string) bool {
+			return strings.EqualFold(metricType, t)
+		}) {
+			return false
+		}
+		if m.names == nil && m.re == nil && len(m.labels) == 0 {
+			return true
+		}
+	}
+	if slices.Contains(m.names, metric.Name()) {
+		return true
+	}
+	if m.re != nil && m.re.MatchString(metric.Name()) {
+		return true
+	}
+	return matchLabels(m.labels, attrs)
+}
+
+// Match returns true if the body matches the regular expression, the severity
+// text is one of the listed ones, or any label matches any of the label
+// filters.
+func (m *LogMatcher) Match(record plog.LogRecord, attrs Attributes) bool {
+	if m == nil {
+		return false
+	}
+	if m.re != nil && m.re.MatchString(record.Body().AsString()) {
+		return true
+	}
+	if slices.ContainsFunc(m.severities, func(s string) bool {
+		return strings.EqualFold(record.SeverityText(), s)
+	}) {
+		return true
+	}
+	return matchLabels(m.labels, attrs)
+}
+
+// Match returns true if the span name matches any of the listed names or the
+// regular expression, or any label matches any of the label filters.
+func (m *SpanMatcher) Match(span ptrace.Span, attrs Attributes) bool {
+	if m == nil {
+		return false
+	}
+	if slices.Contains(m.names, span.Name()) {
+		return true
+	}
+	if m.re != nil && m.re.MatchString(span.Name()) {
+		return true
+	}
+	return matchLabels(m.labels, attrs)
+}
+
+// MatchAll returns true if every label matches its label filter, unlike the
+// label criteria of metric, log and span filters, any of which matches. A
+// matcher without label filters matches everything.
+func (m *LabelMatcher) MatchAll(attrs Attributes) bool {
+	if m == nil {
+		return true
+	}
+	for _, l := range m.labels {
+		if !l.match(attrs) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchLabels(labels []labelMatcher, attrs Attributes) bool {
+	return slices.ContainsFunc(labels, func(l labelMatcher) bool {
+		return l.match(attrs)
+	})
+}
+
+// match returns true if the label exists and, if values or a regular
+// expression are specified, its value is one of the values or matches the
+// regular expression.
+func (l labelMatcher) match(attrs Attributes) bool {
+	value, exists := attrs.Get(l.name)
+	if !exists {
+		return false
+	}
+	if len(l.values) == 0 && l.re == nil {
+		return true
+	}
+	return slices.Contains(l.values, value) ||
+		(l.re != nil && l.re.MatchString(value))
+}
+
+// IncludeMetric returns whether a metric datapoint matches the include
+// matcher, unless it is nil, and doesn't match the exclude matcher.
+func IncludeMetric(include, exclude *MetricMatcher, metric pmetric.Metric, attrs Attributes) bool {
+	return (include == nil || include.Match(metric, attrs)) &&
+		!exclude.Match(metric, attrs)
+}
+
+// IncludeLog returns whether a log record matches the include matcher, unless
+// it is nil, and doesn't match the exclude matcher.
+func IncludeLog(include, exclude *LogMatcher, record plog.LogRecord, attrs Attributes) bool {
+	return (include == nil || include.Match(record, attrs)) &&
+		!exclude.Match(record, attrs)
+}
+
+// IncludeSpan returns whether a span matches the include matcher, unless it is
+// nil, and doesn't match the exclude matcher.
+func IncludeSpan(include, exclude *SpanMatcher, span ptrace.Span, attrs Attributes) bool {
+	return (include == nil || include.Match(span, attrs)) &&
+		!exclude.Match(span, attrs)
+}
+
+// MetricType returns the name of a metric type as listed in metric_types.
+func MetricType(metricType pmetric.MetricType) string {
+	switch metricType {
+	case pmetric.MetricTypeGauge:
+		return "Gauge"
+	case pmetric.MetricTypeSum:
+		return "Counter"
+	case pmetric.MetricTypeHistogram:
+		return "Histogram"
+	case pmetric.MetricTypeSummary:
+		return "Summary"
+	default:
+		return "Unknown"
+	}
+}
@@ -0,0 +1,3 @@
+module otelcommon
+
+go 1.22
@@ -0,0 +1,383 @@
+// Package httpregistry shares local HTTP servers between collector components.
+//
+// Components register a handler for a path on an endpoint such as
+// "localhost:8890" or a unix socket such as "unix:/run/otelcol/stats.sock".
+// The first registration on an endpoint starts the server, later registrations
+// on the same endpoint add paths to it, and the server is shut down when its
+// last path is unregistered. All paths on an endpoint share the same TLS and
+// authentication settings.
+package httpregistry
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	serversLock sync.Mutex
+	servers     = make(map[string]*server)
+)
+
+// ServerConfig defines the endpoint of a shared server along with the TLS and
+// authentication settings common to all paths registered on it.
+type ServerConfig struct {
+	// Endpoint is the address the server listens on, e.g. "localhost:8890",
+	// or the path of a unix socket prefixed with "unix:".
+	Endpoint string
+
+	// TLS enables HTTPS on the server if a certificate is configured.
+	TLS TLSConfig
+
+	// Auth enables authentication of requests if credentials are
+	// configured.
+	Auth AuthConfig
+}
+
+// TLSConfig defines the certificate of a shared server and, optionally, the CA
+// used to verify client certificates for mutual TLS.
+type TLSConfig struct {
+	// CertFile is the path of the PEM encoded server certificate.
+	CertFile string `mapstructure:"cert_file"`
+	// KeyFile is the path of the PEM encoded server private key.
+	KeyFile string `mapstructure:"key_file"`
+	// ClientCAFile is the optional path of a PEM encoded CA bundle used to
+	// require and verify client certificates.
+	ClientCAFile string `mapstructure:"client_ca_file"`
+}
+
+// Enabled returns whether TLS is configured.
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
+}
+
+// Validate checks whether the TLS configuration is usable.
+func (c TLSConfig) Validate() error {
+	if !c.Enabled() {
+		if c.ClientCAFile != "" {
+			return errors.New("client_ca_file requires cert_file and key_file")
+		}
+		return nil
+	}
+	if c.CertFile == "" || c.KeyFile == "" {
+		return errors.New("both cert_file and key_file must be specified")
+	}
+	return nil
+}
+
+// AuthConfig defines the credentials accepted by a shared server. A request is
+// accepted if it presents any of the configured credentials.
+type AuthConfig struct {
+	// BearerToken is accepted in an "Authorization: Bearer <token>" header.
+	BearerToken string `mapstructure:"bearer_token"`
+	// Username and Password are accepted as HTTP basic auth credentials.
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+}
+
+// Enabled returns whether authentication is configured.
+func (c AuthConfig) Enabled() bool {
+	return c.BearerToken != "" || c.Username != ""
+}
+
+// Validate checks whether the authentication configuration is usable.
+func (c AuthConfig) Validate() error {
+	if c.Username == "" && c.Password != "" {
+		return errors.New("password requires username")
+	}
+	return nil
+}
+
+// Registration is a handler registered for a path on a shared server.
+type Registration struct {
+	server   *server
+	path     string
+	handler  http.Handler
+	inFlight sync.WaitGroup // requests being served by the handler
+	once     sync.Once
+}
+
+type server struct {
+	config         ServerConfig
+	logger         *zap.Logger
+	httpServer     *http.Server
+	handlersRWLock sync.RWMutex
+	handlers       map[string]*Registration
+}
+
+// Register adds a handler for the given path on the server for the configured
+// endpoint, starting the server if this is the first path registered on it.
+// Paths ending in "/" match all requests under them. Registering on an
+// endpoint that is already serving with different TLS or authentication
+// settings, or registering a path twice, is an error.
+func Register(
+	config ServerConfig,
+	path string,
+	handler http.Handler,
+	logger *zap.Logger,
+) (*Registration, error) {
+	if config.Endpoint == "" {
+		return nil, errors.New("endpoint cannot be empty")
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("path %q must start with /", path)
+	}
+
+	serversLock.Lock()
+	defer serversLock.Unlock()
+
+	s, exists := servers[config.Endpoint]
+	if exists {
+		if s.config != config {
+			return nil, fmt.Errorf("endpoint %s is already serving with "+
+				"different TLS or auth settings", config.Endpoint)
+		}
+	} else {
+		var err error
+		s, err = startServer(config, logger)
+		if err != nil {
+			return nil, err
+		}
+		servers[config.Endpoint] = s
+	}
+
+	s.handlersRWLock.Lock()
+	defer s.handlersRWLock.Unlock()
+
+	if _, exists := s.handlers[path]; exists {
+		return nil, fmt.Errorf("path %s is already registered on %s",
+			path, config.Endpoint)
+	}
+	r := &Registration{server: s, path: path, handler: handler}
+	s.handlers[path] = r
+
+	return r, nil
+}
+
+// Endpoint returns the endpoint of the server the handler is registered on.
+func (r *Registration) Endpoint() string {
+	return r.server.config.Endpoint
+}
+
+// Unregister removes the handler, waiting for its in progress requests to
+// complete, and shuts down the server if no other paths remain registered.
+// Calling Unregister more than once has no further effect.
+func (r *Registration) Unregister() {
+	r.once.Do(func() {
+		s := r.server
+		s.handlersRWLock.Lock()
+		delete(s.handlers, r.path)
+		s.handlersRWLock.Unlock()
+
+		// no request can start using the handler once it is removed, and
+		// other paths keep being served while waiting for the ones in
+		// progress
+		r.inFlight.Wait()
+
+		serversLock.Lock()
+		defer serversLock.Unlock()
+
+		s.handlersRWLock.RLock()
+		remaining := len(s.handlers)
+		s.handlersRWLock.RUnlock()
+
+		if remaining == 0 && servers[s.config.Endpoint] == s {
+			delete(servers, s.config.Endpoint)
+			s.shutdown()
+		}
+	})
+}
+
+func startServer(config ServerConfig, logger *zap.Logger) (*server, error) {
+	if err := config.TLS.Validate(); err != nil {
+		return nil, err
+	}
+	if err := config.Auth.Validate(); err != nil {
+		return nil, err
+	}
+
+	s := &server{
+		config:   config,
+		logger:   logger,
+		handlers: make(map[string]*Registration),
+	}
+	s.httpServer = &http.Server{
+		Addr:              config.Endpoint,
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	network, address := "tcp", config.Endpoint
+	if path, isUnix := strings.CutPrefix(config.Endpoint, "unix:"); isUnix {
+		// a socket left behind by a collector that didn't shut down
+		// cleanly would make listening fail
+		if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			os.Remove(path)
+		}
+		network, address = "unix", path
+	}
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start server: %w", err)
+	}
+
+	if config.TLS.Enabled() {
+		tlsConfig, err := config.TLS.Load()
+		if err != nil {
+			listener.Close()
+			return nil, err
+		}
+		s.httpServer.TLSConfig = tlsConfig
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
+	go func() {
+		err := s.httpServer.Serve(listener)
+		if err != nil && err != http.ErrServerClosed {
+			logger.Error("HTTP server error",
+				zap.Error(err),
+				zap.String("address", config.Endpoint),
+			)
+		}
+	}()
+
+	logger.Info("Started shared HTTP server",
+		zap.String("address", config.Endpoint),
+		zap.Bool("tls", config.TLS.Enabled()),
+		zap.Bool("auth", config.Auth.Enabled()),
+	)
+
+	return s, nil
+}
+
+// Load returns the TLS configuration of a server, requiring client
+// certificates if a client CA is configured. It is exported for servers other
+// than the shared HTTP servers, e.g. gRPC servers.
+func (c TLSConfig) Load() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if c.ClientCAFile != "" {
+		pem, err := os.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s",
+				c.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConfig, nil
+}
+
+func (s *server) shutdown() {
+	s.logger.Info("Shutting down shared HTTP server",
+		zap.String("address", s.config.Endpoint))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Attempt to gracefully shut down the server
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		s.logger.Error("Error shutting down shared HTTP server",
+			zap.Error(err))
+
+		// If graceful shutdown fails, force close
+		if closeErr := s.httpServer.Close(); closeErr != nil {
+			s.logger.Error("Error closing shared HTTP server",
+				zap.Error(closeErr))
+		}
+	}
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="otelcol"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// the lock is only held to find the handler, so that a slow handler
+	// doesn't hold up registering and unregistering paths
+	s.handlersRWLock.RLock()
+	registration := s.lookup(r.URL.Path)
+	if registration != nil {
+		registration.inFlight.Add(1)
+	}
+	s.handlersRWLock.RUnlock()
+
+	if registration == nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer registration.inFlight.Done()
+	registration.handler.ServeHTTP(w, r)
+}
+
+// lookup finds the registration for an exact path match, otherwise the
+// registration of the longest registered path ending in "/" that prefixes the
+// request path.
+func (s *server) lookup(path string) *Registration {
+	if registration, exists := s.handlers[path]; exists {
+		return registration
+	}
+	var match string
+	for registered := range s.handlers {
+		if strings.HasSuffix(registered, "/") &&
+			strings.HasPrefix(path, registered) &&
+			len(registered) > len(match) {
+			match = registered
+		}
+	}
+	if match == "" {
+		return nil
+	}
+	return s.handlers[match]
+}
+
+func (s *server) authorized(r *http.Request) bool {
+	auth := s.config.Auth
+	if !auth.Enabled() {
+		return true
+	}
+	if auth.BearerToken != "" {
+		header := r.Header.Get("Authorization")
+		if token, found := strings.CutPrefix(header, "Bearer "); found &&
+			secureEqual(token, auth.BearerToken) {
+			return true
+		}
+	}
+	if auth.Username != "" {
+		username, password, ok := r.BasicAuth()
+		if ok && secureEqual(username, auth.Username) &&
+			secureEqual(password, auth.Password) {
+			return true
+		}
+	}
+	return false
+}
+
+func secureEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
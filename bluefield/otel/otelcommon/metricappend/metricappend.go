@@ -0,0 +1,30 @@
+// Package metricappend appends the gauges and cumulative sums that receivers
+// report, so that receivers building metrics from counters they read don't
+// each repeat the metric setup.
+package metricappend
+
+import (
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// Gauge appends a gauge to metrics and returns its datapoints.
+func Gauge(metrics pmetric.MetricSlice, name, unit, description string) pmetric.NumberDataPointSlice {
+	metric := metrics.AppendEmpty()
+	metric.SetName(name)
+	metric.SetUnit(unit)
+	metric.SetDescription(description)
+	return metric.SetEmptyGauge().DataPoints()
+}
+
+// Sum appends a monotonic cumulative sum to metrics and returns its
+// datapoints.
+func Sum(metrics pmetric.MetricSlice, name, unit, description string) pmetric.NumberDataPointSlice {
+	metric := metrics.AppendEmpty()
+	metric.SetName(name)
+	metric.SetUnit(unit)
+	metric.SetDescription(description)
+	sum := metric.SetEmptySum()
+	sum.SetIsMonotonic(true)
+	sum.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	return sum.DataPoints()
+}
@@ -0,0 +1,142 @@
+// Package pdataiter visits the metric datapoints and log records of pdata
+// batches along with the resource and scope they belong to, so that processors
+// don't each repeat the nested loops over resources, scopes and metrics and
+// the type switches over metric types.
+//
+// The values passed to visitors are reused from one call to the next, so that
+// visiting allocates nothing per datapoint or log record. Visitors must not
+// retain them beyond the call.
+package pdataiter
+
+import (
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// Datapoint is a metric datapoint along with the metric, scope and resource it
+// belongs to.
+type Datapoint struct {
+	Resource pcommon.Resource
+	Scope    pcommon.InstrumentationScope
+	Metric   pmetric.Metric
+
+	// Attributes are the attributes of the datapoint itself.
+	Attributes pcommon.Map
+
+	// Index is the index of the datapoint among those of the metric.
+	Index int
+
+	// Points is the number of points the datapoint consists of: one per
+	// bucket of histograms, one per bucket and the zero bucket of
+	// exponential histograms, one per quantile of summaries, and one for
+	// other datapoints.
+	Points int
+
+	// ResourceIndex is the index of the resource in the batch, so that
+	// visitors can cache values derived from the resource.
+	ResourceIndex int
+}
+
+// LogRecord is a log record along with the scope and resource it belongs to.
+type LogRecord struct {
+	Resource pcommon.Resource
+	Scope    pcommon.InstrumentationScope
+	Record   plog.LogRecord
+
+	// ResourceIndex is the index of the resource in the batch, so that
+	// visitors can cache values derived from the resource.
+	ResourceIndex int
+}
+
+// Datapoints calls visit for each datapoint of the batch, in order.
+func Datapoints(md pmetric.Metrics, visit func(*Datapoint)) {
+	var dp Datapoint
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		rm := rms.At(i)
+		dp.Resource = rm.Resource()
+		dp.ResourceIndex = i
+		sms := rm.ScopeMetrics()
+		for j := 0; j < sms.Len(); j++ {
+			sm := sms.At(j)
+			dp.Scope = sm.Scope()
+			metrics := sm.Metrics()
+			for k := 0; k < metrics.Len(); k++ {
+				dp.Metric = metrics.At(k)
+				visitMetric(&dp, visit)
+			}
+		}
+	}
+}
+
+func visitMetric(dp *Datapoint, visit func(*Datapoint)) {
+	metric := dp.Metric
+	switch metric.Type() {
+	case pmetric.MetricTypeGauge:
+		points := metric.Gauge().DataPoints()
+		for i := 0; i < points.Len(); i++ {
+			dp.Attributes = points.At(i).Attributes()
+			dp.Index = i
+			dp.Points = 1
+			visit(dp)
+		}
+	case pmetric.MetricTypeSum:
+		points := metric.Sum().DataPoints()
+		for i := 0; i < points.Len(); i++ {
+			dp.Attributes = points.At(i).Attributes()
+			dp.Index = i
+			dp.Points = 1
+			visit(dp)
+		}
+	case pmetric.MetricTypeHistogram:
+		points := metric.Histogram().DataPoints()
+		for i := 0; i < points.Len(); i++ {
+			point := points.At(i)
+			dp.Attributes = point.Attributes()
+			dp.Index = i
+			dp.Points = point.BucketCounts().Len()
+			visit(dp)
+		}
+	case pmetric.MetricTypeExponentialHistogram:
+		points := metric.ExponentialHistogram().DataPoints()
+		for i := 0; i < points.Len(); i++ {
+			point := points.At(i)
+			dp.Attributes = point.Attributes()
+			dp.Index = i
+			dp.Points = point.Positive().BucketCounts().Len() +
+				point.Negative().BucketCounts().Len() + 1
+			visit(dp)
+		}
+	case pmetric.MetricTypeSummary:
+		points := metric.Summary().DataPoints()
+		for i := 0; i < points.Len(); i++ {
+			point := points.At(i)
+			dp.Attributes = point.Attributes()
+			dp.Index = i
+			dp.Points = point.QuantileValues().Len()
+			visit(dp)
+		}
+	}
+}
+
+// LogRecords calls visit for each log record of the batch, in order.
+func LogRecords(ld plog.Logs, visit func(*LogRecord)) {
+	var lr LogRecord
+	rls := ld.ResourceLogs()
+	for i := 0; i < rls.Len(); i++ {
+		rl := rls.At(i)
+		lr.Resource = rl.Resource()
+		lr.ResourceIndex = i
+		sls := rl.ScopeLogs()
+		for j := 0; j < sls.Len(); j++ {
+			sl := sls.At(j)
+			lr.Scope = sl.Scope()
+			records := sl.LogRecords()
+			for k := 0; k < records.Len(); k++ {
+				lr.Record = records.At(k)
+				visit(&lr)
+			}
+		}
+	}
+}
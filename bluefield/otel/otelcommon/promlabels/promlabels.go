@@ -0,0 +1,104 @@
+// Package promlabels sanitizes metric names, label names and label values for
+// Prometheus, so that every component exposing or writing Prometheus data
+// turns OpenTelemetry names into the same Prometheus names.
+//
+// Metric names may hold letters, digits, underscores and colons, and label
+// names letters, digits and underscores, neither starting with a digit. Any
+// other character, including each non-ASCII character, becomes an underscore.
+// Label names starting with "__" are reserved for Prometheus' internal use and
+// are prefixed with "key", as are label names starting with a digit, following
+// the OpenTelemetry Prometheus translation. Label values may hold any valid
+// UTF-8, so invalid bytes are replaced with the Unicode replacement character.
+package promlabels
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// reservedPrefix starts the label names reserved for Prometheus' internal use
+const reservedPrefix = "__"
+
+// MetricName returns the metric name sanitized for Prometheus.
+func MetricName(name string) string {
+	name = replaceInvalid(name, true)
+	if name == "" {
+		return "_"
+	}
+	if isDigit(name[0]) {
+		return "_" + name
+	}
+	return name
+}
+
+// LabelName returns the label name sanitized for Prometheus.
+func LabelName(name string) string {
+	name = replaceInvalid(name, false)
+	if name == "" {
+		return "_"
+	}
+	if isDigit(name[0]) {
+		return "key_" + name
+	}
+	if strings.HasPrefix(name, reservedPrefix) {
+		return "key" + name
+	}
+	return name
+}
+
+// LabelValue returns the label value sanitized for Prometheus. It does not
+// escape the value for the text exposition format.
+func LabelValue(value string) string {
+	return strings.ToValidUTF8(value, string(utf8.RuneError))
+}
+
+// Sanitize returns the labels with their names and values sanitized for
+// Prometheus. Labels whose names become the same, e.g. "service.name" and
+// "service_name", are merged into one label whose value joins their values
+// with ";", in the order of their original names.
+func Sanitize(labels map[string]string) map[string]string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	sanitized := make(map[string]string, len(labels))
+	for _, name := range names {
+		key := LabelName(name)
+		value := LabelValue(labels[name])
+		if existing, exists := sanitized[key]; exists {
+			value = existing + ";" + value
+		}
+		sanitized[key] = value
+	}
+	return sanitized
+}
+
+// replaceInvalid replaces each character invalid in a metric or label name
+// with an underscore. Colons are only valid in metric names.
+func replaceInvalid(name string, colons bool) string {
+	valid := func(r rune) bool {
+		return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' ||
+			r >= '0' && r <= '9' || r == '_' || colons && r == ':'
+	}
+	if strings.IndexFunc(name, func(r rune) bool { return !valid(r) }) < 0 {
+		return name
+	}
+
+	var b strings.Builder
+	b.Grow(len(name))
+	for _, r := range name {
+		if valid(r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
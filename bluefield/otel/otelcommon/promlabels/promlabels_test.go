@@ -0,0 +1,146 @@
+package promlabels
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestMetricName(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"valid", "telemetry_stats_log_records_total", "telemetry_stats_log_records_total"},
+		{"colons kept", "job:http_requests:rate5m", "job:http_requests:rate5m"},
+		{"dots", "system.cpu.time", "system_cpu_time"},
+		{"dashes and spaces", "rx-bytes total", "rx_bytes_total"},
+		{"leading digit", "5xx_responses", "_5xx_responses"},
+		{"non-ASCII rune", "temperature_°C", "temperature__C"},
+		{"multi-byte rune", "日本", "__"},
+		{"invalid UTF-8", "bad\xffname", "bad_name"},
+		{"empty", "", "_"},
+		{"reserved prefix kept", "__name__", "__name__"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MetricName(tt.in); got != tt.want {
+				t.Errorf("MetricName(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLabelName(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"valid", "grouping", "grouping"},
+		{"mixed case", "metricType", "metricType"},
+		{"dots", "service.name", "service_name"},
+		{"colons replaced", "k8s:pod", "k8s_pod"},
+		{"leading digit", "0day", "key_0day"},
+		{"only digits", "42", "key_42"},
+		{"reserved prefix", "__name__", "key__name__"},
+		{"reserved after sanitization", ".-resource", "key__resource"},
+		{"single underscore kept", "_private", "_private"},
+		{"non-ASCII rune", "région", "r_gion"},
+		{"invalid UTF-8", "a\xc3b", "a_b"},
+		{"empty", "", "_"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LabelName(tt.in); got != tt.want {
+				t.Errorf("LabelName(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLabelValue(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"ASCII", "dpu-0123", "dpu-0123"},
+		{"valid UTF-8 kept", "Zürich 東京", "Zürich 東京"},
+		{"quotes not escaped", `say "hi"\n`, `say "hi"\n`},
+		{"invalid byte", "a\xffb", "a�b"},
+		{"invalid run", "a\xff\xfeb", "a�b"},
+		{"truncated rune", "ab\xe6\x97", "ab�"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LabelValue(tt.in); got != tt.want {
+				t.Errorf("LabelValue(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   map[string]string
+		want map[string]string
+	}{
+		{
+			name: "empty",
+			in:   map[string]string{},
+			want: map[string]string{},
+		},
+		{
+			name: "valid",
+			in:   map[string]string{"grouping": "logs", "source": "agent"},
+			want: map[string]string{"grouping": "logs", "source": "agent"},
+		},
+		{
+			name: "names and values",
+			in:   map[string]string{"host.name": "dpu\xff", "1st": "x"},
+			want: map[string]string{"host_name": "dpu�", "key_1st": "x"},
+		},
+		{
+			name: "collisions merged in order of original names",
+			in: map[string]string{
+				"service_name": "b",
+				"service.name": "a",
+				"service-name": "c",
+			},
+			want: map[string]string{"service_name": "c;a;b"},
+		},
+		{
+			name: "collision with reserved prefix",
+			in:   map[string]string{"__name": "x", "key__name": "y"},
+			want: map[string]string{"key__name": "x;y"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sanitize(tt.in); !maps.Equal(got, tt.want) {
+				t.Errorf("Sanitize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeIdempotent(t *testing.T) {
+	names := []string{
+		"", "valid", "service.name", "0day", "__name__", ".-x", "日本",
+		"bad\xffname", "job:rate5m",
+	}
+	for _, name := range names {
+		if once := MetricName(name); MetricName(once) != once {
+			t.Errorf("MetricName is not idempotent for %q", name)
+		}
+		if once := LabelName(name); LabelName(once) != once {
+			t.Errorf("LabelName is not idempotent for %q", name)
+		}
+		if once := LabelValue(name); LabelValue(once) != once {
+			t.Errorf("LabelValue is not idempotent for %q", name)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+// Package protosize computes the size of log records, metrics and their
+// datapoints, resources and scopes in the OTLP protobuf encoding exporters
+// send, without encoding them, so that components accounting or limiting
+// telemetry by size agree on it.
+package protosize
+
+import (
+	"math/bits"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// The sizes below are those of the OTLP protobuf encoding of a log record or
+// datapoint, including the tag and length of the record or datapoint itself,
+// as exporters send it. The resource, scope and metric it belongs to are
+// shared with other records, so they aren't included. All fields used have
+// numbers below 16, which are encoded as a 1-byte tag.
+
+// varintSize returns the size of a varint.
+func varintSize(x uint64) int {
+	return (bits.Len64(x|1) + 6) / 7
+}
+
+// fieldSize returns the size of a length-delimited field whose content has the
+// given size.
+func fieldSize(size int) int {
+	return 1 + varintSize(uint64(size)) + size
+}
+
+// LogRecord returns the encoded size of a log record.
+func LogRecord(lr plog.LogRecord) int {
+	size := 0
+	if lr.Timestamp() != 0 {
+		size += 9
+	}
+	if lr.ObservedTimestamp() != 0 {
+		size += 9
+	}
+	if lr.SeverityNumber() != 0 {
+		size += 1 + varintSize(uint64(lr.SeverityNumber()))
+	}
+	if text := lr.SeverityText(); text != "" {
+		size += fieldSize(len(text))
+	}
+	size += fieldSize(valueSize(lr.Body())) // encoded even if empty
+	size += attributesSize(lr.Attributes())
+	if dropped := lr.DroppedAttributesCount(); dropped != 0 {
+		size += 1 + varintSize(uint64(dropped))
+	}
+	if lr.Flags() != 0 {
+		size += 5
+	}
+	// trace and span IDs are encoded even if empty
+	if lr.TraceID().IsEmpty() {
+		size += fieldSize(0)
+	} else {
+		size += fieldSize(16)
+	}
+	if lr.SpanID().IsEmpty() {
+		size += fieldSize(0)
+	} else {
+		size += fieldSize(8)
+	}
+	return fieldSize(size)
+}
+
+// Datapoint returns the encoded size of the datapoint of the metric at the
+// index. Exemplars are not included.
+func Datapoint(metric pmetric.Metric, index int) int {
+	size := 0
+	switch metric.Type() {
+	case pmetric.MetricTypeGauge:
+		size = numberDatapointSize(metric.Gauge().DataPoints().At(index))
+	case pmetric.MetricTypeSum:
+		size = numberDatapointSize(metric.Sum().DataPoints().At(index))
+	case pmetric.MetricTypeHistogram:
+		size = histogramDatapointSize(metric.Histogram().DataPoints().At(index))
+	case pmetric.MetricTypeSummary:
+		size = summaryDatapointSize(metric.Summary().DataPoints().At(index))
+	default:
+		return 0
+	}
+	return fieldSize(size)
+}
+
+// Metric returns the encoded size of a metric with its datapoints. Exemplars,
+// metadata and the datapoints of exponential histograms are not included.
+func Metric(metric pmetric.Metric) int {
+	size := 0
+	if name := metric.Name(); name != "" {
+		size += fieldSize(len(name))
+	}
+	if description := metric.Description(); description != "" {
+		size += fieldSize(len(description))
+	}
+	if unit := metric.Unit(); unit != "" {
+		size += fieldSize(len(unit))
+	}
+	data := 0
+	switch metric.Type() {
+	case pmetric.MetricTypeGauge:
+		data = datapointsSize(metric, metric.Gauge().DataPoints().Len())
+	case pmetric.MetricTypeSum:
+		sum := metric.Sum()
+		data = datapointsSize(metric, sum.DataPoints().Len())
+		if sum.AggregationTemporality() != 0 {
+			data += 2
+		}
+		if sum.IsMonotonic() {
+			data += 2
+		}
+	case pmetric.MetricTypeHistogram:
+		histogram := metric.Histogram()
+		data = datapointsSize(metric, histogram.DataPoints().Len())
+		if histogram.AggregationTemporality() != 0 {
+			data += 2
+		}
+	case pmetric.MetricTypeSummary:
+		data = datapointsSize(metric, metric.Summary().DataPoints().Len())
+	default:
+		return fieldSize(size)
+	}
+	return fieldSize(size + fieldSize(data))
+}
+
+func datapointsSize(metric pmetric.Metric, n int) int {
+	size := 0
+	for i := 0; i < n; i++ {
+		size += Datapoint(metric, i)
+	}
+	return size
+}
+
+// Resource returns the encoded size of a resource, as the field of the
+// resource logs or metrics it describes.
+func Resource(resource pcommon.Resource) int {
+	size := attributesSize(resource.Attributes())
+	if dropped := resource.DroppedAttributesCount(); dropped != 0 {
+		size += 1 + varintSize(uint64(dropped))
+	}
+	return fieldSize(size)
+}
+
+// Scope returns the encoded size of an instrumentation scope, as the field of
+// the scope logs or metrics it describes.
+func Scope(scope pcommon.InstrumentationScope) int {
+	size := attributesSize(scope.Attributes())
+	if name := scope.Name(); name != "" {
+		size += fieldSize(len(name))
+	}
+	if version := scope.Version(); version != "" {
+		size += fieldSize(len(version))
+	}
+	if dropped := scope.DroppedAttributesCount(); dropped != 0 {
+		size += 1 + varintSize(uint64(dropped))
+	}
+	return fieldSize(size)
+}
+
+func numberDatapointSize(dp pmetric.NumberDataPoint) int {
+	size := attributesSize(dp.Attributes()) +
+		timestampsSize(dp.StartTimestamp(), dp.Timestamp())
+	if dp.ValueType() != pmetric.NumberDataPointValueTypeEmpty {
+		size += 9
+	}
+	return size + flagsSize(uint32(dp.Flags()))
+}
+
+func histogramDatapointSize(dp pmetric.HistogramDataPoint) int {
+	size := attributesSize(dp.Attributes()) +
+		timestampsSize(dp.StartTimestamp(), dp.Timestamp())
+	if dp.Count() != 0 {
+		size += 9
+	}
+	if dp.HasSum() {
+		size += 9
+	}
+	if n := dp.BucketCounts().Len(); n > 0 {
+		size += fieldSize(8 * n)
+	}
+	if n := dp.ExplicitBounds().Len(); n > 0 {
+		size += fieldSize(8 * n)
+	}
+	if dp.HasMin() {
+		size += 9
+	}
+	if dp.HasMax() {
+		size += 9
+	}
+	return size + flagsSize(uint32(dp.Flags()))
+}
+
+func summaryDatapointSize(dp pmetric.SummaryDataPoint) int {
+	size := attributesSize(dp.Attributes()) +
+		timestampsSize(dp.StartTimestamp(), dp.Timestamp())
+	if dp.Count() != 0 {
+		size += 9
+	}
+	if dp.Sum() != 0 {
+		size += 9
+	}
+	quantiles := dp.QuantileValues()
+	for i := 0; i < quantiles.Len(); i++ {
+		q := quantiles.At(i)
+		quantileSize := 0
+		if q.Quantile() != 0 {
+			quantileSize += 9
+		}
+		if q.Value() != 0 {
+			quantileSize += 9
+		}
+		size += fieldSize(quantileSize)
+	}
+	return size + flagsSize(uint32(dp.Flags()))
+}
+
+func timestampsSize(start, timestamp pcommon.Timestamp) int {
+	size := 0
+	if start != 0 {
+		size += 9
+	}
+	if timestamp != 0 {
+		size += 9
+	}
+	return size
+}
+
+func flagsSize(flags uint32) int {
+	if flags == 0 {
+		return 0
+	}
+	return 1 + varintSize(uint64(flags))
+}
+
+// attributesSize returns the size of the key-value fields of the attributes.
+func attributesSize(attrs pcommon.Map) int {
+	size := 0
+	attrs.Range(func(k string, v pcommon.Value) bool {
+		size += fieldSize(keyValueSize(k, v))
+		return true
+	})
+	return size
+}
+
+func keyValueSize(k string, v pcommon.Value) int {
+	size := 0
+	if k != "" {
+		size += fieldSize(len(k))
+	}
+	return size + fieldSize(valueSize(v))
+}
+
+// valueSize returns the size of the content of an AnyValue.
+func valueSize(v pcommon.Value) int {
+	switch v.Type() {
+	case pcommon.ValueTypeStr:
+		return fieldSize(len(v.Str()))
+	case pcommon.ValueTypeBool:
+		return 2
+	case pcommon.ValueTypeInt:
+		return 1 + varintSize(uint64(v.Int()))
+	case pcommon.ValueTypeDouble:
+		return 9
+	case pcommon.ValueTypeBytes:
+		return fieldSize(v.Bytes().Len())
+	case pcommon.ValueTypeSlice:
+		size := 0
+		values := v.Slice()
+		for i := 0; i < values.Len(); i++ {
+			size += fieldSize(valueSize(values.At(i)))
+		}
+		return fieldSize(size)
+	case pcommon.ValueTypeMap:
+		return fieldSize(attributesSize(v.Map()))
+	}
+	return 0
+}
@@ -0,0 +1,499 @@
+// Package queuestats reports the sending queues of exporters under stable
+// metric names, so that fleet dashboards show every exporter of this directory
+// with the same panels.
+//
+// Exporters wrap the exporter created by exporterhelper, so that batches are
+// recorded as they enter the queue, and the push function passed to it, so
+// that each push attempt is recorded. A batch is
+// identified by its pdata value, which the in-memory queue hands to the push
+// function unchanged, so that retries of a batch are told apart from new
+// batches. Batches leave the queue when they are sent, when the push fails
+// permanently or without retries, or once retries give up after
+// max_elapsed_time. Batches of persistent queues are read back as new values,
+// so only push attempts are reported for them.
+//
+// The statistics of all exporters configuring the same endpoint are served
+// there in the Prometheus text format at /metrics/exporters.
+package queuestats
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+
+	"otelcommon/httpregistry"
+	"otelcommon/promlabels"
+)
+
+const (
+	// Path is the path the statistics are served at
+	Path = "/metrics/exporters"
+
+	// DefaultEndpoint is the endpoint exporters serve their statistics on
+	// unless configured otherwise
+	DefaultEndpoint = "localhost:8890"
+
+	// maxErrorSize limits the size of the last error reported
+	maxErrorSize = 256
+
+	// giveUpMargin is added to max_elapsed_time before a batch whose
+	// retries gave up is considered dropped, as the last retry may start
+	// just before it
+	giveUpMargin = time.Minute
+)
+
+var (
+	// endpoints by address, shared by the exporters configuring the same
+	// endpoint
+	endpointsLock sync.Mutex
+	endpoints     = make(map[string]*endpoint)
+)
+
+// Settings identifies the queue of an exporter.
+type Settings struct {
+	// Endpoint serves the statistics. Empty disables them.
+	Endpoint string
+
+	// Exporter is the ID of the exporter, e.g. "webhook/oncall".
+	Exporter string
+
+	// Destination distinguishes the queues of exporters with several,
+	// e.g. the name of a webhook. Empty for exporters with one queue.
+	Destination string
+
+	// Signal is "logs", "metrics" or "traces".
+	Signal string
+
+	// Untracked disables tracking the batches in the queue, for persistent
+	// queues and exporters handing batches to queues of their own.
+	Untracked bool
+
+	// RetryEnabled and MaxElapsedTime are the retry_on_failure settings of
+	// the exporter, telling when a failed batch leaves the queue.
+	RetryEnabled   bool
+	MaxElapsedTime time.Duration
+}
+
+// Stats are the statistics of the queue of an exporter. A nil *Stats records
+// nothing, so that exporters need not check whether statistics are enabled.
+type Stats struct {
+	settings Settings
+
+	lock      sync.Mutex
+	batches   map[any]*batch
+	attempts  int64
+	retries   int64
+	sent      int64
+	failed    int64
+	lastError string
+	errorTime time.Time
+}
+
+// batch is a batch in the queue.
+type batch struct {
+	enqueued     time.Time
+	items        int
+	firstAttempt time.Time
+}
+
+type endpoint struct {
+	registration *httpregistry.Registration
+	lock         sync.Mutex
+	stats        []*Stats
+}
+
+// Register starts reporting the queue of an exporter on the endpoint of the
+// settings. It returns nil if the endpoint is empty.
+func Register(settings Settings, logger *zap.Logger) (*Stats, error) {
+	if settings.Endpoint == "" {
+		return nil, nil
+	}
+	s := &Stats{settings: settings, batches: make(map[any]*batch)}
+
+	endpointsLock.Lock()
+	defer endpointsLock.Unlock()
+
+	e, exists := endpoints[settings.Endpoint]
+	if !exists {
+		e = &endpoint{}
+		registration, err := httpregistry.Register(
+			httpregistry.ServerConfig{Endpoint: settings.Endpoint},
+			Path,
+			e,
+			logger,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to register queue stats endpoint: %w",
+				err)
+		}
+		e.registration = registration
+		endpoints[settings.Endpoint] = e
+	}
+	e.lock.Lock()
+	e.stats = append(e.stats, s)
+	e.lock.Unlock()
+	return s, nil
+}
+
+// Unregister stops reporting the queue, and stops serving the endpoint once
+// no queue is reported on it.
+func (s *Stats) Unregister() {
+	if s == nil {
+		return
+	}
+	endpointsLock.Lock()
+	e, exists := endpoints[s.settings.Endpoint]
+	if !exists {
+		endpointsLock.Unlock()
+		return
+	}
+	e.lock.Lock()
+	e.stats = slices.DeleteFunc(e.stats, func(other *Stats) bool {
+		return other == s
+	})
+	remaining := len(e.stats)
+	e.lock.Unlock()
+	if remaining > 0 {
+		endpointsLock.Unlock()
+		return
+	}
+	delete(endpoints, s.settings.Endpoint)
+	endpointsLock.Unlock()
+
+	// Unregister without holding the lock, since unregistering waits for
+	// in progress requests.
+	e.registration.Unregister()
+}
+
+// enqueued records a batch entering the queue. It must be called before the
+// batch is handed to the queue, as the queue may push it right away.
+func (s *Stats) enqueued(request any, items int) {
+	if s.settings.Untracked {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.batches[request] = &batch{enqueued: time.Now(), items: items}
+}
+
+// rejected records that the queue refused a batch, e.g. as it is full, or,
+// without a queue, that the batch failed.
+func (s *Stats) rejected(request any, items int, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.setError(err)
+	if b, exists := s.batches[request]; exists {
+		items = b.items
+		delete(s.batches, request)
+	} else if !s.settings.Untracked {
+		// pushed and finished synchronously
+		return
+	}
+	s.failed += int64(items)
+}
+
+// attempted records a push attempt of a batch, holding the items before and
+// after the attempt, and whether it failed. Exporters removing the items they
+// sent from a batch, so that retries only send the remaining ones, hold fewer
+// items after a failed attempt.
+func (s *Stats) attempted(request any, before, after int, err error) {
+	now := time.Now()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.attempts++
+	b, tracked := s.batches[request]
+	if tracked {
+		if b.firstAttempt.IsZero() {
+			b.firstAttempt = now
+		} else {
+			s.retries++
+		}
+	}
+
+	if err == nil {
+		s.sent += int64(before)
+		delete(s.batches, request)
+		return
+	}
+	s.sent += int64(max(before-after, 0))
+	s.setError(err)
+	if consumererror.IsPermanent(err) || !s.settings.RetryEnabled {
+		s.failed += int64(after)
+		delete(s.batches, request)
+		return
+	}
+	if tracked {
+		b.items = after
+	}
+}
+
+// setError records the last error. The lock must be held.
+func (s *Stats) setError(err error) {
+	message := err.Error()
+	if len(message) > maxErrorSize {
+		message = message[:maxErrorSize]
+	}
+	s.lastError = strings.ToValidUTF8(message, "?")
+	s.errorTime = time.Now()
+}
+
+// expire drops the batches whose retries gave up. The lock must be held.
+func (s *Stats) expire(now time.Time) {
+	if !s.settings.RetryEnabled || s.settings.MaxElapsedTime <= 0 {
+		return
+	}
+	for request, b := range s.batches {
+		if !b.firstAttempt.IsZero() &&
+			now.Sub(b.firstAttempt) > s.settings.MaxElapsedTime+giveUpMargin {
+			s.failed += int64(b.items)
+			delete(s.batches, request)
+		}
+	}
+}
+
+// snapshot is the state of a queue at a scrape.
+type snapshot struct {
+	labels    string
+	untracked bool
+	items     int
+	oldest    time.Duration
+	attempts  int64
+	retries   int64
+	sent      int64
+	failed    int64
+	lastError string
+	errorTime time.Time
+}
+
+func (s *Stats) snapshot(now time.Time) snapshot {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.expire(now)
+	snap := snapshot{
+		labels: fmt.Sprintf(`exporter="%s",destination="%s",signal="%s"`,
+			escape(s.settings.Exporter), escape(s.settings.Destination),
+			escape(s.settings.Signal)),
+		untracked: s.settings.Untracked,
+		attempts:  s.attempts,
+		retries:   s.retries,
+		sent:      s.sent,
+		failed:    s.failed,
+		lastError: s.lastError,
+		errorTime: s.errorTime,
+	}
+	for _, b := range s.batches {
+		snap.items += b.items
+		snap.oldest = max(snap.oldest, now.Sub(b.enqueued))
+	}
+	return snap
+}
+
+// ServeHTTP writes the statistics of the queues reported on the endpoint.
+func (e *endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	now := time.Now()
+	e.lock.Lock()
+	snapshots := make([]snapshot, 0, len(e.stats))
+	for _, s := range e.stats {
+		snapshots = append(snapshots, s.snapshot(now))
+	}
+	e.lock.Unlock()
+	slices.SortFunc(snapshots, func(a, b snapshot) int {
+		return strings.Compare(a.labels, b.labels)
+	})
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	metric := func(name, kind, help string, value func(*snapshot) (float64, bool)) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		for i := range snapshots {
+			if v, ok := value(&snapshots[i]); ok {
+				fmt.Fprintf(w, "%s{%s} %g\n", name, snapshots[i].labels, v)
+			}
+		}
+	}
+	metric("bmm_exporter_queue_items", "gauge",
+		"Items in the sending queue, including those being retried.",
+		func(s *snapshot) (float64, bool) { return float64(s.items), !s.untracked })
+	metric("bmm_exporter_queue_oldest_item_age_seconds", "gauge",
+		"Age of the oldest batch in the sending queue, 0 if it is empty.",
+		func(s *snapshot) (float64, bool) { return s.oldest.Seconds(), !s.untracked })
+	metric("bmm_exporter_send_attempts_total", "counter",
+		"Push attempts, including retries.",
+		func(s *snapshot) (float64, bool) { return float64(s.attempts), true })
+	metric("bmm_exporter_send_retries_total", "counter",
+		"Push attempts retrying a batch.",
+		func(s *snapshot) (float64, bool) { return float64(s.retries), !s.untracked })
+	metric("bmm_exporter_sent_items_total", "counter",
+		"Items sent.",
+		func(s *snapshot) (float64, bool) { return float64(s.sent), true })
+	metric("bmm_exporter_failed_items_total", "counter",
+		"Items dropped as the queue was full, the push failed permanently or retries gave up.",
+		func(s *snapshot) (float64, bool) { return float64(s.failed), true })
+	metric("bmm_exporter_last_error_timestamp_seconds", "gauge",
+		"Time of the last error, absent if there was none.",
+		func(s *snapshot) (float64, bool) {
+			return float64(s.errorTime.UnixMilli()) / 1000, !s.errorTime.IsZero()
+		})
+	fmt.Fprintf(w, "# HELP bmm_exporter_last_error_info Last error, as a label.\n"+
+		"# TYPE bmm_exporter_last_error_info gauge\n")
+	for _, s := range snapshots {
+		if !s.errorTime.IsZero() {
+			fmt.Fprintf(w, "bmm_exporter_last_error_info{%s,error=\"%s\"} 1\n",
+				s.labels, escape(s.lastError))
+		}
+	}
+}
+
+// escape escapes a label value for the text exposition format.
+func escape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).
+		Replace(promlabels.LabelValue(value))
+}
+
+// PushLogs returns the push function recording each attempt.
+func (s *Stats) PushLogs(push func(context.Context, plog.Logs) error) func(context.Context, plog.Logs) error {
+	if s == nil {
+		return push
+	}
+	return func(ctx context.Context, ld plog.Logs) error {
+		before := ld.LogRecordCount()
+		err := push(ctx, ld)
+		s.attempted(ld, before, ld.LogRecordCount(), err)
+		return err
+	}
+}
+
+// PushMetrics returns the push function recording each attempt.
+func (s *Stats) PushMetrics(push func(context.Context, pmetric.Metrics) error) func(context.Context, pmetric.Metrics) error {
+	if s == nil {
+		return push
+	}
+	return func(ctx context.Context, md pmetric.Metrics) error {
+		before := md.DataPointCount()
+		err := push(ctx, md)
+		s.attempted(md, before, md.DataPointCount(), err)
+		return err
+	}
+}
+
+// PushTraces returns the push function recording each attempt.
+func (s *Stats) PushTraces(push func(context.Context, ptrace.Traces) error) func(context.Context, ptrace.Traces) error {
+	if s == nil {
+		return push
+	}
+	return func(ctx context.Context, td ptrace.Traces) error {
+		before := td.SpanCount()
+		err := push(ctx, td)
+		s.attempted(td, before, td.SpanCount(), err)
+		return err
+	}
+}
+
+// LogsExporter is an exporter of logs, as created by exporterhelper.
+type LogsExporter interface {
+	component.Component
+	consumer.Logs
+}
+
+// MetricsExporter is an exporter of metrics, as created by exporterhelper.
+type MetricsExporter interface {
+	component.Component
+	consumer.Metrics
+}
+
+// TracesExporter is an exporter of traces, as created by exporterhelper.
+type TracesExporter interface {
+	component.Component
+	consumer.Traces
+}
+
+type logsExporter struct {
+	LogsExporter
+	stats *Stats
+}
+
+type metricsExporter struct {
+	MetricsExporter
+	stats *Stats
+}
+
+type tracesExporter struct {
+	TracesExporter
+	stats *Stats
+}
+
+// WrapLogs returns the exporter recording the batches entering its queue.
+func (s *Stats) WrapLogs(e LogsExporter) LogsExporter {
+	if s == nil {
+		return e
+	}
+	return &logsExporter{LogsExporter: e, stats: s}
+}
+
+// WrapMetrics returns the exporter recording the batches entering its queue.
+func (s *Stats) WrapMetrics(e MetricsExporter) MetricsExporter {
+	if s == nil {
+		return e
+	}
+	return &metricsExporter{MetricsExporter: e, stats: s}
+}
+
+// WrapTraces returns the exporter recording the batches entering its queue.
+func (s *Stats) WrapTraces(e TracesExporter) TracesExporter {
+	if s == nil {
+		return e
+	}
+	return &tracesExporter{TracesExporter: e, stats: s}
+}
+
+func (e *logsExporter) ConsumeLogs(ctx context.Context, ld plog.Logs) error {
+	items := ld.LogRecordCount()
+	e.stats.enqueued(ld, items)
+	err := e.LogsExporter.ConsumeLogs(ctx, ld)
+	if err != nil {
+		e.stats.rejected(ld, items, err)
+	}
+	return err
+}
+
+func (e *metricsExporter) ConsumeMetrics(ctx context.Context, md pmetric.Metrics) error {
+	items := md.DataPointCount()
+	e.stats.enqueued(md, items)
+	err := e.MetricsExporter.ConsumeMetrics(ctx, md)
+	if err != nil {
+		e.stats.rejected(md, items, err)
+	}
+	return err
+}
+
+func (e *tracesExporter) ConsumeTraces(ctx context.Context, td ptrace.Traces) error {
+	items := td.SpanCount()
+	e.stats.enqueued(td, items)
+	err := e.TracesExporter.ConsumeTraces(ctx, td)
+	if err != nil {
+		e.stats.rejected(td, items, err)
+	}
+	return err
+}
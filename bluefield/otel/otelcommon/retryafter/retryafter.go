@@ -0,0 +1,18 @@
+// Package retryafter reads the Retry-After header of HTTP responses, so that
+// exporters retrying rejected requests honour it the same way.
+package retryafter
+
+import (
+	"strconv"
+	"time"
+)
+
+// Parse returns the delay of a Retry-After header in seconds, or zero to back
+// off as configured.
+func Parse(header string) time.Duration {
+	seconds, err := strconv.Atoi(header)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,60 @@
+package pacingprocessor
+
+import (
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+// Config defines the configuration of the pacing processor.
+type Config struct {
+	// Window is the time over which buffered data is passed on. Each batch
+	// sets the pace so that everything buffered is passed on within the
+	// window after it arrived. Defaults to "30s".
+	Window time.Duration `mapstructure:"window"`
+
+	// Interval configures how often buffered data is passed on. Defaults
+	// to "1s".
+	Interval time.Duration `mapstructure:"interval"`
+
+	// ChunkSize is the number of datapoints or log records batches are
+	// split into. Metrics are not split, so chunks of metrics may hold
+	// more datapoints. Defaults to 500.
+	ChunkSize int `mapstructure:"chunk_size"`
+
+	// MaxBufferedItems limits the number of datapoints or log records
+	// buffered. Batches that would exceed it are refused. Defaults to
+	// 100000.
+	MaxBufferedItems int `mapstructure:"max_buffered_items"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.Window <= 0 {
+		return errors.New("window must be positive")
+	}
+	if cfg.Interval <= 0 || cfg.Interval > cfg.Window {
+		return errors.New("interval must be positive and at most window")
+	}
+	if cfg.ChunkSize <= 0 {
+		return errors.New("chunk_size must be positive")
+	}
+	if cfg.MaxBufferedItems < cfg.ChunkSize {
+		return errors.New("max_buffered_items must be at least chunk_size")
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		Window:           30 * time.Second,
+		Interval:         time.Second,
+		ChunkSize:        500,
+		MaxBufferedItems: 100000,
+	}
+}
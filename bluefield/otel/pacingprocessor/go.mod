@@ -0,0 +1,3 @@
+module pacingprocessor
+
+go 1.22
@@ -0,0 +1,263 @@
+package pacingprocessor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+	"go.uber.org/zap"
+)
+
+// errBufferFull is returned for batches refused as the buffer is full
+var errBufferFull = errors.New("pacing buffer is full")
+
+type pacingProcessor struct {
+	config      *Config
+	logger      *zap.Logger
+	nextMetrics consumer.Metrics
+	nextLogs    consumer.Logs
+	stopChannel chan struct{}
+	stopWaiters sync.WaitGroup
+
+	// chunks waiting to be passed on, oldest first
+	bufferLock sync.Mutex
+	buffer     []chunk
+	buffered   int     // items in the buffer
+	rate       float64 // items passed on per second
+	budget     float64 // items that may be passed on at the next interval
+	refused    int64   // items refused since the last warning
+}
+
+// chunk is a part of a batch, holding either metrics or logs.
+type chunk struct {
+	metrics pmetric.Metrics
+	logs    plog.Logs
+	items   int
+}
+
+// processor constructor
+func newPacingProcessor(config *Config, logger *zap.Logger) *pacingProcessor {
+	return &pacingProcessor{
+		config:      config,
+		logger:      logger,
+		stopChannel: make(chan struct{}),
+	}
+}
+
+func (p *pacingProcessor) start() {
+	p.stopWaiters.Add(1)
+	go p.paceLoop()
+}
+
+// processor destructor
+func (p *pacingProcessor) cleanup(ctx context.Context) {
+	close(p.stopChannel)
+	p.stopWaiters.Wait()
+
+	// buffered data is passed on at once rather than lost
+	p.bufferLock.Lock()
+	chunks := p.buffer
+	p.buffer = nil
+	p.buffered = 0
+	p.bufferLock.Unlock()
+	p.send(ctx, chunks)
+}
+
+func (p *pacingProcessor) paceLoop() {
+	defer p.stopWaiters.Done()
+
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(),
+				p.config.Window)
+			p.send(ctx, p.next())
+			cancel()
+		case <-p.stopChannel:
+			return
+		}
+	}
+}
+
+// next removes the chunks to pass on at this interval from the buffer. The
+// last chunk may exceed the budget, which is then made up for at the next
+// intervals.
+func (p *pacingProcessor) next() []chunk {
+	p.bufferLock.Lock()
+	defer p.bufferLock.Unlock()
+
+	if p.refused > 0 {
+		p.logger.Warn("Refused data as the pacing buffer is full",
+			zap.Int64("items", p.refused),
+			zap.Int("max_buffered_items", p.config.MaxBufferedItems))
+		p.refused = 0
+	}
+
+	if len(p.buffer) == 0 {
+		p.budget = 0
+		return nil
+	}
+	p.budget += p.rate * p.config.Interval.Seconds()
+	n := 0
+	for n < len(p.buffer) && (p.budget > 0 || p.buffer[n].items == 0) {
+		p.budget -= float64(p.buffer[n].items)
+		p.buffered -= p.buffer[n].items
+		n++
+	}
+	chunks := p.buffer[:n:n]
+	p.buffer = p.buffer[n:]
+	return chunks
+}
+
+// send passes on the chunks in order.
+func (p *pacingProcessor) send(ctx context.Context, chunks []chunk) {
+	for _, c := range chunks {
+		var err error
+		if p.nextMetrics != nil {
+			err = p.nextMetrics.ConsumeMetrics(ctx, c.metrics)
+		} else {
+			err = p.nextLogs.ConsumeLogs(ctx, c.logs)
+		}
+		if err != nil {
+			p.logger.Error("Failed to pass on paced data",
+				zap.Int("items", c.items), zap.Error(err))
+		}
+	}
+}
+
+// enqueue buffers the chunks of a batch and sets the pace so that the whole
+// buffer is passed on within the window. A batch that doesn't fit into the
+// buffer is refused as a whole.
+func (p *pacingProcessor) enqueue(chunks []chunk, items int) error {
+	p.bufferLock.Lock()
+	defer p.bufferLock.Unlock()
+
+	if p.buffered+items > p.config.MaxBufferedItems {
+		p.refused += int64(items)
+		return fmt.Errorf("%w: %d of %d items buffered", errBufferFull,
+			p.buffered, p.config.MaxBufferedItems)
+	}
+	p.buffer = append(p.buffer, chunks...)
+	p.buffered += items
+	p.rate = float64(p.buffered) / p.config.Window.Seconds()
+	return nil
+}
+
+func (p *pacingProcessor) processMetrics(
+	_ context.Context,
+	md pmetric.Metrics,
+) (pmetric.Metrics, error) {
+	if err := p.enqueue(splitMetrics(md, p.config.ChunkSize),
+		md.DataPointCount()); err != nil {
+		return md, err
+	}
+	return md, processorhelper.ErrSkipProcessingData
+}
+
+func (p *pacingProcessor) processLogs(
+	_ context.Context,
+	ld plog.Logs,
+) (plog.Logs, error) {
+	if err := p.enqueue(splitLogs(ld, p.config.ChunkSize),
+		ld.LogRecordCount()); err != nil {
+		return ld, err
+	}
+	return ld, processorhelper.ErrSkipProcessingData
+}
+
+// splitMetrics copies the metrics into chunks of about size datapoints. A
+// chunk is closed once it holds at least size datapoints, as metrics are not
+// split.
+func splitMetrics(md pmetric.Metrics, size int) []chunk {
+	var chunks []chunk
+	var current *chunk
+	for i := 0; i < md.ResourceMetrics().Len(); i++ {
+		rm := md.ResourceMetrics().At(i)
+		for j := 0; j < rm.ScopeMetrics().Len(); j++ {
+			sm := rm.ScopeMetrics().At(j)
+			var dest pmetric.MetricSlice
+			opened := false
+			for k := 0; k < sm.Metrics().Len(); k++ {
+				metric := sm.Metrics().At(k)
+				if current == nil || current.items >= size {
+					chunks = append(chunks, chunk{metrics: pmetric.NewMetrics()})
+					current = &chunks[len(chunks)-1]
+					opened = false
+				}
+				if !opened {
+					destRM := current.metrics.ResourceMetrics().AppendEmpty()
+					rm.Resource().CopyTo(destRM.Resource())
+					destRM.SetSchemaUrl(rm.SchemaUrl())
+					destSM := destRM.ScopeMetrics().AppendEmpty()
+					sm.Scope().CopyTo(destSM.Scope())
+					destSM.SetSchemaUrl(sm.SchemaUrl())
+					dest = destSM.Metrics()
+					opened = true
+				}
+				metric.CopyTo(dest.AppendEmpty())
+				current.items += dataPointCount(metric)
+			}
+		}
+	}
+	return chunks
+}
+
+// splitLogs copies the log records into chunks of at most size records.
+func splitLogs(ld plog.Logs, size int) []chunk {
+	var chunks []chunk
+	var current *chunk
+	for i := 0; i < ld.ResourceLogs().Len(); i++ {
+		rl := ld.ResourceLogs().At(i)
+		for j := 0; j < rl.ScopeLogs().Len(); j++ {
+			sl := rl.ScopeLogs().At(j)
+			var dest plog.LogRecordSlice
+			opened := false
+			for k := 0; k < sl.LogRecords().Len(); k++ {
+				if current == nil || current.items >= size {
+					chunks = append(chunks, chunk{logs: plog.NewLogs()})
+					current = &chunks[len(chunks)-1]
+					opened = false
+				}
+				if !opened {
+					destRL := current.logs.ResourceLogs().AppendEmpty()
+					rl.Resource().CopyTo(destRL.Resource())
+					destRL.SetSchemaUrl(rl.SchemaUrl())
+					destSL := destRL.ScopeLogs().AppendEmpty()
+					sl.Scope().CopyTo(destSL.Scope())
+					destSL.SetSchemaUrl(sl.SchemaUrl())
+					dest = destSL.LogRecords()
+					opened = true
+				}
+				sl.LogRecords().At(k).CopyTo(dest.AppendEmpty())
+				current.items++
+			}
+		}
+	}
+	return chunks
+}
+
+func dataPointCount(metric pmetric.Metric) int {
+	switch metric.Type() {
+	case pmetric.MetricTypeGauge:
+		return metric.Gauge().DataPoints().Len()
+	case pmetric.MetricTypeSum:
+		return metric.Sum().DataPoints().Len()
+	case pmetric.MetricTypeHistogram:
+		return metric.Histogram().DataPoints().Len()
+	case pmetric.MetricTypeExponentialHistogram:
+		return metric.ExponentialHistogram().DataPoints().Len()
+	case pmetric.MetricTypeSummary:
+		return metric.Summary().DataPoints().Len()
+	default:
+		return 0
+	}
+}
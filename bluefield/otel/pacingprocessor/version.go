@@ -0,0 +1,3 @@
+package pacingprocessor
+
+const Version = "0.0.1"
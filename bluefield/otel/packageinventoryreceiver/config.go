@@ -0,0 +1,75 @@
+package packageinventoryreceiver
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+const (
+	packageManagerAuto = "auto"
+	packageManagerDpkg = "dpkg"
+	packageManagerRpm  = "rpm"
+)
+
+// Config defines the configuration of the package_inventory receiver.
+type Config struct {
+	// CollectionInterval configures how often installed packages are
+	// inventoried. Defaults to "6h".
+	CollectionInterval time.Duration `mapstructure:"collection_interval"`
+
+	// PackageManager is "dpkg", "rpm", or "auto" to use dpkg if
+	// dpkg-query is installed and rpm otherwise. Defaults to "auto".
+	PackageManager string `mapstructure:"package_manager"`
+
+	// DpkgQueryPath is the path of the dpkg-query binary. Defaults to
+	// "dpkg-query".
+	DpkgQueryPath string `mapstructure:"dpkg_query_path"`
+
+	// RpmPath is the path of the rpm binary. Defaults to "rpm".
+	RpmPath string `mapstructure:"rpm_path"`
+
+	// IncludePackages configures whether a package.info datapoint is
+	// emitted for each installed package. Defaults to true.
+	IncludePackages bool `mapstructure:"include_packages"`
+
+	// CVEFeedPath is the path of the JSON CVE feed cached on the DPU by
+	// the control plane. If empty, packages are not matched against CVEs.
+	CVEFeedPath string `mapstructure:"cve_feed_path"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.CollectionInterval <= 0 {
+		return errors.New("collection_interval must be positive")
+	}
+	switch cfg.PackageManager {
+	case packageManagerAuto, packageManagerDpkg, packageManagerRpm:
+	default:
+		return fmt.Errorf("package_manager must be %q, %q or %q",
+			packageManagerAuto, packageManagerDpkg, packageManagerRpm)
+	}
+	if cfg.DpkgQueryPath == "" {
+		return errors.New("dpkg_query_path cannot be empty")
+	}
+	if cfg.RpmPath == "" {
+		return errors.New("rpm_path cannot be empty")
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		CollectionInterval: 6 * time.Hour,
+		PackageManager:     packageManagerAuto,
+		DpkgQueryPath:      "dpkg-query",
+		RpmPath:            "rpm",
+		IncludePackages:    true,
+	}
+}
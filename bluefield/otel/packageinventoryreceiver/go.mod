@@ -0,0 +1,3 @@
+module packageinventoryreceiver
+
+go 1.22
@@ -0,0 +1,318 @@
+package packageinventoryreceiver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+
+	"otelcommon/metricappend"
+)
+
+const scopeName = "packageinventoryreceiver"
+
+type packageInventoryReceiver struct {
+	config       *Config
+	logger       *zap.Logger
+	nextConsumer consumer.Metrics
+	stopChannel  chan struct{}
+	stopWaiters  sync.WaitGroup
+}
+
+// installedPackage is a package installed on the DPU image.
+type installedPackage struct {
+	name         string
+	version      string
+	architecture string
+}
+
+// cveFeed is the JSON CVE feed cached by the control plane, e.g.
+//
+//	{"generated_at": "2024-05-01T00:00:00Z", "vulnerabilities": [
+//	  {"id": "CVE-2024-2511", "package": "openssl",
+//	   "fixed_version": "3.0.2-0ubuntu1.15", "severity": "medium"}]}
+type cveFeed struct {
+	GeneratedAt     time.Time       `json:"generated_at"`
+	Vulnerabilities []vulnerability `json:"vulnerabilities"`
+}
+
+// vulnerability affects the versions of a package older than FixedVersion,
+// or all versions if no fix is available.
+type vulnerability struct {
+	ID           string `json:"id"`
+	Package      string `json:"package"`
+	FixedVersion string `json:"fixed_version"`
+	Severity     string `json:"severity"`
+}
+
+func newPackageInventoryReceiver(
+	config *Config,
+	logger *zap.Logger,
+	nextConsumer consumer.Metrics,
+) *packageInventoryReceiver {
+	return &packageInventoryReceiver{
+		config:       config,
+		logger:       logger,
+		nextConsumer: nextConsumer,
+		stopChannel:  make(chan struct{}),
+	}
+}
+
+func (r *packageInventoryReceiver) Start(_ context.Context, _ component.Host) error {
+	r.stopWaiters.Add(1)
+	go r.collectLoop()
+	return nil
+}
+
+func (r *packageInventoryReceiver) Shutdown(context.Context) error {
+	close(r.stopChannel)
+	r.stopWaiters.Wait()
+	return nil
+}
+
+func (r *packageInventoryReceiver) collectLoop() {
+	defer r.stopWaiters.Done()
+
+	ticker := time.NewTicker(r.config.CollectionInterval)
+	defer ticker.Stop()
+
+	r.collect()
+	for {
+		select {
+		case <-ticker.C:
+			r.collect()
+		case <-r.stopChannel:
+			return
+		}
+	}
+}
+
+func (r *packageInventoryReceiver) collect() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	packageManager := r.packageManager()
+	packages, err := r.listPackages(ctx, packageManager)
+	if err != nil {
+		r.logger.Error("Failed to list installed packages",
+			zap.String("package_manager", packageManager), zap.Error(err))
+		return
+	}
+
+	now := pcommon.NewTimestampFromTime(time.Now())
+	md := pmetric.NewMetrics()
+	sm := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty()
+	sm.Scope().SetName(scopeName)
+	sm.Scope().SetVersion(Version)
+
+	count := metricappend.Gauge(sm.Metrics(), "package.count", "{packages}",
+		"Packages installed")
+	dp := count.AppendEmpty()
+	dp.SetTimestamp(now)
+	dp.SetIntValue(int64(len(packages)))
+	dp.Attributes().PutStr("package.manager", packageManager)
+
+	if r.config.IncludePackages {
+		info := metricappend.Gauge(sm.Metrics(), "package.info", "1",
+			"Installed package, always 1")
+		for _, pkg := range packages {
+			dp := info.AppendEmpty()
+			dp.SetTimestamp(now)
+			dp.SetIntValue(1)
+			dp.Attributes().PutStr("package.manager", packageManager)
+			dp.Attributes().PutStr("package.name", pkg.name)
+			dp.Attributes().PutStr("package.version", pkg.version)
+			dp.Attributes().PutStr("package.architecture", pkg.architecture)
+		}
+	}
+
+	if r.config.CVEFeedPath != "" {
+		feed, err := readFeed(r.config.CVEFeedPath)
+		if err != nil {
+			r.logger.Warn("Failed to read CVE feed, skipping vulnerability "+
+				"metrics", zap.String("path", r.config.CVEFeedPath),
+				zap.Error(err))
+		} else {
+			appendVulnerabilityMetrics(sm.Metrics(), feed,
+				matchVulnerabilities(packageManager, packages, feed), now)
+		}
+	}
+
+	if err := r.nextConsumer.ConsumeMetrics(ctx, md); err != nil {
+		r.logger.Error("Failed to consume package inventory", zap.Error(err))
+	}
+}
+
+// packageManager returns the configured package manager, resolving "auto".
+func (r *packageInventoryReceiver) packageManager() string {
+	if r.config.PackageManager != packageManagerAuto {
+		return r.config.PackageManager
+	}
+	if _, err := exec.LookPath(r.config.DpkgQueryPath); err == nil {
+		return packageManagerDpkg
+	}
+	return packageManagerRpm
+}
+
+// listPackages returns the installed packages sorted by name.
+func (r *packageInventoryReceiver) listPackages(
+	ctx context.Context,
+	packageManager string,
+) ([]installedPackage, error) {
+	var cmd *exec.Cmd
+	if packageManager == packageManagerDpkg {
+		cmd = exec.CommandContext(ctx, r.config.DpkgQueryPath, "-W", "-f",
+			`${db:Status-Abbrev}\t${Package}\t${Version}\t${Architecture}\n`)
+	} else {
+		// the epoch is only included if the package has one, and the
+		// status is faked to match the output of dpkg-query
+		cmd = exec.CommandContext(ctx, r.config.RpmPath, "-qa", "--qf",
+			`ii \t%{NAME}\t%|EPOCH?{%{EPOCH}:}:{}|%{VERSION}-%{RELEASE}\t%{ARCH}\n`)
+	}
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("%w: %s", err, exitErr.Stderr)
+		}
+		return nil, err
+	}
+
+	var packages []installedPackage
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		// only packages that are installed rather than, e.g., removed
+		// with their configuration files left behind
+		if len(fields) != 4 || !strings.HasPrefix(fields[0], "ii") {
+			continue
+		}
+		packages = append(packages, installedPackage{
+			name:         fields[1],
+			version:      fields[2],
+			architecture: fields[3],
+		})
+	}
+	sort.Slice(packages, func(i, j int) bool {
+		return packages[i].name < packages[j].name
+	})
+	return packages, nil
+}
+
+func readFeed(path string) (*cveFeed, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var feed cveFeed
+	if err := json.Unmarshal(data, &feed); err != nil {
+		return nil, fmt.Errorf("invalid CVE feed: %w", err)
+	}
+	return &feed, nil
+}
+
+// finding is a vulnerability affecting an installed package.
+type finding struct {
+	vulnerability
+	installedVersion string
+}
+
+// matchVulnerabilities returns the vulnerabilities of the feed affecting the
+// installed packages.
+func matchVulnerabilities(
+	packageManager string,
+	packages []installedPackage,
+	feed *cveFeed,
+) []finding {
+	versions := make(map[string][]string)
+	for _, pkg := range packages {
+		// packages installed for several architectures are listed once
+		// per architecture
+		versions[pkg.name] = append(versions[pkg.name], pkg.version)
+	}
+
+	var findings []finding
+	for _, v := range feed.Vulnerabilities {
+		for _, version := range versions[v.Package] {
+			if v.FixedVersion == "" ||
+				compareVersions(packageManager, version, v.FixedVersion) < 0 {
+				findings = append(findings, finding{v, version})
+			}
+		}
+	}
+	return findings
+}
+
+func appendVulnerabilityMetrics(
+	metrics pmetric.MetricSlice,
+	feed *cveFeed,
+	findings []finding,
+	now pcommon.Timestamp,
+) {
+	// every severity of the feed is reported, so that a fixed
+	// vulnerability brings its count to zero rather than removing it
+	counts := make(map[string]int64)
+	for _, v := range feed.Vulnerabilities {
+		counts[strings.ToLower(v.Severity)] = 0
+	}
+	for _, f := range findings {
+		counts[strings.ToLower(f.Severity)]++
+	}
+	severities := make([]string, 0, len(counts))
+	for severity := range counts {
+		severities = append(severities, severity)
+	}
+	sort.Strings(severities)
+
+	count := metricappend.Gauge(metrics, "vulnerability.count", "{vulnerabilities}",
+		"Vulnerabilities of the CVE feed affecting installed packages")
+	for _, severity := range severities {
+		dp := count.AppendEmpty()
+		dp.SetTimestamp(now)
+		dp.SetIntValue(counts[severity])
+		dp.Attributes().PutStr("vulnerability.severity", severity)
+	}
+
+	if len(findings) > 0 {
+		appendFindings(metrics, findings, now)
+	}
+
+	if !feed.GeneratedAt.IsZero() {
+		age := metricappend.Gauge(metrics, "vulnerability.feed.age", "s",
+			"Time since the CVE feed was generated")
+		dp := age.AppendEmpty()
+		dp.SetTimestamp(now)
+		dp.SetDoubleValue(now.AsTime().Sub(feed.GeneratedAt).Seconds())
+	}
+}
+
+func appendFindings(
+	metrics pmetric.MetricSlice,
+	findings []finding,
+	now pcommon.Timestamp,
+) {
+	info := metricappend.Gauge(metrics, "vulnerability.info", "1",
+		"Vulnerability affecting an installed package, always 1")
+	for _, f := range findings {
+		dp := info.AppendEmpty()
+		dp.SetTimestamp(now)
+		dp.SetIntValue(1)
+		dp.Attributes().PutStr("vulnerability.id", f.ID)
+		dp.Attributes().PutStr("vulnerability.severity", strings.ToLower(f.Severity))
+		dp.Attributes().PutStr("vulnerability.fixed_version", f.FixedVersion)
+		dp.Attributes().PutStr("package.name", f.Package)
+		dp.Attributes().PutStr("package.version", f.installedVersion)
+	}
+}
@@ -0,0 +1,3 @@
+package packageinventoryreceiver
+
+const Version = "0.0.1"
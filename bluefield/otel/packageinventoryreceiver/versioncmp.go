@@ -0,0 +1,238 @@
+package packageinventoryreceiver
+
+import (
+	"strconv"
+	"strings"
+)
+
+// compareVersions compares two package versions by the rules of the package
+// manager, returning a negative number if a is older than b, zero if they are
+// equal and a positive number if a is newer.
+func compareVersions(packageManager, a, b string) int {
+	if packageManager == packageManagerRpm {
+		return compareRpmVersions(a, b)
+	}
+	return compareDpkgVersions(a, b)
+}
+
+// splitEpoch splits "[epoch:]version" into the numeric epoch and the rest.
+func splitEpoch(version string) (int64, string) {
+	epoch, rest, found := strings.Cut(version, ":")
+	if !found {
+		return 0, version
+	}
+	n, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return 0, version
+	}
+	return n, rest
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compareDpkgVersions compares "[epoch:]upstream[-revision]" versions as
+// described in the Debian policy manual.
+func compareDpkgVersions(a, b string) int {
+	epochA, a := splitEpoch(a)
+	epochB, b := splitEpoch(b)
+	if c := compareInts(epochA, epochB); c != 0 {
+		return c
+	}
+
+	upstreamA, revisionA := a, ""
+	if i := strings.LastIndexByte(a, '-'); i >= 0 {
+		upstreamA, revisionA = a[:i], a[i+1:]
+	}
+	upstreamB, revisionB := b, ""
+	if i := strings.LastIndexByte(b, '-'); i >= 0 {
+		upstreamB, revisionB = b[:i], b[i+1:]
+	}
+	if c := compareDpkgParts(upstreamA, upstreamB); c != 0 {
+		return c
+	}
+	return compareDpkgParts(revisionA, revisionB)
+}
+
+// dpkgOrder returns the sort weight of a character in the non-digit parts of
+// a version: "~" sorts before the end of a part, which sorts before letters,
+// which sort before other characters.
+func dpkgOrder(s string, i int) int {
+	if i >= len(s) {
+		return 0
+	}
+	c := s[i]
+	switch {
+	case isDigit(c):
+		return 0
+	case isLetter(c):
+		return int(c)
+	case c == '~':
+		return -1
+	}
+	return int(c) + 256
+}
+
+// compareDpkgParts compares upstream versions or revisions by alternating
+// non-digit parts, compared by dpkgOrder, and digit parts, compared
+// numerically.
+func compareDpkgParts(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		for (i < len(a) && !isDigit(a[i])) || (j < len(b) && !isDigit(b[j])) {
+			orderA, orderB := dpkgOrder(a, i), dpkgOrder(b, j)
+			if orderA != orderB {
+				return orderA - orderB
+			}
+			i++
+			j++
+		}
+		for i < len(a) && a[i] == '0' {
+			i++
+		}
+		for j < len(b) && b[j] == '0' {
+			j++
+		}
+		firstDiff := 0
+		for i < len(a) && isDigit(a[i]) && j < len(b) && isDigit(b[j]) {
+			if firstDiff == 0 {
+				firstDiff = int(a[i]) - int(b[j])
+			}
+			i++
+			j++
+		}
+		if i < len(a) && isDigit(a[i]) {
+			return 1
+		}
+		if j < len(b) && isDigit(b[j]) {
+			return -1
+		}
+		if firstDiff != 0 {
+			return firstDiff
+		}
+	}
+	return 0
+}
+
+// compareRpmVersions compares "[epoch:]version-release" versions like
+// rpmvercmp.
+func compareRpmVersions(a, b string) int {
+	epochA, a := splitEpoch(a)
+	epochB, b := splitEpoch(b)
+	if c := compareInts(epochA, epochB); c != 0 {
+		return c
+	}
+
+	versionA, releaseA, _ := strings.Cut(a, "-")
+	versionB, releaseB, _ := strings.Cut(b, "-")
+	if c := compareRpmParts(versionA, versionB); c != 0 {
+		return c
+	}
+	return compareRpmParts(releaseA, releaseB)
+}
+
+// compareRpmParts compares versions or releases segment by segment, where
+// segments are runs of digits or letters. Numeric segments are newer than
+// alphabetic ones, "~" sorts before anything and "^" after the end.
+func compareRpmParts(a, b string) int {
+	if a == b {
+		return 0
+	}
+	for {
+		for len(a) > 0 && !isAlnum(a[0]) && a[0] != '~' && a[0] != '^' {
+			a = a[1:]
+		}
+		for len(b) > 0 && !isAlnum(b[0]) && b[0] != '~' && b[0] != '^' {
+			b = b[1:]
+		}
+
+		switch {
+		case strings.HasPrefix(a, "~") || strings.HasPrefix(b, "~"):
+			if !strings.HasPrefix(a, "~") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "~") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		case strings.HasPrefix(a, "^") || strings.HasPrefix(b, "^"):
+			if a == "" {
+				return -1
+			}
+			if b == "" {
+				return 1
+			}
+			if !strings.HasPrefix(a, "^") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "^") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		if a == "" || b == "" {
+			break
+		}
+
+		numeric := isDigit(a[0])
+		segmentA := leadingRun(a, numeric)
+		segmentB := leadingRun(b, numeric)
+		a, b = a[len(segmentA):], b[len(segmentB):]
+		if segmentB == "" {
+			// segments of different types
+			if numeric {
+				return 1
+			}
+			return -1
+		}
+
+		if numeric {
+			segmentA = strings.TrimLeft(segmentA, "0")
+			segmentB = strings.TrimLeft(segmentB, "0")
+			if c := compareInts(int64(len(segmentA)), int64(len(segmentB))); c != 0 {
+				return c
+			}
+		}
+		if c := strings.Compare(segmentA, segmentB); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case a == "" && b == "":
+		return 0
+	case a == "":
+		return -1
+	}
+	return 1
+}
+
+// leadingRun returns the leading digits or letters of s.
+func leadingRun(s string, digits bool) string {
+	i := 0
+	for i < len(s) && ((digits && isDigit(s[i])) || (!digits && isLetter(s[i]))) {
+		i++
+	}
+	return s[:i]
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isAlnum(c byte) bool {
+	return isDigit(c) || isLetter(c)
+}
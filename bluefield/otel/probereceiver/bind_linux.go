@@ -0,0 +1,22 @@
+package probereceiver
+
+import (
+	"syscall"
+)
+
+// bindToDevice returns a dialer control function binding sockets to the
+// network interface or VRF, so that probes take that path regardless of the
+// routing table of the collector's own VRF.
+func bindToDevice(iface string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var bindErr error
+		err := c.Control(func(fd uintptr) {
+			bindErr = syscall.SetsockoptString(int(fd), syscall.SOL_SOCKET,
+				syscall.SO_BINDTODEVICE, iface)
+		})
+		if err != nil {
+			return err
+		}
+		return bindErr
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package probereceiver
+
+import (
+	"errors"
+	"syscall"
+)
+
+func bindToDevice(string) func(network, address string, c syscall.RawConn) error {
+	return func(string, string, syscall.RawConn) error {
+		return errors.New("binding to an interface is only supported on linux")
+	}
+}
@@ -0,0 +1,131 @@
+package probereceiver
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+const (
+	probeTypeDNS   = "dns"
+	probeTypeTCP   = "tcp"
+	probeTypeHTTPS = "https"
+)
+
+// Config defines the configuration of the probe receiver.
+type Config struct {
+	// CollectionInterval configures how often all probes are run.
+	// Defaults to "1m".
+	CollectionInterval time.Duration `mapstructure:"collection_interval"`
+
+	// Timeout limits how long a single probe may take before it fails.
+	// Defaults to "10s".
+	Timeout time.Duration `mapstructure:"timeout"`
+
+	// Probes configures the probes to run.
+	Probes []Probe `mapstructure:"probes"`
+}
+
+// Probe defines a single synthetic probe.
+type Probe struct {
+	// Name identifies the probe in the `probe.name` attribute.
+	Name string `mapstructure:"name"`
+
+	// Type is "dns" to resolve a host name, "tcp" to connect to a
+	// host:port, or "https" to request a URL.
+	Type string `mapstructure:"type"`
+
+	// Target is the host name for dns probes, the host:port for tcp
+	// probes, or the https:// URL for https probes.
+	Target string `mapstructure:"target"`
+
+	// Interface optionally binds the probe's sockets to a network
+	// interface or VRF, e.g. "oob_net0" or "mgmt", to test a specific
+	// path. If empty, the routing table decides.
+	Interface string `mapstructure:"interface"`
+
+	// DNSServer is an optional host:port of the DNS server used by dns
+	// probes, and by tcp and https probes to resolve their target. If
+	// empty, the system resolver configuration is used.
+	DNSServer string `mapstructure:"dns_server"`
+
+	// CAFile is an optional path of a PEM encoded CA bundle used by https
+	// probes to verify the server, instead of the system roots.
+	CAFile string `mapstructure:"ca_file"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.CollectionInterval <= 0 {
+		return errors.New("collection_interval must be positive")
+	}
+	if cfg.Timeout <= 0 || cfg.Timeout > cfg.CollectionInterval {
+		return errors.New("timeout must be positive and not exceed " +
+			"collection_interval")
+	}
+	if len(cfg.Probes) == 0 {
+		return errors.New("at least one probe must be configured")
+	}
+
+	names := make(map[string]bool)
+	for _, probe := range cfg.Probes {
+		if probe.Name == "" {
+			return errors.New("probe name cannot be empty")
+		}
+		if names[probe.Name] {
+			return fmt.Errorf("probe %s is configured more than once",
+				probe.Name)
+		}
+		names[probe.Name] = true
+
+		if err := probe.validate(); err != nil {
+			return fmt.Errorf("probe %s: %w", probe.Name, err)
+		}
+	}
+	return nil
+}
+
+func (probe *Probe) validate() error {
+	if probe.Target == "" {
+		return errors.New("target cannot be empty")
+	}
+	switch probe.Type {
+	case probeTypeDNS:
+	case probeTypeTCP:
+		if _, _, err := net.SplitHostPort(probe.Target); err != nil {
+			return fmt.Errorf("target must be host:port: %w", err)
+		}
+	case probeTypeHTTPS:
+		u, err := url.Parse(probe.Target)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return errors.New("target must be an https:// URL")
+		}
+	default:
+		return fmt.Errorf("type must be %q, %q or %q",
+			probeTypeDNS, probeTypeTCP, probeTypeHTTPS)
+	}
+	if probe.DNSServer != "" {
+		if _, _, err := net.SplitHostPort(probe.DNSServer); err != nil {
+			return fmt.Errorf("dns_server must be host:port: %w", err)
+		}
+	}
+	if probe.CAFile != "" && probe.Type != probeTypeHTTPS {
+		return errors.New("ca_file only applies to https probes")
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		CollectionInterval: time.Minute,
+		Timeout:            10 * time.Second,
+	}
+}
@@ -0,0 +1,3 @@
+module probereceiver
+
+go 1.22
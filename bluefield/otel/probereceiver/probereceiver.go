@@ -0,0 +1,297 @@
+package probereceiver
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+const scopeName = "probereceiver"
+
+type probeReceiver struct {
+	config       *Config
+	logger       *zap.Logger
+	nextConsumer consumer.Metrics
+	probers      []*prober
+	stopChannel  chan struct{}
+	stopWaiters  sync.WaitGroup
+}
+
+// prober runs a single configured probe.
+type prober struct {
+	probe      Probe
+	dialer     *net.Dialer
+	resolver   *net.Resolver
+	httpClient *http.Client
+
+	// success of the previous run, only accessed by the collect loop
+	lastSuccess *bool
+}
+
+type probeResult struct {
+	success    bool
+	duration   time.Duration
+	statusCode int
+	err        error
+}
+
+func newProbeReceiver(
+	config *Config,
+	logger *zap.Logger,
+	nextConsumer consumer.Metrics,
+) *probeReceiver {
+	return &probeReceiver{
+		config:       config,
+		logger:       logger,
+		nextConsumer: nextConsumer,
+		stopChannel:  make(chan struct{}),
+	}
+}
+
+func (r *probeReceiver) Start(_ context.Context, _ component.Host) error {
+	for _, probe := range r.config.Probes {
+		p, err := newProber(probe, r.config.Timeout)
+		if err != nil {
+			return fmt.Errorf("failed to create probe %s: %w", probe.Name, err)
+		}
+		r.probers = append(r.probers, p)
+	}
+
+	r.stopWaiters.Add(1)
+	go r.collectLoop()
+	return nil
+}
+
+func (r *probeReceiver) Shutdown(context.Context) error {
+	close(r.stopChannel)
+	r.stopWaiters.Wait()
+	for _, p := range r.probers {
+		if p.httpClient != nil {
+			p.httpClient.CloseIdleConnections()
+		}
+	}
+	return nil
+}
+
+func newProber(probe Probe, timeout time.Duration) (*prober, error) {
+	p := &prober{
+		probe:  probe,
+		dialer: &net.Dialer{Timeout: timeout},
+	}
+	if probe.Interface != "" {
+		p.dialer.Control = bindToDevice(probe.Interface)
+	}
+
+	p.resolver = &net.Resolver{PreferGo: true}
+	if probe.Interface != "" || probe.DNSServer != "" {
+		p.resolver.Dial = func(ctx context.Context, network, address string) (net.Conn, error) {
+			if probe.DNSServer != "" {
+				address = probe.DNSServer
+			}
+			return p.dialer.DialContext(ctx, network, address)
+		}
+	}
+	p.dialer.Resolver = p.resolver
+
+	if probe.Type == probeTypeHTTPS {
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+		if probe.CAFile != "" {
+			pem, err := os.ReadFile(probe.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA file: %w", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in %s",
+					probe.CAFile)
+			}
+			tlsConfig.RootCAs = pool
+		}
+		p.httpClient = &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				DialContext:       p.dialer.DialContext,
+				TLSClientConfig:   tlsConfig,
+				DisableKeepAlives: true,
+			},
+			// report the status of the probed URL itself
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	}
+
+	return p, nil
+}
+
+func (r *probeReceiver) collectLoop() {
+	defer r.stopWaiters.Done()
+
+	ticker := time.NewTicker(r.config.CollectionInterval)
+	defer ticker.Stop()
+
+	r.collect()
+	for {
+		select {
+		case <-ticker.C:
+			r.collect()
+		case <-r.stopChannel:
+			return
+		}
+	}
+}
+
+// collect runs all probes concurrently so that a probe hanging until its
+// timeout doesn't delay the others.
+func (r *probeReceiver) collect() {
+	ctx, cancel := context.WithTimeout(context.Background(), r.config.Timeout)
+	defer cancel()
+
+	results := make([]probeResult, len(r.probers))
+	var wg sync.WaitGroup
+	for i, p := range r.probers {
+		wg.Add(1)
+		go func(i int, p *prober) {
+			defer wg.Done()
+			results[i] = p.run(ctx)
+		}(i, p)
+	}
+	wg.Wait()
+
+	now := pcommon.NewTimestampFromTime(time.Now())
+	md := pmetric.NewMetrics()
+	sm := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty()
+	sm.Scope().SetName(scopeName)
+	sm.Scope().SetVersion(Version)
+
+	success := sm.Metrics().AppendEmpty()
+	success.SetName("probe.success")
+	success.SetDescription("Whether the probe succeeded (1) or failed (0)")
+	success.SetUnit("1")
+	successPoints := success.SetEmptyGauge().DataPoints()
+
+	duration := sm.Metrics().AppendEmpty()
+	duration.SetName("probe.duration")
+	duration.SetDescription("Time taken by the probe, including failed probes")
+	duration.SetUnit("s")
+	durationPoints := duration.SetEmptyGauge().DataPoints()
+
+	statusCode := sm.Metrics().AppendEmpty()
+	statusCode.SetName("probe.http.status_code")
+	statusCode.SetDescription("HTTP status code returned to https probes")
+	statusCode.SetUnit("1")
+	statusCodePoints := statusCode.SetEmptyGauge().DataPoints()
+
+	for i, p := range r.probers {
+		result := results[i]
+		r.logTransition(p, result)
+
+		dp := successPoints.AppendEmpty()
+		dp.SetTimestamp(now)
+		if result.success {
+			dp.SetIntValue(1)
+		} else {
+			dp.SetIntValue(0)
+		}
+		p.putAttributes(dp.Attributes())
+
+		dp = durationPoints.AppendEmpty()
+		dp.SetTimestamp(now)
+		dp.SetDoubleValue(result.duration.Seconds())
+		p.putAttributes(dp.Attributes())
+
+		if result.statusCode != 0 {
+			dp = statusCodePoints.AppendEmpty()
+			dp.SetTimestamp(now)
+			dp.SetIntValue(int64(result.statusCode))
+			p.putAttributes(dp.Attributes())
+		}
+	}
+
+	if err := r.nextConsumer.ConsumeMetrics(context.Background(), md); err != nil {
+		r.logger.Error("Failed to consume probe metrics", zap.Error(err))
+	}
+}
+
+// logTransition logs when a probe starts failing or recovers, rather than on
+// every failed run.
+func (r *probeReceiver) logTransition(p *prober, result probeResult) {
+	if p.lastSuccess != nil && *p.lastSuccess == result.success {
+		return
+	}
+	success := result.success
+	p.lastSuccess = &success
+
+	fields := []zap.Field{
+		zap.String("probe", p.probe.Name),
+		zap.String("type", p.probe.Type),
+		zap.String("target", p.probe.Target),
+		zap.String("interface", p.probe.Interface),
+	}
+	if result.success {
+		r.logger.Info("Probe succeeded", fields...)
+	} else {
+		r.logger.Warn("Probe failed", append(fields, zap.Error(result.err))...)
+	}
+}
+
+func (p *prober) run(ctx context.Context) probeResult {
+	start := time.Now()
+	var result probeResult
+
+	switch p.probe.Type {
+	case probeTypeDNS:
+		_, result.err = p.resolver.LookupHost(ctx, p.probe.Target)
+	case probeTypeTCP:
+		var conn net.Conn
+		conn, result.err = p.dialer.DialContext(ctx, "tcp", p.probe.Target)
+		if result.err == nil {
+			conn.Close()
+		}
+	case probeTypeHTTPS:
+		result.statusCode, result.err = p.get(ctx)
+	}
+
+	result.duration = time.Since(start)
+	result.success = result.err == nil
+	return result
+}
+
+func (p *prober) get(ctx context.Context) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		p.probe.Target, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+func (p *prober) putAttributes(attrs pcommon.Map) {
+	attrs.PutStr("probe.name", p.probe.Name)
+	attrs.PutStr("probe.type", p.probe.Type)
+	attrs.PutStr("probe.target", p.probe.Target)
+	if p.probe.Interface != "" {
+		attrs.PutStr("probe.interface", p.probe.Interface)
+	}
+}
@@ -0,0 +1,3 @@
+package probereceiver
+
+const Version = "0.0.1"
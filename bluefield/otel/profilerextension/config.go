@@ -0,0 +1,99 @@
+package profilerextension
+
+import (
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+// Config defines the configuration of the profiler extension.
+type Config struct {
+	// Endpoint is the local address serving pprof under /debug/pprof/ and
+	// the captured profiles under /debug/pprof/captured/. It may be
+	// shared with other components. Defaults to "localhost:1777". Leave
+	// empty to only capture profiles.
+	Endpoint string `mapstructure:"endpoint"`
+
+	// Directory is where captured profiles are stored. Defaults to
+	// "/var/lib/otelcol-contrib/profiles".
+	Directory string `mapstructure:"directory"`
+
+	// MaxProfiles limits the number of captured profiles kept in
+	// Directory, removing the oldest first. Defaults to 10.
+	MaxProfiles int `mapstructure:"max_profiles"`
+
+	// CheckInterval configures how often memory and CPU usage are
+	// checked against the thresholds. Defaults to "10s".
+	CheckInterval time.Duration `mapstructure:"check_interval"`
+
+	// MemoryPercent is the percentage of system memory in use above which
+	// a heap profile is captured. Zero disables the threshold. Defaults
+	// to 90.
+	MemoryPercent float64 `mapstructure:"memory_percent"`
+
+	// ProcessMemoryMiB is the resident memory of the collector in MiB
+	// above which a heap profile is captured. Zero disables the
+	// threshold. Defaults to 0.
+	ProcessMemoryMiB int64 `mapstructure:"process_memory_mib"`
+
+	// CPUPercent is the percentage of CPU time of all cores in use,
+	// averaged over CheckInterval, above which a CPU profile is captured.
+	// Zero disables the threshold. Defaults to 90.
+	CPUPercent float64 `mapstructure:"cpu_percent"`
+
+	// CPUProfileDuration configures how long CPU profiles are captured
+	// for. Defaults to "30s".
+	CPUProfileDuration time.Duration `mapstructure:"cpu_profile_duration"`
+
+	// Cooldown is the minimum time between captures of the same kind of
+	// profile, so that sustained pressure doesn't replace all stored
+	// profiles with near identical ones. Defaults to "15m".
+	Cooldown time.Duration `mapstructure:"cooldown"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.Directory == "" {
+		return errors.New("directory cannot be empty")
+	}
+	if cfg.MaxProfiles <= 0 {
+		return errors.New("max_profiles must be positive")
+	}
+	if cfg.CheckInterval <= 0 {
+		return errors.New("check_interval must be positive")
+	}
+	if cfg.MemoryPercent < 0 || cfg.MemoryPercent > 100 {
+		return errors.New("memory_percent must be between 0 and 100")
+	}
+	if cfg.ProcessMemoryMiB < 0 {
+		return errors.New("process_memory_mib cannot be negative")
+	}
+	if cfg.CPUPercent < 0 || cfg.CPUPercent > 100 {
+		return errors.New("cpu_percent must be between 0 and 100")
+	}
+	if cfg.CPUProfileDuration <= 0 {
+		return errors.New("cpu_profile_duration must be positive")
+	}
+	if cfg.Cooldown < 0 {
+		return errors.New("cooldown cannot be negative")
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		Endpoint:           "localhost:1777",
+		Directory:          "/var/lib/otelcol-contrib/profiles",
+		MaxProfiles:        10,
+		CheckInterval:      10 * time.Second,
+		MemoryPercent:      90,
+		CPUPercent:         90,
+		CPUProfileDuration: 30 * time.Second,
+		Cooldown:           15 * time.Minute,
+	}
+}
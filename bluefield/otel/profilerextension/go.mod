@@ -0,0 +1,3 @@
+module profilerextension
+
+go 1.22
@@ -0,0 +1,284 @@
+package profilerextension
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"path/filepath"
+	"runtime"
+	rpprof "runtime/pprof"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.uber.org/zap"
+
+	"otelcommon/httpregistry"
+)
+
+const (
+	profileHeap = "heap"
+	profileCPU  = "cpu"
+)
+
+type profilerExtension struct {
+	config        *Config
+	logger        *zap.Logger
+	registration  *httpregistry.Registration
+	capturingLock sync.Mutex
+	capturing     bool // whether a CPU profile is being captured
+	stopChannel   chan struct{}
+	stopWaiters   sync.WaitGroup
+
+	// pressure tracking, only accessed by the check loop
+	lastCPU      cpuTimes
+	memoryHigh   bool
+	cpuHigh      bool
+	lastCaptures map[string]time.Time
+}
+
+func newProfilerExtension(config *Config, logger *zap.Logger) *profilerExtension {
+	return &profilerExtension{
+		config:       config,
+		logger:       logger,
+		lastCaptures: make(map[string]time.Time),
+		stopChannel:  make(chan struct{}),
+	}
+}
+
+func (p *profilerExtension) Start(_ context.Context, _ component.Host) error {
+	if err := os.MkdirAll(p.config.Directory, 0o755); err != nil {
+		return fmt.Errorf("failed to create profile directory: %w", err)
+	}
+
+	if p.config.Endpoint != "" {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		mux.Handle("/debug/pprof/captured/", http.StripPrefix(
+			"/debug/pprof/captured/", http.FileServer(http.Dir(p.config.Directory))))
+
+		var err error
+		p.registration, err = httpregistry.Register(
+			httpregistry.ServerConfig{Endpoint: p.config.Endpoint},
+			"/debug/pprof/",
+			mux,
+			p.logger,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to register pprof endpoint: %w", err)
+		}
+	}
+
+	if p.config.CPUPercent > 0 {
+		var err error
+		if p.lastCPU, err = readCPUTimes(); err != nil {
+			p.logger.Error("Failed to read CPU times", zap.Error(err))
+		}
+	}
+
+	p.stopWaiters.Add(1)
+	go p.checkLoop()
+	return nil
+}
+
+func (p *profilerExtension) Shutdown(context.Context) error {
+	close(p.stopChannel)
+	p.stopWaiters.Wait()
+
+	if p.registration != nil {
+		p.registration.Unregister()
+		p.registration = nil
+	}
+	return nil
+}
+
+func (p *profilerExtension) checkLoop() {
+	defer p.stopWaiters.Done()
+
+	ticker := time.NewTicker(p.config.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.check(time.Now())
+		case <-p.stopChannel:
+			return
+		}
+	}
+}
+
+// check captures a profile when memory or CPU usage crosses a threshold.
+// Usage has to drop below the threshold again before the next capture, so
+// that each episode of pressure is captured once.
+func (p *profilerExtension) check(now time.Time) {
+	if p.config.MemoryPercent > 0 || p.config.ProcessMemoryMiB > 0 {
+		high, reason := p.memoryPressure()
+		if high && !p.memoryHigh {
+			p.capture(profileHeap, reason, now)
+		}
+		p.memoryHigh = high
+	}
+
+	if p.config.CPUPercent > 0 {
+		times, err := readCPUTimes()
+		if err != nil {
+			p.logger.Error("Failed to read CPU times", zap.Error(err))
+			return
+		}
+		percent := cpuPercent(p.lastCPU, times)
+		p.lastCPU = times
+
+		high := percent > p.config.CPUPercent
+		if high && !p.cpuHigh {
+			p.capture(profileCPU,
+				fmt.Sprintf("CPU usage %.1f%% above %.1f%%", percent,
+					p.config.CPUPercent), now)
+		}
+		p.cpuHigh = high
+	}
+}
+
+// memoryPressure returns whether memory usage is above a threshold, and a
+// description of the threshold crossed.
+func (p *profilerExtension) memoryPressure() (bool, string) {
+	if p.config.MemoryPercent > 0 {
+		percent, err := memoryPercent()
+		if err != nil {
+			p.logger.Error("Failed to read system memory usage", zap.Error(err))
+		} else if percent > p.config.MemoryPercent {
+			return true, fmt.Sprintf("system memory usage %.1f%% above %.1f%%",
+				percent, p.config.MemoryPercent)
+		}
+	}
+	if p.config.ProcessMemoryMiB > 0 {
+		mib, err := processMemoryMiB()
+		if err != nil {
+			p.logger.Error("Failed to read collector memory usage", zap.Error(err))
+		} else if mib > p.config.ProcessMemoryMiB {
+			return true, fmt.Sprintf("collector memory usage %d MiB above %d MiB",
+				mib, p.config.ProcessMemoryMiB)
+		}
+	}
+	return false, ""
+}
+
+// capture stores a profile of the given kind unless one was captured within
+// the cooldown. CPU profiles are captured in the background.
+func (p *profilerExtension) capture(kind, reason string, now time.Time) {
+	if last, captured := p.lastCaptures[kind]; captured &&
+		now.Sub(last) < p.config.Cooldown {
+		p.logger.Debug("Skipping profile capture within cooldown",
+			zap.String("kind", kind), zap.String("reason", reason))
+		return
+	}
+	p.lastCaptures[kind] = now
+
+	path := filepath.Join(p.config.Directory,
+		now.UTC().Format("20060102T150405Z")+"-"+kind+".pprof")
+	if kind == profileHeap {
+		p.writeProfile(path, kind, reason, func(buf *bytes.Buffer) error {
+			// collect garbage first so that the profile reflects live
+			// memory rather than the last collection
+			runtime.GC()
+			return rpprof.Lookup("heap").WriteTo(buf, 0)
+		})
+		return
+	}
+
+	p.capturingLock.Lock()
+	defer p.capturingLock.Unlock()
+	if p.capturing {
+		return
+	}
+	p.capturing = true
+
+	p.stopWaiters.Add(1)
+	go func() {
+		defer p.stopWaiters.Done()
+		defer func() {
+			p.capturingLock.Lock()
+			p.capturing = false
+			p.capturingLock.Unlock()
+		}()
+
+		p.writeProfile(path, kind, reason, func(buf *bytes.Buffer) error {
+			if err := rpprof.StartCPUProfile(buf); err != nil {
+				// e.g. a profile requested through the pprof endpoint
+				return err
+			}
+			timer := time.NewTimer(p.config.CPUProfileDuration)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-p.stopChannel:
+			}
+			rpprof.StopCPUProfile()
+			return nil
+		})
+	}()
+}
+
+// writeProfile stores a profile, removes the oldest profiles beyond
+// MaxProfiles and logs the path of the new profile.
+func (p *profilerExtension) writeProfile(
+	path string,
+	kind string,
+	reason string,
+	profile func(*bytes.Buffer) error,
+) {
+	var buf bytes.Buffer
+	if err := profile(&buf); err != nil {
+		p.logger.Error("Failed to capture profile",
+			zap.String("kind", kind), zap.String("reason", reason),
+			zap.Error(err))
+		return
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		p.logger.Error("Failed to write profile",
+			zap.String("path", path), zap.Error(err))
+		return
+	}
+	p.pruneProfiles()
+
+	p.logger.Warn("Captured profile under pressure",
+		zap.String("kind", kind),
+		zap.String("reason", reason),
+		zap.String("path", path))
+}
+
+// pruneProfiles removes the oldest profiles beyond MaxProfiles. Profile names
+// start with their capture time, so that they sort chronologically.
+func (p *profilerExtension) pruneProfiles() {
+	entries, err := os.ReadDir(p.config.Directory)
+	if err != nil {
+		p.logger.Error("Failed to list profiles", zap.Error(err))
+		return
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.HasSuffix(entry.Name(), ".pprof") {
+			names = append(names, entry.Name())
+		}
+	}
+	slices.Sort(names)
+
+	for len(names) > p.config.MaxProfiles {
+		path := filepath.Join(p.config.Directory, names[0])
+		if err := os.Remove(path); err != nil {
+			p.logger.Error("Failed to remove old profile",
+				zap.String("path", path), zap.Error(err))
+		}
+		names = names[1:]
+	}
+}
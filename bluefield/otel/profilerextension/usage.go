@@ -0,0 +1,125 @@
+package profilerextension
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// readMeminfo returns the values in KiB of the given fields of a meminfo
+// style file such as /proc/meminfo or /proc/self/status.
+func readMeminfo(path string, fields ...string) (map[string]int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	values := make(map[string]int64, len(fields))
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		name, value, found := strings.Cut(scanner.Text(), ":")
+		if !found {
+			continue
+		}
+		for _, field := range fields {
+			if name != field {
+				continue
+			}
+			kib, err := strconv.ParseInt(
+				strings.TrimSuffix(strings.TrimSpace(value), " kB"), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s in %s: %w", name, path, err)
+			}
+			values[name] = kib
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for _, field := range fields {
+		if _, found := values[field]; !found {
+			return nil, fmt.Errorf("no %s in %s", field, path)
+		}
+	}
+	return values, nil
+}
+
+// memoryPercent returns the percentage of system memory in use.
+func memoryPercent() (float64, error) {
+	values, err := readMeminfo("/proc/meminfo", "MemTotal", "MemAvailable")
+	if err != nil {
+		return 0, err
+	}
+	if values["MemTotal"] == 0 {
+		return 0, errors.New("MemTotal is zero")
+	}
+	used := values["MemTotal"] - values["MemAvailable"]
+	return 100 * float64(used) / float64(values["MemTotal"]), nil
+}
+
+// processMemoryMiB returns the resident memory of the collector in MiB.
+func processMemoryMiB() (int64, error) {
+	values, err := readMeminfo("/proc/self/status", "VmRSS")
+	if err != nil {
+		return 0, err
+	}
+	return values["VmRSS"] / 1024, nil
+}
+
+// cpuTimes is the total and idle CPU time of all cores in clock ticks.
+type cpuTimes struct {
+	total int64
+	idle  int64
+}
+
+// readCPUTimes reads the CPU times of all cores from /proc/stat.
+func readCPUTimes() (cpuTimes, error) {
+	file, err := os.Open("/proc/stat")
+	if err != nil {
+		return cpuTimes{}, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return cpuTimes{}, err
+		}
+		return cpuTimes{}, errors.New("/proc/stat is empty")
+	}
+	fields := strings.Fields(scanner.Text())
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return cpuTimes{}, errors.New("unexpected first line in /proc/stat")
+	}
+
+	var times cpuTimes
+	for i, field := range fields[1:] {
+		// guest time is already included in user time
+		if i >= 8 {
+			break
+		}
+		ticks, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return cpuTimes{}, fmt.Errorf("invalid CPU time in /proc/stat: %w", err)
+		}
+		times.total += ticks
+		// idle and iowait
+		if i == 3 || i == 4 {
+			times.idle += ticks
+		}
+	}
+	return times, nil
+}
+
+// cpuPercent returns the percentage of CPU time in use between two readings.
+func cpuPercent(before, after cpuTimes) float64 {
+	total := after.total - before.total
+	if total <= 0 {
+		return 0
+	}
+	return 100 * float64(total-(after.idle-before.idle)) / float64(total)
+}
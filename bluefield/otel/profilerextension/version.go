@@ -0,0 +1,3 @@
+package profilerextension
+
+const Version = "0.0.1"
@@ -0,0 +1,45 @@
+package representorreceiver
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+// Config defines the configuration of the representor receiver.
+type Config struct {
+	// CollectionInterval configures how often the representor count is
+	// reported. Interfaces are also listed on every interval, to catch
+	// netlink notifications the receiver missed. Defaults to "30s".
+	CollectionInterval time.Duration `mapstructure:"collection_interval"`
+
+	// InterfaceRegex matches the names of the representor interfaces to
+	// watch. Defaults to VF representors (pf0vf0) and SF representors
+	// (en3f0pf0sf0).
+	InterfaceRegex string `mapstructure:"interface_regex"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.CollectionInterval <= 0 {
+		return errors.New("collection_interval must be positive")
+	}
+	if _, err := regexp.Compile(cfg.InterfaceRegex); err != nil {
+		return fmt.Errorf("invalid interface_regex: %w", err)
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		CollectionInterval: 30 * time.Second,
+		InterfaceRegex:     `^(pf[0-9]+vf[0-9]+|en3f[0-9]+pf[0-9]+sf[0-9]+)$`,
+	}
+}
@@ -0,0 +1,3 @@
+module representorreceiver
+
+go 1.22
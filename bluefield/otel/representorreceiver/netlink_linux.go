@@ -0,0 +1,100 @@
+package representorreceiver
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// watchLinks subscribes to netlink link notifications and calls handle with
+// each link created, changed or deleted, until stop is closed. ENOBUFS, i.e.
+// notifications dropped because the socket buffer overflowed, is returned
+// as an error, after which the caller must list the links to catch up.
+func watchLinks(stop <-chan struct{}, handle func(linkEvent)) error {
+	fd, err := syscall.Socket(syscall.AF_NETLINK,
+		syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_ROUTE)
+	if err != nil {
+		return fmt.Errorf("failed to open netlink socket: %w", err)
+	}
+	sockFile := os.NewFile(uintptr(fd), "netlink")
+	defer sockFile.Close()
+
+	addr := &syscall.SockaddrNetlink{
+		Family: syscall.AF_NETLINK,
+		// the multicast group mask of RTNLGRP_LINK, i.e. RTMGRP_LINK
+		Groups: 1 << (syscall.RTNLGRP_LINK - 1),
+	}
+	if err := syscall.Bind(fd, addr); err != nil {
+		return fmt.Errorf("failed to bind netlink socket: %w", err)
+	}
+
+	// receive with a timeout, so that stop is noticed
+	timeout := syscall.Timeval{Sec: 1}
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET,
+		syscall.SO_RCVTIMEO, &timeout); err != nil {
+		return fmt.Errorf("failed to set netlink receive timeout: %w", err)
+	}
+
+	buf := make([]byte, os.Getpagesize()*4)
+	for {
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+
+		n, _, err := syscall.Recvfrom(fd, buf, 0)
+		if err != nil {
+			if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR) {
+				continue
+			}
+			return err
+		}
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return fmt.Errorf("invalid netlink message: %w", err)
+		}
+		for _, msg := range msgs {
+			if event, ok := parseLinkMessage(&msg); ok {
+				handle(event)
+			}
+		}
+	}
+}
+
+// parseLinkMessage parses an RTM_NEWLINK or RTM_DELLINK message.
+func parseLinkMessage(msg *syscall.NetlinkMessage) (linkEvent, bool) {
+	if msg.Header.Type != syscall.RTM_NEWLINK &&
+		msg.Header.Type != syscall.RTM_DELLINK {
+		return linkEvent{}, false
+	}
+	if len(msg.Data) < syscall.SizeofIfInfomsg {
+		return linkEvent{}, false
+	}
+	info := (*syscall.IfInfomsg)(unsafe.Pointer(&msg.Data[0]))
+
+	attrs, err := syscall.ParseNetlinkRouteAttr(msg)
+	if err != nil {
+		return linkEvent{}, false
+	}
+	event := linkEvent{
+		index:   int(info.Index),
+		deleted: msg.Header.Type == syscall.RTM_DELLINK,
+	}
+	for _, attr := range attrs {
+		switch attr.Attr.Type {
+		case syscall.IFLA_IFNAME:
+			// null terminated
+			name := attr.Value
+			if len(name) > 0 && name[len(name)-1] == 0 {
+				name = name[:len(name)-1]
+			}
+			event.name = string(name)
+		case syscall.IFLA_ADDRESS:
+			event.mac = formatMAC(attr.Value)
+		}
+	}
+	return event, event.name != ""
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package representorreceiver
+
+import (
+	"errors"
+)
+
+func watchLinks(<-chan struct{}, func(linkEvent)) error {
+	return errors.New("watching netlink is only supported on linux")
+}
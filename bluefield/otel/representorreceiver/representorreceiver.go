@@ -0,0 +1,358 @@
+package representorreceiver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+const scopeName = "representorreceiver"
+
+const (
+	eventCreated = "created"
+	eventDeleted = "deleted"
+)
+
+// rePF matches the physical function of a representor name
+var rePF = regexp.MustCompile(`pf[0-9]+`)
+
+type representorReceiver struct {
+	config          *Config
+	reInterface     *regexp.Regexp
+	logger          *zap.Logger
+	metricsConsumer consumer.Metrics
+	logsConsumer    consumer.Logs
+	startOnce       sync.Once
+	stopOnce        sync.Once
+	stopChannel     chan struct{}
+	stopWaiters     sync.WaitGroup
+
+	representorsLock sync.Mutex
+	representors     map[int]representor // by interface index
+	pfs              map[string]bool     // seen so far, reported even at zero
+}
+
+type representor struct {
+	name string
+	mac  string
+}
+
+// linkEvent is a netlink notification of a link that was created, changed or
+// deleted.
+type linkEvent struct {
+	index   int
+	name    string
+	mac     string
+	deleted bool
+}
+
+// lifecycleEvent is a representor that was created or deleted.
+type lifecycleEvent struct {
+	event string
+	index int
+	representor
+	time pcommon.Timestamp
+}
+
+func newRepresentorReceiver(config *Config, logger *zap.Logger) *representorReceiver {
+	return &representorReceiver{
+		config:       config,
+		reInterface:  regexp.MustCompile(config.InterfaceRegex),
+		logger:       logger,
+		stopChannel:  make(chan struct{}),
+		representors: make(map[int]representor),
+		pfs:          make(map[string]bool),
+	}
+}
+
+func (r *representorReceiver) Start(_ context.Context, _ component.Host) error {
+	var err error
+	r.startOnce.Do(func() {
+		// representors present at startup are not lifecycle events
+		var links []linkEvent
+		links, err = listLinks()
+		if err != nil {
+			err = fmt.Errorf("failed to list interfaces: %w", err)
+			return
+		}
+		r.sync(links)
+
+		r.stopWaiters.Add(2)
+		go r.watchLoop()
+		go r.collectLoop()
+	})
+	return err
+}
+
+func (r *representorReceiver) Shutdown(context.Context) error {
+	r.stopOnce.Do(func() {
+		close(r.stopChannel)
+		r.stopWaiters.Wait()
+		removeReceiver(r.config)
+	})
+	return nil
+}
+
+// watchLoop emits lifecycle events as netlink notifications arrive. If
+// notifications were dropped, the interfaces are listed to catch up.
+func (r *representorReceiver) watchLoop() {
+	defer r.stopWaiters.Done()
+
+	for {
+		err := watchLinks(r.stopChannel, func(link linkEvent) {
+			r.emitEvents(r.update(link))
+		})
+		select {
+		case <-r.stopChannel:
+			return
+		default:
+		}
+
+		if !errors.Is(err, syscall.ENOBUFS) {
+			r.logger.Error("Failed to watch netlink, representor events "+
+				"are only detected every collection interval", zap.Error(err))
+			return
+		}
+		r.logger.Warn("Netlink notifications dropped, listing interfaces")
+		r.resync()
+	}
+}
+
+func (r *representorReceiver) collectLoop() {
+	defer r.stopWaiters.Done()
+
+	ticker := time.NewTicker(r.config.CollectionInterval)
+	defer ticker.Stop()
+
+	r.collect()
+	for {
+		select {
+		case <-ticker.C:
+			r.resync()
+			r.collect()
+		case <-r.stopChannel:
+			return
+		}
+	}
+}
+
+// resync lists the interfaces and emits lifecycle events for representors
+// created or deleted without a netlink notification.
+func (r *representorReceiver) resync() {
+	links, err := listLinks()
+	if err != nil {
+		r.logger.Error("Failed to list interfaces", zap.Error(err))
+		return
+	}
+	r.emitEvents(r.sync(links))
+}
+
+func listLinks() ([]linkEvent, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	links := make([]linkEvent, 0, len(ifaces))
+	for _, iface := range ifaces {
+		links = append(links, linkEvent{
+			index: iface.Index,
+			name:  iface.Name,
+			mac:   iface.HardwareAddr.String(),
+		})
+	}
+	return links, nil
+}
+
+// sync replaces the known representors with those in the list of links, and
+// returns the lifecycle events of the differences.
+func (r *representorReceiver) sync(links []linkEvent) []lifecycleEvent {
+	r.representorsLock.Lock()
+	defer r.representorsLock.Unlock()
+
+	now := pcommon.NewTimestampFromTime(time.Now())
+	current := make(map[int]representor)
+	for _, link := range links {
+		if r.reInterface.MatchString(link.name) {
+			current[link.index] = representor{name: link.name, mac: link.mac}
+		}
+	}
+
+	// representors are identified by index and name, a changed MAC
+	// address is not a lifecycle event
+	var events []lifecycleEvent
+	for index, rep := range r.representors {
+		if current[index].name != rep.name {
+			events = append(events, lifecycleEvent{eventDeleted, index, rep, now})
+		}
+	}
+	for index, rep := range current {
+		if r.representors[index].name != rep.name {
+			events = append(events, lifecycleEvent{eventCreated, index, rep, now})
+		}
+	}
+	r.representors = current
+	r.trackPFs()
+	return events
+}
+
+// update applies a netlink notification to the known representors, and
+// returns the resulting lifecycle events. A renamed interface is deleted
+// under its old name and created under its new name.
+func (r *representorReceiver) update(link linkEvent) []lifecycleEvent {
+	r.representorsLock.Lock()
+	defer r.representorsLock.Unlock()
+
+	now := pcommon.NewTimestampFromTime(time.Now())
+	var events []lifecycleEvent
+	known, exists := r.representors[link.index]
+	matches := !link.deleted && r.reInterface.MatchString(link.name)
+	if exists && (!matches || known.name != link.name) {
+		delete(r.representors, link.index)
+		events = append(events, lifecycleEvent{eventDeleted, link.index, known, now})
+		exists = false
+	}
+	if matches && !exists {
+		rep := representor{name: link.name, mac: link.mac}
+		r.representors[link.index] = rep
+		events = append(events, lifecycleEvent{eventCreated, link.index, rep, now})
+	} else if matches {
+		// e.g. a changed MAC address
+		r.representors[link.index] = representor{name: link.name, mac: link.mac}
+	}
+	r.trackPFs()
+	return events
+}
+
+func (r *representorReceiver) trackPFs() {
+	for _, rep := range r.representors {
+		r.pfs[physicalFunction(rep.name)] = true
+	}
+}
+
+// physicalFunction returns the physical function, e.g. "pf0", of a
+// representor, or an empty string if its name doesn't tell.
+func physicalFunction(name string) string {
+	return rePF.FindString(name)
+}
+
+func (r *representorReceiver) emitEvents(events []lifecycleEvent) {
+	if len(events) == 0 {
+		return
+	}
+	for _, e := range events {
+		r.logger.Info("Representor "+e.event,
+			zap.String("interface", e.name), zap.Int("index", e.index))
+	}
+	if r.logsConsumer == nil {
+		return
+	}
+
+	ld := plog.NewLogs()
+	sl := ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty()
+	sl.Scope().SetName(scopeName)
+	sl.Scope().SetVersion(Version)
+
+	// deletions first, so that a renamed interface reads naturally
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].event == eventDeleted && events[j].event != eventDeleted
+	})
+	for _, e := range events {
+		lr := sl.LogRecords().AppendEmpty()
+		lr.SetObservedTimestamp(e.time)
+		lr.SetTimestamp(e.time)
+		lr.SetSeverityNumber(plog.SeverityNumberInfo)
+		lr.SetSeverityText("INFO")
+		lr.Body().SetStr(fmt.Sprintf("representor %s %s", e.name, e.event))
+
+		attrs := lr.Attributes()
+		attrs.PutStr("representor.event", e.event)
+		attrs.PutStr("interface.name", e.name)
+		attrs.PutInt("interface.index", int64(e.index))
+		if e.mac != "" {
+			attrs.PutStr("interface.mac", e.mac)
+		}
+		if pf := physicalFunction(e.name); pf != "" {
+			attrs.PutStr("representor.pf", pf)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(),
+		r.config.CollectionInterval)
+	defer cancel()
+	if err := r.logsConsumer.ConsumeLogs(ctx, ld); err != nil {
+		r.logger.Error("Failed to consume representor events", zap.Error(err))
+	}
+}
+
+// collect emits the number of representors per physical function.
+func (r *representorReceiver) collect() {
+	if r.metricsConsumer == nil {
+		return
+	}
+
+	r.representorsLock.Lock()
+	counts := make(map[string]int64, len(r.pfs))
+	for pf := range r.pfs {
+		counts[pf] = 0
+	}
+	for _, rep := range r.representors {
+		counts[physicalFunction(rep.name)]++
+	}
+	r.representorsLock.Unlock()
+
+	md := pmetric.NewMetrics()
+	sm := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty()
+	sm.Scope().SetName(scopeName)
+	sm.Scope().SetVersion(Version)
+
+	count := sm.Metrics().AppendEmpty()
+	count.SetName("representor.count")
+	count.SetDescription("Representor interfaces on the DPU")
+	count.SetUnit("{interfaces}")
+	dps := count.SetEmptyGauge().DataPoints()
+
+	now := pcommon.NewTimestampFromTime(time.Now())
+	pfs := make([]string, 0, len(counts))
+	for pf := range counts {
+		pfs = append(pfs, pf)
+	}
+	sort.Strings(pfs)
+	for _, pf := range pfs {
+		dp := dps.AppendEmpty()
+		dp.SetTimestamp(now)
+		dp.SetIntValue(counts[pf])
+		if pf != "" {
+			dp.Attributes().PutStr("representor.pf", pf)
+		}
+	}
+	if dps.Len() == 0 {
+		// no representors seen yet
+		dp := dps.AppendEmpty()
+		dp.SetTimestamp(now)
+		dp.SetIntValue(0)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(),
+		r.config.CollectionInterval)
+	defer cancel()
+	if err := r.metricsConsumer.ConsumeMetrics(ctx, md); err != nil {
+		r.logger.Error("Failed to consume representor metrics", zap.Error(err))
+	}
+}
+
+func formatMAC(addr []byte) string {
+	return net.HardwareAddr(addr).String()
+}
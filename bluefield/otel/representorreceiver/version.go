@@ -0,0 +1,3 @@
+package representorreceiver
+
+const Version = "0.0.1"
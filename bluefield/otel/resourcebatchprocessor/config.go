@@ -0,0 +1,71 @@
+package resourcebatchprocessor
+
+import (
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+// Config defines the configuration of the resource batch processor.
+type Config struct {
+	// ResourceKeys are the resource attributes whose values identify the
+	// batch data is added to, e.g. "tenant.id". Resources lacking all of
+	// them are batched together.
+	ResourceKeys []string `mapstructure:"resource_keys"`
+
+	// SendBatchSize is the number of datapoints or log records at which a
+	// batch is sent. Metrics are not split, so batches of metrics may hold
+	// more datapoints. Defaults to 8192.
+	SendBatchSize int `mapstructure:"send_batch_size"`
+
+	// MaxSeriesPerBatch limits the number of distinct series, i.e.
+	// datapoints of a resource and metric with the same attributes, in a
+	// batch of metrics. Zero means unlimited. Defaults to 0.
+	MaxSeriesPerBatch int `mapstructure:"max_series_per_batch"`
+
+	// Timeout configures how often pending batches are sent regardless of
+	// their size. Defaults to "200ms".
+	Timeout time.Duration `mapstructure:"timeout"`
+
+	// MaxPendingKeys limits the number of batches pending at once. All of
+	// them are sent when data of another key arrives. Defaults to 1000.
+	MaxPendingKeys int `mapstructure:"max_pending_keys"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if len(cfg.ResourceKeys) == 0 {
+		return errors.New("resource_keys must not be empty")
+	}
+	for _, key := range cfg.ResourceKeys {
+		if key == "" {
+			return errors.New("resource_keys must not contain empty keys")
+		}
+	}
+	if cfg.SendBatchSize <= 0 {
+		return errors.New("send_batch_size must be positive")
+	}
+	if cfg.MaxSeriesPerBatch < 0 {
+		return errors.New("max_series_per_batch must not be negative")
+	}
+	if cfg.Timeout <= 0 {
+		return errors.New("timeout must be positive")
+	}
+	if cfg.MaxPendingKeys <= 0 {
+		return errors.New("max_pending_keys must be positive")
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		SendBatchSize:  8192,
+		Timeout:        200 * time.Millisecond,
+		MaxPendingKeys: 1000,
+	}
+}
@@ -0,0 +1,3 @@
+module resourcebatchprocessor
+
+go 1.22
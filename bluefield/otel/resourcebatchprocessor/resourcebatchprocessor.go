@@ -0,0 +1,344 @@
+package resourcebatchprocessor
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+	"go.uber.org/zap"
+
+	"otelcommon/attrhash"
+)
+
+type resourceBatchProcessor struct {
+	config      *Config
+	logger      *zap.Logger
+	nextMetrics consumer.Metrics
+	nextLogs    consumer.Logs
+	stopChannel chan struct{}
+	stopWaiters sync.WaitGroup
+
+	// batches waiting to be sent, by resource key
+	pendingLock sync.Mutex
+	pending     map[string]*batch
+}
+
+// batch holds either metrics or logs of a resource key. Resources and scopes
+// added several times are merged by their hashes.
+type batch struct {
+	key     string
+	metrics pmetric.Metrics
+	logs    plog.Logs
+	items   int                 // datapoints or log records
+	series  map[uint64]struct{} // series hashes of metrics
+
+	metricResources map[uint64]pmetric.ResourceMetrics
+	metricScopes    map[uint64]pmetric.MetricSlice
+	logResources    map[uint64]plog.ResourceLogs
+	logScopes       map[uint64]plog.LogRecordSlice
+}
+
+// processor constructor
+func newResourceBatchProcessor(config *Config, logger *zap.Logger) *resourceBatchProcessor {
+	return &resourceBatchProcessor{
+		config:      config,
+		logger:      logger,
+		stopChannel: make(chan struct{}),
+		pending:     make(map[string]*batch),
+	}
+}
+
+func (p *resourceBatchProcessor) start() {
+	p.stopWaiters.Add(1)
+	go p.flushLoop()
+}
+
+// processor destructor
+func (p *resourceBatchProcessor) cleanup(ctx context.Context) {
+	close(p.stopChannel)
+	p.stopWaiters.Wait()
+
+	// pending batches are sent at once rather than lost
+	p.send(ctx, p.takePending())
+}
+
+func (p *resourceBatchProcessor) flushLoop() {
+	defer p.stopWaiters.Done()
+
+	ticker := time.NewTicker(p.config.Timeout)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.send(context.Background(), p.takePending())
+		case <-p.stopChannel:
+			return
+		}
+	}
+}
+
+// takePending removes all pending batches, in the order of their keys.
+func (p *resourceBatchProcessor) takePending() []*batch {
+	p.pendingLock.Lock()
+	defer p.pendingLock.Unlock()
+	return p.removePending()
+}
+
+// removePending removes all pending batches, in the order of their keys. It
+// must be called while holding the pending lock.
+func (p *resourceBatchProcessor) removePending() []*batch {
+	batches := make([]*batch, 0, len(p.pending))
+	for _, b := range p.pending {
+		batches = append(batches, b)
+	}
+	slices.SortFunc(batches, func(a, b *batch) int {
+		return strings.Compare(a.key, b.key)
+	})
+	clear(p.pending)
+	return batches
+}
+
+// send passes on the batches. As they hold data of several incoming batches,
+// errors are logged rather than returned.
+func (p *resourceBatchProcessor) send(ctx context.Context, batches []*batch) {
+	for _, b := range batches {
+		var err error
+		if p.nextMetrics != nil {
+			err = p.nextMetrics.ConsumeMetrics(ctx, b.metrics)
+		} else {
+			err = p.nextLogs.ConsumeLogs(ctx, b.logs)
+		}
+		if err != nil {
+			p.logger.Error("Failed to send resource batch",
+				zap.String("key", b.key), zap.Int("items", b.items), zap.Error(err))
+		}
+	}
+}
+
+// open returns the pending batch of a key, creating it if needed. If as many
+// batches as allowed are pending, they are removed into full first. It must be
+// called while holding the pending lock.
+func (p *resourceBatchProcessor) open(key string, full *[]*batch) *batch {
+	if b, found := p.pending[key]; found {
+		return b
+	}
+	if len(p.pending) >= p.config.MaxPendingKeys {
+		*full = append(*full, p.removePending()...)
+	}
+	b := &batch{key: key}
+	if p.nextMetrics != nil {
+		b.metrics = pmetric.NewMetrics()
+		b.series = make(map[uint64]struct{})
+		b.metricResources = make(map[uint64]pmetric.ResourceMetrics)
+		b.metricScopes = make(map[uint64]pmetric.MetricSlice)
+	} else {
+		b.logs = plog.NewLogs()
+		b.logResources = make(map[uint64]plog.ResourceLogs)
+		b.logScopes = make(map[uint64]plog.LogRecordSlice)
+	}
+	p.pending[key] = b
+	return b
+}
+
+// resourceKey returns the key of the batch of a resource, the values of the
+// configured attributes.
+func (p *resourceBatchProcessor) resourceKey(attrs pcommon.Map) string {
+	values := make([]string, len(p.config.ResourceKeys))
+	for i, key := range p.config.ResourceKeys {
+		if value, found := attrs.Get(key); found {
+			values[i] = value.AsString()
+		}
+	}
+	return strings.Join(values, "\x00")
+}
+
+func (p *resourceBatchProcessor) processMetrics(
+	ctx context.Context,
+	md pmetric.Metrics,
+) (pmetric.Metrics, error) {
+	maxSeries := p.config.MaxSeriesPerBatch
+	var full []*batch
+
+	p.pendingLock.Lock()
+	for i := 0; i < md.ResourceMetrics().Len(); i++ {
+		rm := md.ResourceMetrics().At(i)
+		key := p.resourceKey(rm.Resource().Attributes())
+		resourceHash := attrhash.String(attrhash.Attributes(0, rm.Resource().Attributes()), rm.SchemaUrl())
+		for j := 0; j < rm.ScopeMetrics().Len(); j++ {
+			sm := rm.ScopeMetrics().At(j)
+			scopeHash := hashScope(resourceHash, sm.Scope(), sm.SchemaUrl())
+			for k := 0; k < sm.Metrics().Len(); k++ {
+				metric := sm.Metrics().At(k)
+				series := metricSeries(resourceHash, metric)
+
+				// a batch that would exceed the series limit is sent
+				// first, unless the metric exceeds it on its own
+				b := p.open(key, &full)
+				if maxSeries > 0 && len(b.series) > 0 &&
+					len(b.series)+b.newSeries(series) > maxSeries {
+					full = append(full, b)
+					delete(p.pending, key)
+					b = p.open(key, &full)
+				}
+				b.addMetric(rm, sm, resourceHash, scopeHash, metric, series)
+				if b.items >= p.config.SendBatchSize ||
+					(maxSeries > 0 && len(b.series) >= maxSeries) {
+					full = append(full, b)
+					delete(p.pending, key)
+				}
+			}
+		}
+	}
+	p.pendingLock.Unlock()
+
+	p.send(ctx, full)
+	return md, processorhelper.ErrSkipProcessingData
+}
+
+func (p *resourceBatchProcessor) processLogs(
+	ctx context.Context,
+	ld plog.Logs,
+) (plog.Logs, error) {
+	var full []*batch
+
+	p.pendingLock.Lock()
+	for i := 0; i < ld.ResourceLogs().Len(); i++ {
+		rl := ld.ResourceLogs().At(i)
+		key := p.resourceKey(rl.Resource().Attributes())
+		resourceHash := attrhash.String(attrhash.Attributes(0, rl.Resource().Attributes()), rl.SchemaUrl())
+		for j := 0; j < rl.ScopeLogs().Len(); j++ {
+			sl := rl.ScopeLogs().At(j)
+			scopeHash := hashScope(resourceHash, sl.Scope(), sl.SchemaUrl())
+			for k := 0; k < sl.LogRecords().Len(); k++ {
+				b := p.open(key, &full)
+				b.addLogRecord(rl, sl, resourceHash, scopeHash, sl.LogRecords().At(k))
+				if b.items >= p.config.SendBatchSize {
+					full = append(full, b)
+					delete(p.pending, key)
+				}
+			}
+		}
+	}
+	p.pendingLock.Unlock()
+
+	p.send(ctx, full)
+	return ld, processorhelper.ErrSkipProcessingData
+}
+
+// newSeries returns the number of series not yet in the batch.
+func (b *batch) newSeries(series []uint64) int {
+	n := 0
+	for _, s := range series {
+		if _, found := b.series[s]; !found {
+			n++
+		}
+	}
+	return n
+}
+
+// addMetric copies a metric into the batch, below a copy of its resource and
+// scope.
+func (b *batch) addMetric(
+	rm pmetric.ResourceMetrics,
+	sm pmetric.ScopeMetrics,
+	resourceHash, scopeHash uint64,
+	metric pmetric.Metric,
+	series []uint64,
+) {
+	dest, found := b.metricScopes[scopeHash]
+	if !found {
+		destRM, found := b.metricResources[resourceHash]
+		if !found {
+			destRM = b.metrics.ResourceMetrics().AppendEmpty()
+			rm.Resource().CopyTo(destRM.Resource())
+			destRM.SetSchemaUrl(rm.SchemaUrl())
+			b.metricResources[resourceHash] = destRM
+		}
+		destSM := destRM.ScopeMetrics().AppendEmpty()
+		sm.Scope().CopyTo(destSM.Scope())
+		destSM.SetSchemaUrl(sm.SchemaUrl())
+		dest = destSM.Metrics()
+		b.metricScopes[scopeHash] = dest
+	}
+	metric.CopyTo(dest.AppendEmpty())
+	b.items += len(series)
+	for _, s := range series {
+		b.series[s] = struct{}{}
+	}
+}
+
+// addLogRecord copies a log record into the batch, below a copy of its
+// resource and scope.
+func (b *batch) addLogRecord(
+	rl plog.ResourceLogs,
+	sl plog.ScopeLogs,
+	resourceHash, scopeHash uint64,
+	lr plog.LogRecord,
+) {
+	dest, found := b.logScopes[scopeHash]
+	if !found {
+		destRL, found := b.logResources[resourceHash]
+		if !found {
+			destRL = b.logs.ResourceLogs().AppendEmpty()
+			rl.Resource().CopyTo(destRL.Resource())
+			destRL.SetSchemaUrl(rl.SchemaUrl())
+			b.logResources[resourceHash] = destRL
+		}
+		destSL := destRL.ScopeLogs().AppendEmpty()
+		sl.Scope().CopyTo(destSL.Scope())
+		destSL.SetSchemaUrl(sl.SchemaUrl())
+		dest = destSL.LogRecords()
+		b.logScopes[scopeHash] = dest
+	}
+	lr.CopyTo(dest.AppendEmpty())
+	b.items++
+}
+
+// metricSeries returns the series hashes of the datapoints of a metric, one
+// per datapoint.
+func metricSeries(resourceHash uint64, metric pmetric.Metric) []uint64 {
+	hash := attrhash.String(resourceHash, metric.Name())
+	var series []uint64
+	add := func(attrs pcommon.Map) {
+		series = append(series, attrhash.Attributes(hash, attrs))
+	}
+	switch metric.Type() {
+	case pmetric.MetricTypeGauge:
+		for i := 0; i < metric.Gauge().DataPoints().Len(); i++ {
+			add(metric.Gauge().DataPoints().At(i).Attributes())
+		}
+	case pmetric.MetricTypeSum:
+		for i := 0; i < metric.Sum().DataPoints().Len(); i++ {
+			add(metric.Sum().DataPoints().At(i).Attributes())
+		}
+	case pmetric.MetricTypeHistogram:
+		for i := 0; i < metric.Histogram().DataPoints().Len(); i++ {
+			add(metric.Histogram().DataPoints().At(i).Attributes())
+		}
+	case pmetric.MetricTypeExponentialHistogram:
+		for i := 0; i < metric.ExponentialHistogram().DataPoints().Len(); i++ {
+			add(metric.ExponentialHistogram().DataPoints().At(i).Attributes())
+		}
+	case pmetric.MetricTypeSummary:
+		for i := 0; i < metric.Summary().DataPoints().Len(); i++ {
+			add(metric.Summary().DataPoints().At(i).Attributes())
+		}
+	}
+	return series
+}
+
+// hashScope adds a scope and its schema URL to a hash.
+func hashScope(hash uint64, scope pcommon.InstrumentationScope, schemaURL string) uint64 {
+	hash = attrhash.String(hash, scope.Name())
+	hash = attrhash.String(hash, scope.Version())
+	hash = attrhash.String(hash, schemaURL)
+	return attrhash.Attributes(hash, scope.Attributes())
+}
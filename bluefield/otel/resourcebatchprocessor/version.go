@@ -0,0 +1,3 @@
+package resourcebatchprocessor
+
+const Version = "0.0.1"
@@ -0,0 +1,53 @@
+package resourceprojectionprocessor
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+// Config defines the configuration of the resource_projection processor.
+type Config struct {
+	// Keep is the allow-list of resource attributes kept on resources.
+	// All other resource attributes are dropped.
+	Keep []string `mapstructure:"keep"`
+
+	// MoveToRecords optionally lists resource attributes that are moved
+	// to the attributes of each log record, datapoint or span of the
+	// resource instead of being dropped. Records that already have the
+	// attribute keep their own value.
+	MoveToRecords []string `mapstructure:"move_to_records"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if len(cfg.Keep) == 0 && len(cfg.MoveToRecords) == 0 {
+		return errors.New("at least one attribute must be kept or moved " +
+			"to records")
+	}
+	for _, key := range cfg.Keep {
+		if key == "" {
+			return errors.New("kept attribute cannot be empty")
+		}
+	}
+	for _, key := range cfg.MoveToRecords {
+		if key == "" {
+			return errors.New("moved attribute cannot be empty")
+		}
+		if slices.Contains(cfg.Keep, key) {
+			return fmt.Errorf("attribute %s cannot be both kept and "+
+				"moved to records", key)
+		}
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{}
+}
@@ -0,0 +1,3 @@
+module resourceprojectionprocessor
+
+go 1.22
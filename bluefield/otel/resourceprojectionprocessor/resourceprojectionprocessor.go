@@ -0,0 +1,111 @@
+package resourceprojectionprocessor
+
+import (
+	"context"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+
+	"otelcommon/pdataiter"
+)
+
+type resourceProjectionProcessor struct {
+	logger *zap.Logger
+	config *Config
+	keep   map[string]bool
+}
+
+// processor constructor
+func newResourceProjectionProcessor(config *Config, logger *zap.Logger) *resourceProjectionProcessor {
+	keep := make(map[string]bool, len(config.Keep))
+	for _, key := range config.Keep {
+		keep[key] = true
+	}
+	return &resourceProjectionProcessor{
+		logger: logger,
+		config: config,
+		keep:   keep,
+	}
+}
+
+func (p *resourceProjectionProcessor) processTraces(
+	ctx context.Context,
+	td ptrace.Traces,
+) (ptrace.Traces, error) {
+	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		rs := td.ResourceSpans().At(i)
+		moved := p.project(rs.Resource())
+		if moved.Len() == 0 {
+			continue
+		}
+		for j := 0; j < rs.ScopeSpans().Len(); j++ {
+			spans := rs.ScopeSpans().At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				putMoved(spans.At(k).Attributes(), moved)
+			}
+		}
+	}
+	return td, nil
+}
+
+func (p *resourceProjectionProcessor) processMetrics(
+	ctx context.Context,
+	md pmetric.Metrics,
+) (pmetric.Metrics, error) {
+	moved := make([]pcommon.Map, md.ResourceMetrics().Len())
+	for i := range moved {
+		moved[i] = p.project(md.ResourceMetrics().At(i).Resource())
+	}
+	if len(p.config.MoveToRecords) > 0 {
+		pdataiter.Datapoints(md, func(dp *pdataiter.Datapoint) {
+			putMoved(dp.Attributes, moved[dp.ResourceIndex])
+		})
+	}
+	return md, nil
+}
+
+func (p *resourceProjectionProcessor) processLogs(
+	ctx context.Context,
+	ld plog.Logs,
+) (plog.Logs, error) {
+	moved := make([]pcommon.Map, ld.ResourceLogs().Len())
+	for i := range moved {
+		moved[i] = p.project(ld.ResourceLogs().At(i).Resource())
+	}
+	if len(p.config.MoveToRecords) > 0 {
+		pdataiter.LogRecords(ld, func(lr *pdataiter.LogRecord) {
+			putMoved(lr.Record.Attributes(), moved[lr.ResourceIndex])
+		})
+	}
+	return ld, nil
+}
+
+// project drops the attributes of a resource that aren't kept, and returns
+// those among them to move to its records.
+func (p *resourceProjectionProcessor) project(resource pcommon.Resource) pcommon.Map {
+	moved := pcommon.NewMap()
+	attrs := resource.Attributes()
+	for _, key := range p.config.MoveToRecords {
+		if value, exists := attrs.Get(key); exists {
+			value.CopyTo(moved.PutEmpty(key))
+		}
+	}
+	attrs.RemoveIf(func(key string, _ pcommon.Value) bool {
+		return !p.keep[key]
+	})
+	return moved
+}
+
+// putMoved adds the moved resource attributes to the attributes of a record,
+// unless the record has its own value.
+func putMoved(attrs pcommon.Map, moved pcommon.Map) {
+	moved.Range(func(key string, value pcommon.Value) bool {
+		if _, exists := attrs.Get(key); !exists {
+			value.CopyTo(attrs.PutEmpty(key))
+		}
+		return true
+	})
+}
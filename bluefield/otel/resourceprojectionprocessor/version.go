@@ -0,0 +1,3 @@
+package resourceprojectionprocessor
+
+const Version = "0.0.1"
@@ -0,0 +1,99 @@
+package ringstoreexporter
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+
+	"otelcommon/queuestats"
+)
+
+// Config defines the configuration of the ring_store exporter.
+type Config struct {
+	// Path is the SQLite database file the data is stored in. Defaults to
+	// "/var/lib/otelcol-contrib/ringstore.db".
+	Path string `mapstructure:"path"`
+
+	// Retention configures how long data is kept. Defaults to "6h".
+	Retention time.Duration `mapstructure:"retention"`
+
+	// MaxSizeMiB limits the size of the database. If it is exceeded, the
+	// oldest data is pruned regardless of its age. Defaults to 64.
+	MaxSizeMiB int64 `mapstructure:"max_size_mib"`
+
+	// PruneInterval configures how often expired data is pruned.
+	// Defaults to "1m".
+	PruneInterval time.Duration `mapstructure:"prune_interval"`
+
+	// MetricNames optionally limits the stored metrics to the listed
+	// names. If neither MetricNames nor MetricRegex is specified, all
+	// metrics are stored.
+	MetricNames []string `mapstructure:"metric_names"`
+
+	// MetricRegex optionally limits the stored metrics to names matching
+	// the regular expression, in addition to the names in MetricNames.
+	MetricRegex string `mapstructure:"metric_regex"`
+
+	// Endpoint is the local address serving the query API. It may be
+	// shared with other components such as the drain extension.
+	// Defaults to "localhost:8890". Leave empty to disable the query API.
+	Endpoint string `mapstructure:"endpoint"`
+
+	// APIPath is the URL path prefix of the query API. Defaults to
+	// "/history/".
+	APIPath string `mapstructure:"api_path"`
+
+	// QueueStatsEndpoint serves the statistics of the writes at
+	// /metrics/exporters, like the sending queues of other exporters.
+	// Empty disables them. Defaults to "localhost:8890".
+	QueueStatsEndpoint string `mapstructure:"queue_stats_endpoint"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.Path == "" {
+		return errors.New("path cannot be empty")
+	}
+	if cfg.Retention <= 0 {
+		return errors.New("retention must be positive")
+	}
+	if cfg.MaxSizeMiB <= 0 {
+		return errors.New("max_size_mib must be positive")
+	}
+	if cfg.PruneInterval <= 0 {
+		return errors.New("prune_interval must be positive")
+	}
+	if cfg.MetricRegex != "" {
+		if _, err := regexp.Compile(cfg.MetricRegex); err != nil {
+			return fmt.Errorf("invalid metric_regex: %w", err)
+		}
+	}
+	if cfg.Endpoint != "" {
+		if !strings.HasPrefix(cfg.APIPath, "/") ||
+			!strings.HasSuffix(cfg.APIPath, "/") {
+			return errors.New("api_path must start and end with /")
+		}
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		Path:          "/var/lib/otelcol-contrib/ringstore.db",
+		Retention:     6 * time.Hour,
+		MaxSizeMiB:    64,
+		PruneInterval: time.Minute,
+		Endpoint:      "localhost:8890",
+		APIPath:       "/history/",
+
+		QueueStatsEndpoint: queuestats.DefaultEndpoint,
+	}
+}
@@ -0,0 +1,124 @@
+package ringstoreexporter
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/exporter"
+	"go.opentelemetry.io/collector/exporter/exporterhelper"
+
+	"otelcommon/queuestats"
+)
+
+const (
+	typeStr   = "ring_store"
+	stability = component.StabilityLevelAlpha
+)
+
+var (
+	// an exporter configured in both metrics and logs pipelines stores
+	// both in the same database
+	exportersLock sync.Mutex
+	exporters     = make(map[*Config]*ringStoreExporter)
+)
+
+func NewFactory() exporter.Factory {
+	return exporter.NewFactory(
+		component.MustNewType(typeStr),
+		createDefaultConfig,
+		exporter.WithMetrics(createMetricsExporter, stability),
+		exporter.WithLogs(createLogsExporter, stability),
+	)
+}
+
+func createMetricsExporter(
+	ctx context.Context,
+	set exporter.CreateSettings,
+	cfg component.Config,
+) (exporter.Metrics, error) {
+	e := getExporter(cfg.(*Config), set)
+	stats, err := registerQueueStats(cfg.(*Config), set, "metrics")
+	if err != nil {
+		return nil, err
+	}
+	exp, err := exporterhelper.NewMetricsExporter(
+		ctx,
+		set,
+		cfg,
+		stats.PushMetrics(e.consumeMetrics),
+		exporterhelper.WithStart(e.start),
+		exporterhelper.WithShutdown(func(ctx context.Context) error {
+			stats.Unregister()
+			return e.shutdown(ctx)
+		}),
+	)
+	if err != nil {
+		stats.Unregister()
+		return nil, err
+	}
+	return exp, nil
+}
+
+func createLogsExporter(
+	ctx context.Context,
+	set exporter.CreateSettings,
+	cfg component.Config,
+) (exporter.Logs, error) {
+	e := getExporter(cfg.(*Config), set)
+	stats, err := registerQueueStats(cfg.(*Config), set, "logs")
+	if err != nil {
+		return nil, err
+	}
+	exp, err := exporterhelper.NewLogsExporter(
+		ctx,
+		set,
+		cfg,
+		stats.PushLogs(e.consumeLogs),
+		exporterhelper.WithStart(e.start),
+		exporterhelper.WithShutdown(func(ctx context.Context) error {
+			stats.Unregister()
+			return e.shutdown(ctx)
+		}),
+	)
+	if err != nil {
+		stats.Unregister()
+		return nil, err
+	}
+	return exp, nil
+}
+
+// registerQueueStats reports the writes of a signal. The database is written
+// synchronously, without a queue or retries, so only attempts, stored items
+// and errors are reported.
+func registerQueueStats(
+	config *Config,
+	set exporter.CreateSettings,
+	signal string,
+) (*queuestats.Stats, error) {
+	return queuestats.Register(queuestats.Settings{
+		Endpoint:  config.QueueStatsEndpoint,
+		Exporter:  set.ID.String(),
+		Signal:    signal,
+		Untracked: true,
+	}, set.Logger)
+}
+
+func getExporter(config *Config, set exporter.CreateSettings) *ringStoreExporter {
+	exportersLock.Lock()
+	defer exportersLock.Unlock()
+
+	e, exists := exporters[config]
+	if !exists {
+		e = newRingStoreExporter(config, set.Logger)
+		exporters[config] = e
+	}
+	return e
+}
+
+func removeExporter(config *Config) {
+	exportersLock.Lock()
+	defer exportersLock.Unlock()
+
+	delete(exporters, config)
+}
@@ -0,0 +1,3 @@
+module ringstoreexporter
+
+go 1.22
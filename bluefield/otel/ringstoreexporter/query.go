@@ -0,0 +1,301 @@
+package ringstoreexporter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultQueryLimit = 1000
+	maxQueryLimit     = 10000
+)
+
+// queryHandler serves the read API under the configured path:
+//
+//	GET <path>metrics        datapoints, filtered by name, name_prefix,
+//	                         attributes and time
+//	GET <path>metrics/names  names of the stored metrics with their counts
+//	GET <path>logs           log records, filtered by severity, body,
+//	                         attributes and time
+type queryHandler struct {
+	exporter *ringStoreExporter
+}
+
+type metricRow struct {
+	Timestamp  string          `json:"timestamp"`
+	Name       string          `json:"name"`
+	Value      float64         `json:"value"`
+	Count      *int64          `json:"count,omitempty"`
+	Attributes json.RawMessage `json:"attributes"`
+	Resource   json.RawMessage `json:"resource"`
+}
+
+type metricName struct {
+	Name   string `json:"name"`
+	Count  int64  `json:"count"`
+	Oldest string `json:"oldest"`
+	Newest string `json:"newest"`
+}
+
+type logRow struct {
+	Timestamp      string          `json:"timestamp"`
+	SeverityNumber int64           `json:"severity_number"`
+	SeverityText   string          `json:"severity_text,omitempty"`
+	Body           string          `json:"body"`
+	Attributes     json.RawMessage `json:"attributes"`
+	Resource       json.RawMessage `json:"resource"`
+}
+
+// query is a SQL query built from the request parameters common to metrics
+// and logs.
+type query struct {
+	conditions []string
+	args       []any
+	limit      int
+}
+
+func (h *queryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var result any
+	var err error
+	switch strings.TrimPrefix(r.URL.Path, h.exporter.config.APIPath) {
+	case "metrics":
+		result, err = h.queryMetrics(r)
+	case "metrics/names":
+		result, err = h.queryMetricNames(r)
+	case "logs":
+		result, err = h.queryLogs(r)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	var badRequest *badRequestError
+	if errors.As(err, &badRequest) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.exporter.logger.Error("Failed to query database", zap.Error(err))
+		http.Error(w, "query failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		h.exporter.logger.Error("Failed to write query result", zap.Error(err))
+	}
+}
+
+func (h *queryHandler) queryMetrics(r *http.Request) (any, error) {
+	q, err := parseQuery(r)
+	if err != nil {
+		return nil, err
+	}
+	params := r.URL.Query()
+	if name := params.Get("name"); name != "" {
+		q.add("name = ?", name)
+	}
+	if prefix := params.Get("name_prefix"); prefix != "" {
+		q.add("substr(name, 1, ?) = ?", len(prefix), prefix)
+	}
+
+	rows, err := h.exporter.db.QueryContext(r.Context(),
+		"SELECT ts, name, value, count, attributes, resource FROM metrics"+
+			q.where()+" ORDER BY ts DESC LIMIT ?", q.limitArgs()...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]metricRow, 0)
+	for rows.Next() {
+		var row metricRow
+		var ts int64
+		var attributes, resource string
+		if err := rows.Scan(&ts, &row.Name, &row.Value, &row.Count,
+			&attributes, &resource); err != nil {
+			return nil, err
+		}
+		row.Timestamp = formatTimestamp(ts)
+		row.Attributes = json.RawMessage(attributes)
+		row.Resource = json.RawMessage(resource)
+		result = append(result, row)
+	}
+	return map[string]any{"metrics": result}, rows.Err()
+}
+
+func (h *queryHandler) queryMetricNames(r *http.Request) (any, error) {
+	q, err := parseQuery(r)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := h.exporter.db.QueryContext(r.Context(),
+		"SELECT name, COUNT(*), MIN(ts), MAX(ts) FROM metrics"+q.where()+
+			" GROUP BY name ORDER BY name LIMIT ?", q.limitArgs()...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]metricName, 0)
+	for rows.Next() {
+		var name metricName
+		var oldest, newest int64
+		if err := rows.Scan(&name.Name, &name.Count, &oldest,
+			&newest); err != nil {
+			return nil, err
+		}
+		name.Oldest = formatTimestamp(oldest)
+		name.Newest = formatTimestamp(newest)
+		result = append(result, name)
+	}
+	return map[string]any{"names": result}, rows.Err()
+}
+
+func (h *queryHandler) queryLogs(r *http.Request) (any, error) {
+	q, err := parseQuery(r)
+	if err != nil {
+		return nil, err
+	}
+	params := r.URL.Query()
+	if severity := params.Get("min_severity"); severity != "" {
+		number, err := strconv.Atoi(severity)
+		if err != nil || number < 1 || number > 24 {
+			return nil, &badRequestError{"min_severity must be a severity " +
+				"number between 1 and 24"}
+		}
+		q.add("severity_number >= ?", number)
+	}
+	if contains := params.Get("contains"); contains != "" {
+		q.add("instr(body, ?) > 0", contains)
+	}
+
+	rows, err := h.exporter.db.QueryContext(r.Context(),
+		"SELECT ts, severity_number, severity_text, body, attributes, "+
+			"resource FROM logs"+q.where()+" ORDER BY ts DESC LIMIT ?",
+		q.limitArgs()...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]logRow, 0)
+	for rows.Next() {
+		var row logRow
+		var ts int64
+		var attributes, resource string
+		if err := rows.Scan(&ts, &row.SeverityNumber, &row.SeverityText,
+			&row.Body, &attributes, &resource); err != nil {
+			return nil, err
+		}
+		row.Timestamp = formatTimestamp(ts)
+		row.Attributes = json.RawMessage(attributes)
+		row.Resource = json.RawMessage(resource)
+		result = append(result, row)
+	}
+	return map[string]any{"logs": result}, rows.Err()
+}
+
+// parseQuery parses the time range, limit and attribute filters common to
+// all queries. "since" and "until" are RFC 3339 times or durations before
+// now such as "30m", "attr.<key>=<value>" filters on an attribute and
+// "resource.<key>=<value>" on a resource attribute.
+func parseQuery(r *http.Request) (*query, error) {
+	params := r.URL.Query()
+	q := &query{limit: defaultQueryLimit}
+
+	if since := params.Get("since"); since != "" {
+		ts, err := parseTime(since)
+		if err != nil {
+			return nil, &badRequestError{"invalid since: " + err.Error()}
+		}
+		q.add("ts >= ?", ts.UnixNano())
+	}
+	if until := params.Get("until"); until != "" {
+		ts, err := parseTime(until)
+		if err != nil {
+			return nil, &badRequestError{"invalid until: " + err.Error()}
+		}
+		q.add("ts <= ?", ts.UnixNano())
+	}
+	if limit := params.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 || n > maxQueryLimit {
+			return nil, &badRequestError{fmt.Sprintf("limit must be between "+
+				"1 and %d", maxQueryLimit)}
+		}
+		q.limit = n
+	}
+
+	for param, values := range params {
+		column, key, found := strings.Cut(param, ".")
+		if !found || (column != "attr" && column != "resource") || key == "" {
+			continue
+		}
+		if column == "attr" {
+			column = "attributes"
+		}
+		for _, value := range values {
+			// attribute values are compared as text, like the values
+			// of the other filters in the collector
+			q.add("CAST(json_extract("+column+", ?) AS TEXT) = ?",
+				jsonPath(key), value)
+		}
+	}
+	return q, nil
+}
+
+func (q *query) add(condition string, args ...any) {
+	q.conditions = append(q.conditions, condition)
+	q.args = append(q.args, args...)
+}
+
+func (q *query) where() string {
+	if len(q.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(q.conditions, " AND ")
+}
+
+func (q *query) limitArgs() []any {
+	return append(q.args, q.limit)
+}
+
+// jsonPath returns the SQLite JSON path of an attribute, quoting the key since
+// attribute names commonly contain dots.
+func jsonPath(key string) string {
+	return `$."` + strings.ReplaceAll(key, `"`, `\"`) + `"`
+}
+
+func parseTime(value string) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func formatTimestamp(ts int64) string {
+	return time.Unix(0, ts).UTC().Format(time.RFC3339Nano)
+}
+
+type badRequestError struct {
+	message string
+}
+
+func (e *badRequestError) Error() string {
+	return e.message
+}
@@ -0,0 +1,413 @@
+package ringstoreexporter
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+
+	// registers the pure Go "sqlite" driver, as the collector is built
+	// without cgo
+	_ "modernc.org/sqlite"
+
+	"otelcommon/httpregistry"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS metrics (
+	ts INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	value REAL NOT NULL,
+	count INTEGER,
+	attributes TEXT NOT NULL,
+	resource TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS metrics_ts ON metrics (ts);
+CREATE INDEX IF NOT EXISTS metrics_name_ts ON metrics (name, ts);
+CREATE TABLE IF NOT EXISTS logs (
+	ts INTEGER NOT NULL,
+	severity_number INTEGER NOT NULL,
+	severity_text TEXT NOT NULL,
+	body TEXT NOT NULL,
+	attributes TEXT NOT NULL,
+	resource TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS logs_ts ON logs (ts);
+`
+
+// tables lists the tables pruned by age and size
+var tables = []string{"metrics", "logs"}
+
+type ringStoreExporter struct {
+	config       *Config
+	logger       *zap.Logger
+	reMetric     *regexp.Regexp
+	db           *sql.DB
+	registration *httpregistry.Registration
+	startOnce    sync.Once
+	stopOnce     sync.Once
+	stopChannel  chan struct{}
+	stopWaiters  sync.WaitGroup
+}
+
+func newRingStoreExporter(config *Config, logger *zap.Logger) *ringStoreExporter {
+	e := &ringStoreExporter{
+		config:      config,
+		logger:      logger,
+		stopChannel: make(chan struct{}),
+	}
+	if config.MetricRegex != "" {
+		e.reMetric = regexp.MustCompile(config.MetricRegex)
+	}
+	return e
+}
+
+// start opens the database and starts pruning and the query API once, even
+// if the exporter is configured in both metrics and logs pipelines.
+func (e *ringStoreExporter) start(_ context.Context, _ component.Host) error {
+	var err error
+	e.startOnce.Do(func() {
+		err = e.open()
+		if err != nil {
+			return
+		}
+
+		if e.config.Endpoint != "" {
+			e.registration, err = httpregistry.Register(
+				httpregistry.ServerConfig{Endpoint: e.config.Endpoint},
+				e.config.APIPath,
+				&queryHandler{exporter: e},
+				e.logger,
+			)
+			if err != nil {
+				err = fmt.Errorf("failed to register query endpoint: %w", err)
+				return
+			}
+		}
+
+		e.stopWaiters.Add(1)
+		go e.pruneLoop()
+	})
+	return err
+}
+
+func (e *ringStoreExporter) shutdown(context.Context) error {
+	var err error
+	e.stopOnce.Do(func() {
+		if e.registration != nil {
+			e.registration.Unregister()
+		}
+		close(e.stopChannel)
+		e.stopWaiters.Wait()
+		if e.db != nil {
+			err = e.db.Close()
+		}
+		removeExporter(e.config)
+	})
+	return err
+}
+
+func (e *ringStoreExporter) open() error {
+	if err := os.MkdirAll(filepath.Dir(e.config.Path), 0755); err != nil {
+		return fmt.Errorf("failed to create database directory: %w", err)
+	}
+
+	// auto_vacuum must be set before the tables are created, so that
+	// pruned pages can later be returned to the filesystem
+	pragmas := url.Values{}
+	pragmas.Add("_pragma", "auto_vacuum(incremental)")
+	pragmas.Add("_pragma", "journal_mode(wal)")
+	pragmas.Add("_pragma", "synchronous(normal)")
+	pragmas.Add("_pragma", "busy_timeout(5000)")
+	db, err := sql.Open("sqlite", "file:"+e.config.Path+"?"+pragmas.Encode())
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return fmt.Errorf("failed to create database schema: %w", err)
+	}
+	e.db = db
+	return nil
+}
+
+func (e *ringStoreExporter) pruneLoop() {
+	defer e.stopWaiters.Done()
+
+	ticker := time.NewTicker(e.config.PruneInterval)
+	defer ticker.Stop()
+
+	for {
+		e.prune()
+
+		select {
+		case <-ticker.C:
+		case <-e.stopChannel:
+			return
+		}
+	}
+}
+
+// prune deletes data older than the retention, then the oldest tenth of each
+// table until the database fits within its size limit.
+func (e *ringStoreExporter) prune() {
+	cutoff := time.Now().Add(-e.config.Retention).UnixNano()
+	for _, table := range tables {
+		result, err := e.db.Exec("DELETE FROM "+table+" WHERE ts < ?", cutoff)
+		if err != nil {
+			e.logger.Error("Failed to prune expired data",
+				zap.String("table", table), zap.Error(err))
+			return
+		}
+		if deleted, _ := result.RowsAffected(); deleted > 0 {
+			e.logger.Debug("Pruned expired data",
+				zap.String("table", table), zap.Int64("rows", deleted))
+		}
+	}
+
+	maxSize := e.config.MaxSizeMiB * 1024 * 1024
+	for i := 0; i < 10; i++ {
+		size, err := e.usedSize()
+		if err != nil {
+			e.logger.Error("Failed to get database size", zap.Error(err))
+			return
+		}
+		if size <= maxSize {
+			break
+		}
+
+		e.logger.Warn("Database exceeds its size limit, pruning oldest data",
+			zap.Int64("size", size), zap.Int64("max_size", maxSize))
+		for _, table := range tables {
+			_, err := e.db.Exec("DELETE FROM " + table + " WHERE rowid IN " +
+				"(SELECT rowid FROM " + table + " ORDER BY ts LIMIT " +
+				"(SELECT COUNT(*) / 10 + 1 FROM " + table + "))")
+			if err != nil {
+				e.logger.Error("Failed to prune oldest data",
+					zap.String("table", table), zap.Error(err))
+				return
+			}
+		}
+	}
+
+	if _, err := e.db.Exec("PRAGMA incremental_vacuum"); err != nil {
+		e.logger.Error("Failed to vacuum database", zap.Error(err))
+	}
+}
+
+// usedSize returns the size of the pages of the database holding data.
+func (e *ringStoreExporter) usedSize() (int64, error) {
+	var pageCount, freelistCount, pageSize int64
+	if err := e.db.QueryRow("PRAGMA page_count").Scan(&pageCount); err != nil {
+		return 0, err
+	}
+	if err := e.db.QueryRow("PRAGMA freelist_count").Scan(&freelistCount); err != nil {
+		return 0, err
+	}
+	if err := e.db.QueryRow("PRAGMA page_size").Scan(&pageSize); err != nil {
+		return 0, err
+	}
+	return (pageCount - freelistCount) * pageSize, nil
+}
+
+func (e *ringStoreExporter) storeMetric(name string) bool {
+	if len(e.config.MetricNames) == 0 && e.reMetric == nil {
+		return true
+	}
+	return slices.Contains(e.config.MetricNames, name) ||
+		(e.reMetric != nil && e.reMetric.MatchString(name))
+}
+
+func (e *ringStoreExporter) consumeMetrics(ctx context.Context, md pmetric.Metrics) error {
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO metrics "+
+		"(ts, name, value, count, attributes, resource) "+
+		"VALUES (?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert: %w", err)
+	}
+	defer stmt.Close()
+
+	now := time.Now().UnixNano()
+	insert := func(name string, ts pcommon.Timestamp, value float64,
+		count *uint64, attrs pcommon.Map, resource string) error {
+		tsNanos := int64(ts)
+		if tsNanos == 0 {
+			tsNanos = now
+		}
+		var countValue any
+		if count != nil {
+			countValue = int64(*count)
+		}
+		_, err := stmt.ExecContext(ctx, tsNanos, name, value, countValue,
+			marshalAttributes(attrs), resource)
+		return err
+	}
+
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		rm := rms.At(i)
+		resource := marshalAttributes(rm.Resource().Attributes())
+		sms := rm.ScopeMetrics()
+		for j := 0; j < sms.Len(); j++ {
+			metrics := sms.At(j).Metrics()
+			for k := 0; k < metrics.Len(); k++ {
+				metric := metrics.At(k)
+				if !e.storeMetric(metric.Name()) {
+					continue
+				}
+				if err := insertMetric(metric, resource, insert); err != nil {
+					return fmt.Errorf("failed to insert metric %s: %w",
+						metric.Name(), err)
+				}
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// insertMetric stores the value of each number datapoint, and the sum and
+// count of each histogram and summary datapoint.
+func insertMetric(
+	metric pmetric.Metric,
+	resource string,
+	insert func(string, pcommon.Timestamp, float64, *uint64, pcommon.Map, string) error,
+) error {
+	name := metric.Name()
+
+	var numberDataPoints pmetric.NumberDataPointSlice
+	switch metric.Type() {
+	case pmetric.MetricTypeGauge:
+		numberDataPoints = metric.Gauge().DataPoints()
+	case pmetric.MetricTypeSum:
+		numberDataPoints = metric.Sum().DataPoints()
+	case pmetric.MetricTypeHistogram:
+		dps := metric.Histogram().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			dp := dps.At(i)
+			count := dp.Count()
+			if err := insert(name, dp.Timestamp(), dp.Sum(), &count,
+				dp.Attributes(), resource); err != nil {
+				return err
+			}
+		}
+		return nil
+	case pmetric.MetricTypeExponentialHistogram:
+		dps := metric.ExponentialHistogram().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			dp := dps.At(i)
+			count := dp.Count()
+			if err := insert(name, dp.Timestamp(), dp.Sum(), &count,
+				dp.Attributes(), resource); err != nil {
+				return err
+			}
+		}
+		return nil
+	case pmetric.MetricTypeSummary:
+		dps := metric.Summary().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			dp := dps.At(i)
+			count := dp.Count()
+			if err := insert(name, dp.Timestamp(), dp.Sum(), &count,
+				dp.Attributes(), resource); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return nil
+	}
+
+	for i := 0; i < numberDataPoints.Len(); i++ {
+		dp := numberDataPoints.At(i)
+		value := dp.DoubleValue()
+		if dp.ValueType() == pmetric.NumberDataPointValueTypeInt {
+			value = float64(dp.IntValue())
+		}
+		if err := insert(name, dp.Timestamp(), value, nil, dp.Attributes(),
+			resource); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *ringStoreExporter) consumeLogs(ctx context.Context, ld plog.Logs) error {
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO logs "+
+		"(ts, severity_number, severity_text, body, attributes, resource) "+
+		"VALUES (?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert: %w", err)
+	}
+	defer stmt.Close()
+
+	now := time.Now().UnixNano()
+	rls := ld.ResourceLogs()
+	for i := 0; i < rls.Len(); i++ {
+		rl := rls.At(i)
+		resource := marshalAttributes(rl.Resource().Attributes())
+		sls := rl.ScopeLogs()
+		for j := 0; j < sls.Len(); j++ {
+			lrs := sls.At(j).LogRecords()
+			for k := 0; k < lrs.Len(); k++ {
+				lr := lrs.At(k)
+				ts := int64(lr.Timestamp())
+				if ts == 0 {
+					ts = int64(lr.ObservedTimestamp())
+				}
+				if ts == 0 {
+					ts = now
+				}
+				if _, err := stmt.ExecContext(ctx, ts,
+					int64(lr.SeverityNumber()), lr.SeverityText(),
+					lr.Body().AsString(), marshalAttributes(lr.Attributes()),
+					resource); err != nil {
+					return fmt.Errorf("failed to insert log record: %w", err)
+				}
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// marshalAttributes returns the attributes as a JSON object, which the query
+// API filters with SQLite's JSON functions.
+func marshalAttributes(attrs pcommon.Map) string {
+	data, err := json.Marshal(attrs.AsRaw())
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
@@ -0,0 +1,3 @@
+package ringstoreexporter
+
+const Version = "0.0.1"
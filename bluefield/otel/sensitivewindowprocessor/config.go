@@ -0,0 +1,122 @@
+package sensitivewindowprocessor
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+
+	"otelcommon/filter"
+)
+
+const (
+	actionSuppress = "suppress"
+	actionRedact   = "redact"
+)
+
+// Config defines the configuration of the sensitive_window processor.
+type Config struct {
+	// FlagFile is the path of a file whose existence signals a sensitive
+	// window. Its content, if any, is the reason of the window, e.g.
+	// "enrollment".
+	FlagFile string `mapstructure:"flag_file"`
+
+	// Endpoint is the local endpoint, e.g. "unix:/run/otelcol/window.sock",
+	// of an API the provisioning agent starts and ends sensitive windows
+	// with. At least one of FlagFile and Endpoint must be specified.
+	Endpoint string `mapstructure:"endpoint"`
+
+	// MaxDuration limits how long a window started through the API lasts,
+	// so that telemetry is not suppressed forever if the agent fails to end
+	// it. Defaults to "1h".
+	MaxDuration time.Duration `mapstructure:"max_duration"`
+
+	// PollInterval configures how often the end of a window is checked
+	// for, to emit its audit event. Defaults to "1s".
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+
+	// Categories are the sensitive telemetry categories.
+	Categories []Category `mapstructure:"categories"`
+}
+
+// Category is sensitive telemetry that is suppressed or redacted during
+// sensitive windows.
+type Category struct {
+	// Name identifies the category in audit events.
+	Name string `mapstructure:"name"`
+
+	// Action is "suppress" to drop the telemetry of the category or
+	// "redact" to redact it. Empty means "suppress".
+	Action string `mapstructure:"action"`
+
+	// Logs selects the log records of the category.
+	Logs *filter.LogFilter `mapstructure:"logs"`
+
+	// Metrics selects the metric datapoints of the category. At least one
+	// of Logs and Metrics must be specified.
+	Metrics *filter.MetricFilter `mapstructure:"metrics"`
+
+	// RedactAttributes are the log record and datapoint attributes whose
+	// values are redacted. The bodies of log records are always redacted.
+	RedactAttributes []string `mapstructure:"redact_attributes"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.FlagFile == "" && cfg.Endpoint == "" {
+		return errors.New("at least one of flag_file and endpoint must be " +
+			"specified")
+	}
+	if cfg.MaxDuration <= 0 {
+		return errors.New("max_duration must be positive")
+	}
+	if cfg.PollInterval <= 0 {
+		return errors.New("poll_interval must be positive")
+	}
+	if len(cfg.Categories) == 0 {
+		return errors.New("categories must be specified")
+	}
+	names := make(map[string]bool)
+	for i, category := range cfg.Categories {
+		if category.Name == "" {
+			return fmt.Errorf("categories[%d]: name must be specified", i)
+		}
+		if names[category.Name] {
+			return fmt.Errorf("categories[%d]: duplicate name %q", i,
+				category.Name)
+		}
+		names[category.Name] = true
+		if category.Action != "" && category.Action != actionSuppress &&
+			category.Action != actionRedact {
+			return fmt.Errorf("category %s: action must be %q or %q",
+				category.Name, actionSuppress, actionRedact)
+		}
+		if category.Logs == nil && category.Metrics == nil {
+			return fmt.Errorf("category %s: logs or metrics must be "+
+				"specified", category.Name)
+		}
+		if _, err := filter.CompileLogFilter(category.Logs); err != nil {
+			return fmt.Errorf("category %s: logs: %w", category.Name, err)
+		}
+		if _, err := filter.CompileMetricFilter(category.Metrics); err != nil {
+			return fmt.Errorf("category %s: metrics: %w", category.Name, err)
+		}
+		if category.Action != actionRedact && len(category.RedactAttributes) > 0 {
+			return fmt.Errorf("category %s: redact_attributes requires "+
+				"action %q", category.Name, actionRedact)
+		}
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		MaxDuration:  time.Hour,
+		PollInterval: time.Second,
+	}
+}
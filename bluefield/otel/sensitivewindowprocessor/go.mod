@@ -0,0 +1,3 @@
+module sensitivewindowprocessor
+
+go 1.22
@@ -0,0 +1,380 @@
+package sensitivewindowprocessor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+
+	"otelcommon/filter"
+)
+
+const (
+	scopeName = "sensitivewindowprocessor"
+
+	// redacted replaces redacted log bodies and attribute values
+	redacted = "[REDACTED]"
+)
+
+type sensitiveWindowProcessor struct {
+	config      *Config
+	logger      *zap.Logger
+	nextLogs    consumer.Logs
+	categories  []*category
+	flagFile    *flagFile
+	api         *apiEndpoint
+	stopChannel chan struct{}
+	stopWaiters sync.WaitGroup
+
+	// the window last seen by the poll loop, and the telemetry suppressed
+	// or redacted by category since it started
+	stateLock sync.Mutex
+	seen      *window
+	counts    map[string]*categoryCounts
+}
+
+// category is a compiled Category.
+type category struct {
+	*Category
+	logs    *filter.LogMatcher
+	metrics *filter.MetricMatcher
+}
+
+type categoryCounts struct {
+	suppressed int64
+	redacted   int64
+}
+
+// attributes looks up labels of the category filters among the log record or
+// datapoint, scope and resource attributes, in that order.
+type attributes struct {
+	resource pcommon.Map
+	scope    pcommon.Map
+	item     pcommon.Map
+}
+
+func (a *attributes) Get(name string) (string, bool) {
+	for _, m := range []pcommon.Map{a.item, a.scope, a.resource} {
+		if v, exists := m.Get(name); exists {
+			return v.AsString(), true
+		}
+	}
+	return "", false
+}
+
+// processor constructor
+func newSensitiveWindowProcessor(
+	config *Config,
+	logger *zap.Logger,
+) (*sensitiveWindowProcessor, error) {
+	p := &sensitiveWindowProcessor{
+		config:      config,
+		logger:      logger,
+		stopChannel: make(chan struct{}),
+		counts:      make(map[string]*categoryCounts),
+	}
+	for i := range config.Categories {
+		c := &category{Category: &config.Categories[i]}
+		var err error
+		if c.logs, err = filter.CompileLogFilter(c.Logs); err != nil {
+			return nil, fmt.Errorf("category %s: logs: %w", c.Name, err)
+		}
+		if c.metrics, err = filter.CompileMetricFilter(c.Metrics); err != nil {
+			return nil, fmt.Errorf("category %s: metrics: %w", c.Name, err)
+		}
+		p.categories = append(p.categories, c)
+	}
+	if config.FlagFile != "" {
+		p.flagFile = &flagFile{path: config.FlagFile}
+	}
+	return p, nil
+}
+
+func (p *sensitiveWindowProcessor) start() error {
+	if p.config.Endpoint != "" {
+		api, err := registerAPIEndpoint(p.config, p.logger)
+		if err != nil {
+			return err
+		}
+		p.api = api
+	}
+
+	p.stopWaiters.Add(1)
+	go p.pollLoop()
+	return nil
+}
+
+// processor destructor
+func (p *sensitiveWindowProcessor) cleanup() {
+	close(p.stopChannel)
+	p.stopWaiters.Wait()
+
+	if p.api != nil {
+		unregisterAPIEndpoint(p.config.Endpoint)
+	}
+}
+
+// current returns the window in progress, if any. A window signaled by the
+// flag file takes precedence over one started through the API. If the flag
+// file cannot be checked, a window is assumed, so that sensitive telemetry is
+// never let through by mistake.
+func (p *sensitiveWindowProcessor) current(now time.Time) *window {
+	if p.flagFile != nil {
+		w, err := p.flagFile.current()
+		if err != nil {
+			p.logger.Error("Failed to check flag file, assuming a sensitive "+
+				"window", zap.Error(err))
+			return &window{reason: "unknown", start: now}
+		}
+		if w != nil {
+			return w
+		}
+	}
+	if p.api != nil {
+		return p.api.current(now)
+	}
+	return nil
+}
+
+func (p *sensitiveWindowProcessor) pollLoop() {
+	defer p.stopWaiters.Done()
+
+	ticker := time.NewTicker(p.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.poll()
+		case <-p.stopChannel:
+			return
+		}
+	}
+}
+
+// poll logs the start of windows and emits the audit event of windows that
+// lifted. Windows shorter than the poll interval are only noticed by the
+// telemetry they suppressed or redacted.
+func (p *sensitiveWindowProcessor) poll() {
+	now := time.Now()
+	w := p.current(now)
+
+	p.stateLock.Lock()
+	seen := p.seen
+	p.seen = w
+	if w != nil || (seen == nil && len(p.counts) == 0) {
+		p.stateLock.Unlock()
+		if w != nil && seen == nil {
+			p.logger.Info("Sensitive window started",
+				zap.String("reason", w.reason), zap.Time("start", w.start))
+		}
+		return
+	}
+	counts := p.counts
+	p.counts = make(map[string]*categoryCounts)
+	p.stateLock.Unlock()
+
+	if seen == nil {
+		seen = &window{start: now}
+	}
+	p.audit(seen, now, counts)
+}
+
+// audit logs that a window lifted and, in a logs pipeline, emits its audit
+// event.
+func (p *sensitiveWindowProcessor) audit(w *window, end time.Time, counts map[string]*categoryCounts) {
+	fields := []zap.Field{
+		zap.String("reason", w.reason),
+		zap.Time("start", w.start),
+		zap.Time("end", end),
+	}
+	for name, c := range counts {
+		fields = append(fields, zap.Int64(name+".suppressed", c.suppressed),
+			zap.Int64(name+".redacted", c.redacted))
+	}
+	p.logger.Info("Sensitive window lifted", fields...)
+
+	if p.nextLogs == nil {
+		return
+	}
+	ld := plog.NewLogs()
+	sl := ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty()
+	sl.Scope().SetName(scopeName)
+	sl.Scope().SetVersion(Version)
+	lr := sl.LogRecords().AppendEmpty()
+	timestamp := pcommon.NewTimestampFromTime(end)
+	lr.SetTimestamp(timestamp)
+	lr.SetObservedTimestamp(timestamp)
+	lr.SetSeverityNumber(plog.SeverityNumberInfo)
+	lr.SetSeverityText("INFO")
+	lr.Body().SetStr("Sensitive window lifted")
+	attrs := lr.Attributes()
+	attrs.PutStr("sensitive_window.event", "lifted")
+	attrs.PutStr("sensitive_window.reason", w.reason)
+	attrs.PutStr("sensitive_window.start", w.start.UTC().Format(time.RFC3339Nano))
+	attrs.PutStr("sensitive_window.end", end.UTC().Format(time.RFC3339Nano))
+	categories := attrs.PutEmptyMap("sensitive_window.categories")
+	for name, c := range counts {
+		m := categories.PutEmptyMap(name)
+		m.PutInt("suppressed", c.suppressed)
+		m.PutInt("redacted", c.redacted)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.config.PollInterval)
+	defer cancel()
+	if err := p.nextLogs.ConsumeLogs(ctx, ld); err != nil {
+		p.logger.Error("Failed to emit sensitive window audit event",
+			zap.Error(err))
+	}
+}
+
+// count adds to the telemetry suppressed and redacted by category.
+func (p *sensitiveWindowProcessor) count(counts map[string]*categoryCounts) {
+	if len(counts) == 0 {
+		return
+	}
+	p.stateLock.Lock()
+	defer p.stateLock.Unlock()
+
+	for name, c := range counts {
+		total, exists := p.counts[name]
+		if !exists {
+			total = &categoryCounts{}
+			p.counts[name] = total
+		}
+		total.suppressed += c.suppressed
+		total.redacted += c.redacted
+	}
+}
+
+// handle suppresses or redacts an item of the category, and returns whether
+// it is suppressed.
+func (c *category) handle(item pcommon.Map, counts map[string]*categoryCounts) bool {
+	count, exists := counts[c.Name]
+	if !exists {
+		count = &categoryCounts{}
+		counts[c.Name] = count
+	}
+	if c.Action != actionRedact {
+		count.suppressed++
+		return true
+	}
+	for _, key := range c.RedactAttributes {
+		if _, exists := item.Get(key); exists {
+			item.PutStr(key, redacted)
+		}
+	}
+	count.redacted++
+	return false
+}
+
+func (p *sensitiveWindowProcessor) processLogs(
+	_ context.Context,
+	ld plog.Logs,
+) (plog.Logs, error) {
+	if p.current(time.Now()) == nil {
+		return ld, nil
+	}
+
+	counts := make(map[string]*categoryCounts)
+	ld.ResourceLogs().RemoveIf(func(rl plog.ResourceLogs) bool {
+		attrs := &attributes{resource: rl.Resource().Attributes()}
+		rl.ScopeLogs().RemoveIf(func(sl plog.ScopeLogs) bool {
+			attrs.scope = sl.Scope().Attributes()
+			sl.LogRecords().RemoveIf(func(lr plog.LogRecord) bool {
+				attrs.item = lr.Attributes()
+				for _, c := range p.categories {
+					if !c.logs.Match(lr, attrs) {
+						continue
+					}
+					if c.handle(lr.Attributes(), counts) {
+						return true
+					}
+					lr.Body().SetStr(redacted)
+					return false
+				}
+				return false
+			})
+			return sl.LogRecords().Len() == 0
+		})
+		return rl.ScopeLogs().Len() == 0
+	})
+	p.count(counts)
+	return ld, nil
+}
+
+func (p *sensitiveWindowProcessor) processMetrics(
+	_ context.Context,
+	md pmetric.Metrics,
+) (pmetric.Metrics, error) {
+	if p.current(time.Now()) == nil {
+		return md, nil
+	}
+
+	counts := make(map[string]*categoryCounts)
+	md.ResourceMetrics().RemoveIf(func(rm pmetric.ResourceMetrics) bool {
+		attrs := &attributes{resource: rm.Resource().Attributes()}
+		rm.ScopeMetrics().RemoveIf(func(sm pmetric.ScopeMetrics) bool {
+			attrs.scope = sm.Scope().Attributes()
+			sm.Metrics().RemoveIf(func(metric pmetric.Metric) bool {
+				return removeDatapoints(metric, func(item pcommon.Map) bool {
+					attrs.item = item
+					for _, c := range p.categories {
+						if c.metrics.Match(metric, attrs) {
+							return c.handle(item, counts)
+						}
+					}
+					return false
+				}) == 0
+			})
+			return sm.Metrics().Len() == 0
+		})
+		return rm.ScopeMetrics().Len() == 0
+	})
+	p.count(counts)
+	return md, nil
+}
+
+// removeDatapoints removes the datapoints of a metric for which remove
+// returns true, and returns the number of remaining datapoints.
+func removeDatapoints(metric pmetric.Metric, remove func(pcommon.Map) bool) int {
+	switch metric.Type() {
+	case pmetric.MetricTypeGauge:
+		dps := metric.Gauge().DataPoints()
+		dps.RemoveIf(func(dp pmetric.NumberDataPoint) bool {
+			return remove(dp.Attributes())
+		})
+		return dps.Len()
+	case pmetric.MetricTypeSum:
+		dps := metric.Sum().DataPoints()
+		dps.RemoveIf(func(dp pmetric.NumberDataPoint) bool {
+			return remove(dp.Attributes())
+		})
+		return dps.Len()
+	case pmetric.MetricTypeHistogram:
+		dps := metric.Histogram().DataPoints()
+		dps.RemoveIf(func(dp pmetric.HistogramDataPoint) bool {
+			return remove(dp.Attributes())
+		})
+		return dps.Len()
+	case pmetric.MetricTypeExponentialHistogram:
+		dps := metric.ExponentialHistogram().DataPoints()
+		dps.RemoveIf(func(dp pmetric.ExponentialHistogramDataPoint) bool {
+			return remove(dp.Attributes())
+		})
+		return dps.Len()
+	case pmetric.MetricTypeSummary:
+		dps := metric.Summary().DataPoints()
+		dps.RemoveIf(func(dp pmetric.SummaryDataPoint) bool {
+			return remove(dp.Attributes())
+		})
+		return dps.Len()
+	}
+	return 1
+}
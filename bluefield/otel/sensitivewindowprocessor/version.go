@@ -0,0 +1,3 @@
+package sensitivewindowprocessor
+
+const Version = "0.0.1"
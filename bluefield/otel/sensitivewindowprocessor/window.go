@@ -0,0 +1,253 @@
+package sensitivewindowprocessor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"otelcommon/httpregistry"
+)
+
+const (
+	// windowPath is the path of the API starting and ending windows
+	windowPath = "/v1/sensitive_window"
+
+	// maxReasonSize limits the size of the reason of a window
+	maxReasonSize = 256
+)
+
+var (
+	// API endpoints by address, shared by the processors configuring the
+	// same endpoint, so that the agent starts a window for all pipelines
+	apiEndpointsLock sync.Mutex
+	apiEndpoints     = make(map[string]*apiEndpoint)
+)
+
+// window is a sensitive window in progress.
+type window struct {
+	reason string
+	start  time.Time
+}
+
+// flagFile signals a window while it exists.
+type flagFile struct {
+	path    string
+	lock    sync.Mutex
+	modTime time.Time
+	reason  string
+}
+
+// current returns the window signaled by the flag file, if it exists. The
+// window starts at the modification time of the file.
+func (f *flagFile) current() (*window, error) {
+	info, err := os.Stat(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if !info.ModTime().Equal(f.modTime) {
+		data, err := os.ReadFile(f.path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		f.modTime = info.ModTime()
+		f.reason = string(bytes.TrimSpace(data[:min(len(data), maxReasonSize)]))
+	}
+	return &window{reason: f.reason, start: f.modTime}, nil
+}
+
+// apiEndpoint serves the API the provisioning agent starts and ends windows
+// with.
+type apiEndpoint struct {
+	logger       *zap.Logger
+	registration *httpregistry.Registration
+	processors   int
+	maxDuration  time.Duration
+	lock         sync.Mutex
+	window       *window
+	deadline     time.Time
+}
+
+// startRequest is the JSON body of a request starting a window, e.g.
+//
+//	{"reason": "key-ceremony", "duration": "15m"}
+type startRequest struct {
+	Reason   string `json:"reason"`
+	Duration string `json:"duration"`
+}
+
+// windowState is the JSON state of the API's window.
+type windowState struct {
+	Active   bool       `json:"active"`
+	Reason   string     `json:"reason,omitempty"`
+	Start    *time.Time `json:"start,omitempty"`
+	Deadline *time.Time `json:"deadline,omitempty"`
+}
+
+// registerAPIEndpoint serves the window API on the configured endpoint, once
+// for all processors configuring the same one.
+func registerAPIEndpoint(config *Config, logger *zap.Logger) (*apiEndpoint, error) {
+	apiEndpointsLock.Lock()
+	defer apiEndpointsLock.Unlock()
+
+	e, exists := apiEndpoints[config.Endpoint]
+	if !exists {
+		e = &apiEndpoint{
+			logger:      logger,
+			maxDuration: config.MaxDuration,
+		}
+		registration, err := httpregistry.Register(
+			httpregistry.ServerConfig{Endpoint: config.Endpoint},
+			windowPath,
+			e,
+			logger,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to register window endpoint: %w", err)
+		}
+		e.registration = registration
+		apiEndpoints[config.Endpoint] = e
+	}
+	e.processors++
+	return e, nil
+}
+
+// unregisterAPIEndpoint stops serving the window API once the last processor
+// configuring the endpoint is shut down.
+func unregisterAPIEndpoint(endpoint string) {
+	apiEndpointsLock.Lock()
+	e, exists := apiEndpoints[endpoint]
+	if !exists {
+		apiEndpointsLock.Unlock()
+		return
+	}
+	e.processors--
+	if e.processors > 0 {
+		apiEndpointsLock.Unlock()
+		return
+	}
+	delete(apiEndpoints, endpoint)
+	apiEndpointsLock.Unlock()
+
+	e.registration.Unregister()
+}
+
+// current returns the window started through the API, unless it ended or
+// exceeded its deadline.
+func (e *apiEndpoint) current(now time.Time) *window {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.window != nil && now.After(e.deadline) {
+		e.logger.Warn("Sensitive window exceeded its deadline, lifting it",
+			zap.String("reason", e.window.reason),
+			zap.Time("deadline", e.deadline))
+		e.window = nil
+	}
+	return e.window
+}
+
+// ServeHTTP returns the state of the window on GET, starts a window or
+// replaces the reason and duration of the current one on POST, and ends the
+// window on DELETE.
+func (e *apiEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var req startRequest
+		decoder := json.NewDecoder(io.LimitReader(r.Body, 4096))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("invalid request: %v", err),
+				http.StatusBadRequest)
+			return
+		}
+		duration, err := e.duration(req.Duration)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(req.Reason) > maxReasonSize {
+			http.Error(w, "reason is too long", http.StatusBadRequest)
+			return
+		}
+		e.start(req.Reason, now, duration)
+	case http.MethodDelete:
+		e.end()
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	state := windowState{}
+	if current := e.current(now); current != nil {
+		e.lock.Lock()
+		deadline := e.deadline
+		e.lock.Unlock()
+		state = windowState{
+			Active:   true,
+			Reason:   current.reason,
+			Start:    &current.start,
+			Deadline: &deadline,
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(state)
+}
+
+// duration parses the requested duration of a window, which defaults to and
+// is limited by max_duration.
+func (e *apiEndpoint) duration(requested string) (time.Duration, error) {
+	if requested == "" {
+		return e.maxDuration, nil
+	}
+	duration, err := time.ParseDuration(requested)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", requested)
+	}
+	return min(duration, e.maxDuration), nil
+}
+
+func (e *apiEndpoint) start(reason string, now time.Time, duration time.Duration) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	// windows are replaced rather than modified, as processors read them
+	// without holding the lock
+	start := now
+	if e.window != nil {
+		start = e.window.start
+	}
+	e.window = &window{reason: reason, start: start}
+	e.deadline = now.Add(duration)
+	e.logger.Info("Sensitive window started through the API",
+		zap.String("reason", reason),
+		zap.Time("deadline", e.deadline))
+}
+
+func (e *apiEndpoint) end() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.window != nil {
+		e.logger.Info("Sensitive window ended through the API",
+			zap.String("reason", e.window.reason))
+	}
+	e.window = nil
+}
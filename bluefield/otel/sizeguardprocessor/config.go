@@ -0,0 +1,59 @@
+package sizeguardprocessor
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+// Config defines the configuration of the size_guard processor.
+type Config struct {
+	// MaxRecordSize limits the size of each log record in bytes, as
+	// encoded in an OTLP protobuf request. The string body and string
+	// attributes of larger records are trimmed, longest first, until the
+	// record fits, and end with `truncation_marker`. 0 disables trimming.
+	// Defaults to 65536.
+	MaxRecordSize int `mapstructure:"max_record_size"`
+
+	// MaxBatchSize limits the size of each batch passed on in bytes, as
+	// encoded in an OTLP protobuf request. Larger batches are split into
+	// batches that fit, each passed on in turn. 0 disables splitting.
+	// Defaults to 4000000, just below the 4 MiB gRPC receivers accept by
+	// default.
+	MaxBatchSize int `mapstructure:"max_batch_size"`
+
+	// TruncationMarker is appended to trimmed strings, so that readers
+	// can tell them from complete ones. Defaults to "...[truncated]".
+	TruncationMarker string `mapstructure:"truncation_marker"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.MaxRecordSize < 0 {
+		return errors.New("max_record_size cannot be negative")
+	}
+	if cfg.MaxBatchSize < 0 {
+		return errors.New("max_batch_size cannot be negative")
+	}
+	if cfg.MaxRecordSize == 0 && cfg.MaxBatchSize == 0 {
+		return errors.New("at least one of max_record_size or " +
+			"max_batch_size must be positive")
+	}
+	if cfg.MaxRecordSize > 0 && len(cfg.TruncationMarker) >= cfg.MaxRecordSize {
+		return errors.New("truncation_marker must be shorter than " +
+			"max_record_size")
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		MaxRecordSize:    65536,
+		MaxBatchSize:     4000000,
+		TruncationMarker: "...[truncated]",
+	}
+}
@@ -0,0 +1,3 @@
+module sizeguardprocessor
+
+go 1.22
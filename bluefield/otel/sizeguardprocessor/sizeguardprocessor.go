@@ -0,0 +1,264 @@
+package sizeguardprocessor
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+	"go.uber.org/zap"
+
+	"otelcommon/pdataiter"
+	"otelcommon/protosize"
+)
+
+// envelopeSize bounds the size of the tags and lengths enclosing the resource
+// and scope of the records in a batch, which protosize doesn't include.
+const envelopeSize = 24
+
+type sizeGuardProcessor struct {
+	config      *Config
+	logger      *zap.Logger
+	nextMetrics consumer.Metrics
+	nextLogs    consumer.Logs
+}
+
+// processor constructor
+func newSizeGuardProcessor(config *Config, logger *zap.Logger) *sizeGuardProcessor {
+	return &sizeGuardProcessor{
+		config: config,
+		logger: logger,
+	}
+}
+
+func (p *sizeGuardProcessor) processLogs(
+	ctx context.Context,
+	ld plog.Logs,
+) (plog.Logs, error) {
+	if p.config.MaxRecordSize > 0 {
+		trimmed, oversized := 0, 0
+		pdataiter.LogRecords(ld, func(lr *pdataiter.LogRecord) {
+			wasTrimmed, size := p.trim(lr.Record)
+			if wasTrimmed {
+				trimmed++
+			}
+			if size > p.config.MaxRecordSize {
+				oversized++
+			}
+		})
+		if trimmed > 0 || oversized > 0 {
+			p.logger.Warn("Trimmed oversized log records",
+				zap.Int("records", trimmed),
+				zap.Int("records_still_oversized", oversized),
+				zap.Int("max_record_size", p.config.MaxRecordSize))
+		}
+	}
+
+	if p.config.MaxBatchSize == 0 {
+		return ld, nil
+	}
+	size := (&plog.ProtoMarshaler{}).LogsSize(ld)
+	if size <= p.config.MaxBatchSize {
+		return ld, nil
+	}
+
+	batches := splitLogs(ld, p.config.MaxBatchSize)
+	p.logger.Debug("Split oversized batch of logs",
+		zap.Int("size", size), zap.Int("batches", len(batches)))
+	var errs []error
+	for _, batch := range batches {
+		if err := p.nextLogs.ConsumeLogs(ctx, batch); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return ld, errors.Join(errs...)
+	}
+	return ld, processorhelper.ErrSkipProcessingData
+}
+
+func (p *sizeGuardProcessor) processMetrics(
+	ctx context.Context,
+	md pmetric.Metrics,
+) (pmetric.Metrics, error) {
+	if p.config.MaxBatchSize == 0 {
+		return md, nil
+	}
+	size := (&pmetric.ProtoMarshaler{}).MetricsSize(md)
+	if size <= p.config.MaxBatchSize {
+		return md, nil
+	}
+
+	batches := splitMetrics(md, p.config.MaxBatchSize)
+	p.logger.Debug("Split oversized batch of metrics",
+		zap.Int("size", size), zap.Int("batches", len(batches)))
+	var errs []error
+	for _, batch := range batches {
+		if err := p.nextMetrics.ConsumeMetrics(ctx, batch); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return md, errors.Join(errs...)
+	}
+	return md, processorhelper.ErrSkipProcessingData
+}
+
+// trim trims the longest strings among the string body and attributes of an
+// oversized log record until it fits into max_record_size, and returns whether
+// any string was trimmed along with the resulting size. Strings no longer than
+// the truncation marker are left as they are, so a record whose size comes
+// from anything else stays oversized.
+func (p *sizeGuardProcessor) trim(lr plog.LogRecord) (bool, int) {
+	size := protosize.LogRecord(lr)
+	if size <= p.config.MaxRecordSize {
+		return false, size
+	}
+
+	var values []pcommon.Value
+	if lr.Body().Type() == pcommon.ValueTypeStr {
+		values = append(values, lr.Body())
+	}
+	lr.Attributes().Range(func(_ string, v pcommon.Value) bool {
+		if v.Type() == pcommon.ValueTypeStr {
+			values = append(values, v)
+		}
+		return true
+	})
+	sort.SliceStable(values, func(i, j int) bool {
+		return len(values[i].Str()) > len(values[j].Str())
+	})
+
+	marker := p.config.TruncationMarker
+	trimmed := false
+	for _, v := range values {
+		if size <= p.config.MaxRecordSize {
+			break
+		}
+		s := v.Str()
+		if len(s) <= len(marker) {
+			break
+		}
+		keep := max(len(s)-(size-p.config.MaxRecordSize)-len(marker), 0)
+		v.SetStr(truncate(s, keep) + marker)
+		size = protosize.LogRecord(lr)
+		trimmed = true
+	}
+	return trimmed, size
+}
+
+// truncate returns at most the first n bytes of s, without splitting a UTF-8
+// encoded character.
+func truncate(s string, n int) string {
+	if n >= len(s) {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// splitLogs copies the log records into batches of at most size bytes. A
+// record larger than that on its own is passed on in a batch of its own.
+func splitLogs(ld plog.Logs, size int) []plog.Logs {
+	var batches []plog.Logs
+	var current plog.Logs
+	currentSize := 0
+	for i := 0; i < ld.ResourceLogs().Len(); i++ {
+		rl := ld.ResourceLogs().At(i)
+		for j := 0; j < rl.ScopeLogs().Len(); j++ {
+			sl := rl.ScopeLogs().At(j)
+			headerSize := protosize.Resource(rl.Resource()) +
+				protosize.Scope(sl.Scope()) + len(rl.SchemaUrl()) +
+				len(sl.SchemaUrl()) + envelopeSize
+			var dest plog.LogRecordSlice
+			opened := false
+			for k := 0; k < sl.LogRecords().Len(); k++ {
+				record := sl.LogRecords().At(k)
+				recordSize := protosize.LogRecord(record)
+				if !opened {
+					recordSize += headerSize
+				}
+				if len(batches) == 0 ||
+					(currentSize > 0 && currentSize+recordSize > size) {
+					current = plog.NewLogs()
+					batches = append(batches, current)
+					currentSize = 0
+					if opened {
+						recordSize += headerSize
+					}
+					opened = false
+				}
+				if !opened {
+					destRL := current.ResourceLogs().AppendEmpty()
+					rl.Resource().CopyTo(destRL.Resource())
+					destRL.SetSchemaUrl(rl.SchemaUrl())
+					destSL := destRL.ScopeLogs().AppendEmpty()
+					sl.Scope().CopyTo(destSL.Scope())
+					destSL.SetSchemaUrl(sl.SchemaUrl())
+					dest = destSL.LogRecords()
+					opened = true
+				}
+				record.CopyTo(dest.AppendEmpty())
+				currentSize += recordSize
+			}
+		}
+	}
+	return batches
+}
+
+// splitMetrics copies the metrics into batches of at most size bytes. Metrics
+// are not split, so a metric larger than that on its own is passed on in a
+// batch of its own.
+func splitMetrics(md pmetric.Metrics, size int) []pmetric.Metrics {
+	var batches []pmetric.Metrics
+	var current pmetric.Metrics
+	currentSize := 0
+	for i := 0; i < md.ResourceMetrics().Len(); i++ {
+		rm := md.ResourceMetrics().At(i)
+		for j := 0; j < rm.ScopeMetrics().Len(); j++ {
+			sm := rm.ScopeMetrics().At(j)
+			headerSize := protosize.Resource(rm.Resource()) +
+				protosize.Scope(sm.Scope()) + len(rm.SchemaUrl()) +
+				len(sm.SchemaUrl()) + envelopeSize
+			var dest pmetric.MetricSlice
+			opened := false
+			for k := 0; k < sm.Metrics().Len(); k++ {
+				metric := sm.Metrics().At(k)
+				metricSize := protosize.Metric(metric)
+				if !opened {
+					metricSize += headerSize
+				}
+				if len(batches) == 0 ||
+					(currentSize > 0 && currentSize+metricSize > size) {
+					current = pmetric.NewMetrics()
+					batches = append(batches, current)
+					currentSize = 0
+					if opened {
+						metricSize += headerSize
+					}
+					opened = false
+				}
+				if !opened {
+					destRM := current.ResourceMetrics().AppendEmpty()
+					rm.Resource().CopyTo(destRM.Resource())
+					destRM.SetSchemaUrl(rm.SchemaUrl())
+					destSM := destRM.ScopeMetrics().AppendEmpty()
+					sm.Scope().CopyTo(destSM.Scope())
+					destSM.SetSchemaUrl(sm.SchemaUrl())
+					dest = destSM.Metrics()
+					opened = true
+				}
+				metric.CopyTo(dest.AppendEmpty())
+				currentSize += metricSize
+			}
+		}
+	}
+	return batches
+}
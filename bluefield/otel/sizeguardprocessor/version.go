@@ -0,0 +1,3 @@
+package sizeguardprocessor
+
+const Version = "0.0.1"
@@ -0,0 +1,275 @@
+package syntheticloadreceiver
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+const (
+	metricTypeCounter = "counter"
+	metricTypeGauge   = "gauge"
+
+	// maxSeries limits the number of series of all metric families, so that
+	// a typo in a cardinality doesn't exhaust the memory of the card
+	maxSeries = 1_000_000
+)
+
+// Config defines the configuration of the synthetic_load receiver.
+type Config struct {
+	// Interval configures how often metrics and logs are generated.
+	// Defaults to "10s".
+	Interval time.Duration `mapstructure:"interval"`
+
+	// Resources is the number of simulated hosts, each with its own
+	// resource. Defaults to 1.
+	Resources int `mapstructure:"resources"`
+
+	// ResourceAttributes are added to the resource of each simulated host,
+	// along with "host.name" set to "synthetic-<index>".
+	ResourceAttributes map[string]string `mapstructure:"resource_attributes"`
+
+	// Metrics are the metric families generated for each resource.
+	// Defaults to families shaped like the network, representor, devlink
+	// health and hugepage metrics of a BlueField.
+	Metrics []MetricFamily `mapstructure:"metrics"`
+
+	// Logs configures the log records generated each interval.
+	Logs LogsConfig `mapstructure:"logs"`
+
+	// Burst configures periodic bursts of load.
+	Burst BurstConfig `mapstructure:"burst"`
+}
+
+// MetricFamily is a metric with a series for each combination of its label
+// values.
+type MetricFamily struct {
+	// Name is the name of the metric.
+	Name string `mapstructure:"name"`
+
+	// Type is "counter" for cumulative monotonic sums or "gauge". Empty
+	// means "counter".
+	Type string `mapstructure:"type"`
+
+	// Value is the mean increase per second of counters and the mean value
+	// of gauges. Each datapoint varies by up to half of it.
+	Value float64 `mapstructure:"value"`
+
+	// Labels are the datapoint attributes of the metric.
+	Labels []Label `mapstructure:"labels"`
+}
+
+// Label is an attribute taking a number of distinct values, "<name>-<n>".
+type Label struct {
+	// Name is the name of the attribute.
+	Name string `mapstructure:"name"`
+
+	// Cardinality is the number of distinct values.
+	Cardinality int `mapstructure:"cardinality"`
+}
+
+// LogsConfig configures the generated log records.
+type LogsConfig struct {
+	// Records is the number of log records generated each interval across
+	// all resources. Defaults to 100.
+	Records int `mapstructure:"records"`
+
+	// BodySize is the size in bytes of the body of each record. Defaults to
+	// 128.
+	BodySize int `mapstructure:"body_size"`
+
+	// Severities are the severity texts picked at random for each record.
+	// List a severity several times to make it more frequent. Defaults to
+	// four INFO for one WARN and one ERROR.
+	Severities []string `mapstructure:"severities"`
+
+	// Attributes are the attributes of each record, with a value picked at
+	// random among their cardinality. Defaults to "syslog.identifier" with
+	// a cardinality of 16.
+	Attributes []Label `mapstructure:"attributes"`
+}
+
+// BurstConfig configures periodic bursts multiplying the load.
+type BurstConfig struct {
+	// Every is the period of bursts. Defaults to "0s", which disables
+	// bursts.
+	Every time.Duration `mapstructure:"every"`
+
+	// Duration is how long each burst lasts. Defaults to "1m".
+	Duration time.Duration `mapstructure:"duration"`
+
+	// Factor multiplies the number of log records and the increase of
+	// counters during bursts. Defaults to 10.
+	Factor float64 `mapstructure:"factor"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.Interval <= 0 {
+		return errors.New("interval must be positive")
+	}
+	if cfg.Resources <= 0 {
+		return errors.New("resources must be positive")
+	}
+
+	series := 0
+	names := make([]string, 0, len(cfg.Metrics))
+	for i, family := range cfg.Metrics {
+		if family.Name == "" {
+			return fmt.Errorf("name of metric %d cannot be empty", i)
+		}
+		if slices.Contains(names, family.Name) {
+			return fmt.Errorf("duplicate metric %s", family.Name)
+		}
+		names = append(names, family.Name)
+		if family.Type != "" && family.Type != metricTypeCounter &&
+			family.Type != metricTypeGauge {
+			return fmt.Errorf("invalid type %q of metric %s, must be "+
+				"counter or gauge", family.Type, family.Name)
+		}
+		if family.Value < 0 {
+			return fmt.Errorf("value of metric %s cannot be negative",
+				family.Name)
+		}
+		if err := validateLabels(family.Labels); err != nil {
+			return fmt.Errorf("invalid labels of metric %s: %w",
+				family.Name, err)
+		}
+		series += family.series() * cfg.Resources
+		if series > maxSeries {
+			return fmt.Errorf("metrics exceed %d series", maxSeries)
+		}
+	}
+
+	if cfg.Logs.Records < 0 {
+		return errors.New("logs.records cannot be negative")
+	}
+	if cfg.Logs.BodySize < 0 {
+		return errors.New("logs.body_size cannot be negative")
+	}
+	if cfg.Logs.Records > 0 && len(cfg.Logs.Severities) == 0 {
+		return errors.New("logs.severities cannot be empty")
+	}
+	if err := validateLabels(cfg.Logs.Attributes); err != nil {
+		return fmt.Errorf("invalid logs.attributes: %w", err)
+	}
+
+	if cfg.Burst.Every < 0 {
+		return errors.New("burst.every cannot be negative")
+	}
+	if cfg.Burst.Every > 0 {
+		if cfg.Burst.Duration <= 0 || cfg.Burst.Duration >= cfg.Burst.Every {
+			return errors.New("burst.duration must be positive and " +
+				"shorter than burst.every")
+		}
+		if cfg.Burst.Factor <= 0 {
+			return errors.New("burst.factor must be positive")
+		}
+	}
+	return nil
+}
+
+func validateLabels(labels []Label) error {
+	names := make([]string, 0, len(labels))
+	for _, label := range labels {
+		if strings.TrimSpace(label.Name) == "" {
+			return errors.New("label name cannot be empty")
+		}
+		if slices.Contains(names, label.Name) {
+			return fmt.Errorf("duplicate label %s", label.Name)
+		}
+		names = append(names, label.Name)
+		if label.Cardinality <= 0 || label.Cardinality > maxSeries {
+			return fmt.Errorf("cardinality of label %s must be between 1 "+
+				"and %d", label.Name, maxSeries)
+		}
+	}
+	return nil
+}
+
+// series returns the number of series of the family for one resource.
+func (f *MetricFamily) series() int {
+	series := 1
+	for _, label := range f.Labels {
+		series *= label.Cardinality
+		if series > maxSeries {
+			return maxSeries + 1
+		}
+	}
+	return series
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		Interval:  10 * time.Second,
+		Resources: 1,
+		Metrics: []MetricFamily{
+			{
+				Name:  "system.network.io",
+				Type:  metricTypeCounter,
+				Value: 1e6,
+				Labels: []Label{
+					{Name: "device", Cardinality: 4},
+					{Name: "direction", Cardinality: 2},
+				},
+			},
+			{
+				Name:  "system.network.errors",
+				Type:  metricTypeCounter,
+				Value: 0.1,
+				Labels: []Label{
+					{Name: "device", Cardinality: 4},
+					{Name: "direction", Cardinality: 2},
+				},
+			},
+			{
+				Name:  "representor.rx.packets",
+				Type:  metricTypeCounter,
+				Value: 1e4,
+				Labels: []Label{
+					{Name: "representor", Cardinality: 64},
+				},
+			},
+			{
+				Name:  "devlink.health.reporter.errors",
+				Type:  metricTypeCounter,
+				Value: 0.01,
+				Labels: []Label{
+					{Name: "devlink.device", Cardinality: 2},
+					{Name: "devlink.health.reporter", Cardinality: 4},
+				},
+			},
+			{
+				Name:  "hugepages.free",
+				Type:  metricTypeGauge,
+				Value: 512,
+				Labels: []Label{
+					{Name: "numa.node", Cardinality: 1},
+					{Name: "hugepages.size", Cardinality: 2},
+				},
+			},
+		},
+		Logs: LogsConfig{
+			Records:  100,
+			BodySize: 128,
+			Severities: []string{
+				"INFO", "INFO", "INFO", "INFO", "WARN", "ERROR",
+			},
+			Attributes: []Label{
+				{Name: "syslog.identifier", Cardinality: 16},
+			},
+		},
+		Burst: BurstConfig{
+			Duration: time.Minute,
+			Factor:   10,
+		},
+	}
+}
@@ -0,0 +1,3 @@
+module syntheticloadreceiver
+
+go 1.22
@@ -0,0 +1,293 @@
+package syntheticloadreceiver
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+const scopeName = "syntheticloadreceiver"
+
+type syntheticLoadReceiver struct {
+	config          *Config
+	logger          *zap.Logger
+	metricsConsumer consumer.Metrics
+	logsConsumer    consumer.Logs
+	startOnce       sync.Once
+	stopOnce        sync.Once
+	startTime       time.Time
+	stopChannel     chan struct{}
+	stopWaiters     sync.WaitGroup
+
+	// cumulative values of the counters by family, resource and series,
+	// only accessed by the generate loop
+	counters [][][]float64
+	// sequence number of the last generated log record
+	logSequence int64
+}
+
+func newSyntheticLoadReceiver(config *Config, logger *zap.Logger) *syntheticLoadReceiver {
+	return &syntheticLoadReceiver{
+		config:      config,
+		logger:      logger,
+		stopChannel: make(chan struct{}),
+	}
+}
+
+func (r *syntheticLoadReceiver) Start(_ context.Context, _ component.Host) error {
+	r.startOnce.Do(func() {
+		r.startTime = time.Now()
+		r.counters = make([][][]float64, len(r.config.Metrics))
+		for i, family := range r.config.Metrics {
+			if family.Type == metricTypeGauge {
+				continue
+			}
+			r.counters[i] = make([][]float64, r.config.Resources)
+			for j := range r.counters[i] {
+				r.counters[i][j] = make([]float64, family.series())
+			}
+		}
+		r.logger.Info("Generating synthetic load",
+			zap.Int("resources", r.config.Resources),
+			zap.Int("series", r.seriesCount()),
+			zap.Int("log_records_per_interval", r.config.Logs.Records))
+
+		r.stopWaiters.Add(1)
+		go r.generateLoop()
+	})
+	return nil
+}
+
+func (r *syntheticLoadReceiver) Shutdown(context.Context) error {
+	r.stopOnce.Do(func() {
+		close(r.stopChannel)
+		r.stopWaiters.Wait()
+		removeReceiver(r.config)
+	})
+	return nil
+}
+
+func (r *syntheticLoadReceiver) seriesCount() int {
+	series := 0
+	for _, family := range r.config.Metrics {
+		series += family.series() * r.config.Resources
+	}
+	return series
+}
+
+func (r *syntheticLoadReceiver) generateLoop() {
+	defer r.stopWaiters.Done()
+
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			r.generate(now)
+		case <-r.stopChannel:
+			return
+		}
+	}
+}
+
+func (r *syntheticLoadReceiver) generate(now time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.config.Interval)
+	defer cancel()
+
+	factor := r.burstFactor(now)
+
+	if r.metricsConsumer != nil && len(r.config.Metrics) > 0 {
+		md := r.buildMetrics(now, factor)
+		if err := r.metricsConsumer.ConsumeMetrics(ctx, md); err != nil {
+			r.logger.Error("Failed to consume synthetic metrics",
+				zap.Error(err))
+		}
+	}
+
+	if r.logsConsumer != nil {
+		ld := r.buildLogs(now, factor)
+		if ld.LogRecordCount() > 0 {
+			if err := r.logsConsumer.ConsumeLogs(ctx, ld); err != nil {
+				r.logger.Error("Failed to consume synthetic logs",
+					zap.Error(err))
+			}
+		}
+	}
+}
+
+// burstFactor returns the factor multiplying the load at the time, which is
+// the burst factor during the first burst.duration of each burst.every
+// since the receiver started, and 1 otherwise.
+func (r *syntheticLoadReceiver) burstFactor(now time.Time) float64 {
+	burst := r.config.Burst
+	if burst.Every <= 0 {
+		return 1
+	}
+	if now.Sub(r.startTime)%burst.Every < burst.Duration {
+		return burst.Factor
+	}
+	return 1
+}
+
+// newResource sets the attributes of the resource of the simulated host.
+func (r *syntheticLoadReceiver) newResource(resource pcommon.Resource, index int) {
+	attrs := resource.Attributes()
+	for k, v := range r.config.ResourceAttributes {
+		attrs.PutStr(k, v)
+	}
+	attrs.PutStr("host.name", "synthetic-"+strconv.Itoa(index))
+}
+
+func (r *syntheticLoadReceiver) buildMetrics(now time.Time, factor float64) pmetric.Metrics {
+	md := pmetric.NewMetrics()
+	timestamp := pcommon.NewTimestampFromTime(now)
+	startTimestamp := pcommon.NewTimestampFromTime(r.startTime)
+	seconds := r.config.Interval.Seconds()
+
+	for resource := 0; resource < r.config.Resources; resource++ {
+		rm := md.ResourceMetrics().AppendEmpty()
+		r.newResource(rm.Resource(), resource)
+		sm := rm.ScopeMetrics().AppendEmpty()
+		sm.Scope().SetName(scopeName)
+		sm.Scope().SetVersion(Version)
+
+		for i, family := range r.config.Metrics {
+			metric := sm.Metrics().AppendEmpty()
+			metric.SetName(family.Name)
+
+			var points pmetric.NumberDataPointSlice
+			if family.Type != metricTypeGauge {
+				sum := metric.SetEmptySum()
+				sum.SetIsMonotonic(true)
+				sum.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+				points = sum.DataPoints()
+			} else {
+				points = metric.SetEmptyGauge().DataPoints()
+			}
+
+			series := family.series()
+			points.EnsureCapacity(series)
+			for s := 0; s < series; s++ {
+				dp := points.AppendEmpty()
+				dp.SetTimestamp(timestamp)
+				putLabels(dp.Attributes(), family.Labels, s)
+				if family.Type != metricTypeGauge {
+					counters := r.counters[i][resource]
+					counters[s] += family.Value * seconds * factor * jitter()
+					dp.SetStartTimestamp(startTimestamp)
+					dp.SetDoubleValue(counters[s])
+				} else {
+					dp.SetDoubleValue(family.Value * jitter())
+				}
+			}
+		}
+	}
+	return md
+}
+
+func (r *syntheticLoadReceiver) buildLogs(now time.Time, factor float64) plog.Logs {
+	ld := plog.NewLogs()
+	records := int(float64(r.config.Logs.Records) * factor)
+	if records == 0 {
+		return ld
+	}
+
+	timestamp := pcommon.NewTimestampFromTime(now)
+	scopeLogs := make([]plog.ScopeLogs, r.config.Resources)
+	for resource := range scopeLogs {
+		rl := ld.ResourceLogs().AppendEmpty()
+		r.newResource(rl.Resource(), resource)
+		scopeLogs[resource] = rl.ScopeLogs().AppendEmpty()
+		scopeLogs[resource].Scope().SetName(scopeName)
+		scopeLogs[resource].Scope().SetVersion(Version)
+	}
+
+	logs := r.config.Logs
+	for i := 0; i < records; i++ {
+		r.logSequence++
+		lr := scopeLogs[rand.IntN(len(scopeLogs))].LogRecords().AppendEmpty()
+		lr.SetTimestamp(timestamp)
+		lr.SetObservedTimestamp(timestamp)
+		severity := logs.Severities[rand.IntN(len(logs.Severities))]
+		lr.SetSeverityText(severity)
+		lr.SetSeverityNumber(severityNumber(severity))
+		lr.Body().SetStr(logBody(r.logSequence, logs.BodySize))
+		for _, label := range logs.Attributes {
+			lr.Attributes().PutStr(label.Name,
+				labelValue(label.Name, rand.IntN(label.Cardinality)))
+		}
+	}
+
+	// resources which got no records when there are fewer records than
+	// resources
+	ld.ResourceLogs().RemoveIf(func(rl plog.ResourceLogs) bool {
+		return rl.ScopeLogs().At(0).LogRecords().Len() == 0
+	})
+	return ld
+}
+
+// putLabels puts the label values of the series with the index, enumerating
+// the combinations of label values with the last label varying fastest.
+func putLabels(attrs pcommon.Map, labels []Label, series int) {
+	attrs.EnsureCapacity(len(labels))
+	for i := len(labels) - 1; i >= 0; i-- {
+		label := labels[i]
+		attrs.PutStr(label.Name, labelValue(label.Name, series%label.Cardinality))
+		series /= label.Cardinality
+	}
+}
+
+// labelValue returns the value of a label, e.g. "device-3" for the label
+// "device" or "reporter-0" for "devlink.health.reporter".
+func labelValue(name string, index int) string {
+	if dot := strings.LastIndexByte(name, '.'); dot >= 0 {
+		name = name[dot+1:]
+	}
+	return name + "-" + strconv.Itoa(index)
+}
+
+// logBody returns a body of the size, starting with the sequence number of
+// the record so that lost records can be spotted downstream.
+func logBody(sequence int64, size int) string {
+	body := fmt.Sprintf("synthetic log record %d ", sequence)
+	if len(body) >= size {
+		return body[:size]
+	}
+	return body + strings.Repeat("x", size-len(body))
+}
+
+func severityNumber(severity string) plog.SeverityNumber {
+	switch strings.ToUpper(severity) {
+	case "TRACE":
+		return plog.SeverityNumberTrace
+	case "DEBUG":
+		return plog.SeverityNumberDebug
+	case "INFO":
+		return plog.SeverityNumberInfo
+	case "WARN", "WARNING":
+		return plog.SeverityNumberWarn
+	case "ERROR":
+		return plog.SeverityNumberError
+	case "FATAL":
+		return plog.SeverityNumberFatal
+	default:
+		return plog.SeverityNumberUnspecified
+	}
+}
+
+// jitter returns a random factor between 0.5 and 1.5.
+func jitter() float64 {
+	return 0.5 + rand.Float64()
+}
@@ -0,0 +1,3 @@
+package syntheticloadreceiver
+
+const Version = "0.0.1"
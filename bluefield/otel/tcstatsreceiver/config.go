@@ -0,0 +1,58 @@
+package tcstatsreceiver
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+// Config defines the configuration of the tc_stats receiver.
+type Config struct {
+	// CollectionInterval configures how often qdisc and class statistics
+	// are collected. Defaults to "30s".
+	CollectionInterval time.Duration `mapstructure:"collection_interval"`
+
+	// TcPath is the path of the tc binary used to query statistics.
+	// Defaults to "tc".
+	TcPath string `mapstructure:"tc_path"`
+
+	// InterfaceRegex matches the names of the interfaces to collect
+	// statistics for. Defaults to the DPU uplinks (p0, p1), host PF
+	// representors (pf0hpf), VF representors (pf0vf0) and SF representors
+	// (en3f0pf0sf0).
+	InterfaceRegex string `mapstructure:"interface_regex"`
+
+	// IncludeClasses configures whether statistics of the classes of
+	// classful qdiscs such as HTB are collected. Defaults to true.
+	IncludeClasses bool `mapstructure:"include_classes"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.CollectionInterval <= 0 {
+		return errors.New("collection_interval must be positive")
+	}
+	if cfg.TcPath == "" {
+		return errors.New("tc_path cannot be empty")
+	}
+	if _, err := regexp.Compile(cfg.InterfaceRegex); err != nil {
+		return fmt.Errorf("invalid interface_regex: %w", err)
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		CollectionInterval: 30 * time.Second,
+		TcPath:             "tc",
+		InterfaceRegex:     `^(p[0-9]+|pf[0-9]+hpf|pf[0-9]+vf[0-9]+|en3f[0-9]+pf[0-9]+sf[0-9]+)$`,
+		IncludeClasses:     true,
+	}
+}
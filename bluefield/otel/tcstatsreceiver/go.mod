@@ -0,0 +1,3 @@
+module tcstatsreceiver
+
+go 1.22
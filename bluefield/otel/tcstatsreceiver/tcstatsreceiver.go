@@ -0,0 +1,271 @@
+package tcstatsreceiver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+
+	"otelcommon/metricappend"
+)
+
+const scopeName = "tcstatsreceiver"
+
+// qdisc kinds that have classes
+var classfulKinds = map[string]bool{
+	"htb":  true,
+	"hfsc": true,
+	"drr":  true,
+	"qfq":  true,
+	"ets":  true,
+	"prio": true,
+}
+
+type tcStatsReceiver struct {
+	config       *Config
+	reInterface  *regexp.Regexp
+	logger       *zap.Logger
+	nextConsumer consumer.Metrics
+	startTime    pcommon.Timestamp
+	stopChannel  chan struct{}
+	stopWaiters  sync.WaitGroup
+}
+
+// tcStats are the statistics common to qdiscs and classes as output by
+// "tc -s -j".
+type tcStats struct {
+	Handle     string `json:"handle"`
+	Parent     string `json:"parent"`
+	Root       bool   `json:"root"`
+	Bytes      int64  `json:"bytes"`
+	Packets    int64  `json:"packets"`
+	Drops      int64  `json:"drops"`
+	Overlimits int64  `json:"overlimits"`
+	Requeues   int64  `json:"requeues"`
+	Backlog    int64  `json:"backlog"`
+	Qlen       int64  `json:"qlen"`
+}
+
+type qdisc struct {
+	tcStats
+	Kind string `json:"kind"`
+	Dev  string `json:"dev"`
+}
+
+type class struct {
+	tcStats
+	Kind string `json:"class"`
+}
+
+// metricSet holds the metrics of one object type, "qdisc" or "class".
+type metricSet struct {
+	bytes      pmetric.NumberDataPointSlice
+	packets    pmetric.NumberDataPointSlice
+	drops      pmetric.NumberDataPointSlice
+	overlimits pmetric.NumberDataPointSlice
+	requeues   pmetric.NumberDataPointSlice
+	backlog    pmetric.NumberDataPointSlice
+	qlen       pmetric.NumberDataPointSlice
+}
+
+func newTcStatsReceiver(
+	config *Config,
+	reInterface *regexp.Regexp,
+	logger *zap.Logger,
+	nextConsumer consumer.Metrics,
+) *tcStatsReceiver {
+	return &tcStatsReceiver{
+		config:       config,
+		reInterface:  reInterface,
+		logger:       logger,
+		nextConsumer: nextConsumer,
+		stopChannel:  make(chan struct{}),
+	}
+}
+
+func (r *tcStatsReceiver) Start(_ context.Context, _ component.Host) error {
+	r.startTime = pcommon.NewTimestampFromTime(time.Now())
+	r.stopWaiters.Add(1)
+	go r.collectLoop()
+	return nil
+}
+
+func (r *tcStatsReceiver) Shutdown(context.Context) error {
+	close(r.stopChannel)
+	r.stopWaiters.Wait()
+	return nil
+}
+
+func (r *tcStatsReceiver) collectLoop() {
+	defer r.stopWaiters.Done()
+
+	ticker := time.NewTicker(r.config.CollectionInterval)
+	defer ticker.Stop()
+
+	r.collect()
+	for {
+		select {
+		case <-ticker.C:
+			r.collect()
+		case <-r.stopChannel:
+			return
+		}
+	}
+}
+
+func (r *tcStatsReceiver) collect() {
+	ctx, cancel := context.WithTimeout(context.Background(),
+		r.config.CollectionInterval)
+	defer cancel()
+
+	var qdiscs []qdisc
+	if err := r.tc(ctx, &qdiscs, "qdisc", "show"); err != nil {
+		r.logger.Error("Failed to query qdisc statistics", zap.Error(err))
+		return
+	}
+
+	now := pcommon.NewTimestampFromTime(time.Now())
+	md := pmetric.NewMetrics()
+	sm := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty()
+	sm.Scope().SetName(scopeName)
+	sm.Scope().SetVersion(Version)
+
+	qdiscMetrics := newMetricSet(sm.Metrics(), "qdisc")
+	classfulDevs := make(map[string]bool)
+	for _, q := range qdiscs {
+		if !r.reInterface.MatchString(q.Dev) {
+			continue
+		}
+		attrs := pcommon.NewMap()
+		attrs.PutStr("interface", q.Dev)
+		attrs.PutStr("qdisc.kind", q.Kind)
+		putHandleAttributes(attrs, "qdisc", q.tcStats)
+		r.appendStats(qdiscMetrics, q.tcStats, attrs, now)
+		if classfulKinds[q.Kind] {
+			classfulDevs[q.Dev] = true
+		}
+	}
+
+	if r.config.IncludeClasses && len(classfulDevs) > 0 {
+		classMetrics := newMetricSet(sm.Metrics(), "class")
+		devs := make([]string, 0, len(classfulDevs))
+		for dev := range classfulDevs {
+			devs = append(devs, dev)
+		}
+		sort.Strings(devs)
+		for _, dev := range devs {
+			var classes []class
+			if err := r.tc(ctx, &classes, "class", "show", "dev", dev); err != nil {
+				r.logger.Error("Failed to query class statistics",
+					zap.String("interface", dev), zap.Error(err))
+				continue
+			}
+			for _, c := range classes {
+				attrs := pcommon.NewMap()
+				attrs.PutStr("interface", dev)
+				attrs.PutStr("class.kind", c.Kind)
+				putHandleAttributes(attrs, "class", c.tcStats)
+				r.appendStats(classMetrics, c.tcStats, attrs, now)
+			}
+		}
+	}
+
+	if md.DataPointCount() == 0 {
+		return
+	}
+	if err := r.nextConsumer.ConsumeMetrics(ctx, md); err != nil {
+		r.logger.Error("Failed to consume tc statistics", zap.Error(err))
+	}
+}
+
+func newMetricSet(metrics pmetric.MetricSlice, object string) metricSet {
+	prefix := "tc." + object + "."
+	return metricSet{
+		bytes: metricappend.Sum(metrics, prefix+"bytes", "By",
+			"Bytes sent by the "+object),
+		packets: metricappend.Sum(metrics, prefix+"packets", "{packets}",
+			"Packets sent by the "+object),
+		drops: metricappend.Sum(metrics, prefix+"drops", "{packets}",
+			"Packets dropped by the "+object),
+		overlimits: metricappend.Sum(metrics, prefix+"overlimits", "{events}",
+			"Times the "+object+" exceeded its limits"),
+		requeues: metricappend.Sum(metrics, prefix+"requeues", "{packets}",
+			"Packets requeued by the "+object),
+		backlog: metricappend.Gauge(metrics, prefix+"backlog", "By",
+			"Bytes queued in the "+object),
+		qlen: metricappend.Gauge(metrics, prefix+"qlen", "{packets}",
+			"Packets queued in the "+object),
+	}
+}
+
+func (r *tcStatsReceiver) appendStats(
+	metrics metricSet,
+	stats tcStats,
+	attrs pcommon.Map,
+	now pcommon.Timestamp,
+) {
+	for _, sum := range []struct {
+		dps   pmetric.NumberDataPointSlice
+		value int64
+	}{
+		{metrics.bytes, stats.Bytes},
+		{metrics.packets, stats.Packets},
+		{metrics.drops, stats.Drops},
+		{metrics.overlimits, stats.Overlimits},
+		{metrics.requeues, stats.Requeues},
+	} {
+		dp := sum.dps.AppendEmpty()
+		dp.SetStartTimestamp(r.startTime)
+		dp.SetTimestamp(now)
+		dp.SetIntValue(sum.value)
+		attrs.CopyTo(dp.Attributes())
+	}
+	for _, gauge := range []struct {
+		dps   pmetric.NumberDataPointSlice
+		value int64
+	}{
+		{metrics.backlog, stats.Backlog},
+		{metrics.qlen, stats.Qlen},
+	} {
+		dp := gauge.dps.AppendEmpty()
+		dp.SetTimestamp(now)
+		dp.SetIntValue(gauge.value)
+		attrs.CopyTo(dp.Attributes())
+	}
+}
+
+func putHandleAttributes(attrs pcommon.Map, object string, stats tcStats) {
+	attrs.PutStr(object+".handle", stats.Handle)
+	if stats.Root {
+		attrs.PutStr(object+".parent", "root")
+	} else {
+		attrs.PutStr(object+".parent", stats.Parent)
+	}
+}
+
+// tc runs tc with statistics in JSON output and decodes the output into v.
+func (r *tcStatsReceiver) tc(ctx context.Context, v any, args ...string) error {
+	cmd := exec.CommandContext(ctx, r.config.TcPath,
+		append([]string{"-s", "-j"}, args...)...)
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return fmt.Errorf("%w: %s", err, exitErr.Stderr)
+		}
+		return err
+	}
+	if err := json.Unmarshal(output, v); err != nil {
+		return fmt.Errorf("failed to parse tc output: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,3 @@
+package tcstatsreceiver
+
+const Version = "0.0.1"
@@ -0,0 +1,120 @@
+package telemetrystatsprocessor
+
+import (
+	"math"
+	"math/bits"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// hllPrecision is the number of hash bits selecting a register of the
+// HyperLogLog sketches. 2^14 registers of one byte estimate cardinalities with
+// a standard error of about 0.8%.
+const hllPrecision = 14
+
+// hyperLogLog is a HyperLogLog sketch of 64-bit hashes.
+type hyperLogLog struct {
+	registers [1 << hllPrecision]uint8
+}
+
+func (h *hyperLogLog) add(hash uint64) {
+	index := hash >> (64 - hllPrecision)
+	// the position of the first set bit among the remaining bits, with a
+	// sentinel bit so that a hash of zeros has a position
+	rank := uint8(bits.LeadingZeros64(hash<<hllPrecision|1<<(hllPrecision-1))) + 1
+	if rank > h.registers[index] {
+		h.registers[index] = rank
+	}
+}
+
+// estimate returns the estimated number of distinct hashes added to the
+// sketch or to other, as if they were merged.
+func (h *hyperLogLog) estimate(other *hyperLogLog) int64 {
+	const m = float64(len(h.registers))
+	sum := 0.0
+	zeros := 0
+	for i, register := range h.registers {
+		if other != nil && other.registers[i] > register {
+			register = other.registers[i]
+		}
+		sum += math.Ldexp(1, -int(register))
+		if register == 0 {
+			zeros++
+		}
+	}
+	alpha := 0.7213 / (1 + 1.079/m)
+	estimate := alpha * m * m / sum
+	// linear counting is more accurate for small cardinalities
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return int64(math.Round(estimate))
+}
+
+// seriesEstimate estimates the distinct series of a metric grouping seen in
+// the current and previous cardinality windows, so that the estimate covers
+// at least one whole window and doesn't drop when a window starts.
+type seriesEstimate struct {
+	current  *hyperLogLog
+	previous *hyperLogLog
+	started  time.Time
+}
+
+func newSeriesEstimate(now time.Time) *seriesEstimate {
+	return &seriesEstimate{current: &hyperLogLog{}, started: now}
+}
+
+// rotate starts a new window once the current one is over.
+func (e *seriesEstimate) rotate(now time.Time, window time.Duration) {
+	if now.Sub(e.started) < window {
+		return
+	}
+	e.previous = e.current
+	e.current = &hyperLogLog{}
+	e.started = now
+}
+
+func (e *seriesEstimate) estimate() int64 {
+	return e.current.estimate(e.previous)
+}
+
+// seriesHash returns the hash of the series of a datapoint: the metric name
+// and the attributes of the datapoint and resource. Attributes are combined
+// independently of their order.
+func seriesHash(metric pmetric.Metric, attrs *Attributes) uint64 {
+	h := mix64(hashString(metric.Name())) + mix64(hashString(attrs.resourceHash()))
+	attrs.datapoint.Range(func(k string, v pcommon.Value) bool {
+		var value string
+		if v.Type() == pcommon.ValueTypeStr {
+			value = v.Str()
+		} else {
+			value = v.AsString()
+		}
+		h += mix64(hashString(k) ^ mix64(hashString(value)))
+		return true
+	})
+	return mix64(h)
+}
+
+// hashString returns the 64-bit FNV-1a hash of a string.
+func hashString(s string) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= 1099511628211
+	}
+	return h
+}
+
+// mix64 is the splitmix64 finalizer, spreading the bits of FNV hashes over
+// the whole word as HyperLogLog requires.
+func mix64(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
@@ -3,13 +3,23 @@ package telemetrystatsprocessor
 import (
 	"errors"
 	"fmt"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
 
 	"go.opentelemetry.io/collector/component"
+
+	"otelcommon/filter"
+	"otelcommon/httpregistry"
 )
 
 // Config defines the configuration of the telemetry_stats processor.
 type Config struct {
+	// GroupingTemplates define settings shared by several metric or log
+	// groupings, which reference them by name with `template`.
+	GroupingTemplates []GroupingTemplate `mapstructure:"grouping_templates"`
+
 	// MetricGroupings configure which grouping or groupings of metrics
 	// are counted, if any.
 	MetricGroupings []MetricGrouping `mapstructure:"metric_groupings"`
@@ -20,6 +30,11 @@ type Config struct {
 	// is configured. Defaults to "1m".
 	MetricScrapeInterval time.Duration `mapstructure:"metric_scrape_interval"`
 
+	// CardinalityWindow configures how long series are remembered by
+	// metric groupings with `estimate_cardinality`. Series not seen for
+	// one to two windows no longer count as active. Defaults to "5m".
+	CardinalityWindow time.Duration `mapstructure:"cardinality_window"`
+
 	// LogGroupings configure which grouping or groupings of logs are
 	// counted, if any.
 	LogGroupings []LogGrouping `mapstructure:"log_groupings"`
@@ -35,10 +50,94 @@ type Config struct {
 	// resulting from `log_stats_port`.
 	LogStatsEndpoint string `mapstructure:"log_stats_endpoint"`
 
+	// LogStatsTLS optionally serves the prometheus endpoint over HTTPS
+	// with a certificate and key, and requires client certificates
+	// signed by a CA if one is configured. The debug and push endpoints
+	// use it too when they are the same as the log stats endpoint.
+	LogStatsTLS httpregistry.TLSConfig `mapstructure:"log_stats_tls"`
+
+	// LogStatsAuth optionally requires requests to the prometheus endpoint
+	// to authenticate, with a bearer token, basic auth credentials or a
+	// collector auth extension.
+	LogStatsAuth LogStatsAuth `mapstructure:"log_stats_auth"`
+
+	// LogStatsExport configures how log stats are exported: "prometheus"
+	// serves them at the log stats endpoint for a prometheus receiver to
+	// scrape, "otlp" pushes them as metrics to the OTLP destination
+	// configured in `log_stats_otlp`, and "logs" emits them as log
+	// records into the logs pipeline of the processor, both without a
+	// local endpoint. Defaults to "prometheus".
+	LogStatsExport string `mapstructure:"log_stats_export"`
+
+	// LogStatsOTLP configures the OTLP destination log stats are pushed
+	// to with `log_stats_export: otlp`.
+	LogStatsOTLP LogStatsOTLP `mapstructure:"log_stats_otlp"`
+
+	// LogStatsInterval configures how often log stats are emitted as log
+	// records with `log_stats_export: logs`. Defaults to "1m".
+	LogStatsInterval time.Duration `mapstructure:"log_stats_interval"`
+
 	// Labels is an optional list of labels to add to all telemetry stats
 	// as resource attributes.
 	Labels []Label `mapstructure:"labels"`
 
+	// MetricPrefix is the namespace of the names of the stats, e.g.
+	// "telemetry_stats" for telemetry_stats_datapoints_total, so that
+	// instances with different purposes don't collide. Metrics with the
+	// prefix are not counted. Defaults to "telemetry_stats".
+	MetricPrefix string `mapstructure:"metric_prefix"`
+
+	// Temporality configures the aggregation temporality of the counters
+	// reported as OTLP sums, i.e. the metric stats and the log stats
+	// pushed with `log_stats_export: otlp`: "cumulative", or "delta" for
+	// the increments since the previous report, for backends preferring
+	// delta sums. Log stats served at the prometheus endpoint stay
+	// cumulative. Defaults to "cumulative".
+	Temporality string `mapstructure:"temporality"`
+
+	// DebugEndpoint optionally serves the resource attributes of each
+	// `resource_hash` counted by groupings with `by_resource` as JSON at
+	// http://<endpoint>/debug/telemetry_stats/resources, and the controls
+	// enabling and disabling groupings at runtime at
+	// http://<endpoint>/debug/telemetry_stats/groupings. It may be the
+	// same as the log stats endpoint.
+	DebugEndpoint string `mapstructure:"debug_endpoint"`
+
+	// PushEndpoint optionally accepts counter increments pushed by local
+	// agents as JSON at http://<endpoint>/v1/counters, where the endpoint
+	// is "localhost:<port>" or a unix socket "unix:<path>". Pushed
+	// counters are reported along with the stats of the processor,
+	// labeled with the `source` the agent pushed them as. It may be the
+	// same as the log stats endpoint.
+	PushEndpoint string `mapstructure:"push_endpoint"`
+
+	// MaxPushedCounters limits the number of distinct pushed counters,
+	// counting each combination of source, name and labels once. Pushes
+	// that would exceed it are rejected. Defaults to 1000.
+	MaxPushedCounters int `mapstructure:"max_pushed_counters"`
+
+	// SummaryLogInterval optionally configures how often a structured
+	// record summarizing the counts of the interval is written to the
+	// collector's own log, so that environments only collecting logs get
+	// telemetry accounting too. Disabled unless specified.
+	SummaryLogInterval time.Duration `mapstructure:"summary_log_interval"`
+
+	// SummaryLogTopGroupings limits the groupings listed in the summary
+	// to those with the largest counts in the interval. Defaults to 5.
+	SummaryLogTopGroupings int `mapstructure:"summary_log_top_groupings"`
+
+	// CountResources configures whether the resource entries of each
+	// batch (ResourceLogs or ResourceMetrics) are counted as well, as
+	// `telemetry_stats_resources_total` labeled with the `signal`, since
+	// batches of many small resources cost exporters more than their
+	// record counts suggest.
+	CountResources bool `mapstructure:"count_resources"`
+
+	// CountScopes configures whether the scope entries of each batch are
+	// counted as well, as `telemetry_stats_scopes_total` labeled with the
+	// `signal`.
+	CountScopes bool `mapstructure:"count_scopes"`
+
 	// IncludeTelemetryStats configures whether reported stats should
 	// include self reporting about telemetry_stats exactly like reporting
 	// about processed metric datapoints.
@@ -48,12 +147,20 @@ type Config struct {
 // ensure that Config implements the component.Config interface
 var _ component.Config = (*Config)(nil)
 
+// metricPrefixRegex matches the valid prometheus metric names, and thus
+// prefixes of them.
+var metricPrefixRegex = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
 // MetricGrouping defines a single grouping of metrics about metrics.
 type MetricGrouping struct {
 	// Name is the grouping name that appears as a datapoint attribute
 	// `grouping="<name>"` on generated metric stats.
 	Name string `mapstructure:"name"`
 
+	// Template optionally names a grouping template whose settings the
+	// grouping inherits unless it overrides them.
+	Template string `mapstructure:"template"`
+
 	// ByMetricName configures whether metrics are counted by name, and it
 	// appears as a datapoint attribute `metric_name="<name>"` on generated
 	// stats.
@@ -69,6 +176,95 @@ type MetricGrouping struct {
 	// attributes `<label-name>="<label-value>"` on generated stats.
 	ByLabel *ByLabel `mapstructure:"by_label"`
 
+	// ByResource configures whether metrics are counted by resource, and
+	// a stable hash of all resource attributes appears as a datapoint
+	// attribute `resource_hash="<hash>"` on generated stats. It is
+	// useful when it isn't known in advance which attributes distinguish
+	// resources.
+	ByResource bool `mapstructure:"by_resource"`
+
+	// ByReceiver configures whether metrics are counted by the receiver
+	// they originate from, as stamped by a receiver_stamp processor
+	// earlier in the pipeline, and it appears as a datapoint attribute
+	// `receiver="<name>"` on generated stats. Metrics without a stamp are
+	// counted without the attribute.
+	ByReceiver bool `mapstructure:"by_receiver"`
+
+	// CountPoints configures whether the points each datapoint consists
+	// of are counted as well, as `telemetry_stats_points_total` with the
+	// same attributes as the datapoint counts. Histogram datapoints
+	// consist of one point per bucket, summary datapoints of one point
+	// per quantile, and other datapoints of a single point.
+	CountPoints bool `mapstructure:"count_points"`
+
+	// CountBytes configures whether the serialized size of each datapoint
+	// is accumulated as well, as `telemetry_stats_bytes_total` with the
+	// same attributes as the datapoint counts. The size is that of the
+	// datapoint in an OTLP protobuf request, without exemplars and the
+	// resource, scope and metric it shares with other datapoints.
+	CountBytes bool `mapstructure:"count_bytes"`
+
+	// EstimateCardinality configures whether the distinct series of the
+	// grouping, each a metric name with a set of datapoint and resource
+	// attributes, are estimated with a HyperLogLog sketch and reported as
+	// the gauge `telemetry_stats_active_series` labeled with the
+	// grouping. The estimate covers the series seen in the current and
+	// previous `cardinality_window`.
+	EstimateCardinality bool `mapstructure:"estimate_cardinality"`
+
+	// ValueStats optionally lists statistics of the values of the gauge
+	// and sum datapoints of each key over each scrape interval, reported
+	// as gauges `telemetry_stats_value_<stat>` with the same attributes as
+	// the datapoint counts: "min", "max", "avg", "sum" and "count", or
+	// "summary" for all of them, e.g. to spot outlier sensor readings.
+	ValueStats []string `mapstructure:"value_stats"`
+
+	// TopK optionally limits the stats reported for the grouping to the
+	// K keys with the highest datapoint counts, and sums the counts of
+	// the other keys into a single key labeled `other="true"`, for
+	// groupings with more keys than backends should store.
+	TopK int `mapstructure:"top_k"`
+
+	// MaxKeys optionally limits the number of keys the grouping counts, so
+	// that its memory doesn't grow without bound. Once the grouping has
+	// MaxKeys keys, datapoints of new keys are counted in a single key
+	// labeled `grouping_overflow="true"`, and in the
+	// `telemetry_stats_overflow_keys_total` counter of the grouping.
+	MaxKeys int `mapstructure:"max_keys"`
+
+	// KeyTTL optionally evicts the keys of the grouping not updated
+	// within the TTL on the next scrape interval, so that the counts of
+	// series that went away, e.g. of deleted pods, don't stay in memory.
+	// Evicted keys are counted in `telemetry_stats_expired_keys_total`,
+	// and counted from 0 again if they reappear.
+	KeyTTL time.Duration `mapstructure:"key_ttl"`
+
+	// StalenessMarkers configures whether the stats of evicted keys are
+	// reported once more without a value, flagged as no recorded value,
+	// which exporters to Prometheus turn into staleness markers, so that
+	// the series end at once rather than after the lookback delta. It
+	// requires `key_ttl`.
+	StalenessMarkers bool `mapstructure:"staleness_markers"`
+
+	// ReportMode configures whether the stats of the grouping are
+	// reported as "cumulative" counters, as "delta" counters of the
+	// increments since the previous scrape interval, as the "rate" per
+	// second of the increments, as gauges named `..._per_second` instead
+	// of `..._total`, or as the "distribution" of the increments over the
+	// keys, as a single exponential histogram per stat named
+	// `..._per_key`. Defaults to "cumulative".
+	ReportMode string `mapstructure:"report_mode"`
+
+	// Unit optionally replaces the unit "1" of the datapoint counts of the
+	// grouping, e.g. "{datapoints}", in the same way as Description.
+	Unit string `mapstructure:"unit"`
+
+	// Description optionally replaces the generic description of the
+	// datapoint counts of the grouping, e.g. for a metric catalog. The
+	// unit and description of rates and distributions are derived from
+	// them. Points, bytes and other stats keep their own.
+	Description string `mapstructure:"description"`
+
 	// Include configures a filter that limits which metrics are included
 	// in the grouping. If unspecified, all metrics are included.
 	Include *MetricFilter `mapstructure:"include"`
@@ -76,6 +272,11 @@ type MetricGrouping struct {
 	// Exclude configures a filter that specifies metrics to exclude from
 	// the grouping. If unspecified, no metrics are excluded.
 	Exclude *MetricFilter `mapstructure:"exclude"`
+
+	// Disabled configures the grouping to not be counted until it is
+	// enabled at runtime on the debug endpoint, e.g. for an expensive
+	// high-cardinality grouping only needed during investigations.
+	Disabled bool `mapstructure:"disabled"`
 }
 
 // LogGrouping defines a single grouping of metrics about logs.
@@ -84,11 +285,169 @@ type LogGrouping struct {
 	// `grouping="<name>"` on generated log stats.
 	Name string `mapstructure:"name"`
 
+	// Template optionally names a grouping template whose by_label,
+	// by_resource, by_receiver, by_severity, patterns, count_bytes, top_k,
+	// max_keys, key_ttl, report_mode, unit and description settings the
+	// grouping inherits unless it overrides them. The metric settings of
+	// the template are ignored.
+	Template string `mapstructure:"template"`
+
 	// ByLabel configures whether logs are counted by distinct values of
 	// labels applied earlier in the pipeline, and they appear as log
 	// record attributes `<label-name>="<label-value>"` on generated
 	// stats.
 	ByLabel *ByLabel `mapstructure:"by_label"`
+
+	// ByResource configures whether logs are counted by resource, and a
+	// stable hash of all resource attributes appears as a log record
+	// attribute `resource_hash="<hash>"` on generated stats.
+	ByResource bool `mapstructure:"by_resource"`
+
+	// ByReceiver configures whether logs are counted by the receiver they
+	// originate from, as stamped by a receiver_stamp processor earlier in
+	// the pipeline, and it appears as a log record attribute
+	// `receiver="<name>"` on generated stats.
+	ByReceiver bool `mapstructure:"by_receiver"`
+
+	// BySeverity configures whether logs are counted by severity, and the
+	// severity number and text of log records appear as log record
+	// attributes `severity_number="<number>"` and
+	// `severity_text="<text>"` on generated stats. Records without a
+	// severity text are counted without the attribute.
+	BySeverity bool `mapstructure:"by_severity"`
+
+	// Patterns optionally classifies logs by the first of the named
+	// regular expressions matching their body, and the name of the
+	// pattern appears as a log record attribute `pattern="<name>"` on
+	// generated stats. Records matching no pattern are counted without
+	// the attribute. Bodies that aren't strings are matched in their
+	// string form, e.g. JSON for maps.
+	Patterns []LogPattern `mapstructure:"patterns"`
+
+	// CountBytes configures whether the serialized size of each log
+	// record is accumulated as well, as `telemetry_stats_bytes_total`
+	// with the same attributes as the log record counts. The size is
+	// that of the log record in an OTLP protobuf request, without the
+	// resource and scope it shares with other log records.
+	CountBytes bool `mapstructure:"count_bytes"`
+
+	// TopK optionally limits the stats reported for the grouping to the
+	// K keys with the highest log record counts, and sums the counts of
+	// the other keys into a single key labeled `other="true"`.
+	TopK int `mapstructure:"top_k"`
+
+	// MaxKeys optionally limits the number of keys the grouping counts, so
+	// that its memory doesn't grow without bound. Once the grouping has
+	// MaxKeys keys, log records of new keys are counted in a single key
+	// labeled `grouping_overflow="true"`, and in the
+	// `telemetry_stats_overflow_keys_total` counter of the grouping.
+	MaxKeys int `mapstructure:"max_keys"`
+
+	// KeyTTL optionally evicts the keys of the grouping not updated
+	// within the TTL on the next request to the prometheus endpoint, so
+	// that the counts of series that went away don't stay in memory.
+	// Evicted keys are counted in `telemetry_stats_expired_keys_total`,
+	// and counted from 0 again if they reappear. Prometheus marks the
+	// series of evicted keys stale itself.
+	KeyTTL time.Duration `mapstructure:"key_ttl"`
+
+	// ReportMode configures whether the stats of the grouping are
+	// reported as "cumulative" counters, as "delta" increments since the
+	// previous request to the prometheus endpoint, or as the "rate" per
+	// second of the increments, named `..._per_second` instead of
+	// `..._total`. Defaults to "cumulative".
+	ReportMode string `mapstructure:"report_mode"`
+
+	// Unit optionally replaces the unit "1" of the log record counts of
+	// the grouping, e.g. "{records}", in the same way as Description.
+	Unit string `mapstructure:"unit"`
+
+	// Description optionally replaces the generic description of the log
+	// record counts of the grouping. The unit and description of rates
+	// are derived from them. Bytes and other stats keep their own.
+	Description string `mapstructure:"description"`
+
+	// Disabled configures the grouping to not be counted until it is
+	// enabled at runtime on the debug endpoint.
+	Disabled bool `mapstructure:"disabled"`
+}
+
+// GroupingTemplate defines settings shared by several groupings. A grouping
+// referencing the template inherits its settings, and overrides them with its
+// own: `by_metric_name`, `by_metric_type`, `by_resource`, `by_receiver`,
+// `by_severity`, `count_points`, `count_bytes`, `estimate_cardinality` and
+// `staleness_markers` can be enabled but not disabled, `by_label`, `patterns`,
+// `top_k`, `max_keys`, `key_ttl`, `report_mode`, `unit` and `description`
+// replace the template's, and each field specified in `include` or `exclude`
+// replaces that field of the template's filter.
+type GroupingTemplate struct {
+	// Name identifies the template in the `template` setting of
+	// groupings and other templates.
+	Name string `mapstructure:"name"`
+
+	// Template optionally names another template this template inherits
+	// from, in the same way as groupings do.
+	Template string `mapstructure:"template"`
+
+	// ByMetricName is inherited by metric groupings.
+	ByMetricName bool `mapstructure:"by_metric_name"`
+
+	// ByMetricType is inherited by metric groupings.
+	ByMetricType bool `mapstructure:"by_metric_type"`
+
+	// ByLabel is inherited by metric and log groupings.
+	ByLabel *ByLabel `mapstructure:"by_label"`
+
+	// ByResource is inherited by metric and log groupings.
+	ByResource bool `mapstructure:"by_resource"`
+
+	// ByReceiver is inherited by metric and log groupings.
+	ByReceiver bool `mapstructure:"by_receiver"`
+
+	// BySeverity is inherited by log groupings.
+	BySeverity bool `mapstructure:"by_severity"`
+
+	// Patterns is inherited by log groupings.
+	Patterns []LogPattern `mapstructure:"patterns"`
+
+	// CountPoints is inherited by metric groupings.
+	CountPoints bool `mapstructure:"count_points"`
+
+	// CountBytes is inherited by metric and log groupings.
+	CountBytes bool `mapstructure:"count_bytes"`
+
+	// EstimateCardinality is inherited by metric groupings.
+	EstimateCardinality bool `mapstructure:"estimate_cardinality"`
+
+	// ValueStats is inherited by metric groupings.
+	ValueStats []string `mapstructure:"value_stats"`
+
+	// TopK is inherited by metric and log groupings.
+	TopK int `mapstructure:"top_k"`
+
+	// MaxKeys is inherited by metric and log groupings.
+	MaxKeys int `mapstructure:"max_keys"`
+
+	// KeyTTL is inherited by metric and log groupings.
+	KeyTTL time.Duration `mapstructure:"key_ttl"`
+
+	// StalenessMarkers is inherited by metric groupings.
+	StalenessMarkers bool `mapstructure:"staleness_markers"`
+
+	// ReportMode is inherited by metric and log groupings.
+	ReportMode string `mapstructure:"report_mode"`
+
+	// Unit is inherited by metric and log groupings.
+	Unit string `mapstructure:"unit"`
+
+	// Description is inherited by metric and log groupings.
+	Description string `mapstructure:"description"`
+
+	// Include is inherited by metric groupings.
+	Include *MetricFilter `mapstructure:"include"`
+
+	// Exclude is inherited by metric groupings.
+	Exclude *MetricFilter `mapstructure:"exclude"`
 }
 
 // ByLabel defines which labels to group by.
@@ -98,17 +457,66 @@ type ByLabel struct {
 	Names []string `mapstructure:"names"`
 }
 
-// MetricFilter defines criteria to limit which metrics are included in the grouping.
-type MetricFilter struct {
-	// MetricNames is a list of metric names to filter by.
-	MetricNames []string `mapstructure:"metric_names"`
-	// MetricRegex is a regular expression that matches metric names to filter by.
-	MetricRegex string `mapstructure:"metric_regex"`
-	// MetricTypes is a list of metric types (Counter, Gauge, Histogram, or
-	// Summary) to filter by.
-	MetricTypes []string `mapstructure:"metric_types"`
-	// Labels is a list of label name and values to filter by.
-	Labels []LabelFilter `mapstructure:"labels"`
+// LogPattern defines a named regular expression classifying log bodies.
+type LogPattern struct {
+	// Name is the pattern name that appears as a log record attribute
+	// `pattern="<name>"` on generated stats, e.g. "oom_kill".
+	Name string `mapstructure:"name"`
+
+	// Regex is the regular expression, in RE2 syntax, matched anywhere
+	// in the body.
+	Regex string `mapstructure:"regex"`
+}
+
+// MetricFilter defines criteria to limit which metrics are included in the
+// grouping, matched as by other processors sharing otelcommon/filter.
+type MetricFilter = filter.MetricFilter
+
+// LogStatsAuth defines how requests to the prometheus endpoint authenticate.
+// The debug and push endpoints accept the same bearer token or credentials
+// when they are the same as the log stats endpoint.
+type LogStatsAuth struct {
+	httpregistry.AuthConfig `mapstructure:",squash"`
+
+	// Authenticator optional ID of a collector auth extension, e.g.
+	// "basicauth/scrapers", authenticating requests instead
+	Authenticator string `mapstructure:"authenticator"`
+}
+
+// Validate checks that the credentials are usable and the authenticator is
+// an extension ID, and not combined with credentials.
+func (cfg *LogStatsAuth) Validate() error {
+	if err := cfg.AuthConfig.Validate(); err != nil {
+		return err
+	}
+	if cfg.Authenticator == "" {
+		return nil
+	}
+	if cfg.AuthConfig.Enabled() {
+		return errors.New("authenticator cannot be combined with " +
+			"bearer_token or username")
+	}
+	var id component.ID
+	if err := id.UnmarshalText([]byte(cfg.Authenticator)); err != nil {
+		return fmt.Errorf("invalid authenticator: %w", err)
+	}
+	return nil
+}
+
+// LogStatsOTLP defines the OTLP destination log stats are pushed to.
+type LogStatsOTLP struct {
+	// Protocol is the OTLP protocol, "grpc" or "http". Defaults to
+	// "grpc".
+	Protocol string `mapstructure:"protocol"`
+
+	// Interval configures how often log stats are pushed. Defaults to
+	// "1m".
+	Interval time.Duration `mapstructure:"interval"`
+
+	// Exporter configures the OTLP exporter exactly as the otlp exporter
+	// for "grpc" or the otlphttp exporter for "http", e.g. its endpoint,
+	// tls, headers, sending_queue and retry_on_failure.
+	Exporter map[string]any `mapstructure:"exporter"`
 }
 
 // Label defines a label as a key-value pair.
@@ -121,14 +529,7 @@ type Label struct {
 
 // LabelFilter defines label criteria to limit which metrics are included in
 // the grouping.
-type LabelFilter struct {
-	// Name is the label name
-	Name string `mapstructure:"name"`
-	// Values is a list of label values to filter by.
-	Values []string `mapstructure:"values"`
-	// ValueRegex is a regular expression that matches label values to filter by.
-	ValueRegex string `mapstructure:"value_regex"`
-}
+type LabelFilter = filter.LabelFilter
 
 // Validate implements the component.Config interface by checking whether the
 // configuration is valid.
@@ -142,7 +543,33 @@ func (cfg *Config) Validate() error {
 				"groupings are configured")
 		}
 	}
-	if len(cfg.LogGroupings) > 0 {
+	switch cfg.LogStatsExport {
+	case logStatsExportPrometheus, logStatsExportOTLP, logStatsExportLogs:
+	default:
+		return fmt.Errorf("log_stats_export must be %q, %q or %q",
+			logStatsExportPrometheus, logStatsExportOTLP, logStatsExportLogs)
+	}
+	if len(cfg.LogGroupings) > 0 && cfg.LogStatsExport != logStatsExportPrometheus {
+		if cfg.LogStatsEndpoint != "" || cfg.LogStatsPort != 0 ||
+			cfg.LogStatsTLS != (httpregistry.TLSConfig{}) ||
+			cfg.LogStatsAuth != (LogStatsAuth{}) {
+			return fmt.Errorf("log_stats_endpoint, log_stats_port, "+
+				"log_stats_tls and log_stats_auth cannot be specified "+
+				"with log_stats_export %q", cfg.LogStatsExport)
+		}
+	}
+	if len(cfg.LogGroupings) > 0 && cfg.LogStatsExport == logStatsExportOTLP {
+		if cfg.LogStatsOTLP.Interval <= 0 {
+			return errors.New("log_stats_otlp interval must be positive")
+		}
+		if _, _, err := cfg.LogStatsOTLP.exporterConfig(); err != nil {
+			return fmt.Errorf("invalid log_stats_otlp: %w", err)
+		}
+	} else if len(cfg.LogGroupings) > 0 && cfg.LogStatsExport == logStatsExportLogs {
+		if cfg.LogStatsInterval <= 0 {
+			return errors.New("log_stats_interval must be positive")
+		}
+	} else if len(cfg.LogGroupings) > 0 {
 		if cfg.LogStatsEndpoint == "" && cfg.LogStatsPort == 0 {
 			return errors.New("either log_stats_endpoint or log_stats_port " +
 				"must be specified when log groupings are configured")
@@ -152,19 +579,358 @@ func (cfg *Config) Validate() error {
 				"log_stats_port should be specified")
 		}
 	}
+	switch cfg.Temporality {
+	case temporalityCumulative, temporalityDelta:
+	default:
+		return fmt.Errorf("temporality must be %q or %q",
+			temporalityCumulative, temporalityDelta)
+	}
+	if !metricPrefixRegex.MatchString(cfg.MetricPrefix) {
+		return fmt.Errorf("metric_prefix %q is not a valid prometheus "+
+			"metric name", cfg.MetricPrefix)
+	}
+	if err := cfg.LogStatsTLS.Validate(); err != nil {
+		return fmt.Errorf("invalid log_stats_tls: %w", err)
+	}
+	if err := cfg.LogStatsAuth.Validate(); err != nil {
+		return fmt.Errorf("invalid log_stats_auth: %w", err)
+	}
+	if cfg.SummaryLogInterval < 0 {
+		return errors.New("summary_log_interval cannot be negative")
+	}
+	if cfg.SummaryLogTopGroupings < 0 {
+		return errors.New("summary_log_top_groupings cannot be negative")
+	}
+	if cfg.PushEndpoint != "" && cfg.MaxPushedCounters <= 0 {
+		return errors.New("max_pushed_counters must be positive when " +
+			"push_endpoint is configured")
+	}
 	for _, g := range cfg.MetricGroupings {
 		if g.Name == "" {
 			return errors.New("grouping name cannot be empty")
 		}
+		if g.TopK < 0 {
+			return fmt.Errorf("grouping %s: top_k cannot be negative", g.Name)
+		}
+		if g.MaxKeys < 0 {
+			return fmt.Errorf("grouping %s: max_keys cannot be negative", g.Name)
+		}
+		if g.KeyTTL < 0 {
+			return fmt.Errorf("grouping %s: key_ttl cannot be negative", g.Name)
+		}
+		if err := validateReportMode(g.ReportMode); err != nil {
+			return fmt.Errorf("grouping %s: %w", g.Name, err)
+		}
+		if err := validateValueStats(g.ValueStats); err != nil {
+			return fmt.Errorf("grouping %s: %w", g.Name, err)
+		}
 	}
 	for _, g := range cfg.LogGroupings {
 		if g.Name == "" {
 			return errors.New("grouping name cannot be empty")
 		}
+		if g.TopK < 0 {
+			return fmt.Errorf("grouping %s: top_k cannot be negative", g.Name)
+		}
+		if g.MaxKeys < 0 {
+			return fmt.Errorf("grouping %s: max_keys cannot be negative", g.Name)
+		}
+		if g.KeyTTL < 0 {
+			return fmt.Errorf("grouping %s: key_ttl cannot be negative", g.Name)
+		}
+		if err := validateReportMode(g.ReportMode); err != nil {
+			return fmt.Errorf("grouping %s: %w", g.Name, err)
+		}
+		if err := validatePatterns(g.Patterns); err != nil {
+			return fmt.Errorf("grouping %s: %w", g.Name, err)
+		}
+	}
+	templateNames := make(map[string]bool)
+	for _, t := range cfg.GroupingTemplates {
+		if t.Name == "" {
+			return errors.New("grouping template name cannot be empty")
+		}
+		if t.TopK < 0 {
+			return fmt.Errorf("grouping template %s: top_k cannot be "+
+				"negative", t.Name)
+		}
+		if t.MaxKeys < 0 {
+			return fmt.Errorf("grouping template %s: max_keys cannot be "+
+				"negative", t.Name)
+		}
+		if t.KeyTTL < 0 {
+			return fmt.Errorf("grouping template %s: key_ttl cannot be "+
+				"negative", t.Name)
+		}
+		if err := validateReportMode(t.ReportMode); err != nil {
+			return fmt.Errorf("grouping template %s: %w", t.Name, err)
+		}
+		if err := validateValueStats(t.ValueStats); err != nil {
+			return fmt.Errorf("grouping template %s: %w", t.Name, err)
+		}
+		if err := validatePatterns(t.Patterns); err != nil {
+			return fmt.Errorf("grouping template %s: %w", t.Name, err)
+		}
+		if templateNames[t.Name] {
+			return fmt.Errorf("grouping template %s is defined more than once",
+				t.Name)
+		}
+		templateNames[t.Name] = true
+	}
+	for _, t := range cfg.GroupingTemplates {
+		if _, err := cfg.resolveTemplate(t.Name, nil); err != nil {
+			return err
+		}
+	}
+	applied, err := cfg.applyTemplates()
+	if err != nil {
+		return err
+	}
+	if _, err := compileMatchers(applied.MetricGroupings); err != nil {
+		return err
+	}
+	if cfg.CardinalityWindow <= 0 && slices.ContainsFunc(applied.MetricGroupings,
+		func(g MetricGrouping) bool { return g.EstimateCardinality }) {
+		return errors.New("cardinality_window must be positive when metric " +
+			"groupings estimate cardinality")
+	}
+	for _, g := range applied.MetricGroupings {
+		if g.StalenessMarkers && g.KeyTTL == 0 {
+			return fmt.Errorf("grouping %s: staleness_markers requires "+
+				"key_ttl", g.Name)
+		}
+	}
+	for _, g := range applied.LogGroupings {
+		// the prometheus text format has no exponential histograms
+		if g.ReportMode == reportModeDistribution {
+			return fmt.Errorf("grouping %s: report_mode %q is only "+
+				"supported by metric groupings", g.Name,
+				reportModeDistribution)
+		}
 	}
 	return nil
 }
 
+// applyTemplates returns a copy of the configuration whose groupings have
+// inherited the settings of the templates they reference.
+func (cfg *Config) applyTemplates() (*Config, error) {
+	applied := *cfg
+	if len(cfg.GroupingTemplates) == 0 {
+		return &applied, nil
+	}
+
+	applied.MetricGroupings = make([]MetricGrouping, 0, len(cfg.MetricGroupings))
+	for _, g := range cfg.MetricGroupings {
+		if g.Template != "" {
+			t, err := cfg.resolveTemplate(g.Template, nil)
+			if err != nil {
+				return nil, fmt.Errorf("grouping %s: %w", g.Name, err)
+			}
+			g.ByMetricName = g.ByMetricName || t.ByMetricName
+			g.ByMetricType = g.ByMetricType || t.ByMetricType
+			g.ByResource = g.ByResource || t.ByResource
+			g.ByReceiver = g.ByReceiver || t.ByReceiver
+			g.CountPoints = g.CountPoints || t.CountPoints
+			g.CountBytes = g.CountBytes || t.CountBytes
+			g.EstimateCardinality = g.EstimateCardinality || t.EstimateCardinality
+			g.StalenessMarkers = g.StalenessMarkers || t.StalenessMarkers
+			if g.TopK == 0 {
+				g.TopK = t.TopK
+			}
+			if g.MaxKeys == 0 {
+				g.MaxKeys = t.MaxKeys
+			}
+			if g.KeyTTL == 0 {
+				g.KeyTTL = t.KeyTTL
+			}
+			if g.ReportMode == "" {
+				g.ReportMode = t.ReportMode
+			}
+			if g.Unit == "" {
+				g.Unit = t.Unit
+			}
+			if g.Description == "" {
+				g.Description = t.Description
+			}
+			if g.ByLabel == nil {
+				g.ByLabel = t.ByLabel
+			}
+			if g.ValueStats == nil {
+				g.ValueStats = t.ValueStats
+			}
+			g.Include = mergeMetricFilter(t.Include, g.Include)
+			g.Exclude = mergeMetricFilter(t.Exclude, g.Exclude)
+		}
+		applied.MetricGroupings = append(applied.MetricGroupings, g)
+	}
+
+	applied.LogGroupings = make([]LogGrouping, 0, len(cfg.LogGroupings))
+	for _, g := range cfg.LogGroupings {
+		if g.Template != "" {
+			t, err := cfg.resolveTemplate(g.Template, nil)
+			if err != nil {
+				return nil, fmt.Errorf("grouping %s: %w", g.Name, err)
+			}
+			if g.ByLabel == nil {
+				g.ByLabel = t.ByLabel
+			}
+			g.ByResource = g.ByResource || t.ByResource
+			g.ByReceiver = g.ByReceiver || t.ByReceiver
+			g.BySeverity = g.BySeverity || t.BySeverity
+			g.CountBytes = g.CountBytes || t.CountBytes
+			if g.TopK == 0 {
+				g.TopK = t.TopK
+			}
+			if g.MaxKeys == 0 {
+				g.MaxKeys = t.MaxKeys
+			}
+			if g.KeyTTL == 0 {
+				g.KeyTTL = t.KeyTTL
+			}
+			if g.ReportMode == "" {
+				g.ReportMode = t.ReportMode
+			}
+			if g.Unit == "" {
+				g.Unit = t.Unit
+			}
+			if g.Description == "" {
+				g.Description = t.Description
+			}
+			if g.Patterns == nil {
+				g.Patterns = t.Patterns
+			}
+		}
+		applied.LogGroupings = append(applied.LogGroupings, g)
+	}
+
+	return &applied, nil
+}
+
+// resolveTemplate returns the named template with the settings inherited from
+// the templates it references applied. seen holds the templates referencing
+// it, to detect cycles.
+func (cfg *Config) resolveTemplate(name string, seen []string) (GroupingTemplate, error) {
+	if slices.Contains(seen, name) {
+		return GroupingTemplate{}, fmt.Errorf("grouping templates %s "+
+			"reference each other", strings.Join(append(seen, name), " -> "))
+	}
+
+	index := slices.IndexFunc(cfg.GroupingTemplates, func(t GroupingTemplate) bool {
+		return t.Name == name
+	})
+	if index < 0 {
+		return GroupingTemplate{}, fmt.Errorf("grouping template %s is not "+
+			"defined", name)
+	}
+	t := cfg.GroupingTemplates[index]
+	if t.Template == "" {
+		return t, nil
+	}
+
+	parent, err := cfg.resolveTemplate(t.Template, append(seen, name))
+	if err != nil {
+		return GroupingTemplate{}, err
+	}
+	t.ByMetricName = t.ByMetricName || parent.ByMetricName
+	t.ByMetricType = t.ByMetricType || parent.ByMetricType
+	t.ByResource = t.ByResource || parent.ByResource
+	t.ByReceiver = t.ByReceiver || parent.ByReceiver
+	t.BySeverity = t.BySeverity || parent.BySeverity
+	t.CountPoints = t.CountPoints || parent.CountPoints
+	t.CountBytes = t.CountBytes || parent.CountBytes
+	t.EstimateCardinality = t.EstimateCardinality || parent.EstimateCardinality
+	t.StalenessMarkers = t.StalenessMarkers || parent.StalenessMarkers
+	if t.TopK == 0 {
+		t.TopK = parent.TopK
+	}
+	if t.MaxKeys == 0 {
+		t.MaxKeys = parent.MaxKeys
+	}
+	if t.KeyTTL == 0 {
+		t.KeyTTL = parent.KeyTTL
+	}
+	if t.ReportMode == "" {
+		t.ReportMode = parent.ReportMode
+	}
+	if t.Unit == "" {
+		t.Unit = parent.Unit
+	}
+	if t.Description == "" {
+		t.Description = parent.Description
+	}
+	if t.ByLabel == nil {
+		t.ByLabel = parent.ByLabel
+	}
+	if t.Patterns == nil {
+		t.Patterns = parent.Patterns
+	}
+	if t.ValueStats == nil {
+		t.ValueStats = parent.ValueStats
+	}
+	t.Include = mergeMetricFilter(parent.Include, t.Include)
+	t.Exclude = mergeMetricFilter(parent.Exclude, t.Exclude)
+	return t, nil
+}
+
+// validateReportMode checks the report_mode of a grouping or template, which
+// is empty if not specified.
+func validateReportMode(mode string) error {
+	switch mode {
+	case "", reportModeCumulative, reportModeDelta, reportModeRate,
+		reportModeDistribution:
+		return nil
+	}
+	return fmt.Errorf("report_mode must be %q, %q, %q or %q",
+		reportModeCumulative, reportModeDelta, reportModeRate,
+		reportModeDistribution)
+}
+
+// validatePatterns checks the patterns of a log grouping or template. Names
+// must be unique, and can't contain the ":" separating the parts of log keys.
+func validatePatterns(patterns []LogPattern) error {
+	names := make(map[string]bool, len(patterns))
+	for _, pattern := range patterns {
+		if pattern.Name == "" || strings.Contains(pattern.Name, ":") {
+			return errors.New("pattern names cannot be empty or contain colons")
+		}
+		if names[pattern.Name] {
+			return fmt.Errorf("pattern %s is defined more than once",
+				pattern.Name)
+		}
+		names[pattern.Name] = true
+		if _, err := regexp.Compile(pattern.Regex); err != nil {
+			return fmt.Errorf("pattern %s: %w", pattern.Name, err)
+		}
+	}
+	return nil
+}
+
+// mergeMetricFilter returns the inherited filter with each field specified in
+// the overriding filter replaced.
+func mergeMetricFilter(inherited, override *MetricFilter) *MetricFilter {
+	if inherited == nil {
+		return override
+	}
+	if override == nil {
+		return inherited
+	}
+
+	merged := *inherited
+	if len(override.MetricNames) > 0 {
+		merged.MetricNames = override.MetricNames
+	}
+	if override.MetricRegex != "" {
+		merged.MetricRegex = override.MetricRegex
+	}
+	if len(override.MetricTypes) > 0 {
+		merged.MetricTypes = override.MetricTypes
+	}
+	if len(override.Labels) > 0 {
+		merged.Labels = override.Labels
+	}
+	return &merged
+}
+
 // GetLogStatsEndpoint gets the prometheus endpoint resulting from
 // `log_stats_port` or set by `log_stats_endpoint`.
 func (cfg *Config) GetLogStatsEndpoint() string {
@@ -177,11 +943,36 @@ func (cfg *Config) GetLogStatsEndpoint() string {
 	return ""
 }
 
+// serverConfig returns the settings of the shared server of an endpoint, which
+// is served over TLS if it is the log stats endpoint and `log_stats_tls` is
+// configured.
+func (cfg *Config) serverConfig(endpoint string) httpregistry.ServerConfig {
+	config := httpregistry.ServerConfig{Endpoint: endpoint}
+	if cfg.LogStatsExport == logStatsExportPrometheus &&
+		endpoint == cfg.GetLogStatsEndpoint() {
+		config.TLS = cfg.LogStatsTLS
+		config.Auth = cfg.LogStatsAuth.AuthConfig
+	}
+	return config
+}
+
 func createDefaultConfig() component.Config {
 	return &Config{
+		GroupingTemplates:    []GroupingTemplate{},
 		MetricGroupings:      []MetricGrouping{},
 		MetricScrapeInterval: 1 * time.Minute,
+		CardinalityWindow:    5 * time.Minute,
 		LogGroupings:         []LogGrouping{},
-		Labels:               []Label{},
+		LogStatsExport:       logStatsExportPrometheus,
+		LogStatsOTLP: LogStatsOTLP{
+			Protocol: logStatsProtocolGRPC,
+			Interval: time.Minute,
+		},
+		LogStatsInterval:       time.Minute,
+		Labels:                 []Label{},
+		MetricPrefix:           typeStr,
+		Temporality:            temporalityCumulative,
+		MaxPushedCounters:      1000,
+		SummaryLogTopGroupings: 5,
 	}
 }
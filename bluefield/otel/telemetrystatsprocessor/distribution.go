@@ -0,0 +1,126 @@
+package telemetrystatsprocessor
+
+import (
+	"math"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+const (
+	// maxDistributionScale is the scale distributions start from, before
+	// being downscaled to fit their observations into
+	// maxDistributionBuckets buckets
+	maxDistributionScale   = 20
+	maxDistributionBuckets = 160
+)
+
+// distribution holds the increments of the keys of a grouping in the
+// `distribution` report mode, as the observations of an exponential
+// histogram.
+type distribution struct {
+	observations []int64
+}
+
+// copyTo sets the buckets, count, sum, min and max of an exponential
+// histogram datapoint. The scale is the highest at which the observations fit
+// into maxDistributionBuckets buckets.
+func (d *distribution) copyTo(dp pmetric.ExponentialHistogramDataPoint) {
+	dp.SetCount(uint64(len(d.observations)))
+	if len(d.observations) == 0 {
+		return
+	}
+
+	var sum int64
+	minimum, maximum := d.observations[0], d.observations[0]
+	var zeros uint64
+	indexes := make([]int32, 0, len(d.observations))
+	for _, value := range d.observations {
+		sum += value
+		minimum = min(minimum, value)
+		maximum = max(maximum, value)
+		if value <= 0 {
+			zeros++
+			continue
+		}
+		indexes = append(indexes, bucketIndex(value, maxDistributionScale))
+	}
+	dp.SetSum(float64(sum))
+	dp.SetMin(float64(minimum))
+	dp.SetMax(float64(maximum))
+	dp.SetZeroCount(zeros)
+	dp.SetScale(maxDistributionScale)
+	if len(indexes) == 0 {
+		return
+	}
+
+	// Halving the scale merges pairs of buckets, so the index of a value
+	// at the lower scale is its index shifted right.
+	lowest, highest := indexes[0], indexes[0]
+	for _, index := range indexes {
+		lowest = min(lowest, index)
+		highest = max(highest, index)
+	}
+	shift := int32(0)
+	for (highest>>shift)-(lowest>>shift)+1 > maxDistributionBuckets {
+		shift++
+	}
+	dp.SetScale(maxDistributionScale - shift)
+
+	offset := lowest >> shift
+	counts := make([]uint64, (highest>>shift)-offset+1)
+	for _, index := range indexes {
+		counts[(index>>shift)-offset]++
+	}
+	dp.Positive().SetOffset(offset)
+	dp.Positive().BucketCounts().FromRaw(counts)
+}
+
+// bucketIndex returns the index of the bucket of a positive value at a scale,
+// the bucket i holding the values in (base^i, base^(i+1)] for a base of
+// 2^(2^-scale).
+func bucketIndex(value int64, scale int32) int32 {
+	return int32(math.Ceil(math.Log2(float64(value))*math.Exp2(float64(scale)))) - 1
+}
+
+// distributionBuckets collects the increments of the keys of the groupings in
+// the `distribution` report mode, by stat name and grouping.
+type distributionBuckets map[[2]string]*telemetryStatsDatapoint
+
+// add adds the increment of a key as an observation. Overflow keys are not
+// observed, as they sum the counts of many keys.
+func (b distributionBuckets) add(name, key string, value int64) {
+	if isOverflowKey(key) {
+		return
+	}
+	grouping, _, _ := strings.Cut(key, ":")
+	bucket, exists := b[[2]string{name, grouping}]
+	if !exists {
+		bucket = &telemetryStatsDatapoint{
+			name:         distributionStatName(name),
+			mode:         reportModeDistribution,
+			distribution: &distribution{},
+		}
+		b[[2]string{name, grouping}] = bucket
+	}
+	bucket.distribution.observations = append(
+		bucket.distribution.observations, value)
+}
+
+// datapoints returns a datapoint for each distribution, labeled as the
+// grouping.
+func (b distributionBuckets) datapoints(labels func(key string) map[string]string) []telemetryStatsDatapoint {
+	datapoints := make([]telemetryStatsDatapoint, 0, len(b))
+	for nameAndGrouping, bucket := range b {
+		dp := *bucket
+		dp.labels = labels(nameAndGrouping[1])
+		datapoints = append(datapoints, dp)
+	}
+	return datapoints
+}
+
+// distributionStatName returns the name of the distribution of a counter over
+// keys, e.g. telemetry_stats_bytes_per_key for telemetry_stats_bytes_total.
+func distributionStatName(name string) string {
+	return strings.TrimSuffix(name, "_total") + "_per_key"
+}
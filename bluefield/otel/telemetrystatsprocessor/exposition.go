@@ -0,0 +1,184 @@
+package telemetrystatsprocessor
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	"otelcommon/promlabels"
+)
+
+// The content types of the exposition formats of the prometheus endpoint.
+const (
+	contentTypeText        = "text/plain; version=0.0.4; charset=utf-8"
+	contentTypeOpenMetrics = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// The types of metric families.
+const (
+	familyCounter = "counter"
+	familyGauge   = "gauge"
+	familyUnknown = "unknown" // "untyped" in the text format
+)
+
+// family holds the series of a metric name, which the exposition formats
+// require to be written together after their HELP and TYPE.
+type family struct {
+	name   string
+	kind   string
+	help   string
+	series map[string]*telemetryStatsDatapoint // by formatted labels
+
+	// the labels of the series the help is of, the first in sort order
+	helpLabels string
+}
+
+// acceptsOpenMetrics returns whether the Accept header of a request lists
+// OpenMetrics, as Prometheus does by default, rather than only the text
+// format.
+func acceptsOpenMetrics(r *http.Request) bool {
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(accepted)
+		if err == nil && mediaType == "application/openmetrics-text" &&
+			params["q"] != "0" {
+			return true
+		}
+	}
+	return false
+}
+
+// familyKind returns the type of the family of a datapoint. Deltas and rates
+// are the increments over the report interval, and thus gauges.
+func familyKind(dp *telemetryStatsDatapoint) string {
+	if dp.gauge || dp.mode == reportModeDelta || dp.mode == reportModeRate {
+		return familyGauge
+	}
+	return familyCounter
+}
+
+// writeExposition writes datapoints in the text exposition format or in
+// OpenMetrics, grouped into families sorted by name, each with its series
+// sorted by labels, so that consecutive scrapes can be diffed. Identical
+// series, e.g. of processors in several pipelines counting the same grouping,
+// are merged into one by summing their values, as scrapers reject duplicates.
+// Datapoints of a name reported both as counters and gauges, e.g. by groupings
+// in different report modes, are written as a family of unknown type. Families
+// of groupings with different descriptions take that of their first series.
+func writeExposition(w http.ResponseWriter, datapoints []telemetryStatsDatapoint, openMetrics bool) {
+	families := make(map[string]*family)
+	for _, dp := range datapoints {
+		name := promlabels.MetricName(dp.name)
+		labels := formatLabels(dp.labels)
+		f, exists := families[name]
+		if !exists {
+			help, _ := dp.metadata()
+			f = &family{
+				name:       name,
+				kind:       familyKind(&dp),
+				help:       help,
+				series:     make(map[string]*telemetryStatsDatapoint),
+				helpLabels: labels,
+			}
+			families[name] = f
+		} else {
+			if f.kind != familyKind(&dp) {
+				f.kind = familyUnknown
+			}
+			if labels < f.helpLabels {
+				f.help, _ = dp.metadata()
+				f.helpLabels = labels
+			}
+		}
+		if series, exists := f.series[labels]; exists {
+			series.value += dp.value
+			series.rate += dp.rate
+			continue
+		}
+		f.series[labels] = &dp
+	}
+
+	if openMetrics {
+		w.Header().Set("Content-Type", contentTypeOpenMetrics)
+	} else {
+		w.Header().Set("Content-Type", contentTypeText)
+	}
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		writeFamily(w, families[name], openMetrics)
+	}
+	if openMetrics {
+		fmt.Fprint(w, "# EOF\n")
+	}
+}
+
+// writeFamily writes the HELP and TYPE of a family, and its samples. In
+// OpenMetrics, the name of a counter family omits the _total suffix of its
+// samples, and counters without it are of unknown type.
+func writeFamily(w io.Writer, f *family, openMetrics bool) {
+	name, kind := f.name, f.kind
+	if openMetrics && kind == familyCounter {
+		if trimmed, found := strings.CutSuffix(name, "_total"); found {
+			name = trimmed
+		} else {
+			kind = familyUnknown
+		}
+	}
+	if !openMetrics && kind == familyUnknown {
+		kind = "untyped"
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n", name, escapeHelp(f.help, openMetrics))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+	series := make([]string, 0, len(f.series))
+	for labels := range f.series {
+		series = append(series, labels)
+	}
+	slices.Sort(series)
+	for _, labels := range series {
+		value := f.series[labels].formatValue()
+		if labels != "" {
+			fmt.Fprintf(w, "%s{%s} %s\n", f.name, labels, value)
+		} else {
+			fmt.Fprintf(w, "%s %s\n", f.name, value)
+		}
+	}
+}
+
+// formatLabels formats labels for the exposition formats, sanitized, escaped
+// and sorted by name.
+func formatLabels(labels map[string]string) string {
+	sanitized := promlabels.Sanitize(labels)
+	names := make([]string, 0, len(sanitized))
+	for name := range sanitized {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var b strings.Builder
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=\"%s\"", name, escapeLabelValue(sanitized[name]))
+	}
+	return b.String()
+}
+
+// escapeLabelValue escapes a label value for the exposition formats.
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// escapeHelp escapes a HELP text, in which OpenMetrics also escapes quotes.
+func escapeHelp(help string, openMetrics bool) string {
+	if openMetrics {
+		return escapeLabelValue(help)
+	}
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
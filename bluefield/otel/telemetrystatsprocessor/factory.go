@@ -3,6 +3,7 @@ package telemetrystatsprocessor
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/consumer"
@@ -12,7 +13,6 @@ import (
 
 const (
 	typeStr       = "telemetry_stats"
-	prefixStr     = typeStr + "_"
 	ProcessorName = "telemetrystatsprocessor"
 	stability     = component.StabilityLevelAlpha
 )
@@ -20,9 +20,16 @@ const (
 var sourceStr string = fmt.Sprintf("%s:%s", ProcessorName, Version)
 var processorCapabilities = consumer.Capabilities{MutatesData: true}
 
-// prefixes telemetry_stats metric names with the processor
-func telemetryStatName(name string) string {
-	return prefixStr + name
+// prefixes telemetry_stats metric names with the metric prefix of the
+// processor
+func (p *telemetryStatsProcessor) telemetryStatName(name string) string {
+	return p.config.MetricPrefix + "_" + name
+}
+
+// isStat returns whether a metric name is that of a stat, whatever the metric
+// prefix of the processor that generated it.
+func isStat(name, stat string) bool {
+	return strings.HasSuffix(name, "_"+stat)
 }
 
 func NewFactory() processor.Factory {
@@ -44,6 +51,11 @@ func createMetricsProcessor(
 	if err != nil {
 		return nil, err
 	}
+	p.nextMetrics = nextConsumer
+	if err := p.createLogStatsOTLP(ctx, set); err != nil {
+		p.cleanup(ctx)
+		return nil, err
+	}
 
 	return processorhelper.NewMetricsProcessor(
 		ctx,
@@ -52,8 +64,9 @@ func createMetricsProcessor(
 		nextConsumer,
 		p.processMetrics,
 		processorhelper.WithCapabilities(processorCapabilities),
-		processorhelper.WithShutdown(func(context.Context) error {
-			p.cleanup()
+		processorhelper.WithStart(p.start),
+		processorhelper.WithShutdown(func(ctx context.Context) error {
+			p.cleanup(ctx)
 			return nil
 		}))
 }
@@ -68,6 +81,11 @@ func createLogsProcessor(
 	if err != nil {
 		return nil, err
 	}
+	p.nextLogs = nextConsumer
+	if err := p.createLogStatsOTLP(ctx, set); err != nil {
+		p.cleanup(ctx)
+		return nil, err
+	}
 
 	return processorhelper.NewLogsProcessor(
 		ctx,
@@ -76,8 +94,9 @@ func createLogsProcessor(
 		nextConsumer,
 		p.processLogs,
 		processorhelper.WithCapabilities(processorCapabilities),
-		processorhelper.WithShutdown(func(context.Context) error {
-			p.cleanup()
+		processorhelper.WithStart(p.start),
+		processorhelper.WithShutdown(func(ctx context.Context) error {
+			p.cleanup(ctx)
 			return nil
 		}))
 }
@@ -0,0 +1,194 @@
+package telemetrystatsprocessor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// groupingsPath is the path of the debug endpoint listing the groupings and
+// enabling or disabling them at runtime
+const groupingsPath = "/debug/telemetry_stats/groupings"
+
+// groupingRevert reverts a grouping switched for a limited time to its
+// configured state.
+type groupingRevert struct {
+	timer *time.Timer
+	at    time.Time
+}
+
+// groupingState is the runtime state of a grouping served as JSON.
+type groupingState struct {
+	Name       string     `json:"name"`
+	Signal     string     `json:"signal"`
+	Enabled    bool       `json:"enabled"`
+	Configured bool       `json:"configured"`
+	RevertAt   *time.Time `json:"revert_at,omitempty"`
+}
+
+// serveGroupings lists the groupings of the processors configuring the debug
+// endpoint on GET, and enables or disables the groupings of a name on POST,
+// e.g. "?name=metrics_by_resource&enabled=true&for=30m". With "for", the
+// grouping reverts to its configured state after the duration.
+func (d *debugEndpoint) serveGroupings(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if status, err := d.switchGrouping(r); err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	data, err := json.Marshal(d.groupingStates())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+func (d *debugEndpoint) switchGrouping(r *http.Request) (int, error) {
+	query := r.URL.Query()
+	name := query.Get("name")
+	if name == "" {
+		return http.StatusBadRequest, errors.New("name must be specified")
+	}
+	enabled, err := strconv.ParseBool(query.Get("enabled"))
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("invalid enabled: %w", err)
+	}
+	var duration time.Duration
+	if value := query.Get("for"); value != "" {
+		duration, err = time.ParseDuration(value)
+		if err != nil || duration <= 0 {
+			return http.StatusBadRequest,
+				errors.New("for must be a positive duration")
+		}
+	}
+
+	debugEndpointsLock.Lock()
+	defer debugEndpointsLock.Unlock()
+
+	if !d.setGrouping(name, func(bool) bool { return enabled }) {
+		return http.StatusNotFound, fmt.Errorf("grouping %s is not configured",
+			name)
+	}
+	logger := d.processors[0].logger
+	logger.Info("Switched telemetry stats grouping",
+		zap.String("grouping", name),
+		zap.Bool("enabled", enabled),
+		zap.Duration("for", duration))
+
+	if revert, exists := d.reverts[name]; exists {
+		revert.timer.Stop()
+		delete(d.reverts, name)
+	}
+	if duration > 0 {
+		revert := &groupingRevert{at: time.Now().Add(duration)}
+		revert.timer = time.AfterFunc(duration, func() {
+			debugEndpointsLock.Lock()
+			defer debugEndpointsLock.Unlock()
+
+			// a later switch replaced the revert
+			if d.reverts[name] != revert {
+				return
+			}
+			delete(d.reverts, name)
+			d.setGrouping(name, func(configured bool) bool { return configured })
+			logger.Info("Reverted telemetry stats grouping",
+				zap.String("grouping", name))
+		})
+		d.reverts[name] = revert
+	}
+	return http.StatusOK, nil
+}
+
+// setGrouping sets whether the groupings of a name are enabled, given their
+// configured state, in all processors configuring the debug endpoint. It
+// returns false if no processor configures such a grouping. The debug
+// endpoints lock must be held.
+func (d *debugEndpoint) setGrouping(name string, enabled func(configured bool) bool) bool {
+	found := false
+	for _, p := range d.processors {
+		for i, g := range p.config.MetricGroupings {
+			if g.Name == name {
+				p.metricGroupingsEnabled[i].Store(enabled(!g.Disabled))
+				found = true
+			}
+		}
+		for i, g := range p.config.LogGroupings {
+			if g.Name == name {
+				p.logGroupingsEnabled[i].Store(enabled(!g.Disabled))
+				found = true
+			}
+		}
+	}
+	return found
+}
+
+// configuresGrouping returns whether any processor configuring the debug
+// endpoint configures a grouping of a name. The debug endpoints lock must be
+// held.
+func (d *debugEndpoint) configuresGrouping(name string) bool {
+	for _, p := range d.processors {
+		for _, g := range p.config.MetricGroupings {
+			if g.Name == name {
+				return true
+			}
+		}
+		for _, g := range p.config.LogGroupings {
+			if g.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// groupingStates returns the state of each grouping name, once for each
+// signal even if several processors configure it.
+func (d *debugEndpoint) groupingStates() []groupingState {
+	debugEndpointsLock.Lock()
+	defer debugEndpointsLock.Unlock()
+
+	states := make([]groupingState, 0)
+	seen := make(map[string]bool)
+	add := func(name, signal string, disabled bool, enabled *atomic.Bool) {
+		if seen[signal+":"+name] {
+			return
+		}
+		seen[signal+":"+name] = true
+		state := groupingState{
+			Name:       name,
+			Signal:     signal,
+			Enabled:    enabled.Load(),
+			Configured: !disabled,
+		}
+		if revert, exists := d.reverts[name]; exists {
+			state.RevertAt = &revert.at
+		}
+		states = append(states, state)
+	}
+	for _, p := range d.processors {
+		for i, g := range p.config.MetricGroupings {
+			add(g.Name, "metrics", g.Disabled, &p.metricGroupingsEnabled[i])
+		}
+		for i, g := range p.config.LogGroupings {
+			add(g.Name, "logs", g.Disabled, &p.logGroupingsEnabled[i])
+		}
+	}
+	return states
+}
@@ -0,0 +1,125 @@
+package telemetrystatsprocessor
+
+import (
+	"strings"
+	"time"
+)
+
+// keyExpiry evicts the keys of the groupings with a `key_ttl` that weren't
+// updated within it, so that the counts of series that went away, e.g. those
+// of deleted pods or interfaces, don't stay in memory for the life of the
+// collector.
+type keyExpiry struct {
+	ttls    map[string]time.Duration // by grouping name
+	expired map[string]int64         // by grouping name, the keys evicted
+}
+
+func newKeyExpiry(ttls map[string]time.Duration) *keyExpiry {
+	return &keyExpiry{
+		ttls:    ttls,
+		expired: make(map[string]int64),
+	}
+}
+
+// tracks returns whether the keys of a grouping expire, and thus whether their
+// updates must be tracked.
+func (e *keyExpiry) tracks(grouping string) bool {
+	return e.ttls[grouping] > 0
+}
+
+// expire returns the keys of the groupings with a `key_ttl` last updated
+// longer than it ago, and counts them as evicted. The caller removes them from
+// the counts while holding their write lock.
+func (e *keyExpiry) expire(updates map[string]time.Time, now time.Time) []string {
+	if len(e.ttls) == 0 {
+		return nil
+	}
+	var keys []string
+	for key, updated := range updates {
+		grouping, _, _ := strings.Cut(key, ":")
+		if ttl := e.ttls[grouping]; ttl > 0 && now.Sub(updated) > ttl {
+			keys = append(keys, key)
+			e.expired[grouping]++
+		}
+	}
+	return keys
+}
+
+// datapoints returns the evicted keys counter of each grouping with a
+// `key_ttl`, named as given and labeled as the grouping. It must be called
+// while holding the read lock of the counts.
+func (e *keyExpiry) datapoints(
+	name string,
+	labels func(key string) map[string]string,
+) []telemetryStatsDatapoint {
+	datapoints := make([]telemetryStatsDatapoint, 0, len(e.ttls))
+	for grouping := range e.ttls {
+		datapoints = append(datapoints, telemetryStatsDatapoint{
+			name: name,
+			description: "Number of keys of a grouping evicted as they " +
+				"were not updated within its key_ttl",
+			value:  e.expired[grouping],
+			labels: labels(grouping),
+		})
+	}
+	return datapoints
+}
+
+// evictMetricKeys removes the metric keys that expired, and returns staleness
+// markers for the stats of those reported on their own by groupings with
+// `staleness_markers`. It must be called while holding the write lock of the
+// metric counts.
+func (p *telemetryStatsProcessor) evictMetricKeys(now time.Time) []telemetryStatsDatapoint {
+	expired := p.metricKeyExpiry.expire(p.metricUpdates, now)
+	if len(expired) == 0 {
+		return nil
+	}
+
+	// Keys summed into `other` or observed in a distribution were not
+	// reported on their own, so they have no series to mark stale.
+	var others map[string]bool
+	if len(p.metricStalenessMarkers) > 0 {
+		others = topKOthers(p.metricCounts, p.metricTopK)
+	}
+	var markers []telemetryStatsDatapoint
+	for _, key := range expired {
+		grouping, _, _ := strings.Cut(key, ":")
+		if p.metricStalenessMarkers[grouping] && !others[key] &&
+			p.metricReport.mode(key) != reportModeDistribution {
+			for _, counts := range []struct {
+				name   string
+				counts map[string]int64
+			}{
+				{p.telemetryStatName("datapoints_total"), p.metricCounts},
+				{p.telemetryStatName("points_total"), p.pointCounts},
+				{p.telemetryStatName("bytes_total"), p.metricByteCounts},
+			} {
+				if _, exists := counts.counts[key]; exists {
+					markers = append(markers, telemetryStatsDatapoint{
+						name:   counts.name,
+						labels: p.metricStatLabels(key),
+						mode:   p.metricReport.mode(key),
+						stale:  true,
+					})
+				}
+			}
+		}
+		delete(p.metricCounts, key)
+		delete(p.pointCounts, key)
+		delete(p.metricByteCounts, key)
+		delete(p.metricUpdates, key)
+		p.metricKeyLimits.remove(key)
+	}
+	return markers
+}
+
+// evictLogKeys removes the log keys that expired. It must be called while
+// holding the write lock of the log counts.
+func (p *telemetryStatsProcessor) evictLogKeys(now time.Time) {
+	for _, key := range p.logKeyExpiry.expire(p.logUpdates, now) {
+		delete(p.logCounts, key)
+		delete(p.logUpdates, key)
+		delete(p.logByteCounts, key)
+		p.logKeyLimits.remove(key)
+	}
+}
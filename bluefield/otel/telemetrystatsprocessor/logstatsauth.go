@@ -0,0 +1,61 @@
+package telemetrystatsprocessor
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/extension/auth"
+	"go.uber.org/zap"
+)
+
+// startLogStatsAuth looks up the auth extension authenticating requests to the
+// prometheus endpoint, if the processor registered the endpoint with an
+// `authenticator`. Extensions are started before processors.
+func (p *telemetryStatsProcessor) startLogStatsAuth(host component.Host) error {
+	e := p.exporter
+	if e == nil {
+		return nil
+	}
+	e.requestsRWLock.Lock()
+	defer e.requestsRWLock.Unlock()
+
+	if e.registrar != p || p.config.LogStatsAuth.Authenticator == "" {
+		return nil
+	}
+	var id component.ID
+	if err := id.UnmarshalText([]byte(p.config.LogStatsAuth.Authenticator)); err != nil {
+		return fmt.Errorf("invalid authenticator: %w", err)
+	}
+	extension, found := host.GetExtensions()[id]
+	if !found {
+		return fmt.Errorf("authenticator %s is not configured as an extension", id)
+	}
+	authenticator, ok := extension.(auth.Server)
+	if !ok {
+		return fmt.Errorf("extension %s is not a server authenticator", id)
+	}
+	e.authenticator = authenticator
+	return nil
+}
+
+// authenticate returns whether a request to the prometheus endpoint may be
+// served, writing the error response if not. Requests arriving before the auth
+// extension was looked up are refused as unavailable. It must be called while
+// holding the read lock of the requests.
+func (e *logStatsExporter) authenticate(w http.ResponseWriter, r *http.Request) bool {
+	if e.registrar == nil || e.registrar.config.LogStatsAuth.Authenticator == "" {
+		return true
+	}
+	if e.authenticator == nil {
+		http.Error(w, "authenticator not started", http.StatusServiceUnavailable)
+		return false
+	}
+	if _, err := e.authenticator.Authenticate(r.Context(), r.Header); err != nil {
+		e.logger.Debug("Refused unauthenticated log stats request",
+			zap.String("remote_addr", r.RemoteAddr), zap.Error(err))
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
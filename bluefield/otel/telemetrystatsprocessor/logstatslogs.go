@@ -0,0 +1,125 @@
+package telemetrystatsprocessor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+)
+
+// startLogStatsLogs starts emitting the log stats of a logs processor with
+// `log_stats_export: logs` as log records on each `log_stats_interval`.
+func (p *telemetryStatsProcessor) startLogStatsLogs() {
+	if p.nextLogs == nil || len(p.config.LogGroupings) == 0 ||
+		p.config.LogStatsExport != logStatsExportLogs {
+		return
+	}
+	p.logStatsLogsEmitted = true
+	p.stopWaiters.Add(1)
+	go p.logStatsEmitLoop()
+}
+
+func (p *telemetryStatsProcessor) logStatsEmitLoop() {
+	defer p.stopWaiters.Done()
+
+	ticker := time.NewTicker(p.config.LogStatsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.emitLogStats(context.Background())
+		case <-p.stopChannel:
+			return
+		}
+	}
+}
+
+// emitLogStats sends the log stats down the logs pipeline, one record per
+// stat, so that they reach log backends along with the logs they count. The
+// configured labels are written as resource attributes, as with OTLP.
+// Distributions have no single value and are not emitted.
+func (p *telemetryStatsProcessor) emitLogStats(ctx context.Context) {
+	datapoints := scrapeLogStats(p)
+	if p.push != nil && len(p.config.MetricGroupings) == 0 {
+		datapoints = append(datapoints,
+			p.push.pushedStats(p, "log_")...)
+	}
+	if len(p.config.MetricGroupings) == 0 && p.isReportTelemetryStatCounts() {
+		datapoints = append(datapoints, p.getTelemetryStatCounts()...)
+	}
+	if len(datapoints) == 0 {
+		return
+	}
+	now := time.Now()
+	if p.logDeltas != nil {
+		p.logDeltas.convert(datapoints, now)
+		p.logDeltas.commit()
+	}
+
+	ld := plog.NewLogs()
+	rl := ld.ResourceLogs().AppendEmpty()
+	for _, configuredLabel := range p.config.Labels {
+		rl.Resource().Attributes().PutStr(configuredLabel.Name,
+			configuredLabel.Value)
+	}
+	sl := rl.ScopeLogs().AppendEmpty()
+	sl.Scope().SetName(ProcessorName)
+	sl.Scope().SetVersion(Version)
+	for _, dp := range datapoints {
+		if dp.distribution != nil || dp.stale {
+			continue
+		}
+		appendLogStat(sl.LogRecords(), dp, now)
+	}
+	if sl.LogRecords().Len() == 0 {
+		return
+	}
+	if err := p.nextLogs.ConsumeLogs(ctx, ld); err != nil {
+		p.logger.Warn("Failed to emit log stats", zap.Error(err))
+	}
+}
+
+// appendLogStat appends the log record of a stat. Its attributes hold the name
+// of the stat, its grouping, its key as formatted in the exposition formats,
+// its labels, and its count, or its rate for groupings reporting rates.
+func appendLogStat(records plog.LogRecordSlice, dp telemetryStatsDatapoint, now time.Time) {
+	record := records.AppendEmpty()
+	timestamp := now
+	if !dp.end.IsZero() {
+		timestamp = dp.end
+	}
+	record.SetTimestamp(pcommon.NewTimestampFromTime(timestamp))
+	record.SetObservedTimestamp(pcommon.NewTimestampFromTime(now))
+	record.SetSeverityNumber(plog.SeverityNumberInfo)
+	record.SetSeverityText(plog.SeverityNumberInfo.String())
+
+	keyLabels := make(map[string]string, len(dp.labels))
+	for name, value := range dp.labels {
+		if name != "grouping" && name != "source" {
+			keyLabels[name] = value
+		}
+	}
+	key := formatLabels(keyLabels)
+
+	attrs := record.Attributes()
+	attrs.PutStr("stat", dp.name)
+	if grouping, exists := dp.labels["grouping"]; exists {
+		attrs.PutStr("grouping", grouping)
+	}
+	attrs.PutStr("key", key)
+	labels := attrs.PutEmptyMap("labels")
+	for name, value := range dp.labels {
+		labels.PutStr(name, value)
+	}
+	if dp.mode == reportModeRate {
+		attrs.PutDouble("rate", dp.rate)
+		record.Body().SetStr(fmt.Sprintf("%s{%s} %g/s", dp.name, key, dp.rate))
+	} else {
+		attrs.PutInt("count", dp.value)
+		record.Body().SetStr(fmt.Sprintf("%s{%s} %d", dp.name, key, dp.value))
+	}
+}
@@ -0,0 +1,182 @@
+package telemetrystatsprocessor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/exporter"
+	"go.opentelemetry.io/collector/exporter/otlpexporter"
+	"go.opentelemetry.io/collector/exporter/otlphttpexporter"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/processor"
+	"go.uber.org/zap"
+)
+
+// The ways log stats are exported, and the protocols they are pushed with.
+const (
+	logStatsExportPrometheus = "prometheus"
+	logStatsExportOTLP       = "otlp"
+	logStatsExportLogs       = "logs"
+
+	logStatsProtocolGRPC = "grpc"
+	logStatsProtocolHTTP = "http"
+)
+
+// exporterConfig returns the factory and the validated configuration of the
+// OTLP exporter log stats are pushed with.
+func (cfg *LogStatsOTLP) exporterConfig() (exporter.Factory, component.Config, error) {
+	var factory exporter.Factory
+	switch cfg.Protocol {
+	case logStatsProtocolGRPC:
+		factory = otlpexporter.NewFactory()
+	case logStatsProtocolHTTP:
+		factory = otlphttpexporter.NewFactory()
+	default:
+		return nil, nil, fmt.Errorf("protocol must be %q or %q",
+			logStatsProtocolGRPC, logStatsProtocolHTTP)
+	}
+	if len(cfg.Exporter) == 0 {
+		return nil, nil, errors.New("exporter must configure the endpoint")
+	}
+
+	exporterConfig := factory.CreateDefaultConfig()
+	if err := confmap.NewFromStringMap(cfg.Exporter).Unmarshal(exporterConfig); err != nil {
+		return nil, nil, fmt.Errorf("invalid exporter config: %w", err)
+	}
+	if validator, ok := exporterConfig.(interface{ Validate() error }); ok {
+		if err := validator.Validate(); err != nil {
+			return nil, nil, fmt.Errorf("invalid exporter config: %w", err)
+		}
+	}
+	return factory, exporterConfig, nil
+}
+
+// createLogStatsOTLP creates the OTLP exporter the log stats of a processor
+// with `log_stats_export: otlp` are pushed with. It is named
+// "otlp/<processor ID>/log_stats" or "otlphttp/..." in the collector's logs.
+func (p *telemetryStatsProcessor) createLogStatsOTLP(
+	ctx context.Context,
+	set processor.CreateSettings,
+) error {
+	if len(p.config.LogGroupings) == 0 ||
+		p.config.LogStatsExport != logStatsExportOTLP {
+		return nil
+	}
+
+	factory, exporterConfig, err := p.config.LogStatsOTLP.exporterConfig()
+	if err != nil {
+		return fmt.Errorf("invalid log_stats_otlp: %w", err)
+	}
+	exporterSet := exporter.CreateSettings{
+		ID: component.NewIDWithName(factory.Type(),
+			set.ID.String()+"/log_stats"),
+		TelemetrySettings: set.TelemetrySettings,
+		BuildInfo:         set.BuildInfo,
+	}
+	exporterSet.Logger = set.Logger.With(zap.String("export", "log_stats"))
+	exp, err := factory.CreateMetricsExporter(ctx, exporterSet, exporterConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create log stats exporter: %w", err)
+	}
+	p.logStatsOTLP = exp
+	return nil
+}
+
+// start looks up the auth extension of the log stats endpoint, if any, and
+// starts the OTLP exporter of the log stats, if any, pushing them on each
+// `log_stats_otlp` interval, or emitting them as log records on each
+// `log_stats_interval`.
+func (p *telemetryStatsProcessor) start(ctx context.Context, host component.Host) error {
+	if err := p.startLogStatsAuth(host); err != nil {
+		return err
+	}
+	p.startLogStatsLogs()
+	if p.logStatsOTLP == nil {
+		return nil
+	}
+	if err := p.logStatsOTLP.Start(ctx, host); err != nil {
+		return fmt.Errorf("failed to start log stats exporter: %w", err)
+	}
+	p.logStatsOTLPStarted = true
+	p.stopWaiters.Add(1)
+	go p.logStatsPushLoop()
+	return nil
+}
+
+func (p *telemetryStatsProcessor) logStatsPushLoop() {
+	defer p.stopWaiters.Done()
+
+	ticker := time.NewTicker(p.config.LogStatsOTLP.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.pushLogStats(context.Background())
+		case <-p.stopChannel:
+			return
+		}
+	}
+}
+
+// pushLogStats hands the log stats to the OTLP exporter, which queues and
+// retries them as configured. The configured labels are written as resource
+// attributes, as a pipeline scraping the prometheus endpoint would.
+func (p *telemetryStatsProcessor) pushLogStats(ctx context.Context) {
+	datapoints := scrapeLogStats(p)
+	if p.push != nil && len(p.config.MetricGroupings) == 0 {
+		datapoints = append(datapoints,
+			p.push.pushedStats(p, "log_")...)
+	}
+	if len(p.config.MetricGroupings) == 0 && p.isReportTelemetryStatCounts() {
+		datapoints = append(datapoints, p.getTelemetryStatCounts()...)
+	}
+	if len(datapoints) == 0 {
+		return
+	}
+	if p.logDeltas != nil {
+		p.logDeltas.convert(datapoints, time.Now())
+		p.logDeltas.commit()
+	}
+
+	md := pmetric.NewMetrics()
+	rm := md.ResourceMetrics().AppendEmpty()
+	for _, configuredLabel := range p.config.Labels {
+		rm.Resource().Attributes().PutStr(configuredLabel.Name,
+			configuredLabel.Value)
+	}
+	sm := rm.ScopeMetrics().AppendEmpty()
+	sm.Scope().SetName(ProcessorName)
+	sm.Scope().SetVersion(Version)
+	for _, dp := range datapoints {
+		appendMetricStat(sm.Metrics(), dp)
+	}
+	if err := p.logStatsOTLP.ConsumeMetrics(ctx, md); err != nil {
+		p.logger.Warn("Failed to push log stats", zap.Error(err))
+	}
+}
+
+// shutdownLogStatsOTLP pushes the final log stats if the OTLP exporter was
+// started, and shuts it down, which drains or persists its queue.
+func (p *telemetryStatsProcessor) shutdownLogStatsOTLP(ctx context.Context) {
+	if p.logStatsOTLP == nil {
+		return
+	}
+	if p.logStatsOTLPStarted {
+		p.pushLogStats(ctx)
+	}
+	if err := p.logStatsOTLP.Shutdown(ctx); err != nil {
+		p.logger.Error("Failed to shut down log stats exporter", zap.Error(err))
+	}
+	p.logStatsOTLP = nil
+}
+
+// servesLogStats returns whether the log stats of the processor are served at
+// the prometheus endpoint rather than pushed over OTLP or emitted as logs.
+func (p *telemetryStatsProcessor) servesLogStats() bool {
+	return p.config.LogStatsExport == logStatsExportPrometheus
+}
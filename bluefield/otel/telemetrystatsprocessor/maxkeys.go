@@ -0,0 +1,83 @@
+package telemetrystatsprocessor
+
+import (
+	"strings"
+)
+
+// overflowPart is the part of the overflow key of a grouping, which counts the
+// keys of the grouping beyond its `max_keys`.
+const overflowPart = "__overflow=true"
+
+// keyLimits limits the number of keys of the groupings with a `max_keys`.
+// Counted keys are never removed, so once a grouping has reached its limit,
+// only its existing keys and its overflow key are counted.
+type keyLimits struct {
+	maxKeys  map[string]int   // by grouping name
+	keys     map[string]int   // by grouping name, without the overflow key
+	overflow map[string]int64 // by grouping name, the items of new keys
+}
+
+func newKeyLimits(maxKeys map[string]int) *keyLimits {
+	return &keyLimits{
+		maxKeys:  maxKeys,
+		keys:     make(map[string]int),
+		overflow: make(map[string]int64),
+	}
+}
+
+// limit returns the key to count an item of a grouping under: its own key, or
+// the overflow key of the grouping if the key is new and the grouping already
+// has `max_keys` keys, in which case it also returns true. It must be called
+// while holding the write lock of the counts.
+func (l *keyLimits) limit(grouping, key string, counts map[string]int64) (string, bool) {
+	maxKeys := l.maxKeys[grouping]
+	if maxKeys == 0 {
+		return key, false
+	}
+	if _, exists := counts[key]; exists {
+		return key, false
+	}
+	if l.keys[grouping] < maxKeys {
+		l.keys[grouping]++
+		return key, false
+	}
+	l.overflow[grouping]++
+	return grouping + ":" + overflowPart, true
+}
+
+// remove forgets a key evicted from the counts, so that a new key of its
+// grouping can take its place. It must be called while holding the write lock
+// of the counts.
+func (l *keyLimits) remove(key string) {
+	if isOverflowKey(key) {
+		return
+	}
+	grouping, _, _ := strings.Cut(key, ":")
+	if l.keys[grouping] > 0 {
+		l.keys[grouping]--
+	}
+}
+
+// datapoints returns the overflow counter of each grouping with a `max_keys`,
+// named as given and labeled as the grouping. It must be called while holding
+// the read lock of the counts.
+func (l *keyLimits) datapoints(
+	name string,
+	labels func(key string) map[string]string,
+) []telemetryStatsDatapoint {
+	datapoints := make([]telemetryStatsDatapoint, 0, len(l.maxKeys))
+	for grouping := range l.maxKeys {
+		datapoints = append(datapoints, telemetryStatsDatapoint{
+			name: name,
+			description: "Number of items of new keys counted in the " +
+				"overflow key of a grouping that reached its max_keys",
+			value:  l.overflow[grouping],
+			labels: labels(grouping),
+		})
+	}
+	return datapoints
+}
+
+func isOverflowKey(key string) bool {
+	return strings.HasSuffix(key, ":"+overflowPart)
+}
@@ -0,0 +1,31 @@
+package telemetrystatsprocessor
+
+// statMetadata is the unit and description configured for the counts of a
+// grouping, empty unless configured.
+type statMetadata struct {
+	unit        string
+	description string
+}
+
+// groupingMetadata holds the metadata configured for the counts of groupings,
+// by grouping name.
+type groupingMetadata map[string]statMetadata
+
+// apply sets the configured metadata on the datapoints of a count stat of the
+// groupings, and on those of its rates and distributions, whose metadata is
+// derived from it.
+func (m groupingMetadata) apply(datapoints []telemetryStatsDatapoint, stat string) {
+	if len(m) == 0 {
+		return
+	}
+	for i := range datapoints {
+		dp := &datapoints[i]
+		if !isStat(dp.counterName(), stat) {
+			continue
+		}
+		if metadata, ok := m[dp.labels["grouping"]]; ok {
+			dp.unit = metadata.unit
+			dp.description = metadata.description
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package telemetrystatsprocessor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"otelcommon/httpregistry"
+)
+
+const (
+	// pushPath is the path of the endpoint accepting counter increments
+	pushPath = "/v1/counters"
+
+	// maxPushRequestSize limits the size of a push request body
+	maxPushRequestSize = 1 << 20
+)
+
+var (
+	// push endpoints by address, shared by the processors configuring the
+	// same push_endpoint, so that agents push to one set of counters
+	pushEndpointsLock sync.Mutex
+	pushEndpoints     = make(map[string]*pushEndpoint)
+
+	rePushSource    = regexp.MustCompile(`^[a-zA-Z0-9_.:/-]{1,128}$`)
+	rePushName      = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	rePushLabelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_.]*$`)
+
+	// escapes the separators of pushed counter keys, which sources and label
+	// values may contain
+	pushedKeyEscaper = strings.NewReplacer(`\`, `\\`, ":", `\:`, "=", `\=`)
+)
+
+type pushEndpoint struct {
+	logger       *zap.Logger
+	registration *httpregistry.Registration
+	processors   int
+	maxCounters  int
+	rejected     atomic.Int64 // pushed counters rejected
+	countsLock   sync.Mutex
+	counts       map[string]*pushedCounter
+}
+
+// pushedCounter is the cumulative value of a counter pushed by an agent.
+type pushedCounter struct {
+	name    string
+	source  string
+	labels  map[string]string
+	value   int64
+	updated time.Time
+}
+
+// pushRequest is the JSON body of a push request, e.g.
+//
+//	{"source": "dpu-agent", "counters": [
+//	  {"name": "log_records_total", "increment": 42, "labels": {"unit": "sshd"}}
+//	]}
+type pushRequest struct {
+	Source   string        `json:"source"`
+	Counters []pushCounter `json:"counters"`
+}
+
+type pushCounter struct {
+	Name      string            `json:"name"`
+	Increment int64             `json:"increment"`
+	Labels    map[string]string `json:"labels"`
+}
+
+// registerPushEndpoint accepts counter increments on the configured push
+// endpoint, once for all processors configuring the same one.
+func registerPushEndpoint(config *Config, logger *zap.Logger) (*pushEndpoint, error) {
+	pushEndpointsLock.Lock()
+	defer pushEndpointsLock.Unlock()
+
+	e, exists := pushEndpoints[config.PushEndpoint]
+	if !exists {
+		e = &pushEndpoint{
+			logger:      logger,
+			maxCounters: config.MaxPushedCounters,
+			counts:      make(map[string]*pushedCounter),
+		}
+		registration, err := httpregistry.Register(
+			config.serverConfig(config.PushEndpoint),
+			pushPath,
+			e,
+			logger,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to register push endpoint: %w", err)
+		}
+		e.registration = registration
+		pushEndpoints[config.PushEndpoint] = e
+	}
+	e.processors++
+	return e, nil
+}
+
+// unregisterPushEndpoint stops accepting counter increments once the last
+// processor configuring the push endpoint is shut down.
+func unregisterPushEndpoint(endpoint string) {
+	pushEndpointsLock.Lock()
+	e, exists := pushEndpoints[endpoint]
+	if !exists {
+		pushEndpointsLock.Unlock()
+		return
+	}
+	e.processors--
+	if e.processors > 0 {
+		pushEndpointsLock.Unlock()
+		return
+	}
+	delete(pushEndpoints, endpoint)
+	pushEndpointsLock.Unlock()
+
+	e.registration.Unregister()
+}
+
+func (e *pushEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req pushRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPushRequestSize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err),
+			http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(); err != nil {
+		e.rejected.Add(int64(len(req.Counters)))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := e.add(&req); err != nil {
+		e.rejected.Add(int64(len(req.Counters)))
+		e.logger.Warn("Rejected pushed counters",
+			zap.String("source", req.Source), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (req *pushRequest) validate() error {
+	if !rePushSource.MatchString(req.Source) {
+		return fmt.Errorf("invalid source %q", req.Source)
+	}
+	for _, c := range req.Counters {
+		if !rePushName.MatchString(c.Name) {
+			return fmt.Errorf("invalid counter name %q", c.Name)
+		}
+		if c.Increment < 0 {
+			return fmt.Errorf("increment of counter %s cannot be negative",
+				c.Name)
+		}
+		for k, v := range c.Labels {
+			if !rePushLabelName.MatchString(k) {
+				return fmt.Errorf("invalid label name %q of counter %s",
+					k, c.Name)
+			}
+			if k == "source" || k == "grouping" {
+				return fmt.Errorf("label %s of counter %s is reserved",
+					k, c.Name)
+			}
+			if strings.ContainsAny(v, "\"\\\n") {
+				return fmt.Errorf("invalid value of label %s of counter %s",
+					k, c.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// add adds the increments of a request to the pushed counters. A request
+// creating more counters than the limit allows is rejected as a whole, so
+// that an agent never sees some of its counters applied and others not.
+func (e *pushEndpoint) add(req *pushRequest) error {
+	keys := make([]string, len(req.Counters))
+	for i, c := range req.Counters {
+		keys[i] = pushedCounterKey(req.Source, c.Name, c.Labels)
+	}
+
+	now := time.Now()
+
+	e.countsLock.Lock()
+	defer e.countsLock.Unlock()
+
+	created := 0
+	for i, key := range keys {
+		if _, exists := e.counts[key]; !exists &&
+			!slices.Contains(keys[:i], key) {
+			created++
+		}
+	}
+	if len(e.counts)+created > e.maxCounters {
+		return fmt.Errorf("limit of %d pushed counters reached", e.maxCounters)
+	}
+
+	for i, c := range req.Counters {
+		counter, exists := e.counts[keys[i]]
+		if !exists {
+			counter = &pushedCounter{
+				name:   c.Name,
+				source: req.Source,
+				labels: c.Labels,
+			}
+			e.counts[keys[i]] = counter
+		}
+		counter.value += c.Increment
+		counter.updated = now
+	}
+	return nil
+}
+
+// The format of the pushed counter key is
+// source:name[:<labelName>=<labelValue>...] with labels sorted by name, and
+// backslashes, colons and equal signs escaped with a backslash so that
+// different counters never share a key.
+func pushedCounterKey(source, name string, labels map[string]string) string {
+	keyParts := []string{pushedKeyEscaper.Replace(source), name}
+	for k, v := range labels {
+		keyParts = append(keyParts, fmt.Sprintf("%s=%s", k,
+			pushedKeyEscaper.Replace(v)))
+	}
+	slices.Sort(keyParts[2:])
+	return strings.Join(keyParts, ":")
+}
+
+// pushedStats returns a datapoint for each pushed counter, named with the
+// metric prefix of the processor writing them. Labels of the counters
+// conflicting with a label configured on the processor are renamed with the
+// prefix, as the configured label is written as a resource attribute.
+func (e *pushEndpoint) pushedStats(
+	p *telemetryStatsProcessor,
+	prefix string,
+) []telemetryStatsDatapoint {
+	e.countsLock.Lock()
+	defer e.countsLock.Unlock()
+
+	datapoints := make([]telemetryStatsDatapoint, 0, len(e.counts))
+	for _, counter := range e.counts {
+		labels := make(map[string]string, len(counter.labels)+1)
+		for k, v := range counter.labels {
+			labels[k] = v
+		}
+		labels["source"] = counter.source
+		for _, configuredLabel := range p.config.Labels {
+			if value, exists := labels[configuredLabel.Name]; exists {
+				delete(labels, configuredLabel.Name)
+				labels[prefix+configuredLabel.Name] = value
+			}
+		}
+		datapoints = append(datapoints, telemetryStatsDatapoint{
+			name:        p.telemetryStatName(counter.name),
+			description: "Counter pushed by a local agent",
+			value:       counter.value,
+			labels:      labels,
+			updated:     counter.updated,
+		})
+	}
+	return datapoints
+}
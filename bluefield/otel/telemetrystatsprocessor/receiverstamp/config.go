@@ -0,0 +1,20 @@
+package receiverstamp
+
+import (
+	"go.opentelemetry.io/collector/component"
+)
+
+// Config defines the configuration of the receiver_stamp processor.
+type Config struct {
+	// Receiver is the receiver name stamped on the data passing through
+	// the processor. Defaults to the name of the processor instance, e.g.
+	// "hostmetrics" for `receiver_stamp/hostmetrics`.
+	Receiver string `mapstructure:"receiver"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+func createDefaultConfig() component.Config {
+	return &Config{}
+}
@@ -0,0 +1,104 @@
+package receiverstamp
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/processor"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+)
+
+const (
+	typeStr   = "receiver_stamp"
+	stability = component.StabilityLevelAlpha
+)
+
+var processorCapabilities = consumer.Capabilities{MutatesData: true}
+
+func NewFactory() processor.Factory {
+	return processor.NewFactory(
+		component.MustNewType(typeStr),
+		createDefaultConfig,
+		processor.WithTraces(createTracesProcessor, stability),
+		processor.WithMetrics(createMetricsProcessor, stability),
+		processor.WithLogs(createLogsProcessor, stability),
+	)
+}
+
+// receiverName returns the configured receiver name, else the name of the
+// processor instance.
+func receiverName(set processor.CreateSettings, cfg *Config) (string, error) {
+	if cfg.Receiver != "" {
+		return cfg.Receiver, nil
+	}
+	if set.ID.Name() != "" {
+		return set.ID.Name(), nil
+	}
+	return "", errors.New("receiver must be specified unless the processor " +
+		"is named after the receiver, e.g. receiver_stamp/hostmetrics")
+}
+
+func createTracesProcessor(
+	ctx context.Context,
+	set processor.CreateSettings,
+	cfg component.Config,
+	nextConsumer consumer.Traces,
+) (processor.Traces, error) {
+	receiver, err := receiverName(set, cfg.(*Config))
+	if err != nil {
+		return nil, err
+	}
+	p := newReceiverStampProcessor(receiver)
+
+	return processorhelper.NewTracesProcessor(
+		ctx,
+		set,
+		cfg,
+		nextConsumer,
+		p.processTraces,
+		processorhelper.WithCapabilities(processorCapabilities))
+}
+
+func createMetricsProcessor(
+	ctx context.Context,
+	set processor.CreateSettings,
+	cfg component.Config,
+	nextConsumer consumer.Metrics,
+) (processor.Metrics, error) {
+	receiver, err := receiverName(set, cfg.(*Config))
+	if err != nil {
+		return nil, err
+	}
+	p := newReceiverStampProcessor(receiver)
+
+	return processorhelper.NewMetricsProcessor(
+		ctx,
+		set,
+		cfg,
+		nextConsumer,
+		p.processMetrics,
+		processorhelper.WithCapabilities(processorCapabilities))
+}
+
+func createLogsProcessor(
+	ctx context.Context,
+	set processor.CreateSettings,
+	cfg component.Config,
+	nextConsumer consumer.Logs,
+) (processor.Logs, error) {
+	receiver, err := receiverName(set, cfg.(*Config))
+	if err != nil {
+		return nil, err
+	}
+	p := newReceiverStampProcessor(receiver)
+
+	return processorhelper.NewLogsProcessor(
+		ctx,
+		set,
+		cfg,
+		nextConsumer,
+		p.processLogs,
+		processorhelper.WithCapabilities(processorCapabilities))
+}
@@ -0,0 +1,65 @@
+// Package receiverstamp provides the receiver_stamp processor, which stamps
+// the data of a pipeline with the receiver it originates from, so that the
+// telemetry_stats processor can count it `by_receiver` further down the
+// pipeline. It is placed first in a pipeline with a single receiver.
+package receiverstamp
+
+import (
+	"context"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// Attribute is the resource attribute holding the name of the receiver the
+// data originates from. A stamp set by an upstream collector is replaced, as
+// the receiver of this collector is where its volume is accounted.
+const Attribute = "otelcol.receiver"
+
+type receiverStampProcessor struct {
+	receiver string
+}
+
+// processor constructor
+func newReceiverStampProcessor(receiver string) *receiverStampProcessor {
+	return &receiverStampProcessor{receiver: receiver}
+}
+
+func (p *receiverStampProcessor) stamp(resource pcommon.Resource) {
+	resource.Attributes().PutStr(Attribute, p.receiver)
+}
+
+func (p *receiverStampProcessor) processTraces(
+	_ context.Context,
+	td ptrace.Traces,
+) (ptrace.Traces, error) {
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		p.stamp(rss.At(i).Resource())
+	}
+	return td, nil
+}
+
+func (p *receiverStampProcessor) processMetrics(
+	_ context.Context,
+	md pmetric.Metrics,
+) (pmetric.Metrics, error) {
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		p.stamp(rms.At(i).Resource())
+	}
+	return md, nil
+}
+
+func (p *receiverStampProcessor) processLogs(
+	_ context.Context,
+	ld plog.Logs,
+) (plog.Logs, error) {
+	rls := ld.ResourceLogs()
+	for i := 0; i < rls.Len(); i++ {
+		p.stamp(rls.At(i).Resource())
+	}
+	return ld, nil
+}
@@ -0,0 +1,132 @@
+package telemetrystatsprocessor
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The report modes of groupings.
+const (
+	reportModeCumulative = "cumulative"
+	reportModeDelta      = "delta"
+	reportModeRate       = "rate"
+
+	// reportModeDistribution reports the increments of the keys of a
+	// metric grouping as a single exponential histogram
+	reportModeDistribution = "distribution"
+)
+
+// reportState turns the cumulative counts of the groupings reporting deltas
+// or rates into the increments since the previous report. Metric stats are
+// reported on each scrape interval, and log stats on each request to the
+// prometheus endpoint. A finished report only becomes the previous report once
+// committed, so the increments of a report that is never delivered are
+// included in the next one.
+type reportState struct {
+	lock     sync.Mutex
+	modes    map[string]string // by grouping name, unless cumulative
+	previous map[string]int64  // by stat name and key, of the previous report
+	current  map[string]int64  // by stat name and key, of the report in progress
+	finished map[string]int64  // by stat name and key, of the uncommitted report
+	last     time.Time         // of the previous report
+	now      time.Time         // of the report in progress or uncommitted
+}
+
+func newReportState(modes map[string]string, start time.Time) *reportState {
+	return &reportState{
+		modes:    modes,
+		previous: make(map[string]int64),
+		last:     start,
+	}
+}
+
+// begin starts a report. Reports are serialized until finish is called.
+func (s *reportState) begin(now time.Time) {
+	s.lock.Lock()
+	s.now = now
+	s.current = make(map[string]int64, len(s.previous))
+	s.finished = nil
+}
+
+// mode returns the report mode of the grouping of a key.
+func (s *reportState) mode(key string) string {
+	grouping, _, _ := strings.Cut(key, ":")
+	if mode, exists := s.modes[grouping]; exists {
+		return mode
+	}
+	return reportModeCumulative
+}
+
+// value returns the value to report for the cumulative count of a key: the
+// count itself, or its increment since the previous report. A count lower
+// than in the previous report was reset, so the count is the increment.
+func (s *reportState) value(name, key string, count int64) int64 {
+	if s.mode(key) == reportModeCumulative {
+		return count
+	}
+	id := name + "\x00" + key
+	s.current[id] = count
+	if previous := s.previous[id]; count >= previous {
+		return count - previous
+	}
+	return count
+}
+
+// finish sets the interval of the datapoints reporting deltas or rates, and
+// turns the increments of rates into rates per second, before ending the
+// report. The report is discarded unless committed before the next begins.
+func (s *reportState) finish(datapoints []telemetryStatsDatapoint) {
+	defer s.lock.Unlock()
+
+	seconds := s.now.Sub(s.last).Seconds()
+	for i := range datapoints {
+		dp := &datapoints[i]
+		if dp.mode == "" || dp.mode == reportModeCumulative {
+			continue
+		}
+		dp.start = s.last
+		dp.end = s.now
+		if dp.mode == reportModeRate {
+			dp.name = rateStatName(dp.name)
+			if seconds > 0 {
+				dp.rate = float64(dp.value) / seconds
+			}
+		}
+	}
+	s.finished = s.current
+	s.current = nil
+}
+
+// commit makes the last finished report the previous report, once its
+// datapoints are delivered.
+func (s *reportState) commit() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.finished == nil {
+		return
+	}
+	s.previous = s.finished
+	s.finished = nil
+	s.last = s.now
+}
+
+// rateStatName returns the name of the rate of a counter, e.g.
+// telemetry_stats_bytes_per_second for telemetry_stats_bytes_total.
+func rateStatName(name string) string {
+	return strings.TrimSuffix(name, "_total") + "_per_second"
+}
+
+// formatValue formats the value of a datapoint, which is a rate per second,
+// a stat of datapoint values or an integer count.
+func (dp *telemetryStatsDatapoint) formatValue() string {
+	if dp.mode == reportModeRate {
+		return strconv.FormatFloat(dp.rate, 'g', -1, 64)
+	}
+	if dp.isDouble {
+		return strconv.FormatFloat(dp.double, 'g', -1, 64)
+	}
+	return strconv.FormatInt(dp.value, 10)
+}
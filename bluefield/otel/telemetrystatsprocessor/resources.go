@@ -0,0 +1,169 @@
+package telemetrystatsprocessor
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"slices"
+	"sync"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+
+	"otelcommon/httpregistry"
+)
+
+// resourcesPath is the path of the debug endpoint listing the resource
+// attributes of each resource hash
+const resourcesPath = "/debug/telemetry_stats/resources"
+
+var (
+	// resource attributes by resource hash, shared by all processors
+	resourcesLock sync.RWMutex
+	resources     = make(map[string]map[string]any)
+
+	// debug endpoints by address, shared by the processors configuring
+	// the same debug_endpoint
+	debugEndpointsLock sync.Mutex
+	debugEndpoints     = make(map[string]*debugEndpoint)
+)
+
+type debugEndpoint struct {
+	registrations []*httpregistry.Registration
+	processors    []*telemetryStatsProcessor
+
+	// groupings switched for a limited time, by name
+	reverts map[string]*groupingRevert
+}
+
+// resourceHash returns a stable hash of all attributes of a resource, which
+// identifies the resource in counts grouped by_resource. The hash does not
+// depend on the order of the attributes.
+func resourceHash(attrs pcommon.Map) string {
+	keys := make([]string, 0, attrs.Len())
+	attrs.Range(func(k string, _ pcommon.Value) bool {
+		keys = append(keys, k)
+		return true
+	})
+	slices.Sort(keys)
+
+	h := fnv.New64a()
+	for _, k := range keys {
+		v, _ := attrs.Get(k)
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write([]byte(v.AsString()))
+		h.Write([]byte{0})
+	}
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// recordResource remembers the attributes of a resource hash for the debug
+// endpoint. It is called when a count grouped by_resource is first created,
+// so only once for each resource and grouping.
+func recordResource(hash string, attrs pcommon.Map) {
+	resourcesLock.RLock()
+	_, exists := resources[hash]
+	resourcesLock.RUnlock()
+	if exists {
+		return
+	}
+
+	raw := attrs.AsRaw()
+	resourcesLock.Lock()
+	resources[hash] = raw
+	resourcesLock.Unlock()
+}
+
+// registerDebugEndpoint serves the resource hash lookup and the grouping
+// controls on the configured debug endpoint, once for all processors
+// configuring the same one.
+func registerDebugEndpoint(p *telemetryStatsProcessor) error {
+	endpoint := p.config.DebugEndpoint
+
+	debugEndpointsLock.Lock()
+	defer debugEndpointsLock.Unlock()
+
+	d, exists := debugEndpoints[endpoint]
+	if !exists {
+		d = &debugEndpoint{reverts: make(map[string]*groupingRevert)}
+		// The resources are registered first, so that they can be
+		// unregistered while holding the lock, which the grouping
+		// controls need to complete their requests.
+		paths := []string{resourcesPath, groupingsPath}
+		handlers := []http.HandlerFunc{serveResources, d.serveGroupings}
+		for i, path := range paths {
+			registration, err := httpregistry.Register(
+				p.config.serverConfig(endpoint),
+				path,
+				handlers[i],
+				p.logger,
+			)
+			if err != nil {
+				for _, r := range d.registrations {
+					r.Unregister()
+				}
+				return fmt.Errorf("failed to register debug endpoint: %w", err)
+			}
+			d.registrations = append(d.registrations, registration)
+		}
+		debugEndpoints[endpoint] = d
+	}
+	d.processors = append(d.processors, p)
+	return nil
+}
+
+// unregisterDebugEndpoint stops serving the debug endpoint once the last
+// processor configuring it is shut down.
+func unregisterDebugEndpoint(p *telemetryStatsProcessor) {
+	endpoint := p.config.DebugEndpoint
+
+	debugEndpointsLock.Lock()
+	d, exists := debugEndpoints[endpoint]
+	if !exists {
+		debugEndpointsLock.Unlock()
+		return
+	}
+	d.processors = slices.DeleteFunc(d.processors,
+		func(other *telemetryStatsProcessor) bool { return other == p })
+	// Stop the reverts of the groupings no remaining processor configures,
+	// so that none fires after shutdown. Deleting them also makes a revert
+	// already waiting for the lock do nothing.
+	for name, revert := range d.reverts {
+		if !d.configuresGrouping(name) {
+			revert.timer.Stop()
+			delete(d.reverts, name)
+		}
+	}
+	if len(d.processors) > 0 {
+		debugEndpointsLock.Unlock()
+		return
+	}
+	delete(debugEndpoints, endpoint)
+	debugEndpointsLock.Unlock()
+
+	// Unregister without holding the lock, since unregistering waits for
+	// in progress requests that need the lock to complete.
+	for _, r := range d.registrations {
+		r.Unregister()
+	}
+}
+
+func serveResources(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	resourcesLock.RLock()
+	data, err := json.Marshal(resources)
+	resourcesLock.RUnlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
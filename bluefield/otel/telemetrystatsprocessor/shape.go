@@ -0,0 +1,73 @@
+package telemetrystatsprocessor
+
+import (
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// countLogsShape counts the resource and scope entries of a logs batch, if
+// configured.
+func (p *telemetryStatsProcessor) countLogsShape(ld plog.Logs) {
+	rls := ld.ResourceLogs()
+	if p.config.CountResources {
+		p.resourcesProcessed.Add(int64(rls.Len()))
+	}
+	if p.config.CountScopes {
+		scopes := 0
+		for i := 0; i < rls.Len(); i++ {
+			scopes += rls.At(i).ScopeLogs().Len()
+		}
+		p.scopesProcessed.Add(int64(scopes))
+	}
+}
+
+// countMetricsShape counts the resource and scope entries of a metrics batch,
+// if configured. It must be called before the metric stats are appended to the
+// batch.
+func (p *telemetryStatsProcessor) countMetricsShape(md pmetric.Metrics) {
+	rms := md.ResourceMetrics()
+	if p.config.CountResources {
+		p.resourcesProcessed.Add(int64(rms.Len()))
+	}
+	if p.config.CountScopes {
+		scopes := 0
+		for i := 0; i < rms.Len(); i++ {
+			scopes += rms.At(i).ScopeMetrics().Len()
+		}
+		p.scopesProcessed.Add(int64(scopes))
+	}
+}
+
+// shapeStats returns the resource and scope entries counted, as configured,
+// labeled with the signal of the processor. Labels conflicting with a
+// configured label are renamed with the prefix.
+func (p *telemetryStatsProcessor) shapeStats(
+	signal string,
+	prefix string,
+) []telemetryStatsDatapoint {
+	var datapoints []telemetryStatsDatapoint
+	add := func(name string, value int64) {
+		labels := map[string]string{
+			"source": sourceStr,
+			"signal": signal,
+		}
+		for _, configuredLabel := range p.config.Labels {
+			if value, exists := labels[configuredLabel.Name]; exists {
+				delete(labels, configuredLabel.Name)
+				labels[prefix+configuredLabel.Name] = value
+			}
+		}
+		datapoints = append(datapoints, telemetryStatsDatapoint{
+			name:   p.telemetryStatName(name),
+			value:  value,
+			labels: labels,
+		})
+	}
+	if p.config.CountResources {
+		add("resources_total", p.resourcesProcessed.Load())
+	}
+	if p.config.CountScopes {
+		add("scopes_total", p.scopesProcessed.Load())
+	}
+	return datapoints
+}
@@ -0,0 +1,112 @@
+package telemetrystatsprocessor
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// groupingSummary is the count of a grouping in a summary interval.
+type groupingSummary struct {
+	Grouping string `json:"grouping"`
+	Signal   string `json:"signal"`
+	Count    int64  `json:"count"`
+}
+
+// statsSummary holds the totals of the previous summary, to summarize the
+// counts of each interval.
+type statsSummary struct {
+	logRecords int64
+	datapoints int64
+	rejected   int64
+	groupings  map[string]int64
+}
+
+func (p *telemetryStatsProcessor) summaryLoop() {
+	defer p.stopWaiters.Done()
+
+	ticker := time.NewTicker(p.config.SummaryLogInterval)
+	defer ticker.Stop()
+
+	previous := &statsSummary{groupings: make(map[string]int64)}
+	for {
+		select {
+		case <-ticker.C:
+			previous = p.logSummary(previous)
+		case <-p.stopChannel:
+			return
+		}
+	}
+}
+
+// logSummary writes a log record summarizing the counts since the previous
+// summary, and returns the totals to summarize the next interval.
+func (p *telemetryStatsProcessor) logSummary(previous *statsSummary) *statsSummary {
+	current := &statsSummary{
+		logRecords: p.logRecordsProcessed.Load(),
+		datapoints: p.datapointsProcessed.Load(),
+		groupings:  make(map[string]int64),
+	}
+	if p.push != nil {
+		current.rejected = p.push.rejected.Load()
+	}
+
+	p.logCountsRWLock.RLock()
+	for key, count := range p.logCounts {
+		current.groupings["logs:"+groupingOfKey(key)] += count
+	}
+	p.logCountsRWLock.RUnlock()
+	p.metricCountsRWLock.RLock()
+	for key, count := range p.metricCounts {
+		current.groupings["metrics:"+groupingOfKey(key)] += count
+	}
+	p.metricCountsRWLock.RUnlock()
+
+	groupings := make([]groupingSummary, 0, len(current.groupings))
+	for key, total := range current.groupings {
+		if count := total - previous.groupings[key]; count > 0 {
+			signal, name, _ := strings.Cut(key, ":")
+			groupings = append(groupings, groupingSummary{
+				Grouping: name,
+				Signal:   signal,
+				Count:    count,
+			})
+		}
+	}
+	slices.SortFunc(groupings, func(a, b groupingSummary) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Grouping, b.Grouping)
+	})
+	if len(groupings) > p.config.SummaryLogTopGroupings {
+		groupings = groupings[:p.config.SummaryLogTopGroupings]
+	}
+
+	fields := []zap.Field{
+		zap.Duration("interval", p.config.SummaryLogInterval),
+		zap.Int64("log_records", current.logRecords-previous.logRecords),
+		zap.Int64("log_records_total", current.logRecords),
+		zap.Int64("datapoints", current.datapoints-previous.datapoints),
+		zap.Int64("datapoints_total", current.datapoints),
+		zap.Any("top_groupings", groupings),
+	}
+	if p.push != nil {
+		fields = append(fields,
+			zap.Int64("pushed_counters_rejected",
+				current.rejected-previous.rejected),
+			zap.Int64("pushed_counters_rejected_total", current.rejected))
+	}
+	p.logger.Info("Telemetry stats summary", fields...)
+
+	return current
+}
+
+// groupingOfKey returns the grouping name a metric or log key starts with.
+func groupingOfKey(key string) string {
+	name, _, _ := strings.Cut(key, ":")
+	return name
+}
@@ -2,17 +2,28 @@ package telemetrystatsprocessor
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/exporter"
+	"go.opentelemetry.io/collector/extension/auth"
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.uber.org/zap"
+
+	"otelcommon/filter"
+	"otelcommon/httpregistry"
+	"otelcommon/pdataiter"
+	"otelcommon/protosize"
+	"telemetrystatsprocessor/receiverstamp"
 )
 
 var (
@@ -27,34 +38,136 @@ var (
 	telemetryStatCountsReporter     *telemetryStatsProcessor
 	telemetryStatCountsReporterLock sync.Mutex
 
-	// regular expressions
-	rePromInvalid = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+	// log records and metric datapoints seen by any processor instance
+	processedTotal atomic.Int64
 )
 
 type telemetryStatsProcessor struct {
 	logger             *zap.Logger
 	config             *Config
 	logCounts          map[string]int64
+	logUpdates         map[string]time.Time // guarded by logCountsRWLock
+	logByteCounts      map[string]int64     // guarded by logCountsRWLock
 	metricCounts       map[string]int64
+	pointCounts        map[string]int64     // guarded by metricCountsRWLock
+	metricByteCounts   map[string]int64     // guarded by metricCountsRWLock
+	metricUpdates      map[string]time.Time // guarded by metricCountsRWLock
+	seriesEstimates    []*seriesEstimate    // guarded by metricCountsRWLock
 	logCountsRWLock    sync.RWMutex
 	metricCountsRWLock sync.RWMutex
-	metricStatsChannel chan telemetryStatsDatapoint
+	metricStatsChannel chan []telemetryStatsDatapoint
+	unsentMetricStats  []telemetryStatsDatapoint // of a scrape stopped by shutdown
+	nextMetrics        consumer.Metrics
+	statsResource      pcommon.Map // resource of the last metric stats
+	statsResourceLock  sync.Mutex
 	exporter           *logStatsExporter
-	stopChannel        chan struct{}
-	stopWaiters        sync.WaitGroup
+	push               *pushEndpoint
+
+	// the exporter pushing log stats with `log_stats_export: otlp`
+	logStatsOTLP        exporter.Metrics
+	logStatsOTLPStarted bool
+
+	// the logs pipeline log stats are emitted into with
+	// `log_stats_export: logs`, and whether they are
+	nextLogs            consumer.Logs
+	logStatsLogsEmitted bool
+
+	stopChannel chan struct{}
+	stopWaiters sync.WaitGroup
+
+	// whether each metric and log grouping is counted, switched at runtime
+	// on the debug endpoint
+	metricGroupingsEnabled []atomic.Bool
+	logGroupingsEnabled    []atomic.Bool
+
+	// filters of each metric grouping
+	metricMatchers []groupingMatchers
+
+	// the `top_k` of the groupings limiting their stats, by grouping name
+	metricTopK map[string]int
+	logTopK    map[string]int
+
+	// the unit and description configured for the counts of groupings, by
+	// grouping name
+	metricMetadata groupingMetadata
+	logMetadata    groupingMetadata
+
+	// the keys of the groupings with a `max_keys`, guarded by
+	// metricCountsRWLock and logCountsRWLock
+	metricKeyLimits *keyLimits
+	logKeyLimits    *keyLimits
+
+	// the expiry of the keys of the groupings with a `key_ttl`, guarded
+	// by metricCountsRWLock and logCountsRWLock, and the metric groupings
+	// reporting staleness markers for expired keys
+	metricKeyExpiry        *keyExpiry
+	logKeyExpiry           *keyExpiry
+	metricStalenessMarkers map[string]bool
+
+	// the datapoint values of the metric groupings with `value_stats` in
+	// the current scrape interval, guarded by metricCountsRWLock
+	metricValueStats *valueStats
+
+	// the previous reports of groupings reporting deltas or rates
+	metricReport *reportState
+	logReport    *reportState
+
+	// the previous reports of the other counters, with delta temporality
+	metricDeltas *deltaState
+	logDeltas    *deltaState
+
+	// log records and metric datapoints seen by this processor, for the
+	// summary log
+	logRecordsProcessed atomic.Int64
+	datapointsProcessed atomic.Int64
+
+	// resource and scope entries of the batches seen by this processor,
+	// if counted
+	resourcesProcessed atomic.Int64
+	scopesProcessed    atomic.Int64
+}
+
+// groupingMatchers are the compiled include and exclude filters of a metric
+// grouping.
+type groupingMatchers struct {
+	include *filter.MetricMatcher
+	exclude *filter.MetricMatcher
 }
 
 type logStatsExporter struct {
 	logger         *zap.Logger
-	server         *http.Server
+	registration   *httpregistry.Registration
 	processors     []*telemetryStatsProcessor
 	requestsRWLock sync.RWMutex // in progress HTTP requests
+
+	// the processor whose settings the endpoint is registered with, and
+	// the auth extension of its `log_stats_auth`, once started
+	registrar     *telemetryStatsProcessor
+	authenticator auth.Server
 }
 
 type telemetryStatsDatapoint struct {
-	name   string
-	value  int64
-	labels map[string]string
+	name        string
+	description string // set unless the description follows from the name
+	unit        string // set unless the unit follows from the name
+	value       int64
+	labels      map[string]string
+	updated     time.Time // last update of the value, if tracked
+	gauge       bool      // whether the value is a gauge rather than a counter
+	stale       bool      // whether the datapoint marks an expired series stale
+
+	// the report mode of the grouping, empty for stats not of a grouping
+	mode string
+	// the interval of deltas and rates
+	start time.Time
+	end   time.Time
+	// the value per second of rates
+	rate float64
+	// the value of stats of datapoint values other than their count
+	double   float64
+	isDouble bool
+	// the increments of the keys of distributions
+	distribution *distribution
 }
 
 // processor constructor
@@ -66,14 +179,105 @@ func newTelemetryStatsProcessor(
 		telemetryStatCounts = make(map[string]int64)
 	})
 
+	// the processor only sees groupings with their templates applied
+	config, err := config.applyTemplates()
+	if err != nil {
+		return nil, err
+	}
+
 	p := &telemetryStatsProcessor{
-		logger:      logger,
-		config:      config,
-		stopChannel: make(chan struct{}),
+		logger:        logger,
+		config:        config,
+		statsResource: pcommon.NewMap(),
+		stopChannel:   make(chan struct{}),
+	}
+
+	p.metricGroupingsEnabled = make([]atomic.Bool, len(config.MetricGroupings))
+	for i, g := range config.MetricGroupings {
+		p.metricGroupingsEnabled[i].Store(!g.Disabled)
+	}
+	p.metricMatchers, err = compileMatchers(config.MetricGroupings)
+	if err != nil {
+		return nil, err
+	}
+	p.logGroupingsEnabled = make([]atomic.Bool, len(config.LogGroupings))
+	for i, g := range config.LogGroupings {
+		p.logGroupingsEnabled[i].Store(!g.Disabled)
+	}
+	p.metricTopK = make(map[string]int)
+	p.metricMetadata = make(groupingMetadata)
+	metricMaxKeys := make(map[string]int)
+	metricKeyTTLs := make(map[string]time.Duration)
+	p.metricStalenessMarkers = make(map[string]bool)
+	metricModes := make(map[string]string)
+	// With delta temporality, groupings counting cumulatively report
+	// deltas, as do log groupings whose stats are pushed over OTLP or
+	// emitted as logs.
+	metricDeltas := config.Temporality == temporalityDelta
+	logDeltas := metricDeltas && config.LogStatsExport != logStatsExportPrometheus
+	for _, g := range config.MetricGroupings {
+		if g.TopK > 0 {
+			p.metricTopK[g.Name] = g.TopK
+		}
+		if g.Unit != "" || g.Description != "" {
+			p.metricMetadata[g.Name] = statMetadata{g.Unit, g.Description}
+		}
+		if g.MaxKeys > 0 {
+			metricMaxKeys[g.Name] = g.MaxKeys
+		}
+		if g.KeyTTL > 0 {
+			metricKeyTTLs[g.Name] = g.KeyTTL
+			if g.StalenessMarkers {
+				p.metricStalenessMarkers[g.Name] = true
+			}
+		}
+		if g.ReportMode != "" && g.ReportMode != reportModeCumulative {
+			metricModes[g.Name] = g.ReportMode
+		} else if metricDeltas {
+			metricModes[g.Name] = reportModeDelta
+		}
+	}
+	p.logTopK = make(map[string]int)
+	p.logMetadata = make(groupingMetadata)
+	logMaxKeys := make(map[string]int)
+	logKeyTTLs := make(map[string]time.Duration)
+	logModes := make(map[string]string)
+	for _, g := range config.LogGroupings {
+		if g.TopK > 0 {
+			p.logTopK[g.Name] = g.TopK
+		}
+		if g.Unit != "" || g.Description != "" {
+			p.logMetadata[g.Name] = statMetadata{g.Unit, g.Description}
+		}
+		if g.MaxKeys > 0 {
+			logMaxKeys[g.Name] = g.MaxKeys
+		}
+		if g.KeyTTL > 0 {
+			logKeyTTLs[g.Name] = g.KeyTTL
+		}
+		if g.ReportMode != "" && g.ReportMode != reportModeCumulative {
+			logModes[g.Name] = g.ReportMode
+		} else if logDeltas {
+			logModes[g.Name] = reportModeDelta
+		}
+	}
+	p.metricKeyLimits = newKeyLimits(metricMaxKeys)
+	p.logKeyLimits = newKeyLimits(logMaxKeys)
+	p.metricKeyExpiry = newKeyExpiry(metricKeyTTLs)
+	p.logKeyExpiry = newKeyExpiry(logKeyTTLs)
+	p.metricReport = newReportState(metricModes, time.Now())
+	p.logReport = newReportState(logModes, time.Now())
+	if metricDeltas {
+		p.metricDeltas = newDeltaState(time.Now())
+	}
+	if logDeltas {
+		p.logDeltas = newDeltaState(time.Now())
 	}
 
 	if len(config.LogGroupings) > 0 {
 		p.logCounts = make(map[string]int64)
+		p.logUpdates = make(map[string]time.Time)
+		p.logByteCounts = make(map[string]int64)
 		exporter, err := getLogStatsExporter(p)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create log stats exporter: %w", err)
@@ -81,23 +285,85 @@ func newTelemetryStatsProcessor(
 		p.exporter = exporter
 	}
 
+	if config.DebugEndpoint != "" {
+		if err := registerDebugEndpoint(p); err != nil {
+			if p.exporter != nil {
+				p.exporter.removeProcessor(p)
+			}
+			return nil, err
+		}
+	}
+
+	if config.PushEndpoint != "" {
+		push, err := registerPushEndpoint(config, logger)
+		if err != nil {
+			if config.DebugEndpoint != "" {
+				unregisterDebugEndpoint(p)
+			}
+			if p.exporter != nil {
+				p.exporter.removeProcessor(p)
+			}
+			return nil, err
+		}
+		p.push = push
+	}
+
 	if len(config.MetricGroupings) > 0 {
 		p.metricCounts = make(map[string]int64)
-		p.metricStatsChannel = make(chan telemetryStatsDatapoint, 128)
+		p.pointCounts = make(map[string]int64)
+		p.metricByteCounts = make(map[string]int64)
+		p.metricUpdates = make(map[string]time.Time)
+		p.metricValueStats = newValueStats(config.MetricGroupings, time.Now())
+		// nil for groupings not estimating cardinality
+		p.seriesEstimates = make([]*seriesEstimate, len(config.MetricGroupings))
+		for i, g := range config.MetricGroupings {
+			if g.EstimateCardinality {
+				p.seriesEstimates[i] = newSeriesEstimate(time.Now())
+			}
+		}
+		p.metricStatsChannel = make(chan []telemetryStatsDatapoint, 16)
 		p.stopWaiters.Add(1)
 		go p.metricStatsLoop()
 	}
 
+	if config.SummaryLogInterval > 0 {
+		p.stopWaiters.Add(1)
+		go p.summaryLoop()
+	}
+
 	return p, nil
 }
 
+// ProcessedTotal returns the number of log records and metric datapoints seen
+// by all telemetry_stats processors since the collector started, so that other
+// components can tell whether pipelines are moving data.
+func ProcessedTotal() int64 {
+	return processedTotal.Load()
+}
+
 // processor destructor
-func (p *telemetryStatsProcessor) cleanup() {
+func (p *telemetryStatsProcessor) cleanup(ctx context.Context) {
 	close(p.stopChannel)
 	p.stopWaiters.Wait()
 
+	if p.nextMetrics != nil && p.metricStatsChannel != nil {
+		p.flushMetricStats(ctx)
+	}
+	p.shutdownLogStatsOTLP(ctx)
+	if p.logStatsLogsEmitted {
+		p.emitLogStats(ctx)
+	}
+
+	if p.config.DebugEndpoint != "" {
+		unregisterDebugEndpoint(p)
+	}
+
+	if p.push != nil {
+		unregisterPushEndpoint(p.config.PushEndpoint)
+		p.push = nil
+	}
+
 	if p.exporter != nil {
-		p.exporter.Shutdown()
 		p.exporter.removeProcessor(p)
 		p.exporter = nil
 	} else {
@@ -113,26 +379,47 @@ func (p *telemetryStatsProcessor) processLogs(
 	ctx context.Context,
 	ld plog.Logs,
 ) (plog.Logs, error) {
+	processedTotal.Add(int64(ld.LogRecordCount()))
+	p.logRecordsProcessed.Add(int64(ld.LogRecordCount()))
+	p.countLogsShape(ld)
+
+	now := time.Now()
+
 	p.logCountsRWLock.Lock()
 	defer p.logCountsRWLock.Unlock()
 
-	for i := 0; i < ld.ResourceLogs().Len(); i++ {
-		rls := ld.ResourceLogs().At(i)
-		resourceAttrs := rls.Resource().Attributes()
-		for j := 0; j < rls.ScopeLogs().Len(); j++ {
-			sl := rls.ScopeLogs().At(j)
-			scopeAttrs := sl.Scope().Attributes()
-			for k := 0; k < sl.LogRecords().Len(); k++ {
-				lr := sl.LogRecords().At(k)
-				logAttrs := lr.Attributes()
-				attrs := NewAttributes(resourceAttrs, scopeAttrs, logAttrs)
-				for _, grouping := range p.config.LogGroupings {
-					key := generateLogKey(grouping, attrs)
-					p.logCounts[key]++
+	attrs := &Attributes{}
+	resourceIndex := -1
+	pdataiter.LogRecords(ld, func(lr *pdataiter.LogRecord) {
+		if lr.ResourceIndex != resourceIndex {
+			resourceIndex = lr.ResourceIndex
+			*attrs = Attributes{resource: lr.Resource.Attributes()}
+		}
+		attrs.scope = lr.Scope.Attributes()
+		attrs.datapoint = lr.Record.Attributes()
+		attrs.SetSeverity(lr.Record.SeverityNumber(), lr.Record.SeverityText())
+		attrs.SetBody(lr.Record.Body())
+		size := -1 // computed once for all groupings counting bytes
+		for i, grouping := range p.config.LogGroupings {
+			if !p.logGroupingsEnabled[i].Load() {
+				continue
+			}
+			key, overflowed := p.logKeyLimits.limit(grouping.Name,
+				generateLogKey(grouping, attrs), p.logCounts)
+			if _, exists := p.logCounts[key]; !exists &&
+				grouping.ByResource && !overflowed {
+				recordResource(attrs.resourceHash(), attrs.resource)
+			}
+			p.logCounts[key]++
+			p.logUpdates[key] = now
+			if grouping.CountBytes {
+				if size < 0 {
+					size = protosize.LogRecord(lr.Record)
 				}
+				p.logByteCounts[key] += int64(size)
 			}
 		}
-	}
+	})
 
 	return ld, nil
 }
@@ -141,20 +428,24 @@ func (p *telemetryStatsProcessor) processMetrics(
 	ctx context.Context,
 	md pmetric.Metrics,
 ) (pmetric.Metrics, error) {
+	processedTotal.Add(int64(md.DataPointCount()))
+	p.datapointsProcessed.Add(int64(md.DataPointCount()))
+	p.countMetricsShape(md)
+
 	// Step 1: Process incoming metrics from the pipeline.
+	now := time.Now()
 	p.metricCountsRWLock.Lock()
-	for i := 0; i < md.ResourceMetrics().Len(); i++ {
-		rm := md.ResourceMetrics().At(i)
-		resourceAttrs := rm.Resource().Attributes()
-		for j := 0; j < rm.ScopeMetrics().Len(); j++ {
-			sm := rm.ScopeMetrics().At(j)
-			scopeAttrs := sm.Scope().Attributes()
-			for k := 0; k < sm.Metrics().Len(); k++ {
-				metric := sm.Metrics().At(k)
-				p.processMetric(metric, resourceAttrs, scopeAttrs)
-			}
+	attrs := &Attributes{}
+	resourceIndex := -1
+	pdataiter.Datapoints(md, func(dp *pdataiter.Datapoint) {
+		if dp.ResourceIndex != resourceIndex {
+			resourceIndex = dp.ResourceIndex
+			*attrs = Attributes{resource: dp.Resource.Attributes()}
 		}
-	}
+		attrs.scope = dp.Scope.Attributes()
+		attrs.datapoint = dp.Attributes
+		p.processDatapoint(dp, attrs, now)
+	})
 	p.metricCountsRWLock.Unlock()
 
 	// Step 2: Drain p.metricStatsChannel of all available datapoints
@@ -181,23 +472,18 @@ func (p *telemetryStatsProcessor) processMetrics(
 	for _, configuredLabel := range p.config.Labels {
 		resourceAttrs.PutStr(configuredLabel.Name, configuredLabel.Value)
 	}
-	// Step 2d: Add a datapoint to the new metric stats for each item
-	// received from the channel.
+	// Step 2d: Remember the resource attributes for the final metric stats
+	// flushed on shutdown, when there are no incoming metrics to copy.
+	p.statsResourceLock.Lock()
+	resourceAttrs.CopyTo(p.statsResource)
+	p.statsResourceLock.Unlock()
+	// Step 2e: Add a datapoint to the new metric stats for each item of
+	// the scrapes received from the channel.
 	for {
 		select {
-		case dp := <-p.metricStatsChannel:
-			metric := smStats.Metrics().AppendEmpty()
-			metric.SetName(dp.name)
-			metric.SetDescription("Number of datapoints counted")
-			metric.SetUnit("1")
-			sum := metric.SetEmptySum()
-			sum.SetIsMonotonic(true)
-			sum.SetAggregationTemporality(
-				pmetric.AggregationTemporalityCumulative)
-			datapoint := sum.DataPoints().AppendEmpty()
-			datapoint.SetIntValue(dp.value)
-			for k, v := range dp.labels {
-				datapoint.Attributes().PutStr(k, v)
+		case datapoints := <-p.metricStatsChannel:
+			for _, dp := range datapoints {
+				appendMetricStat(smStats.Metrics(), dp)
 			}
 		default:
 			// No more metric stats to process
@@ -206,74 +492,248 @@ func (p *telemetryStatsProcessor) processMetrics(
 	}
 }
 
-func (p *telemetryStatsProcessor) processMetric(
-	metric pmetric.Metric,
-	resourceAttrs pcommon.Map,
-	scopeAttrs pcommon.Map,
-) {
-	// In case log stats written to the configured prometheus endpoint pass
-	// through this processor again, exclude them here.
-	if strings.HasPrefix(metric.Name(), prefixStr) {
+// flushMetricStats forwards the final metric stats to the next consumer on
+// shutdown. Receivers are stopped before processors, so no further incoming
+// metrics would carry the stats of the scrapes still waiting in the channel,
+// or the counts accumulated since the last scrape.
+func (p *telemetryStatsProcessor) flushMetricStats(ctx context.Context) {
+	md := pmetric.NewMetrics()
+	rmStats := md.ResourceMetrics().AppendEmpty()
+	p.statsResourceLock.Lock()
+	p.statsResource.CopyTo(rmStats.Resource().Attributes())
+	p.statsResourceLock.Unlock()
+	for _, configuredLabel := range p.config.Labels {
+		rmStats.Resource().Attributes().PutStr(
+			configuredLabel.Name, configuredLabel.Value)
+	}
+	smStats := rmStats.ScopeMetrics().AppendEmpty()
+	smStats.Scope().SetName(ProcessorName)
+	smStats.Scope().SetVersion(Version)
+	// The earlier scrapes come first, as the deltas and rates of the final
+	// scrape are the increments since them.
+	for queued := true; queued; {
+		select {
+		case datapoints := <-p.metricStatsChannel:
+			for _, dp := range datapoints {
+				appendMetricStat(smStats.Metrics(), dp)
+			}
+		default:
+			queued = false
+		}
+	}
+	if p.unsentMetricStats != nil {
+		for _, dp := range p.unsentMetricStats {
+			appendMetricStat(smStats.Metrics(), dp)
+		}
+		p.unsentMetricStats = nil
+		p.commitMetricStats()
+	}
+	for _, dp := range p.generateMetricStats() {
+		appendMetricStat(smStats.Metrics(), dp)
+	}
+	p.commitMetricStats()
+	if smStats.Metrics().Len() == 0 {
 		return
 	}
 
-	for i := range p.config.MetricGroupings {
-		grouping := &p.config.MetricGroupings[i]
-		p.processMetricGrouping(metric, grouping, resourceAttrs, scopeAttrs)
+	if err := p.nextMetrics.ConsumeMetrics(ctx, md); err != nil {
+		p.logger.Error("Failed to flush metric stats", zap.Error(err))
 	}
 }
 
-func (p *telemetryStatsProcessor) processMetricGrouping(
-	metric pmetric.Metric,
-	grouping *MetricGrouping,
-	resourceAttrs pcommon.Map,
-	scopeAttrs pcommon.Map,
-) {
-	var datapointCount int
-
-	switch metric.Type() {
-	case pmetric.MetricTypeGauge:
-		datapointCount = metric.Gauge().DataPoints().Len()
-	case pmetric.MetricTypeSum:
-		datapointCount = metric.Sum().DataPoints().Len()
-	case pmetric.MetricTypeHistogram:
-		datapointCount = metric.Histogram().DataPoints().Len()
-	case pmetric.MetricTypeSummary:
-		datapointCount = metric.Summary().DataPoints().Len()
-	default:
-		return // ignore unsupported metric type
+// counterName returns the name of the counter a stat is of, that of the stat
+// itself unless it is a rate or distribution.
+func (dp *telemetryStatsDatapoint) counterName() string {
+	switch dp.mode {
+	case reportModeRate:
+		return strings.TrimSuffix(dp.name, "_per_second") + "_total"
+	case reportModeDistribution:
+		return strings.TrimSuffix(dp.name, "_per_key") + "_total"
 	}
+	return dp.name
+}
 
-	// Process datapoints
-	for i := 0; i < datapointCount; i++ {
-		var datapointAttrs pcommon.Map
+// metadata returns the description and unit of a stat. Those of rates and
+// distributions are derived from the counter they are of.
+func (dp *telemetryStatsDatapoint) metadata() (string, string) {
+	name := dp.counterName()
+	unit := "1"
+	description := dp.description
+	switch {
+	case description != "":
+	case isStat(name, "log_records_total"):
+		description = "Number of log records counted"
+	case isStat(name, "resources_total"):
+		description = "Number of resource entries of the batches processed"
+	case isStat(name, "scopes_total"):
+		description = "Number of scope entries of the batches processed"
+	case isStat(name, "points_total"):
+		description = "Number of histogram buckets, summary quantiles " +
+			"and other datapoints counted"
+	case isStat(name, "bytes_total"):
+		description = "Serialized size of the datapoints or log records counted"
+		unit = "By"
+	case isStat(name, "active_series"):
+		description = "Estimated number of distinct series seen in the " +
+			"cardinality window"
+	case isStat(name, "value_min"):
+		description = "Minimum of the datapoint values in the interval"
+		unit = ""
+	case isStat(name, "value_max"):
+		description = "Maximum of the datapoint values in the interval"
+		unit = ""
+	case isStat(name, "value_avg"):
+		description = "Average of the datapoint values in the interval"
+		unit = ""
+	case isStat(name, "value_sum"):
+		description = "Sum of the datapoint values in the interval"
+		unit = ""
+	case isStat(name, "value_count"):
+		description = "Number of datapoint values in the interval"
+	default:
+		description = "Number of datapoints counted"
+	}
+	if dp.unit != "" {
+		unit = dp.unit
+	}
+	switch dp.mode {
+	case reportModeRate:
+		description += " per second"
+		unit += "/s"
+	case reportModeDistribution:
+		description += " per key in the interval"
+	}
+	return description, unit
+}
 
-		switch metric.Type() {
-		case pmetric.MetricTypeGauge:
-			datapointAttrs = metric.Gauge().DataPoints().At(i).Attributes()
-		case pmetric.MetricTypeSum:
-			datapointAttrs = metric.Sum().DataPoints().At(i).Attributes()
-		case pmetric.MetricTypeHistogram:
-			datapointAttrs = metric.Histogram().DataPoints().At(i).Attributes()
-		case pmetric.MetricTypeSummary:
-			datapointAttrs = metric.Summary().DataPoints().At(i).Attributes()
+func appendMetricStat(metrics pmetric.MetricSlice, dp telemetryStatsDatapoint) {
+	metric := metrics.AppendEmpty()
+	metric.SetName(dp.name)
+	description, unit := dp.metadata()
+	metric.SetDescription(description)
+	metric.SetUnit(unit)
+	if dp.distribution != nil {
+		histogram := metric.SetEmptyExponentialHistogram()
+		histogram.SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+		datapoint := histogram.DataPoints().AppendEmpty()
+		datapoint.SetStartTimestamp(pcommon.NewTimestampFromTime(dp.start))
+		datapoint.SetTimestamp(pcommon.NewTimestampFromTime(dp.end))
+		dp.distribution.copyTo(datapoint)
+		for k, v := range dp.labels {
+			datapoint.Attributes().PutStr(k, v)
+		}
+		return
+	}
+	var datapoint pmetric.NumberDataPoint
+	if dp.gauge || dp.mode == reportModeRate {
+		datapoint = metric.SetEmptyGauge().DataPoints().AppendEmpty()
+	} else {
+		sum := metric.SetEmptySum()
+		sum.SetIsMonotonic(true)
+		if dp.mode == reportModeDelta {
+			sum.SetAggregationTemporality(
+				pmetric.AggregationTemporalityDelta)
+		} else {
+			sum.SetAggregationTemporality(
+				pmetric.AggregationTemporalityCumulative)
+		}
+		datapoint = sum.DataPoints().AppendEmpty()
+	}
+	if !dp.end.IsZero() {
+		datapoint.SetStartTimestamp(pcommon.NewTimestampFromTime(dp.start))
+		datapoint.SetTimestamp(pcommon.NewTimestampFromTime(dp.end))
+	}
+	if dp.mode == reportModeRate {
+		datapoint.SetDoubleValue(dp.rate)
+	} else if dp.isDouble {
+		datapoint.SetDoubleValue(dp.double)
+	} else {
+		datapoint.SetIntValue(dp.value)
+	}
+	if dp.stale {
+		// exported to Prometheus as a staleness marker, which needs a
+		// timestamp
+		datapoint.SetFlags(pmetric.DefaultDataPointFlags.WithNoRecordedValue(true))
+		if datapoint.Timestamp() == 0 {
+			datapoint.SetTimestamp(pcommon.NewTimestampFromTime(time.Now()))
 		}
-
-		attrs := NewAttributes(resourceAttrs, scopeAttrs, datapointAttrs)
-		p.processDatapoint(metric, grouping, attrs)
+	}
+	for k, v := range dp.labels {
+		datapoint.Attributes().PutStr(k, v)
 	}
 }
 
+// processDatapoint counts a datapoint in each metric grouping including it,
+// and if the grouping counts points, the number of buckets or quantiles it
+// consists of, and if the grouping counts bytes, its serialized size. Groupings
+// estimating cardinality add the series of the datapoint to their sketch.
+// Groupings with a `key_ttl` record when the key was updated, and groupings
+// with `value_stats` summarize the value of gauge and sum datapoints.
 func (p *telemetryStatsProcessor) processDatapoint(
-	metric pmetric.Metric,
-	grouping *MetricGrouping,
+	dp *pdataiter.Datapoint,
 	attrs *Attributes,
+	now time.Time,
 ) {
-	if !includeMetricDatapoint(grouping, metric, attrs) {
+	metric := dp.Metric
+	// In case log stats written to the configured prometheus endpoint pass
+	// through this processor again, exclude them here.
+	if strings.HasPrefix(metric.Name(), p.config.MetricPrefix+"_") {
 		return
 	}
-	key := generateMetricKey(grouping, metric, attrs)
-	p.metricCounts[key]++
+	if metric.Type() == pmetric.MetricTypeExponentialHistogram {
+		return // ignore unsupported metric type
+	}
+
+	size := -1 // computed once for all groupings counting bytes
+	var series uint64
+	seriesHashed := false
+	var value float64
+	hasValue, valueRead := false, false
+	for i := range p.config.MetricGroupings {
+		if !p.metricGroupingsEnabled[i].Load() {
+			continue
+		}
+		grouping := &p.config.MetricGroupings[i]
+		matchers := &p.metricMatchers[i]
+		if !filter.IncludeMetric(matchers.include, matchers.exclude, metric, attrs) {
+			continue
+		}
+		key, overflowed := p.metricKeyLimits.limit(grouping.Name,
+			generateMetricKey(grouping, metric, attrs), p.metricCounts)
+		if _, exists := p.metricCounts[key]; !exists && grouping.ByResource &&
+			!overflowed {
+			recordResource(attrs.resourceHash(), attrs.resource)
+		}
+		p.metricCounts[key]++
+		if p.metricKeyExpiry.tracks(grouping.Name) {
+			p.metricUpdates[key] = now
+		}
+		if grouping.CountPoints {
+			p.pointCounts[key] += int64(dp.Points)
+		}
+		if grouping.CountBytes {
+			if size < 0 {
+				size = protosize.Datapoint(metric, dp.Index)
+			}
+			p.metricByteCounts[key] += int64(size)
+		}
+		if grouping.EstimateCardinality {
+			if !seriesHashed {
+				series = seriesHash(metric, attrs)
+				seriesHashed = true
+			}
+			p.seriesEstimates[i].current.add(series)
+		}
+		if p.metricValueStats.tracks(grouping.Name) {
+			if !valueRead {
+				value, hasValue = datapointValue(metric, dp.Index)
+				valueRead = true
+			}
+			if hasValue {
+				p.metricValueStats.add(key, value)
+			}
+		}
+	}
 }
 
 func (p *telemetryStatsProcessor) metricStatsLoop() {
@@ -293,62 +753,174 @@ func (p *telemetryStatsProcessor) metricStatsLoop() {
 }
 
 func (p *telemetryStatsProcessor) scrapeMetricStats() {
+	// Send the generated datapoints to the channel read by
+	// processMetrics(), blocking whenever the channel is full. Each call to
+	// process incoming metrics will drain the channel until all data points
+	// have been added to the pipeline. Deltas and rates are only committed
+	// once sent, as the scrapes in the channel are flushed on shutdown.
+	datapoints := p.generateMetricStats()
+	select {
+	case p.metricStatsChannel <- datapoints:
+		p.commitMetricStats()
+	case <-p.stopChannel:
+		// flushed and committed on shutdown, before the final scrape
+		p.unsentMetricStats = datapoints
+	}
+}
+
+// commitMetricStats advances the report and delta state past the metric stats
+// last generated, once they are sent.
+func (p *telemetryStatsProcessor) commitMetricStats() {
+	p.metricReport.commit()
+	if p.metricDeltas != nil {
+		p.metricDeltas.commit()
+	}
+}
+
+func (p *telemetryStatsProcessor) generateMetricStats() []telemetryStatsDatapoint {
+	// Step 0: Start new cardinality windows of groupings whose current
+	// window is over, evict the keys that expired, and end the interval of
+	// the value stats.
+	now := time.Now()
+	p.metricCountsRWLock.Lock()
+	for _, estimate := range p.seriesEstimates {
+		if estimate != nil {
+			estimate.rotate(now, p.config.CardinalityWindow)
+		}
+	}
+	staleMarkers := p.evictMetricKeys(now)
+	values, valuesSince := p.metricValueStats.rotate(now)
+	p.metricCountsRWLock.Unlock()
+
 	// Step 1: While holding the read lock, traverse the map of accumulated
-	// metric counts and generate a datapoint for each map entry.
+	// metric counts and generate a datapoint for each map entry. Entries
+	// outside the top K of their grouping are summed per grouping, and
+	// the increments of groupings reporting distributions are observed
+	// in one distribution per grouping.
+	p.metricReport.begin(now)
 	p.metricCountsRWLock.RLock()
-	datapoints := make([]telemetryStatsDatapoint, 0, len(p.metricCounts))
-	for key, count := range p.metricCounts {
-		parts := strings.Split(key, ":")
-		labels := make(map[string]string)
-		labels["source"] = sourceStr
-		labels["grouping"] = parts[0]
-		for _, part := range parts[1:] {
-			kv := strings.SplitN(part, "=", 2)
-			if len(kv) == 2 {
-				switch kv[0] {
-				case "__name":
-					labels["metric_name"] = kv[1]
-				case "__type":
-					labels["metric_type"] = kv[1]
-				default:
-					labels[kv[0]] = kv[1]
-				}
+	datapoints := make([]telemetryStatsDatapoint, 0,
+		len(p.metricCounts)+len(p.pointCounts)+len(p.metricByteCounts))
+	others := topKOthers(p.metricCounts, p.metricTopK)
+	otherStats := make(otherBuckets)
+	distributions := make(distributionBuckets)
+	for _, counts := range []struct {
+		name   string
+		counts map[string]int64
+	}{
+		{p.telemetryStatName("datapoints_total"), p.metricCounts},
+		{p.telemetryStatName("points_total"), p.pointCounts},
+		{p.telemetryStatName("bytes_total"), p.metricByteCounts},
+	} {
+		for key, count := range counts.counts {
+			mode := p.metricReport.mode(key)
+			value := p.metricReport.value(counts.name, key, count)
+			if mode == reportModeDistribution {
+				distributions.add(counts.name, key, value)
+				continue
 			}
-		}
-		for _, configuredLabel := range p.config.Labels {
-			// If a configured label would overwrite an existing
-			// label, rename the existing label. The configured
-			// label will be written later as a resource attribute.
-			if value, exists := labels[configuredLabel.Name]; exists {
-				delete(labels, configuredLabel.Name)
-				labels["metric_"+configuredLabel.Name] = value
+			if others[key] {
+				otherStats.add(counts.name, key, mode, value, time.Time{})
+				continue
 			}
+			datapoints = append(datapoints, telemetryStatsDatapoint{
+				name:   counts.name,
+				value:  value,
+				labels: p.metricStatLabels(key),
+				mode:   mode,
+			})
+		}
+	}
+	datapoints = append(datapoints, otherStats.datapoints(p.metricStatLabels)...)
+	datapoints = append(datapoints, distributions.datapoints(p.metricStatLabels)...)
+	if len(values) > 0 {
+		datapoints = append(datapoints, p.metricValueStats.datapoints(values,
+			valuesSince, now, others, p.telemetryStatName,
+			p.metricStatLabels)...)
+	}
+	for i, estimate := range p.seriesEstimates {
+		if estimate == nil {
+			continue
 		}
 		datapoints = append(datapoints, telemetryStatsDatapoint{
-			name:   telemetryStatName("datapoints_total"),
-			value:  count,
-			labels: labels,
+			name:   p.telemetryStatName("active_series"),
+			value:  estimate.estimate(),
+			labels: p.metricStatLabels(p.config.MetricGroupings[i].Name),
+			gauge:  true,
 		})
 	}
+	datapoints = append(datapoints, p.metricKeyLimits.datapoints(
+		p.telemetryStatName("overflow_keys_total"), p.metricStatLabels)...)
+	datapoints = append(datapoints, p.metricKeyExpiry.datapoints(
+		p.telemetryStatName("expired_keys_total"), p.metricStatLabels)...)
+	datapoints = append(datapoints, staleMarkers...)
 	p.metricCountsRWLock.RUnlock()
+	p.metricReport.finish(datapoints)
+	p.metricMetadata.apply(datapoints, "datapoints_total")
 
 	if p.config.IncludeTelemetryStats {
-		p.updateTelemetryStatCounts(datapoints, telemetryStatName("datapoints_total"))
+		p.updateTelemetryStatCounts(datapoints, p.telemetryStatName("datapoints_total"))
 	}
 
-	// Step 2: Without holding the read lock, send the generated datapoints
-	// to the channel read by processMetrics(), blocking whenever the
-	// channel is full. Each call to process incoming metrics will drain
-	// the channel until all data points have been added to the pipeline.
-	for _, dp := range datapoints {
-		p.metricStatsChannel <- dp
-	}
+	// Step 2: Without holding the read lock, add the telemetry stat counts
+	// if this processor reports them.
 	if p.isReportTelemetryStatCounts() {
-		statDatapoints := p.getTelemetryStatCounts()
-		for _, dp := range statDatapoints {
-			p.metricStatsChannel <- dp
+		datapoints = append(datapoints, p.getTelemetryStatCounts()...)
+	}
+
+	// Step 3: Add the counters pushed by local agents.
+	if p.push != nil {
+		datapoints = append(datapoints,
+			p.push.pushedStats(p, "metric_")...)
+	}
+
+	// Step 4: Add the resource and scope entries counted, if configured.
+	datapoints = append(datapoints, p.shapeStats("metrics", "metric_")...)
+
+	// Step 5: Report the other counters as deltas, with delta temporality.
+	if p.metricDeltas != nil {
+		p.metricDeltas.convert(datapoints, now)
+	}
+
+	return datapoints
+}
+
+// metricStatLabels returns the labels of the metric stats generated for a
+// metric key.
+func (p *telemetryStatsProcessor) metricStatLabels(key string) map[string]string {
+	parts := strings.Split(key, ":")
+	labels := make(map[string]string)
+	labels["source"] = sourceStr
+	labels["grouping"] = parts[0]
+	for _, part := range parts[1:] {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) == 2 {
+			switch kv[0] {
+			case "__name":
+				labels["metric_name"] = kv[1]
+			case "__type":
+				labels["metric_type"] = kv[1]
+			case "__resource":
+				labels["resource_hash"] = kv[1]
+			case "__receiver":
+				labels["receiver"] = kv[1]
+			case "__overflow":
+				labels["grouping_overflow"] = kv[1]
+			default:
+				labels[kv[0]] = kv[1]
+			}
+		}
+	}
+	for _, configuredLabel := range p.config.Labels {
+		// If a configured label would overwrite an existing
+		// label, rename the existing label. The configured
+		// label will be written later as a resource attribute.
+		if value, exists := labels[configuredLabel.Name]; exists {
+			delete(labels, configuredLabel.Name)
+			labels["metric_"+configuredLabel.Name] = value
 		}
 	}
+	return labels
 }
 
 // Limit reporting of telemetry stat counts to a single processor on each
@@ -384,7 +956,7 @@ func (p *telemetryStatsProcessor) updateTelemetryStatCounts(
 
 func (p *telemetryStatsProcessor) getTelemetryStatCounts() []telemetryStatsDatapoint {
 	var statDatapointsCount int
-	thisStatName := telemetryStatName("datapoints_total")
+	thisStatName := p.telemetryStatName("datapoints_total")
 
 	telemetryStatCountsLock.Lock()
 	if _, exists := telemetryStatCounts[thisStatName]; !exists {
@@ -429,37 +1001,21 @@ func getLogStatsExporter(p *telemetryStatsProcessor) (*logStatsExporter, error)
 	e.requestsRWLock.Lock()
 	defer e.requestsRWLock.Unlock()
 
-	if e.server == nil {
-		server := &http.Server{
-			Addr:    p.config.GetLogStatsEndpoint(),
-			Handler: e,
-		}
-
-		errorChannel := make(chan error, 1)
-		go func() {
-			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-				p.logger.Error("HTTP server error",
-					zap.Error(err),
-					zap.String("address", server.Addr),
-				)
-				errorChannel <- err
-			}
-		}()
-
-		// Wait a short time to check whether the server failed to start
-		var serverErr error
-		select {
-		case err := <-errorChannel:
-			serverErr = fmt.Errorf("failed to start server: %w", err)
-		case <-time.After(100 * time.Millisecond):
-			// done waiting for it to fail
-		}
-
-		if serverErr != nil {
-			return nil, fmt.Errorf("failed to start server: %w", serverErr)
+	// Processors pushing log stats over OTLP are only listed, for
+	// LogGroupingCounts.
+	if e.registration == nil && p.servesLogStats() {
+		registration, err := httpregistry.Register(
+			p.config.serverConfig(p.config.GetLogStatsEndpoint()),
+			"/metrics",
+			e,
+			p.logger,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to register log stats endpoint: %w", err)
 		}
-
-		e.server = server
+		e.registration = registration
+		e.registrar = p
+		e.authenticator = nil
 	}
 
 	e.processors = append(e.processors, p)
@@ -467,113 +1023,171 @@ func getLogStatsExporter(p *telemetryStatsProcessor) (*logStatsExporter, error)
 	return e, nil
 }
 
-// logStatsExporter destructor
-func (e *logStatsExporter) Shutdown() {
-	e.requestsRWLock.Lock() // Block new requests and wait for existing ones to complete
-	defer e.requestsRWLock.Unlock()
-
-	if e.server == nil {
-		return // server is already shutdown
-	}
-
-	e.logger.Info("Shutting down log stats processor HTTP server")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	// Attempt to gracefully shut down the server
-	err := e.server.Shutdown(ctx)
-	if err != nil {
-		e.logger.Error("Error shutting down log stats processor HTTP server",
-			zap.Error(err))
-
-		// If graceful shutdown fails, force close
-		if closeErr := e.server.Close(); closeErr != nil {
-			e.logger.Error("Error closing log stats processor HTTP server",
-				zap.Error(closeErr))
-		}
-	} else {
-		e.logger.Info("log stats processor HTTP server shut down successfully")
+// ServeHTTP writes the log stats in OpenMetrics if the scraper accepts it or
+// "?format=openmetrics" is requested, and otherwise in the prometheus text
+// format, or as JSON with "?format=json" for local tools that can't parse the
+// text format.
+func (e *logStatsExporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "prometheus" && format != "openmetrics" &&
+		format != "json" {
+		http.Error(w, fmt.Sprintf("unsupported format %q", format),
+			http.StatusBadRequest)
+		return
 	}
 
-	e.server = nil
-}
-
-func (e *logStatsExporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	e.requestsRWLock.RLock()
 	defer e.requestsRWLock.RUnlock()
 
-	if r.URL.Path != "/metrics" {
-		http.NotFound(w, r)
+	if !e.authenticate(w, r) {
 		return
 	}
 
+	var datapoints []telemetryStatsDatapoint
+	var processors []*telemetryStatsProcessor
 	for _, processor := range e.processors {
-		scrapeLogStats(w, processor)
+		if processor.servesLogStats() {
+			processors = append(processors, processor)
+			datapoints = append(datapoints, scrapeLogStats(processor)...)
+		}
+	}
+
+	// Counters pushed by local agents are written here by processors
+	// without metric groupings, once for each push endpoint.
+	written := make(map[*pushEndpoint]bool)
+	for _, processor := range processors {
+		push := processor.push
+		if push == nil || written[push] || len(processor.config.MetricGroupings) > 0 {
+			continue
+		}
+		written[push] = true
+		datapoints = append(datapoints,
+			push.pushedStats(processor, "log_")...)
 	}
 
-	if len(e.processors) > 0 {
-		p := e.processors[0]
+	if len(processors) > 0 {
+		p := processors[0]
 		if p.config.IncludeTelemetryStats && len(p.config.MetricGroupings) == 0 {
-			statDatapoints := p.getTelemetryStatCounts()
-			for _, dp := range statDatapoints {
-				formattedLabels := formatLabels(dp.labels)
-				fmt.Fprintf(w, "%s{%s} %d\n", dp.name, formattedLabels, dp.value)
-			}
+			datapoints = append(datapoints, p.getTelemetryStatCounts()...)
 		}
 	}
+
+	if format == "json" {
+		writeLogStatsJSON(w, datapoints)
+		return
+	}
+	openMetrics := format == "openmetrics" || format == "" && acceptsOpenMetrics(r)
+	writeExposition(w, datapoints, openMetrics)
 }
 
-func scrapeLogStats(w http.ResponseWriter, p *telemetryStatsProcessor) {
-	// Step 1: While holding the read lock, traverse the map of accumulated
-	// log counts and generate a datapoint for each map entry.
+// scrapeLogStats returns a datapoint for each accumulated log count of the
+// processor.
+func scrapeLogStats(p *telemetryStatsProcessor) []telemetryStatsDatapoint {
+	// Evict the keys that expired, then while holding the read lock,
+	// traverse the map of accumulated log counts and generate a datapoint
+	// for each map entry. Entries outside the top K of their grouping are
+	// summed per grouping.
+	now := time.Now()
+	if len(p.logKeyExpiry.ttls) > 0 {
+		p.logCountsRWLock.Lock()
+		p.evictLogKeys(now)
+		p.logCountsRWLock.Unlock()
+	}
+	p.logReport.begin(now)
 	p.logCountsRWLock.RLock()
-	datapoints := make([]telemetryStatsDatapoint, 0, len(p.logCounts))
-	for key, count := range p.logCounts {
-		parts := strings.Split(key, ":")
-		labels := make(map[string]string)
-		labels["source"] = sourceStr
-		labels["grouping"] = parts[0]
-		for _, part := range parts[1:] {
-			kv := strings.SplitN(part, "=", 2)
-			if len(kv) == 2 {
-				labels[kv[0]] = kv[1]
-			}
-		}
-		for _, configuredLabel := range p.config.Labels {
-			// If a configured label would overwrite an existing
-			// label, rename the existing label.
-			if value, exists := labels[configuredLabel.Name]; exists {
-				delete(labels, configuredLabel.Name)
-				labels["log_"+configuredLabel.Name] = value
+	datapoints := make([]telemetryStatsDatapoint, 0,
+		len(p.logCounts)+len(p.logByteCounts))
+	others := topKOthers(p.logCounts, p.logTopK)
+	otherStats := make(otherBuckets)
+	for _, counts := range []struct {
+		name   string
+		counts map[string]int64
+	}{
+		{p.telemetryStatName("log_records_total"), p.logCounts},
+		{p.telemetryStatName("bytes_total"), p.logByteCounts},
+	} {
+		for key, count := range counts.counts {
+			mode := p.logReport.mode(key)
+			value := p.logReport.value(counts.name, key, count)
+			if others[key] {
+				otherStats.add(counts.name, key, mode, value, p.logUpdates[key])
+				continue
 			}
-			// The pipeline that receives log stats from the
-			// prometheus endpoint is responsible for writing the
-			// configured label as a resource attribute.
+			datapoints = append(datapoints, telemetryStatsDatapoint{
+				name:    counts.name,
+				value:   value,
+				labels:  p.logStatLabels(key),
+				updated: p.logUpdates[key],
+				mode:    mode,
+			})
 		}
-		datapoints = append(datapoints, telemetryStatsDatapoint{
-			name:   telemetryStatName("log_records_total"),
-			value:  count,
-			labels: labels,
-		})
 	}
+	datapoints = append(datapoints, otherStats.datapoints(p.logStatLabels)...)
+	datapoints = append(datapoints, p.logKeyLimits.datapoints(
+		p.telemetryStatName("overflow_keys_total"), p.logStatLabels)...)
+	datapoints = append(datapoints, p.logKeyExpiry.datapoints(
+		p.telemetryStatName("expired_keys_total"), p.logStatLabels)...)
 	p.logCountsRWLock.RUnlock()
+	p.logReport.finish(datapoints)
+	p.logReport.commit()
+	p.logMetadata.apply(datapoints, "log_records_total")
 
 	if p.config.IncludeTelemetryStats {
-		p.updateTelemetryStatCounts(datapoints, telemetryStatName("log_records_total"))
+		p.updateTelemetryStatCounts(datapoints, p.telemetryStatName("log_records_total"))
 	}
 
-	// Step 2: Without holding the read lock, write the generated
-	// datapoints to the configured prometheus endpoint.
-	for _, dp := range datapoints {
-		formattedLabels := formatLabels(dp.labels)
-		fmt.Fprintf(w, "%s{%s} %d\n", dp.name, formattedLabels, dp.value)
+	// Add the resource and scope entries counted, if configured.
+	datapoints = append(datapoints, p.shapeStats("logs", "log_")...)
+
+	return datapoints
+}
+
+// logStatLabels returns the labels of the log stats generated for a log key.
+func (p *telemetryStatsProcessor) logStatLabels(key string) map[string]string {
+	parts := strings.Split(key, ":")
+	labels := make(map[string]string)
+	labels["source"] = sourceStr
+	labels["grouping"] = parts[0]
+	for _, part := range parts[1:] {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) == 2 {
+			switch kv[0] {
+			case "__resource":
+				labels["resource_hash"] = kv[1]
+			case "__receiver":
+				labels["receiver"] = kv[1]
+			case "__severity_number":
+				labels["severity_number"] = kv[1]
+			case "__severity_text":
+				labels["severity_text"] = kv[1]
+			case "__pattern":
+				labels["pattern"] = kv[1]
+			case "__overflow":
+				labels["grouping_overflow"] = kv[1]
+			default:
+				labels[kv[0]] = kv[1]
+			}
+		}
+	}
+	for _, configuredLabel := range p.config.Labels {
+		// If a configured label would overwrite an existing
+		// label, rename the existing label.
+		if value, exists := labels[configuredLabel.Name]; exists {
+			delete(labels, configuredLabel.Name)
+			labels["log_"+configuredLabel.Name] = value
+		}
+		// The pipeline that receives log stats from the
+		// prometheus endpoint is responsible for writing the
+		// configured label as a resource attribute.
 	}
+	return labels
 }
 
+// logStatsExporter destructor, effective when the last processor is removed
 func (e *logStatsExporter) removeProcessor(p *telemetryStatsProcessor) {
-	e.requestsRWLock.Lock()
-	defer e.requestsRWLock.Unlock()
+	var registration *httpregistry.Registration
 
+	e.requestsRWLock.Lock()
 	for i := 0; i < len(e.processors); i++ {
 		if e.processors[i] == p {
 			copy(e.processors[i:], e.processors[i+1:])
@@ -581,17 +1195,113 @@ func (e *logStatsExporter) removeProcessor(p *telemetryStatsProcessor) {
 			break
 		}
 	}
+	serving := false
+	for _, processor := range e.processors {
+		serving = serving || processor.servesLogStats()
+	}
+	if !serving {
+		registration = e.registration
+		e.registration = nil
+		e.registrar = nil
+		e.authenticator = nil
+	}
+	e.requestsRWLock.Unlock()
+
+	// Unregister without holding the lock, since unregistering waits for
+	// in progress requests that need the read lock to complete.
+	if registration != nil {
+		e.logger.Info("Unregistering log stats endpoint")
+		registration.Unregister()
+	}
 }
 
-func formatLabels(labels map[string]string) string {
-	result := ""
-	for k, v := range labels {
-		result += fmt.Sprintf("%s=\"%s\",", rePromInvalid.ReplaceAllString(k, "_"), v)
+// LogGroupingCounts returns the named log grouping along with the cumulative
+// number of log records counted under each of its keys, summed over all
+// telemetry_stats processors that configure it. It returns false if no running
+// processor configures the grouping.
+func LogGroupingCounts(name string) (LogGrouping, map[string]int64, bool) {
+	var grouping LogGrouping
+	var counts map[string]int64
+
+	// Processors are created before any component is started, so callers
+	// in a started component see the exporter if there is one.
+	e := singletonExporter
+	if e == nil {
+		return grouping, nil, false
 	}
-	if len(result) > 0 {
-		result = result[:len(result)-1] // Remove trailing comma
+
+	e.requestsRWLock.RLock()
+	defer e.requestsRWLock.RUnlock()
+
+	for _, p := range e.processors {
+		for _, g := range p.config.LogGroupings {
+			if g.Name != name {
+				continue
+			}
+			if counts == nil {
+				grouping = g
+				counts = make(map[string]int64)
+			}
+			p.logCountsRWLock.RLock()
+			for key, count := range p.logCounts {
+				if strings.HasPrefix(key, name+":") || key == name {
+					counts[key] += count
+				}
+			}
+			p.logCountsRWLock.RUnlock()
+			break
+		}
 	}
-	return result
+
+	return grouping, counts, counts != nil
+}
+
+// LogKey returns the key under which a log record with the given attributes is
+// counted in the log grouping.
+func LogKey(grouping LogGrouping, attrs *Attributes) string {
+	return generateLogKey(grouping, attrs)
+}
+
+// logStatJSON is a log stat served as JSON. The grouping is taken out of the
+// labels, and the last update is only known for counts of the processor and
+// pushed counters.
+type logStatJSON struct {
+	Name       string            `json:"name"`
+	Grouping   string            `json:"grouping,omitempty"`
+	Labels     map[string]string `json:"labels"`
+	Value      json.Number       `json:"value"`
+	LastUpdate *time.Time        `json:"last_update,omitempty"`
+}
+
+func writeLogStatsJSON(w http.ResponseWriter, datapoints []telemetryStatsDatapoint) {
+	stats := make([]logStatJSON, 0, len(datapoints))
+	for _, dp := range datapoints {
+		stat := logStatJSON{
+			Name:     dp.name,
+			Grouping: dp.labels["grouping"],
+			Labels:   make(map[string]string, len(dp.labels)),
+			Value:    json.Number(dp.formatValue()),
+		}
+		for k, v := range dp.labels {
+			if k != "grouping" {
+				stat.Labels[k] = v
+			}
+		}
+		if !dp.updated.IsZero() {
+			updated := dp.updated.UTC()
+			stat.LastUpdate = &updated
+		}
+		stats = append(stats, stat)
+	}
+
+	data, err := json.Marshal(stats)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
 }
 
 // Attributes encapsulates resource, scope, and datapoint level attributes,
@@ -599,9 +1309,16 @@ func formatLabels(labels map[string]string) string {
 // them, and provides a Get() function that gives precedence to attributes from
 // more specific scopes (datapoint > scope > resource).
 type Attributes struct {
-	resource  pcommon.Map
-	scope     pcommon.Map
-	datapoint pcommon.Map
+	resource       pcommon.Map
+	scope          pcommon.Map
+	datapoint      pcommon.Map
+	hash           string // resource hash, computed when first needed
+	severityNumber plog.SeverityNumber
+	severityText   string
+	body           pcommon.Value
+	hasBody        bool
+	bodyStr        string // string form of the body, computed when first needed
+	hasBodyStr     bool
 }
 
 // NewAttributes creates a new Attributes instance.
@@ -613,6 +1330,38 @@ func NewAttributes(resource, scope, datapoint pcommon.Map) *Attributes {
 	}
 }
 
+// SetSeverity sets the severity of the log record the attributes belong to,
+// for log groupings with `by_severity`.
+func (attrs *Attributes) SetSeverity(number plog.SeverityNumber, text string) {
+	attrs.severityNumber = number
+	attrs.severityText = text
+}
+
+// SetBody sets the body of the log record the attributes belong to, for log
+// groupings with `patterns`.
+func (attrs *Attributes) SetBody(body pcommon.Value) {
+	attrs.body = body
+	attrs.hasBody = true
+	attrs.bodyStr = ""
+	attrs.hasBodyStr = false
+}
+
+// bodyString returns the body in string form, or false if no body was set.
+func (attrs *Attributes) bodyString() (string, bool) {
+	if !attrs.hasBody {
+		return "", false
+	}
+	if !attrs.hasBodyStr {
+		if attrs.body.Type() == pcommon.ValueTypeStr {
+			attrs.bodyStr = attrs.body.Str()
+		} else {
+			attrs.bodyStr = attrs.body.AsString()
+		}
+		attrs.hasBodyStr = true
+	}
+	return attrs.bodyStr, true
+}
+
 // Get retrieves the attribute value associated with the given name along with
 // a boolean indicating whether the named attribute exists.
 func (attrs *Attributes) Get(name string) (string, bool) {
@@ -623,6 +1372,23 @@ func (attrs *Attributes) Get(name string) (string, bool) {
 	return value.Str(), true
 }
 
+// resourceHash returns the hash of the resource attributes.
+func (attrs *Attributes) resourceHash() string {
+	if attrs.hash == "" {
+		attrs.hash = resourceHash(attrs.resource)
+	}
+	return attrs.hash
+}
+
+// receiver returns the receiver stamped on the resource, if any.
+func (attrs *Attributes) receiver() (string, bool) {
+	value, exists := attrs.resource.Get(receiverstamp.Attribute)
+	if !exists || value.Type() != pcommon.ValueTypeStr {
+		return "", false
+	}
+	return value.Str(), true
+}
+
 func (attrs *Attributes) getValue(name string) (pcommon.Value, bool) {
 	if v, exists := attrs.datapoint.Get(name); exists {
 		return v, true
@@ -636,114 +1402,9 @@ func (attrs *Attributes) getValue(name string) (pcommon.Value, bool) {
 	return pcommon.NewValueEmpty(), false
 }
 
-// metricDatapointMatchesFilter returns true if (typeMatches AND (nameMatches OR labelMatches)).
-//   - typeMatches is true if filter.MetricTypes is unspecified or if the metric
-//     type matches any of the listed filter.MetricTypes
-//   - nameMatches is true if the metric name matches any of the listed
-//     filter.MetricNames or if it matches filter.MetricRegex
-//   - labelMatches is true if any metric label matches any of the listed filter.Labels
-//   - a metric label matches a filter Label if the names are equal AND
-//   - the filter Label.Values and Label.ValueRegex are both unspecified OR
-//   - the metric label value matches any of the listed Label.Values or if it
-//     matches the Label.ValueRegex
-func metricDatapointMatchesFilter(
-	metric pmetric.Metric,
-	attrs *Attributes,
-	filter *MetricFilter,
-) bool {
-	if filter.MetricTypes != nil {
-		found := false
-		metricType := metricTypeToString(metric.Type())
-		for _, t := range filter.MetricTypes {
-			if strings.EqualFold(metricType, t) {
-				found = true
-				break
-			}
-		}
-		if !found {
-			return false
-		}
-		if filter.MetricNames == nil && filter.MetricRegex == "" && filter.Labels == nil {
-			return true
-		}
-	}
-
-	if filter.MetricNames != nil {
-		for _, name := range filter.MetricNames {
-			if metric.Name() == name {
-				return true
-			}
-		}
-	}
-
-	if filter.MetricRegex != "" {
-		matched, err := regexp.MatchString(filter.MetricRegex, metric.Name())
-		if err == nil && matched {
-			return true
-		}
-	}
-
-	if filter.Labels != nil {
-		for _, labelFilter := range filter.Labels {
-			value, exists := attrs.Get(labelFilter.Name)
-			if !exists {
-				continue
-			}
-
-			if labelFilter.Values == nil && labelFilter.ValueRegex == "" {
-				return true
-			}
-
-			if labelFilter.Values != nil {
-				for _, v := range labelFilter.Values {
-					if value == v {
-						return true
-					}
-				}
-			}
-
-			if labelFilter.ValueRegex != "" {
-				matched, err := regexp.MatchString(labelFilter.ValueRegex, value)
-				if err == nil && matched {
-					return true
-				}
-			}
-		}
-	}
-
-	return false
-}
-
-func includeMetricDatapoint(
-	grouping *MetricGrouping,
-	metric pmetric.Metric,
-	attrs *Attributes,
-) bool {
-	includeMatches := grouping.Include == nil ||
-		metricDatapointMatchesFilter(metric, attrs, grouping.Include)
-	excludeMatches := grouping.Exclude != nil &&
-		metricDatapointMatchesFilter(metric, attrs, grouping.Exclude)
-
-	return includeMatches && !excludeMatches
-}
-
-func metricTypeToString(metricType pmetric.MetricType) string {
-	switch metricType {
-	case pmetric.MetricTypeGauge:
-		return "Gauge"
-	case pmetric.MetricTypeSum:
-		return "Counter"
-	case pmetric.MetricTypeHistogram:
-		return "Histogram"
-	case pmetric.MetricTypeSummary:
-		return "Summary"
-	default:
-		return "Unknown"
-	}
-}
-
 // The format of the generated metric key is
-// grouping:__name=<metricName>:__type=<metricType>[:<labelName>=<labelValue>...]
+// grouping:__name=<metricName>:__type=<metricType>[:__resource=<resourceHash>]
+// [:__receiver=<receiver>][:<labelName>=<labelValue>...]
 func generateMetricKey(
 	grouping *MetricGrouping,
 	metric pmetric.Metric,
@@ -759,7 +1420,17 @@ func generateMetricKey(
 
 	if grouping.ByMetricType {
 		keyParts = append(keyParts, fmt.Sprintf("__type=%s",
-			metricTypeToString(metric.Type())))
+			filter.MetricType(metric.Type())))
+	}
+
+	if grouping.ByResource {
+		keyParts = append(keyParts, "__resource="+attrs.resourceHash())
+	}
+
+	if grouping.ByReceiver {
+		if receiver, exists := attrs.receiver(); exists {
+			keyParts = append(keyParts, "__receiver="+receiver)
+		}
 	}
 
 	if grouping.ByLabel != nil {
@@ -775,12 +1446,38 @@ func generateMetricKey(
 }
 
 // The format of the generated log key is
-// grouping[:<labelName>=<labelValue>...]
+// grouping[:__resource=<resourceHash>][:__receiver=<receiver>]
+// [:__severity_number=<number>[:__severity_text=<text>]][:__pattern=<name>]
+// [:<labelName>=<labelValue>...]
 func generateLogKey(grouping LogGrouping, attrs *Attributes) string {
 	var keyParts []string
 
 	keyParts = append(keyParts, grouping.Name)
 
+	if grouping.ByResource {
+		keyParts = append(keyParts, "__resource="+attrs.resourceHash())
+	}
+
+	if grouping.ByReceiver {
+		if receiver, exists := attrs.receiver(); exists {
+			keyParts = append(keyParts, "__receiver="+receiver)
+		}
+	}
+
+	if grouping.BySeverity {
+		keyParts = append(keyParts, fmt.Sprintf("__severity_number=%d",
+			attrs.severityNumber))
+		if attrs.severityText != "" {
+			keyParts = append(keyParts, "__severity_text="+attrs.severityText)
+		}
+	}
+
+	if len(grouping.Patterns) > 0 {
+		if pattern, matched := matchPattern(grouping.Patterns, attrs); matched {
+			keyParts = append(keyParts, "__pattern="+pattern)
+		}
+	}
+
 	if grouping.ByLabel != nil {
 		for _, labelName := range grouping.ByLabel.Names {
 			if labelValue, exists := attrs.Get(labelName); exists {
@@ -793,6 +1490,47 @@ func generateLogKey(grouping LogGrouping, attrs *Attributes) string {
 	return strings.Join(keyParts, ":")
 }
 
+// patternRegexps caches the compiled regular expressions of log patterns by
+// expression, for all processors and callers of LogKey.
+var patternRegexps sync.Map
+
+// matchPattern returns the name of the first pattern matching the body of the
+// log record, if any.
+func matchPattern(patterns []LogPattern, attrs *Attributes) (string, bool) {
+	body, exists := attrs.bodyString()
+	if !exists {
+		return "", false
+	}
+	for _, pattern := range patterns {
+		re, cached := patternRegexps.Load(pattern.Regex)
+		if !cached {
+			// patterns are validated with the configuration
+			re, _ = patternRegexps.LoadOrStore(pattern.Regex,
+				regexp.MustCompile(pattern.Regex))
+		}
+		if re.(*regexp.Regexp).MatchString(body) {
+			return pattern.Name, true
+		}
+	}
+	return "", false
+}
+
+// compileMatchers compiles the include and exclude filters of the metric
+// groupings.
+func compileMatchers(groupings []MetricGrouping) ([]groupingMatchers, error) {
+	matchers := make([]groupingMatchers, len(groupings))
+	for i, g := range groupings {
+		var err error
+		if matchers[i].include, err = filter.CompileMetricFilter(g.Include); err != nil {
+			return nil, fmt.Errorf("include of grouping %s: %w", g.Name, err)
+		}
+		if matchers[i].exclude, err = filter.CompileMetricFilter(g.Exclude); err != nil {
+			return nil, fmt.Errorf("exclude of grouping %s: %w", g.Name, err)
+		}
+	}
+	return matchers, nil
+}
+
 func copyCounts(counts map[string]int64) map[string]int64 {
 	copiedCounts := make(map[string]int64, len(counts))
 	for key, value := range counts {
@@ -0,0 +1,108 @@
+package telemetrystatsprocessor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+// newTestMetrics returns metrics with a gauge of the given number of
+// datapoints.
+func newTestMetrics(name string, datapoints int) pmetric.Metrics {
+	md := pmetric.NewMetrics()
+	metric := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().
+		Metrics().AppendEmpty()
+	metric.SetName(name)
+	gauge := metric.SetEmptyGauge()
+	for i := 0; i < datapoints; i++ {
+		gauge.DataPoints().AppendEmpty().SetIntValue(int64(i))
+	}
+	return md
+}
+
+// sumDatapointStats returns the sum of the datapoints_total stats of a metric
+// name, failing if any of them is not a delta.
+func sumDatapointStats(t *testing.T, md pmetric.Metrics, metricName string) int64 {
+	t.Helper()
+	var total int64
+	for i := 0; i < md.ResourceMetrics().Len(); i++ {
+		sms := md.ResourceMetrics().At(i).ScopeMetrics()
+		for j := 0; j < sms.Len(); j++ {
+			metrics := sms.At(j).Metrics()
+			for k := 0; k < metrics.Len(); k++ {
+				metric := metrics.At(k)
+				if metric.Name() != "telemetry_stats_datapoints_total" {
+					continue
+				}
+				sum := metric.Sum()
+				if sum.AggregationTemporality() != pmetric.AggregationTemporalityDelta {
+					t.Fatalf("%s has temporality %s, want delta",
+						metric.Name(), sum.AggregationTemporality())
+				}
+				for l := 0; l < sum.DataPoints().Len(); l++ {
+					dp := sum.DataPoints().At(l)
+					if name, _ := dp.Attributes().Get("metric_name"); name.Str() == metricName {
+						total += dp.IntValue()
+					}
+				}
+			}
+		}
+	}
+	return total
+}
+
+func TestShutdownForwardsQueuedDeltas(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.MetricGroupings = []MetricGrouping{{Name: "by_name", ByMetricName: true}}
+	cfg.Temporality = temporalityDelta
+	// long enough that only the test scrapes
+	cfg.MetricScrapeInterval = time.Hour
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	p, err := newTelemetryStatsProcessor(cfg, zap.NewNop())
+	if err != nil {
+		t.Fatalf("newTelemetryStatsProcessor() error = %v", err)
+	}
+	var flushed []pmetric.Metrics
+	p.nextMetrics, err = consumer.NewMetrics(
+		func(_ context.Context, md pmetric.Metrics) error {
+			flushed = append(flushed, md)
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("consumer.NewMetrics() error = %v", err)
+	}
+
+	ctx := context.Background()
+	var total int64
+	for _, datapoints := range []int{3, 2, 4} {
+		md, err := p.processMetrics(ctx, newTestMetrics("test_metric", datapoints))
+		if err != nil {
+			t.Fatalf("processMetrics() error = %v", err)
+		}
+		total += sumDatapointStats(t, md, "test_metric")
+		// carried by the next incoming metrics, except for the last
+		// scrape, which is still queued on shutdown
+		p.scrapeMetricStats()
+	}
+	if want := int64(5); total != want {
+		t.Fatalf("deltas forwarded before shutdown = %d, want %d", total, want)
+	}
+	if len(p.metricStatsChannel) == 0 {
+		t.Fatal("no metric stats queued before shutdown")
+	}
+
+	p.cleanup(ctx)
+	if len(flushed) != 1 {
+		t.Fatalf("flushed %d times, want once", len(flushed))
+	}
+	total += sumDatapointStats(t, flushed[0], "test_metric")
+	if want := int64(9); total != want {
+		t.Errorf("sum of deltas = %d, want %d", total, want)
+	}
+}
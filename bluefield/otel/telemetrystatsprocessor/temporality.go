@@ -0,0 +1,77 @@
+package telemetrystatsprocessor
+
+import (
+	"sync"
+	"time"
+)
+
+// The aggregation temporalities of the counters reported as OTLP sums.
+const (
+	temporalityCumulative = "cumulative"
+	temporalityDelta      = "delta"
+)
+
+// deltaState turns the cumulative counters left in reports with delta
+// temporality, e.g. pushed counters and the overflow and expired keys, into
+// the increments since the previous report. The counts of groupings are turned
+// into increments by their report state instead, before keys outside the top
+// K are summed.
+type deltaState struct {
+	lock      sync.Mutex
+	previous  map[string]int64 // by stat name and labels, of the previous report
+	last      time.Time        // of the previous report
+	converted map[string]int64 // by stat name and labels, of the uncommitted report
+	now       time.Time        // of the uncommitted report
+}
+
+func newDeltaState(start time.Time) *deltaState {
+	return &deltaState{
+		previous: make(map[string]int64),
+		last:     start,
+	}
+}
+
+// convert reports the cumulative counters among the datapoints as deltas over
+// the interval since the previous report. A count lower than in the previous
+// report was reset, so the count is the increment. The report only becomes the
+// previous report once committed, before the next conversion.
+func (s *deltaState) convert(datapoints []telemetryStatsDatapoint, now time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	current := make(map[string]int64, len(s.previous))
+	for i := range datapoints {
+		dp := &datapoints[i]
+		if dp.gauge || dp.distribution != nil ||
+			(dp.mode != "" && dp.mode != reportModeCumulative) {
+			continue
+		}
+		dp.mode = reportModeDelta
+		dp.start = s.last
+		dp.end = now
+		if dp.stale {
+			continue
+		}
+		id := dp.name + "\x00" + formatLabels(dp.labels)
+		current[id] = dp.value
+		if previous := s.previous[id]; dp.value >= previous {
+			dp.value -= previous
+		}
+	}
+	s.converted = current
+	s.now = now
+}
+
+// commit makes the last converted report the previous report, once its
+// datapoints are delivered.
+func (s *deltaState) commit() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.converted == nil {
+		return
+	}
+	s.previous = s.converted
+	s.converted = nil
+	s.last = s.now
+}
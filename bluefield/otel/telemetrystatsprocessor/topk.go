@@ -0,0 +1,73 @@
+package telemetrystatsprocessor
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// topKOthers returns the keys of the groupings with a `top_k` that aren't
+// among the K keys with the highest counts of their grouping. Keys with equal
+// counts are ranked by key, so that the same keys are selected on each scrape.
+// Overflow keys are always reported on their own.
+func topKOthers(counts map[string]int64, topK map[string]int) map[string]bool {
+	if len(topK) == 0 {
+		return nil
+	}
+
+	keysByGrouping := make(map[string][]string)
+	for key := range counts {
+		grouping, _, _ := strings.Cut(key, ":")
+		if topK[grouping] > 0 && !isOverflowKey(key) {
+			keysByGrouping[grouping] = append(keysByGrouping[grouping], key)
+		}
+	}
+
+	others := make(map[string]bool)
+	for grouping, keys := range keysByGrouping {
+		k := topK[grouping]
+		if len(keys) <= k {
+			continue
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if counts[keys[i]] != counts[keys[j]] {
+				return counts[keys[i]] > counts[keys[j]]
+			}
+			return keys[i] < keys[j]
+		})
+		for _, key := range keys[k:] {
+			others[key] = true
+		}
+	}
+	return others
+}
+
+// otherBuckets sums the stats of the keys outside the top K of their grouping
+// by stat name and grouping.
+type otherBuckets map[[2]string]*telemetryStatsDatapoint
+
+func (b otherBuckets) add(name, key, mode string, value int64, updated time.Time) {
+	grouping, _, _ := strings.Cut(key, ":")
+	bucket, exists := b[[2]string{name, grouping}]
+	if !exists {
+		bucket = &telemetryStatsDatapoint{name: name, mode: mode}
+		b[[2]string{name, grouping}] = bucket
+	}
+	bucket.value += value
+	if updated.After(bucket.updated) {
+		bucket.updated = updated
+	}
+}
+
+// datapoints returns a datapoint for each bucket, labeled as the grouping with
+// `other="true"`.
+func (b otherBuckets) datapoints(labels func(key string) map[string]string) []telemetryStatsDatapoint {
+	datapoints := make([]telemetryStatsDatapoint, 0, len(b))
+	for nameAndGrouping, bucket := range b {
+		dp := *bucket
+		dp.labels = labels(nameAndGrouping[1])
+		dp.labels["other"] = "true"
+		datapoints = append(datapoints, dp)
+	}
+	return datapoints
+}
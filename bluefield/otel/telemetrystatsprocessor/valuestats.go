@@ -0,0 +1,229 @@
+package telemetrystatsprocessor
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// The statistics of datapoint values metric groupings can report, and
+// valueStatSummary for all of them.
+const (
+	valueStatMin     = "min"
+	valueStatMax     = "max"
+	valueStatAvg     = "avg"
+	valueStatSum     = "sum"
+	valueStatCount   = "count"
+	valueStatSummary = "summary"
+)
+
+// valueStatNames are the statistics of datapoint values in the order they are
+// reported.
+var valueStatNames = []string{
+	valueStatMin, valueStatMax, valueStatAvg, valueStatSum, valueStatCount,
+}
+
+func validateValueStats(stats []string) error {
+	for _, stat := range stats {
+		if stat != valueStatSummary && !slices.Contains(valueStatNames, stat) {
+			return fmt.Errorf("value_stats must be %q, %q, %q, %q, %q or %q",
+				valueStatMin, valueStatMax, valueStatAvg, valueStatSum,
+				valueStatCount, valueStatSummary)
+		}
+	}
+	return nil
+}
+
+// expandValueStats returns the statistics listed in `value_stats`, each once
+// and in the order they are reported, with "summary" standing for all.
+func expandValueStats(stats []string) []string {
+	if slices.Contains(stats, valueStatSummary) {
+		return valueStatNames
+	}
+	var expanded []string
+	for _, stat := range valueStatNames {
+		if slices.Contains(stats, stat) {
+			expanded = append(expanded, stat)
+		}
+	}
+	return expanded
+}
+
+// valueSummary summarizes the datapoint values of a key in an interval.
+type valueSummary struct {
+	min   float64
+	max   float64
+	sum   float64
+	count int64
+}
+
+func (s *valueSummary) add(value float64) {
+	if s.count == 0 || value < s.min {
+		s.min = value
+	}
+	if s.count == 0 || value > s.max {
+		s.max = value
+	}
+	s.sum += value
+	s.count++
+}
+
+func (s *valueSummary) merge(other *valueSummary) {
+	if other.count == 0 {
+		return
+	}
+	if s.count == 0 || other.min < s.min {
+		s.min = other.min
+	}
+	if s.count == 0 || other.max > s.max {
+		s.max = other.max
+	}
+	s.sum += other.sum
+	s.count += other.count
+}
+
+// stat returns a statistic of the summarized values.
+func (s *valueSummary) stat(name string) float64 {
+	switch name {
+	case valueStatMin:
+		return s.min
+	case valueStatMax:
+		return s.max
+	case valueStatAvg:
+		return s.sum / float64(s.count)
+	case valueStatSum:
+		return s.sum
+	}
+	return float64(s.count)
+}
+
+// valueStats summarizes the datapoint values of the keys of the metric
+// groupings with `value_stats` over each scrape interval.
+type valueStats struct {
+	stats   map[string][]string      // the expanded value_stats, by grouping name
+	current map[string]*valueSummary // by key, of the interval in progress
+	since   time.Time                // start of the interval in progress
+}
+
+// newValueStats returns the value stats of the groupings, or nil if none
+// reports any.
+func newValueStats(groupings []MetricGrouping, start time.Time) *valueStats {
+	stats := make(map[string][]string)
+	for _, g := range groupings {
+		if len(g.ValueStats) > 0 {
+			stats[g.Name] = expandValueStats(g.ValueStats)
+		}
+	}
+	if len(stats) == 0 {
+		return nil
+	}
+	return &valueStats{
+		stats:   stats,
+		current: make(map[string]*valueSummary),
+		since:   start,
+	}
+}
+
+// tracks returns whether a grouping reports value stats.
+func (v *valueStats) tracks(grouping string) bool {
+	return v != nil && v.stats[grouping] != nil
+}
+
+// add summarizes a datapoint value of a key.
+func (v *valueStats) add(key string, value float64) {
+	summary, exists := v.current[key]
+	if !exists {
+		summary = &valueSummary{}
+		v.current[key] = summary
+	}
+	summary.add(value)
+}
+
+// rotate ends the interval in progress, and returns its summaries and start.
+func (v *valueStats) rotate(now time.Time) (map[string]*valueSummary, time.Time) {
+	if v == nil {
+		return nil, time.Time{}
+	}
+	summaries, since := v.current, v.since
+	v.current = make(map[string]*valueSummary, len(summaries))
+	v.since = now
+	return summaries, since
+}
+
+// datapoints returns a gauge per value stat of each key with values in an
+// interval. Keys outside the top K of their grouping are summarized together,
+// labeled as the grouping with `other="true"`.
+func (v *valueStats) datapoints(
+	summaries map[string]*valueSummary,
+	since, now time.Time,
+	others map[string]bool,
+	statName func(name string) string,
+	labels func(key string) map[string]string,
+) []telemetryStatsDatapoint {
+	var datapoints []telemetryStatsDatapoint
+	appendStats := func(summary *valueSummary, key string, other bool) {
+		for _, stat := range v.stats[groupingOfKey(key)] {
+			dp := telemetryStatsDatapoint{
+				name:     statName("value_" + stat),
+				labels:   labels(key),
+				gauge:    true,
+				start:    since,
+				end:      now,
+				double:   summary.stat(stat),
+				isDouble: stat != valueStatCount,
+				value:    summary.count,
+			}
+			if other {
+				dp.labels["other"] = "true"
+			}
+			datapoints = append(datapoints, dp)
+		}
+	}
+
+	otherSummaries := make(map[string]*valueSummary)
+	for key, summary := range summaries {
+		grouping := groupingOfKey(key)
+		if others[key] {
+			other, exists := otherSummaries[grouping]
+			if !exists {
+				other = &valueSummary{}
+				otherSummaries[grouping] = other
+			}
+			other.merge(summary)
+			continue
+		}
+		appendStats(summary, key, false)
+	}
+	for grouping, summary := range otherSummaries {
+		appendStats(summary, grouping, true)
+	}
+	return datapoints
+}
+
+// datapointValue returns the value of a gauge or sum datapoint, unless it has
+// no recorded value or isn't a number.
+func datapointValue(metric pmetric.Metric, index int) (float64, bool) {
+	var dp pmetric.NumberDataPoint
+	switch metric.Type() {
+	case pmetric.MetricTypeGauge:
+		dp = metric.Gauge().DataPoints().At(index)
+	case pmetric.MetricTypeSum:
+		dp = metric.Sum().DataPoints().At(index)
+	default:
+		return 0, false
+	}
+	if dp.Flags().NoRecordedValue() {
+		return 0, false
+	}
+	switch dp.ValueType() {
+	case pmetric.NumberDataPointValueTypeInt:
+		return float64(dp.IntValue()), true
+	case pmetric.NumberDataPointValueTypeDouble:
+		value := dp.DoubleValue()
+		return value, !math.IsNaN(value) && !math.IsInf(value, 0)
+	}
+	return 0, false
+}
@@ -0,0 +1,71 @@
+package tenantencryptprocessor
+
+import (
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/collector/component"
+
+	"otelcommon/envelope"
+	"otelcommon/filter"
+)
+
+const (
+	unknownTenantDrop = "drop"
+	unknownTenantPass = "pass"
+)
+
+// Config defines the configuration of the tenant_encrypt processor.
+type Config struct {
+	// TenantAttribute is the resource attribute holding the tenant ID of
+	// the log records. Defaults to "tenant.id".
+	TenantAttribute string `mapstructure:"tenant_attribute"`
+
+	// Tenants configures the public key of each tenant whose log bodies
+	// are encrypted.
+	Tenants []envelope.TenantKey `mapstructure:"tenants"`
+
+	// Include configures a filter that limits which log records of a
+	// tenant have their bodies encrypted. If unspecified, the bodies of
+	// all log records of the tenant are encrypted.
+	Include *filter.LogFilter `mapstructure:"include"`
+
+	// UnknownTenants configures what happens to the log records of a
+	// tenant without a configured key, "drop" to drop them or "pass" to
+	// pass them on unencrypted. Log records without a tenant are always
+	// passed on. Defaults to "drop".
+	UnknownTenants string `mapstructure:"unknown_tenants"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.TenantAttribute == "" {
+		return errors.New("tenant_attribute cannot be empty")
+	}
+	if len(cfg.Tenants) == 0 {
+		return errors.New("at least one tenant must be configured")
+	}
+	if err := envelope.Validate(cfg.Tenants); err != nil {
+		return err
+	}
+	if _, err := filter.CompileLogFilter(cfg.Include); err != nil {
+		return fmt.Errorf("include: %w", err)
+	}
+	if cfg.UnknownTenants != unknownTenantDrop &&
+		cfg.UnknownTenants != unknownTenantPass {
+		return fmt.Errorf("unknown_tenants must be %q or %q",
+			unknownTenantDrop, unknownTenantPass)
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		TenantAttribute: "tenant.id",
+		UnknownTenants:  unknownTenantDrop,
+	}
+}
@@ -0,0 +1,3 @@
+module tenantencryptprocessor
+
+go 1.22
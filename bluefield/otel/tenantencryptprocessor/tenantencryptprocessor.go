@@ -0,0 +1,149 @@
+package tenantencryptprocessor
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+
+	"otelcommon/envelope"
+	"otelcommon/filter"
+)
+
+// attributeEncrypted marks the log records whose bodies are encrypted, as the
+// include filter may leave others of the same resource unencrypted
+const attributeEncrypted = "bmm.envelope.encrypted"
+
+type tenantEncryptProcessor struct {
+	logger  *zap.Logger
+	config  *Config
+	include *filter.LogMatcher
+	keyring *envelope.Keyring
+}
+
+// attributes looks up labels of the include filter among the log record,
+// scope and resource attributes, in that order.
+type attributes struct {
+	resource pcommon.Map
+	scope    pcommon.Map
+	record   pcommon.Map
+}
+
+func (a *attributes) Get(name string) (string, bool) {
+	for _, m := range []pcommon.Map{a.record, a.scope, a.resource} {
+		if v, exists := m.Get(name); exists {
+			return v.AsString(), true
+		}
+	}
+	return "", false
+}
+
+// processor constructor
+func newTenantEncryptProcessor(
+	config *Config,
+	logger *zap.Logger,
+) (*tenantEncryptProcessor, error) {
+	include, err := filter.CompileLogFilter(config.Include)
+	if err != nil {
+		return nil, fmt.Errorf("include: %w", err)
+	}
+	return &tenantEncryptProcessor{
+		logger:  logger,
+		config:  config,
+		include: include,
+	}, nil
+}
+
+// start reads the tenant keys, failing the collector's startup rather than
+// passing tenant telemetry on unencrypted.
+func (p *tenantEncryptProcessor) start(context.Context, component.Host) error {
+	keyring, err := envelope.LoadKeyring(p.config.Tenants)
+	if err != nil {
+		return err
+	}
+	p.keyring = keyring
+	return nil
+}
+
+// processLogs encrypts the bodies of the log records of each resource with a
+// data key of its own, wrapped for the tenant of the resource. The key ID and
+// wrapped key are added to the resource attributes.
+func (p *tenantEncryptProcessor) processLogs(
+	_ context.Context,
+	ld plog.Logs,
+) (plog.Logs, error) {
+	var err error
+	ld.ResourceLogs().RemoveIf(func(rl plog.ResourceLogs) bool {
+		if err != nil {
+			return false
+		}
+		var drop bool
+		drop, err = p.processResource(rl)
+		return drop
+	})
+	return ld, err
+}
+
+// processResource encrypts the log bodies of a resource, and returns whether
+// the resource is dropped since its tenant has no key.
+func (p *tenantEncryptProcessor) processResource(rl plog.ResourceLogs) (bool, error) {
+	resource := rl.Resource().Attributes()
+	value, exists := resource.Get(p.config.TenantAttribute)
+	if !exists {
+		return false, nil
+	}
+	tenant := value.AsString()
+	if !p.keyring.Has(tenant) {
+		if p.config.UnknownTenants == unknownTenantPass {
+			return false, nil
+		}
+		p.logger.Warn("Dropping log records of tenant without a key",
+			zap.String("tenant", tenant),
+			zap.Int("log_records", logRecordCount(rl)))
+		return true, nil
+	}
+
+	sealer, err := p.keyring.NewSealer(tenant)
+	if err != nil {
+		return false, fmt.Errorf("tenant %s: %w", tenant, err)
+	}
+	resource.PutStr(envelope.AttributeKeyID, sealer.KeyID)
+	resource.PutStr(envelope.AttributeWrappedKey,
+		base64.StdEncoding.EncodeToString(sealer.WrappedKey))
+	resource.PutStr(envelope.AttributeAlgorithm, envelope.Algorithm)
+
+	attrs := &attributes{resource: resource}
+	sls := rl.ScopeLogs()
+	for i := 0; i < sls.Len(); i++ {
+		sl := sls.At(i)
+		attrs.scope = sl.Scope().Attributes()
+		records := sl.LogRecords()
+		for j := 0; j < records.Len(); j++ {
+			record := records.At(j)
+			attrs.record = record.Attributes()
+			if p.include != nil && !p.include.Match(record, attrs) {
+				continue
+			}
+			sealed, err := sealer.Seal([]byte(record.Body().AsString()))
+			if err != nil {
+				return false, fmt.Errorf("tenant %s: %w", tenant, err)
+			}
+			record.Body().SetEmptyBytes().FromRaw(sealed)
+			record.Attributes().PutBool(attributeEncrypted, true)
+		}
+	}
+	return false, nil
+}
+
+func logRecordCount(rl plog.ResourceLogs) int {
+	count := 0
+	sls := rl.ScopeLogs()
+	for i := 0; i < sls.Len(); i++ {
+		count += sls.At(i).LogRecords().Len()
+	}
+	return count
+}
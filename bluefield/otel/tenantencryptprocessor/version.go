@@ -0,0 +1,3 @@
+package tenantencryptprocessor
+
+const Version = "0.0.1"
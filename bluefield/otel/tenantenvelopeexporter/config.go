@@ -0,0 +1,99 @@
+package tenantenvelopeexporter
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configretry"
+	"go.opentelemetry.io/collector/exporter/exporterhelper"
+
+	"otelcommon/envelope"
+	"otelcommon/queuestats"
+)
+
+// Config defines the configuration of the tenant_envelope exporter.
+type Config struct {
+	// Endpoint is the base URL sealed payloads are posted to, at
+	// <endpoint>/v1/logs, /v1/metrics and /v1/traces.
+	Endpoint string `mapstructure:"endpoint"`
+
+	// TenantAttribute is the resource attribute holding the tenant ID of
+	// the telemetry. Defaults to "tenant.id".
+	TenantAttribute string `mapstructure:"tenant_attribute"`
+
+	// Tenants configures the public key of each tenant whose telemetry is
+	// exported. Telemetry of other tenants, or without a tenant, is
+	// dropped, as it is never exported unencrypted.
+	Tenants []envelope.TenantKey `mapstructure:"tenants"`
+
+	// Headers are additional headers sent with each request.
+	Headers map[string]string `mapstructure:"headers"`
+
+	// BearerTokenFile is an optional path of a file holding a token sent
+	// as "Authorization: Bearer <token>". It is read on every request, so
+	// the token can be rotated.
+	BearerTokenFile string `mapstructure:"bearer_token_file"`
+
+	// CAFile is an optional path of a PEM encoded CA bundle used to verify
+	// the endpoint, instead of the system roots.
+	CAFile string `mapstructure:"ca_file"`
+
+	// Timeout is the timeout of each request. Defaults to "10s".
+	Timeout time.Duration `mapstructure:"timeout"`
+
+	// QueueSettings configures the sending queue.
+	QueueSettings exporterhelper.QueueSettings `mapstructure:"sending_queue"`
+
+	// BackOffConfig configures the retries.
+	BackOffConfig configretry.BackOffConfig `mapstructure:"retry_on_failure"`
+
+	// QueueStatsEndpoint serves the statistics of the sending queue of
+	// each signal at /metrics/exporters. Empty disables them. Defaults to
+	// "localhost:8890".
+	QueueStatsEndpoint string `mapstructure:"queue_stats_endpoint"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return errors.New("endpoint must be an http or https URL")
+	}
+	if cfg.TenantAttribute == "" {
+		return errors.New("tenant_attribute cannot be empty")
+	}
+	if len(cfg.Tenants) == 0 {
+		return errors.New("at least one tenant must be configured")
+	}
+	if err := envelope.Validate(cfg.Tenants); err != nil {
+		return err
+	}
+	if cfg.Timeout <= 0 {
+		return errors.New("timeout must be positive")
+	}
+	if err := cfg.QueueSettings.Validate(); err != nil {
+		return fmt.Errorf("invalid sending_queue: %w", err)
+	}
+	if err := cfg.BackOffConfig.Validate(); err != nil {
+		return fmt.Errorf("invalid retry_on_failure: %w", err)
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		TenantAttribute: "tenant.id",
+		Timeout:         10 * time.Second,
+		QueueSettings:   exporterhelper.NewDefaultQueueSettings(),
+		BackOffConfig:   configretry.NewDefaultBackOffConfig(),
+
+		QueueStatsEndpoint: queuestats.DefaultEndpoint,
+	}
+}
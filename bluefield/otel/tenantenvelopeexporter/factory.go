@@ -0,0 +1,153 @@
+package tenantenvelopeexporter
+
+import (
+	"context"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/exporter"
+	"go.opentelemetry.io/collector/exporter/exporterhelper"
+
+	"otelcommon/queuestats"
+)
+
+const (
+	typeStr   = "tenant_envelope"
+	stability = component.StabilityLevelAlpha
+)
+
+// resources are removed from batches once their tenant's payload is sent, so
+// that a retry only sends the remaining tenants
+var exporterCapabilities = consumer.Capabilities{MutatesData: true}
+
+func NewFactory() exporter.Factory {
+	return exporter.NewFactory(
+		component.MustNewType(typeStr),
+		createDefaultConfig,
+		exporter.WithTraces(createTracesExporter, stability),
+		exporter.WithMetrics(createMetricsExporter, stability),
+		exporter.WithLogs(createLogsExporter, stability),
+	)
+}
+
+func createTracesExporter(
+	ctx context.Context,
+	set exporter.CreateSettings,
+	cfg component.Config,
+) (exporter.Traces, error) {
+	e, err := newTenantEnvelopeExporter(cfg.(*Config), set.Logger)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := registerQueueStats(cfg.(*Config), set, "traces")
+	if err != nil {
+		return nil, err
+	}
+
+	exp, err := exporterhelper.NewTracesExporter(
+		ctx,
+		set,
+		cfg,
+		stats.PushTraces(e.pushTraces),
+		exporterhelper.WithCapabilities(exporterCapabilities),
+		exporterhelper.WithStart(e.start),
+		exporterhelper.WithShutdown(unregisterQueueStats(stats)),
+		exporterhelper.WithTimeout(exporterhelper.TimeoutSettings{}),
+		exporterhelper.WithQueue(cfg.(*Config).QueueSettings),
+		exporterhelper.WithRetry(cfg.(*Config).BackOffConfig),
+	)
+	if err != nil {
+		stats.Unregister()
+		return nil, err
+	}
+	return stats.WrapTraces(exp), nil
+}
+
+func createMetricsExporter(
+	ctx context.Context,
+	set exporter.CreateSettings,
+	cfg component.Config,
+) (exporter.Metrics, error) {
+	e, err := newTenantEnvelopeExporter(cfg.(*Config), set.Logger)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := registerQueueStats(cfg.(*Config), set, "metrics")
+	if err != nil {
+		return nil, err
+	}
+
+	exp, err := exporterhelper.NewMetricsExporter(
+		ctx,
+		set,
+		cfg,
+		stats.PushMetrics(e.pushMetrics),
+		exporterhelper.WithCapabilities(exporterCapabilities),
+		exporterhelper.WithStart(e.start),
+		exporterhelper.WithShutdown(unregisterQueueStats(stats)),
+		exporterhelper.WithTimeout(exporterhelper.TimeoutSettings{}),
+		exporterhelper.WithQueue(cfg.(*Config).QueueSettings),
+		exporterhelper.WithRetry(cfg.(*Config).BackOffConfig),
+	)
+	if err != nil {
+		stats.Unregister()
+		return nil, err
+	}
+	return stats.WrapMetrics(exp), nil
+}
+
+func createLogsExporter(
+	ctx context.Context,
+	set exporter.CreateSettings,
+	cfg component.Config,
+) (exporter.Logs, error) {
+	e, err := newTenantEnvelopeExporter(cfg.(*Config), set.Logger)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := registerQueueStats(cfg.(*Config), set, "logs")
+	if err != nil {
+		return nil, err
+	}
+
+	exp, err := exporterhelper.NewLogsExporter(
+		ctx,
+		set,
+		cfg,
+		stats.PushLogs(e.pushLogs),
+		exporterhelper.WithCapabilities(exporterCapabilities),
+		exporterhelper.WithStart(e.start),
+		exporterhelper.WithShutdown(unregisterQueueStats(stats)),
+		exporterhelper.WithTimeout(exporterhelper.TimeoutSettings{}),
+		exporterhelper.WithQueue(cfg.(*Config).QueueSettings),
+		exporterhelper.WithRetry(cfg.(*Config).BackOffConfig),
+	)
+	if err != nil {
+		stats.Unregister()
+		return nil, err
+	}
+	return stats.WrapLogs(exp), nil
+}
+
+// registerQueueStats reports the sending queue of a signal.
+func registerQueueStats(
+	config *Config,
+	set exporter.CreateSettings,
+	signal string,
+) (*queuestats.Stats, error) {
+	return queuestats.Register(queuestats.Settings{
+		Endpoint:       config.QueueStatsEndpoint,
+		Exporter:       set.ID.String(),
+		Signal:         signal,
+		Untracked:      config.QueueSettings.Enabled && config.QueueSettings.StorageID != nil,
+		RetryEnabled:   config.BackOffConfig.Enabled,
+		MaxElapsedTime: config.BackOffConfig.MaxElapsedTime,
+	}, set.Logger)
+}
+
+func unregisterQueueStats(stats *queuestats.Stats) component.ShutdownFunc {
+	return func(context.Context) error {
+		stats.Unregister()
+		return nil
+	}
+}
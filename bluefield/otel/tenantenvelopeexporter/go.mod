@@ -0,0 +1,3 @@
+module tenantenvelopeexporter
+
+go 1.22
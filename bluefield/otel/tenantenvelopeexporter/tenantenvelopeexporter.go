@@ -0,0 +1,282 @@
+package tenantenvelopeexporter
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/exporter/exporterhelper"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+
+	"otelcommon/envelope"
+	"otelcommon/retryafter"
+)
+
+// maxResponseSize limits the part of the endpoint's response that is logged
+const maxResponseSize = 1024
+
+type tenantEnvelopeExporter struct {
+	config  *Config
+	logger  *zap.Logger
+	client  *http.Client
+	keyring *envelope.Keyring
+}
+
+// exporter constructor
+func newTenantEnvelopeExporter(
+	config *Config,
+	logger *zap.Logger,
+) (*tenantEnvelopeExporter, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.CAFile != "" {
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca_file: %w", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificates found in ca_file")
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: roots}
+	}
+	return &tenantEnvelopeExporter{
+		config: config,
+		logger: logger,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   config.Timeout,
+		},
+	}, nil
+}
+
+// start reads the tenant keys, failing the collector's startup if one can't
+// be read.
+func (e *tenantEnvelopeExporter) start(context.Context, component.Host) error {
+	keyring, err := envelope.LoadKeyring(e.config.Tenants)
+	if err != nil {
+		return err
+	}
+	e.keyring = keyring
+	return nil
+}
+
+// tenant returns the tenant of a resource, if it has one with a key.
+func (e *tenantEnvelopeExporter) tenant(resource pcommon.Resource) (string, bool) {
+	value, exists := resource.Attributes().Get(e.config.TenantAttribute)
+	if !exists {
+		return "", false
+	}
+	tenant := value.AsString()
+	return tenant, e.keyring.Has(tenant)
+}
+
+func (e *tenantEnvelopeExporter) pushLogs(ctx context.Context, ld plog.Logs) error {
+	batches := make(map[string]plog.Logs)
+	dropped := 0
+	rls := ld.ResourceLogs()
+	for i := 0; i < rls.Len(); i++ {
+		rl := rls.At(i)
+		tenant, ok := e.tenant(rl.Resource())
+		if !ok {
+			dropped++
+			continue
+		}
+		batch, exists := batches[tenant]
+		if !exists {
+			batch = plog.NewLogs()
+			batches[tenant] = batch
+		}
+		rl.CopyTo(batch.ResourceLogs().AppendEmpty())
+	}
+	e.logDropped("logs", dropped)
+
+	marshaler := &plog.ProtoMarshaler{}
+	sent := make(map[string]bool)
+	var err error
+	for tenant, batch := range batches {
+		payload, marshalErr := marshaler.MarshalLogs(batch)
+		if err = e.send(ctx, "logs", tenant, payload, marshalErr); err != nil {
+			break
+		}
+		sent[tenant] = true
+	}
+	rls.RemoveIf(func(rl plog.ResourceLogs) bool {
+		tenant, ok := e.tenant(rl.Resource())
+		return !ok || sent[tenant]
+	})
+	return err
+}
+
+func (e *tenantEnvelopeExporter) pushMetrics(ctx context.Context, md pmetric.Metrics) error {
+	batches := make(map[string]pmetric.Metrics)
+	dropped := 0
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		rm := rms.At(i)
+		tenant, ok := e.tenant(rm.Resource())
+		if !ok {
+			dropped++
+			continue
+		}
+		batch, exists := batches[tenant]
+		if !exists {
+			batch = pmetric.NewMetrics()
+			batches[tenant] = batch
+		}
+		rm.CopyTo(batch.ResourceMetrics().AppendEmpty())
+	}
+	e.logDropped("metrics", dropped)
+
+	marshaler := &pmetric.ProtoMarshaler{}
+	sent := make(map[string]bool)
+	var err error
+	for tenant, batch := range batches {
+		payload, marshalErr := marshaler.MarshalMetrics(batch)
+		if err = e.send(ctx, "metrics", tenant, payload, marshalErr); err != nil {
+			break
+		}
+		sent[tenant] = true
+	}
+	rms.RemoveIf(func(rm pmetric.ResourceMetrics) bool {
+		tenant, ok := e.tenant(rm.Resource())
+		return !ok || sent[tenant]
+	})
+	return err
+}
+
+func (e *tenantEnvelopeExporter) pushTraces(ctx context.Context, td ptrace.Traces) error {
+	batches := make(map[string]ptrace.Traces)
+	dropped := 0
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		rs := rss.At(i)
+		tenant, ok := e.tenant(rs.Resource())
+		if !ok {
+			dropped++
+			continue
+		}
+		batch, exists := batches[tenant]
+		if !exists {
+			batch = ptrace.NewTraces()
+			batches[tenant] = batch
+		}
+		rs.CopyTo(batch.ResourceSpans().AppendEmpty())
+	}
+	e.logDropped("traces", dropped)
+
+	marshaler := &ptrace.ProtoMarshaler{}
+	sent := make(map[string]bool)
+	var err error
+	for tenant, batch := range batches {
+		payload, marshalErr := marshaler.MarshalTraces(batch)
+		if err = e.send(ctx, "traces", tenant, payload, marshalErr); err != nil {
+			break
+		}
+		sent[tenant] = true
+	}
+	rss.RemoveIf(func(rs ptrace.ResourceSpans) bool {
+		tenant, ok := e.tenant(rs.Resource())
+		return !ok || sent[tenant]
+	})
+	return err
+}
+
+func (e *tenantEnvelopeExporter) logDropped(signal string, resources int) {
+	if resources == 0 {
+		return
+	}
+	e.logger.Warn("Dropping resources without a tenant key",
+		zap.String("signal", signal),
+		zap.Int("resources", resources))
+}
+
+// send seals the OTLP protobuf payload of a tenant with a new data key and
+// posts it, returning an error only if it should be retried. Payloads that
+// can't be marshaled or sealed, or that the endpoint rejects, are dropped.
+func (e *tenantEnvelopeExporter) send(
+	ctx context.Context,
+	signal string,
+	tenant string,
+	payload []byte,
+	marshalErr error,
+) error {
+	if marshalErr != nil {
+		e.logger.Error("Failed to marshal payload, dropping it",
+			zap.String("tenant", tenant), zap.Error(marshalErr))
+		return nil
+	}
+	sealer, err := e.keyring.NewSealer(tenant)
+	if err != nil {
+		e.logger.Error("Failed to seal payload, dropping it",
+			zap.String("tenant", tenant), zap.Error(err))
+		return nil
+	}
+	sealed, err := sealer.Seal(payload)
+	if err != nil {
+		e.logger.Error("Failed to seal payload, dropping it",
+			zap.String("tenant", tenant), zap.Error(err))
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		strings.TrimSuffix(e.config.Endpoint, "/")+"/v1/"+signal,
+		bytes.NewReader(sealed))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("X-Envelope-Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Envelope-Tenant", tenant)
+	req.Header.Set("X-Envelope-Key-Id", sealer.KeyID)
+	req.Header.Set("X-Envelope-Wrapped-Key",
+		base64.StdEncoding.EncodeToString(sealer.WrappedKey))
+	req.Header.Set("X-Envelope-Alg", envelope.Algorithm)
+	for key, value := range e.config.Headers {
+		req.Header.Set(key, value)
+	}
+	if e.config.BearerTokenFile != "" {
+		token, err := os.ReadFile(e.config.BearerTokenFile)
+		if err != nil {
+			return fmt.Errorf("failed to read bearer_token_file: %w", err)
+		}
+		req.Header.Set("Authorization",
+			"Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	response, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return exporterhelper.NewThrottleRetry(
+			fmt.Errorf("endpoint returned %s", resp.Status),
+			retryafter.Parse(resp.Header.Get("Retry-After")))
+	case resp.StatusCode >= 500:
+		return fmt.Errorf("endpoint returned %s: %s", resp.Status,
+			bytes.TrimSpace(response))
+	}
+	e.logger.Error("Endpoint rejected payload, dropping it",
+		zap.String("tenant", tenant),
+		zap.String("status", resp.Status),
+		zap.ByteString("response", bytes.TrimSpace(response)))
+	return nil
+}
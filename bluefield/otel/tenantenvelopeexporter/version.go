@@ -0,0 +1,3 @@
+package tenantenvelopeexporter
+
+const Version = "0.0.1"
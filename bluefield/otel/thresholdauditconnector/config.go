@@ -0,0 +1,88 @@
+package thresholdauditconnector
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+// Config defines the configuration of the threshold_audit connector.
+type Config struct {
+	// Rules are the thresholds whose breaches are recorded.
+	Rules []Rule `mapstructure:"rules"`
+
+	// RecordRecoveries configures whether a record is also emitted when a
+	// series is back within its thresholds. Defaults to true.
+	RecordRecoveries bool `mapstructure:"record_recoveries"`
+
+	// SeriesTTL configures how long the state of a series without new
+	// values is kept. A series seen again after it expired is treated as
+	// new. Defaults to "1h".
+	SeriesTTL time.Duration `mapstructure:"series_ttl"`
+}
+
+// Rule is a threshold on the datapoints of a metric.
+type Rule struct {
+	// ID identifies the rule in audit records. It must be unique.
+	ID string `mapstructure:"id"`
+
+	// MetricName is the name of the gauge or sum metric the rule applies
+	// to.
+	MetricName string `mapstructure:"metric_name"`
+
+	// Attributes restricts the rule to datapoints with these attribute
+	// values.
+	Attributes map[string]string `mapstructure:"attributes"`
+
+	// Above is breached by values greater than it.
+	Above *float64 `mapstructure:"above"`
+
+	// Below is breached by values less than it. At least one of Above and
+	// Below must be specified.
+	Below *float64 `mapstructure:"below"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if len(cfg.Rules) == 0 {
+		return errors.New("rules must be specified")
+	}
+	ids := make(map[string]bool)
+	for i, rule := range cfg.Rules {
+		if rule.ID == "" {
+			return fmt.Errorf("rules[%d]: id must be specified", i)
+		}
+		if ids[rule.ID] {
+			return fmt.Errorf("rules[%d]: duplicate id %q", i, rule.ID)
+		}
+		ids[rule.ID] = true
+		if rule.MetricName == "" {
+			return fmt.Errorf("rule %q: metric_name must be specified", rule.ID)
+		}
+		if rule.Above == nil && rule.Below == nil {
+			return fmt.Errorf("rule %q: above or below must be specified",
+				rule.ID)
+		}
+		if rule.Above != nil && rule.Below != nil && *rule.Below >= *rule.Above {
+			return fmt.Errorf("rule %q: below must be less than above",
+				rule.ID)
+		}
+	}
+	if cfg.SeriesTTL <= 0 {
+		return errors.New("series_ttl must be positive")
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		RecordRecoveries: true,
+		SeriesTTL:        time.Hour,
+	}
+}
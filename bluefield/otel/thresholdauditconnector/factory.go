@@ -0,0 +1,31 @@
+package thresholdauditconnector
+
+import (
+	"context"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/connector"
+	"go.opentelemetry.io/collector/consumer"
+)
+
+const (
+	typeStr   = "threshold_audit"
+	stability = component.StabilityLevelAlpha
+)
+
+func NewFactory() connector.Factory {
+	return connector.NewFactory(
+		component.MustNewType(typeStr),
+		createDefaultConfig,
+		connector.WithMetricsToLogs(createMetricsToLogs, stability),
+	)
+}
+
+func createMetricsToLogs(
+	_ context.Context,
+	set connector.CreateSettings,
+	cfg component.Config,
+	nextConsumer consumer.Logs,
+) (connector.Metrics, error) {
+	return newThresholdAuditConnector(cfg.(*Config), set.Logger, nextConsumer), nil
+}
@@ -0,0 +1,3 @@
+module thresholdauditconnector
+
+go 1.22
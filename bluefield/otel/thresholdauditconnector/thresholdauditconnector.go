@@ -0,0 +1,329 @@
+package thresholdauditconnector
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+
+	"otelcommon/attrhash"
+)
+
+const scopeName = "thresholdauditconnector"
+
+const (
+	eventBreach   = "breach"
+	eventRecovery = "recovery"
+
+	conditionAbove = "above"
+	conditionBelow = "below"
+)
+
+type thresholdAuditConnector struct {
+	config       *Config
+	logger       *zap.Logger
+	logsConsumer consumer.Logs
+	stateLock    sync.Mutex
+	series       map[uint64]*series
+	sequence     int64  // of the last audit record
+	lastHash     string // of the last audit record
+	stopChannel  chan struct{}
+	stopWaiters  sync.WaitGroup
+}
+
+// series holds the state of a time series of a rule.
+type series struct {
+	value     float64
+	condition string // of the breached threshold, empty within thresholds
+	lastSeen  time.Time
+}
+
+// record is the content of an audit record that its hash is computed from.
+type record struct {
+	Sequence     int64          `json:"sequence"`
+	PreviousHash string         `json:"previous_hash"`
+	Timestamp    int64          `json:"timestamp"`
+	RuleID       string         `json:"rule_id"`
+	Event        string         `json:"event"`
+	Condition    string         `json:"condition"`
+	Threshold    float64        `json:"threshold"`
+	MetricName   string         `json:"metric_name"`
+	Attributes   map[string]any `json:"attributes"`
+	ValueBefore  *float64       `json:"value_before"`
+	ValueAfter   float64        `json:"value_after"`
+	Resource     map[string]any `json:"resource"`
+}
+
+func newThresholdAuditConnector(
+	config *Config,
+	logger *zap.Logger,
+	logsConsumer consumer.Logs,
+) *thresholdAuditConnector {
+	return &thresholdAuditConnector{
+		config:       config,
+		logger:       logger,
+		logsConsumer: logsConsumer,
+		series:       make(map[uint64]*series),
+		stopChannel:  make(chan struct{}),
+	}
+}
+
+func (c *thresholdAuditConnector) Start(context.Context, component.Host) error {
+	c.stopWaiters.Add(1)
+	go c.expireLoop()
+	return nil
+}
+
+func (c *thresholdAuditConnector) Shutdown(context.Context) error {
+	close(c.stopChannel)
+	c.stopWaiters.Wait()
+	return nil
+}
+
+func (c *thresholdAuditConnector) Capabilities() consumer.Capabilities {
+	return consumer.Capabilities{MutatesData: false}
+}
+
+func (c *thresholdAuditConnector) expireLoop() {
+	defer c.stopWaiters.Done()
+
+	ticker := time.NewTicker(min(c.config.SeriesTTL, time.Minute))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.expireSeries()
+		case <-c.stopChannel:
+			return
+		}
+	}
+}
+
+func (c *thresholdAuditConnector) expireSeries() {
+	cutoff := time.Now().Add(-c.config.SeriesTTL)
+
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	for key, s := range c.series {
+		if s.lastSeen.Before(cutoff) {
+			delete(c.series, key)
+		}
+	}
+}
+
+func (c *thresholdAuditConnector) ConsumeMetrics(ctx context.Context, md pmetric.Metrics) error {
+	ld := c.buildAuditLogs(md)
+	if ld.LogRecordCount() == 0 {
+		return nil
+	}
+	return c.logsConsumer.ConsumeLogs(ctx, ld)
+}
+
+// buildAuditLogs checks the datapoints of the metrics against the rules, and
+// returns an audit record for each breach and recovery.
+func (c *thresholdAuditConnector) buildAuditLogs(md pmetric.Metrics) plog.Logs {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	ld := plog.NewLogs()
+	now := time.Now()
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		rm := rms.At(i)
+		var sl plog.ScopeLogs
+		sms := rm.ScopeMetrics()
+		for j := 0; j < sms.Len(); j++ {
+			metrics := sms.At(j).Metrics()
+			for k := 0; k < metrics.Len(); k++ {
+				metric := metrics.At(k)
+				for r := range c.config.Rules {
+					rule := &c.config.Rules[r]
+					if rule.MetricName != metric.Name() {
+						continue
+					}
+					c.checkRule(rule, r, metric, rm.Resource(), now,
+						func() plog.LogRecordSlice {
+							// the resource of the metrics is only
+							// added once a record is emitted
+							if sl == (plog.ScopeLogs{}) {
+								rl := ld.ResourceLogs().AppendEmpty()
+								rm.Resource().CopyTo(rl.Resource())
+								sl = rl.ScopeLogs().AppendEmpty()
+								sl.Scope().SetName(scopeName)
+								sl.Scope().SetVersion(Version)
+							}
+							return sl.LogRecords()
+						})
+				}
+			}
+		}
+	}
+	return ld
+}
+
+// checkRule updates the state of the series of a metric a rule applies to, and
+// appends an audit record for each series that breached a threshold or, if
+// configured, recovered.
+func (c *thresholdAuditConnector) checkRule(
+	rule *Rule,
+	ruleIndex int,
+	metric pmetric.Metric,
+	resource pcommon.Resource,
+	now time.Time,
+	logRecords func() plog.LogRecordSlice,
+) {
+	var dps pmetric.NumberDataPointSlice
+	switch metric.Type() {
+	case pmetric.MetricTypeGauge:
+		dps = metric.Gauge().DataPoints()
+	case pmetric.MetricTypeSum:
+		dps = metric.Sum().DataPoints()
+	default:
+		return
+	}
+
+	resourceHash := attrhash.Attributes(attrhash.String(0, fmt.Sprint(ruleIndex)),
+		resource.Attributes())
+	for i := 0; i < dps.Len(); i++ {
+		dp := dps.At(i)
+		if !matchAttributes(rule.Attributes, dp.Attributes()) {
+			continue
+		}
+
+		value := dp.DoubleValue()
+		if dp.ValueType() == pmetric.NumberDataPointValueTypeInt {
+			value = float64(dp.IntValue())
+		}
+		condition := ""
+		switch {
+		case rule.Above != nil && value > *rule.Above:
+			condition = conditionAbove
+		case rule.Below != nil && value < *rule.Below:
+			condition = conditionBelow
+		}
+
+		key := attrhash.Attributes(resourceHash, dp.Attributes())
+		s, exists := c.series[key]
+		if !exists {
+			s = &series{}
+			c.series[key] = s
+		}
+		before := *s
+		s.value, s.condition, s.lastSeen = value, condition, now
+
+		// the first value of a series has no value before it
+		valueBefore := &before.value
+		if !exists {
+			valueBefore = nil
+		}
+
+		switch {
+		case condition != "" && condition != before.condition:
+			c.appendRecord(logRecords(), rule, eventBreach, condition, metric,
+				resource, dp, valueBefore, value)
+		case condition == "" && before.condition != "" &&
+			c.config.RecordRecoveries:
+			c.appendRecord(logRecords(), rule, eventRecovery,
+				before.condition, metric, resource, dp, &before.value, value)
+		}
+	}
+}
+
+// appendRecord appends an audit record, chained to the previous one by
+// including its hash in the hash of the new record, so that removed or
+// modified records can be detected.
+func (c *thresholdAuditConnector) appendRecord(
+	logRecords plog.LogRecordSlice,
+	rule *Rule,
+	event string,
+	condition string,
+	metric pmetric.Metric,
+	resource pcommon.Resource,
+	dp pmetric.NumberDataPoint,
+	valueBefore *float64,
+	valueAfter float64,
+) {
+	threshold := rule.Above
+	if condition == conditionBelow {
+		threshold = rule.Below
+	}
+
+	rec := record{
+		Sequence:     c.sequence + 1,
+		PreviousHash: c.lastHash,
+		Timestamp:    int64(dp.Timestamp()),
+		RuleID:       rule.ID,
+		Event:        event,
+		Condition:    condition,
+		Threshold:    *threshold,
+		MetricName:   metric.Name(),
+		Attributes:   dp.Attributes().AsRaw(),
+		ValueBefore:  valueBefore,
+		ValueAfter:   valueAfter,
+		Resource:     resource.Attributes().AsRaw(),
+	}
+	// map keys are marshaled in sorted order, so the hash is reproducible
+	// from the record
+	content, err := json.Marshal(rec)
+	if err != nil {
+		c.logger.Error("Failed to marshal audit record", zap.Error(err))
+		return
+	}
+	sum := sha256.Sum256(content)
+	c.sequence = rec.Sequence
+	c.lastHash = hex.EncodeToString(sum[:])
+
+	lr := logRecords.AppendEmpty()
+	lr.SetTimestamp(dp.Timestamp())
+	lr.SetObservedTimestamp(pcommon.NewTimestampFromTime(time.Now()))
+	if event == eventBreach {
+		lr.SetSeverityNumber(plog.SeverityNumberWarn)
+		lr.SetSeverityText("WARN")
+		lr.Body().SetStr(fmt.Sprintf("%s breached threshold %s %g of rule %s "+
+			"with value %g", metric.Name(), condition, *threshold, rule.ID,
+			valueAfter))
+	} else {
+		lr.SetSeverityNumber(plog.SeverityNumberInfo)
+		lr.SetSeverityText("INFO")
+		lr.Body().SetStr(fmt.Sprintf("%s recovered from threshold %s %g of "+
+			"rule %s with value %g", metric.Name(), condition, *threshold,
+			rule.ID, valueAfter))
+	}
+
+	attrs := lr.Attributes()
+	dp.Attributes().CopyTo(attrs)
+	attrs.PutInt("audit.sequence", rec.Sequence)
+	attrs.PutStr("audit.previous_hash", rec.PreviousHash)
+	attrs.PutStr("audit.hash", c.lastHash)
+	attrs.PutStr("audit.rule_id", rule.ID)
+	attrs.PutStr("audit.event", event)
+	attrs.PutStr("audit.condition", condition)
+	attrs.PutDouble("audit.threshold", *threshold)
+	attrs.PutStr("audit.metric_name", metric.Name())
+	if valueBefore != nil {
+		attrs.PutDouble("audit.value_before", *valueBefore)
+	}
+	attrs.PutDouble("audit.value_after", valueAfter)
+}
+
+func matchAttributes(want map[string]string, attrs pcommon.Map) bool {
+	for k, v := range want {
+		value, exists := attrs.Get(k)
+		if !exists || value.AsString() != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,3 @@
+package thresholdauditconnector
+
+const Version = "0.0.1"
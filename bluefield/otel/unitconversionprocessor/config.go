@@ -0,0 +1,133 @@
+package unitconversionprocessor
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+// Config defines the configuration of the unit_conversion processor.
+type Config struct {
+	// Conversions convert the values of metrics. The first conversion
+	// matching a metric is applied.
+	Conversions []Conversion `mapstructure:"conversions"`
+
+	// OriginalUnitAttribute is the datapoint attribute the unit of a
+	// converted metric before conversion is set as. Empty to not set it.
+	// Defaults to "unit.original".
+	OriginalUnitAttribute string `mapstructure:"original_unit_attribute"`
+}
+
+// Conversion converts metrics from one unit to another, as
+// value * factor + offset.
+type Conversion struct {
+	// MetricNames is a list of names of metrics to convert.
+	MetricNames []string `mapstructure:"metric_names"`
+
+	// MetricRegex is a regular expression matching names of metrics to
+	// convert.
+	MetricRegex string `mapstructure:"metric_regex"`
+
+	// From is the unit metrics must have to be converted, e.g. "cCel".
+	// Metrics with a different unit, e.g. metrics converted by an earlier
+	// processor, are left unchanged.
+	From string `mapstructure:"from"`
+
+	// To is the unit of converted metrics, e.g. "Cel".
+	To string `mapstructure:"to"`
+
+	// Factor is the factor values are multiplied by. It can be omitted
+	// for conversions between common units, e.g. from "cCel" to "Cel", and
+	// must be specified for others, e.g. from "{cells}" to "By", or to
+	// scale values without changing the unit. Must be positive.
+	Factor float64 `mapstructure:"factor"`
+
+	// Offset is added to values after multiplying them by Factor.
+	Offset float64 `mapstructure:"offset"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if len(cfg.Conversions) == 0 {
+		return errors.New("at least one conversion must be configured")
+	}
+	for i, conversion := range cfg.Conversions {
+		if err := conversion.validate(); err != nil {
+			return fmt.Errorf("conversions[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (conversion *Conversion) validate() error {
+	if len(conversion.MetricNames) == 0 && conversion.MetricRegex == "" {
+		return errors.New("metric_names or metric_regex must be specified")
+	}
+	if conversion.MetricRegex != "" {
+		if _, err := regexp.Compile(conversion.MetricRegex); err != nil {
+			return fmt.Errorf("invalid metric_regex: %w", err)
+		}
+	}
+	if conversion.From == "" || conversion.To == "" {
+		return errors.New("from and to must be specified")
+	}
+	if math.IsNaN(conversion.Factor) || math.IsInf(conversion.Factor, 0) ||
+		math.IsNaN(conversion.Offset) || math.IsInf(conversion.Offset, 0) {
+		return errors.New("factor and offset must be finite")
+	}
+	if conversion.Factor < 0 {
+		// a negative factor would reverse the buckets of histograms
+		return errors.New("factor must be positive")
+	}
+	if _, err := conversion.linear(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// linear returns the conversion of values, from the configured factor and
+// offset or from the known conversions.
+func (conversion *Conversion) linear() (linearConversion, error) {
+	known, isKnown := knownConversions[unitPair{conversion.From, conversion.To}]
+	if conversion.Factor == 0 {
+		if !isKnown {
+			return linearConversion{}, fmt.Errorf("no known conversion from "+
+				"%q to %q, factor must be specified", conversion.From,
+				conversion.To)
+		}
+		if conversion.Offset != 0 {
+			return linearConversion{}, errors.New("offset requires factor")
+		}
+		return known, nil
+	}
+	if conversion.From == conversion.To && conversion.Factor == 1 &&
+		conversion.Offset == 0 {
+		return linearConversion{}, errors.New("conversion doesn't change values")
+	}
+	if isKnown && (!almostEqual(conversion.Factor, known.factor) ||
+		!almostEqual(conversion.Offset, known.offset)) {
+		return linearConversion{}, fmt.Errorf("factor and offset contradict "+
+			"the conversion from %q to %q, which is %g * value + %g, omit "+
+			"them", conversion.From, conversion.To, known.factor, known.offset)
+	}
+	return linearConversion{factor: conversion.Factor, offset: conversion.Offset}, nil
+}
+
+// almostEqual compares the factors and offsets of conversions, which are not
+// exact for inverse conversions.
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(math.Abs(a), math.Abs(b))
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		OriginalUnitAttribute: "unit.original",
+	}
+}
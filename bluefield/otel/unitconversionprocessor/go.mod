@@ -0,0 +1,3 @@
+module unitconversionprocessor
+
+go 1.22
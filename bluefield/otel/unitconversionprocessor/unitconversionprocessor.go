@@ -0,0 +1,182 @@
+package unitconversionprocessor
+
+import (
+	"context"
+	"math"
+	"regexp"
+	"slices"
+	"sync"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+// maxExactInt is the largest integer all smaller integers of which a float64
+// represents exactly
+const maxExactInt = 1 << 53
+
+type unitConversionProcessor struct {
+	config      *Config
+	logger      *zap.Logger
+	conversions []compiledConversion
+
+	// names of exponential histograms that were logged as not converted
+	unsupportedLock sync.Mutex
+	unsupported     map[string]bool
+}
+
+// compiledConversion is a Conversion with its regular expression compiled and
+// its factor and offset resolved.
+type compiledConversion struct {
+	metricNames []string
+	reMetric    *regexp.Regexp
+	from        string
+	to          string
+	linearConversion
+}
+
+// processor constructor
+func newUnitConversionProcessor(config *Config, logger *zap.Logger) *unitConversionProcessor {
+	p := &unitConversionProcessor{
+		config:      config,
+		logger:      logger,
+		unsupported: make(map[string]bool),
+	}
+	for _, conversion := range config.Conversions {
+		c := compiledConversion{
+			metricNames: conversion.MetricNames,
+			from:        conversion.From,
+			to:          conversion.To,
+		}
+		if conversion.MetricRegex != "" {
+			c.reMetric = regexp.MustCompile(conversion.MetricRegex)
+		}
+		c.linearConversion, _ = conversion.linear()
+		p.conversions = append(p.conversions, c)
+	}
+	return p
+}
+
+func (p *unitConversionProcessor) processMetrics(
+	_ context.Context,
+	md pmetric.Metrics,
+) (pmetric.Metrics, error) {
+	for i := 0; i < md.ResourceMetrics().Len(); i++ {
+		rm := md.ResourceMetrics().At(i)
+		for j := 0; j < rm.ScopeMetrics().Len(); j++ {
+			sm := rm.ScopeMetrics().At(j)
+			for k := 0; k < sm.Metrics().Len(); k++ {
+				metric := sm.Metrics().At(k)
+				if c := p.conversion(metric.Name()); c != nil && metric.Unit() == c.from {
+					p.convertMetric(metric, c)
+				}
+			}
+		}
+	}
+	return md, nil
+}
+
+// conversion returns the first conversion matching the name of a metric, or
+// nil if none does.
+func (p *unitConversionProcessor) conversion(name string) *compiledConversion {
+	for i := range p.conversions {
+		c := &p.conversions[i]
+		if slices.Contains(c.metricNames, name) ||
+			(c.reMetric != nil && c.reMetric.MatchString(name)) {
+			return c
+		}
+	}
+	return nil
+}
+
+func (p *unitConversionProcessor) convertMetric(metric pmetric.Metric, c *compiledConversion) {
+	switch metric.Type() {
+	case pmetric.MetricTypeGauge:
+		p.convertNumberDataPoints(metric.Gauge().DataPoints(), c)
+	case pmetric.MetricTypeSum:
+		p.convertNumberDataPoints(metric.Sum().DataPoints(), c)
+	case pmetric.MetricTypeHistogram:
+		dps := metric.Histogram().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			dp := dps.At(i)
+			bounds := dp.ExplicitBounds().AsRaw()
+			for j := range bounds {
+				bounds[j] = c.convert(bounds[j])
+			}
+			dp.ExplicitBounds().FromRaw(bounds)
+			if dp.HasSum() {
+				dp.SetSum(dp.Sum()*c.factor + c.offset*float64(dp.Count()))
+			}
+			if dp.HasMin() {
+				dp.SetMin(c.convert(dp.Min()))
+			}
+			if dp.HasMax() {
+				dp.SetMax(c.convert(dp.Max()))
+			}
+			p.putOriginalUnit(dp.Attributes(), c)
+		}
+	case pmetric.MetricTypeSummary:
+		dps := metric.Summary().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			dp := dps.At(i)
+			dp.SetSum(dp.Sum()*c.factor + c.offset*float64(dp.Count()))
+			for j := 0; j < dp.QuantileValues().Len(); j++ {
+				quantile := dp.QuantileValues().At(j)
+				quantile.SetValue(c.convert(quantile.Value()))
+			}
+			p.putOriginalUnit(dp.Attributes(), c)
+		}
+	default:
+		// the buckets of exponential histograms cannot be scaled by
+		// arbitrary factors
+		p.unsupportedLock.Lock()
+		defer p.unsupportedLock.Unlock()
+		if !p.unsupported[metric.Name()] {
+			p.unsupported[metric.Name()] = true
+			p.logger.Warn("Metric type doesn't support unit conversion, "+
+				"leaving it unchanged", zap.String("metric", metric.Name()),
+				zap.String("type", metric.Type().String()))
+		}
+		return
+	}
+	metric.SetUnit(c.to)
+}
+
+// convertNumberDataPoints converts integer values to doubles unless the
+// conversion maps integers to integers.
+func (p *unitConversionProcessor) convertNumberDataPoints(
+	dps pmetric.NumberDataPointSlice,
+	c *compiledConversion,
+) {
+	for i := 0; i < dps.Len(); i++ {
+		dp := dps.At(i)
+		switch dp.ValueType() {
+		case pmetric.NumberDataPointValueTypeDouble:
+			dp.SetDoubleValue(c.convert(dp.DoubleValue()))
+		case pmetric.NumberDataPointValueTypeInt:
+			value := c.convert(float64(dp.IntValue()))
+			if c.integral() && math.Abs(value) < maxExactInt {
+				dp.SetIntValue(int64(value))
+			} else {
+				dp.SetDoubleValue(value)
+			}
+		}
+		p.putOriginalUnit(dp.Attributes(), c)
+	}
+}
+
+func (p *unitConversionProcessor) putOriginalUnit(attrs pcommon.Map, c *compiledConversion) {
+	if p.config.OriginalUnitAttribute != "" {
+		attrs.PutStr(p.config.OriginalUnitAttribute, c.from)
+	}
+}
+
+func (c *linearConversion) convert(value float64) float64 {
+	return value*c.factor + c.offset
+}
+
+// integral returns whether the conversion maps integers to integers.
+func (c *linearConversion) integral() bool {
+	return c.factor == math.Trunc(c.factor) && c.offset == math.Trunc(c.offset)
+}
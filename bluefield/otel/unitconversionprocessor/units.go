@@ -0,0 +1,66 @@
+package unitconversionprocessor
+
+// linearConversion converts a value v to v*factor + offset.
+type linearConversion struct {
+	factor float64
+	offset float64
+}
+
+type unitPair struct {
+	from string
+	to   string
+}
+
+// knownConversions are the conversions between UCUM units that don't need a
+// factor to be configured. The inverse conversions are added by init.
+var knownConversions = map[unitPair]linearConversion{
+	// temperature, e.g. DTS sensors reporting centi-degrees
+	{"dCel", "Cel"}: {factor: 0.1},
+	{"cCel", "Cel"}: {factor: 0.01},
+	{"mCel", "Cel"}: {factor: 0.001},
+	{"Cel", "K"}:    {factor: 1, offset: 273.15},
+
+	// information
+	{"bit", "By"}:  {factor: 0.125},
+	{"kBy", "By"}:  {factor: 1e3},
+	{"MBy", "By"}:  {factor: 1e6},
+	{"GBy", "By"}:  {factor: 1e9},
+	{"KiBy", "By"}: {factor: 1 << 10},
+	{"MiBy", "By"}: {factor: 1 << 20},
+	{"GiBy", "By"}: {factor: 1 << 30},
+
+	// data rates
+	{"bit/s", "By/s"}:  {factor: 0.125},
+	{"kbit/s", "By/s"}: {factor: 125},
+	{"Mbit/s", "By/s"}: {factor: 125e3},
+	{"Gbit/s", "By/s"}: {factor: 125e6},
+
+	// time
+	{"ns", "s"}:  {factor: 1e-9},
+	{"us", "s"}:  {factor: 1e-6},
+	{"ms", "s"}:  {factor: 1e-3},
+	{"min", "s"}: {factor: 60},
+	{"h", "s"}:   {factor: 3600},
+
+	// electrical
+	{"mV", "V"}: {factor: 1e-3},
+	{"mA", "A"}: {factor: 1e-3},
+	{"mW", "W"}: {factor: 1e-3},
+	{"uW", "W"}: {factor: 1e-6},
+
+	// ratios
+	{"%", "1"}: {factor: 0.01},
+}
+
+func init() {
+	inverses := make(map[unitPair]linearConversion, len(knownConversions))
+	for pair, c := range knownConversions {
+		inverses[unitPair{pair.to, pair.from}] = linearConversion{
+			factor: 1 / c.factor,
+			offset: -c.offset / c.factor,
+		}
+	}
+	for pair, c := range inverses {
+		knownConversions[pair] = c
+	}
+}
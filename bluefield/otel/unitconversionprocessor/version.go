@@ -0,0 +1,3 @@
+package unitconversionprocessor
+
+const Version = "0.0.1"
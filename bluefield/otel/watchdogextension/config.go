@@ -0,0 +1,75 @@
+package watchdogextension
+
+import (
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+// Config defines the configuration of the watchdog extension.
+type Config struct {
+	// Interval configures how often the watchdog is petted. If zero, it
+	// defaults to half of the systemd watchdog timeout given by
+	// WATCHDOG_USEC, or 10s if that is unset.
+	Interval time.Duration `mapstructure:"interval"`
+
+	// StallTimeout configures how long telemetry_stats may report no new
+	// log records or metric datapoints before the watchdog is no longer
+	// petted, so that a wedged collector is restarted. Defaults to "5m".
+	StallTimeout time.Duration `mapstructure:"stall_timeout"`
+
+	// RequireProgress configures whether petting depends on data moving
+	// through telemetry_stats at all, which requires a telemetry_stats
+	// processor in at least one pipeline. If false, the watchdog is petted
+	// as long as the collector is running. Defaults to false.
+	RequireProgress bool `mapstructure:"require_progress"`
+
+	// Systemd configures whether to notify systemd with WATCHDOG=1 when
+	// NOTIFY_SOCKET is set. Defaults to true.
+	Systemd bool `mapstructure:"systemd"`
+
+	// HardwareWatchdog optionally pets a hardware watchdog device as well.
+	HardwareWatchdog HardwareWatchdog `mapstructure:"hardware_watchdog"`
+}
+
+// HardwareWatchdog defines the hardware watchdog device to pet.
+type HardwareWatchdog struct {
+	// Enabled configures whether the hardware watchdog is petted.
+	Enabled bool `mapstructure:"enabled"`
+
+	// Device is the watchdog device path. Defaults to "/dev/watchdog".
+	Device string `mapstructure:"device"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.Interval < 0 {
+		return errors.New("interval cannot be negative")
+	}
+	if cfg.RequireProgress && cfg.StallTimeout <= 0 {
+		return errors.New("stall_timeout must be positive when " +
+			"require_progress is enabled")
+	}
+	if cfg.Interval > 0 && cfg.StallTimeout > 0 && cfg.StallTimeout < cfg.Interval {
+		return errors.New("stall_timeout must not be shorter than interval")
+	}
+	if cfg.HardwareWatchdog.Enabled && cfg.HardwareWatchdog.Device == "" {
+		return errors.New("hardware_watchdog.device cannot be empty")
+	}
+	return nil
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		StallTimeout: 5 * time.Minute,
+		Systemd:      true,
+		HardwareWatchdog: HardwareWatchdog{
+			Device: "/dev/watchdog",
+		},
+	}
+}
@@ -0,0 +1,3 @@
+module watchdogextension
+
+go 1.22
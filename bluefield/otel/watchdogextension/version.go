@@ -0,0 +1,3 @@
+package watchdogextension
+
+const Version = "0.0.1"
@@ -0,0 +1,218 @@
+package watchdogextension
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/extension"
+	"go.uber.org/zap"
+
+	"telemetrystatsprocessor"
+)
+
+const defaultInterval = 10 * time.Second
+
+// the collector notifies pipeline watchers once all pipelines are running,
+// which is when systemd is told the collector is ready
+var _ extension.PipelineWatcher = (*watchdogExtension)(nil)
+
+type watchdogExtension struct {
+	config       *Config
+	logger       *zap.Logger
+	interval     time.Duration
+	readySocket  string // NOTIFY_SOCKET, even if not petting systemd
+	notifySocket string
+	device       *os.File
+	stopChannel  chan struct{}
+	stopWaiters  sync.WaitGroup
+
+	// progress tracking, only accessed by the watchdog loop
+	lastTotal    int64
+	lastProgress time.Time
+	stalled      bool
+}
+
+func newWatchdogExtension(config *Config, logger *zap.Logger) *watchdogExtension {
+	return &watchdogExtension{
+		config:      config,
+		logger:      logger,
+		stopChannel: make(chan struct{}),
+	}
+}
+
+func (w *watchdogExtension) Start(_ context.Context, _ component.Host) error {
+	w.readySocket = os.Getenv("NOTIFY_SOCKET")
+	if w.config.Systemd {
+		w.notifySocket = w.readySocket
+	}
+	w.interval = w.petInterval()
+
+	if w.config.HardwareWatchdog.Enabled {
+		device, err := os.OpenFile(w.config.HardwareWatchdog.Device, os.O_WRONLY, 0)
+		if err != nil {
+			return fmt.Errorf("failed to open hardware watchdog: %w", err)
+		}
+		w.device = device
+	}
+
+	if w.notifySocket == "" && w.device == nil {
+		w.logger.Info("No systemd or hardware watchdog available, " +
+			"watchdog extension is inactive")
+		return nil
+	}
+
+	w.lastTotal = telemetrystatsprocessor.ProcessedTotal()
+	w.lastProgress = time.Now()
+
+	w.logger.Info("Starting watchdog",
+		zap.Duration("interval", w.interval),
+		zap.Bool("systemd", w.notifySocket != ""),
+		zap.Bool("hardware", w.device != nil),
+	)
+
+	w.stopWaiters.Add(1)
+	go w.watchdogLoop()
+
+	return nil
+}
+
+func (w *watchdogExtension) Shutdown(context.Context) error {
+	close(w.stopChannel)
+	w.stopWaiters.Wait()
+
+	if w.device != nil {
+		// Write the magic character so that closing the device disarms
+		// the hardware watchdog instead of letting it reset the card.
+		if _, err := w.device.Write([]byte("V")); err != nil {
+			w.logger.Error("Failed to disarm hardware watchdog", zap.Error(err))
+		}
+		if err := w.device.Close(); err != nil {
+			w.logger.Error("Failed to close hardware watchdog", zap.Error(err))
+		}
+		w.device = nil
+	}
+	return nil
+}
+
+// Ready tells systemd that the collector has started, as required by units with
+// Type=notify. The systemd watchdog only starts once the unit is ready.
+func (w *watchdogExtension) Ready() error {
+	if w.readySocket == "" {
+		return nil
+	}
+	if err := sdNotify(w.readySocket, "READY=1"); err != nil {
+		w.logger.Error("Failed to notify systemd of readiness", zap.Error(err))
+	}
+	return nil
+}
+
+// NotReady tells systemd that the collector is shutting down.
+func (w *watchdogExtension) NotReady() error {
+	if w.readySocket == "" {
+		return nil
+	}
+	if err := sdNotify(w.readySocket, "STOPPING=1"); err != nil {
+		w.logger.Error("Failed to notify systemd of shutdown", zap.Error(err))
+	}
+	return nil
+}
+
+// petInterval returns the configured interval, else half of the systemd
+// watchdog timeout so that a single late pet does not trigger a restart.
+func (w *watchdogExtension) petInterval() time.Duration {
+	if w.config.Interval > 0 {
+		return w.config.Interval
+	}
+	if usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64); err == nil && usec > 0 {
+		return time.Duration(usec) * time.Microsecond / 2
+	}
+	return defaultInterval
+}
+
+func (w *watchdogExtension) watchdogLoop() {
+	defer w.stopWaiters.Done()
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	w.pet()
+	for {
+		select {
+		case <-ticker.C:
+			if w.isHealthy(time.Now()) {
+				w.pet()
+			}
+		case <-w.stopChannel:
+			return
+		}
+	}
+}
+
+// isHealthy returns whether telemetry_stats has seen new data within the
+// stall timeout, logging transitions between moving and stalled pipelines.
+func (w *watchdogExtension) isHealthy(now time.Time) bool {
+	if !w.config.RequireProgress {
+		return true
+	}
+
+	total := telemetrystatsprocessor.ProcessedTotal()
+	if total != w.lastTotal {
+		w.lastTotal = total
+		w.lastProgress = now
+		if w.stalled {
+			w.logger.Info("Pipelines are moving data again, resume petting watchdog")
+			w.stalled = false
+		}
+		return true
+	}
+
+	if now.Sub(w.lastProgress) < w.config.StallTimeout {
+		return true
+	}
+
+	if !w.stalled {
+		w.logger.Error("No data seen by telemetry_stats, stop petting watchdog",
+			zap.Duration("stall_timeout", w.config.StallTimeout),
+			zap.Time("last_progress", w.lastProgress),
+		)
+		w.stalled = true
+	}
+	return false
+}
+
+func (w *watchdogExtension) pet() {
+	if w.notifySocket != "" {
+		if err := sdNotify(w.notifySocket, "WATCHDOG=1"); err != nil {
+			w.logger.Error("Failed to notify systemd watchdog", zap.Error(err))
+		}
+	}
+	if w.device != nil {
+		if _, err := w.device.Write([]byte{0}); err != nil {
+			w.logger.Error("Failed to pet hardware watchdog", zap.Error(err))
+		}
+	}
+}
+
+// sdNotify sends a state string to systemd over the notify socket, which may
+// be in the abstract namespace when prefixed by "@".
+func sdNotify(socket, state string) error {
+	if strings.HasPrefix(socket, "@") {
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil,
+		&net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	return err
+}
@@ -0,0 +1,292 @@
+package webhookexporter
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"text/template"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configretry"
+	"go.opentelemetry.io/collector/exporter/exporterhelper"
+
+	"otelcommon/deadletter"
+	"otelcommon/filter"
+	"otelcommon/queuestats"
+)
+
+const (
+	formatSlack     = "slack"
+	formatPagerDuty = "pagerduty"
+	formatGeneric   = "generic"
+)
+
+// pagerDutyURL is the PagerDuty Events API v2 endpoint used by pagerduty
+// webhooks without a URL
+const pagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+// severities maps the severity names accepted by `min_severity` to the lowest
+// OTLP severity number of each range
+var severities = map[string]int32{
+	"trace": 1,
+	"debug": 5,
+	"info":  9,
+	"warn":  13,
+	"error": 17,
+	"fatal": 21,
+}
+
+// Config defines the configuration of the webhook exporter.
+type Config struct {
+	// Webhooks configures the webhooks log records are posted to.
+	Webhooks []Webhook `mapstructure:"webhooks"`
+
+	// MinSeverity is the lowest severity of log records posted to
+	// webhooks without their own min_severity, one of "trace", "debug",
+	// "info", "warn", "error" or "fatal". Defaults to "warn".
+	MinSeverity string `mapstructure:"min_severity"`
+
+	// RateLimit limits the log records posted to webhooks without their
+	// own rate_limit.
+	RateLimit RateLimit `mapstructure:"rate_limit"`
+
+	// Timeout is the timeout of each request. Defaults to "10s".
+	Timeout time.Duration `mapstructure:"timeout"`
+
+	// QueueSettings configures the sending queue of each webhook.
+	QueueSettings exporterhelper.QueueSettings `mapstructure:"sending_queue"`
+
+	// BackOffConfig configures the retries of each webhook.
+	BackOffConfig configretry.BackOffConfig `mapstructure:"retry_on_failure"`
+
+	// QueueStatsEndpoint serves the statistics of the sending queue of
+	// each webhook at /metrics/exporters. Empty disables them. Defaults to
+	// "localhost:8890".
+	QueueStatsEndpoint string `mapstructure:"queue_stats_endpoint"`
+
+	// DeadLetter optionally is the ID of a dead_letter receiver, e.g.
+	// "dead_letter/rejected", whose logs pipelines receive the log records
+	// that could not be posted: those rejected permanently, and those still
+	// failing when retries give up. Empty drops them.
+	DeadLetter string `mapstructure:"dead_letter"`
+}
+
+// Webhook defines a single webhook and the log records posted to it.
+type Webhook struct {
+	// Name identifies the webhook in the collector's logs.
+	Name string `mapstructure:"name"`
+
+	// URL is the URL log records are posted to. Optional for pagerduty
+	// webhooks, which default to the PagerDuty Events API v2.
+	URL string `mapstructure:"url"`
+
+	// Format is the format of the request body, "slack" for a Slack
+	// incoming webhook message, "pagerduty" for a PagerDuty Events API v2
+	// event or "generic" for a JSON document rendered by Template.
+	Format string `mapstructure:"format"`
+
+	// Template is a Go text/template rendering the JSON body of generic
+	// webhooks. Defaults to the log record as a JSON object.
+	Template string `mapstructure:"template"`
+
+	// RoutingKey is the integration key of the PagerDuty service. Required
+	// for pagerduty webhooks.
+	RoutingKey string `mapstructure:"routing_key"`
+
+	// Headers are additional headers sent with each request.
+	Headers map[string]string `mapstructure:"headers"`
+
+	// BearerTokenFile is an optional path of a file holding a token sent
+	// as "Authorization: Bearer <token>". It is read on every request, so
+	// the token can be rotated.
+	BearerTokenFile string `mapstructure:"bearer_token_file"`
+
+	// CAFile is an optional path of a PEM encoded CA bundle used to verify
+	// the webhook, instead of the system roots.
+	CAFile string `mapstructure:"ca_file"`
+
+	// MinSeverity optionally overrides the exporter's min_severity.
+	MinSeverity string `mapstructure:"min_severity"`
+
+	// RateLimit optionally overrides the exporter's rate_limit.
+	RateLimit *RateLimit `mapstructure:"rate_limit"`
+
+	// Include optionally limits the log records posted to the webhook to
+	// those matching the filter.
+	Include *Filter `mapstructure:"include"`
+
+	// Exclude optionally removes log records matching the filter from
+	// those posted to the webhook.
+	Exclude *Filter `mapstructure:"exclude"`
+}
+
+// RateLimit defines a token bucket limiting the log records posted to a
+// webhook. Log records over the limit are dropped rather than queued, so that
+// a flood of alerts doesn't keep paging long after it ended.
+type RateLimit struct {
+	// PerMinute is the sustained number of log records posted per
+	// minute. Defaults to 10.
+	PerMinute int `mapstructure:"per_minute"`
+
+	// Burst is the number of log records that can be posted at once.
+	// Defaults to 5.
+	Burst int `mapstructure:"burst"`
+}
+
+// Filter defines criteria matching log records. A log record matches the
+// filter if it matches all of the specified criteria.
+type Filter struct {
+	// ResourceAttributes match the attributes of the resource.
+	ResourceAttributes []filter.LabelFilter `mapstructure:"resource_attributes"`
+
+	// Attributes match the attributes of the log record itself.
+	Attributes []filter.LabelFilter `mapstructure:"attributes"`
+
+	// BodyRegex is a regular expression matching the body of the log
+	// record.
+	BodyRegex string `mapstructure:"body_regex"`
+}
+
+// ensure that Config implements the component.Config interface
+var _ component.Config = (*Config)(nil)
+
+// Validate implements the component.Config interface by checking whether the
+// configuration is valid.
+func (cfg *Config) Validate() error {
+	if len(cfg.Webhooks) == 0 {
+		return errors.New("at least one webhook must be configured")
+	}
+	if _, exists := severities[cfg.MinSeverity]; !exists {
+		return fmt.Errorf("invalid min_severity %q", cfg.MinSeverity)
+	}
+	if err := cfg.RateLimit.validate(); err != nil {
+		return err
+	}
+	if cfg.Timeout <= 0 {
+		return errors.New("timeout must be positive")
+	}
+	if err := cfg.QueueSettings.Validate(); err != nil {
+		return fmt.Errorf("invalid sending_queue: %w", err)
+	}
+	if err := cfg.BackOffConfig.Validate(); err != nil {
+		return fmt.Errorf("invalid retry_on_failure: %w", err)
+	}
+	if cfg.DeadLetter != "" {
+		if err := deadletter.ValidateReceiver(cfg.DeadLetter); err != nil {
+			return err
+		}
+	}
+
+	names := make(map[string]bool)
+	for _, webhook := range cfg.Webhooks {
+		if webhook.Name == "" {
+			return errors.New("webhook name cannot be empty")
+		}
+		if names[webhook.Name] {
+			return fmt.Errorf("webhook %s is configured more than once",
+				webhook.Name)
+		}
+		names[webhook.Name] = true
+
+		if err := webhook.validate(); err != nil {
+			return fmt.Errorf("webhook %s: %w", webhook.Name, err)
+		}
+	}
+	return nil
+}
+
+func (webhook *Webhook) validate() error {
+	switch webhook.Format {
+	case formatSlack, formatGeneric:
+		if webhook.URL == "" {
+			return errors.New("url must be specified")
+		}
+	case formatPagerDuty:
+		if webhook.RoutingKey == "" {
+			return errors.New("routing_key must be specified for the " +
+				"pagerduty format")
+		}
+	default:
+		return fmt.Errorf("format must be %q, %q or %q", formatSlack,
+			formatPagerDuty, formatGeneric)
+	}
+	if webhook.URL != "" {
+		u, err := url.Parse(webhook.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return errors.New("url must be an http or https URL")
+		}
+	}
+	if webhook.Template != "" {
+		if webhook.Format != formatGeneric {
+			return errors.New("template is only supported by the generic format")
+		}
+		if _, err := parseTemplate(webhook.Template); err != nil {
+			return fmt.Errorf("invalid template: %w", err)
+		}
+	}
+	if webhook.RoutingKey != "" && webhook.Format != formatPagerDuty {
+		return errors.New("routing_key is only supported by the pagerduty format")
+	}
+	if webhook.MinSeverity != "" {
+		if _, exists := severities[webhook.MinSeverity]; !exists {
+			return fmt.Errorf("invalid min_severity %q", webhook.MinSeverity)
+		}
+	}
+	if webhook.RateLimit != nil {
+		if err := webhook.RateLimit.validate(); err != nil {
+			return err
+		}
+	}
+	for _, f := range []*Filter{webhook.Include, webhook.Exclude} {
+		if f == nil {
+			continue
+		}
+		if err := f.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (limit *RateLimit) validate() error {
+	if limit.PerMinute <= 0 {
+		return errors.New("rate_limit per_minute must be positive")
+	}
+	if limit.Burst <= 0 {
+		return errors.New("rate_limit burst must be positive")
+	}
+	return nil
+}
+
+func (f *Filter) validate() error {
+	if len(f.ResourceAttributes) == 0 && len(f.Attributes) == 0 &&
+		f.BodyRegex == "" {
+		return errors.New("filter must specify at least one criterion")
+	}
+	_, err := compileFilter(f)
+	return err
+}
+
+// parseTemplate parses the template of a generic webhook, which can use the
+// json function to quote values.
+func parseTemplate(text string) (*template.Template, error) {
+	return template.New("body").Funcs(template.FuncMap{
+		"json": toJSON,
+	}).Parse(text)
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		MinSeverity: "warn",
+		RateLimit: RateLimit{
+			PerMinute: 10,
+			Burst:     5,
+		},
+		Timeout:       10 * time.Second,
+		QueueSettings: exporterhelper.NewDefaultQueueSettings(),
+		BackOffConfig: configretry.NewDefaultBackOffConfig(),
+
+		QueueStatsEndpoint: queuestats.DefaultEndpoint,
+	}
+}
@@ -0,0 +1,49 @@
+package webhookexporter
+
+import (
+	"context"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/exporter"
+	"go.opentelemetry.io/collector/exporter/exporterhelper"
+)
+
+const (
+	typeStr   = "webhook"
+	stability = component.StabilityLevelAlpha
+)
+
+// log records are copied for each webhook before they are filtered
+var exporterCapabilities = consumer.Capabilities{MutatesData: false}
+
+func NewFactory() exporter.Factory {
+	return exporter.NewFactory(
+		component.MustNewType(typeStr),
+		createDefaultConfig,
+		exporter.WithLogs(createLogsExporter, stability),
+	)
+}
+
+func createLogsExporter(
+	ctx context.Context,
+	set exporter.CreateSettings,
+	cfg component.Config,
+) (exporter.Logs, error) {
+	e, err := newWebhookExporter(ctx, set, cfg.(*Config))
+	if err != nil {
+		return nil, err
+	}
+
+	// Queueing and retries are left to the webhooks' exporters, so that
+	// an unreachable webhook doesn't hold back the others.
+	return exporterhelper.NewLogsExporter(
+		ctx,
+		set,
+		cfg,
+		e.consumeLogs,
+		exporterhelper.WithCapabilities(exporterCapabilities),
+		exporterhelper.WithStart(e.start),
+		exporterhelper.WithShutdown(e.shutdown),
+	)
+}
@@ -0,0 +1,52 @@
+package webhookexporter
+
+import (
+	"fmt"
+	"regexp"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+
+	"otelcommon/filter"
+)
+
+// compiledFilter is a Filter with its regular expressions compiled.
+type compiledFilter struct {
+	resourceAttributes *filter.LabelMatcher
+	attributes         *filter.LabelMatcher
+	reBody             *regexp.Regexp
+}
+
+// compileFilter returns nil for a nil filter.
+func compileFilter(f *Filter) (*compiledFilter, error) {
+	if f == nil {
+		return nil, nil
+	}
+	resourceAttributes, err := filter.CompileLabelFilters(f.ResourceAttributes)
+	if err != nil {
+		return nil, fmt.Errorf("resource_attributes: %w", err)
+	}
+	attributes, err := filter.CompileLabelFilters(f.Attributes)
+	if err != nil {
+		return nil, fmt.Errorf("attributes: %w", err)
+	}
+	compiled := &compiledFilter{
+		resourceAttributes: resourceAttributes,
+		attributes:         attributes,
+	}
+	if f.BodyRegex != "" {
+		compiled.reBody, err = regexp.Compile(f.BodyRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid body_regex: %w", err)
+		}
+	}
+	return compiled, nil
+}
+
+func (f *compiledFilter) match(resource pcommon.Map, lr plog.LogRecord) bool {
+	if f.reBody != nil && !f.reBody.MatchString(lr.Body().AsString()) {
+		return false
+	}
+	return f.resourceAttributes.MatchAll(filter.MapAttributes(resource)) &&
+		f.attributes.MatchAll(filter.MapAttributes(lr.Attributes()))
+}
@@ -0,0 +1,3 @@
+module webhookexporter
+
+go 1.22
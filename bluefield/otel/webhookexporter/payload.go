@@ -0,0 +1,167 @@
+package webhookexporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+// maxSummaryLength is the longest summary accepted by PagerDuty
+const maxSummaryLength = 1024
+
+// defaultTemplate renders the whole record as the body of generic webhooks.
+const defaultTemplate = `{{ json . }}`
+
+// record is a log record as passed to the template of generic webhooks.
+type record struct {
+	Timestamp      string         `json:"timestamp"`
+	Severity       string         `json:"severity"`
+	SeverityNumber int32          `json:"severity_number"`
+	Body           string         `json:"body"`
+	Attributes     map[string]any `json:"attributes"`
+	Resource       map[string]any `json:"resource"`
+}
+
+func newRecord(resource pcommon.Map, lr plog.LogRecord) record {
+	timestamp := lr.Timestamp()
+	if timestamp == 0 {
+		timestamp = lr.ObservedTimestamp()
+	}
+	return record{
+		Timestamp:      timestamp.AsTime().UTC().Format(time.RFC3339Nano),
+		Severity:       severityName(lr),
+		SeverityNumber: int32(lr.SeverityNumber()),
+		Body:           lr.Body().AsString(),
+		Attributes:     lr.Attributes().AsRaw(),
+		Resource:       resource.AsRaw(),
+	}
+}
+
+// severityName returns the severity text of a log record, or the name of the
+// range of its severity number if it has none.
+func severityName(lr plog.LogRecord) string {
+	if lr.SeverityText() != "" {
+		return lr.SeverityText()
+	}
+	switch number := lr.SeverityNumber(); {
+	case number >= plog.SeverityNumberFatal:
+		return "FATAL"
+	case number >= plog.SeverityNumberError:
+		return "ERROR"
+	case number >= plog.SeverityNumberWarn:
+		return "WARN"
+	case number >= plog.SeverityNumberInfo:
+		return "INFO"
+	case number >= plog.SeverityNumberDebug:
+		return "DEBUG"
+	case number >= plog.SeverityNumberTrace:
+		return "TRACE"
+	}
+	return "UNSPECIFIED"
+}
+
+// formatter renders the request body of a log record.
+type formatter interface {
+	format(r record) ([]byte, error)
+}
+
+// slackFormatter renders Slack incoming webhook messages.
+type slackFormatter struct{}
+
+func (slackFormatter) format(r record) ([]byte, error) {
+	var text strings.Builder
+	fmt.Fprintf(&text, "*%s*", r.Severity)
+	if host, ok := r.Resource["host.name"].(string); ok && host != "" {
+		fmt.Fprintf(&text, " on %s", host)
+	}
+	fmt.Fprintf(&text, ": %s", r.Body)
+	return json.Marshal(map[string]string{"text": text.String()})
+}
+
+// pagerDutyFormatter renders PagerDuty Events API v2 trigger events.
+type pagerDutyFormatter struct {
+	routingKey string
+	hostname   string // source of records without a host.name resource attribute
+}
+
+func (f pagerDutyFormatter) format(r record) ([]byte, error) {
+	source := f.hostname
+	if host, ok := r.Resource["host.name"].(string); ok && host != "" {
+		source = host
+	}
+	summary := r.Body
+	if summary == "" {
+		summary = r.Severity
+	}
+	if len(summary) > maxSummaryLength {
+		summary = summary[:maxSummaryLength]
+	}
+
+	// attributes of the log record take precedence over those of the
+	// resource
+	details := make(map[string]any, len(r.Resource)+len(r.Attributes))
+	for key, value := range r.Resource {
+		details[key] = value
+	}
+	for key, value := range r.Attributes {
+		details[key] = value
+	}
+
+	return json.Marshal(map[string]any{
+		"routing_key":  f.routingKey,
+		"event_action": "trigger",
+		"payload": map[string]any{
+			"summary":        summary,
+			"source":         source,
+			"severity":       pagerDutySeverity(r.SeverityNumber),
+			"timestamp":      r.Timestamp,
+			"custom_details": details,
+		},
+	})
+}
+
+// pagerDutySeverity maps an OTLP severity number to the severities accepted
+// by PagerDuty.
+func pagerDutySeverity(number int32) string {
+	switch {
+	case number >= int32(plog.SeverityNumberFatal):
+		return "critical"
+	case number >= int32(plog.SeverityNumberError):
+		return "error"
+	case number >= int32(plog.SeverityNumberWarn):
+		return "warning"
+	}
+	return "info"
+}
+
+// templateFormatter renders the JSON bodies of generic webhooks.
+type templateFormatter struct {
+	template *template.Template
+}
+
+func (f templateFormatter) format(r record) ([]byte, error) {
+	var body bytes.Buffer
+	if err := f.template.Execute(&body, r); err != nil {
+		return nil, err
+	}
+	if !json.Valid(body.Bytes()) {
+		return nil, errors.New("template did not render valid JSON")
+	}
+	return body.Bytes(), nil
+}
+
+// toJSON is the json function of templates.
+func toJSON(value any) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,3 @@
+package webhookexporter
+
+const Version = "0.0.1"
@@ -0,0 +1,405 @@
+package webhookexporter
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/exporter"
+	"go.opentelemetry.io/collector/exporter/exporterhelper"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+
+	"otelcommon/deadletter"
+	"otelcommon/queuestats"
+	"otelcommon/retryafter"
+)
+
+// maxResponseSize limits the part of a webhook's response that is logged
+const maxResponseSize = 1024
+
+type webhookExporter struct {
+	logger   *zap.Logger
+	webhooks []*webhook
+}
+
+// webhook selects the log records posted to a configured webhook, and hands
+// them to the webhook's exporter, which queues and retries them independently
+// of the other webhooks.
+type webhook struct {
+	name        string
+	minSeverity plog.SeverityNumber
+	include     *compiledFilter
+	exclude     *compiledFilter
+	limiter     *rateLimiter
+	logs        exporter.Logs
+	stats       *queuestats.Stats
+	deadLetter  *deadletter.Capture
+}
+
+// sender posts log records to a webhook.
+type sender struct {
+	config    *Webhook
+	url       string
+	logger    *zap.Logger
+	client    *http.Client
+	formatter formatter
+}
+
+func newWebhookExporter(
+	ctx context.Context,
+	set exporter.CreateSettings,
+	config *Config,
+) (*webhookExporter, error) {
+	e := &webhookExporter{logger: set.Logger}
+	if err := e.addWebhooks(ctx, set, config); err != nil {
+		for _, w := range e.webhooks {
+			w.stats.Unregister()
+			w.deadLetter.Close()
+		}
+		return nil, err
+	}
+	return e, nil
+}
+
+// addWebhooks creates the exporter of each webhook.
+func (e *webhookExporter) addWebhooks(
+	ctx context.Context,
+	set exporter.CreateSettings,
+	config *Config,
+) error {
+	hostname, _ := os.Hostname()
+
+	for i := range config.Webhooks {
+		webhookConfig := &config.Webhooks[i]
+		webhookSet := set
+		name := webhookConfig.Name
+		if set.ID.Name() != "" {
+			name = set.ID.Name() + "/" + name
+		}
+		webhookSet.ID = component.NewIDWithName(set.ID.Type(), name)
+		webhookSet.Logger = set.Logger.With(zap.String("webhook", webhookConfig.Name))
+
+		s, err := newSender(webhookConfig, config.Timeout, hostname,
+			webhookSet.Logger)
+		if err != nil {
+			return fmt.Errorf("webhook %s: %w", webhookConfig.Name, err)
+		}
+		include, err := compileFilter(webhookConfig.Include)
+		if err != nil {
+			return fmt.Errorf("webhook %s: include: %w", webhookConfig.Name, err)
+		}
+		exclude, err := compileFilter(webhookConfig.Exclude)
+		if err != nil {
+			return fmt.Errorf("webhook %s: exclude: %w", webhookConfig.Name, err)
+		}
+
+		stats, err := queuestats.Register(queuestats.Settings{
+			Endpoint:       config.QueueStatsEndpoint,
+			Exporter:       set.ID.String(),
+			Destination:    webhookConfig.Name,
+			Signal:         "logs",
+			Untracked:      config.QueueSettings.Enabled && config.QueueSettings.StorageID != nil,
+			RetryEnabled:   config.BackOffConfig.Enabled,
+			MaxElapsedTime: config.BackOffConfig.MaxElapsedTime,
+		}, webhookSet.Logger)
+		if err != nil {
+			return fmt.Errorf("webhook %s: %w", webhookConfig.Name, err)
+		}
+
+		deadLetter := deadletter.New(deadletter.Settings{
+			Receiver:       config.DeadLetter,
+			Exporter:       set.ID.String(),
+			Destination:    webhookConfig.Name,
+			RetryEnabled:   config.BackOffConfig.Enabled,
+			MaxElapsedTime: config.BackOffConfig.MaxElapsedTime,
+		}, webhookSet.Logger)
+
+		// The sender removes the log records it posted, so that a retry
+		// only posts the remaining ones, and only those that could not
+		// be posted are routed to the dead-letter pipeline. Requests time
+		// out individually rather than the whole batch.
+		logs, err := exporterhelper.NewLogsExporter(
+			ctx,
+			webhookSet,
+			config,
+			stats.PushLogs(deadLetter.PushLogs(s.push)),
+			exporterhelper.WithCapabilities(consumer.Capabilities{MutatesData: true}),
+			exporterhelper.WithTimeout(exporterhelper.TimeoutSettings{}),
+			exporterhelper.WithQueue(config.QueueSettings),
+			exporterhelper.WithRetry(config.BackOffConfig),
+		)
+		if err != nil {
+			stats.Unregister()
+			deadLetter.Close()
+			return fmt.Errorf("failed to create exporter for webhook "+
+				"%s: %w", webhookConfig.Name, err)
+		}
+
+		minSeverity := config.MinSeverity
+		if webhookConfig.MinSeverity != "" {
+			minSeverity = webhookConfig.MinSeverity
+		}
+		rateLimit := config.RateLimit
+		if webhookConfig.RateLimit != nil {
+			rateLimit = *webhookConfig.RateLimit
+		}
+		e.webhooks = append(e.webhooks, &webhook{
+			name:        webhookConfig.Name,
+			minSeverity: plog.SeverityNumber(severities[minSeverity]),
+			include:     include,
+			exclude:     exclude,
+			limiter:     newRateLimiter(rateLimit),
+			logs:        stats.WrapLogs(logs),
+			stats:       stats,
+			deadLetter:  deadLetter,
+		})
+	}
+
+	return nil
+}
+
+func (e *webhookExporter) start(ctx context.Context, host component.Host) error {
+	for _, w := range e.webhooks {
+		if err := w.logs.Start(ctx, host); err != nil {
+			return fmt.Errorf("failed to start exporter for webhook %s: %w",
+				w.name, err)
+		}
+	}
+	return nil
+}
+
+// shutdown shuts down every webhook's exporter, which drains or persists its
+// queue, even if others fail.
+func (e *webhookExporter) shutdown(ctx context.Context) error {
+	var errs []error
+	for _, w := range e.webhooks {
+		if err := w.logs.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s: %w", w.name, err))
+		}
+		w.stats.Unregister()
+		w.deadLetter.Close()
+	}
+	return errors.Join(errs...)
+}
+
+func (e *webhookExporter) consumeLogs(ctx context.Context, ld plog.Logs) error {
+	var errs []error
+	for _, w := range e.webhooks {
+		data := plog.NewLogs()
+		ld.CopyTo(data)
+		if limited := w.filterLogs(data); limited > 0 {
+			e.logger.Warn("Rate limit of webhook exceeded, log records dropped",
+				zap.String("webhook", w.name), zap.Int("dropped", limited))
+		}
+		if data.LogRecordCount() == 0 {
+			continue
+		}
+		if err := w.logs.ConsumeLogs(ctx, data); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s: %w", w.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// filterLogs removes the log records that are not posted to the webhook, and
+// returns the number of those removed by the rate limit.
+func (w *webhook) filterLogs(ld plog.Logs) int {
+	limited := 0
+	now := time.Now()
+	ld.ResourceLogs().RemoveIf(func(rl plog.ResourceLogs) bool {
+		resource := rl.Resource().Attributes()
+		rl.ScopeLogs().RemoveIf(func(sl plog.ScopeLogs) bool {
+			sl.LogRecords().RemoveIf(func(lr plog.LogRecord) bool {
+				if lr.SeverityNumber() < w.minSeverity {
+					return true
+				}
+				if w.include != nil && !w.include.match(resource, lr) {
+					return true
+				}
+				if w.exclude != nil && w.exclude.match(resource, lr) {
+					return true
+				}
+				if !w.limiter.allow(now) {
+					limited++
+					return true
+				}
+				return false
+			})
+			return sl.LogRecords().Len() == 0
+		})
+		return rl.ScopeLogs().Len() == 0
+	})
+	return limited
+}
+
+func newSender(
+	config *Webhook,
+	timeout time.Duration,
+	hostname string,
+	logger *zap.Logger,
+) (*sender, error) {
+	s := &sender{
+		config: config,
+		url:    config.URL,
+		logger: logger,
+	}
+	switch config.Format {
+	case formatSlack:
+		s.formatter = slackFormatter{}
+	case formatPagerDuty:
+		s.formatter = pagerDutyFormatter{
+			routingKey: config.RoutingKey,
+			hostname:   hostname,
+		}
+		if s.url == "" {
+			s.url = pagerDutyURL
+		}
+	default:
+		text := config.Template
+		if text == "" {
+			text = defaultTemplate
+		}
+		tmpl, err := parseTemplate(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template: %w", err)
+		}
+		s.formatter = templateFormatter{template: tmpl}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.CAFile != "" {
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca_file: %w", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificates found in ca_file")
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: roots}
+	}
+	s.client = &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+	}
+	return s, nil
+}
+
+// push posts each log record and removes it once it was posted. Log records
+// that can't be rendered or that the webhook rejects are dropped, while the
+// remaining ones are retried if posting fails.
+func (s *sender) push(ctx context.Context, ld plog.Logs) error {
+	var err error
+	ld.ResourceLogs().RemoveIf(func(rl plog.ResourceLogs) bool {
+		resource := rl.Resource().Attributes()
+		rl.ScopeLogs().RemoveIf(func(sl plog.ScopeLogs) bool {
+			sl.LogRecords().RemoveIf(func(lr plog.LogRecord) bool {
+				if err != nil {
+					return false
+				}
+				err = s.post(ctx, newRecord(resource, lr))
+				return err == nil
+			})
+			return sl.LogRecords().Len() == 0
+		})
+		return rl.ScopeLogs().Len() == 0
+	})
+	return err
+}
+
+// post posts a log record, returning an error only if it should be retried.
+func (s *sender) post(ctx context.Context, r record) error {
+	body, err := s.formatter.format(r)
+	if err != nil {
+		s.logger.Error("Failed to render log record, dropping it",
+			zap.Error(err))
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url,
+		bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range s.config.Headers {
+		req.Header.Set(key, value)
+	}
+	if s.config.BearerTokenFile != "" {
+		token, err := os.ReadFile(s.config.BearerTokenFile)
+		if err != nil {
+			return fmt.Errorf("failed to read bearer_token_file: %w", err)
+		}
+		req.Header.Set("Authorization",
+			"Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	response, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return exporterhelper.NewThrottleRetry(
+			fmt.Errorf("webhook returned %s", resp.Status),
+			retryafter.Parse(resp.Header.Get("Retry-After")))
+	case resp.StatusCode >= 500:
+		return fmt.Errorf("webhook returned %s: %s", resp.Status,
+			bytes.TrimSpace(response))
+	}
+	s.logger.Error("Webhook rejected log record, dropping it",
+		zap.String("status", resp.Status),
+		zap.ByteString("response", bytes.TrimSpace(response)))
+	return nil
+}
+
+// rateLimiter is a token bucket.
+type rateLimiter struct {
+	lock   sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(limit RateLimit) *rateLimiter {
+	return &rateLimiter{
+		rate:   float64(limit.PerMinute) / 60,
+		burst:  float64(limit.Burst),
+		tokens: float64(limit.Burst),
+		last:   time.Now(),
+	}
+}
+
+// allow takes a token if one is available.
+func (l *rateLimiter) allow(now time.Time) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if now.After(l.last) {
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+		l.last = now
+	}
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}